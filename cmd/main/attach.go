@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/client"
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// detachTimeout bounds asking the daemon to let an execution go on
+const detachTimeout = 5 * time.Second
+
+// detacher leaves an execution running in the daemon, streaming it no more: as soon as it started
+// for run -detach, or on Ctrl+\ (SIGQUIT) meanwhile
+type detacher struct {
+	client    *client.Client
+	immediate bool
+	cancel    context.CancelFunc // Stops streaming the execution
+	signals   chan os.Signal
+
+	mu   sync.Mutex
+	id   string
+	done bool
+}
+
+// newDetacher returns a detacher for the daemon of config, listening for Ctrl+\ until stop
+func newDetacher(config *shared.AppConfig, immediate bool, cancel context.CancelFunc) (*detacher, error) {
+	c, err := client.New(client.Options{Socket: config.DaemonSocketPath(), MaxRetries: -1})
+	if err != nil {
+		return nil, err
+	}
+	d := &detacher{client: c, immediate: immediate, cancel: cancel, signals: make(chan os.Signal, 1)}
+	signal.Notify(d.signals, syscall.SIGQUIT)
+	go func() {
+		for range d.signals {
+			d.detach()
+		}
+	}()
+	return d, nil
+}
+
+// started is told the daemon's id of the execution
+func (d *detacher) started(id string) {
+	d.mu.Lock()
+	d.id = id
+	d.mu.Unlock()
+	if d.immediate {
+		d.detach()
+	}
+}
+
+func (d *detacher) detach() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.done {
+		return
+	}
+	if d.id == "" {
+		log.Print(msg("attach.not_started"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), detachTimeout)
+	defer cancel()
+	if err := d.client.Detach(ctx, d.id); err != nil {
+		log.Print(msg("warning", err))
+		return
+	}
+	d.done = true
+	d.cancel()
+}
+
+// detached returns the id of the execution if it was detached from
+func (d *detacher) detached() (string, bool) {
+	if d == nil {
+		return "", false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.id, d.done
+}
+
+func (d *detacher) stop() {
+	signal.Stop(d.signals)
+	close(d.signals)
+	d.client.Close()
+}
+
+// reportDetached tells how to get back to a detached execution
+func reportDetached(plugin, id string) {
+	if output.structured() {
+		output.event(runEvent{Event: "detached", Plugin: plugin, Time: time.Now(), Execution: id})
+	}
+	log.Print(msg("run.detached", id, plugin, id))
+}
+
+// executionDocument is an execution as attach lists it with structured output
+type executionDocument struct {
+	ID       string    `json:"id"`
+	Plugin   string    `json:"plugin"`
+	Started  time.Time `json:"started"`
+	Attached bool      `json:"attached"`
+	Finished bool      `json:"finished"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// runAttach implements the attach command, which streams an execution a run detached from in the
+// daemon, from its start, or lists the executions that can be attached to
+func runAttach(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("attach", flag.ExitOnError)
+	configPath, socket := daemonFlags(fs)
+	outputFlag(fs)
+	logFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() > 1 {
+		fmt.Println(msg("attach.usage"))
+		os.Exit(1)
+	}
+
+	config := loadDaemonConfig(*configPath, *socket)
+	if _, err := shared.DaemonPID(config); errors.Is(err, shared.ErrNoDaemon) {
+		fatal(msg("daemon.not_running", config.DaemonSocketPath()))
+	} else if err != nil {
+		fatal(msg("error", err))
+	}
+	c, err := client.New(client.Options{Socket: config.DaemonSocketPath(), MaxRetries: -1})
+	if err != nil {
+		fatal(msg("error", err))
+	}
+	defer c.Close()
+	executions, err := c.Executions(ctx)
+	if err != nil {
+		fatal(msg("error", err))
+	}
+
+	if fs.NArg() == 0 {
+		listExecutions(executions, time.Now())
+		return
+	}
+	id := fs.Arg(0)
+	var execution *client.Execution
+	for i := range executions {
+		if executions[i].ID == id {
+			execution = &executions[i]
+		}
+	}
+	if execution == nil {
+		fatal(msg("attach.not_found", id))
+	}
+	plugin := execution.Plugin
+
+	// Mask what the configuration asks for; the parameters of the execution aren't known here
+	info, err := c.Info(ctx, plugin)
+	if err != nil {
+		fatal(msg("run.info_failed", err))
+	}
+	redactor, err := shared.NewRedactor(config.Redaction, info.ParameterSchema, nil)
+	if err != nil {
+		fatal(msg("error", err))
+	}
+	capture := shared.NewOutputCapture(config.SpillPath(), nil)
+	defer capture.Close()
+	handler := &outputHandler{pluginName: plugin, executionID: id, redactor: redactor, capture: capture}
+
+	// The execution goes on in the daemon on Ctrl+\, and is canceled on Ctrl+C
+	attachCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	detach, err := newDetacher(config, false, cancel)
+	if err != nil {
+		fatal(msg("error", err))
+	}
+	defer detach.stop()
+	detach.started(id)
+	log.Print(msg("attach.attached", id, plugin))
+
+	err = c.Attach(attachCtx, plugin, id, handler)
+	if id, ok := detach.detached(); ok {
+		reportDetached(plugin, id)
+		return
+	}
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			log.Print(msg("run.canceled", plugin))
+			return
+		}
+		fatal(msg("run.failed", plugin, redactor.String(err.Error())))
+	}
+	log.Print(msg("run.completed"))
+}
+
+// listExecutions shows the executions that can be attached to
+func listExecutions(executions []client.Execution, now time.Time) {
+	if output.structured() {
+		docs := make([]executionDocument, len(executions))
+		for i, e := range executions {
+			docs[i] = executionDocument{e.ID, e.Plugin, e.Started, e.Attached, e.Finished, e.Error}
+		}
+		output.document(docs)
+		return
+	}
+	w := tabwriter.NewWriter(output.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, msg("attach.header"))
+	for _, e := range executions {
+		fmt.Fprintln(w, msg("attach.row", e.ID, e.Plugin, psDuration(shared.Duration(now.Sub(e.Started))), executionState(e)))
+	}
+	w.Flush()
+}
+
+// executionState summarizes where an execution stands
+func executionState(e client.Execution) string {
+	switch {
+	case e.Finished && e.Error != "":
+		return msg("attach.state_failed", e.Error)
+	case e.Finished:
+		return msg("attach.state_finished")
+	case e.Attached:
+		return msg("attach.state_attached")
+	default:
+		return msg("attach.state_detached")
+	}
+}
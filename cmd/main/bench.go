@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// benchHandler discards what an execution streams, keeping the error the plugin reported
+type benchHandler struct {
+	mu  sync.Mutex
+	err string
+}
+
+func (h *benchHandler) OnOutput(line string) error                   { return nil }
+func (h *benchHandler) OnProgress(p shared.Progress) error           { return nil }
+func (h *benchHandler) OnResult(result map[string]interface{}) error { return nil }
+
+func (h *benchHandler) OnError(code, message, details string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.err = code + ": " + message
+	return nil
+}
+
+// failure returns the error the plugin reported, which fails a run as much as a failed execution
+func (h *benchHandler) failure() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.err == "" {
+		return nil
+	}
+	return errors.New(msg("bench.plugin_error", h.err))
+}
+
+// benchDocument is what bench reports with structured output
+type benchDocument struct {
+	Plugin         string          `json:"plugin"`
+	Runs           int             `json:"runs"`
+	Failures       int             `json:"failures"`
+	FirstError     string          `json:"first_error,omitempty"`
+	Concurrency    int             `json:"concurrency"`
+	IncludeStartup bool            `json:"include_startup"`
+	Min            shared.Duration `json:"min"`
+	Mean           shared.Duration `json:"mean"`
+	P50            shared.Duration `json:"p50"`
+	P90            shared.Duration `json:"p90"`
+	P99            shared.Duration `json:"p99"`
+	Max            shared.Duration `json:"max"`
+	Wall           shared.Duration `json:"wall"`
+	Throughput     float64         `json:"throughput_per_second"`
+}
+
+// runBench implements the bench command, which executes a plugin many times and reports the
+// latency distribution and throughput. The benchmark runs its own instance of the plugin, on a
+// free port if it is local, so that it neither collides with nor is recorded by a daemon; its
+// executions are not reported or kept in the history either.
+func runBench(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	configPath := configFlag(fs)
+	profileFlag(fs)
+	runs := fs.Int("n", 100, "Number of measured executions")
+	concurrency := fs.Int("c", 1, "How many executions run at once")
+	warmup := fs.Int("warmup", 1, "Executions before measuring, which aren't counted")
+	includeStartup := fs.Bool("include-startup", false, "Start and stop the plugin for every execution and count that in its latency (with -c 1 only)")
+	timeout := fs.Duration("timeout", 0, "Fail an execution with TIMEOUT if it runs longer (e.g. 5s)")
+	outputFlag(fs)
+	logFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fmt.Println(msg("bench.usage"))
+		os.Exit(1)
+	}
+	if *runs < 1 || *concurrency < 1 || *warmup < 0 {
+		fatal(msg("bench.invalid_counts"))
+	}
+	if *timeout < 0 {
+		fatal(msg("run.invalid_timeout", *timeout))
+	}
+	if *includeStartup && *concurrency > 1 {
+		fatal(msg("bench.startup_concurrency"))
+	}
+
+	config := loadConfig(*configPath)
+	name := fs.Arg(0)
+	pluginConfig, err := config.GetPluginConfig(name)
+	if err != nil {
+		fatal(msg("error", err))
+	}
+	if err := pluginConfig.Validate(); err != nil {
+		fatal(msg("run.invalid_plugin", name, err))
+	}
+	if !pluginConfig.IsRemote() {
+		pluginConfig.Port = 0
+	}
+
+	manager := shared.NewPluginManager(config)
+	defer manager.StopAll()
+	manager.SetProcessExitHandler(func(exit *shared.ProcessExit) {
+		log.Print(msg("run.plugin_exited", exit))
+	})
+	killOrphans(manager)
+
+	if err := manager.StartPlugin(name, pluginConfig); err != nil {
+		fatal(msg("run.start_failed", name, err))
+	}
+	plugin, err := manager.GetPlugin(name)
+	if err != nil {
+		manager.StopAll()
+		fatal(msg("run.get_plugin_failed", name, err))
+	}
+	info, err := plugin.GetInfo(ctx)
+	if err != nil {
+		manager.StopAll()
+		fatal(msg("run.info_failed", err))
+	}
+	params := parseParams(fs.Args()[1:])
+	mergeDefaults(params, info, pluginConfig)
+	credential, err := resolveCredential(ctx, "", name, pluginConfig)
+	if err != nil {
+		manager.StopAll()
+		fatal(msg("error", err))
+	}
+	benchCtx := shared.WithCredential(ctx, credential)
+
+	execute := func(ctx context.Context, plugin shared.PluginInterface) error {
+		handler := &benchHandler{}
+		runParams := make(map[string]string, len(params))
+		for k, v := range params {
+			runParams[k] = v
+		}
+		if err := shared.ExecuteWithTimeout(ctx, plugin, name, *timeout, runParams, handler); err != nil {
+			return err
+		}
+		return handler.failure()
+	}
+
+	// Warm-up runs against the started plugin in either mode
+	for i := 0; i < *warmup; i++ {
+		if err := execute(benchCtx, plugin); err != nil {
+			manager.StopAll()
+			fatal(msg("bench.warmup_failed", name, err))
+		}
+	}
+
+	run := func(ctx context.Context) error {
+		return execute(ctx, plugin)
+	}
+	if *includeStartup {
+		manager.StopPlugin(name)
+		run = func(ctx context.Context) error {
+			if err := manager.StartPlugin(name, pluginConfig); err != nil {
+				return err
+			}
+			defer manager.StopPlugin(name)
+			plugin, err := manager.GetPlugin(name)
+			if err != nil {
+				return err
+			}
+			return execute(ctx, plugin)
+		}
+	}
+
+	log.Print(msg("bench.started", name, *runs, *concurrency, *includeStartup))
+	stats := shared.Bench(benchCtx, *runs, *concurrency, run)
+	reportBench(name, *concurrency, *includeStartup, stats)
+	if ctx.Err() != nil {
+		log.Print(msg("bench.canceled", stats.Runs, *runs))
+	}
+	if stats.Failures > 0 {
+		manager.StopAll()
+		os.Exit(1)
+	}
+}
+
+// reportBench shows the outcome of a benchmark
+func reportBench(name string, concurrency int, includeStartup bool, stats shared.BenchStats) {
+	if output.structured() {
+		doc := benchDocument{
+			Plugin:         name,
+			Runs:           stats.Runs,
+			Failures:       stats.Failures,
+			Concurrency:    concurrency,
+			IncludeStartup: includeStartup,
+			Min:            shared.Duration(stats.Min),
+			Mean:           shared.Duration(stats.Mean),
+			P50:            shared.Duration(stats.P50),
+			P90:            shared.Duration(stats.P90),
+			P99:            shared.Duration(stats.P99),
+			Max:            shared.Duration(stats.Max),
+			Wall:           shared.Duration(stats.Wall),
+			Throughput:     stats.Throughput,
+		}
+		if stats.FirstError != nil {
+			doc.FirstError = stats.FirstError.Error()
+		}
+		output.document(doc)
+		return
+	}
+
+	fmt.Fprintln(output.w, msg("bench.header", name, stats.Runs, stats.Failures, concurrency, stats.Wall.Round(time.Millisecond)))
+	if stats.Runs > stats.Failures {
+		w := tabwriter.NewWriter(output.w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, msg("bench.latency_header"))
+		fmt.Fprintln(w, msg("bench.latency_row", benchDuration(stats.Min), benchDuration(stats.Mean), benchDuration(stats.P50),
+			benchDuration(stats.P90), benchDuration(stats.P99), benchDuration(stats.Max)))
+		w.Flush()
+		fmt.Fprintln(output.w, msg("bench.throughput", stats.Throughput))
+	}
+	if stats.FirstError != nil {
+		fmt.Fprintln(output.w, msg("bench.first_error", stats.FirstError))
+	}
+}
+
+// benchDuration rounds a latency to a precision that suits its size
+func benchDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// featureList collects repeated -drop-feature flags
+type featureList []string
+
+func (f *featureList) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *featureList) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// runCompat implements the compat command
+func runCompat(args []string) {
+	current := shared.CurrentHost()
+
+	fs := flag.NewFlagSet("compat", flag.ExitOnError)
+	configPath := configFlag(fs)
+	profileFlag(fs)
+	minProtocol := fs.Int("min-protocol", current.MinProtocol, "Oldest plugin protocol the planned host will support")
+	maxProtocol := fs.Int("max-protocol", current.MaxProtocol, "Newest plugin protocol the planned host will support")
+	var dropped featureList
+	fs.Var(&dropped, "drop-feature", "Host feature the planned host will no longer provide (repeatable)")
+	logFlags(fs)
+	fs.Parse(args)
+
+	config := loadConfig(*configPath)
+
+	planned := current.WithoutFeatures(dropped)
+	planned.MinProtocol, planned.MaxProtocol = *minProtocol, *maxProtocol
+	upgrade := planned.MinProtocol != current.MinProtocol || planned.MaxProtocol != current.MaxProtocol || len(dropped) > 0
+
+	fmt.Println(msg("compat.host", current.MinProtocol, current.MaxProtocol, strings.Join(current.Features, ", ")))
+	if upgrade {
+		fmt.Println(msg("compat.planned_host", planned.MinProtocol, planned.MaxProtocol, strings.Join(planned.Features, ", ")))
+	}
+	fmt.Println()
+
+	manager := shared.NewPluginManager(config)
+	defer manager.StopAll()
+	manager.SetProcessOutput(io.Discard, io.Discard)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := msg("compat.header")
+	if upgrade {
+		header += msg("compat.header_planned")
+	}
+	fmt.Fprintln(w, header)
+
+	breaking := 0
+	for _, name := range sortedPluginNames(config) {
+		info, err := queryPluginInfo(manager, name, config.Plugins[name])
+		if err != nil {
+			fmt.Fprintln(w, msg("compat.unreachable", name, err))
+			breaking++
+			continue
+		}
+
+		features := "-"
+		if len(info.Features) > 0 {
+			features = strings.Join(info.Features, ",")
+		}
+		row := fmt.Sprintf("%s\t%s\t%d\t%s\t%s", name, info.Version, shared.PluginProtocol(info), features, compatStatus(current, info))
+		if upgrade {
+			row += "\t" + compatStatus(planned, info)
+		}
+		fmt.Fprintln(w, row)
+
+		if len(current.Check(info)) > 0 || len(planned.Check(info)) > 0 {
+			breaking++
+		}
+	}
+	w.Flush()
+
+	if breaking > 0 {
+		fmt.Println(msg("compat.incompatible", breaking))
+		os.Exit(1)
+	}
+}
+
+// queryPluginInfo starts a plugin just long enough to read its info
+func queryPluginInfo(manager *shared.PluginManager, name string, config shared.PluginConfig) (*shared.PluginInfo, error) {
+	if err := manager.StartPlugin(name, config); err != nil {
+		return nil, err
+	}
+	defer manager.StopPlugin(name)
+
+	plugin, err := manager.GetPlugin(name)
+	if err != nil {
+		return nil, err
+	}
+	return plugin.GetInfo(context.Background())
+}
+
+// compatStatus summarizes whether a plugin works with a host
+func compatStatus(host shared.HostCapabilities, info *shared.PluginInfo) string {
+	problems := host.Check(info)
+	if len(problems) == 0 {
+		return msg("compat.ok")
+	}
+	return msg("compat.breaks", strings.Join(problems, "; "))
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// subcommands are the commands completed in place of a plugin name
+var subcommands = []string{
+	"attach", "bench", "compat", "completion", "config", "daemon", "doctor", "encrypt", "exec", "health", "history",
+	"logs", "new", "pipeline", "ps", "regress", "run", "schema", "sign", "stats", "test", "validate", "watch",
+}
+
+// pluginSubcommands are the subcommands taking plugin names as arguments
+var pluginSubcommands = map[string]bool{"bench": true, "health": true, "history": true, "logs": true, "regress": true, "stats": true, "test": true, "watch": true}
+
+// completionInfoTimeout bounds starting a plugin to complete its parameters
+const completionInfoTimeout = 5 * time.Second
+
+// Completion scripts, which ask the binary for candidates with __complete and the command line
+// up to the cursor. Parameters complete as name=, then their allowed values after the =.
+const bashCompletion = `# bash completion for %[1]s; load with: source <(%[1]s completion bash)
+_%[2]s_complete() {
+    local line="${COMP_LINE:0:COMP_POINT}"
+    local cur="${line##*[[:space:]]}"
+    local IFS=$'\n'
+    COMPREPLY=($(%[1]s __complete "$line" 2>/dev/null))
+    if [[ "$cur" == *=* && "$COMP_WORDBREAKS" == *=* ]]; then
+        COMPREPLY=("${COMPREPLY[@]#"${cur%%=*}="}")
+    fi
+    if [[ "${COMPREPLY[*]}" == *= ]]; then
+        compopt -o nospace
+    fi
+}
+complete -o default -F _%[2]s_complete %[1]s
+`
+
+const zshCompletion = `#compdef %[1]s
+# zsh completion for %[1]s; load with: source <(%[1]s completion zsh)
+_%[2]s_complete() {
+    local -a candidates
+    candidates=("${(@f)$(%[1]s __complete "${BUFFER[1,CURSOR]}" 2>/dev/null)}")
+    candidates=(${candidates:#})
+    if (( ${#candidates} == 0 )); then
+        _files
+        return
+    fi
+    local -a params values
+    params=(${(M)candidates:#*=})
+    values=(${candidates:#*=})
+    (( ${#params} )) && compadd -S '' -- $params
+    (( ${#values} )) && compadd -- $values
+}
+compdef _%[2]s_complete %[1]s
+`
+
+const fishCompletion = `# fish completion for %[1]s; load with: %[1]s completion fish | source
+complete -c %[1]s -f -a '(%[1]s __complete (commandline -cp))'
+`
+
+// runCompletion implements the completion command, which prints a shell's completion script
+func runCompletion(args []string) {
+	if len(args) != 1 {
+		fmt.Println(msg("completion.usage"))
+		os.Exit(1)
+	}
+	program := filepath.Base(os.Args[0])
+	function := strings.NewReplacer("-", "_", ".", "_").Replace(program)
+	switch args[0] {
+	case "bash":
+		fmt.Printf(bashCompletion, program, function)
+	case "zsh":
+		fmt.Printf(zshCompletion, program, function)
+	case "fish":
+		fmt.Printf(fishCompletion, program)
+	default:
+		fmt.Println(msg("completion.usage"))
+		os.Exit(1)
+	}
+}
+
+// runComplete implements __complete, which prints the candidates for the last word of a command
+// line, one per line. It prints nothing rather than errors, which the shell would only show
+// as candidates.
+func runComplete(runFlags *flag.FlagSet, args []string) {
+	shared.ConfigureLogging(io.Discard, shared.LogFormatText, shared.LogLevelError)
+	line := strings.Join(args, " ")
+	words := strings.Fields(line)
+	current := ""
+	if len(words) > 0 && !strings.HasSuffix(line, " ") {
+		current = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+	if len(words) > 0 {
+		// The program itself
+		words = words[1:]
+	}
+	for _, candidate := range completeRun(runFlags, words, current) {
+		fmt.Println(candidate)
+	}
+}
+
+// completeRun returns the candidates for the current word of a run command line; words are
+// the complete words before it
+func completeRun(runFlags *flag.FlagSet, words []string, current string) []string {
+	var configPath configFiles
+	parallel := false
+	var positional []string
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		// An explicit run takes the same flags and arguments as the default command
+		if i == 0 && word == "run" {
+			continue
+		}
+		if len(positional) > 0 || !strings.HasPrefix(word, "-") {
+			positional = append(positional, word)
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+		f := runFlags.Lookup(name)
+		if f == nil {
+			continue
+		}
+		if !hasValue && !isBoolFlag(f) {
+			if i+1 == len(words) {
+				// The current word is the flag's value, which the shell completes as a file
+				return nil
+			}
+			i++
+			value = words[i]
+		}
+		switch name {
+		case "config":
+			configPath.Set(value)
+		case "parallel":
+			parallel = !hasValue || value == "true"
+		}
+	}
+
+	// Subcommands other than run take their own arguments
+	if len(words) > 0 && words[0] != "run" {
+		for _, command := range subcommands {
+			if words[0] == command {
+				return completeSubcommand(words, current)
+			}
+		}
+	}
+	if strings.HasPrefix(current, "-") {
+		if len(positional) > 0 {
+			return nil
+		}
+		var flags []string
+		runFlags.VisitAll(func(f *flag.Flag) {
+			flags = append(flags, "-"+f.Name)
+		})
+		return withPrefix(flags, current)
+	}
+
+	config, err := shared.LoadConfig(configPath.base(), configPath.overlays()...)
+	if err != nil {
+		return nil
+	}
+	var plugins, params []string
+	for _, word := range positional {
+		if strings.Contains(word, "=") {
+			params = append(params, word)
+		} else {
+			plugins = append(plugins, word)
+		}
+	}
+	if len(plugins) == 0 {
+		candidates := sortedPluginNames(config)
+		if len(words) == 0 {
+			candidates = append(candidates, subcommands...)
+		}
+		return withPrefix(candidates, current)
+	}
+
+	var candidates []string
+	if parallel && !strings.Contains(current, "=") {
+		candidates = append(candidates, sortedPluginNames(config)...)
+	}
+	given := parseParams(params)
+	for _, name := range plugins {
+		pluginConfig, ok := config.Plugins[name]
+		if !ok {
+			continue
+		}
+		info := completionInfo(config, name, pluginConfig)
+		if info == nil {
+			continue
+		}
+		candidates = append(candidates, paramCandidates(info, given, current)...)
+		if !parallel {
+			break
+		}
+	}
+	sort.Strings(candidates)
+	return withPrefix(dedupe(candidates), current)
+}
+
+// completeSubcommand returns the plugin names for the subcommands taking them
+func completeSubcommand(words []string, current string) []string {
+	if !pluginSubcommands[words[0]] || strings.HasPrefix(current, "-") {
+		return nil
+	}
+	var configPath configFiles
+	for i, word := range words {
+		if word == "-config" || word == "--config" {
+			if i+1 == len(words) {
+				return nil
+			}
+			configPath.Set(words[i+1])
+		} else if value, ok := strings.CutPrefix(strings.TrimLeft(word, "-"), "config="); ok && strings.HasPrefix(word, "-") {
+			configPath.Set(value)
+		}
+	}
+	config, err := shared.LoadConfig(configPath.base(), configPath.overlays()...)
+	if err != nil {
+		return nil
+	}
+	return withPrefix(sortedPluginNames(config), current)
+}
+
+// paramCandidates returns name= for the parameters not given yet, or name=value for the allowed
+// values once the current word has a name
+func paramCandidates(info *shared.PluginInfo, given map[string]string, current string) []string {
+	var candidates []string
+	name, _, hasValue := strings.Cut(current, "=")
+	if !hasValue {
+		for param := range info.ParameterSchema {
+			if _, ok := given[param]; !ok {
+				candidates = append(candidates, param+"=")
+			}
+		}
+		return candidates
+	}
+	spec, ok := info.ParameterSchema[name]
+	if !ok || spec.IsSecret() {
+		return nil
+	}
+	values := spec.AllowedValues
+	if len(values) == 0 && spec.Type == "bool" {
+		values = []string{"true", "false"}
+	}
+	for _, value := range values {
+		candidates = append(candidates, name+"="+value)
+	}
+	return candidates
+}
+
+// completionInfo returns a plugin's info from the cache, or else from the daemon or by starting
+// the plugin, caching what it reports; nil if it can't be had
+func completionInfo(config *shared.AppConfig, name string, pluginConfig shared.PluginConfig) *shared.PluginInfo {
+	cache := shared.NewInfoCache(config.StateDir)
+	if info := cache.Get(name, pluginConfig); info != nil {
+		return info
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionInfoTimeout)
+	defer cancel()
+	var info *shared.PluginInfo
+	if plugin, err := shared.ConnectDaemon(ctx, config, name); err == nil {
+		info, err = plugin.GetInfo(ctx)
+		plugin.Close()
+		if err != nil {
+			info = nil
+		}
+	}
+	if info == nil && !config.ReadOnly {
+		manager := shared.NewPluginManager(config)
+		defer manager.StopAll()
+		manager.SetProcessOutput(io.Discard, io.Discard)
+		var err error
+		if info, err = queryPluginInfo(manager, name, pluginConfig); err != nil {
+			return nil
+		}
+	}
+	if info != nil {
+		cache.Put(name, info)
+	}
+	return info
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// withPrefix returns the candidates starting with prefix
+func withPrefix(candidates []string, prefix string) []string {
+	var matching []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			matching = append(matching, candidate)
+		}
+	}
+	return matching
+}
+
+// dedupe removes repeated candidates from a sorted list
+func dedupe(sorted []string) []string {
+	var unique []string
+	for i, candidate := range sorted {
+		if i == 0 || candidate != sorted[i-1] {
+			unique = append(unique, candidate)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+var completionSchema = &shared.PluginInfo{
+	Name: "hello",
+	ParameterSchema: map[string]shared.ParameterSpec{
+		"message":  {Description: "Who to greet"},
+		"language": {Description: "Greeting language", AllowedValues: []string{"en", "es", "fr"}},
+		"loud":     {Description: "Shout", Type: "bool"},
+		"token":    {Description: "API token", Type: shared.ParamTypeSecret, AllowedValues: []string{"never-shown"}},
+	},
+}
+
+func TestParamCandidates(t *testing.T) {
+	tests := []struct {
+		name    string
+		given   map[string]string
+		current string
+		want    []string
+	}{
+		{
+			name: "Parameter names",
+			want: []string{"language=", "loud=", "message=", "token="},
+		},
+		{
+			name:  "Given parameters are left out",
+			given: map[string]string{"message": "World", "token": "x"},
+			want:  []string{"language=", "loud="},
+		},
+		{
+			name:    "Allowed values",
+			current: "language=",
+			want:    []string{"language=en", "language=es", "language=fr"},
+		},
+		{
+			name:    "Bool values",
+			current: "loud=t",
+			want:    []string{"loud=true", "loud=false"},
+		},
+		{
+			name:    "Free-form value",
+			current: "message=",
+		},
+		{
+			name:    "Secret values are never offered",
+			current: "token=",
+		},
+		{
+			name:    "Unknown parameter",
+			current: "volume=",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := paramCandidates(completionSchema, tt.given, tt.current)
+			sort.Strings(got)
+			want := append([]string(nil), tt.want...)
+			sort.Strings(want)
+			if len(got) != 0 || len(want) != 0 {
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("paramCandidates() = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCompleteRun(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	config := fmt.Sprintf(`{
+  "state_dir": %q,
+  "plugins": {
+    "hello": {"type": "remote", "address": "127.0.0.1:1"},
+    "addition": {"type": "remote", "address": "127.0.0.1:2"}
+  }
+}`, filepath.Join(dir, "state"))
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Completion reads the cached info instead of reaching the plugins
+	cache := shared.NewInfoCache(filepath.Join(dir, "state"))
+	if err := cache.Put("hello", completionSchema); err != nil {
+		t.Fatal(err)
+	}
+	additionSchema := &shared.PluginInfo{Name: "addition", ParameterSchema: map[string]shared.ParameterSpec{"num1": {}, "num2": {}}}
+	if err := cache.Put("addition", additionSchema); err != nil {
+		t.Fatal(err)
+	}
+
+	runFlags := flag.NewFlagSet("run", flag.ContinueOnError)
+	runFlags.String("config", "config.json", "")
+	runFlags.Bool("parallel", false, "")
+	runFlags.Bool("info", false, "")
+	runFlags.Duration("timeout", 0, "")
+
+	tests := []struct {
+		name    string
+		words   []string
+		current string
+		want    []string
+	}{
+		{
+			name:    "Plugin names",
+			words:   []string{"-config", configPath},
+			current: "",
+			want:    []string{"addition", "hello"},
+		},
+		{
+			name:    "Plugin name prefix",
+			words:   []string{"-config=" + configPath},
+			current: "he",
+			want:    []string{"hello"},
+		},
+		{
+			name:    "After explicit run",
+			words:   []string{"run", "-config", configPath},
+			current: "a",
+			want:    []string{"addition"},
+		},
+		{
+			name:    "Parameters of the plugin",
+			words:   []string{"-config", configPath, "hello", "message=hi"},
+			current: "l",
+			want:    []string{"language=", "loud="},
+		},
+		{
+			name:    "Allowed values",
+			words:   []string{"-config", configPath, "hello"},
+			current: "language=e",
+			want:    []string{"language=en", "language=es"},
+		},
+		{
+			name:    "Parallel offers plugins and the parameters of each",
+			words:   []string{"-config", configPath, "-parallel", "hello", "addition"},
+			current: "",
+			want:    []string{"addition", "hello", "language=", "loud=", "message=", "num1=", "num2=", "token="},
+		},
+		{
+			name:    "Flags",
+			words:   []string{},
+			current: "-ti",
+			want:    []string{"-timeout"},
+		},
+		{
+			name:    "Flag value is left to the shell",
+			words:   []string{"-config"},
+			current: "",
+		},
+		{
+			name:    "Subcommand taking plugin names",
+			words:   []string{"logs", "-config", configPath},
+			current: "ad",
+			want:    []string{"addition"},
+		},
+		{
+			name:    "Subcommand without plugin names",
+			words:   []string{"validate", "-config", configPath},
+			current: "",
+		},
+		{
+			name:    "Unknown plugin",
+			words:   []string{"-config", configPath, "missing"},
+			current: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := completeRun(runFlags, tt.words, tt.current)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("completeRun(%q, %q) = %v, want %v", tt.words, tt.current, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// runConfig implements the config command and dispatches its subcommands
+func runConfig(args []string) {
+	if len(args) == 0 {
+		fmt.Println(msg("config.usage"))
+		os.Exit(1)
+	}
+	switch args[0] {
+	case "lint":
+		runConfigLint(args[1:])
+	case "show":
+		runConfigShow(args[1:])
+	case "migrate":
+		runConfigMigrate(args[1:])
+	default:
+		fmt.Println(msg("config.usage"))
+		os.Exit(1)
+	}
+}
+
+// profileValue is -profile, which selects the profile through $PLUGIN_APP_PROFILE so that every
+// load of the configuration applies it, reloads included
+type profileValue struct{}
+
+func (profileValue) String() string {
+	return os.Getenv(shared.ProfileEnvVar)
+}
+
+func (profileValue) Set(name string) error {
+	return os.Setenv(shared.ProfileEnvVar, name)
+}
+
+// profileFlag adds -profile to a command's flags
+func profileFlag(fs *flag.FlagSet) {
+	fs.Var(profileValue{}, "profile", "Configuration profile to apply, such as staging (default $"+shared.ProfileEnvVar+")")
+}
+
+// configFiles collects repeated -config flags: a base configuration and the files overlaid on
+// it, such as environment-specific and user-local ones, each taking precedence over those before
+type configFiles []string
+
+func (c *configFiles) String() string {
+	return strings.Join(c.paths(), ",")
+}
+
+func (c *configFiles) Set(value string) error {
+	*c = append(*c, value)
+	return nil
+}
+
+// paths returns the files in order of precedence, config.json if none was given
+func (c configFiles) paths() []string {
+	if len(c) == 0 {
+		return []string{"config.json"}
+	}
+	return c
+}
+
+// base returns the file the others are overlaid on
+func (c configFiles) base() string {
+	return c.paths()[0]
+}
+
+// overlays returns the files overlaid on the base, lowest precedence first
+func (c configFiles) overlays() []string {
+	return c.paths()[1:]
+}
+
+// configFlag adds -config to a command's flags
+func configFlag(fs *flag.FlagSet) *configFiles {
+	files := new(configFiles)
+	fs.Var(files, "config", "Path to configuration file, repeatable to overlay files on it (default config.json)")
+	return files
+}
+
+// runConfigShow prints the effective configuration: with overlays, includes, discovered plugins
+// and the selected profile applied, and credentials masked. With -effective=false it prints the
+// configuration files merged as written instead.
+func runConfigShow(args []string) {
+	fs := flag.NewFlagSet("config show", flag.ExitOnError)
+	configPath := configFlag(fs)
+	profileFlag(fs)
+	effective := fs.Bool("effective", true, "Show the configuration as applied rather than the files merged as written")
+	outputFlag(fs)
+	logFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fmt.Println(msg("config.usage"))
+		os.Exit(1)
+	}
+
+	var config *shared.AppConfig
+	if *effective {
+		config = loadConfig(*configPath)
+	} else {
+		var err error
+		if config, err = shared.LoadRawConfig(configPath.base(), configPath.overlays()...); err != nil {
+			fatal(msg("config.load_failed", err))
+		}
+	}
+	masked, err := config.Masked()
+	if err != nil {
+		fatal(msg("error", err))
+	}
+	if profile := config.Profile(); profile != "" {
+		log.Print(msg("config.profile", profile))
+	}
+	// The configuration is JSON, so that is what text output shows too
+	if !output.structured() {
+		output.format = outputJSON
+	}
+	output.document(masked)
+}
+
+// runConfigMigrate converts a configuration in the legacy format, which lists plugins with a
+// name field, to the current one: printed, or written over the file with -write
+func runConfigMigrate(args []string) {
+	fs := flag.NewFlagSet("config migrate", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "Path to configuration file")
+	write := fs.Bool("write", false, "Replace the file with the converted configuration, keeping the legacy one as <file>.legacy")
+	logFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fmt.Println(msg("config.usage"))
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(*configPath)
+	if err != nil {
+		fatal(msg("config.load_failed", err))
+	}
+	if !shared.IsLegacyConfig(data) {
+		log.Print(msg("config.migrate_current", *configPath))
+		return
+	}
+	config, err := shared.MigrateLegacyConfig(data)
+	if err != nil {
+		fatal(msg("config.load_failed", err))
+	}
+
+	if !*write {
+		converted, err := json.MarshalIndent(config, "", "  ")
+		if err != nil {
+			fatal(msg("error", err))
+		}
+		fmt.Println(string(converted))
+		return
+	}
+	backup := *configPath + ".legacy"
+	if err := os.WriteFile(backup, data, 0644); err != nil {
+		fatal(msg("error", err))
+	}
+	if err := shared.SaveConfig(config, *configPath); err != nil {
+		fatal(msg("error", err))
+	}
+	log.Print(msg("config.migrated", *configPath, len(config.Plugins), backup))
+}
+
+// runConfigLint checks the configuration against best practices and exits 1 on errors
+func runConfigLint(args []string) {
+	fs := flag.NewFlagSet("config lint", flag.ExitOnError)
+	configPath := configFlag(fs)
+	profileFlag(fs)
+	minSeverity := fs.String("severity", string(shared.LintInfo), "Least severe findings to report (error/warning/info)")
+	logFlags(fs)
+	fs.Parse(args)
+
+	threshold, err := shared.ParseLintSeverity(*minSeverity)
+	if err != nil || fs.NArg() != 0 {
+		fmt.Println(msg("config.usage"))
+		os.Exit(1)
+	}
+
+	config := loadConfig(*configPath)
+	failures := 0
+	reported := 0
+	for _, finding := range config.Lint() {
+		if finding.Severity == shared.LintError {
+			failures++
+		}
+		if !finding.Severity.AtLeast(threshold) {
+			continue
+		}
+		reported++
+		fmt.Println(msg("config.lint_finding", finding.Severity, finding.Plugin, finding.Rule, finding.Message))
+	}
+
+	if reported == 0 {
+		fmt.Println(msg("config.lint_clean"))
+	}
+	if failures > 0 {
+		log.Fatal(msg("config.lint_failed", failures))
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// conformanceDocument is what test reports with structured output
+type conformanceDocument struct {
+	Plugin   string                     `json:"plugin"`
+	Version  string                     `json:"version"`
+	Checks   []shared.ConformanceResult `json:"checks"`
+	Failures int                        `json:"failures"`
+}
+
+// runConformance implements the test command, which checks a plugin against the protocol
+// contract. The plugin is a configured one or, to vet it before adding it, a plugin binary.
+func runConformance(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	configPath := configFlag(fs)
+	profileFlag(fs)
+	timeout := fs.Duration("timeout", 30*time.Second, "Deadline for each execution the checks make")
+	cancelAfter := fs.Duration("cancel-after", 200*time.Millisecond, "How long an execution runs before the cancellation check cancels it, unless it reports something first")
+	cancelGrace := fs.Duration("cancel-grace", 5*time.Second, "How long a canceled execution may take to end")
+	outputFlag(fs)
+	logFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fmt.Println(msg("test.usage"))
+		os.Exit(1)
+	}
+
+	config := loadConfig(*configPath)
+	name, pluginConfig, err := conformanceTarget(config, fs.Arg(0))
+	if err != nil {
+		fatal(msg("test.target_failed", fs.Arg(0), err))
+	}
+
+	manager := shared.NewPluginManager(config)
+	defer manager.StopAll()
+	manager.SetProcessOutput(io.Discard, io.Discard)
+
+	if err := manager.StartPlugin(name, pluginConfig); err != nil {
+		fatal(msg("run.start_failed", name, err))
+	}
+	plugin, err := manager.GetPlugin(name)
+	if err != nil {
+		manager.StopAll()
+		fatal(msg("run.get_plugin_failed", name, err))
+	}
+	credential, err := resolveCredential(ctx, "", name, pluginConfig)
+	if err != nil {
+		manager.StopAll()
+		fatal(msg("error", err))
+	}
+	ctx = shared.WithCredential(ctx, credential)
+
+	// Configured defaults count as valid values, as they do for run
+	params := parseParams(fs.Args()[1:])
+	if info, err := plugin.GetInfo(ctx); err == nil {
+		mergeDefaults(params, info, pluginConfig)
+	}
+	results := shared.CheckConformance(ctx, plugin, shared.ConformanceOptions{
+		Params:      params,
+		Timeout:     *timeout,
+		CancelAfter: *cancelAfter,
+		CancelGrace: *cancelGrace,
+	})
+	version := ""
+	if info, err := plugin.GetInfo(ctx); err == nil {
+		version = info.Version
+	}
+
+	failures := shared.ConformanceFailures(results)
+	reportConformance(name, version, results, failures)
+	if failures > 0 {
+		manager.StopAll()
+		os.Exit(1)
+	}
+}
+
+// conformanceTarget resolves the plugin to check: a configured plugin, run on a free port if it
+// is local so it doesn't collide with a daemon's, or a binary run with default settings
+func conformanceTarget(config *shared.AppConfig, spec string) (string, shared.PluginConfig, error) {
+	if pluginConfig, ok := config.Plugins[spec]; ok {
+		if !pluginConfig.IsRemote() {
+			pluginConfig.Port = 0
+		}
+		pluginConfig.Standby = false
+		pluginConfig.StandbyPort = 0
+		return spec, pluginConfig, pluginConfig.Validate()
+	}
+
+	path, err := filepath.Abs(spec)
+	if err != nil {
+		return "", shared.PluginConfig{}, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", shared.PluginConfig{}, fmt.Errorf("neither a configured plugin nor a plugin binary: %v", err)
+	}
+	pluginConfig := shared.PluginConfig{Type: shared.PluginTypeBinary, Path: path}
+	return filepath.Base(path), pluginConfig, pluginConfig.Validate()
+}
+
+// reportConformance shows the outcome of each check
+func reportConformance(name, version string, results []shared.ConformanceResult, failures int) {
+	if output.structured() {
+		output.document(conformanceDocument{Plugin: name, Version: version, Checks: results, Failures: failures})
+		return
+	}
+
+	fmt.Fprintln(output.w, msg("test.header", name, version))
+	w := tabwriter.NewWriter(output.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, msg("test.table_header"))
+	for _, result := range results {
+		fmt.Fprintln(w, msg("test.row", result.Check, result.Status, result.Detail))
+	}
+	w.Flush()
+	if failures > 0 {
+		fmt.Fprintln(output.w, msg("test.failed", failures, len(results)))
+	} else {
+		fmt.Fprintln(output.w, msg("test.passed"))
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// runDaemon implements the daemon command, which keeps plugins started for later runs to reuse.
+// Without a subcommand the daemon runs in the foreground.
+func runDaemon(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "start":
+			runDaemonStart(args[1:])
+			return
+		case "stop":
+			runDaemonStop(args[1:])
+			return
+		case "status":
+			runDaemonStatus(args[1:])
+			return
+		}
+	}
+
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	configPath, socket := daemonFlags(fs)
+	admin := adminFlag(fs)
+	logFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fmt.Println(msg("daemon.usage"))
+		os.Exit(1)
+	}
+
+	config := loadDaemonConfig(*configPath, *socket)
+	if *admin != "" {
+		config.DaemonAdmin = *admin
+	}
+
+	manager := shared.NewPluginManager(config)
+	// Plugin output goes to the plugins' log files only, for the logs command to show
+	manager.SetProcessOutput(io.Discard, io.Discard)
+	manager.SetReloadFailedHandler(func(err *shared.ReloadError) {
+		log.Print(msg("daemon.reload_failed", err))
+	})
+	killOrphans(manager)
+
+	// Crashes, failed health checks, recoveries and plugins given up on; a crash's error is its *shared.ProcessExit
+	events := manager.Subscribe(0, shared.EventPluginUnhealthy, shared.EventPluginRestarted, shared.EventPluginCircuitOpen)
+	defer events.Close()
+	go func() {
+		for event := range events.Events() {
+			switch event.Type {
+			case shared.EventPluginUnhealthy:
+				log.Print(msg("daemon.plugin_unhealthy", event.Plugin, event.Err))
+			case shared.EventPluginCircuitOpen:
+				log.Print(msg("daemon.plugin_circuit_open", event.Plugin, event.Err))
+			default:
+				log.Print(msg("daemon.plugin_recovered", event.Plugin))
+			}
+		}
+	}()
+
+	daemon := shared.NewDaemon(manager)
+	if err := daemon.Listen(config.DaemonSocketPath()); err != nil {
+		log.Fatal(msg("daemon.failed", err))
+	}
+	if err := daemon.WritePidfile(config.DaemonPidPath()); err != nil {
+		daemon.Stop()
+		log.Fatal(msg("daemon.failed", err))
+	}
+	// Probes are answered while the plugins start, with /readyz failing until they did
+	if config.DaemonAdmin != "" {
+		if err := daemon.ListenAdmin(config.DaemonAdmin); err != nil {
+			daemon.Stop()
+			log.Fatal(msg("daemon.failed", err))
+		}
+		log.Print(msg("daemon.admin_listening", daemon.AdminAddr()))
+	}
+
+	failed := daemon.StartPlugins()
+	for _, name := range sortedPluginNames(config) {
+		if err, ok := failed[name]; ok {
+			log.Print(msg("daemon.plugin_failed", name, err))
+		} else if _, err := manager.GetPlugin(name); err != nil {
+			log.Print(msg("daemon.plugin_lazy", name))
+		} else {
+			log.Print(msg("daemon.plugin_started", name, config.Plugins[name].Type))
+		}
+	}
+
+	// Configuration changes apply without a restart, whether the file is edited or SIGHUP is sent
+	watcher := manager.NewConfigWatcher(configPath.paths(), func(diff shared.ConfigDiff) {
+		log.Print(msg("daemon.reloaded", diff))
+		// Added plugins are started right away unless lazy, as at startup
+		for _, name := range diff.Added {
+			plugin := manager.Config().Plugins[name]
+			if plugin.IsLazy() {
+				log.Print(msg("daemon.plugin_lazy", name))
+				continue
+			}
+			if err := manager.StartPlugin(name, plugin); err != nil {
+				log.Print(msg("daemon.plugin_failed", name, err))
+			} else {
+				log.Print(msg("daemon.plugin_started", name, plugin.Type))
+			}
+		}
+	})
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	go func() {
+		if err := watcher.Run(watchCtx); err != nil {
+			log.Print(msg("daemon.watch_failed", err))
+		}
+	}()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	stopped := make(chan struct{})
+	go func() {
+		for sig := range sigChan {
+			if sig == syscall.SIGHUP {
+				watcher.Reload()
+				continue
+			}
+			log.Print(msg("daemon.stopping"))
+			stopWatching()
+			daemon.Stop()
+			close(stopped)
+			return
+		}
+	}()
+
+	log.Print(msg("daemon.listening", config.DaemonSocketPath()))
+	log.Print(msg("daemon.plugin_logs", config.LogPath()))
+	if err := daemon.Serve(); err != nil {
+		manager.StopAll()
+		log.Fatal(msg("daemon.failed", err))
+	}
+	// Serve returns as soon as stopping begins; wait for the plugins and the socket to be gone
+	<-stopped
+}
+
+// daemonFlags defines the flags locating the daemon, shared by the daemon command and its subcommands
+func daemonFlags(fs *flag.FlagSet) (configPath *configFiles, socket *string) {
+	configPath = configFlag(fs)
+	profileFlag(fs)
+	socket = fs.String("socket", "", "Unix socket of the daemon (default daemon_socket or <state_dir>/daemon.sock)")
+	return configPath, socket
+}
+
+// adminFlag defines -admin, the address of the daemon's admin port
+func adminFlag(fs *flag.FlagSet) *string {
+	return fs.String("admin", "", "Serve /healthz and /readyz over HTTP on this address, such as 127.0.0.1:9090 (default daemon_admin)")
+}
+
+// loadDaemonConfig loads the configuration, with -socket overriding daemon_socket
+func loadDaemonConfig(configPath configFiles, socket string) *shared.AppConfig {
+	config := loadConfig(configPath)
+	if socket != "" {
+		config.DaemonSocket = socket
+	}
+	return config
+}
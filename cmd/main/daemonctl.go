@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/client"
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// runDaemonStart implements daemon start, which runs the daemon in the background and returns
+// once it serves its plugins
+func runDaemonStart(args []string) {
+	fs := flag.NewFlagSet("daemon start", flag.ExitOnError)
+	configPath, socket := daemonFlags(fs)
+	logPath := fs.String("log", "", "File the daemon logs to (default <state_dir>/daemon.log)")
+	admin := adminFlag(fs)
+	timeout := fs.Duration("timeout", 30*time.Second, "How long to wait for the daemon to start its plugins")
+	logFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fmt.Println(msg("daemon.usage"))
+		os.Exit(1)
+	}
+
+	config := loadDaemonConfig(*configPath, *socket)
+	if pid, err := shared.DaemonPID(config); err == nil {
+		log.Fatal(msg("daemon.already_running", pid, config.DaemonSocketPath()))
+	}
+	if *logPath == "" {
+		*logPath = filepath.Join(config.StateDir, "daemon.log")
+	}
+	if err := os.MkdirAll(filepath.Dir(*logPath), 0700); err != nil {
+		log.Fatal(msg("error", err))
+	}
+	logFile, err := os.OpenFile(*logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		log.Fatal(msg("error", err))
+	}
+	defer logFile.Close()
+
+	// The daemon runs this binary in the foreground, in a session of its own so that it outlives
+	// the terminal; paths are made absolute as it keeps the working directory but not the shell
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatal(msg("error", err))
+	}
+	daemonArgs := []string{"daemon", "-log-format", logSettings.format, "-log-level", logSettings.level}
+	for _, path := range configPath.paths() {
+		if !shared.IsRemoteConfig(path) {
+			if path, err = filepath.Abs(path); err != nil {
+				log.Fatal(msg("error", err))
+			}
+		}
+		daemonArgs = append(daemonArgs, "-config", path)
+	}
+	if *socket != "" {
+		absSocket, err := filepath.Abs(*socket)
+		if err != nil {
+			log.Fatal(msg("error", err))
+		}
+		daemonArgs = append(daemonArgs, "-socket", absSocket)
+	}
+	if *admin != "" {
+		daemonArgs = append(daemonArgs, "-admin", *admin)
+	}
+	cmd := exec.Command(executable, daemonArgs...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		log.Fatal(msg("daemon.start_failed", err))
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	plugins, err := waitForDaemon(config, exited, *timeout)
+	if err != nil {
+		log.Fatal(msg("daemon.start_failed", fmt.Errorf("%v; see %s", err, *logPath)))
+	}
+	fmt.Println(msg("daemon.started", cmd.Process.Pid, config.DaemonSocketPath(), len(plugins), *logPath))
+}
+
+// waitForDaemon waits until the daemon answers on its socket, which it does once it started its
+// plugins, and returns them
+func waitForDaemon(config *shared.AppConfig, exited <-chan error, timeout time.Duration) ([]client.PluginStatus, error) {
+	c, err := client.New(client.Options{Socket: config.DaemonSocketPath(), MaxRetries: -1})
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	deadline := time.After(timeout)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		plugins, err := c.List(ctx)
+		cancel()
+		if err == nil {
+			return plugins, nil
+		}
+		select {
+		case err := <-exited:
+			if err == nil {
+				err = errors.New("exit status 0")
+			}
+			return nil, fmt.Errorf("daemon exited during startup: %v", err)
+		case <-deadline:
+			return nil, fmt.Errorf("daemon did not answer within %v", timeout)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// runDaemonStop implements daemon stop
+func runDaemonStop(args []string) {
+	fs := flag.NewFlagSet("daemon stop", flag.ExitOnError)
+	configPath, socket := daemonFlags(fs)
+	timeout := fs.Duration("timeout", time.Minute, "How long to wait for the daemon to stop its plugins and exit")
+	logFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fmt.Println(msg("daemon.usage"))
+		os.Exit(1)
+	}
+
+	config := loadDaemonConfig(*configPath, *socket)
+	pid, err := shared.StopDaemon(config, *timeout)
+	if errors.Is(err, shared.ErrNoDaemon) {
+		fmt.Println(msg("daemon.not_running", config.DaemonSocketPath()))
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal(msg("daemon.stop_failed", err))
+	}
+	fmt.Println(msg("daemon.stopped", pid))
+}
+
+// runDaemonStatus implements daemon status, which reports the daemon and the plugins it manages
+func runDaemonStatus(args []string) {
+	fs := flag.NewFlagSet("daemon status", flag.ExitOnError)
+	configPath, socket := daemonFlags(fs)
+	timeout := fs.Duration("timeout", 5*time.Second, "Deadline for querying the daemon")
+	logFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fmt.Println(msg("daemon.usage"))
+		os.Exit(1)
+	}
+
+	config := loadDaemonConfig(*configPath, *socket)
+	pid, err := shared.DaemonPID(config)
+	if errors.Is(err, shared.ErrNoDaemon) {
+		fmt.Println(msg("daemon.not_running", config.DaemonSocketPath()))
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal(msg("error", err))
+	}
+	fmt.Println(msg("daemon.running", pid, config.DaemonSocketPath()))
+
+	c, err := client.New(client.Options{Socket: config.DaemonSocketPath(), MaxRetries: -1})
+	if err != nil {
+		log.Fatal(msg("error", err))
+	}
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	plugins, err := c.List(ctx)
+	if err != nil {
+		log.Fatal(msg("daemon.status_failed", err))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, msg("daemon.status_header"))
+	for _, plugin := range plugins {
+		since := "-"
+		if !plugin.Since.IsZero() {
+			since = time.Since(plugin.Since).Round(time.Second).String()
+		}
+		detail := plugin.LastError
+		if detail == "" {
+			detail = "-"
+		}
+		fmt.Fprintln(w, msg("daemon.status_row", plugin.Name, plugin.Type, plugin.State, since, plugin.Restarts, plugin.Queued, detail))
+	}
+	w.Flush()
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// doctor prints the outcome of environment checks, each problem with a way to fix it
+type doctor struct {
+	problems int
+	warnings int
+}
+
+func (d *doctor) section(id string, args ...interface{}) {
+	fmt.Println(msg(id, args...))
+}
+
+func (d *doctor) ok(id string, args ...interface{}) {
+	fmt.Println(msg("doctor.ok", msg(id, args...)))
+}
+
+func (d *doctor) warn(problem, fix string) {
+	d.warnings++
+	fmt.Println(msg("doctor.warn", problem))
+	fmt.Println(msg("doctor.fix", fix))
+}
+
+func (d *doctor) fail(problem, fix string) {
+	d.problems++
+	fmt.Println(msg("doctor.fail", problem))
+	fmt.Println(msg("doctor.fix", fix))
+}
+
+// runDoctor implements the doctor command, which checks that plugins can be run here
+func runDoctor(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	configPath := configFlag(fs)
+	profileFlag(fs)
+	timeout := fs.Duration("timeout", 5*time.Second, "Deadline for each connection to a plugin")
+	start := fs.Bool("start", false, "Also start local plugins to check that they serve and speak a compatible protocol")
+	logFlags(fs)
+	fs.Parse(args)
+
+	d := &doctor{}
+	config := d.checkConfig(*configPath)
+	if config != nil {
+		d.checkStateDir(config)
+		d.checkSpawn()
+		daemonPID := d.checkDaemon(config)
+		d.checkPorts(config, daemonPID)
+		d.checkPlugins(config, *timeout, *start)
+	}
+
+	fmt.Println()
+	if d.problems > 0 {
+		fmt.Println(msg("doctor.problems", d.problems, d.warnings))
+		os.Exit(1)
+	}
+	fmt.Println(msg("doctor.healthy", d.warnings))
+}
+
+// checkConfig reports whether the config parses and is valid; nil if it can't be loaded at all
+func (d *doctor) checkConfig(files configFiles) *shared.AppConfig {
+	d.section("doctor.config_header", files.String())
+	config, problems, err := shared.CheckConfig(files.base(), files.overlays()...)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			d.fail(err.Error(), msg("doctor.fix_config_missing"))
+		} else {
+			d.fail(err.Error(), msg("doctor.fix_config_parse"))
+		}
+		return nil
+	}
+	if err := messages.Apply(config.Messages); err != nil {
+		problems = append(problems, shared.ConfigProblem{Err: fmt.Errorf("invalid messages configuration: %v", err)})
+	}
+	for _, problem := range problems {
+		switch {
+		case errors.Is(problem.Err, shared.ErrFileMissing):
+			d.fail(problem.Error(), msg("doctor.fix_missing_file"))
+		case errors.Is(problem.Err, shared.ErrNotExecutable):
+			d.fail(problem.Error(), msg("doctor.fix_not_executable"))
+		default:
+			d.fail(problem.Error(), msg("doctor.fix_config_problem", files.String()))
+		}
+	}
+	if len(problems) == 0 {
+		d.ok("doctor.config_ok", len(config.Plugins))
+	}
+	return config
+}
+
+// checkStateDir reports whether the host can keep its data in the state directory
+func (d *doctor) checkStateDir(config *shared.AppConfig) {
+	d.section("doctor.state_header", config.StateDir)
+	err := os.MkdirAll(config.StateDir, 0755)
+	if err == nil {
+		var file *os.File
+		if file, err = os.CreateTemp(config.StateDir, ".doctor-*"); err == nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}
+	if err != nil {
+		d.fail(err.Error(), msg("doctor.fix_state_dir"))
+		return
+	}
+	d.ok("doctor.state_ok")
+}
+
+// checkSpawn reports whether processes can be started, by starting this binary again
+func (d *doctor) checkSpawn() {
+	d.section("doctor.spawn_header")
+	self, err := os.Executable()
+	if err != nil {
+		d.fail(err.Error(), msg("doctor.fix_spawn"))
+		return
+	}
+	// The help output exits with an error, which doesn't matter; only starting it does
+	cmd := exec.Command(self, "-h")
+	if err := cmd.Start(); err != nil {
+		d.fail(err.Error(), msg("doctor.fix_spawn"))
+		return
+	}
+	cmd.Wait()
+	d.ok("doctor.spawn_ok")
+}
+
+// checkDaemon reports whether a daemon is running and returns its process ID, or 0
+func (d *doctor) checkDaemon(config *shared.AppConfig) int {
+	d.section("doctor.daemon_header")
+	pid, err := shared.DaemonPID(config)
+	if err != nil {
+		if errors.Is(err, shared.ErrNoDaemon) {
+			d.ok("doctor.daemon_not_running")
+		} else {
+			d.warn(err.Error(), msg("doctor.fix_daemon"))
+		}
+		return 0
+	}
+	d.ok("doctor.daemon_running", pid)
+	return pid
+}
+
+// checkPorts reports whether the ports of local plugins are free. With a daemon running, they are
+// expected to be taken by its plugins.
+func (d *doctor) checkPorts(config *shared.AppConfig, daemonPID int) {
+	d.section("doctor.ports_header")
+	checked := 0
+	for _, name := range sortedPluginNames(config) {
+		plugin := config.Plugins[name]
+		if plugin.IsRemote() {
+			continue
+		}
+		for _, port := range []int{plugin.Port, plugin.StandbyPort} {
+			if port <= 0 {
+				continue
+			}
+			checked++
+			listener, err := net.Listen("tcp", net.JoinHostPort("localhost", strconv.Itoa(port)))
+			if err == nil {
+				listener.Close()
+				d.ok("doctor.port_free", name, port)
+				continue
+			}
+			if daemonPID != 0 {
+				d.ok("doctor.port_daemon", name, port, daemonPID)
+				continue
+			}
+			d.fail(msg("doctor.port_busy", name, port, err), msg("doctor.fix_port", port))
+		}
+	}
+	if checked == 0 {
+		d.ok("doctor.no_ports")
+	}
+}
+
+// checkPlugins connects to remote plugins, and local ones if start is set, and reports whether
+// they serve and speak a protocol this host supports
+func (d *doctor) checkPlugins(config *shared.AppConfig, timeout time.Duration, start bool) {
+	d.section("doctor.plugins_header")
+	host := shared.CurrentHost()
+	checked := 0
+	for _, name := range sortedPluginNames(config) {
+		plugin := config.Plugins[name]
+		if !plugin.IsRemote() && !start {
+			continue
+		}
+		checked++
+		// Local plugins the daemon runs are checked through it; remote ones are dialed from here
+		report := probePlugin(config, name, plugin, timeout, !plugin.IsRemote())
+		if report.err != nil {
+			fix := msg("doctor.fix_unreachable_local")
+			if plugin.IsRemote() {
+				fix = msg("doctor.fix_unreachable_remote", plugin.Address)
+			}
+			d.fail(msg("doctor.plugin_unreachable", name, report.err), fix)
+			continue
+		}
+		if !report.probe.Serving() {
+			d.fail(msg("doctor.plugin_not_serving", name, report.probe.Status), msg("doctor.fix_not_serving", name))
+			continue
+		}
+		if report.info == nil {
+			d.warn(msg("doctor.plugin_no_info", name), msg("doctor.fix_no_info"))
+			continue
+		}
+		if problems := host.Check(report.info); len(problems) > 0 {
+			for _, problem := range problems {
+				d.fail(msg("doctor.plugin_incompatible", name, report.version, problem), msg("doctor.fix_incompatible", host.MinProtocol, host.MaxProtocol))
+			}
+			continue
+		}
+		d.ok("doctor.plugin_ok", name, report.version, shared.PluginProtocol(report.info), report.probe.Latency.Round(time.Microsecond))
+	}
+	if checked == 0 {
+		d.ok("doctor.no_plugins")
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// runEncrypt implements the encrypt command, which turns a secret into an enc: config value
+func runEncrypt(args []string) {
+	fs := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	generateKey := fs.Bool("generate-key", false, "Create a new config key at "+shared.DefaultKeyFile())
+	logFlags(fs)
+	fs.Parse(args)
+
+	if *generateKey {
+		path := shared.DefaultKeyFile()
+		if err := shared.GenerateConfigKey(path); err != nil {
+			log.Fatal(msg("error", err))
+		}
+		fmt.Println(msg("encrypt.key_written", path))
+		return
+	}
+
+	if fs.NArg() > 1 {
+		fmt.Println(msg("encrypt.usage"))
+		os.Exit(1)
+	}
+
+	value := fs.Arg(0)
+	if fs.NArg() == 0 {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			log.Fatal(msg("encrypt.read_failed", err))
+		}
+		value = strings.TrimRight(line, "\r\n")
+	}
+
+	key, err := shared.LoadConfigKey()
+	if err != nil {
+		log.Fatal(msg("error", err))
+	}
+	encrypted, err := shared.EncryptValue(key, value)
+	if err != nil {
+		log.Fatal(msg("error", err))
+	}
+	fmt.Println(encrypted)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// runExec implements the exec command, which runs a plugin binary or a plugin listening on an
+// address once, without a configuration. Flags may follow the target, and "--" ends them.
+func runExec(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("exec", flag.ExitOnError)
+	port := fs.Int("port", 0, "Port to start a plugin binary on")
+	portAuto := fs.Bool("port-auto", false, "Start a plugin binary on a free port (the default without -port)")
+	showInfo := fs.Bool("info", false, "Show the plugin's information instead of executing it")
+	timeout := fs.Duration("timeout", 0, "Fail the execution with TIMEOUT if it runs longer (e.g. 30s)")
+	token := fs.String("token", "", "Bearer token for a plugin on an address (default $"+shared.CredentialEnvVar+")")
+	logFlags(fs)
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Println(msg("exec.usage"))
+		os.Exit(1)
+	}
+	target := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+
+	if *portAuto && *port != 0 {
+		log.Fatal(msg("exec.port_flags"))
+	}
+	if *timeout < 0 {
+		log.Fatal(msg("run.invalid_timeout", *timeout))
+	}
+	name, pluginConfig, err := execTarget(target, *port)
+	if err != nil {
+		log.Fatal(msg("exec.invalid_target", target, err))
+	}
+
+	// An ad-hoc plugin leaves nothing behind, so it gets neither the state directory nor history
+	config := &shared.AppConfig{Plugins: map[string]shared.PluginConfig{name: pluginConfig}}
+	manager := shared.NewPluginManager(config)
+	defer manager.StopAll()
+	manager.SetProcessExitHandler(func(exit *shared.ProcessExit) {
+		log.Print(msg("run.plugin_exited", exit))
+	})
+	if err := manager.StartPlugin(name, pluginConfig); err != nil {
+		log.Fatal(msg("run.start_failed", name, err))
+	}
+	log.Print(msg("exec.started", name, pluginConfig.Type, execLocation(pluginConfig)))
+	plugin, err := manager.GetPlugin(name)
+	if err != nil {
+		manager.StopAll()
+		log.Fatal(msg("run.get_plugin_failed", name, err))
+	}
+	info, err := plugin.GetInfo(ctx)
+	if err != nil {
+		manager.StopAll()
+		log.Fatal(msg("run.info_failed", err))
+	}
+	if *showInfo {
+		displayPluginInfo(info, pluginConfig)
+		return
+	}
+
+	params := parseParams(fs.Args())
+	mergeDefaults(params, info, pluginConfig)
+	redactor, err := shared.NewRedactor(nil, info.ParameterSchema, params)
+	if err != nil {
+		manager.StopAll()
+		log.Fatal(msg("error", err))
+	}
+	credential := *token
+	if credential == "" && pluginConfig.IsRemote() {
+		credential = os.Getenv(shared.CredentialEnvVar)
+	}
+	redactor.Mask(credential)
+
+	executionID, err := shared.NewExecutionID()
+	if err != nil {
+		manager.StopAll()
+		log.Fatal(msg("error", err))
+	}
+	capture := shared.NewOutputCapture("", nil)
+	defer capture.Close()
+	handler := &outputHandler{pluginName: name, executionID: executionID, redactor: redactor, capture: capture}
+
+	startTime := time.Now().UnixNano()
+	measured := manager.MeasureUsage(name)
+	execErr := shared.ExecuteWithTimeout(shared.WithExecutionID(shared.WithCredential(ctx, credential), handler.executionID), plugin, name, *timeout, params, handler)
+	usage := measured()
+	execution := &finishedExecution{
+		name:     name,
+		config:   pluginConfig,
+		plugin:   plugin,
+		info:     info,
+		params:   params,
+		redactor: redactor,
+		handler:  handler,
+		start:    startTime,
+		end:      time.Now().UnixNano(),
+		err:      execErr,
+		metadata: outcomeMetadata(execErr, capture),
+		usage:    usage,
+	}
+	execution.report(config, false)
+
+	if execErr != nil {
+		if ctx.Err() == context.Canceled {
+			log.Print(msg("run.canceled", name))
+			return
+		}
+		manager.StopAll()
+		log.Fatal(msg("run.failed", name, redactor.String(execErr.Error())))
+	}
+	log.Println(msg("run.completed"))
+}
+
+// execTarget returns the name and configuration of an ad-hoc plugin: a binary if target is a
+// file, otherwise a remote plugin at the host:port address
+func execTarget(target string, port int) (string, shared.PluginConfig, error) {
+	pluginConfig := shared.PluginConfig{
+		Environment:      make(map[string]string),
+		Defaults:         make(map[string]string),
+		ResultValidation: shared.ResultValidationWarn,
+	}
+	if info, err := os.Stat(target); err == nil {
+		if info.IsDir() {
+			return "", pluginConfig, errors.New("is a directory")
+		}
+		path, err := filepath.Abs(target)
+		if err != nil {
+			return "", pluginConfig, err
+		}
+		pluginConfig.Type = shared.PluginTypeBinary
+		pluginConfig.Path = path
+		pluginConfig.WorkingDir = filepath.Dir(path)
+		pluginConfig.Port = port
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		return name, pluginConfig, pluginConfig.Validate()
+	}
+
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		return "", pluginConfig, errors.New("neither a plugin binary nor a host:port address")
+	}
+	if port != 0 {
+		return "", pluginConfig, errors.New("-port only applies to plugin binaries")
+	}
+	pluginConfig.Type = shared.PluginTypeRemote
+	pluginConfig.Address = target
+	return target, pluginConfig, pluginConfig.Validate()
+}
+
+// execLocation returns where an ad-hoc plugin is reached
+func execLocation(pluginConfig shared.PluginConfig) string {
+	if pluginConfig.IsRemote() {
+		return pluginConfig.Address
+	}
+	return pluginConfig.Path
+}
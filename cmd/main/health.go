@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// healthReport is the outcome of probing one plugin
+type healthReport struct {
+	name    string
+	config  shared.PluginConfig
+	probe   shared.ProbeResult
+	info    *shared.PluginInfo // nil if it couldn't be read
+	version string
+	err     error
+}
+
+// runHealth implements the health command
+func runHealth(args []string) {
+	fs := flag.NewFlagSet("health", flag.ExitOnError)
+	configPath := configFlag(fs)
+	profileFlag(fs)
+	all := fs.Bool("all", false, "Probe every configured plugin")
+	parallel := fs.Int("parallel", 4, "How many plugins to probe at once")
+	timeout := fs.Duration("timeout", 5*time.Second, "Deadline for each health check")
+	noDaemon := fs.Bool("no-daemon", false, "Start local plugins to probe them even if a daemon is running")
+	logFlags(fs)
+	fs.Parse(args)
+
+	config := loadConfig(*configPath)
+
+	names := fs.Args()
+	if *all {
+		names = sortedPluginNames(config)
+	}
+	if len(names) == 0 {
+		fmt.Println(msg("health.usage"))
+		os.Exit(1)
+	}
+	if *parallel < 1 {
+		*parallel = 1
+	}
+
+	reports := make([]healthReport, len(names))
+	sem := make(chan struct{}, *parallel)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		pluginConfig, err := config.GetPluginConfig(name)
+		if err != nil {
+			log.Fatal(msg("error", err))
+		}
+		wg.Add(1)
+		go func(i int, name string, pluginConfig shared.PluginConfig) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			reports[i] = probePlugin(config, name, pluginConfig, *timeout, !*noDaemon)
+		}(i, name, pluginConfig)
+	}
+	wg.Wait()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, msg("health.header"))
+	unhealthy := 0
+	for _, r := range reports {
+		if r.err != nil {
+			fmt.Fprintln(w, msg("health.unreachable", r.name, r.config.Type, r.err))
+			unhealthy++
+			continue
+		}
+		serving := msg("health.yes")
+		if !r.probe.Serving() {
+			serving = msg("health.no")
+			unhealthy++
+		}
+		detail := ""
+		if r.probe.Status != "SERVING" {
+			detail = r.probe.Status
+		}
+		fmt.Fprintln(w, msg("health.row", r.name, r.config.Type, serving, r.probe.Latency.Round(time.Microsecond), r.version, detail))
+	}
+	w.Flush()
+
+	if unhealthy > 0 {
+		fmt.Println(msg("health.unhealthy", unhealthy, len(reports)))
+		os.Exit(1)
+	}
+}
+
+// probePlugin checks the health of one plugin. With useDaemon set and a daemon running, the daemon
+// probes the plugin it keeps warm; otherwise the plugin is connected to, or started briefly if it
+// is local.
+func probePlugin(config *shared.AppConfig, name string, pluginConfig shared.PluginConfig, timeout time.Duration, useDaemon bool) healthReport {
+	report := healthReport{name: name, config: pluginConfig}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Starting a local plugin next to the daemon's would collide on its port
+	if useDaemon {
+		plugin, err := shared.ConnectDaemon(ctx, config, name)
+		if err == nil {
+			defer plugin.Close()
+			report.probe, report.err = shared.ProbeThroughDaemon(ctx, plugin)
+			if report.err == nil {
+				report.readInfo(ctx, plugin)
+			}
+			return report
+		}
+		if !errors.Is(err, shared.ErrNoDaemon) {
+			log.Print(msg("warning", err))
+		}
+	}
+
+	// Each probe gets its own manager so slow starts don't hold up the others
+	manager := shared.NewPluginManager(config)
+	defer manager.StopAll()
+	manager.SetProcessOutput(io.Discard, io.Discard)
+
+	if err := manager.StartPlugin(name, pluginConfig); err != nil {
+		report.err = err
+		return report
+	}
+
+	report.probe, report.err = manager.ProbePlugin(ctx, name)
+	if report.err != nil {
+		return report
+	}
+
+	if plugin, err := manager.GetPlugin(name); err == nil {
+		report.readInfo(ctx, plugin)
+	}
+	return report
+}
+
+// readInfo notes the version of the probed plugin, if it reports one
+func (r *healthReport) readInfo(ctx context.Context, plugin shared.PluginInterface) {
+	if info, err := plugin.GetInfo(ctx); err == nil {
+		r.info = info
+		r.version = info.Version
+	}
+}
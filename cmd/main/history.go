@@ -0,0 +1,148 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// runHistory implements the history command, which lists the recorded executions, newest first
+func runHistory(args []string) {
+	if len(args) > 0 && args[0] == "show" {
+		runHistoryShow(args[1:])
+		return
+	}
+
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	configPath := configFlag(fs)
+	profileFlag(fs)
+	since := fs.String("since", "", "Only list executions since a duration ago (e.g. 24h) or an RFC 3339 time")
+	until := fs.String("until", "", "Only list executions before a duration ago or an RFC 3339 time")
+	failed := fs.Bool("failed", false, "Only list failed executions")
+	limit := fs.Int("limit", 20, "List the latest n executions (0 for all)")
+	outputFlag(fs)
+	logFlags(fs)
+	fs.Parse(args)
+
+	query := shared.HistoryQuery{Plugins: fs.Args(), Failed: *failed, Limit: *limit}
+	if *limit < 0 {
+		fatal(msg("history.invalid_limit", *limit))
+	}
+	if *since != "" {
+		t, err := parseSince(*since)
+		if err != nil {
+			fatal(msg("history.invalid_time", "-since", *since))
+		}
+		query.Since = t
+	}
+	if *until != "" {
+		t, err := parseSince(*until)
+		if err != nil {
+			fatal(msg("history.invalid_time", "-until", *until))
+		}
+		query.Until = t
+	}
+
+	config := loadConfig(*configPath)
+	records, err := config.HistoryStore().Query(query)
+	if err != nil {
+		fatal(msg("error", err))
+	}
+	if output.structured() {
+		if records == nil {
+			records = []shared.HistoryRecord{}
+		}
+		output.document(records)
+		return
+	}
+	if len(records) == 0 {
+		fmt.Println(msg("stats.no_history"))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, msg("history.header"))
+	for _, record := range records {
+		result, failure := msg("history.succeeded"), "-"
+		if !record.Success {
+			result, failure = msg("history.failed"), record.Error
+		}
+		id := record.ID
+		if id == "" {
+			id = "-"
+		}
+		fmt.Fprintln(w, msg("history.row", id, record.Time.Local().Format(time.RFC3339), record.Plugin, record.Version,
+			result, statsDuration(time.Duration(record.Duration)), len(record.Artifacts), failure))
+	}
+	w.Flush()
+}
+
+// runHistoryShow implements history show, which shows everything recorded of one execution
+func runHistoryShow(args []string) {
+	fs := flag.NewFlagSet("history show", flag.ExitOnError)
+	configPath := configFlag(fs)
+	profileFlag(fs)
+	outputFlag(fs)
+	logFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Println(msg("history.show_usage"))
+		os.Exit(1)
+	}
+
+	config := loadConfig(*configPath)
+	record, err := config.HistoryStore().Get(fs.Arg(0))
+	if err != nil {
+		fatal(msg("error", err))
+	}
+	if output.structured() {
+		output.document(record)
+		return
+	}
+
+	result := msg("history.succeeded")
+	if !record.Success {
+		result = msg("history.failed")
+	}
+	fmt.Println(msg("history.show_header", record.ID, record.Plugin))
+	fmt.Println(msg("history.show_time", record.Time.Local().Format(time.RFC3339)))
+	fmt.Println(msg("history.show_version", record.Version))
+	fmt.Println(msg("history.show_result", result))
+	fmt.Println(msg("history.show_duration", statsDuration(time.Duration(record.Duration))))
+	if record.Error != "" {
+		fmt.Println(msg("history.show_error", record.Error))
+	}
+	if len(record.Params) > 0 {
+		fmt.Println(msg("history.show_params"))
+		for _, name := range sortedKeys(record.Params) {
+			fmt.Println(msg("history.show_entry", name, record.Params[name]))
+		}
+	}
+	if len(record.Metadata) > 0 {
+		fmt.Println(msg("history.show_metadata"))
+		for _, name := range sortedKeys(record.Metadata) {
+			fmt.Println(msg("history.show_entry", name, record.Metadata[name]))
+		}
+	}
+	if len(record.Metrics) > 0 {
+		fmt.Println(msg("history.show_metrics"))
+		for _, name := range sortedKeys(record.Metrics) {
+			fmt.Println(msg("history.show_metric", name, record.Metrics[name]))
+		}
+	}
+	if len(record.Artifacts) > 0 {
+		fmt.Println(msg("history.show_artifacts"))
+		for _, artifact := range record.Artifacts {
+			if artifact.Path != "" {
+				fmt.Println(msg("history.show_saved", artifact.Name, artifact.Size, artifact.Path))
+			} else {
+				fmt.Println(msg("history.show_artifact", artifact.Name, artifact.Size))
+			}
+		}
+	}
+}
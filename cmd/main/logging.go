@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// logSettings is what -log-format and -log-level asked for; logging changes as soon as either is
+// parsed, so a command's own flag errors are logged as asked
+var logSettings = struct{ format, level string }{shared.LogFormatText, shared.LogLevelInfo}
+
+// configureLogging makes the log on stderr use format and level
+func configureLogging(format, level string) error {
+	if err := shared.ConfigureLogging(os.Stderr, format, level); err != nil {
+		return err
+	}
+	logSettings.format, logSettings.level = format, level
+	return nil
+}
+
+// logFormatValue is -log-format
+type logFormatValue struct{}
+
+func (logFormatValue) String() string {
+	return logSettings.format
+}
+
+func (logFormatValue) Set(format string) error {
+	return configureLogging(format, logSettings.level)
+}
+
+// logLevelValue is -log-level
+type logLevelValue struct{}
+
+func (logLevelValue) String() string {
+	return logSettings.level
+}
+
+func (logLevelValue) Set(level string) error {
+	return configureLogging(logSettings.format, level)
+}
+
+// logFlags adds -log-format and -log-level to a command's flags
+func logFlags(fs *flag.FlagSet) {
+	fs.Var(logFormatValue{}, "log-format", "Format of the log on stderr: text or json (a record per line)")
+	fs.Var(logLevelValue{}, "log-level", "Least severe log record shown: debug, info, warn or error")
+}
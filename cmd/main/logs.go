@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// runLogs implements the logs command, which shows what the processes of plugins wrote to stdout
+// and stderr
+func runLogs(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("logs", flag.ExitOnError)
+	configPath := configFlag(fs)
+	profileFlag(fs)
+	follow := fs.Bool("follow", false, "Keep showing lines as they are written")
+	since := fs.String("since", "", "Only show lines since a duration ago (e.g. 10m) or an RFC 3339 time")
+	tail := fs.Int("tail", 0, "Only show the last n lines (0 for all)")
+	timestamps := fs.Bool("timestamps", false, "Show when each line was written")
+	logFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fmt.Println(msg("logs.usage"))
+		os.Exit(1)
+	}
+	if *tail < 0 {
+		log.Fatal(msg("logs.invalid_tail", *tail))
+	}
+	options := shared.LogOptions{Tail: *tail, Follow: *follow}
+	if *since != "" {
+		t, err := parseSince(*since)
+		if err != nil {
+			log.Fatal(msg("logs.invalid_since", *since))
+		}
+		options.Since = t
+	}
+
+	config := loadConfig(*configPath)
+	paths := make(map[string]string, fs.NArg())
+	for _, name := range fs.Args() {
+		path := config.PluginLogFile(name)
+		if _, err := config.GetPluginConfig(name); err != nil {
+			// Plugins removed from the configuration keep their logs
+			if _, statErr := os.Stat(path); statErr != nil {
+				log.Fatal(msg("error", err))
+			}
+		}
+		paths[name] = path
+	}
+
+	// The lines of several plugins are told apart by the plugin's name
+	labeled := len(paths) > 1
+	err := shared.TailPluginLogs(ctx, paths, options, func(name string, line shared.LogLine) {
+		out := os.Stdout
+		if line.Stream == "stderr" {
+			out = os.Stderr
+		}
+		text := line.Text
+		if labeled {
+			text = msg("logs.line_plugin", name, text)
+		}
+		if *timestamps {
+			fmt.Fprintln(out, msg("logs.line_timestamp", line.Time.Local().Format(time.RFC3339Nano), text))
+		} else {
+			fmt.Fprintln(out, text)
+		}
+	})
+	if err != nil {
+		log.Fatal(msg("error", err))
+	}
+}
+
+// parseSince parses a duration before now or a point in time
+func parseSince(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
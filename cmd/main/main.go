@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"sync"
 	"syscall"
@@ -43,91 +48,537 @@ func findAvailablePort(basePort int) int {
 
 // displayPluginInfo prints plugin information in a formatted way
 func displayPluginInfo(info *shared.PluginInfo, config shared.PluginConfig) {
-	fmt.Printf("Plugin Information:\n")
-	fmt.Printf("  Name: %s\n", info.Name)
-	fmt.Printf("  Version: %s\n", info.Version)
-	fmt.Printf("  Description: %s\n", info.Description)
-	fmt.Printf("  Type: %s\n", config.Type)
+	fmt.Println(msg("info.header"))
+	fmt.Println(msg("info.name", info.Name))
+	fmt.Println(msg("info.version", info.Version))
+	if config.ExpectedVersion != "" {
+		fmt.Println(msg("info.expected_version", config.ExpectedVersion))
+	}
+	fmt.Println(msg("info.description", info.Description))
+	fmt.Println(msg("info.type", config.Type))
 	if config.Type == shared.PluginTypeCommand {
-		fmt.Printf("  Command Template: %s\n", config.Command)
+		fmt.Println(msg("info.command", config.Command))
+	}
+	if config.IsRemote() {
+		if config.Address != "" {
+			fmt.Println(msg("info.address", config.Address))
+		} else {
+			fmt.Println(msg("info.addresses", strings.Join(config.Addresses, ", ")))
+		}
+		if config.LoadBalancing != "" {
+			fmt.Println(msg("info.load_balancing", config.LoadBalancing))
+		}
+		if config.AffinityKey != "" {
+			fmt.Println(msg("info.affinity_key", config.AffinityKey))
+		}
+		if config.Proxy != "" {
+			fmt.Println(msg("info.proxy", shared.RedactProxyURL(config.Proxy)))
+		}
+		if config.SSH != nil {
+			fmt.Println(msg("info.ssh_tunnel", config.SSH.Host))
+		}
+	} else {
+		fmt.Println(msg("info.workdir", config.WorkingDir))
+		if config.Standby {
+			fmt.Println(msg("info.standby_port", config.StandbyPort))
+		}
 	}
-	fmt.Printf("  Working Directory: %s\n", config.WorkingDir)
 	if len(config.Environment) > 0 {
-		fmt.Printf("  Environment Variables:\n")
+		fmt.Println(msg("info.env_header"))
 		for k, v := range config.Environment {
-			fmt.Printf("    %s: %s\n", k, v)
+			fmt.Println(msg("info.env_entry", k, v))
 		}
 	}
-	fmt.Printf("  Parameters:\n")
+	fmt.Println(msg("info.params_header"))
 	for name, spec := range info.ParameterSchema {
-		fmt.Printf("    %s:\n", name)
-		fmt.Printf("      Description: %s\n", spec.Description)
-		fmt.Printf("      Required: %v\n", spec.Required)
+		fmt.Println(msg("info.param", name))
+		fmt.Println(msg("info.param_description", spec.Description))
+		fmt.Println(msg("info.param_required", spec.Required))
+		if spec.IsSecret() {
+			fmt.Println(msg("info.param_secret", shared.SecretParamEnvVar(name)))
+		}
 		if spec.DefaultValue != "" {
-			fmt.Printf("      Default: %s\n", spec.DefaultValue)
+			fmt.Println(msg("info.param_default", displayParamValue(spec, spec.DefaultValue)))
 		}
 		if configDefault, ok := config.Defaults[name]; ok {
-			fmt.Printf("      Config Default: %s\n", configDefault)
+			fmt.Println(msg("info.param_config_default", displayParamValue(spec, configDefault)))
+		}
+		if len(spec.AllowedValues) > 0 && !spec.IsSecret() {
+			fmt.Println(msg("info.param_allowed", spec.AllowedValues))
 		}
-		if len(spec.AllowedValues) > 0 {
-			fmt.Printf("      Allowed Values: %v\n", spec.AllowedValues)
+	}
+	if len(info.ResultSchema) > 0 {
+		fmt.Println(msg("info.result_header", config.ResultValidation))
+		for name, spec := range info.ResultSchema {
+			fmt.Println(msg("info.result_field", name))
+			fmt.Println(msg("info.result_description", spec.Description))
+			fmt.Println(msg("info.result_type", spec.Type))
+			fmt.Println(msg("info.result_required", spec.Required))
+		}
+	}
+}
+
+// displayParamValue returns a parameter value as it may be shown to the user
+func displayParamValue(spec shared.ParameterSpec, value string) string {
+	if spec.IsSecret() {
+		return shared.SecretMask
+	}
+	return value
+}
+
+// killOrphans kills the plugin processes a crashed earlier run left holding their ports
+func killOrphans(manager *shared.PluginManager) {
+	orphans, err := manager.KillOrphans()
+	if err != nil {
+		log.Print(msg("warning", err))
+	}
+	for _, orphan := range orphans {
+		if orphan.Err != nil {
+			log.Print(msg("orphan.kill_failed", orphan.Plugin, orphan.PID, orphan.Err))
+		} else {
+			log.Print(msg("orphan.killed", orphan.Plugin, orphan.PID, orphan.Port))
 		}
 	}
 }
 
-// displayExecutionSummary prints the execution summary in a formatted way
-func displayExecutionSummary(summary *shared.ExecutionSummary) {
-	log.Printf("Plugin Summary: %s", summary.PluginName)
-	log.Printf("  Duration: %.2f ms", summary.Duration)
-	log.Printf("  Success: %v", summary.Success)
+// displayExecutionSummary prints the summary of the execution of an id in a formatted way
+func displayExecutionSummary(summary *shared.ExecutionSummary, redactor *shared.Redactor, executionID string) {
+	if output.structured() {
+		doc := &summaryDocument{
+			Plugin:     summary.PluginName,
+			DurationMS: summary.Duration,
+			Success:    summary.Success,
+			Metadata:   redactor.Params(summary.Metadata),
+			Metrics:    summary.Metrics,
+		}
+		if summary.Error != nil {
+			doc.Error = redactor.String(summary.Error.Error())
+		}
+		output.event(runEvent{Event: "summary", Plugin: summary.PluginName, Execution: executionID, Time: time.Now(), Summary: doc})
+		return
+	}
+	log.Print(msg("summary.header", summary.PluginName))
+	log.Print(msg("summary.duration", summary.Duration))
+	log.Print(msg("summary.success", summary.Success))
 	if summary.Error != nil {
-		log.Printf("  Error: %s", summary.Error.Error())
+		log.Print(msg("summary.error", redactor.String(summary.Error.Error())))
 	}
-	log.Printf("  Metadata:")
-	for k, v := range summary.Metadata {
-		log.Printf("    %s: %s", k, v)
+	log.Print(msg("summary.metadata"))
+	for k, v := range redactor.Params(summary.Metadata) {
+		log.Print(msg("summary.metadata_entry", k, v))
 	}
-	log.Printf("  Metrics:")
+	log.Print(msg("summary.metrics"))
 	for k, v := range summary.Metrics {
-		log.Printf("    %s: %.2f", k, v)
+		log.Print(msg("summary.metrics_entry", k, v))
+	}
+}
+
+// resolveCredential picks the per-request token for a remote plugin: -token, then the
+// environment, then the plugin's credential helper
+func resolveCredential(ctx context.Context, flagToken, name string, config shared.PluginConfig) (string, error) {
+	if !config.IsRemote() {
+		if flagToken != "" {
+			return "", errors.New(msg("run.token_local"))
+		}
+		return "", nil
+	}
+	if flagToken != "" {
+		return flagToken, nil
+	}
+	if token := os.Getenv(shared.CredentialEnvVar); token != "" {
+		return token, nil
+	}
+	return config.FetchCredential(ctx, name)
+}
+
+// displaySampleReport prints what was collected during a sample run
+func displaySampleReport(window time.Duration, handler *outputHandler) {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	log.Print(msg("sample.header", handler.pluginName))
+	log.Print(msg("sample.window", window))
+	log.Print(msg("sample.output", handler.outputCount))
+	log.Print(msg("sample.progress", handler.progressCount))
+	if handler.lastProgress != nil {
+		p := handler.lastProgress
+		log.Print(msg("sample.last_progress", p.PercentComplete, p.Stage, p.CurrentStep, p.TotalSteps))
 	}
 }
 
 // outputHandler implements shared.OutputHandler for the main application
 type outputHandler struct {
-	pluginName string
-	mutex      sync.Mutex
+	pluginName    string
+	executionID   string // what the execution goes by in logs, the history and artifact names
+	mutex         sync.Mutex
+	outputCount   int
+	progressCount int
+	lastProgress  *shared.Progress
+	resultWriter  io.Writer // receives structured results when stdout is piped
+	redactor      *shared.Redactor
+	capture       *shared.OutputCapture
+	monitor       *shared.MemoryMonitor
+	captureFailed bool
+	artifactSizes map[string]int
+	artifactPaths map[string]string  // where each saved artifact went
+	pluginMetrics map[string]float64 // latest value of each metric the plugin reported
+	lastResult    map[string]interface{}
+	lastError     string // code and message of the last error the plugin reported
+	verbosity     verbosity
+}
+
+// adoptExecutionID makes the execution go by the id the daemon gave it
+func (h *outputHandler) adoptExecutionID(id string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.executionID = id
 }
 
-func (h *outputHandler) OnOutput(msg string) error {
+// print logs a line of the execution for the user, with the id of the execution
+func (h *outputHandler) print(message string) {
+	if h.executionID == "" {
+		logFromCaller(slog.LevelInfo, message)
+		return
+	}
+	logFromCaller(slog.LevelInfo, message, slog.String("execution", h.executionID))
+}
+
+func (h *outputHandler) OnOutput(line string) error {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
-	log.Printf("[%s] %s", h.pluginName, msg)
+	h.outputCount++
+	line = h.redactor.String(line)
+	if h.verbosity == verbosityQuiet {
+		// Quiet runs still capture the output, for spilling and diagnostics
+	} else if output.structured() {
+		output.event(runEvent{Event: "output", Plugin: h.pluginName, Execution: h.executionID, Time: time.Now(), Line: line})
+	} else {
+		h.print(msg("output.line", h.pluginName, line))
+	}
+	if err := h.capture.Append(line); err != nil && !h.captureFailed {
+		h.captureFailed = true
+		log.Print(msg("warning", err))
+	}
 	return nil
 }
 
 func (h *outputHandler) OnProgress(p shared.Progress) error {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
-	log.Printf("[%s] Progress: %.1f%% (%s - Step %d/%d)",
-		h.pluginName, p.PercentComplete, p.Stage, p.CurrentStep, p.TotalSteps)
+	h.progressCount++
+	// Under memory pressure only stage changes and completion are reported
+	if h.verbosity == verbosityQuiet || h.monitor.UnderPressure() && h.lastProgress != nil && h.lastProgress.Stage == p.Stage && p.PercentComplete < 100 {
+		h.lastProgress = &p
+		return nil
+	}
+	h.lastProgress = &p
+	if output.structured() {
+		output.event(runEvent{Event: "progress", Plugin: h.pluginName, Execution: h.executionID, Time: time.Now(), Progress: &progressEvent{
+			Percent:      p.PercentComplete,
+			Stage:        p.Stage,
+			Step:         p.CurrentStep,
+			TotalSteps:   p.TotalSteps,
+			Rate:         p.Rate,
+			StageElapsed: shared.Duration(p.StageElapsed),
+			Remaining:    shared.Duration(p.Remaining),
+		}})
+		return nil
+	}
+	// Plugins tracking work units also report their rate and an estimate of the time left
+	if p.Rate > 0 {
+		h.print(msg("output.progress_rate", h.pluginName, p.PercentComplete, p.Stage, p.CurrentStep, p.TotalSteps,
+			p.StageElapsed.Round(time.Millisecond), p.Rate, p.Remaining.Round(time.Second)))
+		return nil
+	}
+	h.print(msg("output.progress", h.pluginName, p.PercentComplete, p.Stage, p.CurrentStep, p.TotalSteps))
+	return nil
+}
+
+func (h *outputHandler) OnResult(result map[string]interface{}) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.lastResult = result
+	if output.structured() {
+		redacted, _ := h.redactor.Value(result).(map[string]interface{})
+		output.event(runEvent{Event: "result", Plugin: h.pluginName, Execution: h.executionID, Time: time.Now(), Result: redacted})
+		return nil
+	}
+	data, err := json.Marshal(h.redactor.Value(result))
+	if err != nil {
+		return err
+	}
+	h.print(msg("output.result", h.pluginName, data))
+	// Piped results feed the next plugin and are passed on unredacted
+	if h.resultWriter != nil {
+		return json.NewEncoder(h.resultWriter).Encode(pipedResult{Plugin: h.pluginName, Result: result})
+	}
 	return nil
 }
 
 func (h *outputHandler) OnError(code, message, details string) error {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
+	message, details = h.redactor.String(message), h.redactor.String(details)
+	h.lastError = code + ": " + message
+	if output.structured() {
+		output.event(runEvent{Event: "plugin_error", Plugin: h.pluginName, Execution: h.executionID, Time: time.Now(), Code: code, Message: message, Details: details})
+		return nil
+	}
 	if details != "" {
-		log.Printf("[%s] Error %s: %s\nDetails: %s", h.pluginName, code, message, details)
+		h.print(msg("output.error_details", h.pluginName, code, message, details))
 	} else {
-		log.Printf("[%s] Error %s: %s", h.pluginName, code, message)
+		h.print(msg("output.error", h.pluginName, code, message))
 	}
 	return nil
 }
 
+func (h *outputHandler) OnLog(level, message string) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if !h.verbosity.showsLog(level) {
+		return nil
+	}
+	if output.structured() {
+		output.event(runEvent{Event: "log", Plugin: h.pluginName, Execution: h.executionID, Time: time.Now(), Level: strings.ToUpper(level), Message: h.redactor.String(message)})
+		return nil
+	}
+	h.print(msg("output.log", h.pluginName, strings.ToUpper(level), h.redactor.String(message)))
+	return nil
+}
+
+func (h *outputHandler) OnArtifact(name string, data []byte, last bool) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.artifactSizes == nil {
+		h.artifactSizes = make(map[string]int)
+	}
+	first := h.artifactSizes[name] == 0
+	h.artifactSizes[name] += len(data)
+	path, err := saveArtifact(h.pluginName, h.executionID, name, data, first)
+	if err != nil {
+		log.Print(msg("warning", err))
+	} else if path != "" {
+		if h.artifactPaths == nil {
+			h.artifactPaths = make(map[string]string)
+		}
+		h.artifactPaths[name] = path
+	}
+	if h.verbosity == verbosityQuiet {
+		return nil
+	}
+	if last && output.structured() {
+		output.event(runEvent{Event: "artifact", Plugin: h.pluginName, Execution: h.executionID, Time: time.Now(), Artifact: &artifactEvent{name, h.artifactSizes[name], path}})
+	} else if last && path != "" {
+		h.print(msg("output.artifact_saved", h.pluginName, name, h.artifactSizes[name], path))
+	} else if last {
+		h.print(msg("output.artifact", h.pluginName, name, h.artifactSizes[name]))
+	}
+	return nil
+}
+
+// artifactDir is where artifacts are saved, settings.output_dir of the config; "" to not save them
+var artifactDir string
+
+// saveArtifact appends a chunk of an artifact to its file below artifactDir, starting the file
+// over with the first chunk, and returns the file's path; "" if artifacts aren't saved. Files
+// are named after the execution, if it has an id, so that runs don't overwrite each other's.
+func saveArtifact(plugin, executionID, name string, data []byte, first bool) (string, error) {
+	if artifactDir == "" {
+		return "", nil
+	}
+	dir := filepath.Join(artifactDir, plugin)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to save artifact %s: %v", name, err)
+	}
+	// Plugins name artifacts; they don't get to pick where they go
+	base := filepath.Base(filepath.Clean("/" + name))
+	if executionID != "" {
+		base = executionID + "-" + base
+	}
+	path := filepath.Join(dir, base)
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if first {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to save artifact %s: %v", name, err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return "", fmt.Errorf("failed to save artifact %s: %v", name, err)
+	}
+	return path, nil
+}
+
+func (h *outputHandler) OnPrompt(id, message string) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if output.structured() {
+		output.event(runEvent{Event: "prompt", Plugin: h.pluginName, Execution: h.executionID, Time: time.Now(), Message: h.redactor.String(message)})
+		return nil
+	}
+	h.print(msg("output.prompt", h.pluginName, h.redactor.String(message)))
+	return nil
+}
+
+func (h *outputHandler) OnMetric(name string, value float64, labels map[string]string) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.pluginMetrics == nil {
+		h.pluginMetrics = make(map[string]float64)
+	}
+	h.pluginMetrics[name] = value
+	return nil
+}
+
+// mergeDefaults fills unset parameters from the environment (secrets only), the config defaults
+// and the plugin's schema defaults, in that order
+func mergeDefaults(params map[string]string, info *shared.PluginInfo, pluginConfig shared.PluginConfig) {
+	for name, spec := range info.ParameterSchema {
+		if _, exists := params[name]; !exists {
+			// Secrets can come from the environment to keep them off the command line
+			if value, ok := os.LookupEnv(shared.SecretParamEnvVar(name)); ok && spec.IsSecret() {
+				params[name] = value
+			} else if configDefault, ok := pluginConfig.Defaults[name]; ok {
+				params[name] = configDefault
+			} else if spec.DefaultValue != "" {
+				// Fall back to schema defaults
+				params[name] = spec.DefaultValue
+			}
+		}
+	}
+}
+
+// outcomeMetadata notes timeouts, queue timeouts and where the diagnostics and spilled output of an execution went
+func outcomeMetadata(execErr error, capture *shared.OutputCapture) map[string]string {
+	metadata := make(map[string]string)
+	var hang *shared.HangError
+	if errors.As(execErr, &hang) && hang.DumpDir != "" {
+		metadata["diagnostics"] = hang.DumpDir
+	}
+	var timedOut *shared.TimeoutError
+	if errors.As(execErr, &timedOut) {
+		metadata["error_code"] = shared.ErrorCodeTimeout
+		metadata["timeout"] = timedOut.Timeout.String()
+	}
+	var queued *shared.QueueTimeoutError
+	if errors.As(execErr, &queued) {
+		metadata["error_code"] = shared.ErrorCodeQueueTimeout
+		metadata["queue_timeout"] = queued.Timeout.String()
+	}
+	if spillFile := capture.SpillFile(); spillFile != "" {
+		metadata["output_file"] = spillFile
+		log.Print(msg("run.output_spilled", capture.Lines(), spillFile))
+	}
+	return metadata
+}
+
+// finishedExecution is a plugin execution that is over and remains to be reported
+type finishedExecution struct {
+	name       string
+	config     shared.PluginConfig
+	plugin     shared.PluginInterface
+	info       *shared.PluginInfo
+	params     map[string]string
+	redactor   *shared.Redactor
+	handler    *outputHandler
+	start, end int64 // UnixNano
+	err        error
+	metadata   map[string]string     // Notes on the execution beyond the plugin type and parameters
+	usage      *shared.ResourceUsage // Of the plugin's process over the execution, if it runs locally
+}
+
+// report shows the plugin's execution summary, exports the execution metrics and, if keepHistory
+// is set, records the execution in the history
+func (e *finishedExecution) report(config *shared.AppConfig, keepHistory bool) {
+	// Prepare metadata and metrics
+	metadata := make(map[string]string)
+	metrics := make(map[string]float64)
+
+	// Add execution metadata
+	metadata["plugin_type"] = string(e.config.Type)
+	for k, v := range e.redactor.Params(e.params) {
+		metadata[k] = v
+	}
+	for k, v := range e.metadata {
+		metadata[k] = v
+	}
+	if e.handler.executionID != "" {
+		metadata["execution_id"] = e.handler.executionID
+	}
+
+	// Add basic metrics
+	metrics["execution_time_ms"] = float64(e.end-e.start) / float64(time.Millisecond)
+	for name, value := range e.handler.pluginMetrics {
+		metrics["plugin_"+name] = value
+	}
+	host := make(map[string]float64)
+	if e.usage != nil {
+		host["process_cpu_percent"] = e.usage.CPUPercent
+		host["process_rss_bytes"] = float64(e.usage.RSSBytes)
+		if e.usage.OpenFDs >= 0 {
+			host["process_open_fds"] = float64(e.usage.OpenFDs)
+		}
+	}
+	for name, value := range host {
+		metrics[name] = value
+	}
+
+	// Get execution summary
+	summary, err := e.plugin.ReportExecutionSummary(e.start, e.end, e.err == nil, e.err, metadata, metrics)
+	if err != nil {
+		log.Print(msg("run.summary_failed", err))
+	} else {
+		metadata, metrics = summary.Metadata, summary.Metrics
+		if e.handler.verbosity != verbosityQuiet {
+			displayExecutionSummary(summary, e.redactor, e.handler.executionID)
+		}
+	}
+
+	// Export execution metrics, labeled for chargeback, and forward them if configured
+	err = shared.NewMetricsExporter(config).Record(shared.ExecutionMetrics{
+		Plugin:   e.name,
+		Labels:   e.config.MetricLabels,
+		Params:   e.redactor.Params(e.params),
+		Success:  e.err == nil,
+		Duration: time.Duration(e.end - e.start),
+		Metrics:  e.handler.pluginMetrics,
+		Version:  e.info.Version,
+		Host:     host,
+	})
+	if err != nil {
+		log.Print(msg("warning", err))
+	}
+
+	if keepHistory {
+		record := shared.HistoryRecord{
+			Time:     time.Unix(0, e.start),
+			Plugin:   e.name,
+			Version:  e.info.Version,
+			Params:   e.redactor.Params(e.params),
+			Success:  e.err == nil,
+			Duration: shared.Duration(e.end - e.start),
+			ID:       e.handler.executionID,
+			Metadata: e.redactor.Params(metadata),
+			Metrics:  metrics,
+		}
+		if e.err != nil {
+			record.Error = e.redactor.String(e.err.Error())
+		}
+		for _, name := range sortedKeys(e.handler.artifactSizes) {
+			record.Artifacts = append(record.Artifacts, shared.HistoryArtifact{Name: name, Size: e.handler.artifactSizes[name], Path: e.handler.artifactPaths[name]})
+		}
+		if err := config.HistoryStore().Append(record); err != nil {
+			log.Print(msg("warning", err))
+		}
+	}
+}
+
 func main() {
-	// Set up logging
-	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
+	// Set up logging, which -log-format and -log-level change
+	if err := configureLogging(logSettings.format, logSettings.level); err != nil {
+		log.Fatal(err)
+	}
 
 	// Create a context that will be canceled on interrupt
 	ctx, cancel := context.WithCancel(context.Background())
@@ -138,138 +589,484 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-sigChan
-		log.Println("Received interrupt signal, shutting down...")
+		log.Println(msg("run.interrupted"))
 		cancel()
 	}()
 
 	// Parse command line flags
-	configPath := flag.String("config", "config.json", "Path to configuration file")
+	configPath := configFlag(flag.CommandLine)
+	profileFlag(flag.CommandLine)
 	listPlugins := flag.Bool("list", false, "List available plugins")
 	showInfo := flag.Bool("info", false, "Show detailed plugin information")
-	flag.Parse()
+	readOnly := flag.Bool("read-only", false, "Refuse to start, stop or execute plugins (same as read_only in the config)")
+	noDaemon := flag.Bool("no-daemon", false, "Start the plugin for this run even if a daemon is running")
+	parallel := flag.Bool("parallel", false, "Execute several plugins concurrently (plugin names first, <plugin>.<param>=value for one plugin only)")
+	var tags tagList
+	flag.Var(&tags, "tag", "Select the plugins having this tag for -list or -parallel (repeatable, plugins need every tag)")
+	sample := flag.Duration("sample", 0, "Cancel execution after the given window and report what arrived (e.g. 10s)")
+	timeout := flag.Duration("timeout", 0, "Fail the execution with TIMEOUT if it runs longer (e.g. 30s); the plugin's execution_timeout still applies")
+	deadlineFlag := flag.String("deadline", "", "Fail the execution with TIMEOUT if it is still running at this time (RFC 3339, or a clock time today such as 17:30); combines with -timeout")
+	priority := flag.Int("priority", 0, "Queue priority when the plugin is at its concurrency limit; higher runs first")
+	prefer := flag.String("prefer", "", "Connect a remote plugin to this of its address and fallback_addresses first, and fail back to it (bypasses a running daemon)")
+	token := flag.String("token", "", "Bearer token for this execution against a remote plugin (default $"+shared.CredentialEnvVar+" or the credential_helper)")
+	paramsFile := flag.String("params-file", "", "Read parameters from a JSON or YAML file; parameters on the command line override it")
+	var fromStdin stdinMappings
+	flag.Var(&fromStdin, "from-stdin", "Map a field of the piped upstream result to a parameter (<result-field>:<param>, repeatable)")
+	var envFlags envOverrides
+	flag.Var(&envFlags, "env", "Set an environment variable of a local plugin for this run, over its configured env (KEY=VAL, repeatable; bypasses a running daemon)")
+	workdir := flag.String("workdir", "", "Run a local plugin in this directory for this run instead of its configured workdir (bypasses a running daemon)")
+	noInteractive := flag.Bool("no-interactive", false, "Don't ask for missing required parameters at the terminal; the plugin reports them instead")
+	detachFlag := flag.Bool("detach", false, "Leave the execution to the daemon once it started and print its id for 'plugin-app attach'; Ctrl+\\ detaches a run meanwhile")
+	quiet := flag.Bool("quiet", false, "Show only errors, warnings and the result")
+	verbose := flag.Bool("verbose", false, "Show plugin debug logs as well")
+	outputFlag(flag.CommandLine)
+	logFlags(flag.CommandLine)
 
-	// Load configuration
-	config, err := shared.LoadConfig(*configPath)
-	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+	// Dispatch subcommands; "run" is the default command but is accepted explicitly as well
+	cmdArgs := os.Args[1:]
+	if len(cmdArgs) > 0 {
+		switch cmdArgs[0] {
+		case "validate":
+			runValidate(cmdArgs[1:])
+			return
+		case "sign":
+			runSign(cmdArgs[1:])
+			return
+		case "encrypt":
+			runEncrypt(cmdArgs[1:])
+			return
+		case "compat":
+			runCompat(cmdArgs[1:])
+			return
+		case "schema":
+			runSchema(cmdArgs[1:])
+			return
+		case "health":
+			runHealth(cmdArgs[1:])
+			return
+		case "regress":
+			runRegress(cmdArgs[1:])
+			return
+		case "daemon":
+			runDaemon(cmdArgs[1:])
+			return
+		case "exec":
+			runExec(ctx, cmdArgs[1:])
+			return
+		case "logs":
+			runLogs(ctx, cmdArgs[1:])
+			return
+		case "stats":
+			runStats(cmdArgs[1:])
+			return
+		case "history":
+			runHistory(cmdArgs[1:])
+			return
+		case "new":
+			runNew(cmdArgs[1:])
+			return
+		case "config":
+			runConfig(cmdArgs[1:])
+			return
+		case "doctor":
+			runDoctor(cmdArgs[1:])
+			return
+		case "pipeline":
+			runPipeline(ctx, cmdArgs[1:])
+			return
+		case "bench":
+			runBench(ctx, cmdArgs[1:])
+			return
+		case "test":
+			runConformance(ctx, cmdArgs[1:])
+			return
+		case "watch":
+			runWatch(ctx, cmdArgs[1:])
+			return
+		case "ps":
+			runPs(cmdArgs[1:])
+			return
+		case "attach":
+			runAttach(ctx, cmdArgs[1:])
+			return
+		case "completion":
+			runCompletion(cmdArgs[1:])
+			return
+		case "__complete":
+			runComplete(flag.CommandLine, cmdArgs[1:])
+			return
+		case "run":
+			cmdArgs = cmdArgs[1:]
+		}
 	}
+	flag.CommandLine.Parse(cmdArgs)
+
+	// Load configuration
+	config := loadConfig(*configPath)
 
 	// Handle -list flag
 	if *listPlugins {
-		fmt.Println("Available plugins:")
-		for _, desc := range config.ListPlugins() {
-			fmt.Printf("  %s\n", desc)
+		if output.structured() {
+			output.document(pluginList(config, tags))
+			return
+		}
+		fmt.Println(msg("list.header"))
+		for _, doc := range pluginList(config, tags) {
+			entry := doc.Name + ": " + doc.Description
+			if doc.DiscoveredIn != "" {
+				entry = msg("list.discovered", doc.Name, doc.Description, doc.DiscoveredIn)
+			}
+			if len(doc.Tags) > 0 {
+				entry += msg("list.tags", strings.Join(doc.Tags, ", "))
+			}
+			fmt.Println(msg("list.entry", entry))
 		}
 		return
 	}
 
-	// Get plugin name from arguments
+	// Get plugin name from arguments, or the plugins of a group from -tag
 	args := flag.Args()
-	if len(args) < 1 {
-		fmt.Println("Usage: plugin-app [-config path/to/config.json] [-list] [-info] <plugin-name> [param1=value1 ...]")
-		fmt.Println("Use -list to see available plugins")
-		fmt.Println("Use -info to see detailed plugin information")
+	if len(args) < 1 && len(tags) == 0 {
+		fmt.Println(msg("run.usage"))
 		os.Exit(1)
 	}
+	if len(tags) > 0 {
+		if !*parallel {
+			fatal(msg("run.tag_parallel"))
+		}
+		names := config.Tagged(tags)
+		if len(names) == 0 {
+			fatal(msg("run.tag_no_plugins", tags.String()))
+		}
+		args = append(names, args...)
+	}
+
+	if *timeout < 0 {
+		fatal(msg("run.invalid_timeout", *timeout))
+	}
+	level, err := verbosityLevel(*quiet, *verbose)
+	if err != nil {
+		fatal(err.Error())
+	}
+	var deadline time.Time
+	if *deadlineFlag != "" {
+		var err error
+		if deadline, err = shared.ParseDeadline(*deadlineFlag, time.Now()); err != nil {
+			fatal(msg("run.invalid_deadline", err))
+		}
+		if _, err := shared.TimeoutUntil(0, deadline, time.Now()); err != nil {
+			fatal(msg("run.invalid_deadline", err))
+		}
+	}
+	if *priority != 0 {
+		ctx = shared.WithPriority(ctx, *priority)
+	}
+
+	// Execute several plugins at once, each reported on its own
+	if *parallel {
+		if *showInfo || *sample > 0 || len(fromStdin) > 0 || *paramsFile != "" || *detachFlag || len(envFlags) > 0 || *workdir != "" {
+			fatal(msg("run.parallel_flags"))
+		}
+		runParallel(ctx, config, *readOnly, *noDaemon, *prefer, *token, *timeout, deadline, level, args)
+		return
+	}
+
+	overrides, overridden, err := runOverrides(envFlags, *workdir)
+	if err != nil {
+		fatal(msg("error", err))
+	}
+	if *detachFlag && (*noDaemon || *prefer != "" || *sample > 0 || overridden) {
+		fatal(msg("run.detach_flags"))
+	}
+	// Checked before anything is started, which a failed check would leave running
+	if len(fromStdin) > 0 && !isPiped(os.Stdin) {
+		fatal(msg("run.stdin_not_piped"))
+	}
 
 	pluginName := args[0]
 	pluginConfig, err := config.GetPluginConfig(pluginName)
 	if err != nil {
-		log.Fatalf("Error: %v", err)
+		fatal(msg("error", err))
 	}
 
 	// Validate plugin configuration
 	if err := pluginConfig.Validate(); err != nil {
-		log.Fatalf("Invalid plugin configuration for %s: %v", pluginName, err)
+		fatal(msg("run.invalid_plugin", pluginName, err))
+	}
+	if *prefer != "" {
+		if err := pluginConfig.Prefer(*prefer); err != nil {
+			fatal(msg("run.invalid_prefer", pluginName, err))
+		}
 	}
 
+	// Structured results go to stdout when it's piped or -output asks for them, so keep plugin
+	// chatter off it
+	pipedOut := isPiped(os.Stdout)
+
 	// Create plugin manager
 	manager := shared.NewPluginManager(config)
 	defer manager.StopAll()
+	if *readOnly {
+		manager.SetReadOnly(true)
+	}
+	manager.SetProcessExitHandler(func(exit *shared.ProcessExit) {
+		log.Print(msg("run.plugin_exited", exit))
+	})
+	killOrphans(manager)
+	if overridden {
+		if err := manager.SetRunOverrides(pluginName, overrides); err != nil {
+			fatal(msg("run.invalid_overrides", pluginName, err))
+		}
+	}
 
-	// Start the plugin
-	if err := manager.StartPlugin(pluginName, pluginConfig); err != nil {
-		log.Fatalf("Failed to start plugin %s: %v", pluginName, err)
+	// Reuse the daemon's warm plugin instead of starting one when a daemon is running. The
+	// daemon picked its own address for a remote plugin, so -prefer needs a connection of its
+	// own, and the daemon's plugin wasn't started with the -env and -workdir of this run.
+	var plugin shared.PluginInterface
+	viaDaemon := false
+	if !*noDaemon && *prefer == "" && !overridden && !manager.ReadOnly() {
+		plugin, err = shared.ConnectDaemon(ctx, config, pluginName)
+		if err == nil {
+			viaDaemon = true
+			defer plugin.Close()
+			level.print(msg("run.daemon", pluginName, config.DaemonSocketPath()))
+		} else if !errors.Is(err, shared.ErrNoDaemon) {
+			log.Print(msg("warning", err))
+		}
 	}
-	log.Printf("Started plugin: %s (type: %s)", pluginName, pluginConfig.Type)
 
-	// Get the plugin client
-	plugin, err := manager.GetPlugin(pluginName)
-	if err != nil {
-		log.Fatalf("Failed to get plugin %s: %v", pluginName, err)
+	if *detachFlag && !viaDaemon {
+		fatal(msg("run.detach_no_daemon", config.DaemonSocketPath()))
+	}
+
+	if plugin == nil {
+		// Start the plugin
+		if err := manager.StartPlugin(pluginName, pluginConfig); err != nil {
+			fatal(msg("run.start_failed", pluginName, err))
+		}
+		level.print(msg("run.started", pluginName, pluginConfig.Type))
+
+		// Get the plugin client
+		plugin, err = manager.GetPlugin(pluginName)
+		if err != nil {
+			manager.StopAll()
+			fatal(msg("run.get_plugin_failed", pluginName, err))
+		}
 	}
 
 	// Get plugin info
 	info, err := plugin.GetInfo(ctx)
 	if err != nil {
-		log.Fatalf("Failed to get plugin info: %v", err)
+		manager.StopAll()
+		fatal(msg("run.info_failed", err))
 	}
 
 	// Handle -info flag
 	if *showInfo {
+		if output.structured() {
+			output.document(pluginInfoDocument(info, pluginConfig))
+			return
+		}
 		displayPluginInfo(info, pluginConfig)
 		return
 	}
 
-	// Parse parameters
+	// Catch plugin upgrades that would silently break saved parameter sets
+	if err := checkSchemaChanges(config, pluginName, info); err != nil {
+		manager.StopAll()
+		fatal(msg("run.schema_refused", pluginName, err))
+	}
+
+	// Parse parameters, which take precedence over those of a parameters file
 	params := parseParams(args[1:])
+	if *paramsFile != "" {
+		fileParams, err := shared.LoadParamsFile(*paramsFile)
+		if err != nil {
+			manager.StopAll()
+			fatal(msg("error", err))
+		}
+		// A misspelt name in a file would otherwise go unnoticed, unlike one typed for this run
+		if unknown := shared.UnknownParams(info.ParameterSchema, fileParams); len(unknown) > 0 && len(info.ParameterSchema) > 0 {
+			manager.StopAll()
+			fatal(msg("run.params_file_unknown", *paramsFile, pluginName, strings.Join(unknown, ", ")))
+		}
+		for name, value := range fileParams {
+			if _, given := params[name]; !given {
+				params[name] = value
+			}
+		}
+	}
+
+	// Wire the upstream result into parameters when invoked downstream of a pipe
+	if len(fromStdin) > 0 {
+		upstream, err := readPipedResult(os.Stdin)
+		if err != nil {
+			manager.StopAll()
+			fatal(msg("run.upstream_read_failed", err))
+		}
+		if err := applyStdinMappings(params, upstream, fromStdin); err != nil {
+			manager.StopAll()
+			fatal(msg("run.upstream_map_failed", err))
+		}
+	}
 
 	// Merge with defaults from plugin schema and config
-	for name, spec := range info.ParameterSchema {
-		if _, exists := params[name]; !exists {
-			// First try config defaults
-			if configDefault, ok := pluginConfig.Defaults[name]; ok {
-				params[name] = configDefault
-			} else if spec.DefaultValue != "" {
-				// Fall back to schema defaults
-				params[name] = spec.DefaultValue
-			}
+	mergeDefaults(params, info, pluginConfig)
+
+	// Ask for required parameters that are still missing when someone is at the terminal
+	missing := shared.MissingParams(info.ParameterSchema, params)
+	if len(missing) > 0 && !*noInteractive && !isPiped(os.Stdin) {
+		if err := promptParams(ctx, os.Stdin, os.Stderr, pluginName, info.ParameterSchema, params, missing); err != nil {
+			manager.StopAll()
+			fatal(msg("run.prompt_failed", pluginName, err))
 		}
 	}
 
+	// Mask secrets and configured patterns in everything shown or reported
+	redactor, err := shared.NewRedactor(config.Redaction, info.ParameterSchema, params)
+	if err != nil {
+		manager.StopAll()
+		fatal(msg("error", err))
+	}
+
+	// Attach the caller's own credentials to the execution
+	credential, err := resolveCredential(ctx, *token, pluginName, pluginConfig)
+	if err != nil {
+		manager.StopAll()
+		fatal(msg("error", err))
+	}
+	redactor.Mask(credential)
+	for _, secret := range pluginConfig.AuthHeaders.Secrets() {
+		redactor.Mask(secret)
+	}
+
+	// Watch the host's own memory so very large executions spill to disk instead of exhausting it
+	monitor := shared.NewMemoryMonitor(config.Memory)
+	monitor.OnChange(func(pressure bool) {
+		if pressure {
+			log.Print(msg("run.memory_pressure", monitor.Limit()>>20))
+		} else {
+			log.Print(msg("run.memory_recovered"))
+		}
+	})
+	monitor.Start(ctx)
+	capture := shared.NewOutputCapture(config.SpillPath(), monitor)
+	defer capture.Close()
+
 	// Create output handler
+	executionID, err := shared.NewExecutionID()
+	if err != nil {
+		manager.StopAll()
+		fatal(msg("error", err))
+	}
 	handler := &outputHandler{
-		pluginName: pluginName,
+		pluginName:  pluginName,
+		executionID: executionID,
+		redactor:    redactor,
+		capture:     capture,
+		monitor:     monitor,
+		verbosity:   level,
+	}
+	if pipedOut && !output.structured() {
+		handler.resultWriter = os.Stdout
 	}
 
 	// Record start time
 	startTime := time.Now().UnixNano()
 
-	// Execute plugin
-	execErr := plugin.Execute(ctx, params, handler)
+	execCtx := shared.WithExecutionID(shared.WithCredential(ctx, credential), handler.executionID)
+
+	// Limit execution to the sample window if requested
+	if *sample > 0 {
+		var cancelSample context.CancelFunc
+		execCtx, cancelSample = context.WithTimeout(execCtx, *sample)
+		defer cancelSample()
+	}
+
+	// Executions in the daemon can be left to it, at once with -detach or on Ctrl+\ meanwhile
+	var detach *detacher
+	if viaDaemon {
+		var cancelExec context.CancelFunc
+		execCtx, cancelExec = context.WithCancel(execCtx)
+		defer cancelExec()
+		if detach, err = newDetacher(config, *detachFlag, cancelExec); err != nil {
+			fatal(msg("error", err))
+		}
+		defer detach.stop()
+		// The daemon may journal the execution under an id of its own, which the run then goes by
+		execCtx = shared.WithDetachable(execCtx, func(id string) {
+			handler.adoptExecutionID(id)
+			detach.started(id)
+		})
+	}
+
+	// The deadline leaves whatever time is left once the plugin is ready
+	runTimeout, err := shared.TimeoutUntil(*timeout, deadline, time.Now())
+	if err != nil {
+		manager.StopAll()
+		fatal(msg("run.invalid_deadline", err))
+	}
+
+	// Execute plugin, measuring what its process uses meanwhile
+	measured := manager.MeasureUsage(pluginName)
+	execErr := shared.ExecuteWithTimeout(execCtx, plugin, pluginName, runTimeout, params, handler)
+	usage := measured()
 
 	// Record end time
 	endTime := time.Now().UnixNano()
 
-	// Prepare metadata and metrics
-	metadata := make(map[string]string)
-	metrics := make(map[string]float64)
+	// The daemon reports and records a detached execution once it finishes
+	if id, ok := detach.detached(); ok {
+		reportDetached(pluginName, id)
+		return
+	}
 
-	// Add execution metadata
-	metadata["plugin_type"] = string(pluginConfig.Type)
-	for k, v := range params {
-		metadata[k] = v
+	// An elapsed sample window is the expected outcome, not a failure
+	sampled := *sample > 0 && execErr != nil && execCtx.Err() == context.DeadlineExceeded
+	if sampled {
+		execErr = nil
 	}
 
-	// Add basic metrics
-	metrics["execution_time_ms"] = float64(endTime-startTime) / float64(time.Millisecond)
+	metadata := outcomeMetadata(execErr, capture)
+	if !deadline.IsZero() {
+		metadata["deadline"] = deadline.Format(time.RFC3339)
+	}
+	if *sample > 0 {
+		metadata["sample_window"] = sample.String()
+	}
 
-	// Get execution summary
-	summary, err := plugin.ReportExecutionSummary(startTime, endTime, execErr == nil, execErr, metadata, metrics)
-	if err != nil {
-		log.Printf("Failed to get execution summary: %v", err)
-	} else {
-		displayExecutionSummary(summary)
+	// Only complete executions are kept for replays such as regress; the daemon records those it serves
+	execution := &finishedExecution{
+		name:     pluginName,
+		config:   pluginConfig,
+		plugin:   plugin,
+		info:     info,
+		params:   params,
+		redactor: redactor,
+		handler:  handler,
+		start:    startTime,
+		end:      endTime,
+		err:      execErr,
+		metadata: metadata,
+		usage:    usage,
+	}
+	execution.report(config, !sampled && !viaDaemon)
+
+	if sampled {
+		displaySampleReport(*sample, handler)
+		log.Print(msg("run.sample_elapsed", pluginName))
+		return
 	}
 
 	// Handle execution error
 	if execErr != nil {
 		if ctx.Err() == context.Canceled {
-			log.Printf("Plugin %s execution canceled", pluginName)
+			log.Print(msg("run.canceled", pluginName))
 		} else {
-			log.Fatalf("Plugin %s execution failed: %v", pluginName, execErr)
+			manager.StopAll()
+			fatal(msg("run.failed", pluginName, redactor.String(execErr.Error())))
 		}
 	}
 
-	log.Println("Plugin execution completed")
+	level.print(msg("run.completed"))
 }
@@ -0,0 +1,516 @@
+package main
+
+import (
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// defaultMessages is the built-in English catalog of everything the CLI shows. Each format takes
+// the same arguments as its call site; messages in config.json or a message file replace them.
+var defaultMessages = map[string]string{
+	"error":                  "Error: %v",
+	"warning":                "Warning: %v",
+	"config.load_failed":     "Failed to load config: %v",
+	"config.messages_failed": "Invalid messages configuration: %v",
+	"orphan.killed":          "Killed plugin %s (pid %d, port %d) left running by a crashed run",
+	"orphan.kill_failed":     "Failed to kill plugin %s (pid %d) left running by a crashed run: %v",
+
+	// run
+	"run.usage": "Usage: plugin-app [run] [-config path/to/config.json] [-list] [-info] [-read-only] [-no-daemon] [-sample duration] <plugin-name> [param1=value1 ...]\n" +
+		"       plugin-app [run] -parallel <plugin-name> <plugin-name> ... [param1=value1 ...] [plugin-name.param=value ...]\n" +
+		"       plugin-app [run] -parallel -tag <tag> [plugin-name ...] [param1=value1 ...]\n" +
+		"Use -list to see available plugins, -list -tag <tag> those with a tag\n" +
+		"Use -info to see detailed plugin information\n" +
+		"Use -sample to run a plugin for a limited window only\n" +
+		"Use -params-file to read parameters from a JSON or YAML file; name=value arguments override it\n" +
+		"Use -env KEY=VAL and -workdir to change a local plugin's environment and working directory for this run only\n" +
+		"Use -detach to leave an execution to the daemon, or press Ctrl+\\ while it runs; 'plugin-app attach' lists and resumes them\n" +
+		"Use -quiet to show only errors, warnings and the result, or -verbose to show plugin debug logs as well\n" +
+		"Use -log-format json for a JSON log on stderr, and -log-level debug|info|warn|error to choose what it shows, with any command\n" +
+		"Use -timeout or -deadline to fail an execution with TIMEOUT once it runs too long; its summary is still shown\n" +
+		"Required parameters without a value are asked for at the terminal; -no-interactive leaves them to the plugin\n" +
+		"Use -output json or -output yaml for machine-readable -list, -info, stats, history and run events (one document per event) on stdout\n" +
+		"Use -read-only to inspect plugins without starting or executing anything\n" +
+		"Use -from-stdin result:num1 to feed the result of a piped plugin-app run into a parameter\n" +
+		"Use -token to call a remote plugin with your own credentials\n" +
+		"Use 'plugin-app validate [-dial] [-live] [-write-checksums]' to check the configuration\n" +
+		"Use 'plugin-app config lint [-severity level]' to check the configuration against best practices\n" +
+		"Use 'plugin-app compat [-min-protocol n] [-drop-feature f]' to check plugins against a planned host upgrade\n" +
+		"Use 'plugin-app health -all [-parallel n]' to probe every configured plugin\n" +
+		"Use 'plugin-app schema [-ack] <plugin-name>' to review and acknowledge plugin schema changes\n" +
+		"Use 'plugin-app daemon start|stop|status' to keep plugins warm for later runs in the background; -no-daemon starts the plugin for this run anyway\n" +
+		"Use 'plugin-app daemon start -admin host:port' to serve /healthz and /readyz for systemd or Kubernetes probes\n" +
+		"Use 'plugin-app exec <binary|host:port> [-- param=value ...]' to run a plugin that isn't configured\n" +
+		"Use 'plugin-app logs [-follow] [-since d] [-tail n] <plugin-name>...' to see what plugins wrote to stdout and stderr, merged in order\n" +
+		"Use 'plugin-app stats [-since d] [-output json|yaml] [plugin-name...]' to see run counts, success rates and durations from the execution history\n" +
+		"Use 'plugin-app history [-since d] [-until d] [-failed] [-limit n] [-output json|yaml] [plugin-name...]' to list recorded executions with their summaries and artifacts\n" +
+		"Use 'plugin-app history show [-output json|yaml] <execution-id>' to see everything recorded of one execution, by the id its output and artifacts carry\n" +
+		"Use 'plugin-app new plugin [-param name:type:required:description ...] <name>' to scaffold a Go plugin and configure it\n" +
+		"Use 'plugin-app doctor [-start]' to check that config, binaries, ports, processes and plugin connections work here\n" +
+		"Use 'source <(plugin-app completion bash|zsh)' to complete plugin names, parameters and their allowed values\n" +
+		"Use 'plugin-app pipeline <workflow.json>' to execute plugin steps in order, passing results between them\n" +
+		"Use 'plugin-app bench [-n runs] [-c concurrency] [-include-startup] <plugin-name>' to measure execution latency and throughput\n" +
+		"Use 'plugin-app test <plugin-name|plugin-binary>' to check a plugin against the protocol contract\n" +
+		"Use 'plugin-app watch -path <file-or-dir> <plugin-name>' to execute a plugin again whenever files change\n" +
+		"Use 'plugin-app ps [-a]' to list the plugins a running daemon manages\n" +
+		"Use 'plugin-app attach [execution-id]' to list the executions left to the daemon or stream one of them again\n" +
+		"Use 'plugin-app regress -baseline v1 -candidate v2 <plugin-name>' to replay recent executions against two plugin versions\n" +
+		"Use 'plugin-app sign -publisher name -version v <binary>' to write a signed plugin manifest\n" +
+		"Use 'plugin-app encrypt [value]' to write an enc: value for config.json",
+	"run.interrupted":          "Received interrupt signal, shutting down...",
+	"run.invalid_plugin":       "Invalid plugin configuration for %s: %v",
+	"run.start_failed":         "Failed to start plugin %s: %v",
+	"run.started":              "Started plugin: %s (type: %s)",
+	"run.daemon":               "Using plugin %s from the daemon at %s",
+	"run.get_plugin_failed":    "Failed to get plugin %s: %v",
+	"run.info_failed":          "Failed to get plugin info: %v",
+	"run.schema_refused":       "Refusing to run %s: %v",
+	"run.stdin_not_piped":      "-from-stdin requires the output of another plugin-app run on stdin",
+	"run.upstream_read_failed": "Failed to read upstream result: %v",
+	"run.upstream_map_failed":  "Failed to map upstream result: %v",
+	"run.token_local":          "-token is only supported for remote plugins",
+	"run.memory_pressure":      "Memory use above %d MB, spilling output to disk and reducing progress reporting",
+	"run.memory_recovered":     "Memory use back below the soft limit",
+	"run.plugin_exited":        "Plugin process crashed, recovering: %v",
+	"run.output_spilled":       "Output of %d lines spilled to %s",
+	"run.summary_failed":       "Failed to get execution summary: %v",
+	"run.invalid_timeout":      "invalid -timeout %s: must not be negative",
+	"run.invalid_deadline":     "invalid -deadline: %v",
+	"run.verbosity_flags":      "-quiet and -verbose can't be combined",
+	"run.parallel_flags":       "-parallel can't be combined with -info, -sample, -from-stdin, -params-file, -detach, -env or -workdir",
+	"run.tag_parallel":         "-tag selects a group of plugins, which runs with -parallel",
+	"run.tag_no_plugins":       "No plugins are tagged %s",
+	"run.detach_flags":         "-detach can't be combined with -no-daemon, -prefer, -sample, -env or -workdir",
+	"run.detach_no_daemon":     "-detach leaves the execution to a running daemon, but none answers on %s",
+	"run.detached":             "Detached from execution %s of %s, which goes on in the daemon; 'plugin-app attach %s' streams it again",
+	"run.params_file_unknown":  "Parameters file %s sets parameters %s doesn't have: %s",
+	"run.parallel_prefer":      "invalid -prefer: %s is not an address of any of the remote plugins named",
+	"run.invalid_prefer":       "invalid -prefer for plugin %s: %v",
+	"run.invalid_overrides":    "invalid -env or -workdir for plugin %s: %v",
+	"run.invalid_env":          "expected KEY=VAL, got %q",
+	"run.parallel_no_plugins":  "-parallel needs at least one plugin name",
+	"run.parallel_duplicate":   "plugin %s is named more than once",
+	"run.parallel_summary":     "Parallel run: %d of %d plugins succeeded",
+	"run.parallel_succeeded":   "  %s: succeeded in %s",
+	"run.parallel_failed":      "  %s: failed after %s: %s",
+	"run.sample_elapsed":       "Plugin %s sample window elapsed, execution stopped",
+	"run.canceled":             "Plugin %s execution canceled",
+	"run.failed":               "Plugin %s execution failed: %s",
+	"run.prompt_failed":        "Missing parameters for %s: %v",
+	"run.completed":            "Plugin execution completed",
+
+	// -list
+	"list.header":     "Available plugins:",
+	"list.entry":      "  %s",
+	"list.discovered": "%s: %s (discovered in %s)",
+	"list.tags":       " [%s]",
+
+	// Plugin output
+	"output.line":           "[%s] %s",
+	"output.progress":       "[%s] Progress: %.1f%% (%s - Step %d/%d)",
+	"output.progress_rate":  "[%s] Progress: %.1f%% (%s - Step %d/%d, %s in stage, %.1f units/s, ~%s left)",
+	"output.result":         "[%s] Result: %s",
+	"output.error":          "[%s] Error %s: %s",
+	"output.error_details":  "[%s] Error %s: %s\nDetails: %s",
+	"output.log":            "[%s] %s: %s",
+	"output.artifact":       "[%s] Artifact %s: %d bytes",
+	"output.artifact_saved": "[%s] Artifact %s: %d bytes, saved to %s",
+	"output.prompt":         "[%s] Prompt: %s",
+
+	// -info
+	"info.header":               "Plugin Information:",
+	"info.name":                 "  Name: %s",
+	"info.version":              "  Version: %s",
+	"info.expected_version":     "  Expected Version: %s",
+	"info.description":          "  Description: %s",
+	"info.type":                 "  Type: %s",
+	"info.command":              "  Command Template: %s",
+	"info.address":              "  Address: %s",
+	"info.addresses":            "  Addresses: %s",
+	"info.load_balancing":       "  Load Balancing: %s",
+	"info.affinity_key":         "  Affinity Key: %s",
+	"info.proxy":                "  Proxy: %s",
+	"info.ssh_tunnel":           "  SSH Tunnel: %s",
+	"info.workdir":              "  Working Directory: %s",
+	"info.standby_port":         "  Warm Standby Port: %d",
+	"info.env_header":           "  Environment Variables:",
+	"info.env_entry":            "    %s: %s",
+	"info.params_header":        "  Parameters:",
+	"info.param":                "    %s:",
+	"info.param_description":    "      Description: %s",
+	"info.param_required":       "      Required: %v",
+	"info.param_secret":         "      Secret: yes (or set %s)",
+	"info.param_default":        "      Default: %s",
+	"info.param_config_default": "      Config Default: %s",
+	"info.param_allowed":        "      Allowed Values: %v",
+	"info.result_header":        "  Result Schema (validation: %s):",
+	"info.result_field":         "    %s:",
+	"info.result_description":   "      Description: %s",
+	"info.result_type":          "      Type: %s",
+	"info.result_required":      "      Required: %v",
+
+	// Parameter prompts
+	"prompt.header":             "%s needs %d more parameter(s); press Ctrl-C to cancel",
+	"prompt.description":        "%s: %s",
+	"prompt.allowed":            "  one of: %s",
+	"prompt.input":              "%s: ",
+	"prompt.input_secret":       "%s (input hidden): ",
+	"prompt.empty":              "  a value is required",
+	"prompt.not_allowed":        "  %q is not one of: %s",
+	"prompt.invalid":            "  %q is not a valid %s",
+	"prompt.no_value":           "no value given for %s",
+	"prompt.secret_unsupported": "can't hide the input of secret parameter %s here, set $%s instead (%v)",
+
+	// Execution summary
+	"summary.header":         "Plugin Summary: %s",
+	"summary.duration":       "  Duration: %.2f ms",
+	"summary.success":        "  Success: %v",
+	"summary.error":          "  Error: %s",
+	"summary.metadata":       "  Metadata:",
+	"summary.metadata_entry": "    %s: %s",
+	"summary.metrics":        "  Metrics:",
+	"summary.metrics_entry":  "    %s: %.2f",
+
+	// -sample
+	"sample.header":        "Sample Report: %s",
+	"sample.window":        "  Window: %s",
+	"sample.output":        "  Output Messages: %d",
+	"sample.progress":      "  Progress Updates: %d",
+	"sample.last_progress": "  Last Progress: %.1f%% (%s - Step %d/%d)",
+
+	// -from-stdin
+	"pipe.invalid_mapping": "expected <result-field>:<param>, got %q",
+	"pipe.invalid_result":  "invalid result on stdin: %v",
+	"pipe.read_failed":     "failed to read stdin: %v",
+	"pipe.no_result":       "no result received on stdin",
+	"pipe.missing_field":   "upstream result has no field %q",
+
+	// validate
+	"validate.checksums_failed":   "Failed to write checksums: %v",
+	"validate.config_header":      "Configuration:",
+	"validate.config_problem":     "  %v",
+	"validate.config_ok":          "  OK",
+	"validate.remote_header":      "Remote plugins:",
+	"validate.remote_ok":          "  %s: OK (%s)",
+	"validate.remote_not_serving": "  %s: FAILED (status %s)",
+	"validate.defaults_header":    "Defaults:",
+	"validate.no_defaults":        "  %s: no defaults configured",
+	"validate.defaults_problem":   "  %s: FAILED (%v)",
+	"validate.defaults_ok":        "  %s: OK (%d checked)",
+	"validate.checksums_header":   "Checksums:",
+	"validate.no_checksum":        "  %s: no checksum configured",
+	"validate.check_failed":       "  %s: FAILED (%v)",
+	"validate.checksum_ok":        "  %s: OK",
+	"validate.signatures_header":  "Signatures:",
+	"validate.signature_ok":       "  %s: OK (%s %s by %s)",
+	"validate.problems":           "%d problem(s) found",
+	"validate.checksum_skipped":   "  %s: skipped (%v)",
+	"validate.checksum_included":  "  %s: skipped (defined in another config file)",
+	"validate.checksum":           "  %s: %s",
+	"validate.checksums_written":  "Checksums written to %s",
+
+	// sign
+	"sign.usage":         "Usage: plugin-app sign -publisher name -version v [-key signing.key] [-name plugin] [-o manifest] <plugin-binary>",
+	"sign.key_failed":    "Failed to load signing key: %v",
+	"sign.hash_failed":   "Failed to hash plugin: %v",
+	"sign.save_failed":   "Failed to save manifest: %v",
+	"sign.written":       "Manifest written to %s",
+	"sign.key_generated": "Generated signing key %s",
+	"sign.trust_key":     "Add the public key to trusted_keys: %s",
+	"sign.invalid_key":   "%s is not a base64 Ed25519 key",
+
+	// encrypt
+	"encrypt.usage": "Usage: plugin-app encrypt [-generate-key] [value]\n" +
+		"The value is read from stdin when omitted, keeping it out of the shell history",
+	"encrypt.key_written": "Config key written to %s",
+	"encrypt.read_failed": "Failed to read value: %v",
+
+	// compat
+	"compat.host":           "Host: protocol %d-%d, features: %s",
+	"compat.planned_host":   "Planned host: protocol %d-%d, features: %s",
+	"compat.header":         "PLUGIN\tVERSION\tPROTOCOL\tFEATURES\tCURRENT",
+	"compat.header_planned": "\tPLANNED",
+	"compat.unreachable":    "%s\t-\t-\t-\tunreachable (%v)",
+	"compat.ok":             "ok",
+	"compat.breaks":         "BREAKS: %s",
+	"compat.incompatible":   "\n%d plugin(s) incompatible or unreachable",
+
+	// health
+	"health.usage": "Usage: plugin-app health [-config path/to/config.json] [-parallel n] [-timeout d] [-no-daemon] (-all | <plugin-name>...)\n" +
+		"Plugins a running daemon keeps warm are probed through it; -no-daemon starts local plugins to probe them anyway",
+	"health.header":      "PLUGIN\tTYPE\tREACHABLE\tSERVING\tLATENCY\tVERSION\tDETAIL",
+	"health.unreachable": "%s\t%s\tno\t-\t-\t-\t%v",
+	"health.row":         "%s\t%s\tyes\t%s\t%s\t%s\t%s",
+	"health.yes":         "yes",
+	"health.no":          "no",
+	"health.unhealthy":   "\n%d of %d plugin(s) unhealthy",
+
+	// schema
+	"schema.usage":            "Usage: plugin-app schema [-config path/to/config.json] [-ack] <plugin-name>",
+	"schema.none_recorded":    "No schema recorded for %s yet",
+	"schema.changes":          "Schema changes for %s (%s -> %s):",
+	"schema.recorded":         "Recorded schema of %s %s",
+	"schema.no_changes":       "  none",
+	"schema.change":           "  %s %s",
+	"schema.changed":          "Schema of %s changed compatibly (%s -> %s):",
+	"schema.changed_breaking": "WARNING: schema of %s changed in breaking ways (%s -> %s):",
+	"schema.change_entry":     "  %s",
+	"schema.ack_hint":         "Run 'plugin-app schema -ack %s' after reviewing saved parameters",
+	"schema.unacknowledged":   "breaking schema changes in %s have not been acknowledged",
+
+	// daemon
+	"daemon.usage": "Usage: plugin-app daemon [start [-log path] | stop | status] [-config path/to/config.json] [-socket path] [-admin host:port]\n" +
+		"Without a subcommand the daemon runs in the foreground",
+	"daemon.plugin_started":      "Started plugin: %s (type: %s)",
+	"daemon.plugin_failed":       "Failed to start plugin %s, retrying on first use: %v",
+	"daemon.plugin_lazy":         "Plugin %s starts on first use",
+	"daemon.plugin_unhealthy":    "Plugin %s is unhealthy: %v",
+	"daemon.plugin_recovered":    "Plugin %s recovered",
+	"daemon.plugin_circuit_open": "ALERT: plugin %s keeps failing and is no longer restarted: %v",
+	"daemon.reloaded":            "Reloaded configuration (%s)",
+	"daemon.reload_failed":       "%v",
+	"daemon.watch_failed":        "Not watching the configuration for changes: %v",
+	"daemon.listening":           "Daemon listening on %s",
+	"daemon.admin_listening":     "Serving /healthz and /readyz on http://%s",
+	"daemon.failed":              "Daemon failed: %v",
+	"daemon.stopping":            "Stopping daemon...",
+	"daemon.plugin_logs":         "Plugin output is logged to %s",
+	"daemon.already_running":     "A daemon is already running (pid %d) on %s",
+	"daemon.start_failed":        "Failed to start daemon: %v",
+	"daemon.started":             "Daemon started (pid %d) on %s, managing %d plugin(s); logging to %s",
+	"daemon.not_running":         "No daemon is running on %s",
+	"daemon.stop_failed":         "Failed to stop daemon: %v",
+	"daemon.stopped":             "Daemon stopped (pid %d)",
+	"daemon.running":             "Daemon running (pid %d) on %s",
+	"daemon.status_failed":       "Failed to query daemon: %v",
+	"daemon.status_header":       "PLUGIN\tTYPE\tSTATE\tSINCE\tRESTARTS\tQUEUED\tLAST ERROR",
+	"daemon.status_row":          "%s\t%s\t%s\t%s\t%d\t%d\t%s",
+
+	// exec
+	"exec.usage": "Usage: plugin-app exec [-port n | -port-auto] [-info] [-timeout d] [-token t] <binary|host:port> [flags] [--] [param1=value1 ...]\n" +
+		"Runs a plugin binary, or connects to a plugin on an address, without configuring it",
+	"exec.port_flags":     "-port and -port-auto can't be combined",
+	"exec.invalid_target": "Can't execute %s: %v",
+	"exec.started":        "Started plugin: %s (type: %s) from %s",
+
+	// logs
+	"logs.usage":          "Usage: plugin-app logs [-config path/to/config.json] [-follow] [-since 10m|time] [-tail n] [-timestamps] <plugin-name>...",
+	"logs.invalid_tail":   "invalid -tail: %d",
+	"logs.invalid_since":  "invalid -since: %s (must be a duration such as 10m or an RFC 3339 time)",
+	"logs.line_timestamp": "%s %s",
+	"logs.line_plugin":    "%s | %s",
+
+	// stats
+	"stats.invalid_since": "invalid -since: %s (must be a duration such as 24h or an RFC 3339 time)",
+	"stats.no_history":    "No recorded executions",
+	"stats.header":        "PLUGIN\tRUNS\tSUCCESS\tP50\tP95\tLAST FAILURE",
+	"stats.row":           "%s\t%d\t%.1f%%\t%s\t%s\t%s",
+	"stats.no_runs":       "%s\t0\t-\t-\t-\t-",
+	"stats.failure":       "%s: %s",
+
+	// history
+	"history.invalid_time":   "invalid %s: %s (must be a duration such as 24h or an RFC 3339 time)",
+	"history.invalid_limit":  "invalid -limit: %d (must be 0 or more)",
+	"history.header":         "ID\tTIME\tPLUGIN\tVERSION\tRESULT\tDURATION\tARTIFACTS\tERROR",
+	"history.row":            "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s",
+	"history.succeeded":      "ok",
+	"history.failed":         "failed",
+	"history.show_usage":     "Usage: plugin-app history show [-config path/to/config.json] [-output text|json|yaml] <execution-id>",
+	"history.show_header":    "Execution %s of %s",
+	"history.show_time":      "  Started: %s",
+	"history.show_version":   "  Version: %s",
+	"history.show_result":    "  Result: %s",
+	"history.show_duration":  "  Duration: %s",
+	"history.show_error":     "  Error: %s",
+	"history.show_params":    "  Parameters:",
+	"history.show_metadata":  "  Metadata:",
+	"history.show_metrics":   "  Metrics:",
+	"history.show_entry":     "    %s: %s",
+	"history.show_metric":    "    %s: %.2f",
+	"history.show_artifacts": "  Artifacts:",
+	"history.show_artifact":  "    %s: %d bytes",
+	"history.show_saved":     "    %s: %d bytes, saved to %s",
+
+	// new
+	"new.usage": "Usage: plugin-app new plugin [-config path/to/config.json] [-dir plugins] [-description text] [-port n] [-no-config]\n" +
+		"                   [-param name[:string|float|bool|secret[:required|optional[:description]]] ...] <name>",
+	"new.invalid_name":     "invalid plugin name %q: use lowercase letters, digits, - and _, starting with a letter",
+	"new.exists":           "%s already exists",
+	"new.exists_in_config": "plugin %s is already in %s",
+	"new.format_failed":    "generated plugin is not valid Go: %v",
+	"new.created":          "Created %s",
+	"new.configured":       "Added plugin %s to %s on port %d",
+	"new.next_steps":       "Build it with 'go build -o %s %s', then run 'plugin-app %s'",
+	"new.next_steps_exec":  "Build it with 'go build -o %[1]s %[2]s', then run 'plugin-app exec %[1]s'",
+
+	// doctor
+	"doctor.ok":                     "  ok    %s",
+	"doctor.warn":                   "  WARN  %s",
+	"doctor.fail":                   "  FAIL  %s",
+	"doctor.fix":                    "        fix: %s",
+	"doctor.config_header":          "Configuration (%s):",
+	"doctor.config_ok":              "parses and is valid, %d plugin(s)",
+	"doctor.fix_config_missing":     "create it, or point -config at the right file",
+	"doctor.fix_config_parse":       "correct the JSON syntax at the position given",
+	"doctor.fix_missing_file":       "build the plugin (e.g. 'make build') or correct its path",
+	"doctor.fix_not_executable":     "make it executable with 'chmod +x', or install the command",
+	"doctor.fix_config_problem":     "correct the setting in %s; 'plugin-app validate' lists every problem",
+	"doctor.state_header":           "State directory (%s):",
+	"doctor.state_ok":               "writable",
+	"doctor.fix_state_dir":          "set state_dir to a directory this user can write to, or fix its permissions",
+	"doctor.spawn_header":           "Processes:",
+	"doctor.spawn_ok":               "can start processes",
+	"doctor.fix_spawn":              "check process limits ('ulimit -u') and any sandbox or container restrictions on fork/exec",
+	"doctor.daemon_header":          "Daemon:",
+	"doctor.daemon_running":         "running (pid %d)",
+	"doctor.daemon_not_running":     "not running",
+	"doctor.fix_daemon":             "restart it with 'plugin-app daemon stop' and 'plugin-app daemon start'",
+	"doctor.ports_header":           "Ports:",
+	"doctor.port_free":              "%s: port %d is free",
+	"doctor.port_daemon":            "%s: port %d is in use, presumably by the daemon (pid %d)",
+	"doctor.port_busy":              "%s: port %d is in use (%v)",
+	"doctor.fix_port":               "stop what is listening on it (see 'lsof -i :%d'), or change the plugin's port; 0 picks a free one",
+	"doctor.no_ports":               "no fixed ports configured",
+	"doctor.plugins_header":         "Plugin connections:",
+	"doctor.plugin_ok":              "%s: serving, version %s, protocol %d, %s",
+	"doctor.plugin_unreachable":     "%s: unreachable (%v)",
+	"doctor.fix_unreachable_local":  "run it with 'plugin-app exec' to see why it doesn't start, and check 'plugin-app logs'",
+	"doctor.fix_unreachable_remote": "check that %s is running and reachable from here, and its TLS and token settings",
+	"doctor.plugin_not_serving":     "%s: not serving (status %s)",
+	"doctor.fix_not_serving":        "check the plugin's own logs; 'plugin-app health %s' shows its status",
+	"doctor.plugin_no_info":         "%s: serving, but didn't report its version",
+	"doctor.fix_no_info":            "rebuild the plugin against a current SDK",
+	"doctor.plugin_incompatible":    "%s: version %s incompatible: %s",
+	"doctor.fix_incompatible":       "upgrade the plugin to protocol %d-%d or pin a compatible host; 'plugin-app compat' shows details",
+	"doctor.no_plugins":             "no plugins to connect to; -start also starts local ones",
+	"doctor.problems":               "%d problem(s) and %d warning(s) found",
+	"doctor.healthy":                "No problems found, %d warning(s)",
+
+	// pipeline
+	"pipeline.usage": "Usage: plugin-app pipeline [-config path/to/config.json] [-no-daemon] [-output text|json|yaml] <workflow.json>\n" +
+		"Steps go through the plugins a running daemon keeps warm; -no-daemon starts them for the pipeline anyway\n" +
+		"With -output json or yaml, the events of each step's execution are followed by a \"pipeline\" event with the step outcomes\n" +
+		"A workflow lists steps: {\"steps\": [{\"name\": \"sum\", \"plugin\": \"addition\", \"params\": {\"num1\": \"1\", \"num2\": \"2\"}},\n" +
+		"                                   {\"plugin\": \"hello\", \"from\": {\"message\": \"sum.sum\"}, \"timeout\": \"30s\", \"continue_on_failure\": true}]}",
+	"pipeline.step_started": "Step %d/%d: %s (%s)",
+	"pipeline.step_failed":  "Step %s failed: %v",
+	"pipeline.step_skipped": "Step %s skipped: %v",
+	"pipeline.plugin_error": "plugin reported %s",
+	"pipeline.header":       "STEP\tPLUGIN\tSTATUS\tDURATION\tDETAIL",
+	"pipeline.row":          "%s\t%s\t%s\t%s\t%s",
+	"pipeline.failed":       "%d step(s) failed and %d were skipped, of %d",
+	"pipeline.completed":    "All %d step(s) succeeded",
+
+	// bench
+	"bench.usage": "Usage: plugin-app bench [-config path/to/config.json] [-n runs] [-c concurrency] [-warmup n] [-include-startup] [-timeout d] [-output text|json|yaml] <plugin-name> [param1=value1 ...]\n" +
+		"Executes the plugin repeatedly and reports latency percentiles and throughput",
+	"bench.invalid_counts":      "-n and -c must be at least 1 and -warmup not negative",
+	"bench.startup_concurrency": "-include-startup runs one plugin instance at a time, so it needs -c 1",
+	"bench.warmup_failed":       "Warm-up execution of %s failed: %v",
+	"bench.plugin_error":        "plugin reported %s",
+	"bench.started":             "Benchmarking %s: %d run(s), %d at once, startup included: %v",
+	"bench.canceled":            "Benchmark canceled after %d of %d run(s)",
+	"bench.header":              "%s: %d run(s), %d failed, concurrency %d, %s wall time",
+	"bench.latency_header":      "MIN\tMEAN\tP50\tP90\tP99\tMAX",
+	"bench.latency_row":         "%s\t%s\t%s\t%s\t%s\t%s",
+	"bench.throughput":          "Throughput: %.1f executions/s",
+	"bench.first_error":         "First failure: %v",
+
+	// test
+	"test.usage": "Usage: plugin-app test [-config path/to/config.json] [-timeout d] [-cancel-after d] [-cancel-grace d] [-output text|json|yaml] <plugin-name|plugin-binary> [param1=value1 ...]\n" +
+		"Checks a plugin against the protocol contract: its info, rejection of missing required parameters and of values that\n" +
+		"aren't allowed, execution and result, progress, cancellation and execution summary. The checks execute the plugin,\n" +
+		"asking for a dry run if it isn't side-effect free but supports dry_run; the parameters given are used as valid values",
+	"test.target_failed": "Cannot test %s: %v",
+	"test.header":        "Conformance of %s %s",
+	"test.table_header":  "CHECK\tSTATUS\tDETAIL",
+	"test.row":           "%s\t%s\t%s",
+	"test.failed":        "%d of %d check(s) failed",
+	"test.passed":        "The plugin conforms to the protocol contract",
+
+	// watch
+	"watch.usage": "Usage: plugin-app watch [-config path/to/config.json] -path <file-or-dir> [-path ...] [-debounce d] [-timeout d] [-no-daemon] [-output text|json|yaml] <plugin-name> [param1=value1 ...]\n" +
+		"Executes the plugin, then again whenever the watched paths change, canceling the execution still running",
+	"watch.started":   "Watching %s; %s is executed again when they change",
+	"watch.changed":   "Changed: %s",
+	"watch.canceled":  "Execution of %s canceled after %s",
+	"watch.failed":    "Execution of %s failed: %s",
+	"watch.succeeded": "Execution of %s finished in %s",
+	"watch.waiting":   "Waiting for changes",
+
+	// ps
+	"ps.usage": "Usage: plugin-app ps [-config path/to/config.json] [-socket path] [-a] [-timeout d] [-output text|json|yaml]\n" +
+		"Lists the plugins a running daemon manages, with their state, process or address, uptime, restarts and executions in flight",
+	"ps.header":    "PLUGIN\tSTATE\tPID/ADDRESS\tUPTIME\tRESTARTS\tEXECUTING\tQUEUED",
+	"ps.row":       "%s\t%s\t%s\t%s\t%d\t%s\t%d",
+	"ps.executing": "%d for %s",
+
+	// attach
+	"attach.usage": "Usage: plugin-app attach [-config path/to/config.json] [-socket path] [-output text|json|yaml] [execution-id]\n" +
+		"Streams an execution a run detached from in the daemon, from its start; Ctrl+\\ detaches again and Ctrl+C cancels it.\n" +
+		"Without an id, lists the executions that can be attached to",
+	"attach.not_found":      "No execution %s in the daemon; 'plugin-app attach' lists them",
+	"attach.not_started":    "The execution hasn't started in the daemon yet; press Ctrl+\\ again once it has",
+	"attach.attached":       "Attached to execution %s of %s",
+	"attach.header":         "ID\tPLUGIN\tSTARTED\tSTATE",
+	"attach.row":            "%s\t%s\t%s ago\t%s",
+	"attach.state_detached": "running, detached",
+	"attach.state_attached": "running, attached",
+	"attach.state_finished": "finished",
+	"attach.state_failed":   "failed: %s",
+
+	// completion
+	"completion.usage": "Usage: plugin-app completion bash|zsh|fish\n" +
+		"Load it with 'source <(plugin-app completion bash)' or from your shell's startup file",
+
+	// config
+	"config.usage": "Usage: plugin-app config lint [-config path/to/config.json] [-profile name] [-severity error|warning|info]\n" +
+		"       plugin-app config show [-config path/to/config.json] [-profile name] [-effective=false] [-output json|yaml]\n" +
+		"       plugin-app config migrate [-config path/to/config.json] [-write]\n" +
+		"Repeat -config to overlay files on the first one, later files taking precedence\n" +
+		"Suppress a rule for a plugin by adding its ID to the plugin's lint_ignore",
+	"config.migrate_current": "%s is in the current format already",
+	"config.migrated":        "Converted %s, %d plugin(s); the legacy file is kept as %s",
+	"config.profile":         "Profile: %s",
+	"config.lint_finding":    "%s: %s: [%s] %s",
+	"config.lint_clean":      "No findings",
+	"config.lint_failed":     "%d lint error(s)",
+
+	// regress
+	"regress.usage": "Usage: plugin-app regress [-config path/to/config.json] -baseline <plugin|binary> -candidate <plugin|binary> [-from-history n] [-timeout d] <plugin-name>\n" +
+		"Replays the latest executions of the plugin against both versions, which must be side-effect free or support dry_run",
+	"regress.no_history":     "No recorded executions of %s to replay",
+	"regress.start_failed":   "Failed to start %s %s: %v",
+	"regress.remote_binary":  "remote plugins can only be compared with other configured plugins",
+	"regress.header":         "Regression report for %s: %s (baseline) vs %s (candidate), %d parameter set(s)",
+	"regress.same":           "  #%d %s: same",
+	"regress.differs":        "  #%d %s: %d difference(s)",
+	"regress.diff":           "      %s",
+	"regress.summary_failed": "\n%d of %d parameter set(s) differ",
+	"regress.summary_ok":     "\nNo differences in %d parameter set(s)",
+}
+
+// messages is the catalog in effect, with the overrides of the loaded config applied
+var messages = shared.NewCatalog(defaultMessages)
+
+// msg formats a message from the catalog
+func msg(id string, args ...interface{}) string {
+	return messages.Sprintf(id, args...)
+}
+
+// loadConfig loads the configuration files and applies its message overrides and settings
+func loadConfig(files configFiles) *shared.AppConfig {
+	config, err := shared.LoadConfig(files.base(), files.overlays()...)
+	if err != nil {
+		fatal(msg("config.load_failed", err))
+	}
+	if err := messages.Apply(config.Messages); err != nil {
+		fatal(msg("config.messages_failed", err))
+	}
+	if config.Settings != nil {
+		if config.Settings.LogLevel != "" {
+			logLevel = config.Settings.LogLevel
+		}
+		artifactDir = config.Settings.OutputDir
+	}
+	return config
+}
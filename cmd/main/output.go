@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// Formats of -output
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+// outputFormat is the value of -output
+type outputFormat string
+
+func (f *outputFormat) String() string {
+	return string(*f)
+}
+
+func (f *outputFormat) Set(value string) error {
+	switch value {
+	case outputText, outputJSON, outputYAML:
+		*f = outputFormat(value)
+		return nil
+	}
+	return fmt.Errorf("must be %s, %s or %s", outputText, outputJSON, outputYAML)
+}
+
+// verbosity is how much of an execution a run shows, whatever the output format. Errors and the
+// result are always shown.
+type verbosity int
+
+const (
+	verbosityNormal  verbosity = iota // Output, progress, artifacts and plugin logs above debug
+	verbosityQuiet                    // Errors, warnings and the result only
+	verbosityVerbose                  // Debug logs as well
+)
+
+// verbosityLevel returns the verbosity -quiet and -verbose ask for
+func verbosityLevel(quiet, verbose bool) (verbosity, error) {
+	switch {
+	case quiet && verbose:
+		return verbosityNormal, errors.New(msg("run.verbosity_flags"))
+	case quiet:
+		return verbosityQuiet, nil
+	case verbose:
+		return verbosityVerbose, nil
+	}
+	return verbosityNormal, nil
+}
+
+// logLevel is the least severe plugin log a normal run shows, settings.log_level of the config
+var logLevel = shared.LogLevelInfo
+
+// logRank orders plugin log levels by severity; levels it doesn't know count as info
+func logRank(level string) int {
+	switch strings.ToLower(level) {
+	case "trace", shared.LogLevelDebug:
+		return 0
+	case shared.LogLevelWarn, "warning":
+		return 2
+	case shared.LogLevelError, "fatal":
+		return 3
+	}
+	return 1
+}
+
+// showsLog reports whether a plugin log message at level is shown
+func (v verbosity) showsLog(level string) bool {
+	switch v {
+	case verbosityVerbose:
+		return true
+	case verbosityQuiet:
+		return logRank(level) >= logRank(shared.LogLevelWarn)
+	}
+	return logRank(level) >= logRank(logLevel)
+}
+
+// print logs what the run itself has to say, unless it is quiet
+func (v verbosity) print(message string) {
+	if v != verbosityQuiet {
+		logFromCaller(slog.LevelInfo, message)
+	}
+}
+
+// logFromCaller logs message and attrs as the ui at level, from where the caller of its caller is
+func logFromCaller(level slog.Level, message string, attrs ...slog.Attr) {
+	h := slog.Default().Handler()
+	if !h.Enabled(context.Background(), level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // Skip Callers, logFromCaller and its caller
+	r := slog.NewRecord(time.Now(), level, message, pcs[0])
+	r.AddAttrs(attrs...)
+	h.Handle(context.Background(), r)
+}
+
+// formatter writes what commands report to stdout: as text by default, or as JSON or YAML for
+// scripts. Structured output keeps stdout to documents only; the rest goes to the log on stderr.
+type formatter struct {
+	format outputFormat
+	mu     sync.Mutex
+	w      io.Writer
+}
+
+// output is the formatter of the running command
+var output = &formatter{format: outputText, w: os.Stdout}
+
+// outputFlag adds -output to a command's flags
+func outputFlag(fs *flag.FlagSet) {
+	fs.Var(&output.format, "output", "Output format: text, json (one document per line for events) or yaml")
+}
+
+// structured reports whether documents are written instead of text
+func (f *formatter) structured() bool {
+	return f.format != outputText
+}
+
+// document writes a complete answer, such as a list, indented for reading
+func (f *formatter) document(v interface{}) {
+	if f.format == outputJSON {
+		data, err := json.MarshalIndent(v, "", "  ")
+		f.write(data, err)
+		return
+	}
+	data, err := shared.MarshalYAML(v)
+	f.write(data, err)
+}
+
+// event writes one of a stream of documents: a JSON line, or a YAML document after ---
+func (f *formatter) event(v interface{}) {
+	if f.format == outputJSON {
+		data, err := json.Marshal(v)
+		f.write(data, err)
+		return
+	}
+	data, err := shared.MarshalYAML(v)
+	if err == nil {
+		data = append([]byte("---\n"), data...)
+	}
+	f.write(data, err)
+}
+
+func (f *formatter) write(data []byte, err error) {
+	if err != nil {
+		log.Print(msg("warning", err))
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data, '\n')
+	}
+	f.w.Write(data)
+}
+
+// fatal reports an error the command can't recover from and exits 1. Structured output gets an
+// error event, so scripts see why the command failed.
+func fatal(message string) {
+	logFromCaller(slog.LevelError, message)
+	if output.structured() {
+		output.event(runEvent{Event: "error", Time: time.Now(), Error: message})
+	}
+	os.Exit(1)
+}
+
+// runEvent is one thing that happened during a run, as written with structured output
+type runEvent struct {
+	Event     string                 `json:"event"`
+	Plugin    string                 `json:"plugin,omitempty"`
+	Time      time.Time              `json:"time"`
+	Line      string                 `json:"line,omitempty"`
+	Progress  *progressEvent         `json:"progress,omitempty"`
+	Result    map[string]interface{} `json:"result,omitempty"`
+	Code      string                 `json:"code,omitempty"`
+	Level     string                 `json:"level,omitempty"`
+	Message   string                 `json:"message,omitempty"`
+	Details   string                 `json:"details,omitempty"`
+	Artifact  *artifactEvent         `json:"artifact,omitempty"`
+	Summary   *summaryDocument       `json:"summary,omitempty"`
+	Steps     []stepOutcome          `json:"steps,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Execution string                 `json:"execution,omitempty"` // Id of the execution, see shared.WithExecutionID
+}
+
+type progressEvent struct {
+	Percent      float32         `json:"percent"`
+	Stage        string          `json:"stage"`
+	Step         int32           `json:"step"`
+	TotalSteps   int32           `json:"total_steps"`
+	Rate         float64         `json:"rate,omitempty"`
+	StageElapsed shared.Duration `json:"stage_elapsed,omitempty"`
+	Remaining    shared.Duration `json:"remaining,omitempty"`
+}
+
+type artifactEvent struct {
+	Name string `json:"name"`
+	Size int    `json:"size"`
+	Path string `json:"path,omitempty"` // Where the artifact was saved, if settings.output_dir is set
+}
+
+// summaryDocument is a plugin's execution summary
+type summaryDocument struct {
+	Plugin     string             `json:"plugin"`
+	DurationMS float64            `json:"duration_ms"`
+	Success    bool               `json:"success"`
+	Error      string             `json:"error,omitempty"`
+	Metadata   map[string]string  `json:"metadata"`
+	Metrics    map[string]float64 `json:"metrics"`
+}
+
+// listDocument is an entry of -list
+type listDocument struct {
+	Name         string            `json:"name"`
+	Type         shared.PluginType `json:"type"`
+	Description  string            `json:"description"`
+	Tags         []string          `json:"tags,omitempty"`
+	DiscoveredIn string            `json:"discovered_in,omitempty"` // plugin_dirs entry, for plugins without a config entry
+}
+
+// listPlugins returns the configured and discovered plugins having every one of tags, in name
+// order. Discovered plugins are described by what they report, which may take starting them once.
+func pluginList(config *shared.AppConfig, tags []string) []listDocument {
+	plugins := make([]listDocument, 0, len(config.Plugins))
+	for _, name := range config.Tagged(tags) {
+		plugin := config.Plugins[name]
+		doc := listDocument{Name: name, Type: plugin.Type, Description: plugin.Description, Tags: plugin.Tags, DiscoveredIn: config.DiscoveredIn(name)}
+		if doc.DiscoveredIn != "" && doc.Description == "" {
+			if info := completionInfo(config, name, plugin); info != nil {
+				doc.Description = info.Description
+			}
+		}
+		plugins = append(plugins, doc)
+	}
+	return plugins
+}
+
+// infoDocument is what -info shows about a plugin
+type infoDocument struct {
+	Name             string                      `json:"name"`
+	Version          string                      `json:"version"`
+	ExpectedVersion  string                      `json:"expected_version,omitempty"`
+	Description      string                      `json:"description"`
+	Type             shared.PluginType           `json:"type"`
+	ProtocolVersion  int                         `json:"protocol_version"`
+	Features         []string                    `json:"features,omitempty"`
+	Command          string                      `json:"command,omitempty"`
+	Addresses        []string                    `json:"addresses,omitempty"`
+	LoadBalancing    string                      `json:"load_balancing,omitempty"`
+	Proxy            string                      `json:"proxy,omitempty"`
+	WorkingDir       string                      `json:"workdir,omitempty"`
+	Environment      map[string]string           `json:"env,omitempty"`
+	Parameters       []paramDocument             `json:"parameters"`
+	ResultSchema     []resultFieldDocument       `json:"result_schema,omitempty"`
+	ResultValidation shared.ResultValidationMode `json:"result_validation,omitempty"`
+}
+
+type paramDocument struct {
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Type          string   `json:"type,omitempty"`
+	Required      bool     `json:"required"`
+	Secret        bool     `json:"secret,omitempty"`
+	Default       string   `json:"default,omitempty"`
+	ConfigDefault string   `json:"config_default,omitempty"`
+	AllowedValues []string `json:"allowed_values,omitempty"`
+}
+
+type resultFieldDocument struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required"`
+}
+
+// pluginInfoDocument returns what -info shows about a plugin, with secrets masked as in text
+func pluginInfoDocument(info *shared.PluginInfo, config shared.PluginConfig) infoDocument {
+	doc := infoDocument{
+		Name:             info.Name,
+		Version:          info.Version,
+		ExpectedVersion:  config.ExpectedVersion,
+		Description:      info.Description,
+		Type:             config.Type,
+		ProtocolVersion:  shared.PluginProtocol(info),
+		Features:         info.Features,
+		Environment:      config.Environment,
+		Parameters:       []paramDocument{},
+		ResultValidation: config.ResultValidation,
+	}
+	if config.Type == shared.PluginTypeCommand {
+		doc.Command = config.Command
+	}
+	if config.IsRemote() {
+		doc.Addresses = config.Addresses
+		if config.Address != "" {
+			doc.Addresses = []string{config.Address}
+		}
+		doc.LoadBalancing = config.LoadBalancing
+		if config.Proxy != "" {
+			doc.Proxy = shared.RedactProxyURL(config.Proxy)
+		}
+	} else {
+		doc.WorkingDir = config.WorkingDir
+	}
+
+	for _, name := range sortedKeys(info.ParameterSchema) {
+		spec := info.ParameterSchema[name]
+		param := paramDocument{
+			Name:        name,
+			Description: spec.Description,
+			Type:        spec.Type,
+			Required:    spec.Required,
+			Secret:      spec.IsSecret(),
+		}
+		if spec.DefaultValue != "" {
+			param.Default = displayParamValue(spec, spec.DefaultValue)
+		}
+		if configDefault, ok := config.Defaults[name]; ok {
+			param.ConfigDefault = displayParamValue(spec, configDefault)
+		}
+		if !spec.IsSecret() {
+			param.AllowedValues = spec.AllowedValues
+		}
+		doc.Parameters = append(doc.Parameters, param)
+	}
+	for _, name := range sortedKeys(info.ResultSchema) {
+		spec := info.ResultSchema[name]
+		doc.ResultSchema = append(doc.ResultSchema, resultFieldDocument{name, spec.Description, spec.Type, spec.Required})
+	}
+	return doc
+}
+
+// statsDocument is a plugin's entry of stats
+type statsDocument struct {
+	Plugin      string           `json:"plugin"`
+	Runs        int              `json:"runs"`
+	Successes   int              `json:"successes"`
+	SuccessRate float64          `json:"success_rate"`
+	P50         shared.Duration  `json:"p50,omitempty"`
+	P95         shared.Duration  `json:"p95,omitempty"`
+	LastFailure *failureDocument `json:"last_failure,omitempty"`
+}
+
+type failureDocument struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+func pluginStatsDocument(stats shared.PluginStats) statsDocument {
+	doc := statsDocument{
+		Plugin:      stats.Plugin,
+		Runs:        stats.Runs,
+		Successes:   stats.Successes,
+		SuccessRate: stats.SuccessRate(),
+		P50:         shared.Duration(stats.P50),
+		P95:         shared.Duration(stats.P95),
+	}
+	if f := stats.LastFailure; f != nil {
+		doc.LastFailure = &failureDocument{f.Time, f.Error}
+	}
+	return doc
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
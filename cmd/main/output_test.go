@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+func TestOutputFormat_Set(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{value: "text"},
+		{value: "json"},
+		{value: "yaml"},
+		{value: "xml", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var f outputFormat
+			err := f.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && f.String() != tt.value {
+				t.Errorf("String() = %q, want %q", f.String(), tt.value)
+			}
+		})
+	}
+}
+
+func TestFormatter_document(t *testing.T) {
+	doc := []listDocument{{Name: "hello", Type: shared.PluginTypeBinary, Description: "Says hello"}}
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		f := &formatter{format: outputJSON, w: &buf}
+		f.document(doc)
+
+		var got []listDocument
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("document is not JSON: %v\n%s", err, buf.String())
+		}
+		if !reflect.DeepEqual(got, doc) {
+			t.Errorf("document = %+v, want %+v", got, doc)
+		}
+		if !strings.HasSuffix(buf.String(), "\n") {
+			t.Errorf("document %q does not end with a newline", buf.String())
+		}
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		var buf bytes.Buffer
+		f := &formatter{format: outputYAML, w: &buf}
+		f.document(doc)
+
+		got := buf.String()
+		if strings.HasPrefix(got, "---") {
+			t.Errorf("document %q starts with a separator", got)
+		}
+		for _, want := range []string{"name: hello", "type: binary", "description: Says hello"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("document %q is missing %q", got, want)
+			}
+		}
+	})
+}
+
+func TestFormatter_event(t *testing.T) {
+	events := []runEvent{
+		{Event: "output", Plugin: "hello", Time: time.Unix(0, 0).UTC(), Line: "Hello"},
+		{Event: "result", Plugin: "hello", Time: time.Unix(1, 0).UTC(), Result: map[string]interface{}{"greeting": "Hello"}},
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		f := &formatter{format: outputJSON, w: &buf}
+		for _, e := range events {
+			f.event(e)
+		}
+
+		// Each event is a JSON document on its own line
+		scanner := bufio.NewScanner(&buf)
+		var got []runEvent
+		for scanner.Scan() {
+			var e runEvent
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				t.Fatalf("line %q is not a JSON event: %v", scanner.Text(), err)
+			}
+			got = append(got, e)
+		}
+		if len(got) != len(events) {
+			t.Fatalf("got %d events, want %d", len(got), len(events))
+		}
+		if got[0].Line != "Hello" || got[1].Result["greeting"] != "Hello" {
+			t.Errorf("events = %+v, want %+v", got, events)
+		}
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		var buf bytes.Buffer
+		f := &formatter{format: outputYAML, w: &buf}
+		for _, e := range events {
+			f.event(e)
+		}
+
+		docs := strings.Split(buf.String(), "---\n")
+		if docs[0] != "" || len(docs) != len(events)+1 {
+			t.Fatalf("events are not separate YAML documents:\n%s", buf.String())
+		}
+		if !strings.Contains(docs[1], "event: output") || !strings.Contains(docs[2], "event: result") {
+			t.Errorf("events in the wrong documents:\n%s", buf.String())
+		}
+	})
+}
+
+func TestFormatter_structured(t *testing.T) {
+	for format, want := range map[outputFormat]bool{outputText: false, outputJSON: true, outputYAML: true} {
+		f := &formatter{format: format}
+		if got := f.structured(); got != want {
+			t.Errorf("structured() with %s = %v, want %v", format, got, want)
+		}
+	}
+}
+
+func TestPluginInfoDocument(t *testing.T) {
+	info := &shared.PluginInfo{
+		Name:    "login",
+		Version: "1.0.0",
+		ParameterSchema: map[string]shared.ParameterSpec{
+			"user":     {Description: "User name", Required: true, AllowedValues: []string{"alice", "bob"}},
+			"password": {Description: "Password", Type: shared.ParamTypeSecret, DefaultValue: "hunter2", AllowedValues: []string{"hunter2"}},
+		},
+	}
+	config := shared.PluginConfig{
+		Type:     shared.PluginTypeBinary,
+		Defaults: map[string]string{"password": "swordfish", "user": "alice"},
+	}
+
+	doc := pluginInfoDocument(info, config)
+	if len(doc.Parameters) != 2 || doc.Parameters[0].Name != "password" || doc.Parameters[1].Name != "user" {
+		t.Fatalf("Parameters = %+v, want password and user in order", doc.Parameters)
+	}
+	password, user := doc.Parameters[0], doc.Parameters[1]
+	if !password.Secret || password.Default != shared.SecretMask || password.ConfigDefault != shared.SecretMask {
+		t.Errorf("secret parameter = %+v, want its defaults masked", password)
+	}
+	if password.AllowedValues != nil {
+		t.Errorf("secret parameter allowed values = %v, want none", password.AllowedValues)
+	}
+	if user.ConfigDefault != "alice" || len(user.AllowedValues) != 2 {
+		t.Errorf("parameter = %+v, want its config default and allowed values", user)
+	}
+}
+
+func TestVerbosityLevel(t *testing.T) {
+	tests := []struct {
+		quiet, verbose bool
+		want           verbosity
+		wantErr        bool
+	}{
+		{want: verbosityNormal},
+		{quiet: true, want: verbosityQuiet},
+		{verbose: true, want: verbosityVerbose},
+		{quiet: true, verbose: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := verbosityLevel(tt.quiet, tt.verbose)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("verbosityLevel(%v, %v) error = %v, wantErr %v", tt.quiet, tt.verbose, err, tt.wantErr)
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("verbosityLevel(%v, %v) = %d, want %d", tt.quiet, tt.verbose, got, tt.want)
+		}
+	}
+}
+
+func TestVerbosity_showsLog(t *testing.T) {
+	tests := []struct {
+		level                  string
+		quiet, normal, verbose bool
+	}{
+		{level: "debug", verbose: true},
+		{level: "info", normal: true, verbose: true},
+		{level: "warn", quiet: true, normal: true, verbose: true},
+		{level: "ERROR", quiet: true, normal: true, verbose: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			for v, want := range map[verbosity]bool{verbosityQuiet: tt.quiet, verbosityNormal: tt.normal, verbosityVerbose: tt.verbose} {
+				if got := v.showsLog(tt.level); got != want {
+					t.Errorf("showsLog(%q) at verbosity %d = %v, want %v", tt.level, v, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestVerbosity_showsLog_logLevel(t *testing.T) {
+	saved := logLevel
+	logLevel = shared.LogLevelWarn
+	defer func() { logLevel = saved }()
+
+	if verbosityNormal.showsLog("info") || !verbosityNormal.showsLog("warning") {
+		t.Error("a normal run with log_level warn should show warnings only")
+	}
+	if !verbosityVerbose.showsLog("debug") {
+		t.Error("-verbose should show debug logs whatever the log_level")
+	}
+}
+
+func TestSaveArtifact(t *testing.T) {
+	saved := artifactDir
+	artifactDir = t.TempDir()
+	defer func() { artifactDir = saved }()
+
+	for i, chunk := range []string{"first ", "second"} {
+		if _, err := saveArtifact("hello", "", "../report.txt", []byte(chunk), i == 0); err != nil {
+			t.Fatalf("saveArtifact() error = %v", err)
+		}
+	}
+	path, err := saveArtifact("hello", "", "../report.txt", nil, false)
+	if err != nil {
+		t.Fatalf("saveArtifact() error = %v", err)
+	}
+	if want := filepath.Join(artifactDir, "hello", "report.txt"); path != want {
+		t.Errorf("saveArtifact() path = %q, want %q", path, want)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "first second" {
+		t.Errorf("artifact = %q, want the chunks in order", data)
+	}
+
+	// Without an id, another execution's artifact of the same name starts over
+	if _, err := saveArtifact("hello", "", "report.txt", []byte("again"), true); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "again" {
+		t.Errorf("artifact = %q, want it replaced", data)
+	}
+
+	// With one, it is named after the execution and leaves the others alone
+	named, err := saveArtifact("hello", "a1b2c3", "report.txt", []byte("mine"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(artifactDir, "hello", "a1b2c3-report.txt"); named != want {
+		t.Errorf("saveArtifact() path = %q, want %q", named, want)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "again" {
+		t.Errorf("artifact = %q, want it untouched", data)
+	}
+}
+
+func TestOutputHandler_quiet(t *testing.T) {
+	var buf bytes.Buffer
+	saved := output
+	output = &formatter{format: outputJSON, w: &buf}
+	defer func() { output = saved }()
+
+	capture := shared.NewOutputCapture("", nil)
+	defer capture.Close()
+	h := &outputHandler{pluginName: "hello", redactor: &shared.Redactor{}, capture: capture, verbosity: verbosityQuiet}
+	h.OnOutput("working")
+	h.OnProgress(shared.Progress{PercentComplete: 50, Stage: "greeting"})
+	h.OnLog("info", "starting")
+	h.OnLog("warn", "slow")
+	h.OnArtifact("report.txt", []byte("data"), true)
+	h.OnError("INVALID", "bad language", "")
+	h.OnResult(map[string]interface{}{"greeting": "Hello"})
+
+	var got []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var e runEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("line %q is not a JSON event: %v", scanner.Text(), err)
+		}
+		got = append(got, e.Event)
+	}
+	if want := []string{"log", "plugin_error", "result"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	if h.outputCount != 1 || capture.Lines() != 1 {
+		t.Errorf("output counted %d and captured %d times, want the line kept though not shown", h.outputCount, capture.Lines())
+	}
+}
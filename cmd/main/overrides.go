@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// envOverrides collects repeated -env KEY=VAL flags; a later value for a key replaces an earlier one
+type envOverrides map[string]string
+
+func (e *envOverrides) String() string {
+	var parts []string
+	for k, v := range *e {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (e *envOverrides) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return errors.New(msg("run.invalid_env", value))
+	}
+	if *e == nil {
+		*e = make(envOverrides)
+	}
+	(*e)[key] = val
+	return nil
+}
+
+// runOverrides returns the -env and -workdir overrides for this run, with the working directory
+// relative to the current one, and whether there are any
+func runOverrides(env envOverrides, workdir string) (shared.RunOverrides, bool, error) {
+	overrides := shared.RunOverrides{Environment: env}
+	if workdir != "" {
+		dir, err := filepath.Abs(workdir)
+		if err != nil {
+			return overrides, false, err
+		}
+		overrides.WorkingDir = dir
+	}
+	return overrides, len(env) > 0 || workdir != "", nil
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// parallelPlugin is one plugin of a parallel run, prepared for execution
+type parallelPlugin struct {
+	name       string
+	config     shared.PluginConfig
+	plugin     shared.PluginInterface
+	info       *shared.PluginInfo
+	params     map[string]string
+	redactor   *shared.Redactor
+	handler    *outputHandler
+	capture    *shared.OutputCapture
+	credential string
+	viaDaemon  bool // Executed by the daemon's warm plugin, which records it in the history itself
+	preferred  bool // A remote plugin connected to the -prefer address first
+}
+
+// splitParallelArgs separates plugin names from parameters. Parameters apply to every plugin
+// unless prefixed with the name of one, as in addition.num1=5.
+func splitParallelArgs(args []string) ([]string, map[string]map[string]string) {
+	var names []string
+	selected := make(map[string]bool)
+	var assignments []string
+	for _, arg := range args {
+		if strings.Contains(arg, "=") {
+			assignments = append(assignments, arg)
+			continue
+		}
+		names = append(names, arg)
+		selected[arg] = true
+	}
+
+	params := make(map[string]map[string]string, len(names))
+	for _, name := range names {
+		params[name] = make(map[string]string)
+	}
+	assigned := parseParams(assignments)
+	for key, value := range assigned {
+		if prefix, _, ok := strings.Cut(key, "."); ok && selected[prefix] {
+			continue
+		}
+		for _, name := range names {
+			params[name][key] = value
+		}
+	}
+	// Plugin-specific values win over shared ones
+	for key, value := range assigned {
+		if prefix, param, ok := strings.Cut(key, "."); ok && selected[prefix] {
+			params[prefix][param] = value
+		}
+	}
+	return names, params
+}
+
+// tagList collects repeated -tag flags
+type tagList []string
+
+func (t *tagList) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *tagList) Set(value string) error {
+	*t = append(*t, value)
+	return nil
+}
+
+// lockedWriter serializes writes from concurrent executions
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// runParallel executes several plugins concurrently, reports each one's summary and exits 1 if
+// any of them failed. Plugins a running daemon keeps warm are executed through it unless noDaemon
+// is set; prefer applies to the remote plugins having that address, which connect on their own.
+func runParallel(ctx context.Context, config *shared.AppConfig, readOnly, noDaemon bool, prefer, token string, timeout time.Duration, deadline time.Time, level verbosity, args []string) {
+	names, params := splitParallelArgs(args)
+	if len(names) == 0 {
+		fatal(msg("run.parallel_no_plugins"))
+	}
+
+	pipedOut := isPiped(os.Stdout)
+	manager := shared.NewPluginManager(config)
+	defer manager.StopAll()
+	if readOnly {
+		manager.SetReadOnly(true)
+	}
+	manager.SetProcessExitHandler(func(exit *shared.ProcessExit) {
+		log.Print(msg("run.plugin_exited", exit))
+	})
+	killOrphans(manager)
+	stdout := &lockedWriter{w: os.Stdout}
+
+	monitor := shared.NewMemoryMonitor(config.Memory)
+	monitor.OnChange(func(pressure bool) {
+		if pressure {
+			log.Print(msg("run.memory_pressure", monitor.Limit()>>20))
+		} else {
+			log.Print(msg("run.memory_recovered"))
+		}
+	})
+	monitor.Start(ctx)
+
+	// Everything is started and checked before anything is executed
+	useDaemon := !noDaemon && !manager.ReadOnly()
+	plugins := make([]*parallelPlugin, 0, len(names))
+	seen := make(map[string]bool)
+	preferred := false
+	for _, name := range names {
+		if seen[name] {
+			manager.StopAll()
+			fatal(msg("run.parallel_duplicate", name))
+		}
+		seen[name] = true
+
+		p, err := prepareParallelPlugin(ctx, config, manager, name, params[name], token, prefer, useDaemon, monitor)
+		if err != nil {
+			manager.StopAll()
+			fatal(msg("error", err))
+		}
+		defer p.capture.Close()
+		if p.viaDaemon {
+			defer p.plugin.Close()
+		}
+		p.handler.verbosity = level
+		if pipedOut && !output.structured() {
+			p.handler.resultWriter = stdout
+		}
+		preferred = preferred || p.preferred
+		plugins = append(plugins, p)
+	}
+	if prefer != "" && !preferred {
+		manager.StopAll()
+		fatal(msg("run.parallel_prefer", prefer))
+	}
+
+	// The deadline leaves whatever time is left once every plugin is ready
+	timeout, err := shared.TimeoutUntil(timeout, deadline, time.Now())
+	if err != nil {
+		manager.StopAll()
+		fatal(msg("run.invalid_deadline", err))
+	}
+	executions := make([]shared.ParallelExecution, len(plugins))
+	for i, p := range plugins {
+		executions[i] = shared.ParallelExecution{Name: p.name, Params: p.params, Handler: p.handler, Credential: p.credential, ID: p.handler.executionID, Timeout: timeout}
+		if p.viaDaemon {
+			executions[i].Plugin = p.plugin
+		}
+	}
+	results := manager.ExecuteParallel(ctx, executions)
+
+	failed := 0
+	for i, result := range results {
+		p := plugins[i]
+		execution := &finishedExecution{
+			name:     p.name,
+			config:   p.config,
+			plugin:   p.plugin,
+			info:     p.info,
+			params:   p.params,
+			redactor: p.redactor,
+			handler:  p.handler,
+			start:    result.Start.UnixNano(),
+			end:      result.End.UnixNano(),
+			err:      result.Err,
+			metadata: outcomeMetadata(result.Err, p.capture),
+			usage:    result.Usage,
+		}
+		execution.report(config, !p.viaDaemon)
+		if result.Err != nil {
+			failed++
+		}
+	}
+
+	log.Print(msg("run.parallel_summary", len(results)-failed, len(results)))
+	for i, result := range results {
+		duration := result.End.Sub(result.Start).Round(time.Millisecond)
+		if result.Err != nil {
+			log.Print(msg("run.parallel_failed", result.Name, duration, plugins[i].redactor.String(result.Err.Error())))
+		} else {
+			log.Print(msg("run.parallel_succeeded", result.Name, duration))
+		}
+	}
+
+	if failed > 0 {
+		manager.StopAll()
+		os.Exit(1)
+	}
+	level.print(msg("run.completed"))
+}
+
+// prepareParallelPlugin connects to the daemon's warm plugin if useDaemon is set and a daemon is
+// running, or else starts the plugin, and resolves everything its execution needs
+func prepareParallelPlugin(ctx context.Context, config *shared.AppConfig, manager *shared.PluginManager, name string, params map[string]string, token, prefer string, useDaemon bool, monitor *shared.MemoryMonitor) (*parallelPlugin, error) {
+	pluginConfig, err := config.GetPluginConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := pluginConfig.Validate(); err != nil {
+		return nil, errors.New(msg("run.invalid_plugin", name, err))
+	}
+
+	// The daemon picked its own address for a remote plugin, so a preferred one connects on its own
+	preferred := prefer != "" && pluginConfig.IsRemote() && pluginConfig.Prefer(prefer) == nil
+	var plugin shared.PluginInterface
+	viaDaemon := false
+	if useDaemon && !preferred {
+		plugin, err = shared.ConnectDaemon(ctx, config, name)
+		if err == nil {
+			viaDaemon = true
+			log.Print(msg("run.daemon", name, config.DaemonSocketPath()))
+		} else if !errors.Is(err, shared.ErrNoDaemon) {
+			log.Print(msg("warning", err))
+		}
+	}
+	if plugin == nil {
+		if err := manager.StartPlugin(name, pluginConfig); err != nil {
+			return nil, errors.New(msg("run.start_failed", name, err))
+		}
+		log.Print(msg("run.started", name, pluginConfig.Type))
+		if plugin, err = manager.GetPlugin(name); err != nil {
+			return nil, errors.New(msg("run.get_plugin_failed", name, err))
+		}
+	}
+	info, err := plugin.GetInfo(ctx)
+	if err != nil {
+		return nil, errors.New(msg("run.info_failed", err))
+	}
+	if err := checkSchemaChanges(config, name, info); err != nil {
+		return nil, errors.New(msg("run.schema_refused", name, err))
+	}
+
+	mergeDefaults(params, info, pluginConfig)
+	redactor, err := shared.NewRedactor(config.Redaction, info.ParameterSchema, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// -token is meant for the remote plugins of the run
+	if !pluginConfig.IsRemote() {
+		token = ""
+	}
+	credential, err := resolveCredential(ctx, token, name, pluginConfig)
+	if err != nil {
+		return nil, err
+	}
+	redactor.Mask(credential)
+	for _, secret := range pluginConfig.AuthHeaders.Secrets() {
+		redactor.Mask(secret)
+	}
+
+	executionID, err := shared.NewExecutionID()
+	if err != nil {
+		return nil, err
+	}
+	capture := shared.NewOutputCapture(config.SpillPath(), monitor)
+	return &parallelPlugin{
+		name:       name,
+		config:     pluginConfig,
+		plugin:     plugin,
+		info:       info,
+		params:     params,
+		redactor:   redactor,
+		handler:    &outputHandler{pluginName: name, executionID: executionID, redactor: redactor, capture: capture, monitor: monitor},
+		capture:    capture,
+		credential: credential,
+		viaDaemon:  viaDaemon,
+		preferred:  preferred,
+	}, nil
+}
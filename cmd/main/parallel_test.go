@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitParallelArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantNames  []string
+		wantParams map[string]map[string]string
+	}{
+		{
+			name:       "Shared parameters",
+			args:       []string{"hello", "addition", "name=World"},
+			wantNames:  []string{"hello", "addition"},
+			wantParams: map[string]map[string]string{"hello": {"name": "World"}, "addition": {"name": "World"}},
+		},
+		{
+			name:      "Plugin-specific parameters win",
+			args:      []string{"addition.num1=5", "hello", "num1=1", "addition", "num2=2"},
+			wantNames: []string{"hello", "addition"},
+			wantParams: map[string]map[string]string{
+				"hello":    {"num1": "1", "num2": "2"},
+				"addition": {"num1": "5", "num2": "2"},
+			},
+		},
+		{
+			name:       "Prefix of a plugin not named is a shared parameter",
+			args:       []string{"hello", "other.key=v"},
+			wantNames:  []string{"hello"},
+			wantParams: map[string]map[string]string{"hello": {"other.key": "v"}},
+		},
+		{
+			name:       "Value containing =",
+			args:       []string{"hello", "hello.query=a=b"},
+			wantNames:  []string{"hello"},
+			wantParams: map[string]map[string]string{"hello": {"query": "a=b"}},
+		},
+		{
+			name:       "No plugins",
+			args:       []string{"num1=1"},
+			wantParams: map[string]map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, params := splitParallelArgs(tt.args)
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("names = %v, want %v", names, tt.wantNames)
+			}
+			if !reflect.DeepEqual(params, tt.wantParams) {
+				t.Errorf("params = %v, want %v", params, tt.wantParams)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// isPiped reports whether the file is a pipe or regular file rather than a terminal
+func isPiped(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice == 0
+}
+
+// stdinMapping wires a field of the upstream result into a parameter
+type stdinMapping struct {
+	field string
+	param string
+}
+
+// stdinMappings collects repeated -from-stdin flags
+type stdinMappings []stdinMapping
+
+func (m *stdinMappings) String() string {
+	var parts []string
+	for _, mapping := range *m {
+		parts = append(parts, mapping.field+":"+mapping.param)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (m *stdinMappings) Set(value string) error {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return errors.New(msg("pipe.invalid_mapping", value))
+	}
+	*m = append(*m, stdinMapping{field: parts[0], param: parts[1]})
+	return nil
+}
+
+// pipedResult is the line written to stdout for each structured result when output is piped
+type pipedResult struct {
+	Plugin string                 `json:"plugin"`
+	Result map[string]interface{} `json:"result"`
+}
+
+// readPipedResult reads the results written by an upstream invocation and returns the last one
+func readPipedResult(r io.Reader) (map[string]interface{}, error) {
+	var last map[string]interface{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var piped pipedResult
+		if err := json.Unmarshal([]byte(line), &piped); err != nil {
+			return nil, errors.New(msg("pipe.invalid_result", err))
+		}
+		last = piped.Result
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New(msg("pipe.read_failed", err))
+	}
+	if last == nil {
+		return nil, errors.New(msg("pipe.no_result"))
+	}
+	return last, nil
+}
+
+// applyStdinMappings copies the mapped result fields into params unless they were set explicitly
+func applyStdinMappings(params map[string]string, result map[string]interface{}, mappings stdinMappings) error {
+	for _, mapping := range mappings {
+		if _, exists := params[mapping.param]; exists {
+			continue
+		}
+		value, ok := result[mapping.field]
+		if !ok {
+			return errors.New(msg("pipe.missing_field", mapping.field))
+		}
+		params[mapping.param] = shared.ResultValueString(value)
+	}
+	return nil
+}
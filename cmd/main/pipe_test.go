@@ -0,0 +1,135 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStdinMappings_Set(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    stdinMappings
+		wantErr bool
+	}{
+		{
+			name:   "Single mapping",
+			values: []string{"result:num1"},
+			want:   stdinMappings{{field: "result", param: "num1"}},
+		},
+		{
+			name:   "Repeated flag",
+			values: []string{"sum:num1", "sum:num2"},
+			want:   stdinMappings{{field: "sum", param: "num1"}, {field: "sum", param: "num2"}},
+		},
+		{
+			name:   "Colon in the parameter",
+			values: []string{"url:target:port"},
+			want:   stdinMappings{{field: "url", param: "target:port"}},
+		},
+		{name: "No separator", values: []string{"result"}, wantErr: true},
+		{name: "Key=value form", values: []string{"result=num1"}, wantErr: true},
+		{name: "Missing field", values: []string{":num1"}, wantErr: true},
+		{name: "Missing parameter", values: []string{"result:"}, wantErr: true},
+		{name: "Empty", values: []string{""}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got stdinMappings
+			var err error
+			for _, value := range tt.values {
+				if err = got.Set(value); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), tt.values[len(tt.values)-1]) {
+					t.Errorf("Set() error = %v, want it to name the mapping", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Set() = %+v, want %+v", got, tt.want)
+			}
+			if s := strings.Join(tt.values, ","); got.String() != s {
+				t.Errorf("String() = %q, want %q", got.String(), s)
+			}
+		})
+	}
+}
+
+func TestReadPipedResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:  "Last result wins",
+			input: `{"plugin":"addition","result":{"sum":3}}` + "\n\n" + `{"plugin":"addition","result":{"sum":7}}` + "\n",
+			want:  map[string]interface{}{"sum": float64(7)},
+		},
+		{name: "Not JSON", input: "Hello\n", wantErr: true},
+		{name: "No result", input: "\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readPipedResult(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readPipedResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readPipedResult() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyStdinMappings(t *testing.T) {
+	result := map[string]interface{}{"sum": float64(3), "text": "three"}
+	tests := []struct {
+		name     string
+		params   map[string]string
+		mappings stdinMappings
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "Fields become parameters",
+			params:   map[string]string{},
+			mappings: stdinMappings{{field: "sum", param: "num1"}, {field: "text", param: "message"}},
+			want:     map[string]string{"num1": "3", "message": "three"},
+		},
+		{
+			name:     "Explicit parameters are kept",
+			params:   map[string]string{"num1": "5"},
+			mappings: stdinMappings{{field: "sum", param: "num1"}},
+			want:     map[string]string{"num1": "5"},
+		},
+		{
+			name:     "Missing field",
+			params:   map[string]string{},
+			mappings: stdinMappings{{field: "product", param: "num1"}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := applyStdinMappings(tt.params, result, tt.mappings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyStdinMappings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(tt.params, tt.want) {
+				t.Errorf("params = %v, want %v", tt.params, tt.want)
+			}
+		})
+	}
+}
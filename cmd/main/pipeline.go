@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// Outcomes of a pipeline step
+const (
+	stepSucceeded = "succeeded"
+	stepFailed    = "failed"
+	stepSkipped   = "skipped"
+)
+
+// stepOutcome is how one step of a pipeline went
+type stepOutcome struct {
+	Step     string          `json:"step"`
+	Plugin   string          `json:"plugin"`
+	Status   string          `json:"status"`
+	Duration shared.Duration `json:"duration"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// runPipeline implements the pipeline command, which executes the steps of a workflow file in
+// order. A step fails if its execution fails or the plugin reports an error. A failing step
+// stops the pipeline unless it continues on failure; the steps after it are skipped, and so are
+// steps whose inputs come from a step without a result. Steps go through the plugins a running
+// daemon keeps warm unless -no-daemon is given.
+func runPipeline(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("pipeline", flag.ExitOnError)
+	configPath := configFlag(fs)
+	profileFlag(fs)
+	noDaemon := fs.Bool("no-daemon", false, "Start the plugins of the steps even if a running daemon keeps them warm")
+	outputFlag(fs)
+	logFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Println(msg("pipeline.usage"))
+		os.Exit(1)
+	}
+	config := loadConfig(*configPath)
+	workflow, err := shared.LoadWorkflow(fs.Arg(0), config)
+	if err != nil {
+		fatal(msg("error", err))
+	}
+
+	manager := shared.NewPluginManager(config)
+	defer manager.StopAll()
+	manager.SetProcessExitHandler(func(exit *shared.ProcessExit) {
+		log.Print(msg("run.plugin_exited", exit))
+	})
+	killOrphans(manager)
+	useDaemon := !*noDaemon && !manager.ReadOnly()
+
+	monitor := shared.NewMemoryMonitor(config.Memory)
+	monitor.Start(ctx)
+
+	outcomes := make([]stepOutcome, len(workflow.Steps))
+	results := make(map[string]map[string]interface{})
+	stopped := false
+	for i, step := range workflow.Steps {
+		outcome := &outcomes[i]
+		outcome.Step, outcome.Plugin = step.Name, step.Plugin
+		if stopped || ctx.Err() != nil {
+			outcome.Status = stepSkipped
+			continue
+		}
+
+		params, err := step.StepParams(results)
+		if err != nil {
+			outcome.Status, outcome.Error = stepSkipped, err.Error()
+			log.Print(msg("pipeline.step_skipped", step.Name, err))
+			continue
+		}
+
+		log.Print(msg("pipeline.step_started", i+1, len(workflow.Steps), step.Name, step.Plugin))
+		start := time.Now()
+		result, err := runPipelineStep(ctx, config, manager, useDaemon, monitor, step, params)
+		outcome.Duration = shared.Duration(time.Since(start))
+		if err != nil {
+			outcome.Status, outcome.Error = stepFailed, err.Error()
+			log.Print(msg("pipeline.step_failed", step.Name, err))
+			stopped = !step.ContinueOnFailure
+			continue
+		}
+		outcome.Status = stepSucceeded
+		if result != nil {
+			results[step.Name] = result
+		}
+	}
+
+	failed, skipped := reportPipeline(outcomes)
+	if failed+skipped > 0 {
+		manager.StopAll()
+		os.Exit(1)
+	}
+}
+
+// reportPipeline shows how each step went and returns the number of failed and skipped steps.
+// Structured output gets the outcomes as a final event, after those of the steps' executions.
+func reportPipeline(outcomes []stepOutcome) (failed, skipped int) {
+	for _, outcome := range outcomes {
+		switch outcome.Status {
+		case stepFailed:
+			failed++
+		case stepSkipped:
+			skipped++
+		}
+	}
+	if output.structured() {
+		output.event(runEvent{Event: "pipeline", Time: time.Now(), Steps: outcomes})
+	} else {
+		w := tabwriter.NewWriter(output.w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, msg("pipeline.header"))
+		for _, outcome := range outcomes {
+			duration := "-"
+			if outcome.Status != stepSkipped {
+				duration = time.Duration(outcome.Duration).Round(time.Millisecond).String()
+			}
+			fmt.Fprintln(w, msg("pipeline.row", outcome.Step, outcome.Plugin, outcome.Status, duration, outcome.Error))
+		}
+		w.Flush()
+	}
+	if failed+skipped > 0 {
+		log.Print(msg("pipeline.failed", failed, skipped, len(outcomes)))
+	} else {
+		log.Print(msg("pipeline.completed", len(outcomes)))
+	}
+	return failed, skipped
+}
+
+// runPipelineStep executes one step, through the daemon's warm plugin if useDaemon is set and a
+// daemon is running, reports it like a run and returns its last result
+func runPipelineStep(ctx context.Context, config *shared.AppConfig, manager *shared.PluginManager, useDaemon bool, monitor *shared.MemoryMonitor, step shared.WorkflowStep, params map[string]string) (map[string]interface{}, error) {
+	pluginConfig := config.Plugins[step.Plugin]
+	if err := pluginConfig.Validate(); err != nil {
+		return nil, errors.New(msg("run.invalid_plugin", step.Plugin, err))
+	}
+	var plugin shared.PluginInterface
+	var err error
+	viaDaemon := false
+	if useDaemon {
+		plugin, err = shared.ConnectDaemon(ctx, config, step.Plugin)
+		if err == nil {
+			viaDaemon = true
+			defer plugin.Close()
+			log.Print(msg("run.daemon", step.Plugin, config.DaemonSocketPath()))
+		} else if !errors.Is(err, shared.ErrNoDaemon) {
+			log.Print(msg("warning", err))
+		}
+	}
+	if plugin == nil {
+		// Plugins of earlier steps are already started
+		if plugin, err = manager.GetPlugin(step.Plugin); err != nil {
+			if err := manager.StartPlugin(step.Plugin, pluginConfig); err != nil {
+				return nil, errors.New(msg("run.start_failed", step.Plugin, err))
+			}
+			if plugin, err = manager.GetPlugin(step.Plugin); err != nil {
+				return nil, errors.New(msg("run.get_plugin_failed", step.Plugin, err))
+			}
+		}
+	}
+	info, err := plugin.GetInfo(ctx)
+	if err != nil {
+		return nil, errors.New(msg("run.info_failed", err))
+	}
+	if err := checkSchemaChanges(config, step.Plugin, info); err != nil {
+		return nil, errors.New(msg("run.schema_refused", step.Plugin, err))
+	}
+
+	mergeDefaults(params, info, pluginConfig)
+	redactor, err := shared.NewRedactor(config.Redaction, info.ParameterSchema, params)
+	if err != nil {
+		return nil, err
+	}
+	credential, err := resolveCredential(ctx, "", step.Plugin, pluginConfig)
+	if err != nil {
+		return nil, err
+	}
+	redactor.Mask(credential)
+	for _, secret := range pluginConfig.AuthHeaders.Secrets() {
+		redactor.Mask(secret)
+	}
+
+	executionID, err := shared.NewExecutionID()
+	if err != nil {
+		return nil, err
+	}
+	capture := shared.NewOutputCapture(config.SpillPath(), monitor)
+	defer capture.Close()
+	handler := &outputHandler{pluginName: step.Plugin, executionID: executionID, redactor: redactor, capture: capture, monitor: monitor}
+
+	start := time.Now().UnixNano()
+	measured := manager.MeasureUsage(step.Plugin)
+	execErr := shared.ExecuteWithTimeout(shared.WithExecutionID(shared.WithCredential(ctx, credential), handler.executionID), plugin, step.Plugin, time.Duration(step.Timeout), params, handler)
+	usage := measured()
+	end := time.Now().UnixNano()
+
+	metadata := outcomeMetadata(execErr, capture)
+	metadata["pipeline_step"] = step.Name
+	execution := &finishedExecution{
+		name:     step.Plugin,
+		config:   pluginConfig,
+		plugin:   plugin,
+		info:     info,
+		params:   params,
+		redactor: redactor,
+		handler:  handler,
+		start:    start,
+		end:      end,
+		err:      execErr,
+		metadata: metadata,
+		usage:    usage,
+	}
+	// The daemon records the steps it serves in the history itself
+	execution.report(config, !viaDaemon)
+	if execErr != nil {
+		return nil, errors.New(redactor.String(execErr.Error()))
+	}
+	// Plugins may report an error instead of failing the execution, which fails a step all the same
+	if handler.lastError != "" {
+		return nil, errors.New(msg("pipeline.plugin_error", handler.lastError))
+	}
+	return handler.lastResult, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// withOutput directs the command output to a buffer in the given format for the test
+func withOutput(t *testing.T, format outputFormat) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	saved := output
+	output = &formatter{format: format, w: &buf}
+	t.Cleanup(func() { output = saved })
+	return &buf
+}
+
+var testOutcomes = []stepOutcome{
+	{Step: "sum", Plugin: "addition", Status: stepSucceeded, Duration: shared.Duration(20 * time.Millisecond)},
+	{Step: "greet", Plugin: "hello", Status: stepFailed, Duration: shared.Duration(5 * time.Millisecond), Error: "plugin reported INVALID: no name"},
+	{Step: "again", Plugin: "hello", Status: stepSkipped},
+}
+
+func TestReportPipeline_counts(t *testing.T) {
+	withOutput(t, outputText)
+	failed, skipped := reportPipeline(testOutcomes)
+	if failed != 1 || skipped != 1 {
+		t.Errorf("reportPipeline() = %d failed, %d skipped, want 1 and 1", failed, skipped)
+	}
+}
+
+func TestReportPipeline_json(t *testing.T) {
+	buf := withOutput(t, outputJSON)
+
+	// A step's execution reports as a run does before the pipeline reports its outcomes
+	output.event(runEvent{Event: "result", Plugin: "addition", Time: time.Now(), Result: map[string]interface{}{"sum": 3}})
+	output.event(runEvent{Event: "summary", Plugin: "addition", Time: time.Now(), Summary: &summaryDocument{Plugin: "addition", Success: true}})
+	reportPipeline(testOutcomes)
+
+	var events []runEvent
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var e runEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("line %q is not a JSON event: %v", scanner.Text(), err)
+		}
+		events = append(events, e)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3:\n%s", len(events), buf.String())
+	}
+	last := events[2]
+	if last.Event != "pipeline" {
+		t.Fatalf("last event = %q, want pipeline", last.Event)
+	}
+	if len(last.Steps) != len(testOutcomes) {
+		t.Fatalf("pipeline event has %d steps, want %d", len(last.Steps), len(testOutcomes))
+	}
+	for i, step := range last.Steps {
+		if step != testOutcomes[i] {
+			t.Errorf("step %d = %+v, want %+v", i, step, testOutcomes[i])
+		}
+	}
+}
+
+func TestReportPipeline_text(t *testing.T) {
+	buf := withOutput(t, outputText)
+	reportPipeline(testOutcomes)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want a header and 3 steps:\n%s", len(lines), buf.String())
+	}
+	if fields := strings.Fields(lines[3]); len(fields) != 4 || fields[2] != stepSkipped || fields[3] != "-" {
+		t.Errorf("skipped step row = %q, want status skipped without a duration", lines[3])
+	}
+}
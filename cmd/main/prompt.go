@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// promptParams asks at the terminal for the required parameters that have no value, showing
+// each one's description and allowed values, until every one has a valid value
+func promptParams(ctx context.Context, in *os.File, out io.Writer, pluginName string, schema map[string]shared.ParameterSpec, params map[string]string, missing []string) error {
+	// Lines are read one at a time on request, so an interrupt can end the prompt while a read blocks
+	type answer struct {
+		line string
+		err  error
+	}
+	// answers has room for the line a read still blocked at an interrupt gets, so that its
+	// goroutine can end after the prompt has given up on it
+	next := make(chan struct{})
+	answers := make(chan answer, 1)
+	defer close(next)
+	go func() {
+		reader := bufio.NewReader(in)
+		for range next {
+			line, err := reader.ReadString('\n')
+			answers <- answer{strings.TrimRight(line, "\r\n"), err}
+		}
+	}()
+	readLine := func() (string, error) {
+		next <- struct{}{}
+		select {
+		case a := <-answers:
+			if a.err != nil && (a.line == "" || a.err != io.EOF) {
+				return "", a.err
+			}
+			return a.line, nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	fmt.Fprintln(out, msg("prompt.header", pluginName, len(missing)))
+	for _, name := range missing {
+		spec := schema[name]
+		if spec.Description != "" {
+			fmt.Fprintln(out, msg("prompt.description", name, spec.Description))
+		}
+		if len(spec.AllowedValues) > 0 && !spec.IsSecret() {
+			fmt.Fprintln(out, msg("prompt.allowed", strings.Join(spec.AllowedValues, ", ")))
+		}
+		for {
+			value, err := promptValue(in, out, name, spec, readLine)
+			if errors.Is(err, io.EOF) {
+				return errors.New(msg("prompt.no_value", name))
+			}
+			if err != nil {
+				return err
+			}
+			if problem := checkPromptedValue(spec, value); problem != "" {
+				fmt.Fprintln(out, problem)
+				continue
+			}
+			params[name] = value
+			break
+		}
+	}
+	return nil
+}
+
+// promptValue asks for one parameter's value, hiding the input of secrets
+func promptValue(in *os.File, out io.Writer, name string, spec shared.ParameterSpec, readLine func() (string, error)) (string, error) {
+	if !spec.IsSecret() {
+		fmt.Fprint(out, msg("prompt.input", name))
+		return readLine()
+	}
+	if err := setEcho(in, false); err != nil {
+		return "", errors.New(msg("prompt.secret_unsupported", name, shared.SecretParamEnvVar(name), err))
+	}
+	defer setEcho(in, true)
+	fmt.Fprint(out, msg("prompt.input_secret", name))
+	value, err := readLine()
+	// The newline wasn't echoed either
+	fmt.Fprintln(out)
+	return value, err
+}
+
+// checkPromptedValue returns why a value won't do for a parameter, or nothing
+func checkPromptedValue(spec shared.ParameterSpec, value string) string {
+	if value == "" {
+		return msg("prompt.empty")
+	}
+	if len(spec.AllowedValues) > 0 {
+		for _, allowed := range spec.AllowedValues {
+			if value == allowed {
+				return ""
+			}
+		}
+		return msg("prompt.not_allowed", value, strings.Join(spec.AllowedValues, ", "))
+	}
+	switch spec.Type {
+	case "int", "integer", "float", "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return msg("prompt.invalid", value, spec.Type)
+		}
+	case "bool", "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return msg("prompt.invalid", value, spec.Type)
+		}
+	}
+	return ""
+}
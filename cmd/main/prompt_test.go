@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+func TestCheckPromptedValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     shared.ParameterSpec
+		value    string
+		wantNote string // Part of the problem reported, "" if the value will do
+	}{
+		{name: "Any string", value: "World"},
+		{name: "Empty", value: "", wantNote: "required"},
+		{name: "Allowed", spec: shared.ParameterSpec{AllowedValues: []string{"en", "fr"}}, value: "fr"},
+		{name: "Not allowed", spec: shared.ParameterSpec{AllowedValues: []string{"en", "fr"}}, value: "de", wantNote: "not one of: en, fr"},
+		{name: "Number", spec: shared.ParameterSpec{Type: "float"}, value: "2.5"},
+		{name: "Not a number", spec: shared.ParameterSpec{Type: "int"}, value: "two", wantNote: "not a valid int"},
+		{name: "Bool", spec: shared.ParameterSpec{Type: "bool"}, value: "true"},
+		{name: "Not a bool", spec: shared.ParameterSpec{Type: "boolean"}, value: "maybe", wantNote: "not a valid boolean"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkPromptedValue(tt.spec, tt.value)
+			if tt.wantNote == "" {
+				if got != "" {
+					t.Errorf("checkPromptedValue() = %q, want none", got)
+				}
+				return
+			}
+			if !strings.Contains(got, tt.wantNote) {
+				t.Errorf("checkPromptedValue() = %q, want it to contain %q", got, tt.wantNote)
+			}
+		})
+	}
+}
+
+// promptPipe returns the read end of a pipe fed with input, closed after it unless keepOpen is set
+func promptPipe(t *testing.T, input string, keepOpen bool) *os.File {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		r.Close()
+		w.Close()
+	})
+	if _, err := w.WriteString(input); err != nil {
+		t.Fatal(err)
+	}
+	if !keepOpen {
+		w.Close()
+	}
+	return r
+}
+
+func TestPromptParams(t *testing.T) {
+	schema := map[string]shared.ParameterSpec{
+		"message":  {Description: "Who to greet", Required: true},
+		"language": {Required: true, AllowedValues: []string{"en", "fr"}},
+		"count":    {Required: true, Type: "int"},
+		"token":    {Required: true, Type: shared.ParamTypeSecret},
+	}
+
+	t.Run("Asks until values are valid", func(t *testing.T) {
+		in := promptPipe(t, "World\n\nde\nfr\ntwo\n3", false)
+		var out bytes.Buffer
+		params := map[string]string{}
+		err := promptParams(context.Background(), in, &out, "hello", schema, params, []string{"message", "language", "count"})
+		if err != nil {
+			t.Fatalf("promptParams() error = %v", err)
+		}
+		want := map[string]string{"message": "World", "language": "fr", "count": "3"}
+		for name, value := range want {
+			if params[name] != value {
+				t.Errorf("params[%s] = %q, want %q", name, params[name], value)
+			}
+		}
+		for _, shown := range []string{"Who to greet", "one of: en, fr", "a value is required", `"de" is not one of`, `"two" is not a valid int`} {
+			if !strings.Contains(out.String(), shown) {
+				t.Errorf("prompt output is missing %q:\n%s", shown, out.String())
+			}
+		}
+	})
+
+	t.Run("Input ends before a value", func(t *testing.T) {
+		in := promptPipe(t, "World\n", false)
+		err := promptParams(context.Background(), in, &bytes.Buffer{}, "hello", schema, map[string]string{}, []string{"message", "language"})
+		if err == nil || !strings.Contains(err.Error(), "no value given for language") {
+			t.Errorf("promptParams() error = %v, want no value for language", err)
+		}
+	})
+
+	t.Run("Secret input can't be hidden on a pipe", func(t *testing.T) {
+		in := promptPipe(t, "s3cret\n", false)
+		err := promptParams(context.Background(), in, &bytes.Buffer{}, "hello", schema, map[string]string{}, []string{"token"})
+		if err == nil || !strings.Contains(err.Error(), shared.SecretParamEnvVar("token")) {
+			t.Errorf("promptParams() error = %v, want a hint to set the secret's variable", err)
+		}
+	})
+
+	t.Run("Interrupt while waiting for input", func(t *testing.T) {
+		in := promptPipe(t, "", true)
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		done := make(chan error, 1)
+		go func() {
+			done <- promptParams(ctx, in, &bytes.Buffer{}, "hello", schema, map[string]string{}, []string{"message"})
+		}()
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("promptParams() error = %v, want context.Canceled", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("promptParams() did not return after the interrupt")
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/client"
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// psDocument is one plugin as ps reports it with structured output
+type psDocument struct {
+	Name       string          `json:"name"`
+	Type       string          `json:"type"`
+	State      string          `json:"state"`
+	PID        int             `json:"pid,omitempty"`
+	Address    string          `json:"address,omitempty"`
+	Uptime     shared.Duration `json:"uptime,omitempty"`
+	Restarts   int             `json:"restarts"`
+	Executions int             `json:"executions"`
+	Executing  shared.Duration `json:"executing_for,omitempty"`
+	Queued     int             `json:"queued"`
+}
+
+// runPs implements the ps command, which lists the plugins a running daemon manages: their
+// state, process or address, uptime, restarts and the executions in flight. Stopped plugins are
+// left out unless -a is given.
+func runPs(args []string) {
+	fs := flag.NewFlagSet("ps", flag.ExitOnError)
+	configPath, socket := daemonFlags(fs)
+	all := fs.Bool("a", false, "Also list the plugins that aren't running")
+	timeout := fs.Duration("timeout", 5*time.Second, "Deadline for querying the daemon")
+	outputFlag(fs)
+	logFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fmt.Println(msg("ps.usage"))
+		os.Exit(1)
+	}
+
+	config := loadDaemonConfig(*configPath, *socket)
+	if _, err := shared.DaemonPID(config); errors.Is(err, shared.ErrNoDaemon) {
+		fatal(msg("daemon.not_running", config.DaemonSocketPath()))
+	} else if err != nil {
+		fatal(msg("error", err))
+	}
+
+	c, err := client.New(client.Options{Socket: config.DaemonSocketPath(), MaxRetries: -1})
+	if err != nil {
+		fatal(msg("error", err))
+	}
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	plugins, err := c.List(ctx)
+	if err != nil {
+		fatal(msg("daemon.status_failed", err))
+	}
+
+	now := time.Now()
+	docs := make([]psDocument, 0, len(plugins))
+	for _, plugin := range plugins {
+		if !plugin.Running && plugin.State != string(shared.StateStarting) && !*all {
+			continue
+		}
+		docs = append(docs, psPlugin(plugin, now))
+	}
+
+	if output.structured() {
+		output.document(docs)
+		return
+	}
+	w := tabwriter.NewWriter(output.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, msg("ps.header"))
+	for _, doc := range docs {
+		fmt.Fprintln(w, msg("ps.row", doc.Name, doc.State, psLocation(doc), psDuration(doc.Uptime), doc.Restarts, psExecuting(doc), doc.Queued))
+	}
+	w.Flush()
+}
+
+// psPlugin describes a plugin's status at now
+func psPlugin(plugin client.PluginStatus, now time.Time) psDocument {
+	doc := psDocument{
+		Name:       plugin.Name,
+		Type:       plugin.Type,
+		State:      plugin.State,
+		Address:    plugin.Address,
+		Restarts:   plugin.Restarts,
+		Executions: plugin.Executions,
+		Queued:     plugin.Queued,
+	}
+	if plugin.Usage != nil {
+		doc.PID = plugin.Usage.PID
+	}
+	if !plugin.Started.IsZero() {
+		doc.Uptime = shared.Duration(now.Sub(plugin.Started))
+	}
+	// Executing plugins entered their state when the executions in flight began to run
+	if plugin.State == string(shared.StateExecuting) && !plugin.Since.IsZero() {
+		doc.Executing = shared.Duration(now.Sub(plugin.Since))
+	}
+	return doc
+}
+
+// psLocation is the process of a local plugin or the address of a remote one
+func psLocation(doc psDocument) string {
+	switch {
+	case doc.PID != 0:
+		return strconv.Itoa(doc.PID)
+	case doc.Address != "":
+		return doc.Address
+	default:
+		return "-"
+	}
+}
+
+// psExecuting summarizes the executions in flight
+func psExecuting(doc psDocument) string {
+	if doc.Executions == 0 {
+		return "-"
+	}
+	return msg("ps.executing", doc.Executions, psDuration(doc.Executing))
+}
+
+// psDuration rounds a duration to the second, "-" if there is none
+func psDuration(d shared.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return time.Duration(d).Round(time.Second).String()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/client"
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+func TestPsPlugin(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name          string
+		status        client.PluginStatus
+		wantLocation  string
+		wantUptime    string
+		wantExecuting string
+	}{
+		{
+			name: "Local plugin executing",
+			status: client.PluginStatus{
+				Name: "hello", State: "executing", Since: now.Add(-3 * time.Second), Started: now.Add(-time.Minute),
+				Executions: 2, Usage: &shared.ResourceUsage{PID: 4242},
+			},
+			wantLocation:  "4242",
+			wantUptime:    "1m0s",
+			wantExecuting: "2 for 3s",
+		},
+		{
+			name:          "Remote plugin idle",
+			status:        client.PluginStatus{Name: "remote", State: "ready", Since: now.Add(-time.Hour), Started: now.Add(-time.Hour), Address: "10.0.0.1:443"},
+			wantLocation:  "10.0.0.1:443",
+			wantUptime:    "1h0m0s",
+			wantExecuting: "-",
+		},
+		{
+			name:          "Stopped plugin",
+			status:        client.PluginStatus{Name: "addition", State: "stopped"},
+			wantLocation:  "-",
+			wantUptime:    "-",
+			wantExecuting: "-",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := psPlugin(tt.status, now)
+			if got := psLocation(doc); got != tt.wantLocation {
+				t.Errorf("psLocation() = %q, want %q", got, tt.wantLocation)
+			}
+			if got := psDuration(doc.Uptime); got != tt.wantUptime {
+				t.Errorf("uptime = %q, want %q", got, tt.wantUptime)
+			}
+			if got := psExecuting(doc); got != tt.wantExecuting {
+				t.Errorf("psExecuting() = %q, want %q", got, tt.wantExecuting)
+			}
+		})
+	}
+}
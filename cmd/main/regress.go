@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// regressTarget is one of the two plugin versions being compared
+type regressTarget struct {
+	name   string
+	config shared.PluginConfig
+	plugin shared.PluginInterface
+	info   *shared.PluginInfo
+}
+
+// runRegress implements the regress command, which replays recent parameter sets against a
+// baseline and a candidate version of a plugin and reports where their results differ
+func runRegress(args []string) {
+	fs := flag.NewFlagSet("regress", flag.ExitOnError)
+	configPath := configFlag(fs)
+	profileFlag(fs)
+	baseline := fs.String("baseline", "", "Baseline version: a configured plugin name or a plugin binary")
+	candidate := fs.String("candidate", "", "Candidate version: a configured plugin name or a plugin binary")
+	fromHistory := fs.Int("from-history", 10, "How many of the latest executions to replay")
+	timeout := fs.Duration("timeout", time.Minute, "Deadline for each replayed execution")
+	logFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || *baseline == "" || *candidate == "" || *fromHistory < 1 {
+		fmt.Println(msg("regress.usage"))
+		os.Exit(1)
+	}
+	name := fs.Arg(0)
+
+	config := loadConfig(*configPath)
+	pluginConfig, err := config.GetPluginConfig(name)
+	if err != nil {
+		log.Fatal(msg("error", err))
+	}
+
+	records, err := config.HistoryStore().Recent(name, *fromHistory)
+	if err != nil {
+		log.Fatal(msg("error", err))
+	}
+	sets := shared.DistinctParams(records)
+	if len(sets) == 0 {
+		log.Fatal(msg("regress.no_history", name))
+	}
+
+	manager := shared.NewPluginManager(config)
+	defer manager.StopAll()
+	manager.SetProcessOutput(io.Discard, io.Discard)
+
+	var targets [2]*regressTarget
+	for i, spec := range []string{*baseline, *candidate} {
+		role := []string{"baseline", "candidate"}[i]
+		target, err := startRegressTarget(manager, config, name+"@"+role, pluginConfig, spec)
+		if err != nil {
+			manager.StopAll()
+			log.Fatal(msg("regress.start_failed", role, spec, err))
+		}
+		targets[i] = target
+	}
+
+	fmt.Println(msg("regress.header", name, targets[0].info.Version, targets[1].info.Version, len(sets)))
+	regressions := 0
+	for i, recorded := range sets {
+		base := replay(targets[0], recorded, *timeout)
+		cand := replay(targets[1], recorded, *timeout)
+		diffs := shared.CompareReplays(base, cand)
+		if len(diffs) == 0 {
+			fmt.Println(msg("regress.same", i+1, formatRecordedParams(recorded)))
+			continue
+		}
+		regressions++
+		fmt.Println(msg("regress.differs", i+1, formatRecordedParams(recorded), len(diffs)))
+		for _, diff := range diffs {
+			fmt.Println(msg("regress.diff", diff))
+		}
+	}
+
+	if regressions > 0 {
+		fmt.Println(msg("regress.summary_failed", regressions, len(sets)))
+		manager.StopAll()
+		os.Exit(1)
+	}
+	fmt.Println(msg("regress.summary_ok", len(sets)))
+}
+
+// startRegressTarget starts one version of the plugin and checks that it may be replayed against.
+// spec names another configured plugin or a binary run with the plugin's own configuration.
+func startRegressTarget(manager *shared.PluginManager, config *shared.AppConfig, name string, pluginConfig shared.PluginConfig, spec string) (*regressTarget, error) {
+	versionConfig, ok := config.Plugins[spec]
+	if !ok {
+		if pluginConfig.IsRemote() {
+			return nil, errors.New(msg("regress.remote_binary"))
+		}
+		path, err := filepath.Abs(spec)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+		// The checksum and manifest belong to the configured binary, not this one
+		versionConfig = pluginConfig
+		versionConfig.Path = path
+		versionConfig.SHA256 = ""
+		versionConfig.Manifest = ""
+	}
+	// Both versions run side by side
+	if !versionConfig.IsRemote() {
+		versionConfig.Port = 0
+	}
+	versionConfig.Standby = false
+	versionConfig.StandbyPort = 0
+	if err := versionConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := manager.StartPlugin(name, versionConfig); err != nil {
+		return nil, err
+	}
+	plugin, err := manager.GetPlugin(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := plugin.GetInfo(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if err := shared.CheckReplayable(info); err != nil {
+		return nil, err
+	}
+	return &regressTarget{name: name, config: versionConfig, plugin: plugin, info: info}, nil
+}
+
+// replay runs a recorded parameter set against one version
+func replay(target *regressTarget, recorded map[string]string, timeout time.Duration) shared.ReplayResult {
+	params := shared.ReplayParams(target.info, recorded)
+	mergeDefaults(params, target.info, target.config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	collector := &resultCollector{}
+	var result shared.ReplayResult
+	if err := target.plugin.Execute(ctx, params, collector); err != nil {
+		result.Error = err.Error()
+	}
+	result.Result = collector.result
+	return result
+}
+
+// formatRecordedParams renders recorded parameters as sorted name=value pairs
+func formatRecordedParams(params map[string]string) string {
+	pairs := make([]string, 0, len(params))
+	for name, value := range params {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// resultCollector keeps the result of an execution and discards everything else
+type resultCollector struct {
+	result map[string]interface{}
+}
+
+func (c *resultCollector) OnOutput(line string) error {
+	return nil
+}
+
+func (c *resultCollector) OnProgress(progress shared.Progress) error {
+	return nil
+}
+
+func (c *resultCollector) OnResult(result map[string]interface{}) error {
+	c.result = result
+	return nil
+}
+
+func (c *resultCollector) OnError(code, message, details string) error {
+	return nil
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+//go:embed templates/plugin.go.tmpl
+var pluginTemplate string
+
+// pluginNamePattern is what plugin names may look like, so they work as directories, binaries
+// and config keys alike
+var pluginNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// paramNamePattern is what parameter names of a scaffolded plugin may look like
+var paramNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// scaffoldParam is a parameter of a scaffolded plugin
+type scaffoldParam struct {
+	Name        string
+	Type        string
+	Required    bool
+	Description string
+}
+
+// scaffoldParams collects repeated -param flags of the form name[:type[:required[:description]]]
+type scaffoldParams []scaffoldParam
+
+func (p *scaffoldParams) String() string {
+	names := make([]string, len(*p))
+	for i, param := range *p {
+		names[i] = param.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func (p *scaffoldParams) Set(value string) error {
+	parts := strings.SplitN(value, ":", 4)
+	param := scaffoldParam{Name: parts[0], Type: "string"}
+	if !paramNamePattern.MatchString(param.Name) {
+		return fmt.Errorf("invalid parameter name %q", param.Name)
+	}
+	if len(parts) > 1 && parts[1] != "" {
+		param.Type = parts[1]
+	}
+	switch param.Type {
+	case "string", "float", "bool", shared.ParamTypeSecret:
+	default:
+		return fmt.Errorf("invalid type %q for parameter %s (must be string, float, bool or secret)", param.Type, param.Name)
+	}
+	if len(parts) > 2 && parts[2] != "" {
+		switch parts[2] {
+		case "required":
+			param.Required = true
+		case "optional":
+		default:
+			return fmt.Errorf("invalid %q for parameter %s (must be required or optional)", parts[2], param.Name)
+		}
+	}
+	if len(parts) > 3 {
+		param.Description = parts[3]
+	}
+	if param.Description == "" {
+		param.Description = "TODO: describe " + param.Name
+	}
+	for _, existing := range *p {
+		if existing.Name == param.Name {
+			return fmt.Errorf("parameter %s given twice", param.Name)
+		}
+	}
+	*p = append(*p, param)
+	return nil
+}
+
+// runNew implements the new command; "new plugin" scaffolds a Go plugin
+func runNew(args []string) {
+	if len(args) == 0 || args[0] != "plugin" {
+		fmt.Println(msg("new.usage"))
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("new plugin", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "Configuration file to add the plugin to")
+	dir := fs.String("dir", "plugins", "Directory the plugin's directory is created in")
+	description := fs.String("description", "", "What the plugin does")
+	port := fs.Int("port", 0, "Port of the plugin in the configuration (default one past the highest configured)")
+	noConfig := fs.Bool("no-config", false, "Don't add the plugin to the configuration")
+	var params scaffoldParams
+	fs.Var(&params, "param", "Parameter as name[:string|float|bool|secret[:required|optional[:description]]] (repeatable)")
+	logFlags(fs)
+	fs.Parse(args[1:])
+
+	if fs.NArg() != 1 {
+		fmt.Println(msg("new.usage"))
+		os.Exit(1)
+	}
+	name := fs.Arg(0)
+	if !pluginNamePattern.MatchString(name) {
+		log.Fatal(msg("new.invalid_name", name))
+	}
+	if *description == "" {
+		*description = "TODO: describe " + name
+	}
+
+	// Check the configuration first, so nothing is written if the plugin can't be added. Only
+	// names and ports are needed, so encrypted values stay as they are.
+	var rawConfig shared.AppConfig
+	if !*noConfig {
+		data, err := os.ReadFile(*configPath)
+		if err != nil {
+			log.Fatal(msg("error", err))
+		}
+		if err := json.Unmarshal(data, &rawConfig); err != nil {
+			log.Fatal(msg("config.load_failed", err))
+		}
+		if _, exists := rawConfig.Plugins[name]; exists {
+			log.Fatal(msg("new.exists_in_config", name, *configPath))
+		}
+	}
+
+	pluginDir := filepath.Join(*dir, name)
+	source := filepath.Join(pluginDir, "main.go")
+	if _, err := os.Stat(pluginDir); err == nil {
+		log.Fatal(msg("new.exists", pluginDir))
+	}
+	code, err := renderPlugin(name, *description, params)
+	if err != nil {
+		log.Fatal(msg("error", err))
+	}
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		log.Fatal(msg("error", err))
+	}
+	if err := os.WriteFile(source, code, 0644); err != nil {
+		log.Fatal(msg("error", err))
+	}
+	fmt.Println(msg("new.created", source))
+
+	binary := relativePath(filepath.Join("bin", name))
+	if !*noConfig {
+		if *port == 0 {
+			*port = nextPluginPort(&rawConfig)
+		}
+		plugin := shared.PluginConfig{
+			Type:        shared.PluginTypeBinary,
+			Path:        binary,
+			Port:        *port,
+			Description: *description,
+		}
+		if err := shared.AddPluginToConfig(*configPath, name, plugin); err != nil {
+			log.Fatal(msg("error", err))
+		}
+		fmt.Println(msg("new.configured", name, *configPath, *port))
+	}
+	if *noConfig {
+		fmt.Println(msg("new.next_steps_exec", binary, relativePath(pluginDir)))
+	} else {
+		fmt.Println(msg("new.next_steps", binary, relativePath(pluginDir), name))
+	}
+}
+
+// relativePath returns a path as go build takes a package directory
+func relativePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return "./" + filepath.ToSlash(path)
+}
+
+// renderPlugin returns the formatted source of a plugin
+func renderPlugin(name, description string, params scaffoldParams) ([]byte, error) {
+	tmpl, err := template.New("plugin").Parse(pluginTemplate)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, struct {
+		Name        string
+		Type        string
+		Description string
+		Params      scaffoldParams
+	}{name, pluginTypeName(name), description, params})
+	if err != nil {
+		return nil, err
+	}
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, errors.New(msg("new.format_failed", err))
+	}
+	return code, nil
+}
+
+// pluginTypeName returns the Go type of a plugin, e.g. WordCountPlugin for word-count
+func pluginTypeName(name string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return r == '-' || r == '_' }) {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	b.WriteString("Plugin")
+	return b.String()
+}
+
+// nextPluginPort returns the port after the highest one configured for a local plugin
+func nextPluginPort(config *shared.AppConfig) int {
+	port := 50050
+	for _, plugin := range config.Plugins {
+		if !plugin.IsRemote() && plugin.Port > port {
+			port = plugin.Port
+		}
+	}
+	return port + 1
+}
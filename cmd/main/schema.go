@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// runSchema implements the schema command, which shows and acknowledges plugin schema changes
+func runSchema(args []string) {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	configPath := configFlag(fs)
+	profileFlag(fs)
+	ack := fs.Bool("ack", false, "Accept the plugin's current schema, including breaking changes")
+	logFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Println(msg("schema.usage"))
+		os.Exit(1)
+	}
+	name := fs.Arg(0)
+
+	config := loadConfig(*configPath)
+	pluginConfig, err := config.GetPluginConfig(name)
+	if err != nil {
+		log.Fatal(msg("error", err))
+	}
+
+	manager := shared.NewPluginManager(config)
+	defer manager.StopAll()
+	manager.SetProcessOutput(io.Discard, io.Discard)
+	info, err := queryPluginInfo(manager, name, pluginConfig)
+	if err != nil {
+		log.Fatal(msg("run.info_failed", err))
+	}
+
+	store := shared.NewSchemaStore(config.StateDir)
+	previous, err := store.Load(name)
+	if err != nil {
+		log.Fatal(msg("error", err))
+	}
+
+	if previous == nil {
+		fmt.Println(msg("schema.none_recorded", name))
+	} else {
+		changes := shared.DiffSchemas(previous, info)
+		fmt.Println(msg("schema.changes", name, previous.Version, info.Version))
+		printSchemaChanges(changes)
+	}
+
+	if *ack || previous == nil {
+		if err := store.Save(name, info); err != nil {
+			log.Fatal(msg("error", err))
+		}
+		fmt.Println(msg("schema.recorded", name, info.Version))
+	}
+}
+
+// printSchemaChanges lists schema changes, flagging the breaking ones
+func printSchemaChanges(changes []shared.SchemaChange) {
+	if len(changes) == 0 {
+		fmt.Println(msg("schema.no_changes"))
+	}
+	for _, change := range changes {
+		marker := " "
+		if change.Breaking {
+			marker = "!"
+		}
+		fmt.Println(msg("schema.change", marker, change))
+	}
+}
+
+// checkSchemaChanges compares a plugin's schema with the acknowledged one before a run. Compatible
+// changes are recorded right away; breaking ones are reported until acknowledged and block the run
+// when schema_changes is "block".
+func checkSchemaChanges(config *shared.AppConfig, name string, info *shared.PluginInfo) error {
+	store := shared.NewSchemaStore(config.StateDir)
+	previous, err := store.Load(name)
+	if err != nil {
+		return err
+	}
+	if previous == nil {
+		return store.Save(name, info)
+	}
+
+	changes := shared.DiffSchemas(previous, info)
+	if len(changes) == 0 {
+		return nil
+	}
+	if !shared.HasBreakingChanges(changes) {
+		log.Print(msg("schema.changed", name, previous.Version, info.Version))
+		for _, change := range changes {
+			log.Print(msg("schema.change_entry", change))
+		}
+		return store.Save(name, info)
+	}
+
+	log.Print(msg("schema.changed_breaking", name, previous.Version, info.Version))
+	for _, change := range changes {
+		if change.Breaking {
+			log.Print(msg("schema.change_entry", change))
+		}
+	}
+	log.Print(msg("schema.ack_hint", name))
+	if config.SchemaChanges == shared.SchemaChangesBlock {
+		return errors.New(msg("schema.unacknowledged", name))
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// runSign implements the sign command, which writes a signed manifest next to a plugin binary
+func runSign(args []string) {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	keyPath := fs.String("key", "signing.key", "Path to the publisher's private key (created if missing)")
+	publisher := fs.String("publisher", "", "Publisher name, as listed in trusted_keys")
+	name := fs.String("name", "", "Plugin name (defaults to the file name)")
+	version := fs.String("version", "", "Plugin version")
+	output := fs.String("o", "", "Manifest path (defaults to <binary>"+shared.ManifestSuffix+")")
+	logFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || *publisher == "" || *version == "" {
+		fmt.Println(msg("sign.usage"))
+		os.Exit(1)
+	}
+	path := fs.Arg(0)
+
+	key, err := loadOrCreateSigningKey(*keyPath)
+	if err != nil {
+		log.Fatal(msg("sign.key_failed", err))
+	}
+
+	sum, err := shared.FileSHA256(path)
+	if err != nil {
+		log.Fatal(msg("sign.hash_failed", err))
+	}
+	manifest := &shared.PluginManifest{
+		Name:      *name,
+		Version:   *version,
+		SHA256:    sum,
+		Publisher: *publisher,
+	}
+	if manifest.Name == "" {
+		manifest.Name = filepath.Base(path)
+	}
+	manifest.Sign(key)
+
+	manifestPath := *output
+	if manifestPath == "" {
+		manifestPath = path + shared.ManifestSuffix
+	}
+	if err := shared.SaveManifest(manifest, manifestPath); err != nil {
+		log.Fatal(msg("sign.save_failed", err))
+	}
+	fmt.Println(msg("sign.written", manifestPath))
+}
+
+// loadOrCreateSigningKey reads a base64 Ed25519 private key, generating one if the file doesn't exist
+func loadOrCreateSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(private.Seed())
+		if err := os.WriteFile(path, []byte(encoded+"\n"), 0600); err != nil {
+			return nil, err
+		}
+		fmt.Println(msg("sign.key_generated", path))
+		fmt.Println(msg("sign.trust_key", base64.StdEncoding.EncodeToString(public)))
+		return private, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, errors.New(msg("sign.invalid_key", path))
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// runStats implements the stats command, which aggregates the recorded executions per plugin
+func runStats(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	configPath := configFlag(fs)
+	profileFlag(fs)
+	since := fs.String("since", "", "Only count executions since a duration ago (e.g. 24h) or an RFC 3339 time")
+	outputFlag(fs)
+	logFlags(fs)
+	fs.Parse(args)
+
+	var from time.Time
+	if *since != "" {
+		t, err := parseSince(*since)
+		if err != nil {
+			fatal(msg("stats.invalid_since", *since))
+		}
+		from = t
+	}
+
+	config := loadConfig(*configPath)
+	store := config.HistoryStore()
+	names := fs.Args()
+	if len(names) == 0 {
+		var err error
+		if names, err = store.Plugins(); err != nil {
+			fatal(msg("error", err))
+		}
+	}
+	if output.structured() {
+		docs := make([]statsDocument, 0, len(names))
+		for _, name := range names {
+			stats, err := store.Stats(name, from)
+			if err != nil {
+				fatal(msg("error", err))
+			}
+			docs = append(docs, pluginStatsDocument(stats))
+		}
+		output.document(docs)
+		return
+	}
+	if len(names) == 0 {
+		fmt.Println(msg("stats.no_history"))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, msg("stats.header"))
+	for _, name := range names {
+		stats, err := store.Stats(name, from)
+		if err != nil {
+			fatal(msg("error", err))
+		}
+		if stats.Runs == 0 {
+			fmt.Fprintln(w, msg("stats.no_runs", name))
+			continue
+		}
+		lastFailure := "-"
+		if f := stats.LastFailure; f != nil {
+			lastFailure = msg("stats.failure", f.Time.Local().Format(time.RFC3339), f.Error)
+		}
+		fmt.Fprintln(w, msg("stats.row", name, stats.Runs, stats.SuccessRate()*100,
+			statsDuration(stats.P50), statsDuration(stats.P95), lastFailure))
+	}
+	w.Flush()
+}
+
+// statsDuration formats a percentile, which is 0 when no duration was recorded
+func statsDuration(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(time.Millisecond).String()
+}
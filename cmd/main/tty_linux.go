@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// setEcho turns echoing of what is typed at a terminal on or off
+func setEcho(f *os.File, on bool) error {
+	var state syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TCGETS, uintptr(unsafe.Pointer(&state))); errno != 0 {
+		return errno
+	}
+	if on {
+		state.Lflag |= syscall.ECHO
+	} else {
+		state.Lflag &^= syscall.ECHO
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TCSETS, uintptr(unsafe.Pointer(&state))); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// setEcho fails, as terminal modes are only changed on Linux
+func setEcho(f *os.File, on bool) error {
+	return errors.New("terminal echo can only be turned off on Linux")
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// sortedPluginNames returns the configured plugin names in a stable order
+func sortedPluginNames(config *shared.AppConfig) []string {
+	names := make([]string, 0, len(config.Plugins))
+	for name := range config.Plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runValidate implements the validate command. Every problem is reported, not just the first,
+// and the exit status is non-zero if there were any.
+func runValidate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	configPath := configFlag(fs)
+	profileFlag(fs)
+	writeChecksums := fs.Bool("write-checksums", false, "Compute plugin checksums and write them to the config file")
+	dial := fs.Bool("dial", false, "Also connect to every remote plugin and check that it is serving")
+	timeout := fs.Duration("timeout", 5*time.Second, "Deadline for each health check with -dial")
+	live := fs.Bool("live", false, "Also start or connect to every plugin with defaults and check them against its parameter schema")
+	logFlags(fs)
+	fs.Parse(args)
+
+	if *writeChecksums {
+		config := loadConfig(*configPath)
+		if err := writePluginChecksums(configPath.base(), config); err != nil {
+			log.Fatal(msg("validate.checksums_failed", err))
+		}
+		return
+	}
+
+	config, configProblems, err := shared.CheckConfig(configPath.base(), configPath.overlays()...)
+	if err != nil {
+		log.Fatal(msg("config.load_failed", err))
+	}
+	if err := messages.Apply(config.Messages); err != nil {
+		configProblems = append(configProblems, shared.ConfigProblem{Err: fmt.Errorf("invalid messages configuration: %v", err)})
+	}
+
+	problems := len(configProblems)
+	fmt.Println(msg("validate.config_header"))
+	for _, problem := range configProblems {
+		fmt.Println(msg("validate.config_problem", problem))
+	}
+	if problems == 0 {
+		fmt.Println(msg("validate.config_ok"))
+	}
+
+	fmt.Println(msg("validate.checksums_header"))
+	for _, name := range sortedPluginNames(config) {
+		plugin := config.Plugins[name]
+		if plugin.SHA256 == "" {
+			fmt.Println(msg("validate.no_checksum", name))
+			continue
+		}
+		if err := plugin.VerifyChecksum(); err != nil {
+			fmt.Println(msg("validate.check_failed", name, err))
+			problems++
+			continue
+		}
+		fmt.Println(msg("validate.checksum_ok", name))
+	}
+
+	if len(config.TrustedKeys) > 0 {
+		fmt.Println(msg("validate.signatures_header"))
+		for _, name := range sortedPluginNames(config) {
+			plugin := config.Plugins[name]
+			if plugin.IsRemote() {
+				continue
+			}
+			manifest, err := config.VerifyManifest(plugin)
+			if err != nil {
+				fmt.Println(msg("validate.check_failed", name, err))
+				problems++
+				continue
+			}
+			fmt.Println(msg("validate.signature_ok", name, manifest.Name, manifest.Version, manifest.Publisher))
+		}
+	}
+
+	if *dial {
+		problems += dialRemotePlugins(config, *timeout)
+	}
+
+	if *live {
+		problems += checkLiveDefaults(config)
+	}
+
+	if problems > 0 {
+		fmt.Println(msg("validate.problems", problems))
+		os.Exit(1)
+	}
+}
+
+// dialRemotePlugins connects to every remote plugin, reports whether it is serving and returns
+// how many are not
+func dialRemotePlugins(config *shared.AppConfig, timeout time.Duration) int {
+	failed := 0
+	fmt.Println(msg("validate.remote_header"))
+	for _, name := range sortedPluginNames(config) {
+		plugin := config.Plugins[name]
+		if !plugin.IsRemote() {
+			continue
+		}
+		// The configured addresses themselves are checked, not the daemon's connection
+		report := probePlugin(config, name, plugin, timeout, false)
+		switch {
+		case report.err != nil:
+			fmt.Println(msg("validate.check_failed", name, report.err))
+			failed++
+		case !report.probe.Serving():
+			fmt.Println(msg("validate.remote_not_serving", name, report.probe.Status))
+			failed++
+		default:
+			fmt.Println(msg("validate.remote_ok", name, report.probe.Latency.Round(time.Microsecond)))
+		}
+	}
+	return failed
+}
+
+// checkLiveDefaults starts or connects to every plugin with configured defaults, checks them
+// against the parameter schema the plugin reports and returns how many problems there were
+func checkLiveDefaults(config *shared.AppConfig) int {
+	manager := shared.NewPluginManager(config)
+	defer manager.StopAll()
+	manager.SetProcessOutput(io.Discard, io.Discard)
+
+	problems := 0
+	fmt.Println(msg("validate.defaults_header"))
+	for _, name := range sortedPluginNames(config) {
+		plugin := config.Plugins[name]
+		if len(plugin.Defaults) == 0 {
+			fmt.Println(msg("validate.no_defaults", name))
+			continue
+		}
+		info, err := queryPluginInfo(manager, name, plugin)
+		if err != nil {
+			fmt.Println(msg("validate.check_failed", name, err))
+			problems++
+			continue
+		}
+		defaultProblems := shared.CheckDefaults(info.ParameterSchema, plugin.Defaults)
+		for _, problem := range defaultProblems {
+			fmt.Println(msg("validate.defaults_problem", name, problem))
+		}
+		if len(defaultProblems) == 0 {
+			fmt.Println(msg("validate.defaults_ok", name, len(plugin.Defaults)))
+		}
+		problems += len(defaultProblems)
+	}
+	return problems
+}
+
+// writePluginChecksums computes the checksum of every plugin binary and stores it in the config
+// file at configPath, the base of any overlays
+func writePluginChecksums(configPath string, config *shared.AppConfig) error {
+	// Update the file as written so relative paths and omitted defaults are preserved
+	rawConfig, err := shared.LoadRawConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range sortedPluginNames(config) {
+		plugin := config.Plugins[name]
+		if plugin.IsRemote() {
+			continue
+		}
+		sum, err := shared.FileSHA256(plugin.Path)
+		if err != nil {
+			fmt.Println(msg("validate.checksum_skipped", name, err))
+			continue
+		}
+		// Plugins of included fragments and overlays are left to their own files
+		raw, ok := rawConfig.Plugins[name]
+		if !ok {
+			fmt.Println(msg("validate.checksum_included", name))
+			continue
+		}
+		raw.SHA256 = sum
+		rawConfig.Plugins[name] = raw
+		fmt.Println(msg("validate.checksum", name, sum))
+	}
+
+	if err := shared.SaveConfig(rawConfig, configPath); err != nil {
+		return err
+	}
+	fmt.Println(msg("validate.checksums_written", filepath.Clean(configPath)))
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+)
+
+// pathList collects repeated -path flags
+type pathList []string
+
+func (p *pathList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *pathList) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+// runWatch implements the watch command, which executes a plugin and executes it again whenever
+// the watched paths change, canceling the execution still running. The plugin is started once,
+// or kept warm by a running daemon unless -no-daemon is given.
+func runWatch(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	configPath := configFlag(fs)
+	profileFlag(fs)
+	var paths pathList
+	fs.Var(&paths, "path", "File or directory to watch, directories with everything below them (repeatable)")
+	debounce := fs.Duration("debounce", 300*time.Millisecond, "How long changes must settle before the plugin is executed again")
+	timeout := fs.Duration("timeout", 0, "Fail an execution with TIMEOUT if it runs longer (e.g. 5s)")
+	noDaemon := fs.Bool("no-daemon", false, "Start the plugin even if a running daemon keeps it warm")
+	outputFlag(fs)
+	logFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() < 1 || len(paths) == 0 {
+		fmt.Println(msg("watch.usage"))
+		os.Exit(1)
+	}
+	if *timeout < 0 {
+		fatal(msg("run.invalid_timeout", *timeout))
+	}
+
+	config := loadConfig(*configPath)
+	name := fs.Arg(0)
+	manager := shared.NewPluginManager(config)
+	defer manager.StopAll()
+	manager.SetProcessExitHandler(func(exit *shared.ProcessExit) {
+		log.Print(msg("run.plugin_exited", exit))
+	})
+	killOrphans(manager)
+	monitor := shared.NewMemoryMonitor(config.Memory)
+	monitor.Start(ctx)
+
+	p, err := prepareParallelPlugin(ctx, config, manager, name, parseParams(fs.Args()[1:]), "", "", !*noDaemon, monitor)
+	if err != nil {
+		manager.StopAll()
+		fatal(msg("error", err))
+	}
+	p.capture.Close()
+	if p.viaDaemon {
+		defer p.plugin.Close()
+	}
+
+	changes, err := shared.WatchPaths(ctx, paths, *debounce)
+	if err != nil {
+		manager.StopAll()
+		fatal(msg("error", err))
+	}
+	log.Print(msg("watch.started", strings.Join(paths, ", "), name))
+
+	shared.RerunOnChange(ctx, changes, func(runCtx context.Context, changed []string) {
+		if len(changed) > 0 {
+			log.Print(msg("watch.changed", strings.Join(changed, ", ")))
+		}
+		watchExecution(runCtx, config, p, *timeout)
+	})
+}
+
+// watchExecution executes the watched plugin once and reports it, unless it was superseded by
+// another change or the command was interrupted
+func watchExecution(ctx context.Context, config *shared.AppConfig, p *parallelPlugin, timeout time.Duration) {
+	executionID, err := shared.NewExecutionID()
+	if err != nil {
+		log.Print(msg("watch.failed", p.name, err))
+		return
+	}
+	capture := shared.NewOutputCapture(config.SpillPath(), p.handler.monitor)
+	defer capture.Close()
+	handler := &outputHandler{pluginName: p.name, executionID: executionID, redactor: p.redactor, capture: capture, monitor: p.handler.monitor}
+
+	// Each execution gets its own copy, which the plugin may not change for the next
+	params := make(map[string]string, len(p.params))
+	for k, v := range p.params {
+		params[k] = v
+	}
+	start := time.Now()
+	err = shared.ExecuteWithTimeout(shared.WithExecutionID(shared.WithCredential(ctx, p.credential), handler.executionID), p.plugin, p.name, timeout, params, handler)
+	end := time.Now()
+	if ctx.Err() != nil {
+		log.Print(msg("watch.canceled", p.name, end.Sub(start).Round(time.Millisecond)))
+		return
+	}
+
+	execution := &finishedExecution{
+		name:     p.name,
+		config:   p.config,
+		plugin:   p.plugin,
+		info:     p.info,
+		params:   params,
+		redactor: p.redactor,
+		handler:  handler,
+		start:    start.UnixNano(),
+		end:      end.UnixNano(),
+		err:      err,
+		metadata: outcomeMetadata(err, capture),
+	}
+	execution.report(config, !p.viaDaemon)
+	if err != nil {
+		log.Print(msg("watch.failed", p.name, p.redactor.String(err.Error())))
+	} else {
+		log.Print(msg("watch.succeeded", p.name, end.Sub(start).Round(time.Millisecond)))
+	}
+	log.Print(msg("watch.waiting"))
+}
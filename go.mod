@@ -3,14 +3,16 @@ module github.com/example/grpc-plugin-app
 go 1.21
 
 require (
+	github.com/fsnotify/fsnotify v1.7.0
+	golang.org/x/net v0.20.0
 	google.golang.org/grpc v1.56.0
 	google.golang.org/protobuf v1.32.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/golang/protobuf v1.5.3 // indirect
 	github.com/google/go-cmp v0.6.0 // indirect
-	golang.org/x/net v0.20.0 // indirect
 	golang.org/x/sys v0.17.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240123012728-ef4313101c80 // indirect
@@ -0,0 +1,39 @@
+package client
+
+import "github.com/example/grpc-plugin-app/pkg/shared"
+
+// Callbacks is an OutputHandler calling the functions that are set and ignoring the rest
+type Callbacks struct {
+	Output   func(message string) error
+	Progress func(progress shared.Progress) error
+	Result   func(result map[string]interface{}) error
+	Error    func(code, message, details string) error
+}
+
+func (c Callbacks) OnOutput(message string) error {
+	if c.Output == nil {
+		return nil
+	}
+	return c.Output(message)
+}
+
+func (c Callbacks) OnProgress(progress shared.Progress) error {
+	if c.Progress == nil {
+		return nil
+	}
+	return c.Progress(progress)
+}
+
+func (c Callbacks) OnResult(result map[string]interface{}) error {
+	if c.Result == nil {
+		return nil
+	}
+	return c.Result(result)
+}
+
+func (c Callbacks) OnError(code, message, details string) error {
+	if c.Error == nil {
+		return nil
+	}
+	return c.Error(code, message, details)
+}
@@ -0,0 +1,329 @@
+// Package client drives a plugin-app daemon from Go programs: listing its plugins, executing them
+// with streamed output, and querying their status and execution history.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultMaxRetries is how often a call is retried while the daemon is unavailable
+	DefaultMaxRetries = 3
+	// DefaultRetryDelay is the wait before the first retry, doubled for each further one
+	DefaultRetryDelay = 200 * time.Millisecond
+)
+
+// Options configure a Client
+type Options struct {
+	Socket     string        // Daemon socket, see AppConfig.DaemonSocketPath
+	Credential string        // Bearer token passed on to remote plugins, unless the call carries one
+	MaxRetries int           // 0 for DefaultMaxRetries, negative to never retry
+	RetryDelay time.Duration // 0 for DefaultRetryDelay
+}
+
+// PluginStatus is the state of a plugin in the daemon
+type PluginStatus struct {
+	Name        string
+	Type        string
+	Description string
+	State       string    // starting, ready, executing, unhealthy, restarting or stopped
+	Since       time.Time // When the plugin entered State; zero if it was never started
+	Running     bool
+	Restarts    int
+	Failovers   int
+	LastError   string
+	Queued      int                   // Executions waiting for a concurrency limit
+	Address     string                // Of a remote plugin, the one it is connected to after failovers
+	Usage       *shared.ResourceUsage // Of a running local plugin
+	Started     time.Time             // When the plugin last became ready after starting or restarting; zero unless it is running
+	Executions  int                   // In flight
+}
+
+// Execution is an execution the daemon journals, which callers can detach from and attach to
+type Execution struct {
+	ID       string
+	Plugin   string
+	Started  time.Time
+	Attached bool // A caller is streaming its output
+	Finished bool
+	Error    string // Why a finished execution failed
+}
+
+// Client talks to a daemon over its socket. It is safe for concurrent use.
+type Client struct {
+	options Options
+	conn    *grpc.ClientConn
+	control proto.DaemonClient
+	health  healthpb.HealthClient
+
+	mu      sync.Mutex
+	plugins map[string]shared.PluginInterface
+}
+
+// New returns a client for the daemon on options.Socket. The connection is made lazily, so New
+// succeeds while the daemon is still starting.
+func New(options Options) (*Client, error) {
+	if options.Socket == "" {
+		return nil, errors.New("daemon socket is required")
+	}
+	if options.MaxRetries == 0 {
+		options.MaxRetries = DefaultMaxRetries
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = DefaultRetryDelay
+	}
+	conn, err := grpc.Dial("unix://"+options.Socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to daemon at %s: %v", options.Socket, err)
+	}
+	return &Client{
+		options: options,
+		conn:    conn,
+		control: proto.NewDaemonClient(conn),
+		health:  healthpb.NewHealthClient(conn),
+		plugins: make(map[string]shared.PluginInterface),
+	}, nil
+}
+
+// ForConfig returns a client for the daemon serving config
+func ForConfig(config *shared.AppConfig, credential string) (*Client, error) {
+	return New(Options{Socket: config.DaemonSocketPath(), Credential: credential})
+}
+
+// Close closes the connections to the daemon
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, plugin := range c.plugins {
+		plugin.Close()
+		delete(c.plugins, name)
+	}
+	return c.conn.Close()
+}
+
+// List returns the configured plugins of the daemon, sorted by name
+func (c *Client) List(ctx context.Context) ([]PluginStatus, error) {
+	var resp *proto.ListPluginsResponse
+	err := c.retry(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = c.control.ListPlugins(ctx, &proto.ListPluginsRequest{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	plugins := make([]PluginStatus, len(resp.Plugins))
+	for i, plugin := range resp.Plugins {
+		plugins[i] = statusFromProto(plugin)
+	}
+	return plugins, nil
+}
+
+// Status returns the state of one plugin; the error has codes.NotFound if the daemon doesn't know it
+func (c *Client) Status(ctx context.Context, name string) (*PluginStatus, error) {
+	var resp *proto.DaemonPluginStatus
+	err := c.retry(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = c.control.PluginStatus(ctx, &proto.PluginStatusRequest{Name: name})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	state := statusFromProto(resp)
+	return &state, nil
+}
+
+// History returns the executions the daemon recorded for a plugin, newest first. A limit of 0
+// returns all of them.
+func (c *Client) History(ctx context.Context, plugin string, limit int) ([]shared.HistoryRecord, error) {
+	var resp *proto.HistoryResponse
+	err := c.retry(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = c.control.History(ctx, &proto.HistoryRequest{Plugin: plugin, Limit: int32(limit)})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	records := make([]shared.HistoryRecord, len(resp.Entries))
+	for i, entry := range resp.Entries {
+		records[i] = shared.HistoryRecord{
+			Time:     time.Unix(0, entry.Time),
+			Plugin:   entry.Plugin,
+			Version:  entry.Version,
+			Params:   entry.Params,
+			Success:  entry.Success,
+			Error:    entry.Error,
+			Duration: shared.Duration(entry.Duration),
+		}
+	}
+	return records, nil
+}
+
+// Info returns the information of a plugin, starting it in the daemon if needed
+func (c *Client) Info(ctx context.Context, name string) (*shared.PluginInfo, error) {
+	plugin, err := c.plugin(name)
+	if err != nil {
+		return nil, err
+	}
+	var info *shared.PluginInfo
+	err = c.retry(ctx, func(ctx context.Context) error {
+		var err error
+		info, err = plugin.GetInfo(c.withCredential(ctx))
+		return err
+	})
+	return info, err
+}
+
+// Execute runs a plugin in the daemon, streaming its output to handler; see Callbacks for a
+// handler built from functions. The daemon is waited for, but an execution is never retried once
+// started, as plugins may have side effects.
+func (c *Client) Execute(ctx context.Context, name string, params map[string]string, handler shared.OutputHandler) error {
+	plugin, err := c.plugin(name)
+	if err != nil {
+		return err
+	}
+	if err := c.retry(ctx, c.ping); err != nil {
+		return fmt.Errorf("daemon unavailable: %v", err)
+	}
+	return plugin.Execute(c.withCredential(ctx), params, handler)
+}
+
+// Executions returns the executions that can be attached to, oldest first. Executions are
+// journaled when started with shared.WithDetachable.
+func (c *Client) Executions(ctx context.Context) ([]Execution, error) {
+	var resp *proto.ExecutionsResponse
+	err := c.retry(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = c.control.Executions(ctx, &proto.ExecutionsRequest{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	executions := make([]Execution, len(resp.Executions))
+	for i, execution := range resp.Executions {
+		executions[i] = Execution{
+			ID:       execution.Id,
+			Plugin:   execution.Plugin,
+			Started:  time.Unix(0, execution.Started),
+			Attached: execution.Attached,
+			Finished: execution.Finished,
+			Error:    execution.Error,
+		}
+	}
+	return executions, nil
+}
+
+// Detach lets an execution go on once its callers stop streaming it, instead of canceling it;
+// the error has codes.NotFound if the daemon doesn't know the execution
+func (c *Client) Detach(ctx context.Context, id string) error {
+	return c.retry(ctx, func(ctx context.Context) error {
+		_, err := c.control.Detach(ctx, &proto.DetachRequest{Id: id})
+		return err
+	})
+}
+
+// Attach streams an execution of the named plugin to handler, from its start to its end. The
+// execution is canceled when ctx is done unless it was detached from again.
+func (c *Client) Attach(ctx context.Context, name, id string, handler shared.OutputHandler) error {
+	plugin, err := c.plugin(name)
+	if err != nil {
+		return err
+	}
+	if err := c.retry(ctx, c.ping); err != nil {
+		return fmt.Errorf("daemon unavailable: %v", err)
+	}
+	return shared.Attach(ctx, plugin, id, handler)
+}
+
+// plugin returns the client routing calls to the named plugin
+func (c *Client) plugin(name string) (shared.PluginInterface, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if plugin, ok := c.plugins[name]; ok {
+		return plugin, nil
+	}
+	plugin, err := shared.DialDaemon(c.options.Socket, name)
+	if err != nil {
+		return nil, err
+	}
+	c.plugins[name] = plugin
+	return plugin, nil
+}
+
+func (c *Client) ping(ctx context.Context) error {
+	_, err := c.health.Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+// withCredential adds the client's credential unless the call carries its own
+func (c *Client) withCredential(ctx context.Context) context.Context {
+	if shared.CredentialFromContext(ctx) != "" || c.options.Credential == "" {
+		return ctx
+	}
+	return shared.WithCredential(ctx, c.options.Credential)
+}
+
+// retry calls call until it succeeds, fails with anything but codes.Unavailable, or the retries
+// are used up
+func (c *Client) retry(ctx context.Context, call func(ctx context.Context) error) error {
+	delay := c.options.RetryDelay
+	for attempt := 0; ; attempt++ {
+		err := call(ctx)
+		if err == nil || status.Code(err) != codes.Unavailable || attempt >= c.options.MaxRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func statusFromProto(state *proto.DaemonPluginStatus) PluginStatus {
+	converted := PluginStatus{
+		Name:        state.Name,
+		Type:        state.Type,
+		Description: state.Description,
+		State:       state.State,
+		Running:     state.Running,
+		Restarts:    int(state.Restarts),
+		Failovers:   int(state.Failovers),
+		LastError:   state.LastError,
+		Queued:      int(state.Queued),
+		Address:     state.Address,
+		Executions:  int(state.Executions),
+	}
+	if state.Since != 0 {
+		converted.Since = time.Unix(0, state.Since)
+	}
+	if state.Started != 0 {
+		converted.Started = time.Unix(0, state.Started)
+	}
+	if usage := state.Usage; usage != nil {
+		converted.Usage = &shared.ResourceUsage{
+			PID:        int(usage.Pid),
+			CPUSeconds: usage.CpuSeconds,
+			CPUPercent: usage.CpuPercent,
+			RSSBytes:   usage.RssBytes,
+			OpenFDs:    int(usage.OpenFds),
+		}
+	}
+	return converted
+}
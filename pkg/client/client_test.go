@@ -0,0 +1,199 @@
+package client
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// greeter greets the name parameter and reports the authorization the call arrived with
+type greeter struct{}
+
+func (greeter) GetInfo(ctx context.Context) (*shared.PluginInfo, error) {
+	return &shared.PluginInfo{Name: "greeter", Version: "1.0.0"}, nil
+}
+
+func (greeter) Execute(ctx context.Context, params map[string]string, output shared.OutputHandler) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if err := output.OnOutput("hello " + params["name"]); err != nil {
+		return err
+	}
+	return output.OnOutput("auth " + strings.Join(md.Get("authorization"), ","))
+}
+
+func (greeter) ReportExecutionSummary(startTime, endTime int64, success bool, err error, metadata map[string]string, metrics map[string]float64) (*shared.ExecutionSummary, error) {
+	return &shared.ExecutionSummary{}, nil
+}
+
+func (greeter) ValidateParameters(params map[string]string) error { return nil }
+
+func (greeter) Close() error { return nil }
+
+func TestClient(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	proto.RegisterPluginServer(server, &shared.GRPCServer{Impl: greeter{}})
+	shared.StartHealthServer(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	config := &shared.AppConfig{
+		StateDir: t.TempDir(),
+		Plugins: map[string]shared.PluginConfig{
+			"greeter": {Type: shared.PluginTypeRemote, Address: listener.Addr().String(), Description: "Greets"},
+		},
+	}
+	client, err := ForConfig(config, "client-token")
+	if err != nil {
+		t.Fatalf("ForConfig() error = %v", err)
+	}
+	defer client.Close()
+
+	// The client waits for a daemon that is still starting
+	daemon := shared.NewDaemon(shared.NewPluginManager(config))
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		if err := daemon.Listen(config.DaemonSocketPath()); err != nil {
+			t.Errorf("Listen() error = %v", err)
+			return
+		}
+		daemon.Serve()
+	}()
+	defer daemon.Stop()
+
+	t.Run("Execute", func(t *testing.T) {
+		var output []string
+		handler := Callbacks{Output: func(message string) error {
+			output = append(output, message)
+			return nil
+		}}
+		if err := client.Execute(context.Background(), "greeter", map[string]string{"name": "client"}, handler); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		want := []string{"hello client", "auth Bearer client-token"}
+		if strings.Join(output, "|") != strings.Join(want, "|") {
+			t.Errorf("output = %q, want %q", output, want)
+		}
+
+		// A credential of the call wins over the client's
+		output = nil
+		ctx := shared.WithCredential(context.Background(), "call-token")
+		if err := client.Execute(ctx, "greeter", map[string]string{"name": "again"}, handler); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if len(output) != 2 || output[1] != "auth Bearer call-token" {
+			t.Errorf("output = %q, want the call's credential", output)
+		}
+	})
+
+	t.Run("Info", func(t *testing.T) {
+		info, err := client.Info(context.Background(), "greeter")
+		if err != nil || info.Name != "greeter" {
+			t.Errorf("Info() = %v, %v", info, err)
+		}
+		if _, err := client.Info(context.Background(), "missing"); status.Code(err) != codes.NotFound {
+			t.Errorf("Info() of unknown plugin error = %v, want NotFound", err)
+		}
+	})
+
+	t.Run("List and Status", func(t *testing.T) {
+		plugins, err := client.List(context.Background())
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		if len(plugins) != 1 || plugins[0].Name != "greeter" || plugins[0].State != "ready" || plugins[0].Since.IsZero() || plugins[0].Started.IsZero() || plugins[0].Description != "Greets" {
+			t.Errorf("List() = %+v", plugins)
+		}
+		if _, err := client.Status(context.Background(), "missing"); status.Code(err) != codes.NotFound {
+			t.Errorf("Status() of unknown plugin error = %v, want NotFound", err)
+		}
+	})
+
+	t.Run("History", func(t *testing.T) {
+		records, err := client.History(context.Background(), "greeter", 1)
+		if err != nil {
+			t.Fatalf("History() error = %v", err)
+		}
+		if len(records) != 1 || records[0].Params["name"] != "again" || !records[0].Success || records[0].Version != "1.0.0" {
+			t.Errorf("History() = %+v, want the latest execution", records)
+		}
+		if _, err := client.History(context.Background(), "greeter", -1); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("History() with negative limit error = %v, want InvalidArgument", err)
+		}
+	})
+
+	t.Run("Detach and Attach", func(t *testing.T) {
+		// The caller detaches as soon as the execution started, which then runs to its end
+		ctx, cancel := context.WithCancel(context.Background())
+		var id string
+		ctx = shared.WithDetachable(ctx, func(started string) {
+			id = started
+			if err := client.Detach(context.Background(), id); err != nil {
+				t.Errorf("Detach() error = %v", err)
+			}
+			cancel()
+		})
+		client.Execute(ctx, "greeter", map[string]string{"name": "detached"}, Callbacks{})
+		if id == "" {
+			t.Fatal("no execution id received")
+		}
+
+		var output []string
+		handler := Callbacks{Output: func(message string) error {
+			output = append(output, message)
+			return nil
+		}}
+		if err := client.Attach(context.Background(), "greeter", id, handler); err != nil {
+			t.Fatalf("Attach() error = %v", err)
+		}
+		if len(output) != 2 || output[0] != "hello detached" {
+			t.Errorf("output = %q, want the whole execution", output)
+		}
+		executions, err := client.Executions(context.Background())
+		if err != nil {
+			t.Fatalf("Executions() error = %v", err)
+		}
+		if len(executions) != 1 || executions[0].ID != id || executions[0].Plugin != "greeter" || !executions[0].Finished {
+			t.Errorf("Executions() = %+v", executions)
+		}
+		if err := client.Attach(context.Background(), "greeter", "missing", handler); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("Attach() of unknown execution error = %v, want NotFound", err)
+		}
+	})
+}
+
+func TestClient_NoDaemon(t *testing.T) {
+	client, err := New(Options{Socket: filepath.Join(t.TempDir(), "none.sock"), MaxRetries: 2, RetryDelay: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+
+	start := time.Now()
+	if _, err := client.List(context.Background()); status.Code(err) != codes.Unavailable {
+		t.Errorf("List() error = %v, want Unavailable", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("List() gave up after %v, want two retries", elapsed)
+	}
+	if err := client.Execute(context.Background(), "any", nil, Callbacks{}); err == nil || !strings.Contains(err.Error(), "daemon unavailable") {
+		t.Errorf("Execute() error = %v, want daemon unavailable", err)
+	}
+
+	if _, err := New(Options{}); err == nil {
+		t.Error("New() without socket succeeded")
+	}
+}
@@ -0,0 +1,114 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/example/grpc-plugin-app/proto"
+)
+
+// Params gives plugins typed access to execution parameters, preferring the typed
+// values sent by the host and falling back to the string map for older hosts
+type Params struct {
+	typed   map[string]interface{}
+	strings map[string]string
+}
+
+// NewParams wraps the parameters of an execute request
+func NewParams(req *proto.ExecuteRequest) *Params {
+	p := &Params{
+		typed:   make(map[string]interface{}),
+		strings: req.Params,
+	}
+	if req.TypedParams != nil {
+		p.typed = req.TypedParams.AsMap()
+	}
+	return p
+}
+
+// Has reports whether the parameter was provided
+func (p *Params) Has(name string) bool {
+	if _, ok := p.typed[name]; ok {
+		return true
+	}
+	_, ok := p.strings[name]
+	return ok
+}
+
+// String returns the parameter as a string, or "" if it wasn't provided
+func (p *Params) String(name string) string {
+	if s, ok := p.strings[name]; ok {
+		return s
+	}
+	switch v := p.typed[name].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// Float returns the parameter as a number
+func (p *Params) Float(name string) (float64, error) {
+	if v, ok := p.typed[name].(float64); ok {
+		return v, nil
+	}
+	if !p.Has(name) {
+		return 0, fmt.Errorf("missing parameter: %s", name)
+	}
+	f, err := strconv.ParseFloat(p.String(name), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number for %s: %v", name, err)
+	}
+	return f, nil
+}
+
+// Bool returns the parameter as a boolean
+func (p *Params) Bool(name string) (bool, error) {
+	if v, ok := p.typed[name].(bool); ok {
+		return v, nil
+	}
+	if !p.Has(name) {
+		return false, fmt.Errorf("missing parameter: %s", name)
+	}
+	b, err := strconv.ParseBool(p.String(name))
+	if err != nil {
+		return false, fmt.Errorf("invalid boolean for %s: %v", name, err)
+	}
+	return b, nil
+}
+
+// Value returns the parameter as a decoded JSON value (object, list, number, bool or string)
+func (p *Params) Value(name string) (interface{}, error) {
+	if v, ok := p.typed[name]; ok {
+		return v, nil
+	}
+	if !p.Has(name) {
+		return nil, fmt.Errorf("missing parameter: %s", name)
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(p.String(name)), &v); err != nil {
+		return p.String(name), nil
+	}
+	return v, nil
+}
+
+// Names returns the names of all provided parameters
+func (p *Params) Names() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for name := range p.strings {
+		seen[name] = true
+		names = append(names, name)
+	}
+	for name := range p.typed {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
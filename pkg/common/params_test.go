@@ -0,0 +1,79 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+	"github.com/example/grpc-plugin-app/proto"
+)
+
+func TestParams(t *testing.T) {
+	schema := map[string]shared.ParameterSpec{
+		"count":   {Name: "count", Type: "int"},
+		"enabled": {Name: "enabled", Type: "bool"},
+		"tags":    {Name: "tags", Type: "list"},
+		"name":    {Name: "name", Type: "string"},
+	}
+	strParams := map[string]string{
+		"count":   "42",
+		"enabled": "true",
+		"tags":    `["a","b"]`,
+		"name":    "demo",
+	}
+
+	typedParams, err := shared.TypedParams(schema, strParams)
+	if err != nil {
+		t.Fatalf("TypedParams() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  *proto.ExecuteRequest
+	}{
+		{
+			name: "Typed values from host",
+			req:  &proto.ExecuteRequest{Params: strParams, TypedParams: typedParams},
+		},
+		{
+			name: "String values only (older host)",
+			req:  &proto.ExecuteRequest{Params: strParams},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := NewParams(tt.req)
+
+			if got, err := params.Float("count"); err != nil || got != 42 {
+				t.Errorf("Float(count) = %v, %v, want 42", got, err)
+			}
+			if got, err := params.Bool("enabled"); err != nil || !got {
+				t.Errorf("Bool(enabled) = %v, %v, want true", got, err)
+			}
+			if got, err := params.Value("tags"); err != nil || len(got.([]interface{})) != 2 {
+				t.Errorf("Value(tags) = %v, %v, want 2 elements", got, err)
+			}
+			if got := params.String("name"); got != "demo" {
+				t.Errorf("String(name) = %q, want %q", got, "demo")
+			}
+			if _, err := params.Float("missing"); err == nil {
+				t.Errorf("Float(missing) expected error")
+			}
+		})
+	}
+}
+
+func TestParams_InvalidTypedValueFallsBackToString(t *testing.T) {
+	schema := map[string]shared.ParameterSpec{"num1": {Name: "num1", Type: "float"}}
+	strParams := map[string]string{"num1": "abc"}
+
+	typedParams, err := shared.TypedParams(schema, strParams)
+	if err != nil {
+		t.Fatalf("TypedParams() error = %v", err)
+	}
+
+	params := NewParams(&proto.ExecuteRequest{Params: strParams, TypedParams: typedParams})
+	if _, err := params.Float("num1"); err == nil {
+		t.Errorf("Float(num1) expected error for non-numeric value")
+	}
+}
@@ -0,0 +1,185 @@
+package common
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+)
+
+// DefaultProgressInterval is the least time between two progress messages sent as work completes
+const DefaultProgressInterval = 250 * time.Millisecond
+
+// ProgressSender is the part of an Execute stream progress is reported on
+type ProgressSender interface {
+	Send(*proto.ExecuteOutput) error
+}
+
+// ProgressTracker reports the progress of an execution from the work units it completes.
+// The plugin declares its stages and total work units and counts units as it goes; the
+// tracker computes the percentage, rate and time left and batches messages so that tight
+// loops don't flood the stream. Each stage has an equal share of the percentage, within
+// which the units completed in it advance, so 100% is only reached once the last stage is
+// done. It is safe for concurrent use.
+type ProgressTracker struct {
+	mu         sync.Mutex
+	stream     ProgressSender
+	stages     []string
+	step       int32
+	total      int64
+	done       int64
+	stageDone  int64 // units done when the current stage began
+	start      time.Time
+	stageStart time.Time
+	lastSent   time.Time
+	pending    bool // units completed since the last message
+	interval   time.Duration
+	now        func() time.Time
+}
+
+// NewProgress returns a tracker reporting on stream. stages names the stages of the execution
+// in order; the tracker starts in the first one.
+func NewProgress(stream ProgressSender, stages ...string) *ProgressTracker {
+	t := &ProgressTracker{
+		stream:   stream,
+		stages:   stages,
+		interval: DefaultProgressInterval,
+		now:      time.Now,
+	}
+	t.start = t.now()
+	t.stageStart = t.start
+	if len(stages) > 0 {
+		t.step = 1
+	}
+	return t
+}
+
+// SetInterval changes how often progress is sent while units complete; 0 sends every update
+func (t *ProgressTracker) SetInterval(interval time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.interval = interval
+}
+
+// SetTotal sets the number of work units of the execution, which may become known only after
+// the first stages
+func (t *ProgressTracker) SetTotal(total int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total = total
+}
+
+// Start sends the progress of the first stage
+func (t *ProgressTracker) Start() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.send()
+}
+
+// Stage moves on to one of the declared stages and sends progress right away
+func (t *ProgressTracker) Stage(name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, stage := range t.stages {
+		if stage == name {
+			t.step = int32(i + 1)
+			t.stageStart = t.now()
+			t.stageDone = t.done
+			return t.send()
+		}
+	}
+	return fmt.Errorf("undeclared progress stage: %s", name)
+}
+
+// Add records completed work units, sending progress once the interval has passed since the
+// last message or when all units are done
+func (t *ProgressTracker) Add(units int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done += units
+	t.pending = true
+	if t.now().Sub(t.lastSent) < t.interval && (t.total == 0 || t.done < t.total) {
+		return nil
+	}
+	return t.send()
+}
+
+// Done completes all remaining work and sends the final progress of 100%
+func (t *ProgressTracker) Done() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done < t.total {
+		t.done = t.total
+	}
+	if last := int32(len(t.stages)); t.step != last {
+		t.step = last
+		t.stageStart = t.now()
+	}
+	return t.sendProgress(t.progress(100))
+}
+
+// Flush sends progress held back by batching
+func (t *ProgressTracker) Flush() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.pending {
+		return nil
+	}
+	return t.send()
+}
+
+// send reports the current progress; callers hold mu
+func (t *ProgressTracker) send() error {
+	return t.sendProgress(t.progress(t.percent()))
+}
+
+func (t *ProgressTracker) sendProgress(progress *proto.Progress) error {
+	t.lastSent = t.now()
+	t.pending = false
+	return t.stream.Send(&proto.ExecuteOutput{
+		Content: &proto.ExecuteOutput_Progress{Progress: progress},
+	})
+}
+
+// percent is the share of stages passed plus, within the current stage's share, that of the
+// units left when it began which are done since. A stage that began with no units left, or
+// before the total was known, advances only when the next one begins.
+func (t *ProgressTracker) percent() float32 {
+	stages := len(t.stages)
+	passed := 0
+	if stages == 0 {
+		stages = 1
+	} else if t.step > 0 {
+		passed = int(t.step - 1)
+	}
+	var fraction float32
+	if left := t.total - t.stageDone; left > 0 {
+		fraction = float32(t.done-t.stageDone) / float32(left)
+		if fraction > 1 {
+			fraction = 1
+		}
+	}
+	return (float32(passed) + fraction) * 100 / float32(stages)
+}
+
+func (t *ProgressTracker) progress(percent float32) *proto.Progress {
+	now := t.now()
+	progress := &proto.Progress{
+		PercentComplete: percent,
+		CurrentStep:     t.step,
+		TotalSteps:      int32(len(t.stages)),
+		StageElapsedMs:  now.Sub(t.stageStart).Milliseconds(),
+	}
+	if t.step > 0 {
+		progress.Stage = t.stages[t.step-1]
+	}
+	if elapsed := now.Sub(t.start).Seconds(); elapsed > 0 && t.done > 0 {
+		rate := float64(t.done) / elapsed
+		progress.UnitsPerSecond = rate
+		if t.total > t.done {
+			progress.RemainingMs = int64(float64(t.total-t.done) / rate * 1000)
+		}
+	}
+	return progress
+}
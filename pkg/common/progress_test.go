@@ -0,0 +1,208 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+)
+
+// progressRecorder keeps the progress messages sent on a stream
+type progressRecorder struct {
+	sent []*proto.Progress
+}
+
+func (r *progressRecorder) Send(out *proto.ExecuteOutput) error {
+	r.sent = append(r.sent, out.GetProgress())
+	return nil
+}
+
+// fakeClock advances only when told to
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestProgress(stages ...string) (*ProgressTracker, *progressRecorder, *fakeClock) {
+	recorder := &progressRecorder{}
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	tracker := NewProgress(recorder, stages...)
+	tracker.now = clock.Now
+	tracker.start = clock.now
+	tracker.stageStart = clock.now
+	return tracker, recorder, clock
+}
+
+func TestProgressTracker(t *testing.T) {
+	tracker, recorder, clock := newTestProgress("Loading", "Working", "Finishing")
+	tracker.SetTotal(10)
+
+	if err := tracker.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	clock.now = clock.now.Add(time.Second)
+	if err := tracker.Stage("Working"); err != nil {
+		t.Fatalf("Stage() error = %v", err)
+	}
+
+	// Units completed within the interval are batched into one message
+	for i := 0; i < 4; i++ {
+		clock.now = clock.now.Add(50 * time.Millisecond)
+		if err := tracker.Add(1); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if len(recorder.sent) != 2 {
+		t.Fatalf("sent %d messages before the interval passed, want 2", len(recorder.sent))
+	}
+	clock.now = clock.now.Add(50 * time.Millisecond)
+	tracker.Add(1)
+	if len(recorder.sent) != 3 {
+		t.Fatalf("sent %d messages after the interval passed, want 3", len(recorder.sent))
+	}
+
+	got := recorder.sent[2]
+	if got.PercentComplete != 50 {
+		t.Errorf("PercentComplete = %v, want 50", got.PercentComplete)
+	}
+	if got.Stage != "Working" || got.CurrentStep != 2 || got.TotalSteps != 3 {
+		t.Errorf("stage = %s step %d/%d, want Working step 2/3", got.Stage, got.CurrentStep, got.TotalSteps)
+	}
+	if got.StageElapsedMs != 250 {
+		t.Errorf("StageElapsedMs = %d, want 250", got.StageElapsedMs)
+	}
+	// 5 units in 1.25s
+	if got.UnitsPerSecond != 4 {
+		t.Errorf("UnitsPerSecond = %v, want 4", got.UnitsPerSecond)
+	}
+	if got.RemainingMs != 1250 {
+		t.Errorf("RemainingMs = %d, want 1250", got.RemainingMs)
+	}
+
+	// Completing the last unit is always sent, and completes only the current stage
+	tracker.Add(5)
+	if last := recorder.sent[len(recorder.sent)-1]; !approx(last.PercentComplete, 200.0/3) || last.RemainingMs != 0 {
+		t.Errorf("last progress = %v%%, %dms left, want 66.7%%, 0ms", last.PercentComplete, last.RemainingMs)
+	}
+
+	clock.now = clock.now.Add(time.Second)
+	if err := tracker.Done(); err != nil {
+		t.Fatalf("Done() error = %v", err)
+	}
+	if last := recorder.sent[len(recorder.sent)-1]; last.Stage != "Finishing" || last.CurrentStep != 3 || last.StageElapsedMs != 0 || last.PercentComplete != 100 {
+		t.Errorf("final progress = %v%% in %s step %d after %dms, want 100%% in Finishing step 3 just started", last.PercentComplete, last.Stage, last.CurrentStep, last.StageElapsedMs)
+	}
+}
+
+// approx reports whether two percentages agree to within rounding
+func approx(got, want float32) bool {
+	return got > want-0.01 && got < want+0.01
+}
+
+func TestProgressTracker_StageTransitions(t *testing.T) {
+	// Units are counted in two of four stages, as the addition plugin does
+	tracker, recorder, _ := newTestProgress("Initialization", "Processing Input", "Calculating", "Finalizing")
+	tracker.SetInterval(0)
+	tracker.Start()
+	tracker.SetTotal(4)
+
+	steps := []struct {
+		do          func() error
+		wantStage   string
+		wantPercent float32
+	}{
+		{func() error { return tracker.Stage("Processing Input") }, "Processing Input", 25},
+		{func() error { return tracker.Add(1) }, "Processing Input", 31.25},
+		{func() error { return tracker.Add(1) }, "Processing Input", 37.5},
+		// Half of the units are left for the next stage, whose share they fill
+		{func() error { return tracker.Stage("Calculating") }, "Calculating", 50},
+		{func() error { return tracker.Add(1) }, "Calculating", 62.5},
+		{func() error { return tracker.Add(1) }, "Calculating", 75},
+		// No units are left, so the last stage advances only when done
+		{func() error { return tracker.Stage("Finalizing") }, "Finalizing", 75},
+		{func() error { return tracker.Flush() }, "Finalizing", 75},
+		{tracker.Done, "Finalizing", 100},
+	}
+
+	last := float32(0)
+	for i, step := range steps {
+		if err := step.do(); err != nil {
+			t.Fatalf("step %d: error = %v", i, err)
+		}
+		got := recorder.sent[len(recorder.sent)-1]
+		if got.Stage != step.wantStage || !approx(got.PercentComplete, step.wantPercent) {
+			t.Errorf("step %d: progress = %v%% in %s, want %v%% in %s", i, got.PercentComplete, got.Stage, step.wantPercent, step.wantStage)
+		}
+		if got.PercentComplete < last {
+			t.Errorf("step %d: progress went back from %v%% to %v%%", i, last, got.PercentComplete)
+		}
+		if got.PercentComplete >= 100 && i != len(steps)-1 {
+			t.Errorf("step %d: reported 100%% before Done()", i)
+		}
+		last = got.PercentComplete
+	}
+}
+
+func TestProgressTracker_EveryUnitInOneStage(t *testing.T) {
+	// The hello plugin counts all of its units in the middle stage
+	tracker, recorder, _ := newTestProgress("Starting", "Processing", "Finalizing")
+	tracker.SetInterval(0)
+	tracker.SetTotal(3)
+	tracker.Stage("Processing")
+	tracker.Add(3)
+	if got := recorder.sent[len(recorder.sent)-1]; got.Stage != "Processing" || !approx(got.PercentComplete, 200.0/3) {
+		t.Errorf("progress = %v%% in %s, want 66.7%% in Processing", got.PercentComplete, got.Stage)
+	}
+	tracker.Done()
+	if got := recorder.sent[len(recorder.sent)-1]; got.PercentComplete != 100 {
+		t.Errorf("Done() progress = %v%%, want 100%%", got.PercentComplete)
+	}
+}
+
+func TestProgressTracker_Stages(t *testing.T) {
+	tests := []struct {
+		name        string
+		stage       string
+		wantPercent float32
+		wantErr     bool
+	}{
+		{name: "First stage", stage: "a", wantPercent: 0},
+		{name: "Later stage", stage: "c", wantPercent: 50},
+		{name: "Undeclared stage", stage: "e", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, recorder, _ := newTestProgress("a", "b", "c", "d")
+			err := tracker.Stage(tt.stage)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Stage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			// Without a total, progress follows the stages
+			if got := recorder.sent[0].PercentComplete; got != tt.wantPercent {
+				t.Errorf("PercentComplete = %v, want %v", got, tt.wantPercent)
+			}
+		})
+	}
+}
+
+func TestProgressTracker_Flush(t *testing.T) {
+	tracker, recorder, _ := newTestProgress()
+	tracker.SetTotal(100)
+	tracker.Start()
+	tracker.Add(3)
+	if len(recorder.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(recorder.sent))
+	}
+	tracker.Flush()
+	tracker.Flush()
+	if len(recorder.sent) != 2 || recorder.sent[1].PercentComplete != 3 {
+		t.Errorf("Flush() sent %d messages, want the batched progress once", len(recorder.sent)-1)
+	}
+}
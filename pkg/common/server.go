@@ -1,41 +1,94 @@
 package common
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"net"
 	"os"
 	"os/exec"
+	"os/signal"
+	"syscall"
 
 	"github.com/example/grpc-plugin-app/pkg/shared"
 	"github.com/example/grpc-plugin-app/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-// RunGRPCServer initializes and runs a gRPC server for a plugin
-func RunGRPCServer(plugin proto.PluginServer, port int) error {
+// ProtocolVersion is the host/plugin protocol spoken by plugins built with this package; report it from GetInfo
+const ProtocolVersion = shared.ProtocolVersion
+
+// RunGRPCServer initializes and runs a gRPC server for a plugin. Interceptors run after the
+// token check, in the order given.
+func RunGRPCServer(plugin proto.PluginServer, port int, interceptors ...shared.ServerInterceptors) error {
 	if port <= 0 {
 		return fmt.Errorf("invalid port: %d", port)
 	}
 
-	// Create and configure gRPC server
-	server := grpc.NewServer()
-	proto.RegisterPluginServer(server, plugin)
+	// Serve TLS if the host handed us certificates
+	tlsOpts, err := shared.ServerTLSFromEnv()
+	if err != nil {
+		return err
+	}
+
+	// Apply the message size and stream limits configured on the host
+	limitOpts, err := shared.ServerLimitsFromEnv()
+	if err != nil {
+		return err
+	}
+
+	// Create and configure gRPC server, rejecting callers without the host's token
+	chain := shared.AuthInterceptors(os.Getenv(shared.AuthTokenEnvVar))
+	for _, set := range interceptors {
+		chain = chain.Add(set)
+	}
+	serverOpts := append(chain.ServerOptions(), tlsOpts...)
+	server := grpc.NewServer(append(serverOpts, limitOpts...)...)
+	proto.RegisterPluginServer(server, diagnosticsServer{plugin})
 
 	// Add health checking
-	shared.StartHealthServer(server)
+	healthServer := shared.StartHealthServer(server)
 
 	// Listen on specified port
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	listener, err := shared.Listen(port)
 	if err != nil {
-		return fmt.Errorf("failed to listen on port %d: %v", port, err)
+		return err
 	}
 
+	// Let in-flight calls finish when the host stops us; it kills the process once its grace period is over
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+	defer func() {
+		signal.Stop(stop)
+		close(stop)
+	}()
+	go func() {
+		if _, ok := <-stop; ok {
+			healthServer.Shutdown()
+			server.GracefulStop()
+		}
+	}()
+
 	// Start serving
 	log.Printf("Starting plugin server on port %d\n", port)
 	return server.Serve(listener)
 }
 
+// diagnosticsServer answers Diagnostics with the plugin process's goroutine and heap dumps
+// unless the plugin implements the RPC itself
+type diagnosticsServer struct {
+	proto.PluginServer
+}
+
+func (s diagnosticsServer) Diagnostics(ctx context.Context, req *proto.DiagnosticsRequest) (*proto.DiagnosticsResponse, error) {
+	resp, err := s.PluginServer.Diagnostics(ctx, req)
+	if status.Code(err) != codes.Unimplemented {
+		return resp, err
+	}
+	return &proto.DiagnosticsResponse{Dumps: shared.ProcessDumps()}, nil
+}
+
 // StartPluginFromConfig starts a plugin using the shared configuration
 func StartPluginFromConfig(config shared.PluginConfig) (*exec.Cmd, error) {
 	// Start the plugin process
@@ -1,8 +1,10 @@
 package common
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/example/grpc-plugin-app/pkg/shared"
@@ -109,3 +111,14 @@ func TestStartPluginFromConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestDiagnosticsServer(t *testing.T) {
+	server := diagnosticsServer{&MockPluginServer{}}
+	resp, err := server.Diagnostics(context.Background(), &proto.DiagnosticsRequest{Reason: "test"})
+	if err != nil {
+		t.Fatalf("Diagnostics() error = %v", err)
+	}
+	if !strings.Contains(resp.Dumps["goroutine"], "TestDiagnosticsServer") {
+		t.Errorf("Diagnostics() goroutine dump does not include the calling goroutine")
+	}
+}
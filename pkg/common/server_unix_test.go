@@ -0,0 +1,56 @@
+//go:build unix
+
+package common
+
+import (
+	"context"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/pkg/shared"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestRunGRPCServer_StopsOnSIGTERM(t *testing.T) {
+	t.Setenv(shared.AuthTokenEnvVar, "")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- RunGRPCServer(&MockPluginServer{}, port)
+	}()
+
+	// Signal only once the server answers, so the handler is in place
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true)); err != nil {
+		t.Fatalf("server did not start: %v", err)
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("RunGRPCServer() error = %v, want nil after SIGTERM", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunGRPCServer() did not return after SIGTERM")
+	}
+}
@@ -0,0 +1,93 @@
+package shared
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+)
+
+// LoadBalancingConsistentHash routes executions sharing an affinity value to the same instance
+const LoadBalancingConsistentHash = "consistent_hash"
+
+// affinityVirtualNodes is the number of ring points per instance, which evens out the key distribution
+const affinityVirtualNodes = 100
+
+// affinityContextKey is the context key holding the affinity value of a call
+type affinityContextKey struct{}
+
+func init() {
+	balancer.Register(base.NewBalancerBuilder(LoadBalancingConsistentHash, hashPickerBuilder{}, base.Config{}))
+}
+
+// WithAffinity returns a context whose calls are routed by consistent hashing of value
+func WithAffinity(ctx context.Context, value string) context.Context {
+	return context.WithValue(ctx, affinityContextKey{}, value)
+}
+
+// hashPickerBuilder builds a consistent-hash ring over the ready instances. The balancer rebuilds
+// it whenever an instance comes or goes, so only keys owned by that instance move.
+type hashPickerBuilder struct{}
+
+func (hashPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+
+	picker := &hashPicker{}
+	for sc, scInfo := range info.ReadySCs {
+		picker.all = append(picker.all, sc)
+		for i := 0; i < affinityVirtualNodes; i++ {
+			picker.ring = append(picker.ring, ringPoint{
+				hash:    affinityHash(fmt.Sprintf("%s#%d", scInfo.Address.Addr, i)),
+				subConn: sc,
+			})
+		}
+	}
+	sort.Slice(picker.ring, func(i, j int) bool { return picker.ring[i].hash < picker.ring[j].hash })
+	return picker
+}
+
+// ringPoint is one virtual node of an instance on the hash ring
+type ringPoint struct {
+	hash    uint64
+	subConn balancer.SubConn
+}
+
+// hashPicker sends calls carrying an affinity value to the owner of its ring segment and
+// spreads the others round-robin
+type hashPicker struct {
+	ring []ringPoint
+	all  []balancer.SubConn
+	next atomic.Uint32
+}
+
+func (p *hashPicker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	value, ok := info.Ctx.Value(affinityContextKey{}).(string)
+	if !ok {
+		n := p.next.Add(1)
+		return balancer.PickResult{SubConn: p.all[int(n)%len(p.all)]}, nil
+	}
+	return balancer.PickResult{SubConn: p.owner(value)}, nil
+}
+
+// owner returns the instance responsible for an affinity value
+func (p *hashPicker) owner(value string) balancer.SubConn {
+	h := affinityHash(value)
+	i := sort.Search(len(p.ring), func(i int) bool { return p.ring[i].hash >= h })
+	if i == len(p.ring) {
+		i = 0
+	}
+	return p.ring[i].subConn
+}
+
+// affinityHash maps a string onto the ring
+func affinityHash(s string) uint64 {
+	sum := sha256.Sum256([]byte(s))
+	return binary.BigEndian.Uint64(sum[:8])
+}
@@ -0,0 +1,72 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/resolver"
+)
+
+// fakeSubConn stands in for a connection to one replica
+type fakeSubConn struct {
+	balancer.SubConn
+	addr string
+}
+
+// buildHashPicker builds a picker over one fake SubConn per address
+func buildHashPicker(addrs ...string) *hashPicker {
+	info := base.PickerBuildInfo{ReadySCs: make(map[balancer.SubConn]base.SubConnInfo)}
+	for _, addr := range addrs {
+		info.ReadySCs[&fakeSubConn{addr: addr}] = base.SubConnInfo{Address: resolver.Address{Addr: addr}}
+	}
+	return hashPickerBuilder{}.Build(info).(*hashPicker)
+}
+
+// pickAddr returns the address of the replica chosen for ctx
+func pickAddr(t *testing.T, p *hashPicker, ctx context.Context) string {
+	t.Helper()
+	result, err := p.Pick(balancer.PickInfo{Ctx: ctx})
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	return result.SubConn.(*fakeSubConn).addr
+}
+
+func TestHashPicker(t *testing.T) {
+	full := buildHashPicker("a:1", "b:1", "c:1")
+	reduced := buildHashPicker("a:1", "b:1")
+
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		ctx := WithAffinity(context.Background(), fmt.Sprintf("customer-%d", i))
+		owner := pickAddr(t, full, ctx)
+		counts[owner]++
+
+		if again := pickAddr(t, full, ctx); again != owner {
+			t.Fatalf("Pick() routed the same key to %s and %s", owner, again)
+		}
+		// Only keys owned by the removed replica may move
+		if owner != "c:1" {
+			if moved := pickAddr(t, reduced, ctx); moved != owner {
+				t.Errorf("Pick() moved customer-%d from %s to %s after an unrelated replica left", i, owner, moved)
+			}
+		}
+	}
+	for _, addr := range []string{"a:1", "b:1", "c:1"} {
+		if counts[addr] < 200 {
+			t.Errorf("replica %s owns %d of 1000 keys, want a roughly even share", addr, counts[addr])
+		}
+	}
+
+	// Calls without an affinity value are spread over every replica
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		seen[pickAddr(t, full, context.Background())] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("Pick() without affinity used %d replicas, want 3", len(seen))
+	}
+}
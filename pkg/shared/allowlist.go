@@ -0,0 +1,170 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// AddressPolicy restricts which hosts remote plugins may connect to. Entries are IP addresses,
+// CIDR ranges, host names, or wildcards such as *.plugins.internal.
+type AddressPolicy struct {
+	networks []*net.IPNet
+	names    []string
+}
+
+// NewAddressPolicy parses an allowlist, returning nil if it is empty and nothing is restricted
+func NewAddressPolicy(allowlist []string) (*AddressPolicy, error) {
+	if len(allowlist) == 0 {
+		return nil, nil
+	}
+
+	policy := &AddressPolicy{}
+	for _, entry := range allowlist {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			return nil, fmt.Errorf("empty remote_allowlist entry")
+		case strings.Contains(entry, "/"):
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid remote_allowlist entry %s: %v", entry, err)
+			}
+			policy.networks = append(policy.networks, network)
+		case net.ParseIP(entry) != nil:
+			ip := net.ParseIP(entry)
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			policy.networks = append(policy.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		default:
+			policy.names = append(policy.names, entry)
+		}
+	}
+	return policy, nil
+}
+
+// allowsName reports whether a host name matches a name or wildcard entry
+func (p *AddressPolicy) allowsName(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, name := range p.names {
+		if suffix, ok := strings.CutPrefix(name, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+		} else if host == name {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsIP reports whether an address falls in an allowed range
+func (p *AddressPolicy) allowsIP(ip net.IP) bool {
+	for _, network := range p.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckTarget verifies a configured remote target without DNS lookups. Host names that match
+// no entry are only accepted if CIDR entries exist, in which case they are checked when dialing.
+func (p *AddressPolicy) CheckTarget(target string) error {
+	host, err := targetHost(target)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !p.allowsIP(ip) {
+			return fmt.Errorf("remote address %s is not in remote_allowlist", host)
+		}
+		return nil
+	}
+	if !p.allowsName(host) && len(p.networks) == 0 {
+		return fmt.Errorf("remote host %s is not in remote_allowlist", host)
+	}
+	return nil
+}
+
+// DialOption returns a dialer that enforces the policy on every connection made for target,
+// checking the addresses host names resolve to
+func (p *AddressPolicy) DialOption(target string) grpc.DialOption {
+	return grpc.WithContextDialer(p.Dialer(target, directDial))
+}
+
+// Dialer wraps next so that it only reaches addresses allowed by the policy
+func (p *AddressPolicy) Dialer(target string, next dialFunc) dialFunc {
+	host, _ := targetHost(target)
+	trusted := p.allowsName(host)
+
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if trusted || p.allowsName(host) {
+			return next(ctx, addr)
+		}
+
+		ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			if !p.allowsIP(ip) {
+				return nil, fmt.Errorf("remote address %s (%s) is not in remote_allowlist", host, ip)
+			}
+		}
+		return next(ctx, net.JoinHostPort(ips[0].String(), port))
+	}
+}
+
+// targetHost extracts the host from host:port, dns:///host:port and passthrough:///host:port targets
+func targetHost(target string) (string, error) {
+	rest := target
+	if scheme, after, ok := strings.Cut(target, "://"); ok {
+		if scheme != "dns" && scheme != "passthrough" {
+			return "", fmt.Errorf("remote target %s can't be checked against remote_allowlist (only dns and passthrough targets are supported)", target)
+		}
+		// Drop the authority, e.g. the DNS server in dns://8.8.8.8/host:port
+		_, rest, _ = strings.Cut(after, "/")
+	}
+	host, _, err := net.SplitHostPort(rest)
+	if err != nil {
+		return rest, nil
+	}
+	return host, nil
+}
+
+// checkRemoteAllowlist verifies every remote plugin target against the configured allowlist
+func (c *AppConfig) checkRemoteAllowlist() error {
+	policy, err := NewAddressPolicy(c.RemoteAllowlist)
+	if err != nil || policy == nil {
+		return err
+	}
+	for name, plugin := range c.Plugins {
+		if !plugin.IsRemote() {
+			continue
+		}
+		for _, target := range plugin.remoteTargets() {
+			if err := policy.CheckTarget(target); err != nil {
+				return fmt.Errorf("invalid configuration for plugin %q: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// remoteTargets returns every address a remote plugin may connect to
+func (p *PluginConfig) remoteTargets() []string {
+	if p.Address != "" {
+		return p.failoverAddresses()
+	}
+	return p.Addresses
+}
@@ -0,0 +1,104 @@
+package shared
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestAddressPolicy_CheckTarget(t *testing.T) {
+	policy, err := NewAddressPolicy([]string{"10.0.0.0/8", "192.168.1.5", "plugins.internal", "*.svc.cluster.local"})
+	if err != nil {
+		t.Fatalf("NewAddressPolicy() error = %v", err)
+	}
+	namesOnly, err := NewAddressPolicy([]string{"plugins.internal"})
+	if err != nil {
+		t.Fatalf("NewAddressPolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		policy  *AddressPolicy
+		target  string
+		wantErr bool
+	}{
+		{name: "IP in range", policy: policy, target: "10.1.2.3:50051"},
+		{name: "Single IP", policy: policy, target: "192.168.1.5:50051"},
+		{name: "IP outside range", policy: policy, target: "8.8.8.8:50051", wantErr: true},
+		{name: "Exact host name", policy: policy, target: "plugins.internal:50051"},
+		{name: "Wildcard host name", policy: policy, target: "dns:///calc.default.svc.cluster.local:50051"},
+		{name: "DNS target with authority", policy: policy, target: "dns://8.8.8.8/plugins.internal:50051"},
+		{name: "Unlisted name checked when dialing", policy: policy, target: "example.com:443"},
+		{name: "Unlisted name without ranges", policy: namesOnly, target: "example.com:443", wantErr: true},
+		{name: "Unsupported scheme", policy: policy, target: "unix:///tmp/plugin.sock", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.CheckTarget(tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckTarget(%q) error = %v, wantErr %v", tt.target, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewAddressPolicy(t *testing.T) {
+	if policy, err := NewAddressPolicy(nil); policy != nil || err != nil {
+		t.Errorf("NewAddressPolicy(nil) = %v, %v, want no policy", policy, err)
+	}
+	if _, err := NewAddressPolicy([]string{"10.0.0.0/33"}); err == nil {
+		t.Errorf("NewAddressPolicy() accepted an invalid CIDR")
+	}
+}
+
+func TestAddressPolicy_DialOption(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	StartHealthServer(server)
+	go server.Serve(listener)
+	defer server.Stop()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	tests := []struct {
+		name      string
+		allowlist []string
+		wantErr   bool
+	}{
+		{name: "Resolved address in range", allowlist: []string{"127.0.0.0/8"}},
+		{name: "Resolved address outside range", allowlist: []string{"10.0.0.0/8"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewAddressPolicy(tt.allowlist)
+			if err != nil {
+				t.Fatalf("NewAddressPolicy() error = %v", err)
+			}
+			target := "localhost:" + port
+			client, err := NewClientWithAddress(target, policy.DialOption(target))
+			if err != nil {
+				t.Fatalf("NewClientWithAddress() error = %v", err)
+			}
+			defer client.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			_, err = healthpb.NewHealthClient(client.(*GRPCClient).conn).Check(ctx, &healthpb.HealthCheckRequest{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Health check error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), "remote_allowlist") {
+				t.Errorf("Health check error = %v, want an allowlist violation", err)
+			}
+		})
+	}
+}
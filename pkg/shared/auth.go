@@ -0,0 +1,104 @@
+package shared
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// AuthTokenEnvVar is the environment variable used to hand the auth token to local plugins
+	AuthTokenEnvVar = "PLUGIN_AUTH_TOKEN"
+
+	authMetadataKey = "authorization"
+	authScheme      = "Bearer "
+)
+
+// GenerateAuthToken returns a random token suitable for authenticating a single plugin
+func GenerateAuthToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate auth token: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// tokenCredentials attaches a bearer token to every outgoing RPC
+type tokenCredentials struct {
+	token string
+}
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{authMetadataKey: authScheme + t.token}, nil
+}
+
+// RequireTransportSecurity is false because local plugins are reached over plain TCP on localhost
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// WithAuthToken returns a dial option that authenticates every call with the given token
+func WithAuthToken(token string) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(tokenCredentials{token: token})
+}
+
+// checkAuthToken verifies that the incoming context carries the expected token
+func checkAuthToken(ctx context.Context, token string) error {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing auth metadata")
+	}
+	values := md.Get(authMetadataKey)
+	if len(values) == 0 || !strings.HasPrefix(values[0], authScheme) {
+		return status.Error(codes.Unauthenticated, "missing auth token")
+	}
+	provided := strings.TrimPrefix(values[0], authScheme)
+	if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+		return status.Error(codes.Unauthenticated, "invalid auth token")
+	}
+	return nil
+}
+
+// AuthUnaryInterceptor rejects unary calls that don't carry the expected token
+func AuthUnaryInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := checkAuthToken(ctx, token); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamInterceptor rejects streaming calls that don't carry the expected token
+func AuthStreamInterceptor(token string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := checkAuthToken(ss.Context(), token); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// AuthInterceptors returns the interceptors enforcing token auth, or none if token is empty
+func AuthInterceptors(token string) ServerInterceptors {
+	if token == "" {
+		return ServerInterceptors{}
+	}
+	return ServerInterceptors{
+		Unary:  []grpc.UnaryServerInterceptor{AuthUnaryInterceptor(token)},
+		Stream: []grpc.StreamServerInterceptor{AuthStreamInterceptor(token)},
+	}
+}
+
+// AuthServerOptions returns the server options enforcing token auth, or none if token is empty
+func AuthServerOptions(token string) []grpc.ServerOption {
+	return AuthInterceptors(token).ServerOptions()
+}
@@ -0,0 +1,77 @@
+package shared
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthUnaryInterceptor(t *testing.T) {
+	const token = "secret-token"
+
+	tests := []struct {
+		name     string
+		md       metadata.MD
+		wantCode codes.Code
+	}{
+		{
+			name:     "Valid token",
+			md:       metadata.Pairs("authorization", "Bearer secret-token"),
+			wantCode: codes.OK,
+		},
+		{
+			name:     "Missing metadata",
+			md:       nil,
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "Missing bearer scheme",
+			md:       metadata.Pairs("authorization", "secret-token"),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "Wrong token",
+			md:       metadata.Pairs("authorization", "Bearer other-token"),
+			wantCode: codes.Unauthenticated,
+		},
+	}
+
+	interceptor := AuthUnaryInterceptor(token)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/plugin.Plugin/GetInfo"}, handler)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("AuthUnaryInterceptor() code = %v, want %v", got, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestTokenCredentials(t *testing.T) {
+	creds := tokenCredentials{token: "abc"}
+	md, err := creds.GetRequestMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("GetRequestMetadata() error = %v", err)
+	}
+	if got := md["authorization"]; got != "Bearer abc" {
+		t.Errorf("GetRequestMetadata() authorization = %q, want %q", got, "Bearer abc")
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.New(md))
+	if err := checkAuthToken(ctx, "abc"); err != nil {
+		t.Errorf("checkAuthToken() rejected credentials it produced: %v", err)
+	}
+}
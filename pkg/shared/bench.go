@@ -0,0 +1,97 @@
+package shared
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BenchStats summarizes the runs of a benchmark. The latency distribution covers the successful
+// runs only, so that fast failures don't flatter it.
+type BenchStats struct {
+	Runs       int           // Runs made, which is fewer than asked for if the benchmark was canceled
+	Failures   int           // Runs that failed
+	FirstError error         // Of the first run that failed, nil if none did
+	Min        time.Duration // Of the successful runs, all 0 without any
+	Mean       time.Duration
+	P50        time.Duration
+	P90        time.Duration
+	P99        time.Duration
+	Max        time.Duration
+	Wall       time.Duration // From the first run starting to the last one ending
+	Throughput float64       // Successful runs per second of wall time
+}
+
+// Bench calls run n times, at most concurrency at once, and summarizes how long the calls took.
+// Runs not yet started when ctx is done are skipped.
+func Bench(ctx context.Context, n, concurrency int, run func(ctx context.Context) error) BenchStats {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var mu sync.Mutex
+	var latencies []time.Duration
+	stats := BenchStats{}
+
+	jobs := make(chan struct{})
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				began := time.Now()
+				err := run(ctx)
+				took := time.Since(began)
+
+				mu.Lock()
+				stats.Runs++
+				if err != nil {
+					stats.Failures++
+					if stats.FirstError == nil {
+						stats.FirstError = err
+					}
+				} else {
+					latencies = append(latencies, took)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- struct{}{}:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	stats.Wall = time.Since(start)
+	summarizeLatencies(&stats, latencies)
+	return stats
+}
+
+// summarizeLatencies fills in the distribution and throughput of the successful runs
+func summarizeLatencies(stats *BenchStats, latencies []time.Duration) {
+	if len(latencies) == 0 {
+		return
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	stats.Min = latencies[0]
+	stats.Max = latencies[len(latencies)-1]
+	stats.Mean = total / time.Duration(len(latencies))
+	stats.P50 = percentile(latencies, 50)
+	stats.P90 = percentile(latencies, 90)
+	stats.P99 = percentile(latencies, 99)
+	if stats.Wall > 0 {
+		stats.Throughput = float64(len(latencies)) / stats.Wall.Seconds()
+	}
+}
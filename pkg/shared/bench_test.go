@@ -0,0 +1,107 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBench(t *testing.T) {
+	var calls int32
+	stats := Bench(context.Background(), 10, 1, func(ctx context.Context) error {
+		n := atomic.AddInt32(&calls, 1)
+		if n%5 == 0 {
+			return errors.New("failed run")
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+
+	if stats.Runs != 10 || stats.Failures != 2 {
+		t.Fatalf("Runs = %d, Failures = %d, want 10 and 2", stats.Runs, stats.Failures)
+	}
+	if stats.FirstError == nil || stats.FirstError.Error() != "failed run" {
+		t.Errorf("FirstError = %v, want failed run", stats.FirstError)
+	}
+	if stats.Min < time.Millisecond || stats.Min > stats.P50 || stats.P50 > stats.P90 || stats.P90 > stats.P99 || stats.P99 > stats.Max {
+		t.Errorf("distribution out of order: min %v p50 %v p90 %v p99 %v max %v", stats.Min, stats.P50, stats.P90, stats.P99, stats.Max)
+	}
+	if stats.Mean < stats.Min || stats.Mean > stats.Max {
+		t.Errorf("Mean = %v, outside %v-%v", stats.Mean, stats.Min, stats.Max)
+	}
+	if stats.Throughput <= 0 || stats.Wall <= 0 {
+		t.Errorf("Throughput = %v over %v, want positive", stats.Throughput, stats.Wall)
+	}
+}
+
+func TestBench_concurrency(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	stats := Bench(context.Background(), 12, 3, func(ctx context.Context) error {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+
+	if stats.Runs != 12 {
+		t.Errorf("Runs = %d, want 12", stats.Runs)
+	}
+	if peak != 3 {
+		t.Errorf("peak concurrency = %d, want 3", peak)
+	}
+}
+
+func TestBench_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+	stats := Bench(ctx, 100, 1, func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) == 3 {
+			cancel()
+		}
+		return nil
+	})
+
+	if stats.Runs >= 100 || stats.Runs < 3 {
+		t.Errorf("Runs = %d, want the runs before the cancellation only", stats.Runs)
+	}
+}
+
+func TestSummarizeLatencies(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	stats := BenchStats{Wall: 2 * time.Second}
+	summarizeLatencies(&stats, latencies)
+
+	want := BenchStats{
+		Wall:       2 * time.Second,
+		Min:        time.Millisecond,
+		Mean:       50500 * time.Microsecond,
+		P50:        50 * time.Millisecond,
+		P90:        90 * time.Millisecond,
+		P99:        99 * time.Millisecond,
+		Max:        100 * time.Millisecond,
+		Throughput: 50,
+	}
+	if stats != want {
+		t.Errorf("summarizeLatencies() = %+v, want %+v", stats, want)
+	}
+
+	empty := BenchStats{Runs: 2, Failures: 2}
+	summarizeLatencies(&empty, nil)
+	if empty.Min != 0 || empty.Throughput != 0 {
+		t.Errorf("summarizeLatencies() without successes = %+v, want no distribution", empty)
+	}
+}
@@ -0,0 +1,124 @@
+package shared
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// DefaultCaptureMemory is how much output is kept in memory before it spills to disk even
+// without memory pressure
+const DefaultCaptureMemory = 8 << 20
+
+// OutputCapture records the output of one execution, in memory while it is small and the host
+// has room, and in a spill file once it grows or the memory monitor reports pressure
+type OutputCapture struct {
+	mu        sync.Mutex
+	dir       string
+	maxMemory int
+	monitor   *MemoryMonitor
+	buf       bytes.Buffer
+	file      *os.File
+	lines     int
+	size      int64
+}
+
+// NewOutputCapture returns a capture spilling into dir when needed
+func NewOutputCapture(dir string, monitor *MemoryMonitor) *OutputCapture {
+	return &OutputCapture{dir: dir, maxMemory: DefaultCaptureMemory, monitor: monitor}
+}
+
+// Append records one line of output
+func (c *OutputCapture) Append(line string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lines++
+	c.size += int64(len(line)) + 1
+	if c.file == nil && (c.monitor.UnderPressure() || c.buf.Len()+len(line) >= c.maxMemory) {
+		if err := c.spill(); err != nil {
+			// Keep the output in memory rather than losing it
+			c.buf.WriteString(line)
+			c.buf.WriteByte('\n')
+			return err
+		}
+	}
+	if c.file != nil {
+		_, err := io.WriteString(c.file, line+"\n")
+		return err
+	}
+	c.buf.WriteString(line)
+	c.buf.WriteByte('\n')
+	return nil
+}
+
+// spill moves the buffered output to a file; the caller must hold c.mu
+func (c *OutputCapture) spill() error {
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create spill directory: %v", err)
+	}
+	file, err := os.CreateTemp(c.dir, "output-*.log")
+	if err != nil {
+		return fmt.Errorf("failed to create spill file: %v", err)
+	}
+	if _, err := c.buf.WriteTo(file); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return fmt.Errorf("failed to write spill file: %v", err)
+	}
+	c.buf = bytes.Buffer{}
+	c.file = file
+	return nil
+}
+
+// SpillFile returns the path of the spill file, or "" if the output is still in memory
+func (c *OutputCapture) SpillFile() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return ""
+	}
+	return c.file.Name()
+}
+
+// Lines returns the number of lines captured
+func (c *OutputCapture) Lines() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lines
+}
+
+// Size returns the number of bytes captured
+func (c *OutputCapture) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// WriteTo writes the captured output to w
+func (c *OutputCapture) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		n, err := w.Write(c.buf.Bytes())
+		return int64(n), err
+	}
+	file, err := os.Open(c.file.Name())
+	if err != nil {
+		return 0, fmt.Errorf("failed to open spill file: %v", err)
+	}
+	defer file.Close()
+	return io.Copy(w, file)
+}
+
+// Close closes the spill file, leaving it on disk for inspection
+func (c *OutputCapture) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	return c.file.Close()
+}
@@ -0,0 +1,49 @@
+package shared
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOutputCapture(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxMemory int
+		pressure  bool
+		wantSpill bool
+	}{
+		{name: "Small output stays in memory", maxMemory: DefaultCaptureMemory},
+		{name: "Large output spills", maxMemory: 10, wantSpill: true},
+		{name: "Memory pressure spills", maxMemory: DefaultCaptureMemory, pressure: true, wantSpill: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := NewMemoryMonitor(&MemoryConfig{SoftLimitMB: 1})
+			monitor.pressure.Store(tt.pressure)
+			c := NewOutputCapture(t.TempDir(), monitor)
+			c.maxMemory = tt.maxMemory
+			defer c.Close()
+
+			for _, line := range []string{"first line", "second line", "third line"} {
+				if err := c.Append(line); err != nil {
+					t.Fatalf("Append() error = %v", err)
+				}
+			}
+
+			if got := c.SpillFile() != ""; got != tt.wantSpill {
+				t.Errorf("spilled = %v, want %v", got, tt.wantSpill)
+			}
+			if c.Lines() != 3 {
+				t.Errorf("Lines() = %d, want 3", c.Lines())
+			}
+			var out bytes.Buffer
+			if _, err := c.WriteTo(&out); err != nil {
+				t.Fatalf("WriteTo() error = %v", err)
+			}
+			if want := "first line\nsecond line\nthird line\n"; out.String() != want {
+				t.Errorf("WriteTo() = %q, want %q", out.String(), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/example/grpc-plugin-app/proto"
+)
+
+// Channel names used as keys of a plugin's channels setting
+const (
+	ChannelOutput    = "output"
+	ChannelControl   = "control"
+	ChannelLogs      = "logs"
+	ChannelMetrics   = "metrics"
+	ChannelArtifacts = "artifacts"
+)
+
+// channelIDs maps channel names to their protocol values
+var channelIDs = map[string]proto.Channel{
+	ChannelOutput:    proto.Channel_CHANNEL_OUTPUT,
+	ChannelControl:   proto.Channel_CHANNEL_CONTROL,
+	ChannelLogs:      proto.Channel_CHANNEL_LOGS,
+	ChannelMetrics:   proto.Channel_CHANNEL_METRICS,
+	ChannelArtifacts: proto.Channel_CHANNEL_ARTIFACTS,
+}
+
+// channelPriority is the order queued messages are sent in: control messages never wait
+// behind output, and artifacts only go out when nothing else is waiting
+var channelPriority = []proto.Channel{
+	proto.Channel_CHANNEL_CONTROL,
+	proto.Channel_CHANNEL_OUTPUT,
+	proto.Channel_CHANNEL_LOGS,
+	proto.Channel_CHANNEL_METRICS,
+	proto.Channel_CHANNEL_ARTIFACTS,
+}
+
+// ChannelFlow is the flow control applied to one channel of the Execute stream
+type ChannelFlow struct {
+	Buffer int  `json:"buffer,omitempty"` // Messages queued on the channel before senders block or drop
+	Lossy  bool `json:"lossy,omitempty"`  // Drop the oldest queued message when full instead of blocking
+}
+
+// defaultChannelFlow applies to channels the plugin config doesn't mention
+var defaultChannelFlow = map[proto.Channel]ChannelFlow{
+	proto.Channel_CHANNEL_OUTPUT:    {Buffer: 64},
+	proto.Channel_CHANNEL_CONTROL:   {Buffer: 64},
+	proto.Channel_CHANNEL_LOGS:      {Buffer: 256},
+	proto.Channel_CHANNEL_METRICS:   {Buffer: 64, Lossy: true},
+	proto.Channel_CHANNEL_ARTIFACTS: {Buffer: 8},
+}
+
+// validateChannels checks the per-channel flow control of a plugin
+func (p *PluginConfig) validateChannels() error {
+	for name, flow := range p.Channels {
+		if _, ok := channelIDs[name]; !ok {
+			return fmt.Errorf("unknown channel: %s (must be one of %s)", name, channelList())
+		}
+		if flow.Buffer < 0 {
+			return fmt.Errorf("invalid buffer for channel %s: %d", name, flow.Buffer)
+		}
+	}
+	return nil
+}
+
+// channelName returns the config name of a channel
+func channelName(channel proto.Channel) string {
+	for name, id := range channelIDs {
+		if id == channel {
+			return name
+		}
+	}
+	return channel.String()
+}
+
+func channelList() string {
+	names := make([]string, 0, len(channelIDs))
+	for name := range channelIDs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Sprint(names)
+}
+
+// channelFlows converts configured flow control to its protocol form
+func channelFlows(channels map[string]ChannelFlow) map[string]*proto.ChannelFlow {
+	if len(channels) == 0 {
+		return nil
+	}
+	flows := make(map[string]*proto.ChannelFlow, len(channels))
+	for name, flow := range channels {
+		flows[name] = &proto.ChannelFlow{Buffer: uint32(flow.Buffer), Lossy: flow.Lossy}
+	}
+	return flows
+}
+
+// resolveFlow returns the flow control of a channel, filling in defaults
+func resolveFlow(channel proto.Channel, flows map[string]*proto.ChannelFlow) ChannelFlow {
+	flow := defaultChannelFlow[channel]
+	if requested := flows[channelName(channel)]; requested != nil {
+		if requested.Buffer > 0 {
+			flow.Buffer = int(requested.Buffer)
+		}
+		flow.Lossy = requested.Lossy
+	}
+	return flow
+}
+
+// ChannelHandler receives the messages of the logs, artifacts, prompts and metrics channels.
+// Output handlers implement it optionally; plugins type-assert their OutputHandler to send on
+// these channels.
+type ChannelHandler interface {
+	OnLog(level, message string) error
+	OnArtifact(name string, data []byte, last bool) error
+	OnPrompt(id, message string) error
+	OnMetric(name string, value float64, labels map[string]string) error
+}
+
+// asChannelHandler returns handler's ChannelHandler, or one that shows logs and prompts as
+// output and ignores artifacts and metrics for handlers that don't implement it
+func asChannelHandler(handler OutputHandler) ChannelHandler {
+	if channels, ok := handler.(ChannelHandler); ok {
+		return channels
+	}
+	return fallbackChannels{handler}
+}
+
+type fallbackChannels struct {
+	OutputHandler
+}
+
+func (f fallbackChannels) OnLog(level, message string) error {
+	return f.OnOutput(fmt.Sprintf("[%s] %s", level, message))
+}
+
+func (f fallbackChannels) OnArtifact(name string, data []byte, last bool) error {
+	return nil
+}
+
+func (f fallbackChannels) OnPrompt(id, message string) error {
+	return f.OnOutput(message)
+}
+
+func (f fallbackChannels) OnMetric(name string, value float64, labels map[string]string) error {
+	return nil
+}
+
+// channelMux queues a plugin's messages per channel and sends them on the Execute stream in
+// priority order, so a plugin producing bulk artifacts can't hold up its progress reports
+type channelMux struct {
+	stream proto.Plugin_ExecuteServer
+	flows  map[string]*proto.ChannelFlow
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending map[proto.Channel][]*proto.ExecuteOutput
+	dropped map[proto.Channel]int
+	closed  bool
+	err     error
+	done    chan struct{}
+	stop    func() bool
+}
+
+// newChannelMux starts sending queued messages on stream until close is called or ctx ends
+func newChannelMux(ctx context.Context, stream proto.Plugin_ExecuteServer, flows map[string]*proto.ChannelFlow) *channelMux {
+	m := &channelMux{
+		stream:  stream,
+		flows:   flows,
+		pending: make(map[proto.Channel][]*proto.ExecuteOutput),
+		dropped: make(map[proto.Channel]int),
+		done:    make(chan struct{}),
+	}
+	m.cond = sync.NewCond(&m.mu)
+	m.stop = context.AfterFunc(ctx, func() {
+		m.fail(ctx.Err())
+	})
+	go m.run()
+	return m
+}
+
+// send queues out on channel, blocking while a lossless channel is full
+func (m *channelMux) send(channel proto.Channel, out *proto.ExecuteOutput) error {
+	flow := resolveFlow(channel, m.flows)
+	out.Channel = channel
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for !flow.Lossy && len(m.pending[channel]) >= flow.Buffer && m.err == nil && !m.closed {
+		m.cond.Wait()
+	}
+	if m.err != nil {
+		return m.err
+	}
+	if m.closed {
+		return fmt.Errorf("execution finished")
+	}
+	if len(m.pending[channel]) >= flow.Buffer {
+		m.pending[channel] = m.pending[channel][1:]
+		m.dropped[channel]++
+	}
+	m.pending[channel] = append(m.pending[channel], out)
+	m.cond.Broadcast()
+	return nil
+}
+
+// next removes the highest priority queued message
+func (m *channelMux) next() *proto.ExecuteOutput {
+	for _, channel := range channelPriority {
+		if queue := m.pending[channel]; len(queue) > 0 {
+			m.pending[channel] = queue[1:]
+			return queue[0]
+		}
+	}
+	return nil
+}
+
+func (m *channelMux) run() {
+	defer close(m.done)
+	for {
+		m.mu.Lock()
+		out := m.next()
+		for out == nil && !m.closed && m.err == nil {
+			m.cond.Wait()
+			out = m.next()
+		}
+		if out == nil || m.err != nil {
+			m.mu.Unlock()
+			return
+		}
+		m.cond.Broadcast()
+		m.mu.Unlock()
+
+		if err := m.stream.Send(out); err != nil {
+			m.fail(err)
+			return
+		}
+	}
+}
+
+// fail records the first error and wakes every blocked sender
+func (m *channelMux) fail(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err == nil {
+		m.err = err
+	}
+	m.cond.Broadcast()
+}
+
+// close sends everything still queued, reports dropped messages as a warning and returns the
+// first send error
+func (m *channelMux) close() error {
+	m.mu.Lock()
+	m.closed = true
+	m.cond.Broadcast()
+	m.mu.Unlock()
+	<-m.done
+	m.stop()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	for _, channel := range channelPriority {
+		if count := m.dropped[channel]; count > 0 {
+			err := m.stream.Send(&proto.ExecuteOutput{
+				Content: &proto.ExecuteOutput_Log{
+					Log: &proto.LogEntry{
+						Level:   "warn",
+						Message: fmt.Sprintf("dropped %d messages on the %s channel", count, channelName(channel)),
+					},
+				},
+				Channel: proto.Channel_CHANNEL_LOGS,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// artifactPump delivers artifact chunks to a handler on its own goroutine. Once its buffer is
+// full, push blocks, which applies backpressure to the plugin through the stream.
+type artifactPump struct {
+	handler ChannelHandler
+	chunks  chan *proto.Artifact
+	cancel  context.CancelFunc
+	done    chan struct{}
+	once    sync.Once
+	err     error
+}
+
+// newArtifactPump starts delivering artifacts; cancel is called if the handler fails
+func newArtifactPump(handler ChannelHandler, buffer int, cancel context.CancelFunc) *artifactPump {
+	p := &artifactPump{
+		handler: handler,
+		chunks:  make(chan *proto.Artifact, buffer),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+func (p *artifactPump) run() {
+	defer close(p.done)
+	for chunk := range p.chunks {
+		if p.err != nil {
+			continue
+		}
+		if err := p.handler.OnArtifact(chunk.Name, chunk.Data, chunk.Last); err != nil {
+			p.err = fmt.Errorf("error handling artifact %s: %v", chunk.Name, err)
+			p.cancel()
+		}
+	}
+}
+
+// push queues a chunk, waiting while the buffer is full
+func (p *artifactPump) push(chunk *proto.Artifact) {
+	p.chunks <- chunk
+}
+
+// close waits for queued chunks to be delivered and returns the handler's first error
+func (p *artifactPump) close() error {
+	p.once.Do(func() {
+		close(p.chunks)
+	})
+	<-p.done
+	return p.err
+}
@@ -0,0 +1,334 @@
+package shared
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+)
+
+// gatedStream records sent messages, holding each Send until the gate lets it through
+type gatedStream struct {
+	grpc.ServerStream
+	gate    chan struct{}
+	entered chan struct{}
+	mu      sync.Mutex
+	sent    []*proto.ExecuteOutput
+}
+
+func newGatedStream() *gatedStream {
+	return &gatedStream{gate: make(chan struct{}), entered: make(chan struct{}, 16)}
+}
+
+func (s *gatedStream) Send(out *proto.ExecuteOutput) error {
+	select {
+	case s.entered <- struct{}{}:
+	default:
+	}
+	<-s.gate
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, out)
+	return nil
+}
+
+func (s *gatedStream) channels() []proto.Channel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var channels []proto.Channel
+	for _, out := range s.sent {
+		channels = append(channels, out.Channel)
+	}
+	return channels
+}
+
+func TestPluginConfig_validateChannels(t *testing.T) {
+	tests := []struct {
+		name     string
+		channels map[string]ChannelFlow
+		wantErr  bool
+		errorMsg string
+	}{
+		{
+			name:     "Known channels",
+			channels: map[string]ChannelFlow{"artifacts": {Buffer: 2}, "logs": {Lossy: true}},
+		},
+		{
+			name:     "Unknown channel",
+			channels: map[string]ChannelFlow{"video": {Buffer: 2}},
+			wantErr:  true,
+			errorMsg: "unknown channel: video",
+		},
+		{
+			name:     "Negative buffer",
+			channels: map[string]ChannelFlow{"logs": {Buffer: -1}},
+			wantErr:  true,
+			errorMsg: "invalid buffer for channel logs",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PluginConfig{Channels: tt.channels}
+			err := p.validateChannels()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateChannels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("error %q does not contain %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestChannelMux_Priority(t *testing.T) {
+	stream := newGatedStream()
+	mux := newChannelMux(context.Background(), stream, nil)
+	handler := &grpcOutputHandler{mux: mux}
+
+	// The first artifact is taken off its queue and held in Send; the rest wait behind it
+	for i := 0; i < 3; i++ {
+		if err := handler.OnArtifact("bulk", []byte("chunk"), i == 2); err != nil {
+			t.Fatalf("OnArtifact() error = %v", err)
+		}
+		if i == 0 {
+			<-stream.entered
+		}
+	}
+	if err := handler.OnOutput("line"); err != nil {
+		t.Fatalf("OnOutput() error = %v", err)
+	}
+	if err := handler.OnProgress(Progress{PercentComplete: 50}); err != nil {
+		t.Fatalf("OnProgress() error = %v", err)
+	}
+	close(stream.gate)
+	if err := mux.close(); err != nil {
+		t.Fatalf("close() error = %v", err)
+	}
+
+	want := []proto.Channel{
+		proto.Channel_CHANNEL_ARTIFACTS,
+		proto.Channel_CHANNEL_CONTROL,
+		proto.Channel_CHANNEL_OUTPUT,
+		proto.Channel_CHANNEL_ARTIFACTS,
+		proto.Channel_CHANNEL_ARTIFACTS,
+	}
+	if got := stream.channels(); !reflect.DeepEqual(got, want) {
+		t.Errorf("sent channels = %v, want %v", got, want)
+	}
+}
+
+func TestChannelMux_Flow(t *testing.T) {
+	t.Run("Lossy channel drops oldest", func(t *testing.T) {
+		stream := newGatedStream()
+		flows := map[string]*proto.ChannelFlow{"metrics": {Buffer: 1, Lossy: true}}
+		mux := newChannelMux(context.Background(), stream, flows)
+		handler := &grpcOutputHandler{mux: mux}
+
+		for i := 0; i < 4; i++ {
+			if err := handler.OnMetric("rows", float64(i), nil); err != nil {
+				t.Fatalf("OnMetric() error = %v", err)
+			}
+		}
+		close(stream.gate)
+		if err := mux.close(); err != nil {
+			t.Fatalf("close() error = %v", err)
+		}
+
+		var values []float64
+		var warning string
+		for _, out := range stream.sent {
+			switch content := out.Content.(type) {
+			case *proto.ExecuteOutput_Metric:
+				values = append(values, content.Metric.Value)
+			case *proto.ExecuteOutput_Log:
+				warning = content.Log.Message
+			}
+		}
+		if last := values[len(values)-1]; last != 3 {
+			t.Errorf("last metric = %v, want 3", last)
+		}
+		if !strings.Contains(warning, "on the metrics channel") {
+			t.Errorf("drop warning = %q", warning)
+		}
+	})
+
+	t.Run("Full lossless channel blocks until cancelled", func(t *testing.T) {
+		stream := newGatedStream()
+		defer close(stream.gate)
+		flows := map[string]*proto.ChannelFlow{"artifacts": {Buffer: 1}}
+		ctx, cancel := context.WithCancel(context.Background())
+		mux := newChannelMux(ctx, stream, flows)
+		handler := &grpcOutputHandler{mux: mux}
+
+		errs := make(chan error, 1)
+		go func() {
+			for {
+				if err := handler.OnArtifact("bulk", []byte("chunk"), false); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+		select {
+		case err := <-errs:
+			t.Fatalf("OnArtifact() returned %v before the channel filled", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+		// Control messages still get queued while artifacts are blocked
+		if err := handler.OnProgress(Progress{PercentComplete: 10}); err != nil {
+			t.Fatalf("OnProgress() error = %v", err)
+		}
+
+		cancel()
+		select {
+		case err := <-errs:
+			if err != context.Canceled {
+				t.Errorf("OnArtifact() error = %v, want %v", err, context.Canceled)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("OnArtifact() still blocked after cancel")
+		}
+	})
+}
+
+// channelPlugin sends one message on every channel
+type channelPlugin struct {
+	stubPlugin
+}
+
+func (channelPlugin) Execute(ctx context.Context, params map[string]string, output OutputHandler) error {
+	channels := output.(ChannelHandler)
+	steps := []error{
+		channels.OnLog("info", "starting"),
+		channels.OnPrompt("approve", "approve the change"),
+		channels.OnArtifact("report.txt", []byte("hello "), false),
+		channels.OnArtifact("report.txt", []byte("world"), true),
+		channels.OnMetric("rows", 42, map[string]string{"table": "users"}),
+		output.OnOutput("done"),
+	}
+	for _, err := range steps {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordingHandler keeps everything sent on the output and channel streams
+type recordingHandler struct {
+	discardHandler
+	mu        sync.Mutex
+	output    []string
+	logs      []string
+	prompts   []string
+	artifacts map[string]string
+	metrics   map[string]float64
+}
+
+func (h *recordingHandler) OnOutput(msg string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.output = append(h.output, msg)
+	return nil
+}
+
+func (h *recordingHandler) OnLog(level, message string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.logs = append(h.logs, level+": "+message)
+	return nil
+}
+
+func (h *recordingHandler) OnArtifact(name string, data []byte, last bool) error {
+	// A slow artifact consumer must not hold up the rest of the stream
+	time.Sleep(20 * time.Millisecond)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.artifacts[name] += string(data)
+	return nil
+}
+
+func (h *recordingHandler) OnPrompt(id, message string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.prompts = append(h.prompts, id+": "+message)
+	return nil
+}
+
+func (h *recordingHandler) OnMetric(name string, value float64, labels map[string]string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.metrics[name+"/"+labels["table"]] = value
+	return nil
+}
+
+func TestGRPCClient_ExecuteChannels(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	proto.RegisterPluginServer(server, &GRPCServer{Impl: channelPlugin{}})
+	go server.Serve(listener)
+	defer server.Stop()
+
+	client, err := NewClientWithAddress(listener.Addr().String())
+	if err != nil {
+		t.Fatalf("NewClientWithAddress() error = %v", err)
+	}
+	defer client.Close()
+
+	t.Run("Channel handler", func(t *testing.T) {
+		handler := &recordingHandler{artifacts: map[string]string{}, metrics: map[string]float64{}}
+		if err := client.Execute(context.Background(), nil, handler); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if !reflect.DeepEqual(handler.logs, []string{"info: starting"}) {
+			t.Errorf("logs = %v", handler.logs)
+		}
+		if !reflect.DeepEqual(handler.prompts, []string{"approve: approve the change"}) {
+			t.Errorf("prompts = %v", handler.prompts)
+		}
+		if got := handler.artifacts["report.txt"]; got != "hello world" {
+			t.Errorf("artifact = %q, want %q", got, "hello world")
+		}
+		if got := handler.metrics["rows/users"]; got != 42 {
+			t.Errorf("metric = %v, want 42", got)
+		}
+		if !reflect.DeepEqual(handler.output, []string{"done"}) {
+			t.Errorf("output = %v", handler.output)
+		}
+	})
+
+	t.Run("Plain output handler", func(t *testing.T) {
+		handler := &outputRecorder{}
+		if err := client.Execute(context.Background(), nil, handler); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		want := []string{"[info] starting", "approve the change", "done"}
+		got := append([]string(nil), handler.output...)
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("output = %v, want %v", got, want)
+		}
+	})
+}
+
+// outputRecorder is an OutputHandler without channel support
+type outputRecorder struct {
+	discardHandler
+	output []string
+}
+
+func (h *outputRecorder) OnOutput(msg string) error {
+	h.output = append(h.output, msg)
+	return nil
+}
@@ -0,0 +1,41 @@
+package shared
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// FileSHA256 returns the hex-encoded SHA-256 checksum of the file at path
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyChecksum checks the plugin binary at Path against the configured sha256, if any
+func (p *PluginConfig) VerifyChecksum() error {
+	if p.SHA256 == "" {
+		return nil
+	}
+
+	actual, err := FileSHA256(p.Path)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actual, p.SHA256) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", p.Path, p.SHA256, actual)
+	}
+	return nil
+}
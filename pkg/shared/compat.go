@@ -0,0 +1,95 @@
+package shared
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ProtocolVersion is the host/plugin protocol this host speaks. Plugins that don't report a
+// version are treated as protocol 1, which only knew string parameters.
+const (
+	ProtocolVersion       = 2
+	LegacyProtocolVersion = 1
+)
+
+// Host features plugins may rely on
+const (
+	FeatureTypedParams   = "typed_params"   // Parameters are also sent converted to their declared types
+	FeatureResultSchema  = "result_schema"  // Structured results are checked against the published schema
+	FeatureAuthToken     = "auth_token"     // Calls carry the per-start auth token
+	FeatureAutoMTLS      = "auto_mtls"      // TLS material is handed over in the environment
+	FeatureListenFD      = "listen_fd"      // The listening socket may be inherited from the host
+	FeatureMessageLimits = "message_limits" // Message size and stream limits are passed in the environment
+)
+
+// HostCapabilities describes which plugin protocols and features a host supports
+type HostCapabilities struct {
+	MinProtocol int      `json:"min_protocol"`
+	MaxProtocol int      `json:"max_protocol"`
+	Features    []string `json:"features"`
+}
+
+// CurrentHost returns the capabilities of this host
+func CurrentHost() HostCapabilities {
+	return HostCapabilities{
+		MinProtocol: LegacyProtocolVersion,
+		MaxProtocol: ProtocolVersion,
+		Features: []string{
+			FeatureAuthToken,
+			FeatureAutoMTLS,
+			FeatureListenFD,
+			FeatureMessageLimits,
+			FeatureResultSchema,
+			FeatureTypedParams,
+		},
+	}
+}
+
+// WithoutFeatures returns a copy of the capabilities lacking the given features
+func (h HostCapabilities) WithoutFeatures(drop []string) HostCapabilities {
+	dropped := make(map[string]bool, len(drop))
+	for _, feature := range drop {
+		dropped[feature] = true
+	}
+	result := h
+	result.Features = nil
+	for _, feature := range h.Features {
+		if !dropped[feature] {
+			result.Features = append(result.Features, feature)
+		}
+	}
+	return result
+}
+
+// PluginProtocol returns the protocol a plugin speaks, mapping unversioned plugins to the legacy protocol
+func PluginProtocol(info *PluginInfo) int {
+	if info.ProtocolVersion == 0 {
+		return LegacyProtocolVersion
+	}
+	return info.ProtocolVersion
+}
+
+// Check lists the reasons a plugin can't work with a host, or nothing if it is compatible
+func (h HostCapabilities) Check(info *PluginInfo) []string {
+	var problems []string
+	protocol := PluginProtocol(info)
+	if protocol < h.MinProtocol || protocol > h.MaxProtocol {
+		problems = append(problems, fmt.Sprintf("protocol %d not in supported range %d-%d", protocol, h.MinProtocol, h.MaxProtocol))
+	}
+
+	supported := make(map[string]bool, len(h.Features))
+	for _, feature := range h.Features {
+		supported[feature] = true
+	}
+	var missing []string
+	for _, feature := range info.Features {
+		if !supported[feature] {
+			missing = append(missing, feature)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		problems = append(problems, fmt.Sprintf("missing features %v", missing))
+	}
+	return problems
+}
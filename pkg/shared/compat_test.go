@@ -0,0 +1,65 @@
+package shared
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHostCapabilities_Check(t *testing.T) {
+	host := CurrentHost()
+	planned := host.WithoutFeatures([]string{FeatureTypedParams})
+	planned.MinProtocol = ProtocolVersion
+
+	tests := []struct {
+		name     string
+		host     HostCapabilities
+		info     PluginInfo
+		wantErr  bool
+		errorMsg string
+	}{
+		{
+			name: "Current plugin on current host",
+			host: host,
+			info: PluginInfo{ProtocolVersion: ProtocolVersion, Features: []string{FeatureTypedParams}},
+		},
+		{
+			name: "Unversioned plugin on current host",
+			host: host,
+			info: PluginInfo{},
+		},
+		{
+			name:     "Unversioned plugin after dropping the legacy protocol",
+			host:     planned,
+			info:     PluginInfo{},
+			wantErr:  true,
+			errorMsg: "protocol 1 not in supported range",
+		},
+		{
+			name:     "Plugin relying on a dropped feature",
+			host:     planned,
+			info:     PluginInfo{ProtocolVersion: ProtocolVersion, Features: []string{FeatureTypedParams}},
+			wantErr:  true,
+			errorMsg: "missing features [typed_params]",
+		},
+		{
+			name:     "Plugin newer than the host",
+			host:     host,
+			info:     PluginInfo{ProtocolVersion: ProtocolVersion + 1},
+			wantErr:  true,
+			errorMsg: "not in supported range",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := tt.host.Check(&tt.info)
+			if (len(problems) > 0) != tt.wantErr {
+				t.Errorf("Check() = %v, wantErr %v", problems, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !strings.Contains(strings.Join(problems, "; "), tt.errorMsg) {
+				t.Errorf("Check() = %v, want problem containing %q", problems, tt.errorMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package shared
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// Supported values for PluginConfig.Compression. Importing the gzip package registers the
+// compressor on both sides; gRPC servers answer with the compressor the client used, so
+// plugins built on this package compress their stream as soon as the host asks for it.
+const (
+	CompressionNone = "none"
+	CompressionGzip = gzip.Name
+)
+
+// CompressionDialOption returns the dial option enabling the named compressor for every call
+func CompressionDialOption(name string) (grpc.DialOption, error) {
+	switch name {
+	case "", CompressionNone:
+		return nil, nil
+	case CompressionGzip:
+		return grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s (must be none or gzip)", name)
+	}
+}
@@ -1,11 +1,15 @@
 package shared
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 // PluginType represents the type of plugin
@@ -16,27 +20,256 @@ const (
 	PluginTypeBinary PluginType = "binary"
 	// PluginTypeCommand represents a plugin started with a custom command
 	PluginTypeCommand PluginType = "command"
+	// PluginTypeRemote represents an already running plugin reached over the network
+	PluginTypeRemote PluginType = "remote"
 )
 
 // PluginConfig represents the configuration for a plugin
 type PluginConfig struct {
-	Path        string            `json:"path"`        // Path to binary or command
-	Port        int               `json:"port"`        // Port to run the gRPC server on
-	Type        PluginType        `json:"type"`        // Type of plugin (go/command)
-	Command     string            `json:"command"`     // Command template with {port} and {path} placeholders
-	Description string            `json:"description"` // Plugin description
-	Defaults    map[string]string `json:"defaults"`    // Default parameter values
-	WorkingDir  string            `json:"workdir"`     // Working directory for the command
-	Environment map[string]string `json:"env"`         // Additional environment variables
+	Path        string            `json:"path"`                     // Path to binary or command
+	Port        int               `json:"port" min:"0" max:"65535"` // Port to run the gRPC server on, 0 to use a free one
+	Type        PluginType        `json:"type"`                     // Type of plugin (go/command)
+	Command     string            `json:"command,omitempty"`        // Command template with {port} and {path} placeholders
+	Description string            `json:"description"`              // Plugin description
+	Tags        []string          `json:"tags,omitempty"`           // Labels to select the plugin by, together with others, such as math
+	Defaults    map[string]string `json:"defaults,omitempty"`       // Default parameter values
+	WorkingDir  string            `json:"workdir,omitempty"`        // Working directory for the command
+	Environment map[string]string `json:"env,omitempty"`            // Additional environment variables
+	EnvPolicy   EnvPolicy         `json:"env_policy,omitempty"`     // Host variables the plugin inherits (inherit-all/allowlist/none, default settings.env_policy or inherit-all)
+	EnvAllow    []string          `json:"env_allow,omitempty"`      // Host variables passed with env_policy allowlist, such as HOME or AWS_*
+	EnvDeny     []string          `json:"env_deny,omitempty"`       // Host variables never passed, such as *_TOKEN
+
+	// Security settings
+	AuthToken string `json:"auth_token,omitempty"` // Shared secret for plugin calls (generated per start if empty)
+	SHA256    string `json:"sha256,omitempty"`     // Expected checksum of the file at Path
+	AutoMTLS  bool   `json:"auto_mtls,omitempty"`  // Secure the connection with ephemeral mutual TLS
+	User      string `json:"user,omitempty"`       // User name or uid the plugin process runs as (host must run as root)
+	Group     string `json:"group,omitempty"`      // Group name or gid, defaults to the user's primary group
+	Manifest  string `json:"manifest,omitempty"`   // Signed manifest for the binary, defaults to <path>.manifest.json
+
+	// Transport settings
+	Compression          string   `json:"compression,omitempty"`            // Compressor for plugin calls (none/gzip)
+	MaxSendMessageSize   int      `json:"max_send_message_size,omitempty"`  // Largest message in bytes the host sends to the plugin
+	MaxRecvMessageSize   int      `json:"max_recv_message_size,omitempty"`  // Largest message in bytes the host accepts from the plugin (gRPC default 4MB)
+	MaxConcurrentStreams uint32   `json:"max_concurrent_streams,omitempty"` // Concurrent calls a local plugin's server accepts
+	CallTimeout          Duration `json:"call_timeout,omitempty" min:"0"`   // Deadline applied to each GetInfo and Execute call
+
+	// Connection settings
+	ConnectTimeout Duration `json:"connect_timeout,omitempty" min:"0"` // How long to wait for a remote plugin to become reachable
+	ReadyTimeout   Duration `json:"ready_timeout,omitempty" min:"0"`   // How long a started local plugin has to report ready
+
+	// Shutdown settings
+	StopTimeout Duration `json:"stop_timeout,omitempty" min:"0"` // How long a stopping local plugin has to exit after SIGTERM before it is killed (default 5s)
+
+	// Keep-alive settings
+	KeepAlive Duration `json:"keep_alive,omitempty" min:"0"` // How long an idle plugin keeps running in a long-lived host such as the daemon, 0 until the host stops
+
+	// Startup settings
+	Start StartMode `json:"start,omitempty"` // When a long-lived host starts the plugin (eager/lazy, default eager)
+
+	// Dependency settings
+	DependsOn []string `json:"depends_on,omitempty"` // Plugins started and ready before this one, and stopped after it
+
+	// Availability settings
+	Standby     bool `json:"standby,omitempty"`                          // Keep a warm spare process to fail over to when the plugin turns unhealthy
+	StandbyPort int  `json:"standby_port,omitempty" min:"0" max:"65535"` // Port the spare process listens on
+	Replicas    int  `json:"replicas,omitempty" min:"0"`                 // Processes of a local plugin that share its executions round-robin, on free ports besides port; 0 or 1 for one
+
+	// Restart settings
+	Restart *RestartConfig `json:"restart,omitempty"` // How a crashed or unhealthy local plugin is restarted (default always, circuit breaker after 3 in 10m)
+
+	// Health check settings
+	HealthCheck *HealthCheckConfig `json:"health_check,omitempty"` // How the running plugin is health checked (default settings.health_check_interval, 3 attempts 5s apart)
+
+	// Watchdog settings
+	Watchdog *WatchdogConfig `json:"watchdog,omitempty"` // Cancel hung executions after collecting diagnostic dumps
+
+	// Timeout settings
+	ExecutionTimeout Duration `json:"execution_timeout,omitempty" min:"0"` // Deadline of each execution, which then fails with TIMEOUT; 0 for none
+
+	// Queue settings
+	MaxConcurrentExecutions int      `json:"max_concurrent_executions,omitempty" min:"0"` // Executions of the plugin running at once, further ones wait in a queue; 0 for no limit
+	QueueTimeout            Duration `json:"queue_timeout,omitempty" min:"0"`             // How long an execution may wait in the queue before it fails with QUEUE_TIMEOUT; 0 for no limit
+
+	// Stream channel settings
+	Channels map[string]ChannelFlow `json:"channels,omitempty"` // Flow control per Execute stream channel (output/control/logs/metrics/artifacts)
+
+	// Metrics settings
+	MetricLabels map[string]string `json:"metric_labels,omitempty"` // Labels on the plugin's exported metrics, e.g. team
+
+	// Containment settings
+	Sandbox *SandboxConfig `json:"sandbox,omitempty"` // Namespaces, rlimits and seccomp applied to the plugin process (Linux only)
+
+	// Remote plugin settings
+	Address       string   `json:"address,omitempty"`        // host:port or gRPC target (e.g. dns:///host:port) of a remote plugin
+	Addresses     []string `json:"addresses,omitempty"`      // host:port of each replica of a remote plugin
+	LoadBalancing string   `json:"load_balancing,omitempty"` // Balancing policy across replicas (pick_first/round_robin/consistent_hash)
+	AffinityKey   string   `json:"affinity_key,omitempty"`   // Parameter whose value pins related executions to one replica
+	Proxy         string   `json:"proxy,omitempty"`          // http:// or socks5:// proxy for remote connections, "direct" to ignore HTTPS_PROXY
+
+	// Remote failover settings
+	FallbackAddresses []string `json:"fallback_addresses,omitempty"` // Addresses tried in order when the remote plugin's address is unreachable or turns unhealthy
+	FailBack          Duration `json:"fail_back,omitempty" min:"0"`  // How often to probe the preferred address while on a fallback, moving back once it is reachable; 0 to stay
+
+	// Remote credential settings
+	CredentialHelper string       `json:"credential_helper,omitempty"` // Command printing a bearer token for each execution against a remote plugin
+	AuthHeaders      *AuthHeaders `json:"auth_headers,omitempty"`      // Static auth metadata sent with every call to a remote plugin
+
+	// SSH transport settings
+	SSH *SSHConfig `json:"ssh,omitempty"` // Reach the remote plugin through an SSH tunnel to its address
+
+	ResultValidation ResultValidationMode `json:"result_validation,omitempty"` // How to treat results violating the schema (off/warn/error)
+
+	// Version settings
+	ExpectedVersion string           `json:"expected_version,omitempty"` // Semver range GetInfo's version must be in, such as ">=1.2.0 <2.0.0" or "^1.4"
+	VersionCheck    VersionCheckMode `json:"version_check,omitempty"`    // How to treat a version outside expected_version (warn/error, default error)
+
+	// Lint settings
+	LintIgnore []string `json:"lint_ignore,omitempty"` // Lint rule IDs not reported for this plugin
 }
 
 // Validate checks if the plugin configuration is valid
 func (p *PluginConfig) Validate() error {
-	if p.Path == "" {
-		return fmt.Errorf("path is required")
+	if p.IsRemote() {
+		if err := p.validateRemote(); err != nil {
+			return err
+		}
+	} else {
+		if p.Path == "" {
+			return fmt.Errorf("path is required")
+		}
+		if p.Port < 0 || p.Port > 65535 {
+			return fmt.Errorf("invalid port: %d", p.Port)
+		}
+	}
+	if p.SHA256 != "" {
+		if _, err := hex.DecodeString(p.SHA256); err != nil || len(p.SHA256) != 64 {
+			return fmt.Errorf("invalid sha256: must be 64 hex characters")
+		}
+	}
+
+	if p.ConnectTimeout < 0 {
+		return fmt.Errorf("invalid connect_timeout: %s", p.ConnectTimeout)
+	}
+	if p.ReadyTimeout < 0 {
+		return fmt.Errorf("invalid ready_timeout: %s", p.ReadyTimeout)
+	}
+	if p.ReadyTimeout != 0 && p.IsRemote() {
+		return fmt.Errorf("ready_timeout is only supported for local plugins")
+	}
+	if p.StopTimeout < 0 {
+		return fmt.Errorf("invalid stop_timeout: %s", p.StopTimeout)
+	}
+	if p.StopTimeout != 0 && p.IsRemote() {
+		return fmt.Errorf("stop_timeout is only supported for local plugins")
 	}
-	if p.Port <= 0 {
-		return fmt.Errorf("invalid port: %d", p.Port)
+	if p.KeepAlive < 0 {
+		return fmt.Errorf("invalid keep_alive: %s", p.KeepAlive)
+	}
+	if p.ExecutionTimeout < 0 {
+		return fmt.Errorf("invalid execution_timeout: %s", p.ExecutionTimeout)
+	}
+
+	if err := p.validateLimits(); err != nil {
+		return err
+	}
+
+	if err := p.validateStart(); err != nil {
+		return err
+	}
+
+	if err := p.validateQueue(); err != nil {
+		return err
+	}
+
+	if err := p.validateFallback(); err != nil {
+		return err
+	}
+
+	if err := p.validateRestart(); err != nil {
+		return err
+	}
+
+	if err := p.validateVersion(); err != nil {
+		return err
+	}
+
+	if err := p.validateEnvFilter(); err != nil {
+		return err
+	}
+
+	if err := p.validateStandby(); err != nil {
+		return err
+	}
+
+	if err := p.validateReplicas(); err != nil {
+		return err
+	}
+
+	if err := p.validateSandbox(); err != nil {
+		return err
+	}
+
+	if p.HealthCheck != nil {
+		if err := p.HealthCheck.validate(); err != nil {
+			return err
+		}
+	}
+
+	if p.Watchdog != nil {
+		if err := p.Watchdog.validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := p.validateChannels(); err != nil {
+		return err
+	}
+
+	if err := validateMetricLabels(p.MetricLabels); err != nil {
+		return err
+	}
+
+	if err := p.validateLintIgnore(); err != nil {
+		return err
+	}
+
+	if err := p.validateTags(); err != nil {
+		return err
+	}
+
+	if p.Group != "" && p.User == "" {
+		return fmt.Errorf("group requires user to be set")
+	}
+	if p.User != "" && p.IsRemote() {
+		return fmt.Errorf("user is only supported for local plugins")
+	}
+	if p.Proxy != "" && !p.IsRemote() {
+		return fmt.Errorf("proxy is only supported for remote plugins")
+	}
+	if p.CredentialHelper != "" && !p.IsRemote() {
+		return fmt.Errorf("credential_helper is only supported for remote plugins")
+	}
+	if p.AuthHeaders != nil {
+		if !p.IsRemote() {
+			return fmt.Errorf("auth_headers is only supported for remote plugins")
+		}
+		if err := p.AuthHeaders.validate(p); err != nil {
+			return err
+		}
+	}
+	if p.SSH != nil && !p.IsRemote() {
+		return fmt.Errorf("ssh is only supported for remote plugins")
+	}
+
+	if _, err := CompressionDialOption(p.Compression); err != nil {
+		return err
+	}
+
+	switch p.ResultValidation {
+	case "", ResultValidationOff, ResultValidationWarn, ResultValidationError:
+	default:
+		return fmt.Errorf("invalid result_validation: %s (must be off, warn, or error)", p.ResultValidation)
 	}
 
 	switch p.Type {
@@ -50,6 +283,9 @@ func (p *PluginConfig) Validate() error {
 		if !strings.Contains(p.Command, "{port}") {
 			return fmt.Errorf("command must contain {port} placeholder")
 		}
+	case PluginTypeRemote:
+		// Remote settings were validated above
+		return nil
 	default:
 		return fmt.Errorf("unsupported plugin type: %s", p.Type)
 	}
@@ -57,38 +293,234 @@ func (p *PluginConfig) Validate() error {
 	return nil
 }
 
+// Duration is a time.Duration written as a string such as "30s" in the config file
+type Duration time.Duration
+
+// String returns the duration in time.Duration notation
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\": %v", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// DefaultStateDir is where the host keeps its own data unless state_dir says otherwise
+const DefaultStateDir = ".plugin-app"
+
 // AppConfig represents the main application configuration
 type AppConfig struct {
-	Plugins map[string]PluginConfig `json:"plugins"`
+	Plugins  map[string]PluginConfig `json:"plugins"`
+	ReadOnly bool                    `json:"read_only,omitempty"` // Refuse to start, stop or execute plugins
+
+	// Include names config fragments defining further plugins, as globs relative to the config
+	// file such as "plugins.d/*.json"
+	Include []string `json:"include,omitempty"`
+
+	// PluginDirs are scanned for plugin binaries, which are registered without a config entry
+	PluginDirs []string `json:"plugin_dirs,omitempty"`
+
+	// Profiles override plugin addresses, env and defaults per environment; $PLUGIN_APP_PROFILE or
+	// -profile selects the one LoadConfig applies
+	Profiles map[string]ProfileConfig `json:"profiles,omitempty"`
+
+	// RemoteAllowlist restricts the hosts remote plugins may reach (IPs, CIDRs, names, *.domain wildcards)
+	RemoteAllowlist []string `json:"remote_allowlist,omitempty"`
+
+	// TrustedKeys maps publisher names to base64 Ed25519 public keys; when set, local plugins
+	// only start with a manifest signed by one of them
+	TrustedKeys map[string]string `json:"trusted_keys,omitempty"`
+
+	// Redaction masks additional parameters and patterns in output, summaries and history
+	Redaction *RedactionConfig `json:"redaction,omitempty"`
+
+	StateDir      string `json:"state_dir,omitempty"`      // Where the host keeps its own data, defaults to .plugin-app
+	SchemaChanges string `json:"schema_changes,omitempty"` // Handling of breaking plugin schema changes (warn/block)
+	DaemonSocket  string `json:"daemon_socket,omitempty"`  // Unix socket of the daemon, defaults to <state_dir>/daemon.sock
+	DaemonAdmin   string `json:"daemon_admin,omitempty"`   // TCP address of the daemon's HTTP admin port with /healthz and /readyz, such as 127.0.0.1:9090; none by default
+
+	// MaxConcurrentExecutions caps the executions running at once across all plugins; further
+	// ones wait in a queue. 0 for no limit.
+	MaxConcurrentExecutions int `json:"max_concurrent_executions,omitempty"`
+
+	// Settings are host-wide defaults such as the execution timeout of plugins without their own
+	Settings *Settings `json:"settings,omitempty"`
+
+	// Memory sheds optional work and spills output to disk when the host itself runs short
+	Memory *MemoryConfig `json:"memory,omitempty"`
+
+	// Messages overrides and localizes the messages the CLI shows
+	Messages *MessagesConfig `json:"messages,omitempty"`
+
+	// Metrics exports execution metrics with labels such as tenant, team and environment
+	Metrics *MetricsConfig `json:"metrics,omitempty"`
+
+	encrypted  map[string]encryptedValue // enc: values as loaded, by JSON path, so SaveConfig keeps them encrypted
+	discovered map[string]string         // Plugins found in plugin_dirs, by name, to the directory they were found in
+	profile    string                    // Profile applied to the plugins
 }
 
-// LoadConfig loads the configuration from the specified file
-func LoadConfig(configPath string) (*AppConfig, error) {
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %v", err)
+// LoadRawConfig loads the configuration as written, without resolving paths or applying defaults.
+// Overlays are merged over it like LoadConfig does.
+func LoadRawConfig(configPath string, overlays ...string) (*AppConfig, error) {
+	config, _, err := loadRawConfig(append([]string{configPath}, overlays...))
+	return config, err
+}
+
+// loadRawConfig loads the configuration as written along with its violations of the schema,
+// merging the files of paths over each other in order. The error is for a file that can't be
+// read or decoded, listing every violation that prevents it.
+func loadRawConfig(paths []string) (*AppConfig, []ConfigProblem, error) {
+	var problems []ConfigProblem
+	var data []byte
+	var layers map[string]interface{}
+	for i, path := range paths {
+		layer, err := readConfigData(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		violations, err := checkConfigSchema(layer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+		}
+		if i > 0 {
+			for k := range violations {
+				violations[k].File = path
+			}
+		}
+		problems = append(problems, violations...)
+		if len(paths) == 1 {
+			data = layer
+			break
+		}
+		if layers, err = mergeConfigLayer(layers, layer); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+		}
+	}
+	if data == nil {
+		var err error
+		if data, err = json.Marshal(layers); err != nil {
+			return nil, nil, fmt.Errorf("failed to merge config files: %v", err)
+		}
 	}
 
 	var config AppConfig
 	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %v", err)
+		if len(problems) > 0 {
+			return nil, nil, ConfigErrors(problems)
+		}
+		return nil, nil, fmt.Errorf("failed to parse config file: %v", err)
+	}
+
+	// Values written as enc:... are decrypted here and encrypted again by SaveConfig
+	if err := decryptConfig(&config); err != nil {
+		return nil, nil, err
+	}
+
+	return &config, problems, nil
+}
+
+// LoadConfig loads the configuration from the specified file, failing with ConfigErrors listing
+// every problem if there are any. Overlays are files merged over it in order, such as one per
+// environment and a user's own: objects are merged key by key, and other values of later files
+// replace those of earlier ones.
+func LoadConfig(configPath string, overlays ...string) (*AppConfig, error) {
+	config, problems, err := loadConfig(append([]string{configPath}, overlays...))
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, ConfigErrors(problems)
+	}
+	return config, nil
+}
+
+// loadConfig reads the configuration, resolving paths and setting defaults, and returns it along
+// with everything that is wrong with it. The error is for a file that can't be read at all.
+// Includes are relative to the first of paths, the base configuration.
+func loadConfig(paths []string) (*AppConfig, []ConfigProblem, error) {
+	rawConfig, problems, err := loadRawConfig(paths)
+	if err != nil {
+		return nil, nil, err
+	}
+	config := *rawConfig
+	included, err := includeFragments(&config, paths[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	problems = append(problems, included...)
+	problem := func(plugin string, err error) {
+		problems = append(problems, ConfigProblem{Plugin: plugin, Err: err})
+	}
+	// Validating a plugin whose entry violates the schema would mostly repeat the violations
+	violated := make(map[string]bool)
+	for _, p := range problems {
+		violated[p.Plugin] = true
 	}
 
 	// Get workspace root (where config.json is)
 	workspaceRoot, err := os.Getwd()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get workspace root: %v", err)
+		return nil, nil, fmt.Errorf("failed to get workspace root: %v", err)
+	}
+	for _, err := range discoverPlugins(&config, workspaceRoot) {
+		problem("", err)
+	}
+	overridden, err := applyProfile(&config, os.Getenv(ProfileEnvVar))
+	if err != nil {
+		return nil, nil, err
 	}
+	problems = append(problems, overridden...)
 
-	// Resolve relative paths and set defaults
-	for name, plugin := range config.Plugins {
+	// Evaluate templates, resolve relative paths and set defaults, in a stable order so problems are too
+	templates := newTemplateContext(workspaceRoot)
+	names := make([]string, 0, len(config.Plugins))
+	for name := range config.Plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		plugin := config.Plugins[name]
+		if err := plugin.expandTemplates(name, templates); err != nil {
+			problem(name, err)
+			violated[name] = true
+		}
 		// Resolve relative paths
-		if !filepath.IsAbs(plugin.Path) {
+		if plugin.Path != "" && !filepath.IsAbs(plugin.Path) {
 			plugin.Path = filepath.Join(workspaceRoot, plugin.Path)
 		}
 		if plugin.WorkingDir != "" && !filepath.IsAbs(plugin.WorkingDir) {
 			plugin.WorkingDir = filepath.Join(workspaceRoot, plugin.WorkingDir)
 		}
+		if plugin.Manifest != "" && !filepath.IsAbs(plugin.Manifest) {
+			plugin.Manifest = filepath.Join(workspaceRoot, plugin.Manifest)
+		}
+		if plugin.Sandbox != nil && plugin.Sandbox.Seccomp != "" && !filepath.IsAbs(plugin.Sandbox.Seccomp) {
+			plugin.Sandbox.Seccomp = filepath.Join(workspaceRoot, plugin.Sandbox.Seccomp)
+		}
+		if plugin.SSH != nil {
+			if plugin.SSH.IdentityFile != "" && !filepath.IsAbs(plugin.SSH.IdentityFile) {
+				plugin.SSH.IdentityFile = filepath.Join(workspaceRoot, plugin.SSH.IdentityFile)
+			}
+			if plugin.SSH.KnownHostsFile != "" && !filepath.IsAbs(plugin.SSH.KnownHostsFile) {
+				plugin.SSH.KnownHostsFile = filepath.Join(workspaceRoot, plugin.SSH.KnownHostsFile)
+			}
+		}
 
 		// Set defaults
 		if plugin.Type == "" {
@@ -97,22 +529,100 @@ func LoadConfig(configPath string) (*AppConfig, error) {
 		if plugin.Environment == nil {
 			plugin.Environment = make(map[string]string)
 		}
-		if plugin.WorkingDir == "" {
+		if plugin.WorkingDir == "" && plugin.Path != "" {
 			plugin.WorkingDir = filepath.Dir(plugin.Path)
 		}
 		if plugin.Defaults == nil {
 			plugin.Defaults = make(map[string]string)
 		}
+		if plugin.ResultValidation == "" {
+			plugin.ResultValidation = ResultValidationWarn
+		}
+		if plugin.ExecutionTimeout == 0 && config.Settings != nil {
+			plugin.ExecutionTimeout = config.Settings.ExecutionTimeout
+		}
+		if plugin.EnvPolicy == "" && !plugin.IsRemote() && config.Settings != nil {
+			plugin.EnvPolicy = config.Settings.EnvPolicy
+		}
 
 		// Validate the configuration
-		if err := plugin.Validate(); err != nil {
-			return nil, fmt.Errorf("invalid configuration for plugin %q: %v", name, err)
+		if err := plugin.Validate(); err != nil && !violated[name] {
+			problem(name, err)
 		}
 
 		config.Plugins[name] = plugin
 	}
 
-	return &config, nil
+	if err := config.checkRemoteAllowlist(); err != nil {
+		problem("", err)
+	}
+	if err := config.checkTrustedKeys(); err != nil {
+		problem("", err)
+	}
+	for _, err := range config.portClashes() {
+		problem("", err)
+	}
+	if err := config.checkDependencies(); err != nil {
+		problem("", err)
+	}
+	if config.MaxConcurrentExecutions < 0 {
+		problem("", fmt.Errorf("invalid max_concurrent_executions: %d", config.MaxConcurrentExecutions))
+	}
+	if config.StateDir == "" {
+		config.StateDir = DefaultStateDir
+	}
+	if !filepath.IsAbs(config.StateDir) {
+		config.StateDir = filepath.Join(workspaceRoot, config.StateDir)
+	}
+	if config.DaemonSocket != "" && !filepath.IsAbs(config.DaemonSocket) {
+		config.DaemonSocket = filepath.Join(workspaceRoot, config.DaemonSocket)
+	}
+	if config.DaemonAdmin != "" {
+		if _, _, err := net.SplitHostPort(config.DaemonAdmin); err != nil {
+			problem("", fmt.Errorf("invalid daemon_admin: %v", err))
+		}
+	}
+	switch config.SchemaChanges {
+	case "":
+		config.SchemaChanges = SchemaChangesWarn
+	case SchemaChangesWarn, SchemaChangesBlock:
+	default:
+		problem("", fmt.Errorf("invalid schema_changes: %s (must be %s or %s)", config.SchemaChanges, SchemaChangesWarn, SchemaChangesBlock))
+	}
+	if config.Redaction != nil {
+		if err := config.Redaction.validate(); err != nil {
+			problem("", err)
+		}
+	}
+	if config.Settings != nil {
+		if err := config.Settings.validate(); err != nil {
+			problem("", err)
+		}
+		if config.Settings.OutputDir != "" && !filepath.IsAbs(config.Settings.OutputDir) {
+			config.Settings.OutputDir = filepath.Join(workspaceRoot, config.Settings.OutputDir)
+		}
+	}
+	if config.Memory != nil {
+		if err := config.Memory.validate(); err != nil {
+			problem("", err)
+		}
+		if config.Memory.SpillDir != "" && !filepath.IsAbs(config.Memory.SpillDir) {
+			config.Memory.SpillDir = filepath.Join(workspaceRoot, config.Memory.SpillDir)
+		}
+	}
+	if config.Metrics != nil {
+		if err := config.Metrics.validate(); err != nil {
+			problem("", err)
+		}
+		if config.Metrics.File != "" && !filepath.IsAbs(config.Metrics.File) {
+			config.Metrics.File = filepath.Join(workspaceRoot, config.Metrics.File)
+		}
+	}
+	if config.Messages != nil && config.Messages.File != "" && !filepath.IsAbs(config.Messages.File) {
+		config.Messages.File = filepath.Join(workspaceRoot, config.Messages.File)
+	}
+
+	return &config, problems, nil
 }
 
 // GetPluginConfig retrieves the configuration for a specific plugin
@@ -132,13 +642,31 @@ func (c *AppConfig) ListPlugins() []string {
 	return result
 }
 
-// SaveConfig saves the configuration to the specified file
+// SaveConfig saves the configuration to the specified file, encrypting the values that were
+// encrypted when it was loaded
 func SaveConfig(config *AppConfig, configPath string) error {
+	if IsRemoteConfig(configPath) {
+		return fmt.Errorf("config %s is remote; change it at its source", configPath)
+	}
 	data, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %v", err)
 	}
 
+	if len(config.encrypted) > 0 {
+		// Encrypt a copy so the caller keeps working with plaintext
+		var encrypted AppConfig
+		if err := json.Unmarshal(data, &encrypted); err != nil {
+			return fmt.Errorf("failed to copy config: %v", err)
+		}
+		if err := encryptConfig(&encrypted, config.encrypted); err != nil {
+			return fmt.Errorf("failed to encrypt config values: %v", err)
+		}
+		if data, err = json.MarshalIndent(&encrypted, "", "  "); err != nil {
+			return fmt.Errorf("failed to marshal config: %v", err)
+		}
+	}
+
 	if err := os.WriteFile(configPath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %v", err)
 	}
@@ -167,6 +695,8 @@ func (p *PluginConfig) GetStartCommand(port int) (string, []string, error) {
 		}
 
 		return parts[0], parts[1:], nil
+	case PluginTypeRemote:
+		return "", nil, fmt.Errorf("remote plugins are not started locally")
 	default:
 		return "", nil, fmt.Errorf("unsupported plugin type: %s", p.Type)
 	}
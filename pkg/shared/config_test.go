@@ -3,6 +3,7 @@ package shared
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestPluginConfig_Validate(t *testing.T) {
@@ -41,14 +42,13 @@ func TestPluginConfig_Validate(t *testing.T) {
 			errorMsg:  "path is required",
 		},
 		{
-			name: "Invalid Port (zero)",
+			name: "Automatic Port (zero)",
 			config: PluginConfig{
 				Path: "/path/to/binary",
 				Port: 0,
 				Type: PluginTypeBinary,
 			},
-			wantErr: true,
-			errorMsg:  "invalid port",
+			wantErr: false,
 		},
 		{
 			name: "Invalid Port (negative)",
@@ -82,6 +82,328 @@ func TestPluginConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errorMsg:  "command must contain {port} placeholder",
 		},
+		{
+			name: "Invalid result validation mode",
+			config: PluginConfig{
+				Path:             "/path/to/binary",
+				Port:             8080,
+				Type:             PluginTypeBinary,
+				ResultValidation: "strict",
+			},
+			wantErr:  true,
+			errorMsg: "invalid result_validation: strict",
+		},
+		{
+			name: "Malformed sha256",
+			config: PluginConfig{
+				Path:   "/path/to/binary",
+				Port:   8080,
+				Type:   PluginTypeBinary,
+				SHA256: "not-a-checksum",
+			},
+			wantErr:  true,
+			errorMsg: "invalid sha256",
+		},
+		{
+			name: "Unsupported compression",
+			config: PluginConfig{
+				Path:        "/path/to/binary",
+				Port:        8080,
+				Type:        PluginTypeBinary,
+				Compression: "brotli",
+			},
+			wantErr:  true,
+			errorMsg: "unsupported compression: brotli",
+		},
+		{
+			name: "Valid Remote type with replicas",
+			config: PluginConfig{
+				Type:          PluginTypeRemote,
+				Addresses:     []string{"10.0.0.1:50051", "10.0.0.2:50051"},
+				LoadBalancing: LoadBalancingRoundRobin,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Remote type, missing address",
+			config: PluginConfig{
+				Type: PluginTypeRemote,
+			},
+			wantErr:  true,
+			errorMsg: "address or addresses is required",
+		},
+		{
+			name: "Remote type, unsupported balancing policy",
+			config: PluginConfig{
+				Type:          PluginTypeRemote,
+				Address:       "dns:///plugins.internal:50051",
+				LoadBalancing: "least_request",
+			},
+			wantErr:  true,
+			errorMsg: "unsupported load_balancing",
+		},
+		{
+			name: "Negative connect timeout",
+			config: PluginConfig{
+				Type:           PluginTypeRemote,
+				Address:        "localhost:50051",
+				ConnectTimeout: Duration(-time.Second),
+			},
+			wantErr:  true,
+			errorMsg: "invalid connect_timeout",
+		},
+		{
+			name: "Negative ready timeout",
+			config: PluginConfig{
+				Type:         PluginTypeBinary,
+				Path:         "/path/to/binary",
+				ReadyTimeout: Duration(-time.Second),
+			},
+			wantErr:  true,
+			errorMsg: "invalid ready_timeout",
+		},
+		{
+			name: "Ready timeout on remote plugin",
+			config: PluginConfig{
+				Type:         PluginTypeRemote,
+				Address:      "localhost:50051",
+				ReadyTimeout: Duration(time.Second),
+			},
+			wantErr:  true,
+			errorMsg: "only supported for local plugins",
+		},
+		{
+			name: "Negative stop timeout",
+			config: PluginConfig{
+				Type:        PluginTypeBinary,
+				Path:        "/path/to/binary",
+				StopTimeout: Duration(-time.Second),
+			},
+			wantErr:  true,
+			errorMsg: "invalid stop_timeout",
+		},
+		{
+			name: "Negative keep alive",
+			config: PluginConfig{
+				Path:      "/path/to/binary",
+				Type:      PluginTypeBinary,
+				KeepAlive: Duration(-time.Second),
+			},
+			wantErr:  true,
+			errorMsg: "invalid keep_alive",
+		},
+		{
+			name: "Negative execution timeout",
+			config: PluginConfig{
+				Path:             "/path/to/binary",
+				Type:             PluginTypeBinary,
+				ExecutionTimeout: Duration(-time.Second),
+			},
+			wantErr:  true,
+			errorMsg: "invalid execution_timeout",
+		},
+		{
+			name: "Invalid start mode",
+			config: PluginConfig{
+				Path:  "/path/to/binary",
+				Type:  PluginTypeBinary,
+				Start: "sometimes",
+			},
+			wantErr:  true,
+			errorMsg: "invalid start: sometimes",
+		},
+		{
+			name: "Negative concurrency limit",
+			config: PluginConfig{
+				Path:                    "/path/to/binary",
+				Type:                    PluginTypeBinary,
+				MaxConcurrentExecutions: -1,
+			},
+			wantErr:  true,
+			errorMsg: "invalid max_concurrent_executions",
+		},
+		{
+			name: "Negative queue timeout",
+			config: PluginConfig{
+				Path:         "/path/to/binary",
+				Type:         PluginTypeBinary,
+				QueueTimeout: Duration(-time.Second),
+			},
+			wantErr:  true,
+			errorMsg: "invalid queue_timeout",
+		},
+		{
+			name: "Stop timeout on remote plugin",
+			config: PluginConfig{
+				Type:        PluginTypeRemote,
+				Address:     "localhost:50051",
+				StopTimeout: Duration(time.Second),
+			},
+			wantErr:  true,
+			errorMsg: "stop_timeout is only supported for local plugins",
+		},
+		{
+			name: "Negative message size",
+			config: PluginConfig{
+				Path:               "/path/to/binary",
+				Port:               8080,
+				Type:               PluginTypeBinary,
+				MaxRecvMessageSize: -1,
+			},
+			wantErr:  true,
+			errorMsg: "invalid max_recv_message_size",
+		},
+		{
+			name: "Standby without standby port",
+			config: PluginConfig{
+				Path:    "/path/to/binary",
+				Port:    8080,
+				Type:    PluginTypeBinary,
+				Standby: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Standby port same as port",
+			config: PluginConfig{
+				Path:        "/path/to/binary",
+				Port:        8080,
+				Type:        PluginTypeBinary,
+				Standby:     true,
+				StandbyPort: 8080,
+			},
+			wantErr:  true,
+			errorMsg: "invalid standby_port",
+		},
+		{
+			name: "Standby on remote plugin",
+			config: PluginConfig{
+				Type:        PluginTypeRemote,
+				Address:     "localhost:50051",
+				Standby:     true,
+				StandbyPort: 50052,
+			},
+			wantErr:  true,
+			errorMsg: "only supported for local plugins",
+		},
+		{
+			name: "Negative replicas",
+			config: PluginConfig{
+				Path:     "/path/to/binary",
+				Port:     8080,
+				Type:     PluginTypeBinary,
+				Replicas: -1,
+			},
+			wantErr:  true,
+			errorMsg: "invalid replicas",
+		},
+		{
+			name: "Replicas with standby",
+			config: PluginConfig{
+				Path:     "/path/to/binary",
+				Port:     8080,
+				Type:     PluginTypeBinary,
+				Standby:  true,
+				Replicas: 2,
+			},
+			wantErr:  true,
+			errorMsg: "replicas can't be combined with standby",
+		},
+		{
+			name: "Fallback addresses on local plugin",
+			config: PluginConfig{
+				Path:              "/path/to/binary",
+				Port:              8080,
+				Type:              PluginTypeBinary,
+				FallbackAddresses: []string{"localhost:50052"},
+			},
+			wantErr:  true,
+			errorMsg: "fallback_addresses is only supported for remote plugins",
+		},
+		{
+			name: "Fallback address same as address",
+			config: PluginConfig{
+				Type:              PluginTypeRemote,
+				Address:           "localhost:50051",
+				FallbackAddresses: []string{"localhost:50051"},
+			},
+			wantErr:  true,
+			errorMsg: "invalid fallback address",
+		},
+		{
+			name: "Fail back without fallback addresses",
+			config: PluginConfig{
+				Type:     PluginTypeRemote,
+				Address:  "localhost:50051",
+				FailBack: Duration(time.Minute),
+			},
+			wantErr:  true,
+			errorMsg: "fail_back requires fallback_addresses",
+		},
+		{
+			name: "Sandbox with unknown rlimit",
+			config: PluginConfig{
+				Path:    "/path/to/binary",
+				Port:    8080,
+				Type:    PluginTypeBinary,
+				Sandbox: &SandboxConfig{Rlimits: map[string]uint64{"bogus": 1}},
+			},
+			wantErr:  true,
+			errorMsg: "unsupported sandbox rlimit: bogus",
+		},
+		{
+			name: "Sandbox with relative read-only path",
+			config: PluginConfig{
+				Path:    "/path/to/binary",
+				Port:    8080,
+				Type:    PluginTypeBinary,
+				Sandbox: &SandboxConfig{ReadOnlyPaths: []string{"data"}},
+			},
+			wantErr:  true,
+			errorMsg: "must be absolute",
+		},
+		{
+			name: "Affinity key with incompatible balancing policy",
+			config: PluginConfig{
+				Type:          PluginTypeRemote,
+				Addresses:     []string{"10.0.0.1:50051", "10.0.0.2:50051"},
+				LoadBalancing: LoadBalancingRoundRobin,
+				AffinityKey:   "customer_id",
+			},
+			wantErr:  true,
+			errorMsg: "affinity_key requires load_balancing consistent_hash",
+		},
+		{
+			name: "Reserved metric label",
+			config: PluginConfig{
+				Path:         "/path/to/binary",
+				Type:         PluginTypeBinary,
+				MetricLabels: map[string]string{"status": "ok"},
+			},
+			wantErr:  true,
+			errorMsg: "metric label status is reserved",
+		},
+		{
+			name: "Unknown lint rule ignored",
+			config: PluginConfig{
+				Path:       "/path/to/binary",
+				Type:       PluginTypeBinary,
+				LintIgnore: []string{"missing-docs"},
+			},
+			wantErr:  true,
+			errorMsg: "unknown lint rule in lint_ignore: missing-docs",
+		},
+		{
+			name: "Group without user",
+			config: PluginConfig{
+				Path:  "/path/to/binary",
+				Port:  8080,
+				Type:  PluginTypeBinary,
+				Group: "plugins",
+			},
+			wantErr:  true,
+			errorMsg: "group requires user",
+		},
 		{
 			name: "Unsupported Plugin Type",
 			config: PluginConfig{
@@ -0,0 +1,124 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Errors of plugin files found by CheckConfig
+var (
+	ErrFileMissing   = errors.New("does not exist")
+	ErrNotExecutable = errors.New("is not executable")
+)
+
+// ConfigProblem is one thing wrong with a configuration
+type ConfigProblem struct {
+	Plugin string // Empty for settings outside a plugin's entry
+	Path   string // JSON path of the offending value, such as plugins.hello.port, if known
+	File   string // Included config fragment the value is in, empty for the config file
+	Err    error
+}
+
+func (p ConfigProblem) Error() string {
+	if p.Path != "" {
+		if p.File != "" {
+			return fmt.Sprintf("%s: %s: %v", p.File, p.Path, p.Err)
+		}
+		return fmt.Sprintf("%s: %v", p.Path, p.Err)
+	}
+	if p.Plugin == "" {
+		return p.Err.Error()
+	}
+	return fmt.Sprintf("invalid configuration for plugin %q: %v", p.Plugin, p.Err)
+}
+
+func (p ConfigProblem) Unwrap() error {
+	return p.Err
+}
+
+// ConfigErrors is everything wrong with a configuration that can't be used, reported at once
+type ConfigErrors []ConfigProblem
+
+func (e ConfigErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	lines := make([]string, len(e))
+	for i, problem := range e {
+		lines[i] = "\n  " + problem.Error()
+	}
+	return fmt.Sprintf("%d problems:%s", len(e), strings.Join(lines, ""))
+}
+
+// CheckConfig loads the configuration like LoadConfig but returns its problems instead of failing,
+// including plugin files that are missing or not executable, which only fail once a plugin is
+// started. The configuration is returned as far as it could be resolved; the error
+// is for a file that can't be read or parsed at all.
+func CheckConfig(configPath string, overlays ...string) (*AppConfig, []ConfigProblem, error) {
+	config, problems, err := loadConfig(append([]string{configPath}, overlays...))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := make([]string, 0, len(config.Plugins))
+	for name := range config.Plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		plugin := config.Plugins[name]
+		if err := plugin.checkFiles(); err != nil {
+			problems = append(problems, ConfigProblem{Plugin: name, Err: err})
+		}
+	}
+
+	// Plugins first, in name order, then the settings that concern several of them
+	sort.SliceStable(problems, func(i, j int) bool {
+		if (problems[i].Plugin == "") != (problems[j].Plugin == "") {
+			return problems[j].Plugin == ""
+		}
+		return problems[i].Plugin < problems[j].Plugin
+	})
+	return config, problems, nil
+}
+
+// checkFiles checks that what starts a local plugin exists and can be run
+func (p *PluginConfig) checkFiles() error {
+	switch p.Type {
+	case PluginTypeBinary:
+		if p.Path == "" {
+			return nil
+		}
+		info, err := os.Stat(p.Path)
+		if err != nil {
+			return fmt.Errorf("binary %s %w", p.Path, ErrFileMissing)
+		}
+		if info.IsDir() || info.Mode().Perm()&0111 == 0 {
+			return fmt.Errorf("binary %s %w", p.Path, ErrNotExecutable)
+		}
+	case PluginTypeCommand:
+		if p.Path != "" {
+			if _, err := os.Stat(p.Path); err != nil {
+				return fmt.Errorf("path %s %w", p.Path, ErrFileMissing)
+			}
+		}
+		fields := strings.Fields(p.Command)
+		if len(fields) == 0 || strings.Contains(fields[0], "{") {
+			return nil
+		}
+		// Relative commands run from the working directory, like the plugin does
+		command := fields[0]
+		if strings.Contains(command, "/") && !filepath.IsAbs(command) {
+			command = filepath.Join(p.WorkingDir, command)
+		}
+		if _, err := exec.LookPath(command); err != nil {
+			return fmt.Errorf("command %s %w or not on PATH", fields[0], ErrNotExecutable)
+		}
+	}
+	return nil
+}
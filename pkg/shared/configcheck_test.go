@@ -0,0 +1,63 @@
+package shared
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckConfig(t *testing.T) {
+	dir := t.TempDir()
+	executable := filepath.Join(dir, "plugin")
+	if err := os.WriteFile(executable, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	plain := filepath.Join(dir, "plain")
+	if err := os.WriteFile(plain, []byte("#!/bin/sh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "config.json")
+	data := fmt.Sprintf(`{"max_concurrent_executions": -1, "plugins": {
+		"ok": {"path": %q, "port": 9001},
+		"missing": {"path": %q, "port": 9002},
+		"plain": {"path": %q, "port": 9003},
+		"invalid": {"path": %q, "port": 9004, "replicas": -1},
+		"clash": {"path": %q, "port": 9001},
+		"command": {"type": "command", "command": "no-such-interpreter {path} {port}", "path": %q, "port": 9005}
+	}}`, executable, filepath.Join(dir, "nope"), plain, executable, executable, executable)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, problems, err := CheckConfig(path)
+	if err != nil {
+		t.Fatalf("CheckConfig() error = %v", err)
+	}
+	if config.Plugins["ok"].Path != executable {
+		t.Error("CheckConfig() did not return the resolved configuration")
+	}
+	want := []string{
+		`plugin "command": command no-such-interpreter is not executable`,
+		"plugins.invalid.replicas: must be >= 0",
+		`plugin "missing": binary ` + filepath.Join(dir, "nope") + " does not exist",
+		`plugin "plain": binary ` + plain + " is not executable",
+		`plugins "clash" and "ok" both use port 9001`,
+		"invalid max_concurrent_executions",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("CheckConfig() problems = %v, want %d", problems, len(want))
+	}
+	for i, w := range want {
+		if !strings.Contains(problems[i].Error(), w) {
+			t.Errorf("problem %d = %q, want it to contain %q", i, problems[i].Error(), w)
+		}
+	}
+
+	// Loading fails with every problem that makes the configuration unusable
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "3 problems") || !strings.Contains(err.Error(), "plugins.invalid.replicas") {
+		t.Errorf("LoadConfig() error = %v, want the invalid plugin among 3 problems", err)
+	}
+}
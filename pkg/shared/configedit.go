@@ -0,0 +1,128 @@
+package shared
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// AddPluginToConfig adds a plugin to the config file without rewriting the rest of it, unlike
+// SaveConfig, so the order, formatting and encrypted values of existing entries are kept. The
+// entry is written like SaveConfig writes it, indented by two spaces per level.
+func AddPluginToConfig(configPath, name string, plugin PluginConfig) error {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+	updated, err := insertPlugin(data, name, plugin)
+	if err != nil {
+		return fmt.Errorf("failed to add plugin %s to %s: %v", name, configPath, err)
+	}
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(configPath, updated, info.Mode().Perm())
+}
+
+// insertPlugin returns the configuration with the plugin added as the last of its plugins
+func insertPlugin(data []byte, name string, plugin PluginConfig) ([]byte, error) {
+	entry, err := json.MarshalIndent(map[string]PluginConfig{name: plugin}, "  ", "  ")
+	if err != nil {
+		return nil, err
+	}
+	// Strip the braces of the wrapping object, keeping the entry's lines
+	entry = bytes.TrimSpace(entry[1 : len(entry)-1])
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	settings := 0
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		settings++
+		if token != "plugins" {
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := expectDelim(dec, '{'); err != nil {
+			return nil, errors.New("plugins is not an object")
+		}
+		entries := 0
+		for dec.More() {
+			token, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			if token == name {
+				return nil, fmt.Errorf("plugin %s already exists", name)
+			}
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return nil, err
+			}
+			entries++
+		}
+		// The closing brace is next; what precedes it is the last entry, or the opening brace
+		to := dec.InputOffset()
+		from := lastNonSpace(data, to)
+		if entries > 0 {
+			return splice(data, from, from, ",\n    "+string(entry)), nil
+		}
+		return splice(data, from, to, "\n    "+string(entry)+"\n  "), nil
+	}
+
+	// Without plugins, they are added after the last setting
+	to := dec.InputOffset()
+	from := lastNonSpace(data, to)
+	plugins := "\"plugins\": {\n    " + string(entry) + "\n  }"
+	if settings > 0 {
+		return splice(data, from, from, ",\n  "+plugins), nil
+	}
+	return splice(data, from, to, "\n  "+plugins+"\n"), nil
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err == io.EOF {
+		return errors.New("file is empty")
+	}
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %v", delim)
+	}
+	return nil
+}
+
+// lastNonSpace returns the offset after the last non-space byte before at
+func lastNonSpace(data []byte, at int64) int64 {
+	for at > 0 && isSpace(data[at-1]) {
+		at--
+	}
+	return at
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// splice returns data with the bytes from from to to replaced by insert
+func splice(data []byte, from, to int64, insert string) []byte {
+	updated := make([]byte, 0, len(data)+len(insert))
+	updated = append(updated, data[:from]...)
+	updated = append(updated, insert...)
+	return append(updated, data[to:]...)
+}
@@ -0,0 +1,96 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInsertPlugin(t *testing.T) {
+	plugin := PluginConfig{Type: PluginTypeBinary, Path: "./bin/new", Port: 50060, Description: "New"}
+	entry := `"new": {
+      "path": "./bin/new",
+      "port": 50060,
+      "type": "binary",
+      "description": "New"
+    }`
+
+	tests := []struct {
+		name     string
+		config   string
+		want     string
+		wantErr  bool
+		errorMsg string
+	}{
+		{
+			name:   "After the last plugin",
+			config: "{\n  \"plugins\": {\n    \"old\": {\"path\": \"enc:abc\"}\n  },\n  \"state_dir\": \"s\"\n}\n",
+			want:   "{\n  \"plugins\": {\n    \"old\": {\"path\": \"enc:abc\"},\n    " + entry + "\n  },\n  \"state_dir\": \"s\"\n}\n",
+		},
+		{
+			name:   "Empty plugins",
+			config: "{\n  \"plugins\": {\n  }\n}\n",
+			want:   "{\n  \"plugins\": {\n    " + entry + "\n  }\n}\n",
+		},
+		{
+			name:   "No plugins",
+			config: "{\n  \"state_dir\": \"s\"\n}\n",
+			want:   "{\n  \"state_dir\": \"s\",\n  \"plugins\": {\n    " + entry + "\n  }\n}\n",
+		},
+		{
+			name:   "Empty configuration",
+			config: "{}",
+			want:   "{\n  \"plugins\": {\n    " + entry + "\n  }\n}",
+		},
+		{
+			name:     "Existing plugin",
+			config:   `{"plugins": {"new": {}}}`,
+			wantErr:  true,
+			errorMsg: "plugin new already exists",
+		},
+		{
+			name:     "Plugins not an object",
+			config:   `{"plugins": []}`,
+			wantErr:  true,
+			errorMsg: "plugins is not an object",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := insertPlugin([]byte(tt.config), "new", plugin)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("insertPlugin() error = %v, want %q", err, tt.errorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("insertPlugin() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("insertPlugin() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddPluginToConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"plugins": {"old": {"path": "./bin/old", "port": 50051}}}`), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddPluginToConfig(path, "new", PluginConfig{Path: "./bin/new", Port: 50052}); err != nil {
+		t.Fatalf("AddPluginToConfig() error = %v", err)
+	}
+	config, err := LoadRawConfig(path)
+	if err != nil {
+		t.Fatalf("LoadRawConfig() error = %v", err)
+	}
+	if config.Plugins["old"].Port != 50051 || config.Plugins["new"].Port != 50052 {
+		t.Errorf("plugins after AddPluginToConfig() = %+v", config.Plugins)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("config file mode = %v, %v; want it kept", info.Mode().Perm(), err)
+	}
+}
@@ -0,0 +1,212 @@
+package shared
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationType is checked as a duration string rather than the number it is underneath
+var durationType = reflect.TypeOf(Duration(0))
+
+// checkConfigSchema checks a configuration file against its schema, the Go types it decodes into:
+// json tags name the keys an object may have, and min and max tags bound numbers and durations.
+// Every violation is reported with the JSON path of the value, such as plugins.hello.port, so
+// that typos like "comand" are caught rather than ignored. The error is for data that isn't
+// JSON at all.
+func checkConfigSchema(data []byte) ([]ConfigProblem, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	var problems []ConfigProblem
+	checkSchema(value, reflect.TypeOf(AppConfig{}), "", nil, func(path []string, err error) {
+		problem := ConfigProblem{Path: strings.Join(path, "."), Err: err}
+		if len(path) > 1 && path[0] == "plugins" {
+			problem.Plugin = path[1]
+		}
+		problems = append(problems, problem)
+	})
+	return problems, nil
+}
+
+// checkSchema checks a decoded JSON value against type t, and the tag of the field it is for if
+// any, calling report for each violation
+func checkSchema(value interface{}, t reflect.Type, tag reflect.StructTag, path []string, report func(path []string, err error)) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if value == nil {
+		return // null leaves the setting unset
+	}
+
+	if t == durationType {
+		s, ok := value.(string)
+		if !ok {
+			report(path, fmt.Errorf("must be a duration string like \"30s\""))
+			return
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			report(path, fmt.Errorf("must be a duration string like \"30s\""))
+			return
+		}
+		checkBounds(float64(d), tag, path, report, func(bound float64) string { return time.Duration(bound).String() })
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			report(path, fmt.Errorf("must be an object"))
+			return
+		}
+		fields := schemaFields(t)
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			field, ok := fields[key]
+			if !ok {
+				report(append(path, key), unknownKey(key, fields))
+				continue
+			}
+			checkSchema(object[key], field.Type, field.Tag, append(path, key), report)
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			report(path, fmt.Errorf("must be an object"))
+			return
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			checkSchema(object[key], t.Elem(), "", append(path, key), report)
+		}
+	case reflect.Slice:
+		array, ok := value.([]interface{})
+		if !ok {
+			report(path, fmt.Errorf("must be an array"))
+			return
+		}
+		for i, element := range array {
+			checkSchema(element, t.Elem(), "", append(path, strconv.Itoa(i)), report)
+		}
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			report(path, fmt.Errorf("must be a string"))
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			report(path, fmt.Errorf("must be true or false"))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number, ok := value.(json.Number)
+		if !ok {
+			report(path, fmt.Errorf("must be a number"))
+			return
+		}
+		n, err := strconv.ParseInt(string(number), 10, 64)
+		if err != nil {
+			report(path, fmt.Errorf("must be a whole number"))
+			return
+		}
+		if t.Kind() >= reflect.Uint && n < 0 {
+			report(path, fmt.Errorf("must be >= 0"))
+			return
+		}
+		checkBounds(float64(n), tag, path, report, func(bound float64) string { return strconv.FormatFloat(bound, 'f', -1, 64) })
+	case reflect.Float32, reflect.Float64:
+		if _, ok := value.(json.Number); !ok {
+			report(path, fmt.Errorf("must be a number"))
+		}
+	}
+}
+
+// checkBounds reports a number or duration outside the min and max tags of its field
+func checkBounds(value float64, tag reflect.StructTag, path []string, report func(path []string, err error), format func(float64) string) {
+	if bound, err := strconv.ParseFloat(tag.Get("min"), 64); err == nil && value < bound {
+		report(path, fmt.Errorf("must be >= %s", format(bound)))
+	}
+	if bound, err := strconv.ParseFloat(tag.Get("max"), 64); err == nil && value > bound {
+		report(path, fmt.Errorf("must be <= %s", format(bound)))
+	}
+}
+
+// schemaFields returns the fields of a struct type by the key they are decoded from, including
+// those of embedded structs
+func schemaFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			for key, f := range schemaFields(embedded) {
+				fields[key] = f
+			}
+			continue
+		}
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// unknownKey describes a key the schema doesn't have, suggesting the closest one it does
+func unknownKey(key string, fields map[string]reflect.StructField) error {
+	best, distance := "", 3 // Suggest only keys up to two edits away
+	for known := range fields {
+		if d := editDistance(key, known); d < distance || d == distance && known < best {
+			best, distance = known, d
+		}
+	}
+	if best == "" {
+		return fmt.Errorf("unknown key")
+	}
+	return fmt.Errorf("unknown key (did you mean %s?)", best)
+}
+
+// editDistance is the Levenshtein distance between two keys
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,70 @@
+package shared
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckConfigSchema(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "Valid",
+			data: `{"read_only": true, "plugins": {"hello": {"path": "bin/hello", "port": 9001, "call_timeout": "5s", "env": {"A": "1"}}}}`,
+		},
+		{
+			name: "Typo in a plugin",
+			data: `{"plugins": {"hello": {"type": "command", "comand": "python {path} {port}"}}}`,
+			want: []string{"plugins.hello.comand: unknown key (did you mean command?)"},
+		},
+		{
+			name: "Unknown top-level key",
+			data: `{"plugins": {}, "zzz": 1}`,
+			want: []string{"zzz: unknown key"},
+		},
+		{
+			name: "Every violation at once",
+			data: `{"read_only": "yes", "plugins": {"a": {"port": 70000, "replicas": 1.5}, "b": {"keep_alive": 30, "stop_timeout": "-1s", "depends_on": "a"}}}`,
+			want: []string{
+				"plugins.a.port: must be <= 65535",
+				"plugins.a.replicas: must be a whole number",
+				"plugins.b.depends_on: must be an array",
+				"plugins.b.keep_alive: must be a duration string like \"30s\"",
+				"plugins.b.stop_timeout: must be >= 0s",
+				"read_only: must be true or false",
+			},
+		},
+		{
+			name: "Nested settings",
+			data: `{"plugins": {"a": {"sandbox": {"no_netwrk": true, "rlimits": 1}, "channels": {"output": {"buffr": 1}}}}}`,
+			want: []string{
+				"plugins.a.channels.output.buffr: unknown key (did you mean buffer?)",
+				"plugins.a.sandbox.no_netwrk: unknown key (did you mean no_network?)",
+				"plugins.a.sandbox.rlimits: must be an object",
+			},
+		},
+		{
+			name: "Null leaves settings unset",
+			data: `{"plugins": {"a": {"port": null, "sandbox": null}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems, err := checkConfigSchema([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("checkConfigSchema() error = %v", err)
+			}
+			var got []string
+			for _, problem := range problems {
+				got = append(got, problem.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkConfigSchema() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package shared
+
+import (
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// TemplateContext is what Go templates in a plugin's path, command, workdir and env values can
+// refer to, such as "bin/{{.OS}}_{{.Arch}}/hello" or "{{.Env.HOME}}/plugins"
+type TemplateContext struct {
+	Env       map[string]string // The host's environment variables; a missing one is an error, {{index .Env "NAME"}} is empty instead
+	Hostname  string
+	OS        string // runtime.GOOS, such as linux
+	Arch      string // runtime.GOARCH, such as amd64
+	Workspace string // Directory relative paths are resolved against
+}
+
+// newTemplateContext returns the context templates are evaluated in on this host
+func newTemplateContext(workspaceRoot string) TemplateContext {
+	ctx := TemplateContext{
+		Env:       make(map[string]string),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Workspace: workspaceRoot,
+	}
+	ctx.Hostname, _ = os.Hostname()
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			ctx.Env[k] = v
+		}
+	}
+	return ctx
+}
+
+// expandTemplates evaluates the templates in the plugin's path, command, workdir and env values.
+// Errors name the value and the line and column of the template within it.
+func (p *PluginConfig) expandTemplates(name string, ctx TemplateContext) error {
+	prefix := "plugins." + name + "."
+	var err error
+	if p.Path, err = expandTemplate(prefix+"path", p.Path, ctx); err != nil {
+		return err
+	}
+	if p.Command, err = expandTemplate(prefix+"command", p.Command, ctx); err != nil {
+		return err
+	}
+	if p.WorkingDir, err = expandTemplate(prefix+"workdir", p.WorkingDir, ctx); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(p.Environment))
+	for k := range p.Environment {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value, err := expandTemplate(prefix+"env."+k, p.Environment[k], ctx)
+		if err != nil {
+			return err
+		}
+		p.Environment[k] = value
+	}
+	return nil
+}
+
+// expandTemplate evaluates value as a template named after where it is in the configuration;
+// values without {{ are returned as they are
+func expandTemplate(path, value string, ctx TemplateContext) (string, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+	t, err := template.New(path).Option("missingkey=error").Parse(value)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, ctx); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,90 @@
+package shared
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	ctx := TemplateContext{
+		Env:       map[string]string{"HOME": "/home/me"},
+		Hostname:  "build-1",
+		OS:        "linux",
+		Arch:      "arm64",
+		Workspace: "/work",
+	}
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr string
+	}{
+		{name: "No template", value: "bin/hello {port}", want: "bin/hello {port}"},
+		{name: "Platform", value: "bin/{{.OS}}_{{.Arch}}/hello", want: "bin/linux_arm64/hello"},
+		{name: "Environment and host", value: "{{.Env.HOME}}/{{.Hostname}}", want: "/home/me/build-1"},
+		{name: "Optional variable", value: `x{{index .Env "UNSET"}}`, want: "x"},
+		{name: "Workspace", value: "{{.Workspace}}/plugins", want: "/work/plugins"},
+		{name: "Missing variable", value: "{{.Env.UNSET}}", wantErr: `plugins.p.path:1:6: executing "plugins.p.path" at <.Env.UNSET>: map has no entry for key "UNSET"`},
+		{name: "Unknown field", value: "a {{.Platform}}", wantErr: "plugins.p.path:1:4: executing \"plugins.p.path\" at <.Platform>: can't evaluate field Platform"},
+		{name: "Malformed", value: "{{.OS", wantErr: "plugins.p.path:1: unclosed action"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandTemplate("plugins.p.path", tt.value, ctx)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expandTemplate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandTemplate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expandTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_templates(t *testing.T) {
+	dir := t.TempDir()
+	workspace, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PLUGIN_TEMPLATE_TEST", "debug")
+	path := filepath.Join(dir, "config.json")
+	data := `{"plugins": {"hello": {"type": "command", "path": "bin/{{.OS}}_{{.Arch}}/hello", "command": "{path} -port {port}",
+		"env": {"LEVEL": "{{.Env.PLUGIN_TEMPLATE_TEST}}"}}}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	hello := config.Plugins["hello"]
+	if want := filepath.Join(workspace, "bin", runtime.GOOS+"_"+runtime.GOARCH, "hello"); hello.Path != want {
+		t.Errorf("path = %q, want %q", hello.Path, want)
+	}
+	if hello.Environment["LEVEL"] != "debug" {
+		t.Errorf("env LEVEL = %q, want debug", hello.Environment["LEVEL"])
+	}
+
+	data = `{"plugins": {"hello": {"type": "command", "path": "bin/hello", "workdir": "{{.Nope}}"}}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadConfig(path)
+	var problems ConfigErrors
+	if !errors.As(err, &problems) || len(problems) != 1 || problems[0].Plugin != "hello" ||
+		!strings.Contains(problems[0].Error(), "plugins.hello.workdir:1:2") {
+		t.Errorf("LoadConfig() error = %v, want the position of the template", err)
+	}
+}
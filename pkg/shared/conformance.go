@@ -0,0 +1,477 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Conformance check statuses
+const (
+	ConformancePass = "pass"
+	ConformanceFail = "fail"
+	ConformanceSkip = "skip" // The check didn't apply or couldn't be made
+)
+
+// conformanceInvalidValue is passed for parameters with allowed values, which it is never one of
+const conformanceInvalidValue = "conformance-check-invalid-value"
+
+// ConformanceResult is the outcome of one conformance check
+type ConformanceResult struct {
+	Check  string `json:"check"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ConformanceOptions tunes CheckConformance
+type ConformanceOptions struct {
+	Params      map[string]string // Valid values for parameters, on top of the schema's defaults
+	Timeout     time.Duration     // Of each execution, none if 0
+	CancelAfter time.Duration     // How long an execution runs before the cancellation check cancels it
+	CancelGrace time.Duration     // How long a canceled execution may take to end
+}
+
+// CheckConformance exercises a plugin against the protocol contract: the completeness of its
+// info, the rejection of missing required parameters and of values that aren't allowed, the
+// progress it reports, how it ends a canceled execution and the summary it returns. Executions
+// are real, though plugins that aren't side-effect free but support dry_run are asked for one.
+func CheckConformance(ctx context.Context, plugin PluginInterface, opts ConformanceOptions) []ConformanceResult {
+	if opts.CancelAfter <= 0 {
+		opts.CancelAfter = 200 * time.Millisecond
+	}
+	if opts.CancelGrace <= 0 {
+		opts.CancelGrace = 5 * time.Second
+	}
+
+	info, err := plugin.GetInfo(ctx)
+	if err != nil {
+		return []ConformanceResult{{Check: "info", Status: ConformanceFail, Detail: err.Error()}}
+	}
+	results := []ConformanceResult{checkInfo(info)}
+
+	c := conformanceChecker{ctx: ctx, plugin: plugin, info: info, opts: opts}
+	c.params, c.missing = conformanceParams(info, opts.Params)
+	results = append(results,
+		c.checkRequired(),
+		c.checkAllowedValues(),
+	)
+	results = append(results, c.checkExecution()...)
+	results = append(results,
+		c.checkCancellation(),
+		c.checkSummary(),
+	)
+	return results
+}
+
+// ConformanceFailures counts the failed checks
+func ConformanceFailures(results []ConformanceResult) int {
+	failures := 0
+	for _, result := range results {
+		if result.Status == ConformanceFail {
+			failures++
+		}
+	}
+	return failures
+}
+
+// checkInfo checks that the plugin's info is complete and consistent
+func checkInfo(info *PluginInfo) ConformanceResult {
+	var problems []string
+	if info.Name == "" {
+		problems = append(problems, "no name")
+	}
+	if info.Version == "" {
+		problems = append(problems, "no version")
+	}
+	if info.Description == "" {
+		problems = append(problems, "no description")
+	}
+	problems = append(problems, CurrentHost().Check(info)...)
+
+	for _, name := range sortedSpecNames(info.ParameterSchema) {
+		spec := info.ParameterSchema[name]
+		if spec.Name != "" && spec.Name != name {
+			problems = append(problems, fmt.Sprintf("parameter %s is named %s in its spec", name, spec.Name))
+		}
+		if spec.Description == "" {
+			problems = append(problems, fmt.Sprintf("parameter %s has no description", name))
+		}
+		if spec.DefaultValue != "" && len(spec.AllowedValues) > 0 && !containsString(spec.AllowedValues, spec.DefaultValue) {
+			problems = append(problems, fmt.Sprintf("default of parameter %s is not one of its allowed values", name))
+		}
+	}
+	for name, spec := range info.ResultSchema {
+		if spec.Name != "" && spec.Name != name {
+			problems = append(problems, fmt.Sprintf("result field %s is named %s in its spec", name, spec.Name))
+		}
+	}
+	return conformanceOutcome("info", problems)
+}
+
+// conformanceParams returns the parameters executions start from: the given ones over the
+// schema's defaults, with a dry run requested where one is needed and possible. missing lists
+// the required parameters still without a value.
+func conformanceParams(info *PluginInfo, given map[string]string) (params map[string]string, missing []string) {
+	params = make(map[string]string)
+	for name, spec := range info.ParameterSchema {
+		if spec.DefaultValue != "" {
+			params[name] = spec.DefaultValue
+		}
+	}
+	for name, value := range given {
+		params[name] = value
+	}
+	if _, set := params[DryRunParam]; !set && !info.SideEffectFree && CheckReplayable(info) == nil {
+		params[DryRunParam] = "true"
+	}
+	return params, MissingParams(info.ParameterSchema, params)
+}
+
+// conformanceChecker runs the checks that execute the plugin
+type conformanceChecker struct {
+	ctx     context.Context
+	plugin  PluginInterface
+	info    *PluginInfo
+	opts    ConformanceOptions
+	params  map[string]string
+	missing []string
+}
+
+// withParams returns the starting parameters changed by set, deleting those set to ""
+func (c *conformanceChecker) withParams(set map[string]string) map[string]string {
+	params := make(map[string]string, len(c.params))
+	for name, value := range c.params {
+		params[name] = value
+	}
+	for name, value := range set {
+		if value == "" {
+			delete(params, name)
+		} else {
+			params[name] = value
+		}
+	}
+	return params
+}
+
+// execute runs the plugin with params under the configured timeout
+func (c *conformanceChecker) execute(ctx context.Context, params map[string]string) (*conformanceHandler, error) {
+	handler := &conformanceHandler{}
+	err := ExecuteWithTimeout(ctx, c.plugin, c.info.Name, c.opts.Timeout, params, handler)
+	return handler, err
+}
+
+// skipWithoutParams explains why checks that need a valid execution are skipped, "" if they aren't
+func (c *conformanceChecker) skipWithoutParams() string {
+	if len(c.missing) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("no value for required parameter(s) %s; pass them as name=value", strings.Join(c.missing, ", "))
+}
+
+// checkRequired checks that executions missing a required parameter are rejected
+func (c *conformanceChecker) checkRequired() ConformanceResult {
+	const check = "required-params"
+	var required []string
+	for _, name := range sortedSpecNames(c.info.ParameterSchema) {
+		if c.info.ParameterSchema[name].Required {
+			required = append(required, name)
+		}
+	}
+	if len(required) == 0 {
+		return ConformanceResult{Check: check, Status: ConformanceSkip, Detail: "no required parameters"}
+	}
+	if reason := c.skipWithoutParams(); reason != "" {
+		return ConformanceResult{Check: check, Status: ConformanceSkip, Detail: reason}
+	}
+
+	var problems []string
+	for _, name := range required {
+		handler, err := c.execute(c.ctx, c.withParams(map[string]string{name: ""}))
+		if handler.rejection(err) == "" {
+			problems = append(problems, fmt.Sprintf("execution without %s was accepted", name))
+		}
+	}
+	return conformanceOutcome(check, problems)
+}
+
+// checkAllowedValues checks that executions with a value outside a parameter's allowed values
+// are rejected
+func (c *conformanceChecker) checkAllowedValues() ConformanceResult {
+	const check = "allowed-values"
+	var restricted []string
+	for _, name := range sortedSpecNames(c.info.ParameterSchema) {
+		if len(c.info.ParameterSchema[name].AllowedValues) > 0 {
+			restricted = append(restricted, name)
+		}
+	}
+	if len(restricted) == 0 {
+		return ConformanceResult{Check: check, Status: ConformanceSkip, Detail: "no parameters with allowed values"}
+	}
+	if reason := c.skipWithoutParams(); reason != "" {
+		return ConformanceResult{Check: check, Status: ConformanceSkip, Detail: reason}
+	}
+
+	var problems []string
+	for _, name := range restricted {
+		handler, err := c.execute(c.ctx, c.withParams(map[string]string{name: conformanceInvalidValue}))
+		if handler.rejection(err) == "" {
+			problems = append(problems, fmt.Sprintf("execution with %s=%s was accepted", name, conformanceInvalidValue))
+		}
+	}
+	return conformanceOutcome(check, problems)
+}
+
+// checkExecution runs the plugin with valid parameters, checking that it succeeds with a result
+// matching its schema and that the progress it reports only moves forward
+func (c *conformanceChecker) checkExecution() []ConformanceResult {
+	if reason := c.skipWithoutParams(); reason != "" {
+		return []ConformanceResult{
+			{Check: "execution", Status: ConformanceSkip, Detail: reason},
+			{Check: "progress", Status: ConformanceSkip, Detail: reason},
+		}
+	}
+
+	handler, err := c.execute(c.ctx, c.params)
+	var problems []string
+	if rejection := handler.rejection(err); rejection != "" {
+		problems = append(problems, "failed: "+rejection)
+	} else if handler.result == nil && len(c.info.ResultSchema) > 0 {
+		problems = append(problems, "no result although the plugin publishes a result schema")
+	} else if handler.result != nil {
+		problems = append(problems, ValidateResult(c.info.ResultSchema, handler.result)...)
+	}
+	execution := conformanceOutcome("execution", problems)
+
+	if len(handler.progress) == 0 {
+		return []ConformanceResult{execution, {Check: "progress", Status: ConformanceSkip, Detail: "no progress reported"}}
+	}
+	return []ConformanceResult{execution, conformanceOutcome("progress", checkProgress(handler.progress))}
+}
+
+// checkProgress returns the ways a series of progress reports goes backwards or out of range
+func checkProgress(reports []Progress) []string {
+	var problems []string
+	last := float32(-1)
+	for i, p := range reports {
+		if p.PercentComplete < 0 || p.PercentComplete > 100 || math.IsNaN(float64(p.PercentComplete)) {
+			problems = append(problems, fmt.Sprintf("report %d: %.1f%% is out of range", i+1, p.PercentComplete))
+		} else if p.PercentComplete < last {
+			problems = append(problems, fmt.Sprintf("report %d: %.1f%% after %.1f%%", i+1, p.PercentComplete, last))
+		}
+		if p.PercentComplete > last {
+			last = p.PercentComplete
+		}
+		if p.TotalSteps > 0 && (p.CurrentStep < 0 || p.CurrentStep > p.TotalSteps) {
+			problems = append(problems, fmt.Sprintf("report %d: step %d of %d", i+1, p.CurrentStep, p.TotalSteps))
+		}
+	}
+	return problems
+}
+
+// checkCancellation cancels an execution once it is under way and checks that it ends promptly
+// and leaves the plugin able to execute again
+func (c *conformanceChecker) checkCancellation() ConformanceResult {
+	const check = "cancellation"
+	if reason := c.skipWithoutParams(); reason != "" {
+		return ConformanceResult{Check: check, Status: ConformanceSkip, Detail: reason}
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+	handler := &conformanceHandler{started: make(chan struct{})}
+	done := make(chan error, 1)
+	go func() {
+		done <- c.plugin.Execute(ctx, c.params, handler)
+	}()
+
+	select {
+	case err := <-done:
+		return ConformanceResult{Check: check, Status: ConformanceSkip, Detail: "execution ended before it could be canceled: " + describeEnd(handler, err)}
+	case <-handler.started:
+	case <-time.After(c.opts.CancelAfter):
+	}
+	cancel()
+	canceledAt := time.Now()
+
+	select {
+	case err := <-done:
+		if err == nil && handler.errorCode == "" {
+			return ConformanceResult{Check: check, Status: ConformanceFail, Detail: "canceled execution reported success"}
+		}
+	case <-time.After(c.opts.CancelGrace):
+		return ConformanceResult{Check: check, Status: ConformanceFail, Detail: fmt.Sprintf("execution still running %s after it was canceled", c.opts.CancelGrace)}
+	}
+	took := time.Since(canceledAt).Round(time.Millisecond)
+
+	after, err := c.execute(c.ctx, c.params)
+	if rejection := after.rejection(err); rejection != "" {
+		return ConformanceResult{Check: check, Status: ConformanceFail, Detail: "execution after the cancellation failed: " + rejection}
+	}
+	return ConformanceResult{Check: check, Status: ConformancePass, Detail: fmt.Sprintf("ended %s after it was canceled", took)}
+}
+
+// describeEnd summarizes how an execution ended
+func describeEnd(handler *conformanceHandler, err error) string {
+	if rejection := handler.rejection(err); rejection != "" {
+		return rejection
+	}
+	return "success"
+}
+
+// checkSummary checks that the plugin's execution summary matches what it was told, for a
+// successful and a failed execution
+func (c *conformanceChecker) checkSummary() ConformanceResult {
+	var problems []string
+	end := time.Now()
+	start := end.Add(-1500 * time.Millisecond)
+	metadata := map[string]string{"conformance": "true"}
+	metrics := map[string]float64{"conformance_metric": 1}
+
+	for _, success := range []bool{true, false} {
+		var execErr error
+		outcome := "successful"
+		if !success {
+			execErr = errors.New("conformance check failure")
+			outcome = "failed"
+		}
+		summary, err := c.plugin.ReportExecutionSummary(start.UnixNano(), end.UnixNano(), success, execErr, metadata, metrics)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s execution: %v", outcome, err))
+			continue
+		}
+		for _, problem := range summaryProblems(c.info.Name, summary, start, end, success, execErr, metadata, metrics) {
+			problems = append(problems, fmt.Sprintf("%s execution: %s", outcome, problem))
+		}
+	}
+	return conformanceOutcome("summary", problems)
+}
+
+// summaryProblems compares a summary with what it was made from
+func summaryProblems(name string, summary *ExecutionSummary, start, end time.Time, success bool, execErr error, metadata map[string]string, metrics map[string]float64) []string {
+	var problems []string
+	if summary.PluginName != name {
+		problems = append(problems, fmt.Sprintf("plugin name %q, want %q", summary.PluginName, name))
+	}
+	if summary.StartTime != start.UnixNano() || summary.EndTime != end.UnixNano() {
+		problems = append(problems, "start or end time not kept")
+	}
+	if want := float64(end.Sub(start)) / float64(time.Millisecond); math.Abs(summary.Duration-want) > 1 {
+		problems = append(problems, fmt.Sprintf("duration %.1fms, want %.1fms", summary.Duration, want))
+	}
+	if summary.Success != success {
+		problems = append(problems, fmt.Sprintf("success %v, want %v", summary.Success, success))
+	}
+	gotErr := ""
+	if summary.Error != nil {
+		gotErr = summary.Error.Error()
+	}
+	if execErr == nil && gotErr != "" {
+		problems = append(problems, fmt.Sprintf("error %q although the execution succeeded", gotErr))
+	}
+	if execErr != nil && gotErr != execErr.Error() {
+		problems = append(problems, fmt.Sprintf("error %q, want %q", gotErr, execErr))
+	}
+	for key, value := range metadata {
+		if summary.Metadata[key] != value {
+			problems = append(problems, fmt.Sprintf("metadata %s not kept", key))
+		}
+	}
+	for key, value := range metrics {
+		if got, ok := summary.Metrics[key]; !ok || got != value {
+			problems = append(problems, fmt.Sprintf("metric %s not kept", key))
+		}
+	}
+	return problems
+}
+
+// conformanceOutcome passes a check without problems and fails it otherwise
+func conformanceOutcome(check string, problems []string) ConformanceResult {
+	if len(problems) == 0 {
+		return ConformanceResult{Check: check, Status: ConformancePass}
+	}
+	return ConformanceResult{Check: check, Status: ConformanceFail, Detail: strings.Join(problems, "; ")}
+}
+
+// sortedSpecNames returns the parameter names of a schema in order
+func sortedSpecNames(schema map[string]ParameterSpec) []string {
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// containsString reports whether values holds value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// conformanceHandler records what an execution streams. started, if set, is closed on the
+// first output or progress.
+type conformanceHandler struct {
+	mu           sync.Mutex
+	started      chan struct{}
+	once         sync.Once
+	progress     []Progress
+	result       map[string]interface{}
+	errorCode    string
+	errorMessage string
+}
+
+func (h *conformanceHandler) markStarted() {
+	if h.started != nil {
+		h.once.Do(func() { close(h.started) })
+	}
+}
+
+func (h *conformanceHandler) OnOutput(line string) error {
+	h.markStarted()
+	return nil
+}
+
+func (h *conformanceHandler) OnProgress(p Progress) error {
+	h.mu.Lock()
+	h.progress = append(h.progress, p)
+	h.mu.Unlock()
+	h.markStarted()
+	return nil
+}
+
+func (h *conformanceHandler) OnResult(result map[string]interface{}) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.result = result
+	return nil
+}
+
+func (h *conformanceHandler) OnError(code, message, details string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.errorCode, h.errorMessage = code, message
+	return nil
+}
+
+// rejection describes how the execution failed, from the error the plugin reported or the one it
+// ended with, "" if it succeeded
+func (h *conformanceHandler) rejection(err error) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.errorCode != "" {
+		return h.errorCode + ": " + h.errorMessage
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
@@ -0,0 +1,232 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// conformancePlugin is an in-process plugin whose deviations from the contract can be switched on
+type conformancePlugin struct {
+	info             *PluginInfo
+	acceptAnything   bool // Skips parameter validation
+	progress         []float32
+	ignoreCancel     bool
+	summaryName      string
+	summaryDropError bool
+}
+
+func newConformancePlugin() *conformancePlugin {
+	return &conformancePlugin{
+		info: &PluginInfo{
+			Name:        "greeter",
+			Version:     "1.0.0",
+			Description: "Greets",
+			ParameterSchema: map[string]ParameterSpec{
+				"message":  {Description: "Who to greet", Required: true},
+				"language": {Description: "Language", AllowedValues: []string{"en", "fr"}, DefaultValue: "en"},
+			},
+			ResultSchema:   map[string]ResultFieldSpec{"greeting": {Type: "string", Required: true}},
+			SideEffectFree: true,
+		},
+		progress: []float32{0, 50, 100},
+	}
+}
+
+func (p *conformancePlugin) GetInfo(ctx context.Context) (*PluginInfo, error) {
+	return p.info, nil
+}
+
+func (p *conformancePlugin) ValidateParameters(params map[string]string) error {
+	if p.acceptAnything {
+		return nil
+	}
+	if _, ok := params["message"]; !ok {
+		return errors.New("missing required parameter: message")
+	}
+	if lang := params["language"]; lang != "en" && lang != "fr" {
+		return fmt.Errorf("invalid value for language: %s", lang)
+	}
+	return nil
+}
+
+func (p *conformancePlugin) Execute(ctx context.Context, params map[string]string, output OutputHandler) error {
+	if err := p.ValidateParameters(params); err != nil {
+		return output.OnError("INVALID_PARAMETERS", err.Error(), "")
+	}
+	for _, percent := range p.progress {
+		output.OnProgress(Progress{PercentComplete: percent})
+		wait := time.After(20 * time.Millisecond)
+		if p.ignoreCancel {
+			<-wait
+			continue
+		}
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return output.OnResult(map[string]interface{}{"greeting": "hello " + params["message"]})
+}
+
+func (p *conformancePlugin) ReportExecutionSummary(startTime, endTime int64, success bool, err error, metadata map[string]string, metrics map[string]float64) (*ExecutionSummary, error) {
+	name := p.info.Name
+	if p.summaryName != "" {
+		name = p.summaryName
+	}
+	if p.summaryDropError {
+		err = nil
+	}
+	return &ExecutionSummary{
+		PluginName: name,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		Duration:   float64(endTime-startTime) / float64(time.Millisecond),
+		Success:    success,
+		Error:      err,
+		Metadata:   metadata,
+		Metrics:    metrics,
+	}, nil
+}
+
+func (p *conformancePlugin) Close() error {
+	return nil
+}
+
+// conformanceStatuses maps each check to its status and detail
+func conformanceStatuses(results []ConformanceResult) map[string]ConformanceResult {
+	byCheck := make(map[string]ConformanceResult, len(results))
+	for _, result := range results {
+		byCheck[result.Check] = result
+	}
+	return byCheck
+}
+
+func TestCheckConformance(t *testing.T) {
+	opts := ConformanceOptions{Params: map[string]string{"message": "World"}, CancelAfter: 10 * time.Millisecond, CancelGrace: 500 * time.Millisecond}
+	checks := []string{"info", "required-params", "allowed-values", "execution", "progress", "cancellation", "summary"}
+
+	tests := []struct {
+		name       string
+		change     func(p *conformancePlugin)
+		opts       ConformanceOptions
+		wantFailed map[string]string // Check to part of its detail
+		wantSkip   []string
+	}{
+		{
+			name: "Conforming plugin",
+			opts: opts,
+		},
+		{
+			name:       "Accepts invalid parameters",
+			change:     func(p *conformancePlugin) { p.acceptAnything = true },
+			opts:       opts,
+			wantFailed: map[string]string{"required-params": "without message", "allowed-values": "language=" + conformanceInvalidValue},
+		},
+		{
+			name:       "Progress goes backwards",
+			change:     func(p *conformancePlugin) { p.progress = []float32{10, 60, 40, 120} },
+			opts:       opts,
+			wantFailed: map[string]string{"progress": "40.0% after 60.0%"},
+		},
+		{
+			name:       "Ignores cancellation",
+			change:     func(p *conformancePlugin) { p.ignoreCancel = true; p.progress = make([]float32, 50) },
+			opts:       opts,
+			wantFailed: map[string]string{"cancellation": "still running"},
+		},
+		{
+			name: "Wrong summary",
+			change: func(p *conformancePlugin) {
+				p.summaryName = "other"
+				p.summaryDropError = true
+			},
+			opts:       opts,
+			wantFailed: map[string]string{"summary": `plugin name "other"`},
+		},
+		{
+			name: "Incomplete info",
+			change: func(p *conformancePlugin) {
+				p.info.Version = ""
+				p.info.ParameterSchema["language"] = ParameterSpec{AllowedValues: []string{"en", "fr"}, DefaultValue: "de"}
+			},
+			opts:       ConformanceOptions{Params: map[string]string{"message": "World", "language": "en"}, CancelAfter: 10 * time.Millisecond},
+			wantFailed: map[string]string{"info": "no version; parameter language has no description; default of parameter language"},
+		},
+		{
+			name:     "Required parameter without value",
+			opts:     ConformanceOptions{CancelAfter: 10 * time.Millisecond},
+			wantSkip: []string{"required-params", "allowed-values", "execution", "progress", "cancellation"},
+		},
+		{
+			name:     "No progress",
+			change:   func(p *conformancePlugin) { p.progress = nil },
+			opts:     opts,
+			wantSkip: []string{"progress", "cancellation"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newConformancePlugin()
+			if tt.change != nil {
+				tt.change(plugin)
+			}
+			results := CheckConformance(context.Background(), plugin, tt.opts)
+			byCheck := conformanceStatuses(results)
+			if len(results) != len(checks) {
+				t.Fatalf("CheckConformance() = %+v, want %d checks", results, len(checks))
+			}
+
+			skipped := make(map[string]bool)
+			for _, check := range tt.wantSkip {
+				skipped[check] = true
+			}
+			for _, check := range checks {
+				got := byCheck[check]
+				switch {
+				case tt.wantFailed[check] != "":
+					if got.Status != ConformanceFail || !strings.Contains(got.Detail, tt.wantFailed[check]) {
+						t.Errorf("%s = %+v, want a failure mentioning %q", check, got, tt.wantFailed[check])
+					}
+				case skipped[check]:
+					if got.Status != ConformanceSkip {
+						t.Errorf("%s = %+v, want it skipped", check, got)
+					}
+				default:
+					if got.Status != ConformancePass {
+						t.Errorf("%s = %+v, want it passed", check, got)
+					}
+				}
+			}
+			if want := len(tt.wantFailed); ConformanceFailures(results) != want {
+				t.Errorf("ConformanceFailures() = %d, want %d", ConformanceFailures(results), want)
+			}
+		})
+	}
+}
+
+func TestConformanceParams(t *testing.T) {
+	info := &PluginInfo{ParameterSchema: map[string]ParameterSpec{
+		"target":    {Required: true},
+		"mode":      {Required: true, DefaultValue: "fast"},
+		DryRunParam: {Type: "bool"},
+	}}
+
+	params, missing := conformanceParams(info, map[string]string{"mode": "slow"})
+	if params["mode"] != "slow" || params[DryRunParam] != "true" {
+		t.Errorf("conformanceParams() = %v, want mode=slow and a dry run", params)
+	}
+	if len(missing) != 1 || missing[0] != "target" {
+		t.Errorf("conformanceParams() missing = %v, want [target]", missing)
+	}
+
+	info.SideEffectFree = true
+	if params, _ := conformanceParams(info, nil); params[DryRunParam] != "" {
+		t.Errorf("conformanceParams() = %v, want no dry run for a side-effect free plugin", params)
+	}
+}
@@ -0,0 +1,176 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultConnectTimeout bounds how long the host waits for a plugin to become reachable
+	DefaultConnectTimeout = 10 * time.Second
+
+	// DefaultReadyTimeout bounds how long a started plugin process has to report ready
+	DefaultReadyTimeout = 10 * time.Second
+
+	connectRetryDelay = 200 * time.Millisecond
+	readyRetryDelay   = 20 * time.Millisecond
+)
+
+// localConnectParams retry connections to a local plugin quickly, so a process that is still
+// binding its port is picked up within milliseconds instead of after gRPC's default 1s backoff
+var localConnectParams = grpc.ConnectParams{
+	Backoff: backoff.Config{
+		BaseDelay:  10 * time.Millisecond,
+		Multiplier: 1.6,
+		Jitter:     0.2,
+		MaxDelay:   time.Second,
+	},
+	MinConnectTimeout: time.Second,
+}
+
+// waitStarted waits for a plugin process the host just started to report SERVING. Health checks
+// wait for the connection instead of failing fast, so it returns as soon as the plugin is up.
+func (c *GRPCClient) waitStarted(ctx context.Context, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultReadyTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	healthClient := healthpb.NewHealthClient(c.conn)
+	var lastErr error
+	for {
+		resp, err := healthClient.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+		switch {
+		case err == nil && resp.Status == healthpb.HealthCheckResponse_SERVING:
+			return nil
+		case err == nil:
+			lastErr = fmt.Errorf("plugin reports status %s", resp.Status)
+		case status.Code(err) == codes.Unimplemented:
+			// Plugins without a health service are ready once they answer GetInfo
+			return c.checkPluginService(ctx)
+		case isPermanentConnectError(err):
+			return diagnoseConnectError(c.address, err)
+		case ctx.Err() == nil:
+			lastErr = err
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr == nil {
+				lastErr = fmt.Errorf("nothing is accepting connections on %s", c.address)
+			}
+			return fmt.Errorf("not ready after %s: %v", timeout, lastErr)
+		case <-time.After(readyRetryDelay):
+		}
+	}
+}
+
+// WaitReady waits until the plugin answers health and info requests, returning an error that
+// explains why it isn't reachable when the timeout elapses first
+func (c *GRPCClient) WaitReady(ctx context.Context, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultConnectTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := checkResolvable(ctx, c.address); err != nil {
+		return err
+	}
+
+	healthClient := healthpb.NewHealthClient(c.conn)
+	var lastErr error
+	for {
+		resp, err := healthClient.Check(ctx, &healthpb.HealthCheckRequest{})
+		switch {
+		case err == nil && resp.Status == healthpb.HealthCheckResponse_SERVING:
+			return c.checkPluginService(ctx)
+		case err == nil:
+			lastErr = fmt.Errorf("plugin at %s reports status %s", c.address, resp.Status)
+		case status.Code(err) == codes.Unimplemented:
+			// Plugins without a health service are still usable if they answer GetInfo
+			return c.checkPluginService(ctx)
+		case isPermanentConnectError(err):
+			return diagnoseConnectError(c.address, err)
+		default:
+			lastErr = err
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr == nil {
+				lastErr = ctx.Err()
+			}
+			return fmt.Errorf("timed out after %s: %v", timeout, diagnoseConnectError(c.address, lastErr))
+		case <-time.After(connectRetryDelay):
+		}
+	}
+}
+
+// checkPluginService verifies the target actually implements the plugin service
+func (c *GRPCClient) checkPluginService(ctx context.Context) error {
+	if _, err := c.GetInfo(ctx); err != nil {
+		return diagnoseConnectError(c.address, err)
+	}
+	return nil
+}
+
+// checkResolvable performs a DNS lookup for host:port and dns:/// targets so name errors are reported as such
+func checkResolvable(ctx context.Context, address string) error {
+	target := strings.TrimPrefix(address, "dns:///")
+	if strings.Contains(target, "://") {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(target)
+	if err != nil || host == "" || net.ParseIP(host) != nil {
+		return nil
+	}
+	if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+		return fmt.Errorf("DNS lookup failed for %s: %v", host, err)
+	}
+	return nil
+}
+
+// isPermanentConnectError reports whether retrying the connection cannot help
+func isPermanentConnectError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unimplemented, codes.Unauthenticated, codes.PermissionDenied:
+		return true
+	}
+	return isTLSError(err)
+}
+
+// isTLSError reports whether the error comes from a failed TLS handshake
+func isTLSError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "handshake") || strings.Contains(msg, "tls:") || strings.Contains(msg, "x509:")
+}
+
+// diagnoseConnectError turns a raw gRPC connection error into a message naming the likely cause
+func diagnoseConnectError(address string, err error) error {
+	msg := err.Error()
+	switch {
+	case status.Code(err) == codes.Unimplemented:
+		return fmt.Errorf("wrong service at %s: it does not implement the plugin protocol (%s)", address, status.Convert(err).Message())
+	case status.Code(err) == codes.Unauthenticated || status.Code(err) == codes.PermissionDenied:
+		return fmt.Errorf("plugin at %s rejected the credentials: %s", address, status.Convert(err).Message())
+	case strings.Contains(msg, "no such host"):
+		return fmt.Errorf("DNS lookup failed for %s: %v", address, err)
+	case strings.Contains(msg, "connection refused"):
+		return fmt.Errorf("connection refused by %s: is the plugin running and listening on that port?", address)
+	case isTLSError(err):
+		return fmt.Errorf("TLS handshake with %s failed: %s", address, status.Convert(err).Message())
+	default:
+		return fmt.Errorf("failed to connect to %s: %v", address, err)
+	}
+}
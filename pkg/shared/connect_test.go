@@ -0,0 +1,231 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestDiagnoseConnectError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		errorMsg string
+	}{
+		{
+			name:     "Connection refused",
+			err:      status.Error(codes.Unavailable, `connection error: desc = "transport: Error while dialing: dial tcp 127.0.0.1:1: connect: connection refused"`),
+			errorMsg: "connection refused",
+		},
+		{
+			name:     "DNS failure",
+			err:      status.Error(codes.Unavailable, "dial tcp: lookup nowhere.invalid: no such host"),
+			errorMsg: "DNS lookup failed",
+		},
+		{
+			name:     "TLS handshake failure",
+			err:      status.Error(codes.Unavailable, "connection error: desc = \"transport: authentication handshake failed: tls: first record does not look like a TLS handshake\""),
+			errorMsg: "TLS handshake",
+		},
+		{
+			name:     "Wrong service",
+			err:      status.Error(codes.Unimplemented, "unknown service plugin.Plugin"),
+			errorMsg: "wrong service",
+		},
+		{
+			name:     "Rejected credentials",
+			err:      status.Error(codes.Unauthenticated, "invalid auth token"),
+			errorMsg: "rejected the credentials",
+		},
+		{
+			name:     "Unclassified error",
+			err:      errors.New("boom"),
+			errorMsg: "failed to connect",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := diagnoseConnectError("example:1", tt.err)
+			if !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("diagnoseConnectError() = %q, want substring %q", err.Error(), tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestGRPCClient_WaitReady(t *testing.T) {
+	// A gRPC server with a health service but no plugin service
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	StartHealthServer(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	// A port with nothing listening on it
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	tests := []struct {
+		name     string
+		address  string
+		errorMsg string
+	}{
+		{
+			name:     "Wrong service",
+			address:  listener.Addr().String(),
+			errorMsg: "wrong service",
+		},
+		{
+			name:     "Nothing listening",
+			address:  closedAddr,
+			errorMsg: "connection refused",
+		},
+		{
+			name:     "Unresolvable host",
+			address:  "plugin.invalid:50051",
+			errorMsg: "DNS lookup failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClientWithAddress(tt.address)
+			if err != nil {
+				t.Fatalf("NewClientWithAddress() error = %v", err)
+			}
+			defer client.Close()
+
+			err = client.(*GRPCClient).WaitReady(context.Background(), time.Second)
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("WaitReady() error = %v, want substring %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestGRPCClient_waitStarted(t *testing.T) {
+	tests := []struct {
+		name     string
+		serve    func(t *testing.T, listener net.Listener) *grpc.Server // nil leaves the port closed
+		timeout  time.Duration
+		within   time.Duration
+		errorMsg string
+	}{
+		{
+			name: "Plugin binds its port late",
+			serve: func(t *testing.T, listener net.Listener) *grpc.Server {
+				server := grpc.NewServer()
+				StartHealthServer(server)
+				addr := listener.Addr().String()
+				listener.Close()
+				go func() {
+					time.Sleep(300 * time.Millisecond)
+					late, err := net.Listen("tcp", addr)
+					if err != nil {
+						t.Errorf("Failed to listen: %v", err)
+						return
+					}
+					server.Serve(late)
+				}()
+				return server
+			},
+			timeout: 5 * time.Second,
+			within:  time.Second,
+		},
+		{
+			name: "Health service reports serving later",
+			serve: func(t *testing.T, listener net.Listener) *grpc.Server {
+				server := grpc.NewServer()
+				healthServer := StartHealthServer(server)
+				healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+				go server.Serve(listener)
+				time.AfterFunc(200*time.Millisecond, func() {
+					healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+				})
+				return server
+			},
+			timeout: 5 * time.Second,
+			within:  time.Second,
+		},
+		{
+			name: "Plugin without health service",
+			serve: func(t *testing.T, listener net.Listener) *grpc.Server {
+				server := grpc.NewServer()
+				proto.RegisterPluginServer(server, &GRPCServer{Impl: stubPlugin{}})
+				go server.Serve(listener)
+				return server
+			},
+			timeout: 5 * time.Second,
+			within:  time.Second,
+		},
+		{
+			name:     "Nothing listening",
+			timeout:  300 * time.Millisecond,
+			errorMsg: "not ready after 300ms",
+		},
+		{
+			name: "Never serving",
+			serve: func(t *testing.T, listener net.Listener) *grpc.Server {
+				server := grpc.NewServer()
+				StartHealthServer(server).SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+				go server.Serve(listener)
+				return server
+			},
+			timeout:  300 * time.Millisecond,
+			errorMsg: "reports status NOT_SERVING",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("Failed to listen: %v", err)
+			}
+			addr := listener.Addr().String()
+			if tt.serve == nil {
+				listener.Close()
+			} else {
+				defer tt.serve(t, listener).Stop()
+			}
+
+			client, err := NewClientWithAddress(addr, grpc.WithConnectParams(localConnectParams))
+			if err != nil {
+				t.Fatalf("NewClientWithAddress() error = %v", err)
+			}
+			defer client.Close()
+
+			start := time.Now()
+			err = client.(*GRPCClient).waitStarted(context.Background(), tt.timeout)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("waitStarted() error = %v, want substring %q", err, tt.errorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("waitStarted() error = %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > tt.within {
+				t.Errorf("waitStarted() took %s, want under %s", elapsed, tt.within)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package shared
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// applyCredential fails if a user is configured because switching users needs Unix credentials
+func applyCredential(process *exec.Cmd, config *PluginConfig) error {
+	if config.User == "" {
+		return nil
+	}
+	return fmt.Errorf("running plugins as another user is not supported on %s", runtime.GOOS)
+}
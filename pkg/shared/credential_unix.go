@@ -0,0 +1,97 @@
+//go:build unix
+
+package shared
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// processCredential resolves the configured user and group to the IDs the plugin runs as
+func (p *PluginConfig) processCredential() (*syscall.Credential, error) {
+	uid, gid, err := resolveUser(p.User)
+	if err != nil {
+		return nil, err
+	}
+	if p.Group != "" {
+		gid, err = resolveGroup(p.Group)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if gid == "" {
+		return nil, fmt.Errorf("user %s has no passwd entry, so group must be set", p.User)
+	}
+
+	uidNum, err := strconv.ParseUint(uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uid for user %s: %s", p.User, uid)
+	}
+	gidNum, err := strconv.ParseUint(gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gid for group %s: %s", p.Group, gid)
+	}
+	return &syscall.Credential{Uid: uint32(uidNum), Gid: uint32(gidNum)}, nil
+}
+
+// resolveUser looks up a user name or numeric ID. Numeric IDs without a passwd entry are
+// accepted as-is and return an empty primary group.
+func resolveUser(name string) (string, string, error) {
+	u, err := user.Lookup(name)
+	if err == nil {
+		return u.Uid, u.Gid, nil
+	}
+	if _, numErr := strconv.ParseUint(name, 10, 32); numErr != nil {
+		return "", "", fmt.Errorf("unknown user %s: %v", name, err)
+	}
+	if u, err := user.LookupId(name); err == nil {
+		return u.Uid, u.Gid, nil
+	}
+	return name, "", nil
+}
+
+// resolveGroup looks up a group name or numeric ID
+func resolveGroup(name string) (string, error) {
+	g, err := user.LookupGroup(name)
+	if err == nil {
+		return g.Gid, nil
+	}
+	if _, numErr := strconv.ParseUint(name, 10, 32); numErr != nil {
+		return "", fmt.Errorf("unknown group %s: %v", name, err)
+	}
+	return name, nil
+}
+
+// applyCredential makes the process run as the configured user, dropping the host's root privileges
+func applyCredential(process *exec.Cmd, config *PluginConfig) error {
+	if config.User == "" {
+		return nil
+	}
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("running plugins as user %s requires the host to run as root", config.User)
+	}
+
+	cred, err := config.processCredential()
+	if err != nil {
+		return err
+	}
+
+	if process.SysProcAttr == nil {
+		process.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	if len(process.SysProcAttr.UidMappings) > 0 {
+		// In a sandbox, root of the plugin's user namespace is mapped onto the plugin user and
+		// the sandbox init step switches to it, clearing the host's supplementary groups
+		process.SysProcAttr.UidMappings[0].HostID = int(cred.Uid)
+		process.SysProcAttr.GidMappings[0].HostID = int(cred.Gid)
+		process.SysProcAttr.GidMappingsEnableSetgroups = true
+		process.SysProcAttr.Credential = &syscall.Credential{Uid: 0, Gid: 0}
+		return nil
+	}
+	process.SysProcAttr.Credential = cred
+	return nil
+}
@@ -0,0 +1,89 @@
+//go:build unix
+
+package shared
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestPluginConfig_ProcessCredential(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		group    string
+		wantUID  uint32
+		wantGID  uint32
+		wantErr  bool
+		errorMsg string
+	}{
+		{
+			name:    "User name with primary group",
+			user:    "root",
+			wantUID: 0,
+			wantGID: 0,
+		},
+		{
+			name:    "Numeric IDs without passwd entries",
+			user:    "4242",
+			group:   "4343",
+			wantUID: 4242,
+			wantGID: 4343,
+		},
+		{
+			name:     "Numeric user without group",
+			user:     "4242",
+			wantErr:  true,
+			errorMsg: "group must be set",
+		},
+		{
+			name:     "Unknown user name",
+			user:     "no-such-plugin-user",
+			wantErr:  true,
+			errorMsg: "unknown user",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := PluginConfig{User: tt.user, Group: tt.group}
+			cred, err := config.processCredential()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("processCredential() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("processCredential() error = %q, want substring %q", err.Error(), tt.errorMsg)
+				}
+				return
+			}
+			if cred.Uid != tt.wantUID || cred.Gid != tt.wantGID {
+				t.Errorf("processCredential() = %d:%d, want %d:%d", cred.Uid, cred.Gid, tt.wantUID, tt.wantGID)
+			}
+		})
+	}
+}
+
+func TestApplyCredential(t *testing.T) {
+	process := exec.Command("id", "-u")
+	err := applyCredential(process, &PluginConfig{User: "65534", Group: "65534"})
+
+	if os.Geteuid() != 0 {
+		if err == nil || !strings.Contains(err.Error(), "requires the host to run as root") {
+			t.Errorf("applyCredential() on a non-root host error = %v, want a root requirement", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("applyCredential() error = %v", err)
+	}
+	output, err := process.Output()
+	if err != nil {
+		t.Fatalf("Failed to run process: %v", err)
+	}
+	if got := strings.TrimSpace(string(output)); got != "65534" {
+		t.Errorf("process ran as uid %s, want 65534", got)
+	}
+}
@@ -0,0 +1,166 @@
+package shared
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// CredentialEnvVar supplies a per-request bearer token for remote plugins when -token isn't given
+const CredentialEnvVar = "PLUGIN_APP_TOKEN"
+
+// credentialKey is the context key of the per-request credential
+type credentialKey struct{}
+
+// WithCredential returns a context whose calls to remote plugins authenticate with token
+// instead of the plugin's configured auth_token
+func WithCredential(ctx context.Context, token string) context.Context {
+	if token == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, credentialKey{}, token)
+}
+
+// CredentialFromContext returns the per-request credential carried by ctx, if any
+func CredentialFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(credentialKey{}).(string)
+	return token
+}
+
+// AuthHeaders is static auth metadata sent with every call to a remote plugin, e.g. for an
+// authenticating proxy in front of it
+type AuthHeaders struct {
+	Bearer  string            `json:"bearer,omitempty"`  // Token sent as "authorization: Bearer <token>"
+	Basic   *BasicAuth        `json:"basic,omitempty"`   // Credentials sent as "authorization: Basic ..."
+	Headers map[string]string `json:"headers,omitempty"` // Additional metadata, e.g. {"x-api-key": "..."}
+}
+
+// BasicAuth is a user name and password for HTTP basic auth
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// validate checks the auth headers of a plugin
+func (a *AuthHeaders) validate(p *PluginConfig) error {
+	if a.Bearer != "" && a.Basic != nil {
+		return fmt.Errorf("auth_headers bearer and basic are mutually exclusive")
+	}
+	if (a.Bearer != "" || a.Basic != nil) && p.AuthToken != "" {
+		return fmt.Errorf("auth_headers bearer and basic can't be combined with auth_token")
+	}
+	if a.Basic != nil && a.Basic.Username == "" {
+		return fmt.Errorf("auth_headers basic requires a username")
+	}
+	for name := range a.Headers {
+		key := strings.ToLower(name)
+		switch {
+		case key == "" || strings.HasPrefix(key, ":") || strings.HasPrefix(key, "grpc-"):
+			return fmt.Errorf("invalid auth_headers header: %q", name)
+		case key == authMetadataKey:
+			return fmt.Errorf("set the authorization header with auth_headers bearer or basic")
+		}
+	}
+	return nil
+}
+
+// metadata returns the headers to send, keyed by lowercase name
+func (a *AuthHeaders) metadata() map[string]string {
+	if a == nil {
+		return nil
+	}
+	md := make(map[string]string, len(a.Headers)+1)
+	for name, value := range a.Headers {
+		md[strings.ToLower(name)] = value
+	}
+	switch {
+	case a.Bearer != "":
+		md[authMetadataKey] = authScheme + a.Bearer
+	case a.Basic != nil:
+		md[authMetadataKey] = "Basic " + base64.StdEncoding.EncodeToString([]byte(a.Basic.Username+":"+a.Basic.Password))
+	}
+	return md
+}
+
+// Secrets returns the values to mask in output and errors
+func (a *AuthHeaders) Secrets() []string {
+	if a == nil {
+		return nil
+	}
+	var secrets []string
+	for _, value := range a.Headers {
+		secrets = append(secrets, value)
+	}
+	if a.Bearer != "" {
+		secrets = append(secrets, a.Bearer)
+	}
+	if a.Basic != nil && a.Basic.Password != "" {
+		secrets = append(secrets, a.Basic.Password)
+	}
+	return secrets
+}
+
+// requestCredentials attaches the per-request credential of each call, falling back to a static
+// token, along with the plugin's static auth headers
+type requestCredentials struct {
+	fallback string
+	headers  map[string]string
+}
+
+func (r requestCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	md := make(map[string]string, len(r.headers)+1)
+	for name, value := range r.headers {
+		md[name] = value
+	}
+	token := CredentialFromContext(ctx)
+	if token == "" {
+		token = r.fallback
+	}
+	if token != "" {
+		md[authMetadataKey] = authScheme + token
+	}
+	return md, nil
+}
+
+// RequireTransportSecurity matches tokenCredentials so remote plugins without TLS keep working
+func (r requestCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// WithRequestCredentials returns a dial option that authenticates each call with the credential
+// in its context, or with token when the context carries none, and sends the static headers
+func WithRequestCredentials(token string, headers *AuthHeaders) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(requestCredentials{fallback: token, headers: headers.metadata()})
+}
+
+// FetchCredential runs the plugin's credential helper and returns the token it prints on stdout
+func (p *PluginConfig) FetchCredential(ctx context.Context, name string) (string, error) {
+	parts := strings.Fields(p.CredentialHelper)
+	if len(parts) == 0 {
+		return "", nil
+	}
+
+	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+	cmd.Env = append(os.Environ(), "PLUGIN_NAME="+name, "PLUGIN_ADDRESS="+p.Address)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("credential helper for %s failed: %v: %s", name, err, msg)
+		}
+		return "", fmt.Errorf("credential helper for %s failed: %v", name, err)
+	}
+
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", fmt.Errorf("credential helper for %s printed no token", name)
+	}
+	return token, nil
+}
@@ -0,0 +1,202 @@
+package shared
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRequestCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback string
+		token    string
+		want     string
+	}{
+		{name: "Per-request token", fallback: "static", token: "user-token", want: "Bearer user-token"},
+		{name: "Falls back to static token", fallback: "static", want: "Bearer static"},
+		{name: "No credentials", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithCredential(context.Background(), tt.token)
+			md, err := requestCredentials{fallback: tt.fallback}.GetRequestMetadata(ctx)
+			if err != nil {
+				t.Fatalf("GetRequestMetadata() error = %v", err)
+			}
+			if got := md[authMetadataKey]; got != tt.want {
+				t.Errorf("authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPluginConfig_FetchCredential(t *testing.T) {
+	tests := []struct {
+		name     string
+		helper   string
+		want     string
+		wantErr  bool
+		errorMsg string
+	}{
+		{name: "No helper", helper: ""},
+		{name: "Token on stdout", helper: "echo short-lived-token", want: "short-lived-token"},
+		{name: "Helper fails", helper: "false", wantErr: true, errorMsg: "credential helper for api failed"},
+		{name: "Empty output", helper: "true", wantErr: true, errorMsg: "printed no token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := PluginConfig{Type: PluginTypeRemote, Address: "localhost:50051", CredentialHelper: tt.helper}
+			got, err := p.FetchCredential(context.Background(), "api")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchCredential() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("FetchCredential() error = %v, want substring %q", err, tt.errorMsg)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("FetchCredential() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPluginManager_RemoteExecuteWithCredential(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	// Record the credential each Execute call arrives with
+	seen := make(chan string, 1)
+	server := grpc.NewServer(grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, _ := metadata.FromIncomingContext(ss.Context())
+		seen <- strings.Join(md.Get(authMetadataKey), ",")
+		return handler(srv, ss)
+	}))
+	proto.RegisterPluginServer(server, &GRPCServer{Impl: stubPlugin{}})
+	StartHealthServer(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	config := PluginConfig{Type: PluginTypeRemote, Address: listener.Addr().String(), AuthToken: "static"}
+	pm := NewPluginManager(&AppConfig{Plugins: map[string]PluginConfig{"stub": config}})
+	defer pm.StopAll()
+	if err := pm.StartPlugin("stub", config); err != nil {
+		t.Fatalf("StartPlugin() error = %v", err)
+	}
+	plugin, err := pm.GetPlugin("stub")
+	if err != nil {
+		t.Fatalf("GetPlugin() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{name: "Per-request token", token: "user-token", want: "Bearer user-token"},
+		{name: "Static token", want: "Bearer static"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithCredential(context.Background(), tt.token)
+			if err := plugin.Execute(ctx, nil, nil); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if got := <-seen; got != tt.want {
+				t.Errorf("authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthHeaders(t *testing.T) {
+	tests := []struct {
+		name      string
+		auth      AuthHeaders
+		authToken string
+		token     string
+		want      map[string]string
+		wantErr   bool
+	}{
+		{
+			name: "API key header",
+			auth: AuthHeaders{Headers: map[string]string{"X-API-Key": "k1"}},
+			want: map[string]string{"x-api-key": "k1"},
+		},
+		{
+			name: "Bearer",
+			auth: AuthHeaders{Bearer: "proxy-token"},
+			want: map[string]string{"authorization": "Bearer proxy-token"},
+		},
+		{
+			name: "Basic",
+			auth: AuthHeaders{Basic: &BasicAuth{Username: "alice", Password: "secret"}},
+			want: map[string]string{"authorization": "Basic YWxpY2U6c2VjcmV0"},
+		},
+		{
+			name:  "Per-request credential replaces static authorization",
+			auth:  AuthHeaders{Bearer: "proxy-token", Headers: map[string]string{"x-api-key": "k1"}},
+			token: "user-token",
+			want:  map[string]string{"authorization": "Bearer user-token", "x-api-key": "k1"},
+		},
+		{
+			name:    "Bearer and basic",
+			auth:    AuthHeaders{Bearer: "t", Basic: &BasicAuth{Username: "alice"}},
+			wantErr: true,
+		},
+		{
+			name:      "Bearer with auth_token",
+			auth:      AuthHeaders{Bearer: "t"},
+			authToken: "static",
+			wantErr:   true,
+		},
+		{
+			name:    "Authorization as a plain header",
+			auth:    AuthHeaders{Headers: map[string]string{"Authorization": "Bearer t"}},
+			wantErr: true,
+		},
+		{
+			name:    "Reserved header",
+			auth:    AuthHeaders{Headers: map[string]string{"grpc-timeout": "1S"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := PluginConfig{Type: PluginTypeRemote, Address: "localhost:50051", AuthToken: tt.authToken, AuthHeaders: &tt.auth}
+			err := p.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			creds := requestCredentials{fallback: tt.authToken, headers: tt.auth.metadata()}
+			got, err := creds.GetRequestMetadata(WithCredential(context.Background(), tt.token))
+			if err != nil {
+				t.Fatalf("GetRequestMetadata() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("GetRequestMetadata() = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
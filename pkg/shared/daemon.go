@@ -0,0 +1,519 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata the daemon routes calls by
+const (
+	daemonPluginKey     = "x-plugin-app-plugin"
+	daemonCredentialKey = "x-plugin-app-credential"
+	daemonPriorityKey   = "x-plugin-app-priority"
+	daemonDetachableKey = "x-plugin-app-detachable"
+	daemonAttachKey     = "x-plugin-app-attach"
+	daemonExecutionKey  = "x-plugin-app-execution" // Header naming the id a journaled execution goes by
+)
+
+// daemonProbeTimeout bounds the check whether a daemon is listening
+const daemonProbeTimeout = 500 * time.Millisecond
+
+// ErrNoDaemon is returned when no daemon is listening on the socket
+var ErrNoDaemon = errors.New("no daemon running")
+
+// DaemonSocketPath returns the socket the daemon listens on
+func (c *AppConfig) DaemonSocketPath() string {
+	if c.DaemonSocket != "" {
+		return c.DaemonSocket
+	}
+	return filepath.Join(c.StateDir, "daemon.sock")
+}
+
+// Daemon keeps plugins of a PluginManager warm and serves them to other host processes over a
+// Unix socket. Calls name their plugin in metadata and reach it through the Plugin service.
+type Daemon struct {
+	manager  *PluginManager
+	server   *grpc.Server
+	listener net.Listener
+	path     string
+	pidfile  string
+
+	admin     *http.Server // Of the admin port, nil without one
+	adminAddr string
+	started   atomic.Bool // The plugins that aren't lazy were started
+	stopping  atomic.Bool
+}
+
+// NewDaemon returns a daemon serving the plugins of manager
+func NewDaemon(manager *PluginManager) *Daemon {
+	server := grpc.NewServer()
+	journals := newExecutionJournals()
+	proto.RegisterPluginServer(server, &daemonServer{manager: manager, journals: journals})
+	proto.RegisterDaemonServer(server, &daemonControl{manager: manager, journals: journals})
+	healthpb.RegisterHealthServer(server, &daemonHealth{Server: health.NewServer(), manager: manager})
+	return &Daemon{manager: manager, server: server}
+}
+
+// StartPlugins starts the plugins that aren't lazy and returns the errors of those that failed,
+// by name; see PluginManager.StartEager. Failed and lazy plugins are started when first used.
+func (d *Daemon) StartPlugins() map[string]error {
+	defer d.started.Store(true)
+	return d.manager.StartEager()
+}
+
+// Listen creates the socket, replacing a stale one left behind by a daemon that didn't shut down
+func (d *Daemon) Listen(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create socket directory: %v", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.DialTimeout("unix", path, daemonProbeTimeout); err == nil {
+			conn.Close()
+			return fmt.Errorf("a daemon is already listening on %s", path)
+		}
+		os.Remove(path)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", path, err)
+	}
+	// Only the user running the daemon may drive its plugins
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict socket: %v", err)
+	}
+	d.listener = listener
+	d.path = path
+	return nil
+}
+
+// Serve answers calls until Stop is called
+func (d *Daemon) Serve() error {
+	if d.listener == nil {
+		return fmt.Errorf("daemon is not listening")
+	}
+	return d.server.Serve(d.listener)
+}
+
+// Stop stops the plugins, which cancels executions in flight, then the server and the admin
+// port, and removes the socket and pidfile
+func (d *Daemon) Stop() {
+	d.stopping.Store(true)
+	d.manager.StopAll()
+	d.server.GracefulStop()
+	if d.listener != nil {
+		d.listener.Close() // Already closed if it was served
+	}
+	if d.path != "" {
+		os.Remove(d.path)
+	}
+	if d.pidfile != "" {
+		os.Remove(d.pidfile)
+	}
+	d.stopAdmin()
+}
+
+// daemonServer resolves the plugin of each call and hands the call to a GRPCServer around it
+type daemonServer struct {
+	proto.UnimplementedPluginServer
+	manager  *PluginManager
+	journals *executionJournals
+}
+
+// plugin returns the server for the plugin named in the call, starting the plugin if it isn't running
+func (s *daemonServer) plugin(ctx context.Context) (*GRPCServer, context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	names := md.Get(daemonPluginKey)
+	if len(names) != 1 || names[0] == "" {
+		return nil, nil, status.Error(codes.InvalidArgument, "no plugin named in the call")
+	}
+	name := names[0]
+
+	if _, ok := s.manager.Config().Plugins[name]; !ok {
+		return nil, nil, status.Errorf(codes.NotFound, "plugin %q not found in configuration", name)
+	}
+	plugin, err := s.manager.runningPlugin(name)
+	if err != nil {
+		return nil, nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	// Pass on the caller's own credential to remote plugins
+	if credentials := md.Get(daemonCredentialKey); len(credentials) == 1 {
+		ctx = WithCredential(ctx, credentials[0])
+	}
+	// Callers waiting for a busy plugin are served by priority
+	if priorities := md.Get(daemonPriorityKey); len(priorities) == 1 {
+		if priority, err := strconv.Atoi(priorities[0]); err == nil {
+			ctx = WithPriority(ctx, priority)
+		}
+	}
+	recorded := historyPlugin{PluginInterface: plugin, name: name, config: s.manager.Config()}
+	return &GRPCServer{Impl: recorded, name: name}, ctx, nil
+}
+
+func (s *daemonServer) GetInfo(ctx context.Context, req *proto.InfoRequest) (*proto.PluginInfo, error) {
+	server, ctx, err := s.plugin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return server.GetInfo(ctx, req)
+}
+
+func (s *daemonServer) Execute(req *proto.ExecuteRequest, stream proto.Plugin_ExecuteServer) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	if ids := md.Get(daemonAttachKey); len(ids) == 1 {
+		journal := s.journals.get(ids[0])
+		if journal == nil {
+			return status.Errorf(codes.NotFound, "execution %q not found", ids[0])
+		}
+		return journal.follow(stream.Context(), stream.Send)
+	}
+
+	server, ctx, err := s.plugin(stream.Context())
+	if err != nil {
+		return err
+	}
+	if len(md.Get(daemonDetachableKey)) == 0 {
+		return server.Execute(req, contextStream{stream, ctx})
+	}
+
+	// A detachable execution runs on its own and the caller follows its journal, which outlives the call
+	execCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	journal, err := s.journals.start(ExecutionIDFromContext(incomingExecutionID(ctx)), server.name, cancel)
+	if err != nil {
+		cancel()
+		return status.Error(codes.Internal, err.Error())
+	}
+	if err := stream.SendHeader(metadata.Pairs(daemonExecutionKey, journal.id)); err != nil {
+		cancel()
+		return err
+	}
+	go func() {
+		defer cancel()
+		execCtx := WithExecutionID(execCtx, journal.id)
+		journal.finish(server.Execute(req, journalStream{ctx: execCtx, journal: journal}))
+	}()
+	return journal.follow(stream.Context(), stream.Send)
+}
+
+func (s *daemonServer) ReportExecutionSummary(ctx context.Context, req *proto.SummaryRequest) (*proto.SummaryResponse, error) {
+	server, ctx, err := s.plugin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return server.ReportExecutionSummary(ctx, req)
+}
+
+func (s *daemonServer) Diagnostics(ctx context.Context, req *proto.DiagnosticsRequest) (*proto.DiagnosticsResponse, error) {
+	server, ctx, err := s.plugin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return server.Diagnostics(ctx, req)
+}
+
+// daemonHealth answers health checks of the daemon itself, and of the plugin named as the service
+// by probing the plugin the daemon keeps warm
+type daemonHealth struct {
+	*health.Server
+	manager *PluginManager
+}
+
+func (h *daemonHealth) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if req.Service == "" {
+		return h.Server.Check(ctx, req)
+	}
+	if _, ok := h.manager.Config().Plugins[req.Service]; !ok {
+		return nil, status.Errorf(codes.NotFound, "plugin %q not found in configuration", req.Service)
+	}
+	if _, err := h.manager.runningPlugin(req.Service); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	result, err := h.manager.ProbePlugin(ctx, req.Service)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	// Passed on as is, so that the caller sees the plugin has no health service
+	if result.Status == "UNIMPLEMENTED" {
+		return nil, status.Error(codes.Unimplemented, "plugin has no health service")
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_ServingStatus(healthpb.HealthCheckResponse_ServingStatus_value[result.Status])}, nil
+}
+
+// historyPlugin records the executions the daemon serves, so that the history covers every
+// client and not just the CLI
+type historyPlugin struct {
+	PluginInterface
+	name   string
+	config *AppConfig
+}
+
+func (p historyPlugin) Execute(ctx context.Context, params map[string]string, output OutputHandler) error {
+	started := time.Now()
+	recorded := &recordedHandler{OutputHandler: output}
+	err := p.PluginInterface.Execute(ctx, params, recorded)
+	// Canceled executions are incomplete and not worth replaying
+	if ctx.Err() == nil {
+		if recordErr := p.record(ctx, started, params, recorded, err); recordErr != nil {
+			Logger(LogDaemon).Warn("failed to record execution", "plugin", p.name, "error", recordErr)
+		}
+	}
+	return err
+}
+
+func (p historyPlugin) record(ctx context.Context, started time.Time, params map[string]string, recorded *recordedHandler, execErr error) error {
+	info, err := p.GetInfo(ctx)
+	if err != nil {
+		return err
+	}
+	redactor, err := NewRedactor(p.config.Redaction, info.ParameterSchema, params)
+	if err != nil {
+		return err
+	}
+	record := HistoryRecord{
+		Time:     started,
+		Plugin:   p.name,
+		Version:  info.Version,
+		Params:   redactor.Params(params),
+		Success:  execErr == nil,
+		Duration: Duration(time.Since(started)),
+		Metrics:  map[string]float64{"execution_time_ms": float64(time.Since(started)) / float64(time.Millisecond)},
+	}
+	record.ID = ExecutionIDFromContext(ctx)
+	if execErr != nil {
+		record.Error = redactor.String(execErr.Error())
+	}
+	recorded.mu.Lock()
+	for name, value := range recorded.metrics {
+		record.Metrics["plugin_"+name] = value
+	}
+	record.Artifacts = recorded.artifacts
+	recorded.mu.Unlock()
+	return p.config.HistoryStore().Append(record)
+}
+
+// recordedHandler notes the metrics and artifacts of an execution on their way to the caller
+type recordedHandler struct {
+	OutputHandler
+	mu        sync.Mutex
+	metrics   map[string]float64 // Latest value of each
+	artifacts []HistoryArtifact
+}
+
+func (h *recordedHandler) OnLog(level, message string) error {
+	return asChannelHandler(h.OutputHandler).OnLog(level, message)
+}
+
+func (h *recordedHandler) OnArtifact(name string, data []byte, last bool) error {
+	h.mu.Lock()
+	i := 0
+	for i < len(h.artifacts) && h.artifacts[i].Name != name {
+		i++
+	}
+	if i == len(h.artifacts) {
+		h.artifacts = append(h.artifacts, HistoryArtifact{Name: name})
+	}
+	h.artifacts[i].Size += len(data)
+	h.mu.Unlock()
+	return asChannelHandler(h.OutputHandler).OnArtifact(name, data, last)
+}
+
+func (h *recordedHandler) OnPrompt(id, message string) error {
+	return asChannelHandler(h.OutputHandler).OnPrompt(id, message)
+}
+
+func (h *recordedHandler) OnMetric(name string, value float64, labels map[string]string) error {
+	h.mu.Lock()
+	if h.metrics == nil {
+		h.metrics = make(map[string]float64)
+	}
+	h.metrics[name] = value
+	h.mu.Unlock()
+	return asChannelHandler(h.OutputHandler).OnMetric(name, value, labels)
+}
+
+// daemonControl serves the daemon's control API
+type daemonControl struct {
+	proto.UnimplementedDaemonServer
+	manager  *PluginManager
+	journals *executionJournals
+}
+
+func (c *daemonControl) ListPlugins(ctx context.Context, req *proto.ListPluginsRequest) (*proto.ListPluginsResponse, error) {
+	resp := &proto.ListPluginsResponse{}
+	for _, state := range c.manager.Statuses() {
+		resp.Plugins = append(resp.Plugins, statusToProto(state))
+	}
+	return resp, nil
+}
+
+func (c *daemonControl) PluginStatus(ctx context.Context, req *proto.PluginStatusRequest) (*proto.DaemonPluginStatus, error) {
+	state, err := c.manager.Status(req.Name)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return statusToProto(state), nil
+}
+
+func (c *daemonControl) History(ctx context.Context, req *proto.HistoryRequest) (*proto.HistoryResponse, error) {
+	config := c.manager.Config()
+	// The name becomes part of a path, so only configured plugins are looked up
+	if _, ok := config.Plugins[req.Plugin]; !ok {
+		return nil, status.Errorf(codes.NotFound, "plugin %q not found in configuration", req.Plugin)
+	}
+	if req.Limit < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid limit: %d", req.Limit)
+	}
+	records, err := config.HistoryStore().Recent(req.Plugin, int(req.Limit))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &proto.HistoryResponse{}
+	for _, record := range records {
+		resp.Entries = append(resp.Entries, &proto.HistoryEntry{
+			Time:     record.Time.UnixNano(),
+			Plugin:   record.Plugin,
+			Version:  record.Version,
+			Params:   record.Params,
+			Success:  record.Success,
+			Error:    record.Error,
+			Duration: int64(record.Duration),
+		})
+	}
+	return resp, nil
+}
+
+func (c *daemonControl) Executions(ctx context.Context, req *proto.ExecutionsRequest) (*proto.ExecutionsResponse, error) {
+	return &proto.ExecutionsResponse{Executions: c.journals.list()}, nil
+}
+
+func (c *daemonControl) Detach(ctx context.Context, req *proto.DetachRequest) (*proto.DetachResponse, error) {
+	journal := c.journals.get(req.Id)
+	if journal == nil {
+		return nil, status.Errorf(codes.NotFound, "execution %q not found", req.Id)
+	}
+	journal.detach()
+	return &proto.DetachResponse{}, nil
+}
+
+// statusToProto converts a plugin's status for the control API
+func statusToProto(state PluginStatus) *proto.DaemonPluginStatus {
+	resp := &proto.DaemonPluginStatus{
+		Name:        state.Name,
+		Type:        string(state.Type),
+		Description: state.Description,
+		Running:     state.Running,
+		Restarts:    int32(state.Restarts),
+		Failovers:   int32(state.Failovers),
+		State:       string(state.State),
+		Queued:      int32(state.Queued),
+		Address:     state.Address,
+		Executions:  int32(state.Executions),
+	}
+	if !state.Started.IsZero() {
+		resp.Started = state.Started.UnixNano()
+	}
+	if state.LastError != nil {
+		resp.LastError = state.LastError.Error()
+	}
+	if !state.Since.IsZero() {
+		resp.Since = state.Since.UnixNano()
+	}
+	if usage := state.Usage; usage != nil {
+		resp.Usage = &proto.ProcessUsage{
+			Pid:        int32(usage.PID),
+			CpuSeconds: usage.CPUSeconds,
+			CpuPercent: usage.CPUPercent,
+			RssBytes:   usage.RSSBytes,
+			OpenFds:    int32(usage.OpenFDs),
+		}
+	}
+	return resp
+}
+
+// contextStream replaces the context of an Execute stream
+type contextStream struct {
+	proto.Plugin_ExecuteServer
+	ctx context.Context
+}
+
+func (s contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// daemonRouting names the plugin and carries the caller's credential and priority on each call
+// to the daemon
+type daemonRouting struct {
+	plugin string
+}
+
+func (r daemonRouting) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	md := map[string]string{daemonPluginKey: r.plugin}
+	if token := CredentialFromContext(ctx); token != "" {
+		md[daemonCredentialKey] = token
+	}
+	if priority := PriorityFromContext(ctx); priority != 0 {
+		md[daemonPriorityKey] = strconv.Itoa(priority)
+	}
+	if detachableFromContext(ctx) != nil {
+		md[daemonDetachableKey] = "true"
+	}
+	return md, nil
+}
+
+// RequireTransportSecurity is false as the daemon is reached over a Unix socket
+func (r daemonRouting) RequireTransportSecurity() bool {
+	return false
+}
+
+// ConnectDaemon returns a client for a plugin kept warm by the daemon, or ErrNoDaemon when no
+// daemon answers on the configured socket
+func ConnectDaemon(ctx context.Context, config *AppConfig, name string) (PluginInterface, error) {
+	path := config.DaemonSocketPath()
+	if _, err := os.Stat(path); err != nil {
+		return nil, ErrNoDaemon
+	}
+	client, err := DialDaemon(path, name)
+	if err != nil {
+		return nil, err
+	}
+	grpcClient := client.(*GRPCClient)
+
+	probeCtx, cancel := context.WithTimeout(ctx, daemonProbeTimeout)
+	defer cancel()
+	if _, err := healthpb.NewHealthClient(grpcClient.conn).Check(probeCtx, &healthpb.HealthCheckRequest{}); err != nil {
+		client.Close()
+		return nil, ErrNoDaemon
+	}
+	return client, nil
+}
+
+// DialDaemon returns a client executing the named plugin through the daemon listening on path.
+// The connection is made lazily; calls fail with codes.Unavailable while no daemon answers.
+func DialDaemon(path, name string) (PluginInterface, error) {
+	client, err := NewClientWithAddress("unix://"+path, grpc.WithPerRPCCredentials(daemonRouting{plugin: name}))
+	if err != nil {
+		return nil, err
+	}
+	client.(*GRPCClient).name = name
+	return client, nil
+}
@@ -0,0 +1,155 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// echoPlugin greets the name parameter and reports the credential the call arrived with
+type echoPlugin struct {
+	stubPlugin
+}
+
+func (echoPlugin) Execute(ctx context.Context, params map[string]string, output OutputHandler) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if err := output.OnOutput("hello " + params["name"]); err != nil {
+		return err
+	}
+	return output.OnOutput("auth " + strings.Join(md.Get(authMetadataKey), ","))
+}
+
+func TestDaemon(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	proto.RegisterPluginServer(server, &GRPCServer{Impl: echoPlugin{}})
+	StartHealthServer(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	config := &AppConfig{
+		StateDir: t.TempDir(),
+		Plugins: map[string]PluginConfig{
+			"echo": {Type: PluginTypeRemote, Address: listener.Addr().String()},
+		},
+	}
+
+	if _, err := ConnectDaemon(context.Background(), config, "echo"); !errors.Is(err, ErrNoDaemon) {
+		t.Fatalf("ConnectDaemon() without daemon error = %v, want ErrNoDaemon", err)
+	}
+
+	daemon := NewDaemon(NewPluginManager(config))
+	if err := daemon.Listen(config.DaemonSocketPath()); err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	go daemon.Serve()
+	defer daemon.Stop()
+
+	t.Run("Second daemon on the socket", func(t *testing.T) {
+		if err := NewDaemon(NewPluginManager(config)).Listen(config.DaemonSocketPath()); err == nil || !strings.Contains(err.Error(), "already listening") {
+			t.Errorf("Listen() error = %v, want already listening", err)
+		}
+	})
+
+	t.Run("Plugin is started on first use", func(t *testing.T) {
+		plugin, err := ConnectDaemon(context.Background(), config, "echo")
+		if err != nil {
+			t.Fatalf("ConnectDaemon() error = %v", err)
+		}
+		defer plugin.Close()
+
+		info, err := plugin.GetInfo(context.Background())
+		if err != nil || info.Name != "stub" {
+			t.Fatalf("GetInfo() = %v, %v", info, err)
+		}
+		handler := &recordingHandler{}
+		ctx := WithCredential(context.Background(), "caller-token")
+		if err := plugin.Execute(ctx, map[string]string{"name": "daemon"}, handler); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		want := []string{"hello daemon", "auth Bearer caller-token"}
+		if strings.Join(handler.output, "|") != strings.Join(want, "|") {
+			t.Errorf("output = %q, want %q", handler.output, want)
+		}
+	})
+
+	t.Run("Detachable executions keep the caller's id while it is free", func(t *testing.T) {
+		plugin, err := ConnectDaemon(context.Background(), config, "echo")
+		if err != nil {
+			t.Fatalf("ConnectDaemon() error = %v", err)
+		}
+		defer plugin.Close()
+		var ids []string
+		for i := 0; i < 2; i++ {
+			ctx := WithDetachable(WithExecutionID(context.Background(), "a1b2c3"), func(id string) { ids = append(ids, id) })
+			if err := plugin.Execute(ctx, map[string]string{"name": "daemon"}, &recordingHandler{}); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+		}
+		// The first journal still holds the id, so the second execution is told of another
+		if len(ids) != 2 || ids[0] != "a1b2c3" || ids[1] == "" || ids[1] == "a1b2c3" {
+			t.Errorf("started with ids %q, want a1b2c3 and then a new one", ids)
+		}
+	})
+
+	t.Run("Unknown plugin", func(t *testing.T) {
+		plugin, err := ConnectDaemon(context.Background(), config, "missing")
+		if err != nil {
+			t.Fatalf("ConnectDaemon() error = %v", err)
+		}
+		defer plugin.Close()
+		if _, err := plugin.GetInfo(context.Background()); status.Code(err) != codes.NotFound {
+			t.Errorf("GetInfo() error = %v, want NotFound", err)
+		}
+	})
+
+	t.Run("Plugin probed through the daemon", func(t *testing.T) {
+		plugin, err := ConnectDaemon(context.Background(), config, "echo")
+		if err != nil {
+			t.Fatalf("ConnectDaemon() error = %v", err)
+		}
+		defer plugin.Close()
+		result, err := ProbeThroughDaemon(context.Background(), plugin)
+		if err != nil || !result.Serving() {
+			t.Errorf("ProbeThroughDaemon() = %+v, %v, want serving", result, err)
+		}
+
+		missing, err := ConnectDaemon(context.Background(), config, "missing")
+		if err != nil {
+			t.Fatalf("ConnectDaemon() error = %v", err)
+		}
+		defer missing.Close()
+		if _, err := ProbeThroughDaemon(context.Background(), missing); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("ProbeThroughDaemon() error = %v, want not found", err)
+		}
+	})
+
+	t.Run("Stale socket is replaced", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "stale.sock")
+		stale, err := net.Listen("unix", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Leave the socket file behind without anyone listening
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		stale.Close()
+
+		other := NewDaemon(NewPluginManager(config))
+		if err := other.Listen(path); err != nil {
+			t.Fatalf("Listen() error = %v", err)
+		}
+		other.Stop()
+	})
+}
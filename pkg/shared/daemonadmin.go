@@ -0,0 +1,151 @@
+package shared
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// adminShutdownTimeout bounds waiting for probes in flight when the daemon stops
+const adminShutdownTimeout = 2 * time.Second
+
+// ListenAdmin serves the daemon's HTTP admin port on addr, such as 127.0.0.1:9090, right away so
+// that probes get answers while plugins are starting:
+//
+//   - /healthz answers 200 while the daemon is up and 503 once it is stopping, for liveness probes
+//   - /readyz answers 200 once the plugins were started and none of them is starting, restarting
+//     or unhealthy, and 503 until then, for readiness probes; ?plugin=name, repeatable, only
+//     considers those plugins
+//
+// Both answer with a JSON document of the state they report.
+func (d *Daemon) ListenAdmin(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", d.serveHealthz)
+	mux.HandleFunc("/readyz", d.serveReadyz)
+	d.admin = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	d.adminAddr = listener.Addr().String()
+	go func() {
+		if err := d.admin.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			Logger(LogDaemon).Error("admin port failed", "address", addr, "error", err)
+		}
+	}()
+	return nil
+}
+
+// AdminAddr returns the address the admin port listens on, "" without one
+func (d *Daemon) AdminAddr() string {
+	return d.adminAddr
+}
+
+// stopAdmin stops serving the admin port
+func (d *Daemon) stopAdmin() {
+	if d.admin == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), adminShutdownTimeout)
+	defer cancel()
+	d.admin.Shutdown(ctx)
+}
+
+// healthDocument is the answer of /healthz
+type healthDocument struct {
+	Status string `json:"status"` // ok or stopping
+}
+
+func (d *Daemon) serveHealthz(w http.ResponseWriter, r *http.Request) {
+	if d.stopping.Load() || d.manager.ctx.Err() != nil {
+		writeAdminDocument(w, http.StatusServiceUnavailable, healthDocument{Status: "stopping"})
+		return
+	}
+	writeAdminDocument(w, http.StatusOK, healthDocument{Status: "ok"})
+}
+
+// readinessDocument is the answer of /readyz
+type readinessDocument struct {
+	Status  string                     `json:"status"`            // ready, starting, not_ready or stopping
+	Plugins map[string]pluginReadiness `json:"plugins,omitempty"` // Those considered, by name
+}
+
+type pluginReadiness struct {
+	State PluginState `json:"state"`
+	Ready bool        `json:"ready"`
+	Error string      `json:"error,omitempty"`
+}
+
+// pluginReady reports whether a plugin lets the daemon be ready: stopped plugins are, as lazy
+// plugins and those that failed to start are started on first use
+func pluginReady(state PluginStatus) bool {
+	switch state.State {
+	case StateStarting, StateRestarting, StateUnhealthy:
+		return false
+	}
+	return true
+}
+
+func (d *Daemon) serveReadyz(w http.ResponseWriter, r *http.Request) {
+	doc := readinessDocument{Status: "ready", Plugins: make(map[string]pluginReadiness)}
+	selected := r.URL.Query()["plugin"]
+	config := d.manager.Config()
+	for _, name := range selected {
+		if _, ok := config.Plugins[name]; !ok {
+			http.Error(w, fmt.Sprintf("plugin %q not found in configuration", name), http.StatusNotFound)
+			return
+		}
+	}
+	considered := func(name string) bool {
+		if len(selected) == 0 {
+			return true
+		}
+		for _, s := range selected {
+			if s == name {
+				return true
+			}
+		}
+		return false
+	}
+	for _, state := range d.manager.Statuses() {
+		if !considered(state.Name) {
+			continue
+		}
+		readiness := pluginReadiness{State: state.State, Ready: pluginReady(state)}
+		if state.LastError != nil {
+			readiness.Error = state.LastError.Error()
+		}
+		if !readiness.Ready {
+			doc.Status = "not_ready"
+		}
+		doc.Plugins[state.Name] = readiness
+	}
+
+	switch {
+	case d.stopping.Load() || d.manager.ctx.Err() != nil:
+		doc.Status = "stopping"
+	case !d.started.Load():
+		doc.Status = "starting"
+	}
+	code := http.StatusOK
+	if doc.Status != "ready" {
+		code = http.StatusServiceUnavailable
+	}
+	writeAdminDocument(w, code, doc)
+}
+
+func writeAdminDocument(w http.ResponseWriter, code int, doc interface{}) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	w.Write(append(data, '\n'))
+}
@@ -0,0 +1,87 @@
+package shared
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+)
+
+func TestDaemon_admin(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	proto.RegisterPluginServer(server, &GRPCServer{Impl: echoPlugin{}})
+	StartHealthServer(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	config := &AppConfig{
+		StateDir: t.TempDir(),
+		Plugins: map[string]PluginConfig{
+			"echo": {Type: PluginTypeRemote, Address: listener.Addr().String()},
+		},
+	}
+	daemon := NewDaemon(NewPluginManager(config))
+	if err := daemon.ListenAdmin("127.0.0.1:0"); err != nil {
+		t.Fatalf("ListenAdmin() error = %v", err)
+	}
+	defer daemon.Stop()
+
+	get := func(path string) (int, readinessDocument) {
+		t.Helper()
+		resp, err := http.Get("http://" + daemon.AdminAddr() + path)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", path, err)
+		}
+		defer resp.Body.Close()
+		var doc readinessDocument
+		json.NewDecoder(resp.Body).Decode(&doc)
+		return resp.StatusCode, doc
+	}
+
+	if code, doc := get("/healthz"); code != http.StatusOK || doc.Status != "ok" {
+		t.Errorf("/healthz before starting = %d %q, want 200 ok", code, doc.Status)
+	}
+	if code, doc := get("/readyz"); code != http.StatusServiceUnavailable || doc.Status != "starting" {
+		t.Errorf("/readyz before starting = %d %q, want 503 starting", code, doc.Status)
+	}
+
+	if failed := daemon.StartPlugins(); len(failed) != 0 {
+		t.Fatalf("StartPlugins() = %v", failed)
+	}
+	code, doc := get("/readyz")
+	if code != http.StatusOK || doc.Status != "ready" || !doc.Plugins["echo"].Ready || doc.Plugins["echo"].State != StateReady {
+		t.Errorf("/readyz = %d %+v, want 200 with echo ready", code, doc)
+	}
+	if code, _ := get("/readyz?plugin=missing"); code != http.StatusNotFound {
+		t.Errorf("/readyz of an unknown plugin = %d, want 404", code)
+	}
+
+	// A plugin being restarted holds readiness back
+	pm := daemon.manager
+	pm.mu.Lock()
+	pm.states.update(pm.plugins["echo"], StateRestarting)
+	pm.mu.Unlock()
+	if code, doc := get("/readyz"); code != http.StatusServiceUnavailable || doc.Status != "not_ready" {
+		t.Errorf("/readyz while restarting = %d %q, want 503 not_ready", code, doc.Status)
+	}
+	if code, _ := get("/readyz?plugin=echo"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz?plugin=echo while restarting = %d, want 503", code)
+	}
+
+	daemon.stopping.Store(true)
+	for _, path := range []string{"/healthz", "/readyz"} {
+		rec := httptest.NewRecorder()
+		daemon.admin.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s while stopping = %d, want 503", path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// daemonRecord is what the daemon's pidfile holds. The start time tells the daemon apart from a
+// later process that reused its PID; it is 0 where processes can't be identified.
+type daemonRecord struct {
+	PID     int    `json:"pid"`
+	Started uint64 `json:"started"`
+}
+
+// DaemonPidPath returns the pidfile of the daemon listening on DaemonSocketPath
+func (c *AppConfig) DaemonPidPath() string {
+	return strings.TrimSuffix(c.DaemonSocketPath(), ".sock") + ".pid"
+}
+
+// WritePidfile records the daemon's process at path until Stop
+func (d *Daemon) WritePidfile(path string) error {
+	started, _ := processStartTime(os.Getpid())
+	data, err := json.Marshal(daemonRecord{PID: os.Getpid(), Started: started})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write pidfile: %v", err)
+	}
+	d.pidfile = path
+	return nil
+}
+
+// DaemonPID returns the PID of the daemon serving config, or ErrNoDaemon if none is running. A
+// pidfile left behind by a daemon that is gone is removed.
+func DaemonPID(config *AppConfig) (int, error) {
+	record, err := readDaemonRecord(config.DaemonPidPath())
+	if err != nil {
+		return 0, err
+	}
+	if !record.running(config) {
+		os.Remove(config.DaemonPidPath())
+		return 0, ErrNoDaemon
+	}
+	return record.PID, nil
+}
+
+// StopDaemon asks the daemon serving config to stop, which stops its plugins, and waits up to
+// timeout for it to exit. It returns the daemon's PID.
+func StopDaemon(config *AppConfig, timeout time.Duration) (int, error) {
+	pid, err := DaemonPID(config)
+	if err != nil {
+		return 0, err
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return 0, err
+	}
+	if err := process.Signal(syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return pid, fmt.Errorf("failed to signal daemon %d: %v", pid, err)
+	}
+
+	record, _ := readDaemonRecord(config.DaemonPidPath())
+	deadline := time.Now().Add(timeout)
+	for record != nil && record.running(config) {
+		if time.Now().After(deadline) {
+			return pid, fmt.Errorf("daemon %d did not stop within %v", pid, timeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return pid, nil
+}
+
+func readDaemonRecord(path string) (*daemonRecord, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoDaemon
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pidfile: %v", err)
+	}
+	var record daemonRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("invalid pidfile %s: %v", path, err)
+	}
+	return &record, nil
+}
+
+// running reports whether the recorded daemon is still alive. Without a start time to compare,
+// the daemon counts as running while its socket answers.
+func (r *daemonRecord) running(config *AppConfig) bool {
+	if r.Started != 0 {
+		return isRunning(r.PID, r.Started) && !isZombie(r.PID)
+	}
+	conn, err := net.DialTimeout("unix", config.DaemonSocketPath(), daemonProbeTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
@@ -0,0 +1,65 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestStopDaemon(t *testing.T) {
+	config := &AppConfig{StateDir: t.TempDir()}
+
+	if _, err := DaemonPID(config); !errors.Is(err, ErrNoDaemon) {
+		t.Fatalf("DaemonPID() without pidfile error = %v, want ErrNoDaemon", err)
+	}
+	if _, err := StopDaemon(config, time.Second); !errors.Is(err, ErrNoDaemon) {
+		t.Fatalf("StopDaemon() without pidfile error = %v, want ErrNoDaemon", err)
+	}
+
+	// A sleep stands in for the daemon, which exits on SIGTERM
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	process := watchProcess("sleep", cmd, nil, nil)
+	defer process.terminate(0)
+	started, err := processStartTime(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("processStartTime() error = %v", err)
+	}
+	writeRecord := func(record daemonRecord) {
+		t.Helper()
+		data, err := json.Marshal(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(config.DaemonPidPath(), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A process that reused the PID isn't the daemon
+	writeRecord(daemonRecord{PID: cmd.Process.Pid, Started: started + 1})
+	if _, err := DaemonPID(config); !errors.Is(err, ErrNoDaemon) {
+		t.Errorf("DaemonPID() with a reused PID error = %v, want ErrNoDaemon", err)
+	}
+	if _, err := os.Stat(config.DaemonPidPath()); !errors.Is(err, os.ErrNotExist) {
+		t.Error("stale pidfile was not removed")
+	}
+
+	writeRecord(daemonRecord{PID: cmd.Process.Pid, Started: started})
+	if pid, err := DaemonPID(config); err != nil || pid != cmd.Process.Pid {
+		t.Fatalf("DaemonPID() = %d, %v; want %d", pid, err, cmd.Process.Pid)
+	}
+	if pid, err := StopDaemon(config, 5*time.Second); err != nil || pid != cmd.Process.Pid {
+		t.Fatalf("StopDaemon() = %d, %v; want %d", pid, err, cmd.Process.Pid)
+	}
+	select {
+	case <-process.exited:
+	case <-time.After(time.Second):
+		t.Fatal("daemon process is still running")
+	}
+}
@@ -0,0 +1,178 @@
+package shared
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// checkDependencies rejects depends_on entries naming unknown plugins and dependency cycles,
+// which could never be started
+func (c *AppConfig) checkDependencies() error {
+	names := make([]string, 0, len(c.Plugins))
+	for name := range c.Plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, dep := range c.Plugins[name].DependsOn {
+			if dep == name {
+				return fmt.Errorf("plugin %q depends on itself", name)
+			}
+			if _, ok := c.Plugins[dep]; !ok {
+				return fmt.Errorf("plugin %q depends on unknown plugin %q", name, dep)
+			}
+		}
+	}
+
+	// Walk the dependencies depth first; meeting a plugin still on the path closes a cycle
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	marks := make(map[string]int)
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch marks[name] {
+		case visiting:
+			for i, n := range path {
+				if n == name {
+					return fmt.Errorf("dependency cycle: %s", strings.Join(append(path[i:], name), " -> "))
+				}
+			}
+		case visited:
+			return nil
+		}
+		marks[name] = visiting
+		path = append(path, name)
+		for _, dep := range c.Plugins[name].DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		marks[name] = visited
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartupGroups returns the configured plugins in the order they are started: each group only
+// depends on plugins of earlier groups. Names are sorted within a group.
+func (c *AppConfig) StartupGroups() [][]string {
+	levels := make(map[string]int)
+	var level func(name string, seen map[string]bool) int
+	level = func(name string, seen map[string]bool) int {
+		if l, ok := levels[name]; ok {
+			return l
+		}
+		// Cycles are rejected on load; guard against configurations built in code
+		if seen[name] {
+			return 0
+		}
+		seen[name] = true
+		l := 0
+		for _, dep := range c.Plugins[name].DependsOn {
+			if _, ok := c.Plugins[dep]; ok {
+				if depLevel := level(dep, seen) + 1; depLevel > l {
+					l = depLevel
+				}
+			}
+		}
+		levels[name] = l
+		return l
+	}
+
+	var groups [][]string
+	for name := range c.Plugins {
+		l := level(name, make(map[string]bool))
+		for len(groups) <= l {
+			groups = append(groups, nil)
+		}
+		groups[l] = append(groups[l], name)
+	}
+	for _, group := range groups {
+		sort.Strings(group)
+	}
+	return groups
+}
+
+// startDependencies starts the plugins config depends on that aren't running yet, their own
+// dependencies first. Each is ready once started; the caller must hold pm.mu.
+func (pm *PluginManager) startDependencies(name string, config PluginConfig, starting map[string]bool) error {
+	starting[name] = true
+	for _, dep := range config.DependsOn {
+		if _, running := pm.plugins[dep]; running {
+			continue
+		}
+		if starting[dep] {
+			return fmt.Errorf("dependency cycle between %s and %s", name, dep)
+		}
+		depConfig, ok := pm.config.Plugins[dep]
+		if !ok {
+			return fmt.Errorf("dependency %s of plugin %s not found in configuration", dep, name)
+		}
+		if err := pm.startDependencies(dep, depConfig, starting); err != nil {
+			return err
+		}
+		pm.states.starting(dep, depConfig)
+		if err := pm.startPlugin(dep, depConfig); err != nil {
+			pm.states.stopped(dep, err)
+			return fmt.Errorf("dependency %s of plugin %s failed to start: %v", dep, name, err)
+		}
+		pm.states.update(pm.plugins[dep], StateReady)
+	}
+	return nil
+}
+
+// stopInOrder shuts down plugins removed from pm.plugins, dependents before their
+// dependencies. Plugins with no dependents left among them stop together, each with its grace
+// period.
+func (pm *PluginManager) stopInOrder(plugins map[string]*ManagedPlugin) {
+	for len(plugins) > 0 {
+		var batch []*ManagedPlugin
+		for name, plugin := range plugins {
+			if !dependedOn(name, plugins) {
+				batch = append(batch, plugin)
+			}
+		}
+		// Only cycles, which aren't started in the first place, leave nothing to stop first
+		if len(batch) == 0 {
+			for _, plugin := range plugins {
+				batch = append(batch, plugin)
+			}
+		}
+
+		var wg sync.WaitGroup
+		for _, plugin := range batch {
+			delete(plugins, plugin.Name)
+			wg.Add(1)
+			go func(plugin *ManagedPlugin) {
+				defer wg.Done()
+				plugin.shutdown()
+				pm.states.stopped(plugin.Name, nil)
+			}(plugin)
+		}
+		wg.Wait()
+	}
+}
+
+// dependedOn reports whether one of plugins depends on name
+func dependedOn(name string, plugins map[string]*ManagedPlugin) bool {
+	for _, m := range plugins {
+		for _, dep := range m.Config.DependsOn {
+			if dep == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package shared
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppConfig_checkDependencies(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugins  map[string][]string // Dependencies by plugin
+		wantErr  bool
+		errorMsg string
+	}{
+		{
+			name:    "No dependencies",
+			plugins: map[string][]string{"a": nil, "b": nil},
+		},
+		{
+			name:    "Chain and diamond",
+			plugins: map[string][]string{"app": {"cache", "db"}, "cache": {"db"}, "db": nil},
+		},
+		{
+			name:     "Unknown dependency",
+			plugins:  map[string][]string{"app": {"db"}},
+			wantErr:  true,
+			errorMsg: `plugin "app" depends on unknown plugin "db"`,
+		},
+		{
+			name:     "Self dependency",
+			plugins:  map[string][]string{"app": {"app"}},
+			wantErr:  true,
+			errorMsg: `plugin "app" depends on itself`,
+		},
+		{
+			name:     "Cycle",
+			plugins:  map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}},
+			wantErr:  true,
+			errorMsg: "dependency cycle: a -> b -> c -> a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &AppConfig{Plugins: make(map[string]PluginConfig)}
+			for name, deps := range tt.plugins {
+				config.Plugins[name] = PluginConfig{Type: PluginTypeRemote, Address: "localhost:1", DependsOn: deps}
+			}
+			err := config.checkDependencies()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkDependencies() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("checkDependencies() error = %v, want error containing %v", err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestAppConfig_StartupGroups(t *testing.T) {
+	config := &AppConfig{Plugins: map[string]PluginConfig{
+		"app":    {DependsOn: []string{"cache", "db"}},
+		"cache":  {DependsOn: []string{"db"}},
+		"db":     {},
+		"report": {DependsOn: []string{"db"}},
+		"solo":   {},
+	}}
+	want := [][]string{{"db", "solo"}, {"cache", "report"}, {"app"}}
+	if got := config.StartupGroups(); !reflect.DeepEqual(got, want) {
+		t.Errorf("StartupGroups() = %v, want %v", got, want)
+	}
+}
+
+func TestPluginManager_DependsOn(t *testing.T) {
+	server, addr := startStubPluginServer(t)
+	defer server.Stop()
+
+	// Nothing listens on a closed listener's address, so connecting times out
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+
+	config := &AppConfig{Plugins: map[string]PluginConfig{
+		"db":      {Type: PluginTypeRemote, Address: addr},
+		"cache":   {Type: PluginTypeRemote, Address: addr, DependsOn: []string{"db"}},
+		"app":     {Type: PluginTypeRemote, Address: addr, DependsOn: []string{"cache", "db"}},
+		"missing": {Type: PluginTypeRemote, Address: closed.Addr().String(), ConnectTimeout: Duration(200 * time.Millisecond)},
+		"broken":  {Type: PluginTypeRemote, Address: addr, DependsOn: []string{"missing"}},
+	}}
+	pm := NewPluginManager(config)
+	defer pm.StopAll()
+
+	// Dependencies are started first, each ready before its dependents
+	if err := pm.StartPlugin("app", config.Plugins["app"]); err != nil {
+		t.Fatalf("StartPlugin() error = %v", err)
+	}
+	var ready []time.Time
+	for _, name := range []string{"db", "cache", "app"} {
+		status, err := pm.Status(name)
+		if err != nil || status.State != StateReady {
+			t.Fatalf("Status(%s) = %+v, %v; want ready", name, status, err)
+		}
+		ready = append(ready, status.Since)
+	}
+	if ready[1].Before(ready[0]) || ready[2].Before(ready[1]) {
+		t.Errorf("ready at %v, want db, cache, then app", ready)
+	}
+
+	// A dependency that can't start fails its dependent
+	if err := pm.StartPlugin("broken", config.Plugins["broken"]); err == nil || !strings.Contains(err.Error(), "dependency missing of plugin broken failed to start") {
+		t.Errorf("StartPlugin() with a failing dependency error = %v", err)
+	}
+	if _, err := pm.GetPlugin("broken"); err == nil {
+		t.Error("plugin with a failed dependency is running")
+	}
+
+	// Dependents are stopped before their dependencies
+	pm.StopAll()
+	var stopped []time.Time
+	for _, name := range []string{"app", "cache", "db"} {
+		status, _ := pm.Status(name)
+		if status.State != StateStopped {
+			t.Fatalf("Status(%s).State = %s after StopAll()", name, status.State)
+		}
+		stopped = append(stopped, status.Since)
+	}
+	if stopped[1].Before(stopped[0]) || stopped[2].Before(stopped[1]) {
+		t.Errorf("stopped at %v, want app, cache, then db", stopped)
+	}
+}
@@ -0,0 +1,48 @@
+package shared
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+
+	"github.com/example/grpc-plugin-app/proto"
+)
+
+// DiagnosticsProvider is implemented by plugins that produce their own diagnostic dumps;
+// plugins without it report the goroutines and heap of their process
+type DiagnosticsProvider interface {
+	Diagnostics(ctx context.Context, reason string) (map[string]string, error)
+}
+
+// ProcessDumps returns the goroutine stacks and heap profile of the current process as text
+func ProcessDumps() map[string]string {
+	dumps := make(map[string]string)
+	for name, debug := range map[string]int{"goroutine": 2, "heap": 1} {
+		var buf bytes.Buffer
+		if profile := pprof.Lookup(name); profile != nil && profile.WriteTo(&buf, debug) == nil {
+			dumps[name] = buf.String()
+		}
+	}
+	return dumps
+}
+
+// Diagnostics implements the Diagnostics RPC method
+func (s *GRPCServer) Diagnostics(ctx context.Context, req *proto.DiagnosticsRequest) (*proto.DiagnosticsResponse, error) {
+	if provider, ok := s.Impl.(DiagnosticsProvider); ok {
+		dumps, err := provider.Diagnostics(ctx, req.Reason)
+		if err != nil {
+			return nil, err
+		}
+		return &proto.DiagnosticsResponse{Dumps: dumps}, nil
+	}
+	return &proto.DiagnosticsResponse{Dumps: ProcessDumps()}, nil
+}
+
+// Diagnostics asks the plugin for its diagnostic dumps
+func (c *GRPCClient) Diagnostics(ctx context.Context, reason string) (map[string]string, error) {
+	resp, err := c.client.Diagnostics(ctx, &proto.DiagnosticsRequest{Reason: reason})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Dumps, nil
+}
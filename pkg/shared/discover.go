@@ -0,0 +1,81 @@
+package shared
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// discoverPlugins registers the executables found in config.PluginDirs as binary plugins, so that
+// dropping a plugin into a directory is enough to use it. A plugin is named by the sidecar
+// manifest next to it if there is one, or else by its file name. Configured plugins take
+// precedence over discovered ones of the same name or binary.
+func discoverPlugins(config *AppConfig, workspaceRoot string) []error {
+	if len(config.PluginDirs) == 0 {
+		return nil
+	}
+	plugins := make(map[string]PluginConfig, len(config.Plugins))
+	configuredPaths := make(map[string]bool)
+	for name, plugin := range config.Plugins {
+		plugins[name] = plugin
+		if plugin.Path != "" {
+			configuredPaths[resolvePath(workspaceRoot, plugin.Path)] = true
+		}
+	}
+	config.Plugins = plugins
+	config.discovered = make(map[string]string)
+
+	var errs []error
+	for _, dir := range config.PluginDirs {
+		dir = resolvePath(workspaceRoot, dir)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid plugin_dirs entry: %v", err))
+			continue
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if configuredPaths[path] || !isPluginExecutable(entry) {
+				continue
+			}
+			name := entry.Name()
+			if manifest, err := LoadManifest(path + ManifestSuffix); err == nil && manifest.Name != "" {
+				name = manifest.Name
+			}
+			if found, ok := config.discovered[name]; ok {
+				errs = append(errs, fmt.Errorf("plugins discovered in %s and %s are both named %q", found, dir, name))
+				continue
+			}
+			if _, ok := config.Plugins[name]; ok {
+				continue
+			}
+			config.Plugins[name] = PluginConfig{Type: PluginTypeBinary, Path: path}
+			config.discovered[name] = dir
+		}
+	}
+	return errs
+}
+
+// isPluginExecutable reports whether a directory entry could be a plugin binary: an executable
+// regular file other than hidden ones and manifests
+func isPluginExecutable(entry os.DirEntry) bool {
+	if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") || strings.HasSuffix(entry.Name(), ManifestSuffix) {
+		return false
+	}
+	info, err := entry.Info()
+	return err == nil && info.Mode().Perm()&0111 != 0
+}
+
+// resolvePath makes a configured path absolute against the workspace root
+func resolvePath(workspaceRoot, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(workspaceRoot, path)
+}
+
+// DiscoveredIn returns the plugin_dirs entry a plugin was discovered in, or "" for a configured one
+func (c *AppConfig) DiscoveredIn(name string) string {
+	return c.discovered[name]
+}
@@ -0,0 +1,59 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig_pluginDirs(t *testing.T) {
+	dir := t.TempDir()
+	plugins := filepath.Join(dir, "plugins")
+	if err := os.Mkdir(plugins, 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name string, mode os.FileMode, content string) {
+		if err := os.WriteFile(filepath.Join(plugins, name), []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("hello", 0755, "#!/bin/sh\n")
+	write("adder", 0755, "#!/bin/sh\n")
+	write("adder"+ManifestSuffix, 0644, `{"name": "addition", "version": "1.0.0"}`)
+	write("configured", 0755, "#!/bin/sh\n")
+	write("README", 0644, "not a plugin\n")
+	write(".hidden", 0755, "#!/bin/sh\n")
+
+	path := filepath.Join(dir, "config.json")
+	config := `{"plugin_dirs": ["` + plugins + `"], "plugins": {"mine": {"path": "` + filepath.Join(plugins, "configured") + `", "description": "Configured"}}}`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	want := map[string]string{"addition": plugins, "hello": plugins, "mine": ""}
+	got := make(map[string]string)
+	for name := range loaded.Plugins {
+		got[name] = loaded.DiscoveredIn(name)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("plugins = %v, want %v", got, want)
+	}
+	if hello := loaded.Plugins["hello"]; hello.Type != PluginTypeBinary || hello.Path != filepath.Join(plugins, "hello") {
+		t.Errorf("discovered plugin = %+v", hello)
+	}
+
+	// A missing directory is a problem of the configuration
+	config = `{"plugin_dirs": ["` + filepath.Join(dir, "nope") + `"], "plugins": {}}`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "invalid plugin_dirs entry") {
+		t.Errorf("LoadConfig() error = %v, want the missing directory", err)
+	}
+}
@@ -0,0 +1,236 @@
+package shared
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+const (
+	// EncryptedPrefix marks a config value encrypted with the config key
+	EncryptedPrefix = "enc:"
+
+	// ConfigKeyEnvVar holds the base64 config key; it takes precedence over the key file
+	ConfigKeyEnvVar = "PLUGIN_APP_KEY"
+	// ConfigKeyFileEnvVar overrides where the config key file is read from
+	ConfigKeyFileEnvVar = "PLUGIN_APP_KEY_FILE"
+
+	configKeySize = 32
+)
+
+// DefaultKeyFile returns the per-user file the config key is kept in
+func DefaultKeyFile() string {
+	if path := os.Getenv(ConfigKeyFileEnvVar); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "plugin-app", "config.key")
+}
+
+// LoadConfigKey returns the AES-256 key for encrypted config values
+func LoadConfigKey() ([]byte, error) {
+	encoded := os.Getenv(ConfigKeyEnvVar)
+	source := "$" + ConfigKeyEnvVar
+	if encoded == "" {
+		path := DefaultKeyFile()
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no config key: set %s or create %s with 'plugin-app encrypt -generate-key'", ConfigKeyEnvVar, path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config key: %v", err)
+		}
+		encoded = string(data)
+		source = path
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != configKeySize {
+		return nil, fmt.Errorf("invalid config key in %s: must be %d base64-encoded bytes", source, configKeySize)
+	}
+	return key, nil
+}
+
+// GenerateConfigKey writes a new random key to path, refusing to replace an existing one
+func GenerateConfigKey(path string) error {
+	key := make([]byte, configKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate config key: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create key directory: %v", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create config key: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(base64.StdEncoding.EncodeToString(key) + "\n"); err != nil {
+		return fmt.Errorf("failed to write config key: %v", err)
+	}
+	return nil
+}
+
+// EncryptValue encrypts a config value with AES-GCM, returning it with the enc: prefix
+func EncryptValue(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptValue decrypts a value produced by EncryptValue
+func DecryptValue(key []byte, value string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, EncryptedPrefix))
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: wrong key or corrupted data")
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config key: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptedValue is a config value that was encrypted on disk
+type encryptedValue struct {
+	plaintext  string
+	ciphertext string
+}
+
+// decryptConfig replaces every enc: value in config with its plaintext and remembers where
+// they were so SaveConfig can encrypt them again. The key is only loaded if one is needed.
+func decryptConfig(config *AppConfig) error {
+	var key []byte
+	config.encrypted = make(map[string]encryptedValue)
+	return walkStrings(reflect.ValueOf(config).Elem(), "", func(path, value string) (string, error) {
+		if !strings.HasPrefix(value, EncryptedPrefix) {
+			return value, nil
+		}
+		if key == nil {
+			var err error
+			if key, err = LoadConfigKey(); err != nil {
+				return "", fmt.Errorf("config has encrypted values: %v", err)
+			}
+		}
+		plaintext, err := DecryptValue(key, value)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", path, err)
+		}
+		config.encrypted[path] = encryptedValue{plaintext: plaintext, ciphertext: value}
+		return plaintext, nil
+	})
+}
+
+// encryptConfig encrypts the values that were encrypted when config was loaded. Unchanged
+// values keep their original ciphertext so saving doesn't rewrite them.
+func encryptConfig(config *AppConfig, encrypted map[string]encryptedValue) error {
+	var key []byte
+	return walkStrings(reflect.ValueOf(config).Elem(), "", func(path, value string) (string, error) {
+		original, ok := encrypted[path]
+		if !ok || strings.HasPrefix(value, EncryptedPrefix) {
+			return value, nil
+		}
+		if value == original.plaintext {
+			return original.ciphertext, nil
+		}
+		if key == nil {
+			var err error
+			if key, err = LoadConfigKey(); err != nil {
+				return "", err
+			}
+		}
+		return EncryptValue(key, value)
+	})
+}
+
+// walkStrings calls fn for every string reachable from v, keyed by its JSON path
+// (e.g. plugins/hello/auth_token), and stores what fn returns
+func walkStrings(v reflect.Value, path string, fn func(path, value string) (string, error)) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return walkStrings(v.Elem(), path, fn)
+	case reflect.String:
+		value, err := fn(path, v.String())
+		if err != nil {
+			return err
+		}
+		if v.CanSet() {
+			v.SetString(value)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if err := walkStrings(v.Field(i), joinPath(path, name), fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := walkStrings(v.Index(i), joinPath(path, fmt.Sprint(i)), fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			// Map values aren't addressable, so walk a copy and store it back
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(iter.Value())
+			if err := walkStrings(elem, joinPath(path, iter.Key().String()), fn); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	}
+	return nil
+}
+
+// joinPath appends a key to a JSON path, escaping it like a JSON pointer
+func joinPath(path, key string) string {
+	key = strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+	if path == "" {
+		return key
+	}
+	return path + "/" + key
+}
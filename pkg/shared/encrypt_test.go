@@ -0,0 +1,112 @@
+package shared
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testConfigKey(t *testing.T, fill byte) []byte {
+	t.Helper()
+	key := make([]byte, configKeySize)
+	for i := range key {
+		key[i] = fill
+	}
+	t.Setenv(ConfigKeyEnvVar, base64.StdEncoding.EncodeToString(key))
+	t.Setenv(ConfigKeyFileEnvVar, filepath.Join(t.TempDir(), "missing.key"))
+	return key
+}
+
+func TestEncryptValue(t *testing.T) {
+	key := testConfigKey(t, 1)
+	encrypted, err := EncryptValue(key, "s3cret")
+	if err != nil {
+		t.Fatalf("EncryptValue() error = %v", err)
+	}
+	if !strings.HasPrefix(encrypted, EncryptedPrefix) || strings.Contains(encrypted, "s3cret") {
+		t.Fatalf("EncryptValue() = %q", encrypted)
+	}
+
+	tests := []struct {
+		name    string
+		key     []byte
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "Round trip", key: key, value: encrypted, want: "s3cret"},
+		{name: "Wrong key", key: make([]byte, configKeySize), value: encrypted, wantErr: true},
+		{name: "Malformed", key: key, value: EncryptedPrefix + "not base64!", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecryptValue(tt.key, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecryptValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DecryptValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRawConfig_Encrypted(t *testing.T) {
+	key := testConfigKey(t, 2)
+	token, _ := EncryptValue(key, "plugin-token")
+	apiKey, _ := EncryptValue(key, "default-api-key")
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"plugins": {"api": {"type": "remote", "address": "localhost:50051", "auth_token": "` + token +
+		`", "defaults": {"api_key": "` + apiKey + `", "region": "eu"}}}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadRawConfig(path)
+	if err != nil {
+		t.Fatalf("LoadRawConfig() error = %v", err)
+	}
+	plugin := config.Plugins["api"]
+	if plugin.AuthToken != "plugin-token" || plugin.Defaults["api_key"] != "default-api-key" {
+		t.Fatalf("values not decrypted: auth_token=%q api_key=%q", plugin.AuthToken, plugin.Defaults["api_key"])
+	}
+
+	// Saving keeps unchanged ciphertexts and encrypts changed values again
+	plugin.Defaults["api_key"] = "rotated-api-key"
+	plugin.Defaults["region"] = "us"
+	if err := SaveConfig(config, path); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+	saved, _ := os.ReadFile(path)
+	for _, plaintext := range []string{"plugin-token", "rotated-api-key"} {
+		if strings.Contains(string(saved), plaintext) {
+			t.Errorf("saved config contains plaintext %q", plaintext)
+		}
+	}
+	if !strings.Contains(string(saved), token) {
+		t.Errorf("unchanged auth_token was re-encrypted")
+	}
+	if plugin.Defaults["api_key"] != "rotated-api-key" {
+		t.Errorf("SaveConfig() encrypted the caller's config")
+	}
+
+	reloaded, err := LoadRawConfig(path)
+	if err != nil {
+		t.Fatalf("LoadRawConfig() after save error = %v", err)
+	}
+	if got := reloaded.Plugins["api"].Defaults["api_key"]; got != "rotated-api-key" {
+		t.Errorf("api_key after save = %q, want rotated-api-key", got)
+	}
+	if got := reloaded.Plugins["api"].Defaults["region"]; got != "us" {
+		t.Errorf("region after save = %q, want plaintext us", got)
+	}
+
+	// Without the key the config can't be loaded
+	t.Setenv(ConfigKeyEnvVar, "")
+	if _, err := LoadRawConfig(path); err == nil || !strings.Contains(err.Error(), "no config key") {
+		t.Errorf("LoadRawConfig() without key error = %v, want missing key", err)
+	}
+}
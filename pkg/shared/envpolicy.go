@@ -0,0 +1,74 @@
+package shared
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// EnvPolicy is how much of the host's environment a local plugin's processes inherit. The
+// plugin's own env, and what the host provides it such as its auth token, is passed regardless.
+type EnvPolicy string
+
+const (
+	EnvInheritAll EnvPolicy = "inherit-all" // Every host variable but those in env_deny (default)
+	EnvAllowlist  EnvPolicy = "allowlist"   // Only host variables in env_allow and not in env_deny
+	EnvNone       EnvPolicy = "none"        // No host variables
+)
+
+// validateEnvFilter checks the plugin's env_policy and its env_allow and env_deny patterns
+func (p *PluginConfig) validateEnvFilter() error {
+	switch p.EnvPolicy {
+	case "", EnvInheritAll, EnvAllowlist, EnvNone:
+	default:
+		return fmt.Errorf("invalid env_policy: %s (must be inherit-all, allowlist, or none)", p.EnvPolicy)
+	}
+	if p.IsRemote() && (p.EnvPolicy != "" || len(p.EnvAllow) > 0 || len(p.EnvDeny) > 0) {
+		return fmt.Errorf("env_policy, env_allow and env_deny only apply to local plugins")
+	}
+	for _, pattern := range append(append([]string(nil), p.EnvAllow...), p.EnvDeny...) {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("invalid environment variable pattern: %q", pattern)
+		}
+	}
+	return nil
+}
+
+// envPolicy returns the plugin's env_policy with the default filled in
+func (p *PluginConfig) envPolicy() EnvPolicy {
+	if p.EnvPolicy == "" {
+		return EnvInheritAll
+	}
+	return p.EnvPolicy
+}
+
+// hostEnvironment returns the entries of the host's environment the plugin inherits
+func (p *PluginConfig) hostEnvironment(environ []string) []string {
+	policy := p.envPolicy()
+	if policy == EnvNone {
+		return nil
+	}
+	var env []string
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		if matchesEnvPattern(p.EnvDeny, name) {
+			continue
+		}
+		if policy == EnvAllowlist && !matchesEnvPattern(p.EnvAllow, name) {
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
+
+// matchesEnvPattern reports whether a variable name matches one of the patterns, such as
+// AWS_* or HOME
+func matchesEnvPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
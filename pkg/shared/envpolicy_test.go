@@ -0,0 +1,108 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPluginConfig_hostEnvironment(t *testing.T) {
+	environ := []string{"HOME=/home/me", "PATH=/usr/bin", "AWS_REGION=eu", "AWS_SECRET_TOKEN=s3cret", "GITHUB_TOKEN=ghp"}
+	tests := []struct {
+		name   string
+		config PluginConfig
+		want   []string
+	}{
+		{name: "Inherit all by default", config: PluginConfig{}, want: environ},
+		{name: "Inherit all but denied", config: PluginConfig{EnvPolicy: EnvInheritAll, EnvDeny: []string{"*_TOKEN"}}, want: []string{"HOME=/home/me", "PATH=/usr/bin", "AWS_REGION=eu"}},
+		{name: "Allowlist", config: PluginConfig{EnvPolicy: EnvAllowlist, EnvAllow: []string{"HOME", "AWS_*"}}, want: []string{"HOME=/home/me", "AWS_REGION=eu", "AWS_SECRET_TOKEN=s3cret"}},
+		{name: "Denied wins over allowed", config: PluginConfig{EnvPolicy: EnvAllowlist, EnvAllow: []string{"AWS_*"}, EnvDeny: []string{"*_TOKEN"}}, want: []string{"AWS_REGION=eu"}},
+		{name: "Allowlist without entries", config: PluginConfig{EnvPolicy: EnvAllowlist}, want: nil},
+		{name: "None", config: PluginConfig{EnvPolicy: EnvNone, EnvAllow: []string{"HOME"}}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.hostEnvironment(environ); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hostEnvironment() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPluginConfig_validateEnvFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  PluginConfig
+		wantErr string
+	}{
+		{name: "Valid", config: PluginConfig{EnvPolicy: EnvAllowlist, EnvAllow: []string{"HOME", "AWS_*"}, EnvDeny: []string{"*_TOKEN"}}},
+		{name: "Unknown policy", config: PluginConfig{EnvPolicy: "some"}, wantErr: "invalid env_policy"},
+		{name: "Bad pattern", config: PluginConfig{EnvDeny: []string{"[A-"}}, wantErr: "invalid environment variable pattern"},
+		{name: "Remote plugin", config: PluginConfig{Type: PluginTypeRemote, EnvPolicy: EnvNone}, wantErr: "only apply to local plugins"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.validateEnvFilter()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateEnvFilter() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateEnvFilter() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManagedPlugin_environmentPolicy(t *testing.T) {
+	t.Setenv("PLUGIN_TEST_SECRET", "s3cret")
+	m := &ManagedPlugin{Config: PluginConfig{EnvPolicy: EnvNone, Environment: map[string]string{"LOG_LEVEL": "debug"}}, authToken: "token"}
+	env := strings.Join(m.environment(), "\n")
+	if strings.Contains(env, "PLUGIN_TEST_SECRET") {
+		t.Errorf("environment() passes host variables with env_policy none:\n%s", env)
+	}
+	// The plugin's own env and the host's credentials are passed regardless
+	for _, want := range []string{"LOG_LEVEL=debug", AuthTokenEnvVar + "=token"} {
+		if !strings.Contains(env, want) {
+			t.Errorf("environment() lacks %s:\n%s", want, env)
+		}
+	}
+}
+
+func TestLoadConfig_envPolicySetting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{
+		"settings": {"env_policy": "none"},
+		"plugins": {
+			"default": {"type": "binary", "path": "/bin/true"},
+			"own": {"type": "binary", "path": "/bin/true", "env_policy": "allowlist", "env_allow": ["HOME"]},
+			"remote": {"type": "remote", "address": "localhost:9001"}
+		}
+	}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	for name, want := range map[string]EnvPolicy{"default": EnvNone, "own": EnvAllowlist, "remote": ""} {
+		if got := config.Plugins[name].EnvPolicy; got != want {
+			t.Errorf("env_policy of %s = %q, want %q", name, got, want)
+		}
+	}
+
+	data = `{"settings": {"env_policy": "some"}, "plugins": {}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "invalid settings env_policy") {
+		t.Errorf("LoadConfig() error = %v, want the invalid env_policy", err)
+	}
+}
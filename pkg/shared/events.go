@@ -0,0 +1,119 @@
+package shared
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType is the kind of a lifecycle event
+type EventType string
+
+const (
+	EventPluginStarted     EventType = "plugin_started"      // A start began: the process was launched or the remote is being reached
+	EventPluginReady       EventType = "plugin_ready"        // The plugin can execute, after a start, restart or failover
+	EventPluginUnhealthy   EventType = "plugin_unhealthy"    // A health check failed or the process crashed
+	EventPluginRestarted   EventType = "plugin_restarted"    // An unhealthy plugin was restarted or failed over
+	EventPluginStopped     EventType = "plugin_stopped"      // The plugin was stopped or failed to start
+	EventPluginCircuitOpen EventType = "plugin_circuit_open" // Restarts of the plugin stopped after too many failures in the restart window
+	EventExecutionStarted  EventType = "execution_started"   // An execution began
+	EventExecutionFinished EventType = "execution_finished"  // An execution ended, successfully or not
+)
+
+// DefaultEventBuffer is how many events a subscription holds before dropping newer ones
+const DefaultEventBuffer = 256
+
+// Event is something that happened to a plugin or one of its executions
+type Event struct {
+	Type     EventType
+	Plugin   string
+	Time     time.Time
+	Err      error         // Why the plugin turned unhealthy or failed to start, or how the execution failed
+	Duration time.Duration // Of a finished execution
+}
+
+// Subscription receives the events it subscribed to, in the order they happened
+type Subscription struct {
+	bus     *eventBus
+	events  chan Event
+	types   map[EventType]bool // Empty for all
+	dropped atomic.Uint64
+}
+
+// Events returns the channel events are delivered on; it is closed by Close
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns how many events were dropped because the subscriber fell behind
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close stops delivery and closes the events channel
+func (s *Subscription) Close() {
+	s.bus.unsubscribe(s)
+}
+
+// eventBus fans events out to subscriptions. Publishing never blocks, so events can be
+// published while holding pm.mu; a subscriber that falls behind loses events instead.
+type eventBus struct {
+	mu            sync.Mutex
+	subscriptions map[*Subscription]bool
+}
+
+// subscribe adds a subscription to the given event types, or all of them if none are given
+func (b *eventBus) subscribe(buffer int, types []EventType) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultEventBuffer
+	}
+	s := &Subscription{bus: b, events: make(chan Event, buffer), types: make(map[EventType]bool)}
+	for _, t := range types {
+		s.types[t] = true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscriptions == nil {
+		b.subscriptions = make(map[*Subscription]bool)
+	}
+	b.subscriptions[s] = true
+	return s
+}
+
+func (b *eventBus) unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscriptions[s] {
+		delete(b.subscriptions, s)
+		close(s.events)
+	}
+}
+
+// publish delivers an event to every interested subscription; a nil bus drops it
+func (b *eventBus) publish(event Event) {
+	if b == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subscriptions {
+		if len(s.types) > 0 && !s.types[event.Type] {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Subscribe returns a subscription to the manager's lifecycle events of the given types, or of
+// all types if none are given. Up to buffer events (DefaultEventBuffer if 0) wait for the
+// subscriber; further ones are dropped and counted. Close the subscription when done.
+func (pm *PluginManager) Subscribe(buffer int, types ...EventType) *Subscription {
+	return pm.events.subscribe(buffer, types)
+}
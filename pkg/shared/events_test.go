@@ -0,0 +1,132 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPluginManager_Subscribe(t *testing.T) {
+	server, addr := startStubPluginServer(t)
+	defer server.Stop()
+
+	config := &AppConfig{Plugins: map[string]PluginConfig{
+		"stub": {Type: PluginTypeRemote, Address: addr},
+	}}
+	pm := NewPluginManager(config)
+	defer pm.StopAll()
+
+	all := pm.Subscribe(0)
+	defer all.Close()
+	executions := pm.Subscribe(0, EventExecutionFinished)
+	defer executions.Close()
+
+	if err := pm.StartPlugin("stub", config.Plugins["stub"]); err != nil {
+		t.Fatalf("StartPlugin() error = %v", err)
+	}
+	plugin, err := pm.GetPlugin("stub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plugin.Execute(context.Background(), nil, discardHandler{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if err := pm.StopPlugin("stub"); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []EventType
+	for len(got) < 5 {
+		select {
+		case event := <-all.Events():
+			if event.Plugin != "stub" || event.Time.IsZero() {
+				t.Errorf("event = %+v, want one of plugin stub with a time", event)
+			}
+			got = append(got, event.Type)
+		case <-time.After(time.Second):
+			t.Fatalf("events = %v, want five", got)
+		}
+	}
+	want := []EventType{EventPluginStarted, EventPluginReady, EventExecutionStarted, EventExecutionFinished, EventPluginStopped}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("events = %v, want %v", got, want)
+		}
+	}
+
+	// A subscription only receives the types it asked for
+	select {
+	case event := <-executions.Events():
+		if event.Type != EventExecutionFinished || event.Err != nil || event.Duration <= 0 {
+			t.Errorf("event = %+v, want a successful finished execution", event)
+		}
+	default:
+		t.Fatal("no finished execution was delivered")
+	}
+	if len(executions.Events()) != 0 {
+		t.Errorf("filtered subscription received %d more events", len(executions.Events()))
+	}
+}
+
+func TestPluginManager_SubscribeUnhealthy(t *testing.T) {
+	server, addr := startStubPluginServer(t)
+	defer server.Stop()
+
+	config := &AppConfig{Plugins: map[string]PluginConfig{
+		"stub": {Type: PluginTypeRemote, Address: addr},
+	}}
+	pm := NewPluginManager(config)
+	defer pm.StopAll()
+	if err := pm.StartPlugin("stub", config.Plugins["stub"]); err != nil {
+		t.Fatalf("StartPlugin() error = %v", err)
+	}
+
+	sub := pm.Subscribe(0, EventPluginUnhealthy, EventPluginReady)
+	defer sub.Close()
+
+	// The state tracker publishes transitions however they come about
+	failure := errors.New("health check failed")
+	pm.mu.Lock()
+	managed := pm.plugins["stub"]
+	managed.LastError = failure
+	pm.states.update(managed, StateUnhealthy)
+	pm.states.update(managed, StateUnhealthy)
+	pm.states.update(managed, StateReady)
+	pm.mu.Unlock()
+
+	unhealthy, ready := <-sub.Events(), <-sub.Events()
+	if unhealthy.Type != EventPluginUnhealthy || unhealthy.Err != failure {
+		t.Errorf("first event = %+v, want unhealthy with the failure", unhealthy)
+	}
+	if ready.Type != EventPluginReady {
+		t.Errorf("second event = %+v, want ready", ready)
+	}
+	if len(sub.Events()) != 0 {
+		t.Error("repeating a state published it again")
+	}
+}
+
+func TestSubscription_Dropped(t *testing.T) {
+	var bus eventBus
+	sub := bus.subscribe(2, nil)
+	for i := 0; i < 5; i++ {
+		bus.publish(Event{Type: EventExecutionStarted, Plugin: "p"})
+	}
+	if len(sub.Events()) != 2 || sub.Dropped() != 3 {
+		t.Errorf("buffered %d, dropped %d; want 2 and 3", len(sub.Events()), sub.Dropped())
+	}
+
+	sub.Close()
+	sub.Close()
+	bus.publish(Event{Type: EventExecutionStarted, Plugin: "p"})
+	for range sub.Events() {
+	}
+	if sub.Dropped() != 3 {
+		t.Errorf("closed subscription dropped %d, want no more events counted", sub.Dropped())
+	}
+
+	// A nil bus, as of clients outside a manager, drops everything
+	var nilBus *eventBus
+	nilBus.publish(Event{Type: EventExecutionStarted})
+}
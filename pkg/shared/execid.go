@@ -0,0 +1,62 @@
+package shared
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// executionIDMetadataKey carries the id of an execution on its Execute call, to the daemon and
+// on to the plugin
+const executionIDMetadataKey = "x-plugin-app-execution-id"
+
+// executionIDKey is the context key of the execution id
+type executionIDKey struct{}
+
+// NewExecutionID returns a short random id for an execution, easy to type after attach or
+// history show
+func NewExecutionID() (string, error) {
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate execution id: %v", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// WithExecutionID returns a context whose execution is known by id: plugins get it in the
+// metadata of the call and in the context of Execute, and the daemon records it in the history
+func WithExecutionID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, executionIDKey{}, id)
+}
+
+// ExecutionIDFromContext returns the id of the execution ctx belongs to, "" if there is none
+func ExecutionIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(executionIDKey{}).(string)
+	return id
+}
+
+// outgoingExecutionID adds the execution id carried by ctx to the metadata of the calls made with it
+func outgoingExecutionID(ctx context.Context) context.Context {
+	if id := ExecutionIDFromContext(ctx); id != "" {
+		return metadata.AppendToOutgoingContext(ctx, executionIDMetadataKey, id)
+	}
+	return ctx
+}
+
+// incomingExecutionID returns ctx knowing the id of the execution the caller named in the
+// metadata, unless it knows one already
+func incomingExecutionID(ctx context.Context) context.Context {
+	if ExecutionIDFromContext(ctx) != "" {
+		return ctx
+	}
+	if ids := metadata.ValueFromIncomingContext(ctx, executionIDMetadataKey); len(ids) == 1 {
+		return WithExecutionID(ctx, ids[0])
+	}
+	return ctx
+}
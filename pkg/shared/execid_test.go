@@ -0,0 +1,26 @@
+package shared
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestExecutionIDMetadata(t *testing.T) {
+	if ctx := outgoingExecutionID(context.Background()); ctx != context.Background() {
+		t.Error("outgoingExecutionID() changed a context without an id")
+	}
+
+	// What the caller sends is what the server side knows the execution by
+	md, _ := metadata.FromOutgoingContext(outgoingExecutionID(WithExecutionID(context.Background(), "a1b2c3")))
+	incoming := metadata.NewIncomingContext(context.Background(), md)
+	if got := ExecutionIDFromContext(incomingExecutionID(incoming)); got != "a1b2c3" {
+		t.Errorf("incoming execution id = %q, want %q", got, "a1b2c3")
+	}
+
+	// An id the server side gave the execution itself is kept
+	if got := ExecutionIDFromContext(incomingExecutionID(WithExecutionID(incoming, "d4e5f6"))); got != "d4e5f6" {
+		t.Errorf("incoming execution id = %q, want %q", got, "d4e5f6")
+	}
+}
@@ -0,0 +1,158 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// validateFallback checks the failover addresses of a remote plugin
+func (p *PluginConfig) validateFallback() error {
+	if p.FailBack < 0 {
+		return fmt.Errorf("invalid fail_back: %s", p.FailBack)
+	}
+	if len(p.FallbackAddresses) == 0 {
+		if p.FailBack > 0 {
+			return fmt.Errorf("fail_back requires fallback_addresses")
+		}
+		return nil
+	}
+	if !p.IsRemote() {
+		return fmt.Errorf("fallback_addresses is only supported for remote plugins")
+	}
+	if len(p.Addresses) > 0 {
+		return fmt.Errorf("fallback_addresses requires address, addresses are balanced instead")
+	}
+	if p.SSH != nil {
+		return fmt.Errorf("fallback_addresses can't be combined with ssh")
+	}
+	seen := map[string]bool{p.Address: true}
+	for _, addr := range p.FallbackAddresses {
+		if addr == "" || seen[addr] {
+			return fmt.Errorf("invalid fallback address %q: must be set and differ from the other addresses", addr)
+		}
+		seen[addr] = true
+	}
+	return nil
+}
+
+// failoverAddresses returns the addresses a remote plugin is connected to in order of preference:
+// its address, then the fallbacks. It is [""] for a plugin balancing over addresses.
+func (p *PluginConfig) failoverAddresses() []string {
+	return append([]string{p.Address}, p.FallbackAddresses...)
+}
+
+// Prefer makes address, one of the plugin's address and fallback addresses, the one connected to
+// first and failed back to; the others keep their order behind it
+func (p *PluginConfig) Prefer(address string) error {
+	addresses := p.failoverAddresses()
+	for i, addr := range addresses {
+		if addr != address || address == "" {
+			continue
+		}
+		p.Address = address
+		p.FallbackAddresses = append(addresses[:i:i], addresses[i+1:]...)
+		return nil
+	}
+	return fmt.Errorf("%s is not an address of the plugin", address)
+}
+
+// activeAddress returns the address a remote plugin is connected to, "" for a local or balanced one
+func (m *ManagedPlugin) activeAddress() string {
+	if !m.Config.IsRemote() {
+		return ""
+	}
+	return m.Config.failoverAddresses()[m.address]
+}
+
+// failoverRemote connects a remote plugin whose address turned unhealthy to the next of its
+// addresses that is reachable, wrapping around to the preferred one; the caller must hold pm.mu
+func (pm *PluginManager) failoverRemote(m *ManagedPlugin) {
+	addresses := m.Config.failoverAddresses()
+	if len(addresses) < 2 {
+		return
+	}
+	policy, err := NewAddressPolicy(pm.config.RemoteAllowlist)
+	if err != nil {
+		m.LastError = err
+		return
+	}
+	for i := 1; i < len(addresses); i++ {
+		next := (m.address + i) % len(addresses)
+		client, err := pm.dialRemote(m, policy, addresses[next])
+		if err != nil {
+			continue
+		}
+		m.Client.Close()
+		m.FailoverCnt++
+		pm.useAddress(m, next, client)
+		pm.events.publish(Event{Type: EventPluginRestarted, Plugin: m.Name})
+		return
+	}
+}
+
+// useAddress makes client, connected to the remote plugin's address at index, the current one;
+// the caller must hold pm.mu
+func (pm *PluginManager) useAddress(m *ManagedPlugin, index int, client *GRPCClient) {
+	m.Client = client
+	m.GRPCClient = client
+	m.address = index
+	pm.monitorHealth(m)
+	pm.watchFailBack(m)
+	pm.states.update(m, StateReady)
+}
+
+// watchFailBack probes the preferred address of a remote plugin connected to a fallback every
+// fail_back, and moves new executions back once it is reachable again. Executions in flight
+// finish on the fallback. The caller must hold pm.mu.
+func (pm *PluginManager) watchFailBack(m *ManagedPlugin) {
+	if m.stopFailBack != nil {
+		m.stopFailBack()
+		m.stopFailBack = nil
+	}
+	interval := time.Duration(m.Config.FailBack)
+	if interval <= 0 || m.address == 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(pm.ctx)
+	m.stopFailBack = cancel
+	preferred := m.Config.failoverAddresses()[0]
+	grace := m.Config.stopTimeout()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			policy, _ := NewAddressPolicy(pm.Config().RemoteAllowlist)
+			client, err := pm.dialRemote(m, policy, preferred)
+			if err != nil {
+				continue
+			}
+
+			pm.mu.Lock()
+			if ctx.Err() != nil || pm.plugins[m.Name] != m {
+				pm.mu.Unlock()
+				client.Close()
+				return
+			}
+			previous := m.GRPCClient
+			pm.useAddress(m, 0, client)
+			pm.mu.Unlock()
+
+			closeWhenIdle(previous, grace)
+			return
+		}
+	}()
+}
+
+// closeWhenIdle closes a client that was replaced once its executions finished, or after timeout
+func closeWhenIdle(c *GRPCClient, timeout time.Duration) {
+	waitIdle([]*GRPCClient{c}, timeout)
+	c.Close()
+}
@@ -0,0 +1,142 @@
+package shared
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+)
+
+func TestPluginConfig_Prefer(t *testing.T) {
+	tests := []struct {
+		name          string
+		prefer        string
+		wantAddress   string
+		wantFallbacks []string
+		wantErr       bool
+	}{
+		{
+			name:          "Fallback becomes the address",
+			prefer:        "b:1",
+			wantAddress:   "b:1",
+			wantFallbacks: []string{"a:1", "c:1"},
+		},
+		{
+			name:          "Address stays first",
+			prefer:        "a:1",
+			wantAddress:   "a:1",
+			wantFallbacks: []string{"b:1", "c:1"},
+		},
+		{
+			name:    "Unknown address",
+			prefer:  "d:1",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := PluginConfig{Type: PluginTypeRemote, Address: "a:1", FallbackAddresses: []string{"b:1", "c:1"}}
+			err := config.Prefer(tt.prefer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Prefer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if config.Address != tt.wantAddress || !reflect.DeepEqual(config.FallbackAddresses, tt.wantFallbacks) {
+				t.Errorf("Prefer() = %s %v, want %s %v", config.Address, config.FallbackAddresses, tt.wantAddress, tt.wantFallbacks)
+			}
+		})
+	}
+}
+
+// restartStubPluginServer serves the stub plugin again on addr, where an earlier server was stopped
+func restartStubPluginServer(t *testing.T, addr string) *grpc.Server {
+	t.Helper()
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	proto.RegisterPluginServer(server, &GRPCServer{Impl: stubPlugin{}})
+	StartHealthServer(server)
+	go server.Serve(listener)
+	return server
+}
+
+func TestPluginManager_FallbackAddresses(t *testing.T) {
+	preferred, preferredAddr := startStubPluginServer(t)
+	preferred.Stop()
+	fallback, fallbackAddr := startStubPluginServer(t)
+	defer fallback.Stop()
+
+	config := &AppConfig{Plugins: map[string]PluginConfig{
+		"remote": {
+			Type:              PluginTypeRemote,
+			Address:           preferredAddr,
+			FallbackAddresses: []string{fallbackAddr},
+			ConnectTimeout:    Duration(time.Second),
+			FailBack:          Duration(50 * time.Millisecond),
+		},
+	}}
+	pm := NewPluginManager(config)
+	defer pm.StopAll()
+
+	// The preferred address is down, so the plugin starts on the fallback
+	if err := pm.StartPlugin("remote", config.Plugins["remote"]); err != nil {
+		t.Fatalf("StartPlugin() error = %v", err)
+	}
+	status, _ := pm.Status("remote")
+	if status.Address != fallbackAddr || status.LastError == nil {
+		t.Fatalf("Address = %s, LastError = %v; want the fallback with the reason", status.Address, status.LastError)
+	}
+
+	// Once the preferred address is back, new executions move there
+	preferred = restartStubPluginServer(t, preferredAddr)
+	defer preferred.Stop()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		if status, _ := pm.Status("remote"); status.Address == preferredAddr {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("plugin did not fail back to the preferred address")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// An unhealthy address fails over to the next reachable one
+	sub := pm.Subscribe(0, EventPluginRestarted)
+	defer sub.Close()
+	pm.mu.Lock()
+	pm.failoverRemote(pm.plugins["remote"])
+	pm.mu.Unlock()
+	status, _ = pm.Status("remote")
+	if status.Address != fallbackAddr || status.Failovers != 1 {
+		t.Errorf("Address = %s, Failovers = %d; want the fallback after one failover", status.Address, status.Failovers)
+	}
+	if len(sub.Events()) != 1 {
+		t.Error("failover was not published")
+	}
+}
+
+func TestPluginManager_FallbackAddressesUnreachable(t *testing.T) {
+	config := PluginConfig{
+		Type:              PluginTypeRemote,
+		Address:           "127.0.0.1:1",
+		FallbackAddresses: []string{"127.0.0.1:2"},
+		ConnectTimeout:    Duration(time.Second),
+	}
+	pm := NewPluginManager(&AppConfig{Plugins: map[string]PluginConfig{"remote": config}})
+	defer pm.StopAll()
+
+	err := pm.StartPlugin("remote", config)
+	if err == nil || !strings.Contains(err.Error(), "127.0.0.1:1") || !strings.Contains(err.Error(), "127.0.0.1:2") {
+		t.Errorf("StartPlugin() error = %v, want the failure of each address", err)
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 )
 
 // HealthCheck represents the health check configuration
@@ -16,6 +18,7 @@ type HealthCheck struct {
 	MaxRetries  int
 	RetryDelay  time.Duration
 	OnUnhealthy func(error)
+	OnHealthy   func() // Called when a check passes again after OnUnhealthy
 }
 
 // DefaultHealthCheck returns the default health check configuration
@@ -27,6 +30,48 @@ func DefaultHealthCheck() HealthCheck {
 	}
 }
 
+// HealthCheckConfig is how a plugin is health checked while it runs, local or remote
+type HealthCheckConfig struct {
+	Disabled   bool     `json:"disabled,omitempty"`            // Don't health check the plugin
+	Interval   Duration `json:"interval,omitempty" min:"0"`    // How often the plugin is checked (default settings.health_check_interval)
+	Retries    int      `json:"retries,omitempty" min:"0"`     // Attempts of each check before the plugin counts as unhealthy (default 3)
+	RetryDelay Duration `json:"retry_delay,omitempty" min:"0"` // Pause between the attempts (default 5s)
+}
+
+// validate checks the health check settings
+func (h *HealthCheckConfig) validate() error {
+	if h.Interval < 0 {
+		return fmt.Errorf("invalid health_check interval: %s", h.Interval)
+	}
+	if h.Retries < 0 {
+		return fmt.Errorf("invalid health_check retries: %d", h.Retries)
+	}
+	if h.RetryDelay < 0 {
+		return fmt.Errorf("invalid health_check retry_delay: %s", h.RetryDelay)
+	}
+	return nil
+}
+
+// healthCheck returns how a plugin is health checked, false if it isn't
+func (c *AppConfig) healthCheck(plugin PluginConfig) (HealthCheck, bool) {
+	check := DefaultHealthCheck()
+	check.Interval = c.healthCheckInterval()
+	settings := plugin.HealthCheck
+	if settings == nil {
+		return check, true
+	}
+	if settings.Interval > 0 {
+		check.Interval = time.Duration(settings.Interval)
+	}
+	if settings.Retries > 0 {
+		check.MaxRetries = settings.Retries
+	}
+	if settings.RetryDelay > 0 {
+		check.RetryDelay = time.Duration(settings.RetryDelay)
+	}
+	return check, !settings.Disabled
+}
+
 // StartHealthServer starts the gRPC health checking server
 func StartHealthServer(server *grpc.Server) *health.Server {
 	healthServer := health.NewServer()
@@ -42,6 +87,7 @@ func MonitorPluginHealth(ctx context.Context, client *GRPCClient, config HealthC
 
 	healthClient := healthpb.NewHealthClient(client.conn)
 
+	unhealthy := false
 	for {
 		select {
 		case <-ctx.Done():
@@ -53,18 +99,29 @@ func MonitorPluginHealth(ctx context.Context, client *GRPCClient, config HealthC
 				resp, err := healthClient.Check(checkCtx, &healthpb.HealthCheckRequest{})
 				cancel()
 
-				if err == nil && resp.Status == healthpb.HealthCheckResponse_SERVING {
+				// Remote plugins need not serve the health service; answering at all will do
+				if (err == nil && resp.Status == healthpb.HealthCheckResponse_SERVING) || status.Code(err) == codes.Unimplemented {
 					lastErr = nil
 					break
 				}
 
+				if err == nil {
+					err = fmt.Errorf("status %s", resp.Status)
+				}
 				lastErr = fmt.Errorf("health check failed: %v", err)
 				time.Sleep(config.RetryDelay)
 			}
 
+			// Checks cut short by the monitor stopping say nothing about the plugin
+			if ctx.Err() != nil {
+				return
+			}
 			if lastErr != nil && config.OnUnhealthy != nil {
 				config.OnUnhealthy(lastErr)
+			} else if lastErr == nil && unhealthy && config.OnHealthy != nil {
+				config.OnHealthy()
 			}
+			unhealthy = lastErr != nil
 		}
 	}
 }
@@ -73,3 +130,56 @@ func MonitorPluginHealth(ctx context.Context, client *GRPCClient, config HealthC
 func (c *GRPCClient) EnableHealthCheck(ctx context.Context, config HealthCheck) {
 	go MonitorPluginHealth(ctx, c, config)
 }
+
+// ProbeResult is the outcome of a one-off health check
+type ProbeResult struct {
+	Status  string        // Serving status reported by the plugin, UNIMPLEMENTED without a health service
+	Latency time.Duration // Round trip of the health check
+}
+
+// Serving reports whether the plugin can take calls
+func (r ProbeResult) Serving() bool {
+	return r.Status == healthpb.HealthCheckResponse_SERVING.String() || r.Status == "UNIMPLEMENTED"
+}
+
+// Probe runs a single health check against the plugin
+func (c *GRPCClient) Probe(ctx context.Context) (ProbeResult, error) {
+	return c.probe(ctx, "")
+}
+
+// ProbeThroughDaemon runs a single health check of a plugin client returned by ConnectDaemon. The
+// daemon probes the plugin it keeps warm, starting it if it isn't running.
+func ProbeThroughDaemon(ctx context.Context, plugin PluginInterface) (ProbeResult, error) {
+	client, ok := plugin.(*GRPCClient)
+	if !ok || client.name == "" {
+		return ProbeResult{}, fmt.Errorf("not a daemon plugin client")
+	}
+	return client.probe(ctx, client.name)
+}
+
+// probe runs a single health check of the service, "" for the server as a whole
+func (c *GRPCClient) probe(ctx context.Context, service string) (ProbeResult, error) {
+	start := time.Now()
+	resp, err := healthpb.NewHealthClient(c.conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	result := ProbeResult{Latency: time.Since(start)}
+	switch {
+	case err == nil:
+		result.Status = resp.Status.String()
+	case status.Code(err) == codes.Unimplemented:
+		result.Status = "UNIMPLEMENTED"
+	default:
+		return result, diagnoseConnectError(c.address, err)
+	}
+	return result, nil
+}
+
+// ProbePlugin runs a single health check against a running plugin
+func (pm *PluginManager) ProbePlugin(ctx context.Context, name string) (ProbeResult, error) {
+	pm.mu.RLock()
+	plugin, exists := pm.plugins[name]
+	pm.mu.RUnlock()
+	if !exists {
+		return ProbeResult{}, fmt.Errorf("plugin %s is not running", name)
+	}
+	return plugin.GRPCClient.Probe(ctx)
+}
@@ -0,0 +1,211 @@
+package shared
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestGRPCClient_Probe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	healthServer := StartHealthServer(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	tests := []struct {
+		name        string
+		address     string
+		status      healthpb.HealthCheckResponse_ServingStatus
+		wantServing bool
+		wantErr     bool
+		errorMsg    string
+	}{
+		{
+			name:        "Serving",
+			address:     listener.Addr().String(),
+			status:      healthpb.HealthCheckResponse_SERVING,
+			wantServing: true,
+		},
+		{
+			name:    "Not serving",
+			address: listener.Addr().String(),
+			status:  healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+		{
+			name:     "Nothing listening",
+			address:  closedAddr,
+			wantErr:  true,
+			errorMsg: "connection refused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			healthServer.SetServingStatus("", tt.status)
+			client, err := NewClientWithAddress(tt.address)
+			if err != nil {
+				t.Fatalf("NewClientWithAddress() error = %v", err)
+			}
+			defer client.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			result, err := client.(*GRPCClient).Probe(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Probe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Probe() error = %v, want substring %q", err, tt.errorMsg)
+				}
+				return
+			}
+			if result.Serving() != tt.wantServing {
+				t.Errorf("Probe() status = %s, want serving %v", result.Status, tt.wantServing)
+			}
+			if result.Latency <= 0 {
+				t.Errorf("Probe() latency = %v, want positive", result.Latency)
+			}
+		})
+	}
+}
+
+func TestAppConfig_healthCheck(t *testing.T) {
+	settings := &Settings{HealthCheckInterval: Duration(time.Minute)}
+	tests := []struct {
+		name        string
+		settings    *Settings
+		healthCheck *HealthCheckConfig
+		want        HealthCheck
+		wantEnabled bool
+	}{
+		{
+			name:        "Defaults",
+			want:        DefaultHealthCheck(),
+			wantEnabled: true,
+		},
+		{
+			name:        "Interval of the settings",
+			settings:    settings,
+			want:        HealthCheck{Interval: time.Minute, MaxRetries: 3, RetryDelay: 5 * time.Second},
+			wantEnabled: true,
+		},
+		{
+			name:        "Plugin's own settings",
+			settings:    settings,
+			healthCheck: &HealthCheckConfig{Interval: Duration(time.Second), Retries: 5, RetryDelay: Duration(100 * time.Millisecond)},
+			want:        HealthCheck{Interval: time.Second, MaxRetries: 5, RetryDelay: 100 * time.Millisecond},
+			wantEnabled: true,
+		},
+		{
+			name:        "Disabled",
+			healthCheck: &HealthCheckConfig{Disabled: true},
+			want:        DefaultHealthCheck(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &AppConfig{Settings: tt.settings}
+			got, enabled := config.healthCheck(PluginConfig{HealthCheck: tt.healthCheck})
+			if enabled != tt.wantEnabled {
+				t.Errorf("healthCheck() enabled = %v, want %v", enabled, tt.wantEnabled)
+			}
+			if got.Interval != tt.want.Interval || got.MaxRetries != tt.want.MaxRetries || got.RetryDelay != tt.want.RetryDelay {
+				t.Errorf("healthCheck() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonitorPluginHealth(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	healthServer := StartHealthServer(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	client, err := NewClientWithAddress(listener.Addr().String())
+	if err != nil {
+		t.Fatalf("NewClientWithAddress() error = %v", err)
+	}
+	defer client.Close()
+
+	unhealthy := make(chan error, 10)
+	healthy := make(chan struct{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client.(*GRPCClient).EnableHealthCheck(ctx, HealthCheck{
+		Interval:    10 * time.Millisecond,
+		MaxRetries:  1,
+		OnUnhealthy: func(err error) { unhealthy <- err },
+		OnHealthy:   func() { healthy <- struct{}{} },
+	})
+
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	select {
+	case err := <-unhealthy:
+		if !strings.Contains(err.Error(), "NOT_SERVING") {
+			t.Errorf("OnUnhealthy() error = %v, want the status", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnUnhealthy was not called")
+	}
+
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	select {
+	case <-healthy:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnHealthy was not called")
+	}
+}
+
+func TestMonitorPluginHealth_noHealthService(t *testing.T) {
+	// Remote plugins need not serve the health service
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	go server.Serve(listener)
+	defer server.Stop()
+
+	client, err := NewClientWithAddress(listener.Addr().String())
+	if err != nil {
+		t.Fatalf("NewClientWithAddress() error = %v", err)
+	}
+	defer client.Close()
+
+	unhealthy := make(chan error, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	MonitorPluginHealth(ctx, client.(*GRPCClient), HealthCheck{
+		Interval:    10 * time.Millisecond,
+		MaxRetries:  1,
+		OnUnhealthy: func(err error) { unhealthy <- err },
+	})
+	select {
+	case err := <-unhealthy:
+		t.Errorf("OnUnhealthy() called with %v", err)
+	default:
+	}
+}
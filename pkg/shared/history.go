@@ -0,0 +1,327 @@
+package shared
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HistoryRecord is one execution as kept in the history
+type HistoryRecord struct {
+	Time     time.Time         `json:"time"`
+	Plugin   string            `json:"plugin"`
+	Version  string            `json:"version"`
+	Params   map[string]string `json:"params"` // Redacted, so masked values are never replayed
+	Success  bool              `json:"success"`
+	Error    string            `json:"error,omitempty"`
+	Duration Duration          `json:"duration,omitempty"` // Zero in records from before durations were kept
+
+	ID        string             `json:"id,omitempty"`       // The execution's id, see WithExecutionID
+	Metadata  map[string]string  `json:"metadata,omitempty"` // Of the execution summary, redacted
+	Metrics   map[string]float64 `json:"metrics,omitempty"`  // Of the execution summary
+	Artifacts []HistoryArtifact  `json:"artifacts,omitempty"`
+}
+
+// HistoryArtifact is an artifact an execution sent
+type HistoryArtifact struct {
+	Name string `json:"name"`
+	Size int    `json:"size"`
+	Path string `json:"path,omitempty"` // Where the CLI saved it, if it did
+}
+
+// HistoryRetention bounds what the history keeps of each plugin; zero fields keep everything
+type HistoryRetention struct {
+	MaxAge  time.Duration // Executions that started longer ago are dropped
+	MaxRuns int           // Only the latest executions are kept
+}
+
+// HistoryStore keeps the executions of each plugin as JSON lines below the state directory
+type HistoryStore struct {
+	dir       string
+	retention HistoryRetention
+}
+
+// NewHistoryStore returns a store keeping history below the state directory, without limits
+func NewHistoryStore(stateDir string) *HistoryStore {
+	return &HistoryStore{dir: filepath.Join(stateDir, "history")}
+}
+
+// HistoryStore returns the store of the configured state directory, which keeps what
+// settings.history_max_age and history_max_runs allow
+func (c *AppConfig) HistoryStore() *HistoryStore {
+	store := NewHistoryStore(c.StateDir)
+	if c.Settings != nil {
+		store.retention = HistoryRetention{MaxAge: time.Duration(c.Settings.HistoryMaxAge), MaxRuns: c.Settings.HistoryMaxRuns}
+	}
+	return store
+}
+
+// WithRetention returns a store of the same history that keeps only what retention allows
+func (s *HistoryStore) WithRetention(retention HistoryRetention) *HistoryStore {
+	return &HistoryStore{dir: s.dir, retention: retention}
+}
+
+// Append records an execution, pruning the plugin's history to what the retention keeps
+func (s *HistoryStore) Append(record HistoryRecord) error {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create history directory: %v", err)
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode history record: %v", err)
+	}
+	f, err := os.OpenFile(s.path(record.Plugin), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %v", err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write history: %v", err)
+	}
+	return s.Prune(record.Plugin)
+}
+
+// Prune drops the executions of a plugin the store's retention no longer keeps
+func (s *HistoryStore) Prune(plugin string) error {
+	if s.retention == (HistoryRetention{}) {
+		return nil
+	}
+	records, err := s.Recent(plugin, 0)
+	if err != nil {
+		return err
+	}
+	kept := s.retained(records, time.Now())
+	if len(kept) == len(records) {
+		return nil
+	}
+
+	// The history is replaced as a whole, so a reader never sees it half written
+	tmp, err := os.CreateTemp(s.dir, plugin+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to prune history: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	for i := len(kept) - 1; i >= 0; i-- {
+		data, err := json.Marshal(kept[i])
+		if err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to encode history record: %v", err)
+		}
+		w.Write(append(data, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to prune history: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to prune history: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(plugin)); err != nil {
+		return fmt.Errorf("failed to prune history: %v", err)
+	}
+	return nil
+}
+
+// retained returns the leading records, newest first, that the retention keeps at now
+func (s *HistoryStore) retained(records []HistoryRecord, now time.Time) []HistoryRecord {
+	if s.retention.MaxRuns > 0 && len(records) > s.retention.MaxRuns {
+		records = records[:s.retention.MaxRuns]
+	}
+	if s.retention.MaxAge > 0 {
+		cutoff := now.Add(-s.retention.MaxAge)
+		for i, record := range records {
+			if record.Time.Before(cutoff) {
+				return records[:i]
+			}
+		}
+	}
+	return records
+}
+
+// Recent returns up to n of the latest executions of a plugin, newest first. Records the
+// retention no longer keeps are returned until they are pruned.
+func (s *HistoryStore) Recent(plugin string, n int) ([]HistoryRecord, error) {
+	f, err := os.Open(s.path(plugin))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %v", err)
+	}
+	defer f.Close()
+
+	var records []HistoryRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var record HistoryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("failed to parse history of %s, line %d: %v", plugin, line, err)
+		}
+		records = append(records, record)
+		if n > 0 && len(records) > n {
+			records = records[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %v", err)
+	}
+
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, nil
+}
+
+func (s *HistoryStore) path(plugin string) string {
+	return filepath.Join(s.dir, plugin+".jsonl")
+}
+
+// Plugins returns the plugins with recorded executions, sorted by name
+func (s *HistoryStore) Plugins() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %v", err)
+	}
+	var plugins []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".jsonl"); ok && !entry.IsDir() {
+			plugins = append(plugins, name)
+		}
+	}
+	sort.Strings(plugins)
+	return plugins, nil
+}
+
+// HistoryQuery selects recorded executions; its zero fields don't narrow the selection
+type HistoryQuery struct {
+	ID      string    // The execution of this id
+	Plugins []string  // Of these plugins rather than all of them
+	Since   time.Time // Started at or after Since
+	Until   time.Time // Started before Until
+	Failed  bool      // Only failed executions
+	Limit   int       // Up to Limit of the latest
+}
+
+func (q HistoryQuery) matches(record HistoryRecord) bool {
+	if q.ID != "" && record.ID != q.ID {
+		return false
+	}
+	if !q.Since.IsZero() && record.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !record.Time.Before(q.Until) {
+		return false
+	}
+	return !q.Failed || !record.Success
+}
+
+// Query returns the recorded executions q selects, newest first. Executions the retention no
+// longer keeps are left out even before they are pruned.
+func (s *HistoryStore) Query(q HistoryQuery) ([]HistoryRecord, error) {
+	plugins := q.Plugins
+	if len(plugins) == 0 {
+		var err error
+		if plugins, err = s.Plugins(); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+	var selected []HistoryRecord
+	for _, plugin := range plugins {
+		records, err := s.Recent(plugin, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range s.retained(records, now) {
+			if q.matches(record) {
+				selected = append(selected, record)
+			}
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool { return selected[i].Time.After(selected[j].Time) })
+	if q.Limit > 0 && len(selected) > q.Limit {
+		selected = selected[:q.Limit]
+	}
+	return selected, nil
+}
+
+// Get returns the recorded execution of an id
+func (s *HistoryStore) Get(id string) (HistoryRecord, error) {
+	records, err := s.Query(HistoryQuery{ID: id, Limit: 1})
+	if err != nil {
+		return HistoryRecord{}, err
+	}
+	if len(records) == 0 {
+		return HistoryRecord{}, fmt.Errorf("execution %q not found in the history", id)
+	}
+	return records[0], nil
+}
+
+// PluginStats aggregates the recorded executions of a plugin
+type PluginStats struct {
+	Plugin      string
+	Runs        int
+	Successes   int
+	P50         time.Duration  // Of the executions with a recorded duration, 0 without any
+	P95         time.Duration  // Likewise
+	LastFailure *HistoryRecord // nil if none failed
+}
+
+// SuccessRate returns the share of successful runs, from 0 to 1
+func (s PluginStats) SuccessRate() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.Successes) / float64(s.Runs)
+}
+
+// Stats aggregates the executions of a plugin recorded since since, or all of them if it is zero
+func (s *HistoryStore) Stats(plugin string, since time.Time) (PluginStats, error) {
+	stats := PluginStats{Plugin: plugin}
+	records, err := s.Query(HistoryQuery{Plugins: []string{plugin}, Since: since})
+	if err != nil {
+		return stats, err
+	}
+	var durations []time.Duration
+	for i, record := range records {
+		stats.Runs++
+		if record.Success {
+			stats.Successes++
+		} else if stats.LastFailure == nil {
+			stats.LastFailure = &records[i]
+		}
+		if record.Duration > 0 {
+			durations = append(durations, time.Duration(record.Duration))
+		}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	stats.P50 = percentile(durations, 50)
+	stats.P95 = percentile(durations, 95)
+	return stats, nil
+}
+
+// percentile returns the nearest-rank percentile p of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,248 @@
+package shared
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryStore_Recent(t *testing.T) {
+	dir := t.TempDir()
+	store := NewHistoryStore(dir)
+
+	records, err := store.Recent("hello", 5)
+	if err != nil || records != nil {
+		t.Fatalf("Recent() without history = %v, %v", records, err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"a", "b", "c"} {
+		record := HistoryRecord{Time: start.Add(time.Duration(i) * time.Minute), Plugin: "hello", Version: "1.0.0", Params: map[string]string{"name": name}, Success: true}
+		if err := store.Append(record); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		n    int
+		want []string
+	}{
+		{name: "Latest first", n: 2, want: []string{"c", "b"}},
+		{name: "More than recorded", n: 10, want: []string{"c", "b", "a"}},
+		{name: "Everything", n: 0, want: []string{"c", "b", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := store.Recent("hello", tt.n)
+			if err != nil {
+				t.Fatalf("Recent() error = %v", err)
+			}
+			var got []string
+			for _, record := range records {
+				got = append(got, record.Params["name"])
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Recent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Corrupt line", func(t *testing.T) {
+		f, err := os.OpenFile(filepath.Join(dir, "history", "hello.jsonl"), os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString("{not json\n")
+		f.Close()
+		if _, err := store.Recent("hello", 1); err == nil || !strings.Contains(err.Error(), "line 4") {
+			t.Errorf("Recent() error = %v, want parse error on line 4", err)
+		}
+	})
+}
+
+func TestHistoryStore_Stats(t *testing.T) {
+	store := NewHistoryStore(t.TempDir())
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 20; i++ {
+		record := HistoryRecord{Time: start.Add(time.Duration(i) * time.Minute), Plugin: "hello", Success: i%5 != 0, Duration: Duration(time.Duration(i) * time.Second)}
+		if !record.Success {
+			record.Error = fmt.Sprintf("failure %d", i)
+		}
+		if err := store.Append(record); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	// Records from before durations were kept count as runs only
+	if err := store.Append(HistoryRecord{Time: start, Plugin: "hello", Success: true}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if err := store.Append(HistoryRecord{Time: start, Plugin: "other", Success: true}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	plugins, err := store.Plugins()
+	if err != nil || strings.Join(plugins, ",") != "hello,other" {
+		t.Errorf("Plugins() = %v, %v", plugins, err)
+	}
+
+	tests := []struct {
+		name        string
+		since       time.Time
+		runs        int
+		successes   int
+		p50, p95    time.Duration
+		lastFailure string
+	}{
+		{name: "Everything", runs: 21, successes: 17, p50: 10 * time.Second, p95: 19 * time.Second, lastFailure: "failure 20"},
+		{name: "Since", since: start.Add(16 * time.Minute), runs: 5, successes: 4, p50: 18 * time.Second, p95: 20 * time.Second, lastFailure: "failure 20"},
+		{name: "Nothing recent", since: start.Add(time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, err := store.Stats("hello", tt.since)
+			if err != nil {
+				t.Fatalf("Stats() error = %v", err)
+			}
+			if stats.Runs != tt.runs || stats.Successes != tt.successes || stats.P50 != tt.p50 || stats.P95 != tt.p95 {
+				t.Errorf("Stats() = %d runs, %d successes, p50 %v, p95 %v; want %d, %d, %v, %v",
+					stats.Runs, stats.Successes, stats.P50, stats.P95, tt.runs, tt.successes, tt.p50, tt.p95)
+			}
+			lastFailure := ""
+			if stats.LastFailure != nil {
+				lastFailure = stats.LastFailure.Error
+			}
+			if lastFailure != tt.lastFailure {
+				t.Errorf("Stats() last failure = %q, want %q", lastFailure, tt.lastFailure)
+			}
+		})
+	}
+}
+
+func TestHistoryStore_Query(t *testing.T) {
+	store := NewHistoryStore(t.TempDir())
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Minutes past start, interleaving two plugins; every third execution fails
+	for i := 1; i <= 6; i++ {
+		plugin := "hello"
+		if i%2 == 0 {
+			plugin = "addition"
+		}
+		record := HistoryRecord{Time: start.Add(time.Duration(i) * time.Minute), Plugin: plugin, Success: i%3 != 0, Params: map[string]string{"n": fmt.Sprint(i)}, ID: fmt.Sprintf("run%d", i)}
+		if err := store.Append(record); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query HistoryQuery
+		want  string
+	}{
+		{name: "Everything", want: "6,5,4,3,2,1"},
+		{name: "Plugin", query: HistoryQuery{Plugins: []string{"hello"}}, want: "5,3,1"},
+		{name: "Window", query: HistoryQuery{Since: start.Add(2 * time.Minute), Until: start.Add(5 * time.Minute)}, want: "4,3,2"},
+		{name: "Failed", query: HistoryQuery{Failed: true}, want: "6,3"},
+		{name: "Limit", query: HistoryQuery{Limit: 2}, want: "6,5"},
+		{name: "Unknown plugin", query: HistoryQuery{Plugins: []string{"missing"}}, want: ""},
+		{name: "ID", query: HistoryQuery{ID: "run4"}, want: "4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := store.Query(tt.query)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			var got []string
+			for _, record := range records {
+				got = append(got, record.Params["n"])
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("Query() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistoryStore_Get(t *testing.T) {
+	store := NewHistoryStore(t.TempDir())
+	for _, record := range []HistoryRecord{
+		{Time: time.Now().Add(-time.Minute), Plugin: "hello", Success: true, ID: "a1b2c3"},
+		{Time: time.Now(), Plugin: "addition", Success: false, ID: "d4e5f6"},
+	} {
+		if err := store.Append(record); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	record, err := store.Get("d4e5f6")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if record.Plugin != "addition" || record.Success {
+		t.Errorf("Get() = %+v, want the failed addition execution", record)
+	}
+	if _, err := store.Get("missing"); err == nil {
+		t.Error("Get() of an unknown id succeeded")
+	}
+}
+
+func TestHistoryStore_Retention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	appendRuns := func(store *HistoryStore, plugin string, ages ...time.Duration) {
+		for i, age := range ages {
+			record := HistoryRecord{Time: now.Add(-age), Plugin: plugin, Success: true, Params: map[string]string{"n": fmt.Sprint(i)}}
+			if err := store.Append(record); err != nil {
+				t.Fatalf("Append() error = %v", err)
+			}
+		}
+	}
+	recorded := func(plugin string) string {
+		records, err := NewHistoryStore(dir).Recent(plugin, 0)
+		if err != nil {
+			t.Fatalf("Recent() error = %v", err)
+		}
+		var got []string
+		for _, record := range records {
+			got = append(got, record.Params["n"])
+		}
+		return strings.Join(got, ",")
+	}
+
+	t.Run("Max runs", func(t *testing.T) {
+		appendRuns(NewHistoryStore(dir).WithRetention(HistoryRetention{MaxRuns: 2}), "runs", 4*time.Hour, 3*time.Hour, 2*time.Hour, time.Hour)
+		if got := recorded("runs"); got != "3,2" {
+			t.Errorf("kept %s, want 3,2", got)
+		}
+	})
+
+	t.Run("Max age", func(t *testing.T) {
+		appendRuns(NewHistoryStore(dir), "age", 72*time.Hour, 48*time.Hour, time.Hour)
+		store := NewHistoryStore(dir).WithRetention(HistoryRetention{MaxAge: 24 * time.Hour})
+		// Reads leave out what the retention no longer keeps before it is pruned
+		records, err := store.Query(HistoryQuery{Plugins: []string{"age"}})
+		if err != nil || len(records) != 1 {
+			t.Fatalf("Query() = %v, %v, want 1 record", records, err)
+		}
+		if got := recorded("age"); got != "2,1,0" {
+			t.Errorf("kept %s before pruning, want 2,1,0", got)
+		}
+		if err := store.Prune("age"); err != nil {
+			t.Fatalf("Prune() error = %v", err)
+		}
+		if got := recorded("age"); got != "2" {
+			t.Errorf("kept %s, want 2", got)
+		}
+	})
+
+	t.Run("Settings", func(t *testing.T) {
+		config := &AppConfig{StateDir: dir, Settings: &Settings{HistoryMaxRuns: 1}}
+		appendRuns(config.HistoryStore(), "settings", 2*time.Hour, time.Hour)
+		if got := recorded("settings"); got != "1" {
+			t.Errorf("kept %s, want 1", got)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// includeFragments merges the plugins of the config fragments that config.Include names into
+// config. Patterns are globs relative to the directory of the config file, so that a conf.d
+// directory can sit next to it, or to the working directory for a remote configuration.
+// Fragments hold plugins only, as {"plugins": {...}}, and each plugin may be defined once across
+// the config file and its fragments. Violations of the schema in fragments are returned as
+// problems.
+func includeFragments(config *AppConfig, configPath string) ([]ConfigProblem, error) {
+	if len(config.Include) == 0 {
+		return nil, nil
+	}
+	sources := make(map[string]string, len(config.Plugins))
+	plugins := make(map[string]PluginConfig, len(config.Plugins))
+	for name, plugin := range config.Plugins {
+		sources[name] = configPath
+		plugins[name] = plugin
+	}
+
+	var problems []ConfigProblem
+	dir := filepath.Dir(configPath)
+	if IsRemoteConfig(configPath) {
+		dir = "." // Fragments of a remote configuration are local to the host
+	}
+	for _, pattern := range config.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include %q: %v", pattern, err)
+		}
+		for _, path := range paths {
+			fragment, violations, err := loadFragment(path)
+			if err != nil {
+				return nil, err
+			}
+			problems = append(problems, violations...)
+			names := make([]string, 0, len(fragment))
+			for name := range fragment {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if source, ok := sources[name]; ok {
+					return nil, fmt.Errorf("plugin %q is defined in both %s and %s", name, source, path)
+				}
+				sources[name] = path
+				plugins[name] = fragment[name]
+			}
+		}
+	}
+	config.Plugins = plugins
+	return problems, nil
+}
+
+// loadFragment reads the plugins of a config fragment and its violations of the schema, decrypting
+// enc: values like LoadRawConfig
+func loadFragment(path string) (map[string]PluginConfig, []ConfigProblem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read included config %s: %v", path, err)
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse included config %s: %v", path, err)
+	}
+	for key := range keys {
+		if key != "plugins" {
+			return nil, nil, fmt.Errorf("included config %s may only define plugins, not %s", path, key)
+		}
+	}
+	problems, err := checkConfigSchema(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse included config %s: %v", path, err)
+	}
+	for i := range problems {
+		problems[i].File = path
+	}
+
+	var fragment AppConfig
+	if err := json.Unmarshal(data, &fragment); err != nil {
+		if len(problems) > 0 {
+			return nil, nil, ConfigErrors(problems)
+		}
+		return nil, nil, fmt.Errorf("failed to parse included config %s: %v", path, err)
+	}
+	if err := decryptConfig(&fragment); err != nil {
+		return nil, nil, fmt.Errorf("included config %s: %v", path, err)
+	}
+	return fragment.Plugins, problems, nil
+}
@@ -0,0 +1,104 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig_include(t *testing.T) {
+	const main = `{"include": ["plugins.d/*.json"], "plugins": {"main": {"type": "remote", "address": "localhost:9001"}}}`
+	tests := []struct {
+		name      string
+		fragments map[string]string
+		want      []string
+		wantErr   string
+	}{
+		{
+			name: "Fragments merged",
+			fragments: map[string]string{
+				"a.json": `{"plugins": {"first": {"type": "remote", "address": "localhost:9002"}}}`,
+				"b.json": `{"plugins": {"second": {"type": "remote", "address": "localhost:9003"}}}`,
+			},
+			want: []string{"first", "main", "second"},
+		},
+		{
+			name: "No fragments",
+			want: []string{"main"},
+		},
+		{
+			name: "Plugin of the config file redefined",
+			fragments: map[string]string{
+				"a.json": `{"plugins": {"main": {"type": "remote", "address": "localhost:9002"}}}`,
+			},
+			wantErr: `plugin "main" is defined in both`,
+		},
+		{
+			name: "Plugin defined by two fragments",
+			fragments: map[string]string{
+				"a.json": `{"plugins": {"first": {"type": "remote", "address": "localhost:9002"}}}`,
+				"b.json": `{"plugins": {"first": {"type": "remote", "address": "localhost:9003"}}}`,
+			},
+			wantErr: "a.json and ",
+		},
+		{
+			name:      "Fragment with settings",
+			fragments: map[string]string{"a.json": `{"read_only": true}`},
+			wantErr:   "may only define plugins, not read_only",
+		},
+		{
+			name:      "Malformed fragment",
+			fragments: map[string]string{"a.json": `{"plugins": `},
+			wantErr:   "failed to parse included config",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.Mkdir(filepath.Join(dir, "plugins.d"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			for name, content := range tt.fragments {
+				if err := os.WriteFile(filepath.Join(dir, "plugins.d", name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			path := filepath.Join(dir, "config.json")
+			if err := os.WriteFile(path, []byte(main), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := LoadConfig(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			var got []string
+			for name := range config.Plugins {
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("plugins = %v, want %v", got, tt.want)
+			}
+
+			// Saving the raw configuration keeps the fragments out of the config file
+			raw, err := LoadRawConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(raw.Plugins) != 1 {
+				t.Errorf("raw plugins = %v, want only main", raw.Plugins)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// InfoCacheTTL is how long the info a plugin reported is used before it is queried again
+const InfoCacheTTL = time.Hour
+
+// InfoCache keeps the info plugins reported below the state directory, so that shell completion
+// doesn't have to start a plugin on every keystroke
+type InfoCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// NewInfoCache returns a cache keeping plugin info below the state directory for InfoCacheTTL
+func NewInfoCache(stateDir string) *InfoCache {
+	return &InfoCache{dir: filepath.Join(stateDir, "cache", "info"), ttl: InfoCacheTTL}
+}
+
+// Get returns the cached info of a plugin, or nil if there is none or it is stale: older than the
+// cache's TTL, or than the binary or path the plugin is started from
+func (c *InfoCache) Get(plugin string, config PluginConfig) *PluginInfo {
+	path := filepath.Join(c.dir, plugin+".json")
+	cached, err := os.Stat(path)
+	if err != nil || time.Since(cached.ModTime()) > c.ttl {
+		return nil
+	}
+	if !config.IsRemote() && config.Path != "" {
+		if source, err := os.Stat(config.Path); err == nil && source.ModTime().After(cached.ModTime()) {
+			return nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var info PluginInfo
+	if json.Unmarshal(data, &info) != nil {
+		return nil
+	}
+	return &info
+}
+
+// Put caches the info a plugin reported
+func (c *InfoCache) Put(plugin string, info *PluginInfo) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create info cache directory: %v", err)
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to encode plugin info: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, plugin+".json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write info cache: %v", err)
+	}
+	return nil
+}
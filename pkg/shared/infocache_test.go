@@ -0,0 +1,55 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInfoCache(t *testing.T) {
+	stateDir := t.TempDir()
+	binary := filepath.Join(stateDir, "hello")
+	if err := os.WriteFile(binary, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := PluginConfig{Type: PluginTypeBinary, Path: binary}
+	info := &PluginInfo{Name: "hello", Version: "1.0.0", ParameterSchema: map[string]ParameterSpec{
+		"language": {Name: "language", AllowedValues: []string{"en", "de"}},
+	}}
+
+	cache := NewInfoCache(stateDir)
+	if got := cache.Get("hello", config); got != nil {
+		t.Fatalf("Get() before Put() = %+v, want nil", got)
+	}
+	// The binary predates the cached info
+	old := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(binary, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Put("hello", info); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	got := cache.Get("hello", config)
+	if got == nil || got.Version != "1.0.0" || len(got.ParameterSchema["language"].AllowedValues) != 2 {
+		t.Fatalf("Get() = %+v, want the cached info", got)
+	}
+
+	t.Run("Stale after the TTL", func(t *testing.T) {
+		expiring := &InfoCache{dir: cache.dir, ttl: time.Nanosecond}
+		time.Sleep(time.Millisecond)
+		if got := expiring.Get("hello", config); got != nil {
+			t.Errorf("Get() = %+v, want nil", got)
+		}
+	})
+
+	t.Run("Stale after the binary changed", func(t *testing.T) {
+		later := time.Now().Add(time.Minute)
+		if err := os.Chtimes(binary, later, later); err != nil {
+			t.Fatal(err)
+		}
+		if got := cache.Get("hello", config); got != nil {
+			t.Errorf("Get() = %+v, want nil", got)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package shared
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// ServerInterceptors are unary and stream interceptors for a plugin's gRPC server. Sets are
+// chained in the order they are added, the first running outermost.
+type ServerInterceptors struct {
+	Unary  []grpc.UnaryServerInterceptor
+	Stream []grpc.StreamServerInterceptor
+}
+
+// Add returns the chain with other's interceptors running after i's
+func (i ServerInterceptors) Add(other ServerInterceptors) ServerInterceptors {
+	return ServerInterceptors{
+		Unary:  append(append([]grpc.UnaryServerInterceptor{}, i.Unary...), other.Unary...),
+		Stream: append(append([]grpc.StreamServerInterceptor{}, i.Stream...), other.Stream...),
+	}
+}
+
+// ServerOptions returns the server options installing the chain
+func (i ServerInterceptors) ServerOptions() []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if len(i.Unary) > 0 {
+		opts = append(opts, grpc.ChainUnaryInterceptor(i.Unary...))
+	}
+	if len(i.Stream) > 0 {
+		opts = append(opts, grpc.ChainStreamInterceptor(i.Stream...))
+	}
+	return opts
+}
+
+// ClientInterceptors are unary and stream interceptors for the host's connections to plugins
+type ClientInterceptors struct {
+	Unary  []grpc.UnaryClientInterceptor
+	Stream []grpc.StreamClientInterceptor
+}
+
+// Add returns the chain with other's interceptors running after i's
+func (i ClientInterceptors) Add(other ClientInterceptors) ClientInterceptors {
+	return ClientInterceptors{
+		Unary:  append(append([]grpc.UnaryClientInterceptor{}, i.Unary...), other.Unary...),
+		Stream: append(append([]grpc.StreamClientInterceptor{}, i.Stream...), other.Stream...),
+	}
+}
+
+// DialOptions returns the dial options installing the chain
+func (i ClientInterceptors) DialOptions() []grpc.DialOption {
+	var opts []grpc.DialOption
+	if len(i.Unary) > 0 {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(i.Unary...))
+	}
+	if len(i.Stream) > 0 {
+		opts = append(opts, grpc.WithChainStreamInterceptor(i.Stream...))
+	}
+	return opts
+}
+
+// CallTimeoutInterceptors applies a deadline to every call, or does nothing if timeout is zero
+func CallTimeoutInterceptors(timeout time.Duration) ClientInterceptors {
+	if timeout <= 0 {
+		return ClientInterceptors{}
+	}
+	unary := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		s, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		return &deadlineStream{ClientStream: s, cancel: cancel}, nil
+	}
+	return ClientInterceptors{
+		Unary:  []grpc.UnaryClientInterceptor{unary},
+		Stream: []grpc.StreamClientInterceptor{stream},
+	}
+}
+
+// deadlineStream releases its deadline once the stream has ended
+type deadlineStream struct {
+	grpc.ClientStream
+	cancel context.CancelFunc
+}
+
+func (s *deadlineStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.cancel()
+	}
+	return err
+}
+
+// UseInterceptors adds interceptors to the connections of plugins started from now on. They
+// run inside the manager's defaults, so a retry interceptor stays within the call timeout.
+func (pm *PluginManager) UseInterceptors(interceptors ClientInterceptors) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.interceptors = pm.interceptors.Add(interceptors)
+}
+
+// clientInterceptors returns the manager's default interceptors for a plugin followed by the registered ones
+func (pm *PluginManager) clientInterceptors(config PluginConfig) ClientInterceptors {
+	return CallTimeoutInterceptors(time.Duration(config.CallTimeout)).Add(pm.interceptors)
+}
@@ -0,0 +1,152 @@
+package shared
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServerInterceptors_RunAfterAuth(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	var mu sync.Mutex
+	var calls []string
+	logging := ServerInterceptors{
+		Unary: []grpc.UnaryServerInterceptor{func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			mu.Lock()
+			calls = append(calls, info.FullMethod)
+			mu.Unlock()
+			return handler(ctx, req)
+		}},
+	}
+	server := grpc.NewServer(AuthInterceptors("secret").Add(logging).ServerOptions()...)
+	proto.RegisterPluginServer(server, &GRPCServer{Impl: stubPlugin{}})
+	go server.Serve(listener)
+	defer server.Stop()
+
+	tests := []struct {
+		name      string
+		token     string
+		wantCode  codes.Code
+		wantCalls int
+	}{
+		{name: "Rejected before the chain", token: "wrong", wantCode: codes.Unauthenticated, wantCalls: 0},
+		{name: "Authenticated call reaches the chain", token: "secret", wantCode: codes.OK, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClientWithAddress(listener.Addr().String(), WithAuthToken(tt.token))
+			if err != nil {
+				t.Fatalf("NewClientWithAddress() error = %v", err)
+			}
+			defer client.Close()
+			mu.Lock()
+			calls = nil
+			mu.Unlock()
+
+			_, err = client.GetInfo(context.Background())
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("GetInfo() code = %v, want %v", got, tt.wantCode)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(calls) != tt.wantCalls {
+				t.Errorf("interceptor saw %v, want %d call(s)", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestPluginManager_UseInterceptors(t *testing.T) {
+	server, addr := startStubPluginServer(t)
+	defer server.Stop()
+
+	var mu sync.Mutex
+	var methods []string
+	record := func(method string) {
+		mu.Lock()
+		defer mu.Unlock()
+		methods = append(methods, method)
+	}
+
+	config := PluginConfig{Type: PluginTypeRemote, Address: addr, CallTimeout: Duration(5 * time.Second)}
+	pm := NewPluginManager(&AppConfig{Plugins: map[string]PluginConfig{"stub": config}})
+	defer pm.StopAll()
+	pm.UseInterceptors(ClientInterceptors{
+		Unary: []grpc.UnaryClientInterceptor{func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			// The manager's call timeout runs outside registered interceptors
+			if _, ok := ctx.Deadline(); !ok {
+				t.Errorf("%s: no call deadline inside the chain", method)
+			}
+			record(method)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}},
+		Stream: []grpc.StreamClientInterceptor{func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			record(method)
+			return streamer(ctx, desc, cc, method, opts...)
+		}},
+	})
+
+	if err := pm.StartPlugin("stub", config); err != nil {
+		t.Fatalf("StartPlugin() error = %v", err)
+	}
+	plugin, err := pm.GetPlugin("stub")
+	if err != nil {
+		t.Fatalf("GetPlugin() error = %v", err)
+	}
+	if err := plugin.Execute(context.Background(), nil, nil); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	seen := make(map[string]bool)
+	for _, method := range methods {
+		seen[method] = true
+	}
+	for _, want := range []string{"/plugin.Plugin/GetInfo", "/plugin.Plugin/Execute"} {
+		if !seen[want] {
+			t.Errorf("interceptors saw %v, missing %s", methods, want)
+		}
+	}
+}
+
+func TestCallTimeoutInterceptors(t *testing.T) {
+	tests := []struct {
+		name         string
+		timeout      time.Duration
+		wantDeadline bool
+	}{
+		{name: "No timeout", timeout: 0, wantDeadline: false},
+		{name: "Timeout", timeout: time.Second, wantDeadline: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := CallTimeoutInterceptors(tt.timeout)
+			if !tt.wantDeadline {
+				if len(chain.Unary) != 0 || len(chain.Stream) != 0 {
+					t.Errorf("CallTimeoutInterceptors(0) installed interceptors")
+				}
+				return
+			}
+			invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				if _, ok := ctx.Deadline(); !ok {
+					t.Errorf("call has no deadline")
+				}
+				return nil
+			}
+			if err := chain.Unary[0](context.Background(), "/plugin.Plugin/GetInfo", nil, nil, nil, invoker); err != nil {
+				t.Errorf("interceptor error = %v", err)
+			}
+		})
+	}
+}
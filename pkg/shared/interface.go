@@ -2,14 +2,19 @@ package shared
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"net"
+	"os"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/example/grpc-plugin-app/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 // Version information
@@ -44,6 +49,10 @@ type PluginInfo struct {
 	Version         string
 	Description     string
 	ParameterSchema map[string]ParameterSpec
+	ResultSchema    map[string]ResultFieldSpec
+	ProtocolVersion int      // 0 for plugins predating protocol versions
+	Features        []string // Host features the plugin relies on
+	SideEffectFree  bool     // Executions only compute a result and may be replayed, e.g. by regress
 }
 
 // ParameterSpec describes a plugin parameter
@@ -56,18 +65,30 @@ type ParameterSpec struct {
 	AllowedValues []string
 }
 
+// ResultFieldSpec describes a field of a plugin's structured result
+type ResultFieldSpec struct {
+	Name        string
+	Description string
+	Required    bool
+	Type        string
+}
+
 // Progress represents execution progress information
 type Progress struct {
 	PercentComplete float32
 	Stage           string
 	CurrentStep     int32
 	TotalSteps      int32
+	Rate            float64       // Work units completed per second, 0 if not reported
+	StageElapsed    time.Duration // Time spent in the current stage
+	Remaining       time.Duration // Estimated time left, 0 if unknown
 }
 
 // OutputHandler handles different types of plugin output
 type OutputHandler interface {
 	OnOutput(msg string) error
 	OnProgress(progress Progress) error
+	OnResult(result map[string]interface{}) error
 	OnError(code, message, details string) error
 }
 
@@ -109,17 +130,31 @@ func (s *GRPCServer) GetInfo(ctx context.Context, req *proto.InfoRequest) (*prot
 		}
 	}
 
+	resultSchema := make(map[string]*proto.ResultFieldSpec)
+	for name, spec := range info.ResultSchema {
+		resultSchema[name] = &proto.ResultFieldSpec{
+			Name:        spec.Name,
+			Description: spec.Description,
+			Required:    spec.Required,
+			Type:        spec.Type,
+		}
+	}
+
 	return &proto.PluginInfo{
-		Name:           info.Name,
-		Version:        info.Version,
-		Description:    info.Description,
-		ParameterSpecs: paramSpecs,
+		Name:            info.Name,
+		Version:         info.Version,
+		Description:     info.Description,
+		ParameterSpecs:  paramSpecs,
+		ResultSchema:    resultSchema,
+		ProtocolVersion: uint32(info.ProtocolVersion),
+		Features:        info.Features,
+		SideEffectFree:  info.SideEffectFree,
 	}, nil
 }
 
 // Execute implements the Execute RPC method
 func (s *GRPCServer) Execute(req *proto.ExecuteRequest, stream proto.Plugin_ExecuteServer) error {
-	ctx := stream.Context()
+	ctx := incomingExecutionID(stream.Context())
 	s.wg.Add(1)
 	defer s.wg.Done()
 
@@ -135,17 +170,28 @@ func (s *GRPCServer) Execute(req *proto.ExecuteRequest, stream proto.Plugin_Exec
 		})
 	}
 
-	// Create an output handler that sends messages through the stream
-	handler := &grpcOutputHandler{stream: stream}
+	// Create an output handler that sends messages through the stream, one queue per channel
+	mux := newChannelMux(ctx, stream, req.Channels)
+	handler := &grpcOutputHandler{mux: mux}
 
 	// Execute the plugin
-	if err := s.Impl.Execute(ctx, req.Params, handler); err != nil {
+	err := s.Impl.Execute(ctx, req.Params, handler)
+	if closeErr := mux.close(); closeErr != nil && err == nil {
+		return closeErr
+	}
+	if err != nil {
 		// Only send error if it hasn't been sent through the handler
 		if _, ok := err.(*handledError); !ok {
+			code := "EXECUTION_ERROR"
+			// Executions the daemon's queue gave up on keep their code for the caller
+			var queued *QueueTimeoutError
+			if errors.As(err, &queued) {
+				code = ErrorCodeQueueTimeout
+			}
 			return stream.Send(&proto.ExecuteOutput{
 				Content: &proto.ExecuteOutput_Error{
 					Error: &proto.Error{
-						Code:    "EXECUTION_ERROR",
+						Code:    code,
 						Message: err.Error(),
 					},
 				},
@@ -166,13 +212,13 @@ func (e *handledError) Error() string {
 	return e.err.Error()
 }
 
-// grpcOutputHandler implements OutputHandler for gRPC streaming
+// grpcOutputHandler implements OutputHandler and ChannelHandler for gRPC streaming
 type grpcOutputHandler struct {
-	stream proto.Plugin_ExecuteServer
+	mux *channelMux
 }
 
 func (h *grpcOutputHandler) OnOutput(msg string) error {
-	return h.stream.Send(&proto.ExecuteOutput{
+	return h.mux.send(proto.Channel_CHANNEL_OUTPUT, &proto.ExecuteOutput{
 		Content: &proto.ExecuteOutput_Output{
 			Output: msg,
 		},
@@ -180,20 +226,35 @@ func (h *grpcOutputHandler) OnOutput(msg string) error {
 }
 
 func (h *grpcOutputHandler) OnProgress(p Progress) error {
-	return h.stream.Send(&proto.ExecuteOutput{
+	return h.mux.send(proto.Channel_CHANNEL_CONTROL, &proto.ExecuteOutput{
 		Content: &proto.ExecuteOutput_Progress{
 			Progress: &proto.Progress{
 				PercentComplete: p.PercentComplete,
 				Stage:           p.Stage,
 				CurrentStep:     p.CurrentStep,
 				TotalSteps:      p.TotalSteps,
+				UnitsPerSecond:  p.Rate,
+				StageElapsedMs:  p.StageElapsed.Milliseconds(),
+				RemainingMs:     p.Remaining.Milliseconds(),
 			},
 		},
 	})
 }
 
+func (h *grpcOutputHandler) OnResult(result map[string]interface{}) error {
+	data, err := structpb.NewStruct(result)
+	if err != nil {
+		return fmt.Errorf("invalid result: %v", err)
+	}
+	return h.mux.send(proto.Channel_CHANNEL_OUTPUT, &proto.ExecuteOutput{
+		Content: &proto.ExecuteOutput_Result{
+			Result: data,
+		},
+	})
+}
+
 func (h *grpcOutputHandler) OnError(code, message, details string) error {
-	err := h.stream.Send(&proto.ExecuteOutput{
+	err := h.mux.send(proto.Channel_CHANNEL_OUTPUT, &proto.ExecuteOutput{
 		Content: &proto.ExecuteOutput_Error{
 			Error: &proto.Error{
 				Code:    code,
@@ -208,6 +269,38 @@ func (h *grpcOutputHandler) OnError(code, message, details string) error {
 	return &handledError{fmt.Errorf("%s: %s", code, message)}
 }
 
+func (h *grpcOutputHandler) OnLog(level, message string) error {
+	return h.mux.send(proto.Channel_CHANNEL_LOGS, &proto.ExecuteOutput{
+		Content: &proto.ExecuteOutput_Log{
+			Log: &proto.LogEntry{Level: level, Message: message},
+		},
+	})
+}
+
+func (h *grpcOutputHandler) OnArtifact(name string, data []byte, last bool) error {
+	return h.mux.send(proto.Channel_CHANNEL_ARTIFACTS, &proto.ExecuteOutput{
+		Content: &proto.ExecuteOutput_Artifact{
+			Artifact: &proto.Artifact{Name: name, Data: data, Last: last},
+		},
+	})
+}
+
+func (h *grpcOutputHandler) OnPrompt(id, message string) error {
+	return h.mux.send(proto.Channel_CHANNEL_CONTROL, &proto.ExecuteOutput{
+		Content: &proto.ExecuteOutput_Prompt{
+			Prompt: &proto.Prompt{Id: id, Message: message},
+		},
+	})
+}
+
+func (h *grpcOutputHandler) OnMetric(name string, value float64, labels map[string]string) error {
+	return h.mux.send(proto.Channel_CHANNEL_METRICS, &proto.ExecuteOutput{
+		Content: &proto.ExecuteOutput_Metric{
+			Metric: &proto.Metric{Name: name, Value: value, Labels: labels},
+		},
+	})
+}
+
 // ReportExecutionSummary implements the ReportExecutionSummary RPC method
 func (s *GRPCServer) ReportExecutionSummary(ctx context.Context, req *proto.SummaryRequest) (*proto.SummaryResponse, error) {
 	summary, err := s.Impl.ReportExecutionSummary(
@@ -239,15 +332,30 @@ func (s *GRPCServer) ReportExecutionSummary(ctx context.Context, req *proto.Summ
 	}, nil
 }
 
-// StartPluginServer starts the gRPC server for the plugin
-func StartPluginServer(impl PluginInterface, port int) (chan struct{}, error) {
+// StartPluginServer starts the gRPC server for the plugin; interceptors run after the token check
+func StartPluginServer(impl PluginInterface, port int, interceptors ...ServerInterceptors) (chan struct{}, error) {
 	// Listen on the specified port
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	listener, err := Listen(port)
 	if err != nil {
-		return nil, fmt.Errorf("failed to listen on port %d: %v", port, err)
+		return nil, err
 	}
 
-	server := grpc.NewServer()
+	tlsOpts, err := ServerTLSFromEnv()
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	limitOpts, err := ServerLimitsFromEnv()
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	chain := AuthInterceptors(os.Getenv(AuthTokenEnvVar))
+	for _, set := range interceptors {
+		chain = chain.Add(set)
+	}
+	serverOpts := append(chain.ServerOptions(), tlsOpts...)
+	server := grpc.NewServer(append(serverOpts, limitOpts...)...)
 	done := make(chan struct{})
 	grpcServer := &GRPCServer{
 		Impl:   impl,
@@ -258,7 +366,7 @@ func StartPluginServer(impl PluginInterface, port int) (chan struct{}, error) {
 
 	go func() {
 		if err := server.Serve(listener); err != nil {
-			log.Printf("Server error: %v\n", err)
+			Logger(LogServer).Error("serving failed", "error", err)
 		}
 	}()
 
@@ -273,33 +381,61 @@ func StartPluginServer(impl PluginInterface, port int) (chan struct{}, error) {
 }
 
 // NewPluginClient creates a new plugin client
-func NewPluginClient(port int) (PluginInterface, error) {
-	address := fmt.Sprintf("localhost:%d", port)
-	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewPluginClient(port int, opts ...grpc.DialOption) (PluginInterface, error) {
+	return NewClientWithAddress(fmt.Sprintf("localhost:%d", port), opts...)
+}
+
+// NewClientWithAddress creates a plugin client for any gRPC target
+func NewClientWithAddress(address string, opts ...grpc.DialOption) (PluginInterface, error) {
+	dialOpts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	conn, err := grpc.Dial(address, dialOpts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to port %d: %v", port, err)
+		return nil, fmt.Errorf("failed to connect to %s: %v", address, err)
 	}
 
 	return &GRPCClient{
-		client: proto.NewPluginClient(conn),
-		conn:   conn,
+		client:  proto.NewPluginClient(conn),
+		conn:    conn,
+		address: address,
 	}, nil
 }
 
 // GRPCClient implements the PluginInterface for the client side
 type GRPCClient struct {
-	client proto.PluginClient
-	conn   *grpc.ClientConn
-	name   string
-	info   *PluginInfo
+	client           proto.PluginClient
+	conn             *grpc.ClientConn
+	address          string
+	name             string
+	infoMu           sync.Mutex
+	info             *PluginInfo // Cached by GetInfo, which concurrent executions share
+	resultValidation ResultValidationMode
+	affinityKey      string
+	watchdog         *WatchdogConfig
+	executionTimeout time.Duration
+	infoTimeout      time.Duration // Deadline of GetInfo, 0 for none
+	events           *eventBus     // Where executions are published, if anywhere
+	dumpDir          string
+	channels         map[string]ChannelFlow
+	expectedVersion  *VersionConstraint // Range the plugin's version must be in, nil for any
+	versionCheck     VersionCheckMode
+	versionWarned    sync.Once
+	inflight         executions
 }
 
 // GetInfo retrieves plugin information
 func (c *GRPCClient) GetInfo(ctx context.Context) (*PluginInfo, error) {
-	if c.info != nil {
-		return c.info, nil
+	c.infoMu.Lock()
+	cached := c.info
+	c.infoMu.Unlock()
+	if cached != nil {
+		return cached, nil
 	}
 
+	if c.infoTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.infoTimeout)
+		defer cancel()
+	}
 	resp, err := c.client.GetInfo(ctx, &proto.InfoRequest{})
 	if err != nil {
 		return nil, err
@@ -317,13 +453,32 @@ func (c *GRPCClient) GetInfo(ctx context.Context) (*PluginInfo, error) {
 		}
 	}
 
-	c.info = &PluginInfo{
+	resultSchema := make(map[string]ResultFieldSpec)
+	for name, spec := range resp.ResultSchema {
+		resultSchema[name] = ResultFieldSpec{
+			Name:        spec.Name,
+			Description: spec.Description,
+			Required:    spec.Required,
+			Type:        spec.Type,
+		}
+	}
+
+	info := &PluginInfo{
 		Name:            resp.Name,
 		Version:         resp.Version,
 		Description:     resp.Description,
 		ParameterSchema: paramSchema,
+		ResultSchema:    resultSchema,
+		ProtocolVersion: int(resp.ProtocolVersion),
+		Features:        resp.Features,
+		SideEffectFree:  resp.SideEffectFree,
 	}
 
+	c.infoMu.Lock()
+	defer c.infoMu.Unlock()
+	if c.info == nil {
+		c.info = info
+	}
 	return c.info, nil
 }
 
@@ -353,6 +508,9 @@ func (c *GRPCClient) ValidateParameters(params map[string]string) error {
 					}
 				}
 				if !valid {
+					if spec.IsSecret() {
+						return fmt.Errorf("invalid value for %s (must be one of the allowed values)", name)
+					}
 					return fmt.Errorf("invalid value for %s: %s (allowed values: %v)", name, value, spec.AllowedValues)
 				}
 			}
@@ -364,20 +522,92 @@ func (c *GRPCClient) ValidateParameters(params map[string]string) error {
 	return nil
 }
 
-// Execute calls the Execute RPC method
+// Execute calls the Execute RPC method, under the plugin's execution_timeout and watchdog if
+// they are configured
 func (c *GRPCClient) Execute(ctx context.Context, params map[string]string, handler OutputHandler) error {
-	stream, err := c.client.Execute(ctx, &proto.ExecuteRequest{
-		Params: params,
+	started := time.Now()
+	c.events.publish(Event{Type: EventExecutionStarted, Plugin: c.name, Time: started})
+	err := withTimeout(ctx, c.name, c.executionTimeout, func(ctx context.Context) error {
+		return c.executeWatched(ctx, params, handler)
+	})
+	c.events.publish(Event{Type: EventExecutionFinished, Plugin: c.name, Err: err, Duration: time.Since(started)})
+	return err
+}
+
+// executeWatched executes under the plugin's watchdog if one is configured
+func (c *GRPCClient) executeWatched(ctx context.Context, params map[string]string, handler OutputHandler) error {
+	if c.watchdog == nil {
+		return c.execute(ctx, params, handler)
+	}
+	ctx, w := c.startWatchdog(ctx)
+	err := c.execute(ctx, params, watchedHandler{OutputHandler: handler, watchdog: w})
+	if hang := w.finish(); hang != nil {
+		return hang
+	}
+	return err
+}
+
+// execute streams one execution to the handler
+func (c *GRPCClient) execute(ctx context.Context, params map[string]string, handler OutputHandler) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer c.inflight.track(cancel)()
+
+	// Route executions with the same affinity value to the same replica
+	if value, ok := params[c.affinityKey]; ok && c.affinityKey != "" {
+		ctx = WithAffinity(ctx, value)
+	}
+
+	info, err := c.GetInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get plugin info: %v", err)
+	}
+	if err := c.checkVersion(info); err != nil {
+		return err
+	}
+	typedParams, err := TypedParams(info.ParameterSchema, params)
+	if err != nil {
+		return fmt.Errorf("failed to convert parameters: %v", err)
+	}
+
+	stream, err := c.client.Execute(outgoingExecutionID(ctx), &proto.ExecuteRequest{
+		Params:      params,
+		TypedParams: typedParams,
+		Channels:    channelFlows(c.channels),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to start execution: %v", err)
 	}
+	// The daemon names journaled executions in the header, which comes before any output
+	if started := detachableFromContext(ctx); started != nil {
+		if header, err := stream.Header(); err == nil {
+			if ids := header.Get(daemonExecutionKey); len(ids) == 1 {
+				started(ids[0])
+			}
+		}
+	}
+	return c.receive(ctx, stream, handler, cancel)
+}
+
+// receive hands the output of an execution's stream to the handler until it ends
+func (c *GRPCClient) receive(ctx context.Context, stream proto.Plugin_ExecuteClient, handler OutputHandler, cancel context.CancelFunc) error {
+	// Artifacts are handed over on their own goroutine so a slow consumer doesn't hold up
+	// progress and output arriving behind them
+	channels := asChannelHandler(handler)
+	artifacts := newArtifactPump(channels, resolveFlow(proto.Channel_CHANNEL_ARTIFACTS, channelFlows(c.channels)).Buffer, cancel)
+	defer artifacts.close()
 
 	for {
 		resp, err := stream.Recv()
 		if err != nil {
 			if err.Error() == "EOF" {
-				return nil
+				return artifacts.close()
+			}
+			if pumpErr := artifacts.close(); pumpErr != nil {
+				return pumpErr
+			}
+			if status.Code(err) == codes.ResourceExhausted {
+				return fmt.Errorf("error receiving output: %v (raise max_recv_message_size for this plugin)", err)
 			}
 			return fmt.Errorf("error receiving output: %v", err)
 		}
@@ -395,11 +625,66 @@ func (c *GRPCClient) Execute(ctx context.Context, params map[string]string, hand
 				Stage:           content.Progress.Stage,
 				CurrentStep:     content.Progress.CurrentStep,
 				TotalSteps:      content.Progress.TotalSteps,
+				Rate:            content.Progress.UnitsPerSecond,
+				StageElapsed:    time.Duration(content.Progress.StageElapsedMs) * time.Millisecond,
+				Remaining:       time.Duration(content.Progress.RemainingMs) * time.Millisecond,
 			}); err != nil {
 				return fmt.Errorf("error handling progress: %v", err)
 			}
+		case *proto.ExecuteOutput_Result:
+			result := content.Result.AsMap()
+			if err := c.checkResult(ctx, result, handler); err != nil {
+				return err
+			}
+			if err := handler.OnResult(result); err != nil {
+				return fmt.Errorf("error handling result: %v", err)
+			}
+		case *proto.ExecuteOutput_Log:
+			if err := channels.OnLog(content.Log.Level, content.Log.Message); err != nil {
+				return fmt.Errorf("error handling log: %v", err)
+			}
+		case *proto.ExecuteOutput_Prompt:
+			if err := channels.OnPrompt(content.Prompt.Id, content.Prompt.Message); err != nil {
+				return fmt.Errorf("error handling prompt: %v", err)
+			}
+		case *proto.ExecuteOutput_Metric:
+			if err := channels.OnMetric(content.Metric.Name, content.Metric.Value, content.Metric.Labels); err != nil {
+				return fmt.Errorf("error handling metric: %v", err)
+			}
+		case *proto.ExecuteOutput_Artifact:
+			artifacts.push(content.Artifact)
+		}
+	}
+}
+
+// checkResult validates a structured result against the plugin's published result schema
+func (c *GRPCClient) checkResult(ctx context.Context, result map[string]interface{}, handler OutputHandler) error {
+	if c.resultValidation == ResultValidationOff {
+		return nil
+	}
+
+	info, err := c.GetInfo(ctx)
+	if err != nil || len(info.ResultSchema) == 0 {
+		return nil
+	}
+
+	violations := ValidateResult(info.ResultSchema, result)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	if c.resultValidation != ResultValidationError {
+		for _, v := range violations {
+			Logger(LogClient).Warn("result violates the schema", "plugin", c.name, "violation", v)
 		}
+		return nil
+	}
+
+	details := strings.Join(violations, "; ")
+	if err := handler.OnError("RESULT_SCHEMA_VIOLATION", "result does not match the published schema", details); err != nil {
+		return err
 	}
+	return fmt.Errorf("result schema violation: %s", details)
 }
 
 // ReportExecutionSummary sends execution summary to the main application
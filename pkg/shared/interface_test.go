@@ -0,0 +1,47 @@
+package shared
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestGRPCClient_GetInfoConcurrent(t *testing.T) {
+	server, address := startStubPluginServer(t)
+	defer server.Stop()
+
+	client, err := NewClientWithAddress(address)
+	if err != nil {
+		t.Fatalf("NewClientWithAddress() error = %v", err)
+	}
+	defer client.Close()
+
+	// Executions sharing a daemon's client all ask for its info; run with -race
+	var wg sync.WaitGroup
+	infos := make([]*PluginInfo, 8)
+	for i := range infos {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			info, err := client.GetInfo(context.Background())
+			if err != nil {
+				t.Errorf("GetInfo() error = %v", err)
+				return
+			}
+			infos[i] = info
+		}(i)
+	}
+	wg.Wait()
+
+	for i, info := range infos {
+		if info == nil {
+			continue
+		}
+		if info.Name != "stub" {
+			t.Errorf("GetInfo() name = %q, want stub", info.Name)
+		}
+		if info != infos[0] {
+			t.Errorf("GetInfo() call %d returned a different cached info", i)
+		}
+	}
+}
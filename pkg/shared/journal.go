@@ -0,0 +1,250 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// journalLimit bounds the messages kept of one execution; attaching replays the latest ones
+	journalLimit = 10000
+
+	// journalRetention is how long a finished execution can still be attached to, to see how it ended
+	journalRetention = time.Hour
+)
+
+// executionJournal keeps what an execution the daemon serves sends, so that its caller can
+// detach from it and another caller attach to it later. The execution is canceled when its last
+// caller goes away without detaching, as it would be without a journal.
+type executionJournal struct {
+	id      string
+	plugin  string
+	started time.Time
+	cancel  context.CancelFunc
+
+	mu        sync.Mutex
+	messages  []*proto.ExecuteOutput
+	dropped   int           // Messages dropped from the front to stay within journalLimit
+	changed   chan struct{} // Closed and replaced whenever a message arrives or the execution finishes
+	followers int
+	detached  bool
+	finished  time.Time
+	err       error
+}
+
+// Send journals a message of the execution, as its stream to the caller
+func (j *executionJournal) Send(msg *proto.ExecuteOutput) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.messages = append(j.messages, msg)
+	if len(j.messages) > journalLimit {
+		j.messages[0] = nil
+		j.messages = j.messages[1:]
+		j.dropped++
+	}
+	j.notify()
+	return nil
+}
+
+// finish records how the execution ended
+func (j *executionJournal) finish(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.finished = time.Now()
+	j.err = err
+	j.notify()
+}
+
+// notify wakes the followers; j.mu must be held
+func (j *executionJournal) notify() {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// detach lets the execution go on once its callers go away
+func (j *executionJournal) detach() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.detached = true
+}
+
+// follow sends the journaled messages and those still to come until the execution finishes,
+// returning its error, or ctx is done. Following takes the execution back from being detached.
+func (j *executionJournal) follow(ctx context.Context, send func(*proto.ExecuteOutput) error) error {
+	j.mu.Lock()
+	j.followers++
+	j.detached = false
+	j.mu.Unlock()
+	defer func() {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		j.followers--
+		if j.followers == 0 && !j.detached && j.finished.IsZero() {
+			j.cancel()
+		}
+	}()
+
+	next := 0 // Of all messages the execution sent, including dropped ones
+	for {
+		j.mu.Lock()
+		if next < j.dropped {
+			next = j.dropped
+		}
+		pending := j.messages[next-j.dropped:]
+		next += len(pending)
+		finished, err, changed := !j.finished.IsZero(), j.err, j.changed
+		j.mu.Unlock()
+
+		for _, msg := range pending {
+			if err := send(msg); err != nil {
+				return err
+			}
+		}
+		if finished && len(pending) == 0 {
+			return err
+		}
+		if len(pending) > 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// execution describes the journaled execution for the control API
+func (j *executionJournal) execution() *proto.DaemonExecution {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	execution := &proto.DaemonExecution{
+		Id:       j.id,
+		Plugin:   j.plugin,
+		Started:  j.started.UnixNano(),
+		Attached: j.followers > 0,
+		Finished: !j.finished.IsZero(),
+	}
+	if j.err != nil {
+		execution.Error = j.err.Error()
+	}
+	return execution
+}
+
+// expired reports whether the execution finished long enough ago to be forgotten
+func (j *executionJournal) expired(now time.Time) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return !j.finished.IsZero() && j.followers == 0 && now.Sub(j.finished) > journalRetention
+}
+
+// executionJournals are the journals of the executions a daemon serves, by id
+type executionJournals struct {
+	mu       sync.Mutex
+	journals map[string]*executionJournal
+}
+
+func newExecutionJournals() *executionJournals {
+	return &executionJournals{journals: make(map[string]*executionJournal)}
+}
+
+// start journals a new execution of plugin, which cancel cancels, forgetting expired ones. The
+// journal goes by the execution's own id unless it is empty or taken.
+func (js *executionJournals) start(id, plugin string, cancel context.CancelFunc) (*executionJournal, error) {
+	j := &executionJournal{
+		id:      id,
+		plugin:  plugin,
+		started: time.Now(),
+		cancel:  cancel,
+		changed: make(chan struct{}),
+	}
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	for id, journal := range js.journals {
+		if journal.expired(j.started) {
+			delete(js.journals, id)
+		}
+	}
+	for j.id == "" || js.journals[j.id] != nil {
+		var err error
+		if j.id, err = NewExecutionID(); err != nil {
+			return nil, err
+		}
+	}
+	js.journals[j.id] = j
+	return j, nil
+}
+
+// get returns the journal of an execution, nil if there is none
+func (js *executionJournals) get(id string) *executionJournal {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	return js.journals[id]
+}
+
+// list returns the journaled executions, oldest first
+func (js *executionJournals) list() []*proto.DaemonExecution {
+	js.mu.Lock()
+	executions := make([]*proto.DaemonExecution, 0, len(js.journals))
+	for _, journal := range js.journals {
+		executions = append(executions, journal.execution())
+	}
+	js.mu.Unlock()
+	sort.Slice(executions, func(i, k int) bool {
+		return executions[i].Started < executions[k].Started
+	})
+	return executions
+}
+
+// journalStream is the stream of a journaled execution, which runs on after its caller went away
+type journalStream struct {
+	proto.Plugin_ExecuteServer
+	ctx     context.Context
+	journal *executionJournal
+}
+
+func (s journalStream) Send(msg *proto.ExecuteOutput) error {
+	return s.journal.Send(msg)
+}
+
+func (s journalStream) Context() context.Context {
+	return s.ctx
+}
+
+type detachableKey struct{}
+
+// WithDetachable returns a context whose executions through the daemon are journaled, so that
+// the caller can detach from them; started gets the daemon's id of each once it began. That is
+// the id of WithExecutionID unless the caller gave none or it was taken, in which case the
+// execution goes by the daemon's, in its history as well.
+func WithDetachable(ctx context.Context, started func(id string)) context.Context {
+	return context.WithValue(ctx, detachableKey{}, started)
+}
+
+// detachableFromContext returns the callback of WithDetachable, nil if executions aren't detachable
+func detachableFromContext(ctx context.Context) func(id string) {
+	started, _ := ctx.Value(detachableKey{}).(func(id string))
+	return started
+}
+
+// Attach streams an execution journaled by the daemon to handler from its start, as Execute
+// would; plugin is a client of the execution's plugin through the daemon, see DialDaemon
+func Attach(ctx context.Context, plugin PluginInterface, id string, handler OutputHandler) error {
+	client, ok := plugin.(*GRPCClient)
+	if !ok {
+		return fmt.Errorf("plugin %T isn't reached through the daemon", plugin)
+	}
+	ctx, cancel := context.WithCancel(metadata.AppendToOutgoingContext(ctx, daemonAttachKey, id))
+	defer cancel()
+	stream, err := client.client.Execute(ctx, &proto.ExecuteRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to attach to execution %s: %v", id, err)
+	}
+	return client.receive(ctx, stream, handler, cancel)
+}
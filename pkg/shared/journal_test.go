@@ -0,0 +1,116 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+)
+
+func outputMessage(line string) *proto.ExecuteOutput {
+	return &proto.ExecuteOutput{Content: &proto.ExecuteOutput_Output{Output: line}}
+}
+
+func TestExecutionJournal_follow(t *testing.T) {
+	canceled := false
+	journals := newExecutionJournals()
+	journal, err := journals.start("", "hello", func() { canceled = true })
+	if err != nil {
+		t.Fatalf("start() error = %v", err)
+	}
+	journal.Send(outputMessage("first"))
+
+	// A follower gets what was sent before it came and what follows until the execution finishes
+	got := make(chan string, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- journal.follow(context.Background(), func(msg *proto.ExecuteOutput) error {
+			got <- msg.GetOutput()
+			return nil
+		})
+	}()
+	if line := <-got; line != "first" {
+		t.Errorf("first message = %q, want %q", line, "first")
+	}
+	journal.Send(outputMessage("second"))
+	if line := <-got; line != "second" {
+		t.Errorf("second message = %q, want %q", line, "second")
+	}
+	failed := errors.New("failed")
+	journal.finish(failed)
+	if err := <-done; err != failed {
+		t.Errorf("follow() error = %v, want the execution's", err)
+	}
+	if canceled {
+		t.Error("finished execution was canceled")
+	}
+
+	// Finished executions are replayed in full
+	var replayed []string
+	journal.follow(context.Background(), func(msg *proto.ExecuteOutput) error {
+		replayed = append(replayed, msg.GetOutput())
+		return nil
+	})
+	if len(replayed) != 2 {
+		t.Errorf("replayed %q, want both messages", replayed)
+	}
+
+	executions := journals.list()
+	if len(executions) != 1 || executions[0].Id != journal.id || executions[0].Plugin != "hello" || !executions[0].Finished || executions[0].Error != "failed" {
+		t.Errorf("list() = %v", executions)
+	}
+}
+
+func TestExecutionJournal_leave(t *testing.T) {
+	tests := []struct {
+		name         string
+		detach       bool
+		wantCanceled bool
+	}{
+		{name: "Caller goes away", wantCanceled: true},
+		{name: "Caller detached", detach: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canceled := false
+			journal, err := newExecutionJournals().start("", "hello", func() { canceled = true })
+			if err != nil {
+				t.Fatalf("start() error = %v", err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() {
+				done <- journal.follow(ctx, func(*proto.ExecuteOutput) error { return nil })
+			}()
+			for !journal.execution().Attached {
+				time.Sleep(time.Millisecond)
+			}
+			if tt.detach {
+				journal.detach()
+			}
+			cancel()
+			if err := <-done; err != context.Canceled {
+				t.Errorf("follow() error = %v, want the caller's", err)
+			}
+			if canceled != tt.wantCanceled {
+				t.Errorf("canceled = %v, want %v", canceled, tt.wantCanceled)
+			}
+		})
+	}
+}
+
+func TestExecutionJournals_start(t *testing.T) {
+	journals := newExecutionJournals()
+	if journal, err := journals.start("a1b2c3", "hello", func() {}); err != nil || journal.id != "a1b2c3" {
+		t.Errorf("start() = %v, %v, want the execution's id", journal, err)
+	}
+	// Ids taken or missing are replaced by new ones
+	for _, id := range []string{"a1b2c3", ""} {
+		if journal, err := journals.start(id, "hello", func() {}); err != nil || journal.id == "" || journal.id == "a1b2c3" {
+			t.Errorf("start(%q) = %v, %v, want a new id", id, journal, err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package shared
+
+import (
+	"context"
+	"time"
+)
+
+// maxIdleCheckInterval bounds how long an idle plugin may outlive its keep_alive
+const maxIdleCheckInterval = time.Minute
+
+// idleSince returns when the last execution finished, or false while executions are running
+func (e *executions) idleSince() (time.Time, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.last, len(e.cancels) == 0
+}
+
+// busySince reports whether executions are in flight, and since when there have been
+func (e *executions) busySince() (time.Time, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.busy, len(e.cancels) > 0
+}
+
+// idleSince returns when the last execution on any of the clients finished, or false while any
+// of them is executing
+func idleSince(clients []*GRPCClient) (time.Time, bool) {
+	var last time.Time
+	for _, client := range clients {
+		finished, idle := client.inflight.idleSince()
+		if !idle {
+			return time.Time{}, false
+		}
+		if finished.After(last) {
+			last = finished
+		}
+	}
+	return last, true
+}
+
+// busySince reports whether any of the clients is executing, and since when one has been
+func busySince(clients []*GRPCClient) (time.Time, bool) {
+	var since time.Time
+	for _, client := range clients {
+		if started, busy := client.inflight.busySince(); busy && (since.IsZero() || started.Before(since)) {
+			since = started
+		}
+	}
+	return since, !since.IsZero()
+}
+
+// watchIdle stops the plugin once no execution has used it for its keep_alive, so a long-lived
+// manager such as the daemon holds on to warm plugins only while they're in use. Plugins are
+// started again on their next use. The caller must hold pm.mu.
+func (pm *PluginManager) watchIdle(managed *ManagedPlugin) {
+	keepAlive := time.Duration(managed.Config.KeepAlive)
+	if keepAlive <= 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(pm.ctx)
+	managed.stopIdle = cancel
+	started := time.Now()
+
+	interval := keepAlive / 4
+	if interval > maxIdleCheckInterval {
+		interval = maxIdleCheckInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if pm.stopIfIdle(managed, started, keepAlive) {
+				return
+			}
+		}
+	}()
+}
+
+// stopIfIdle stops the plugin if it's still the one running under its name and has been idle
+// for keepAlive
+func (pm *PluginManager) stopIfIdle(managed *ManagedPlugin, started time.Time, keepAlive time.Duration) bool {
+	pm.mu.Lock()
+	if pm.plugins[managed.Name] != managed {
+		pm.mu.Unlock()
+		return true
+	}
+	last, idle := idleSince(managed.clients())
+	if last.Before(started) {
+		last = started
+	}
+	// A plugin others depend on stays up as long as they do
+	if !idle || time.Since(last) < keepAlive || dependedOn(managed.Name, pm.plugins) {
+		pm.mu.Unlock()
+		return false
+	}
+	delete(pm.plugins, managed.Name)
+	// Recorded now, as the plugin may be started again while this instance shuts down
+	pm.states.stopped(managed.Name, nil)
+	pm.mu.Unlock()
+
+	// Other plugins stay usable while this one gets its grace period
+	managed.shutdown()
+	return true
+}
@@ -0,0 +1,72 @@
+package shared
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+)
+
+func TestPluginManager_KeepAlive(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	proto.RegisterPluginServer(server, &GRPCServer{Impl: tickingPlugin{}})
+	StartHealthServer(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	pm := NewPluginManager(&AppConfig{})
+	defer pm.StopAll()
+	keepAlive := 200 * time.Millisecond
+	config := PluginConfig{Type: PluginTypeRemote, Address: listener.Addr().String(), KeepAlive: Duration(keepAlive)}
+	if err := pm.StartPlugin("idle", config); err != nil {
+		t.Fatalf("StartPlugin() error = %v", err)
+	}
+	config.KeepAlive = 0
+	if err := pm.StartPlugin("kept", config); err != nil {
+		t.Fatalf("StartPlugin() error = %v", err)
+	}
+	plugin, err := pm.GetPlugin("idle")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A running execution keeps the plugin alive past its keep_alive
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		plugin.Execute(ctx, nil, discardHandler{})
+		close(done)
+	}()
+	time.Sleep(3 * keepAlive)
+	if _, err := pm.GetPlugin("idle"); err != nil {
+		t.Fatalf("plugin stopped during an execution: %v", err)
+	}
+
+	cancel()
+	<-done
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := pm.GetPlugin("idle"); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("idle plugin was not stopped")
+		}
+	}
+
+	if _, err := pm.GetPlugin("kept"); err != nil {
+		t.Errorf("plugin without keep_alive was stopped: %v", err)
+	}
+
+	// It can be started again on its next use
+	config.KeepAlive = Duration(keepAlive)
+	if err := pm.StartPlugin("idle", config); err != nil {
+		t.Errorf("StartPlugin() after idle stop error = %v", err)
+	}
+}
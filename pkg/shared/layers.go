@@ -0,0 +1,60 @@
+package shared
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// readConfigData reads a configuration file as the current format has it: a remote one is
+// fetched, or read from its cache when it can't be, and a legacy one is converted
+func readConfigData(path string) ([]byte, error) {
+	source := path
+	if IsRemoteConfig(path) {
+		var err error
+		if source, err = fetchRemoteConfig(path); err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+	if IsLegacyConfig(data) {
+		if data, err = migrateLegacyData(data); err != nil {
+			return nil, err
+		}
+		Logger(LogConfig).Warn("plugins are listed in the deprecated legacy format; convert them with 'config migrate'", "file", path)
+	}
+	return data, nil
+}
+
+// mergeConfigLayer merges a configuration file over the files merged so far: objects are merged
+// key by key, and other values, lists included, replace the ones before
+func mergeConfigLayer(merged map[string]interface{}, data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // Numbers pass through unchanged
+	var layer map[string]interface{}
+	if err := decoder.Decode(&layer); err != nil {
+		return nil, err
+	}
+	return mergeObjects(merged, layer), nil
+}
+
+// mergeObjects merges overlay into base, which it returns
+func mergeObjects(base, overlay map[string]interface{}) map[string]interface{} {
+	if base == nil {
+		return overlay
+	}
+	for key, value := range overlay {
+		baseObject, ok := base[key].(map[string]interface{})
+		overlayObject, isObject := value.(map[string]interface{})
+		if ok && isObject {
+			base[key] = mergeObjects(baseObject, overlayObject)
+			continue
+		}
+		base[key] = value
+	}
+	return base
+}
@@ -0,0 +1,81 @@
+package shared
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig_overlays(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	base := write("config.json", `{"plugins": {
+		"api": {"type": "remote", "address": "localhost:9001", "fallback_addresses": ["localhost:9002"], "execution_timeout": "10s"},
+		"db": {"type": "remote", "address": "localhost:9003"}}}`)
+	staging := write("staging.json", `{"plugins": {"api": {"address": "staging:9001", "fallback_addresses": ["staging:9002", "staging:9003"]}}}`)
+	local := write("local.json", `{"plugins": {"api": {"address": "127.0.0.1:9001"}, "cache": {"type": "remote", "address": "localhost:9004"}}}`)
+
+	config, err := LoadConfig(base, staging, local)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	api := config.Plugins["api"]
+	if api.Address != "127.0.0.1:9001" {
+		t.Errorf("address = %q, want the one of the last overlay", api.Address)
+	}
+	if want := []string{"staging:9002", "staging:9003"}; !reflect.DeepEqual(api.FallbackAddresses, want) {
+		t.Errorf("fallback_addresses = %v, want %v, replaced rather than appended", api.FallbackAddresses, want)
+	}
+	if time.Duration(api.ExecutionTimeout) != 10*time.Second {
+		t.Errorf("execution_timeout = %v, want 10s kept from the base", api.ExecutionTimeout)
+	}
+	if _, ok := config.Plugins["db"]; !ok {
+		t.Error("plugin of the base only is missing")
+	}
+	if _, ok := config.Plugins["cache"]; !ok {
+		t.Error("plugin added by an overlay is missing")
+	}
+
+	// The base is loaded alone as written, for saving it back
+	raw, err := LoadRawConfig(base)
+	if err != nil {
+		t.Fatalf("LoadRawConfig() error = %v", err)
+	}
+	if got := raw.Plugins["api"].Address; got != "localhost:9001" {
+		t.Errorf("raw address = %q, want the base's", got)
+	}
+}
+
+func TestLoadConfig_overlayProblems(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.json")
+	overlay := filepath.Join(dir, "local.json")
+	if err := os.WriteFile(base, []byte(`{"plugins": {"api": {"type": "remote", "address": "localhost:9001"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(overlay, []byte(`{"plugins": {"api": {"adress": "localhost:9002"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadConfig(base, overlay)
+	var problems ConfigErrors
+	if !errors.As(err, &problems) || len(problems) != 1 {
+		t.Fatalf("LoadConfig() error = %v, want one problem", err)
+	}
+	if problems[0].File != overlay {
+		t.Errorf("problem file = %q, want %q", problems[0].File, overlay)
+	}
+
+	if _, err := LoadConfig(base, filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadConfig() with a missing overlay succeeded")
+	}
+}
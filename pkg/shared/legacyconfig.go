@@ -0,0 +1,73 @@
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// legacyConfig is the configuration format of pkg/common, which lists plugins named by a field
+// instead of mapping names to them
+type legacyConfig struct {
+	Plugins []legacyPlugin `json:"plugins"`
+}
+
+type legacyPlugin struct {
+	Name        string            `json:"name"`
+	Type        string            `json:"type"`
+	Command     string            `json:"command"`
+	Path        string            `json:"path"`
+	Environment map[string]string `json:"env"`
+	WorkingDir  string            `json:"workdir"`
+}
+
+// IsLegacyConfig reports whether data is a configuration in the legacy format, whose plugins
+// are a list
+func IsLegacyConfig(data []byte) bool {
+	var config struct {
+		Plugins json.RawMessage `json:"plugins"`
+	}
+	if json.Unmarshal(data, &config) != nil {
+		return false
+	}
+	var plugins []json.RawMessage
+	return json.Unmarshal(config.Plugins, &plugins) == nil && plugins != nil
+}
+
+// MigrateLegacyConfig converts a configuration in the legacy format to the current one
+func MigrateLegacyConfig(data []byte) (*AppConfig, error) {
+	var legacy legacyConfig
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return nil, fmt.Errorf("failed to parse legacy config: %v", err)
+	}
+	config := &AppConfig{Plugins: make(map[string]PluginConfig, len(legacy.Plugins))}
+	for i, plugin := range legacy.Plugins {
+		if plugin.Name == "" {
+			return nil, fmt.Errorf("plugin %d of the legacy config has no name", i+1)
+		}
+		if _, ok := config.Plugins[plugin.Name]; ok {
+			return nil, fmt.Errorf("plugin %q is listed more than once in the legacy config", plugin.Name)
+		}
+		migrated := PluginConfig{
+			Type:        PluginType(plugin.Type),
+			Command:     plugin.Command,
+			Path:        plugin.Path,
+			Environment: plugin.Environment,
+			WorkingDir:  plugin.WorkingDir,
+		}
+		// Legacy command plugins could leave their path to the directory they run in
+		if migrated.Type == PluginTypeCommand && migrated.Path == "" {
+			migrated.Path = migrated.WorkingDir
+		}
+		config.Plugins[plugin.Name] = migrated
+	}
+	return config, nil
+}
+
+// migrateLegacyData converts legacy configuration data to the current format's
+func migrateLegacyData(data []byte) ([]byte, error) {
+	config, err := MigrateLegacyConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(config)
+}
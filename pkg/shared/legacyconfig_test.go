@@ -0,0 +1,67 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const legacyConfigData = `{"plugins": [
+	{"name": "hello", "type": "binary", "path": "/opt/plugins/hello", "env": {"GREETING": "hi"}},
+	{"name": "multiply", "type": "command", "command": "python3 multiply.py --port {port}", "workdir": "/opt/plugins/multiply"}
+]}`
+
+func TestIsLegacyConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"Legacy", legacyConfigData, true},
+		{"Current", `{"plugins": {"hello": {"path": "bin/hello"}}}`, false},
+		{"No plugins", `{"read_only": true}`, false},
+		{"Not JSON", `plugins:`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsLegacyConfig([]byte(tt.data)); got != tt.want {
+				t.Errorf("IsLegacyConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrateLegacyConfig(t *testing.T) {
+	config, err := MigrateLegacyConfig([]byte(legacyConfigData))
+	if err != nil {
+		t.Fatalf("MigrateLegacyConfig() error = %v", err)
+	}
+	hello, multiply := config.Plugins["hello"], config.Plugins["multiply"]
+	if hello.Type != PluginTypeBinary || hello.Path != "/opt/plugins/hello" || hello.Environment["GREETING"] != "hi" {
+		t.Errorf("hello = %+v", hello)
+	}
+	if multiply.Type != PluginTypeCommand || multiply.Command != "python3 multiply.py --port {port}" || multiply.Path != "/opt/plugins/multiply" {
+		t.Errorf("multiply = %+v", multiply)
+	}
+
+	if _, err := MigrateLegacyConfig([]byte(`{"plugins": [{"name": "a"}, {"name": "a"}]}`)); err == nil || !strings.Contains(err.Error(), "more than once") {
+		t.Errorf("MigrateLegacyConfig() error = %v, want the duplicate", err)
+	}
+	if _, err := MigrateLegacyConfig([]byte(`{"plugins": [{"path": "a"}]}`)); err == nil || !strings.Contains(err.Error(), "no name") {
+		t.Errorf("MigrateLegacyConfig() error = %v, want the missing name", err)
+	}
+
+	// The loader reads legacy files as they are
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(legacyConfigData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(loaded.Plugins) != 2 {
+		t.Errorf("LoadConfig() plugins = %v, want both legacy ones", loaded.Plugins)
+	}
+}
@@ -3,10 +3,14 @@ package shared
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"strings"
 	"sync"
 	"time"
+
+	"google.golang.org/grpc"
 )
 
 // PluginManager handles plugin lifecycle management
@@ -16,28 +20,145 @@ type PluginManager struct {
 	mu         sync.RWMutex
 	ctx        context.Context
 	cancelFunc context.CancelFunc
+	stdout     io.Writer             // Of plugin processes; nil to log their lines
+	stderr     io.Writer             // Likewise
+	logs       map[string]*pluginLog // By plugin, also written by the processes of replaced instances
+	logsMu     sync.Mutex
+	readOnly   bool
+	states     stateTracker
+	queue      executionQueue
+	events     eventBus
+	ports      portAllocator
+	overrides  map[string]RunOverrides // By plugin, applied when it is started
+
+	reloadFailed  func(*ReloadError)
+	processExited func(*ProcessExit)
+	interceptors  ClientInterceptors
 }
 
 // ManagedPlugin represents a managed plugin instance
 type ManagedPlugin struct {
-	Name       string
-	Config     PluginConfig
-	Client     PluginInterface
-	GRPCClient *GRPCClient
-	Cmd        *exec.Cmd
-	RestartCnt int
-	LastError  error
+	Name         string
+	Config       PluginConfig
+	Client       PluginInterface
+	GRPCClient   *GRPCClient
+	Cmd          *exec.Cmd
+	RestartCnt   int
+	FailoverCnt  int
+	LastError    error
+	LastExit     *ProcessExit // How the last crashed process ended
+	process      *pluginProcess
+	authToken    string
+	autoTLS      *AutoMTLS
+	standby      *standbyProcess
+	replicas     []*replica  // Additional instances by index-1, nil while one is being restarted
+	dispatch     *replicaSet // Spreads executions over the instances when there are replicas
+	tunnel       *sshTunnel
+	chain        ClientInterceptors
+	stopHealth   context.CancelFunc
+	stopIdle     context.CancelFunc
+	autoPort     bool       // Config.Port was allocated rather than configured
+	ports        *portLease // Ports allocated to the plugin's instances
+	address      int        // Index of the connected one among a remote plugin's failover addresses
+	stopFailBack context.CancelFunc
+	restarts     []time.Time // Restarts within the restart window, for the circuit breaker
+	restartTimer *time.Timer // Restart waiting for its backoff
+	circuitOpen  bool        // Restarts stopped after too many within the window
+	overrides    RunOverrides
+}
+
+// environment returns the process environment for the plugin: what its env_policy lets it inherit
+// from the host, its own env and host-provided credentials
+func (m *ManagedPlugin) environment() []string {
+	env := m.Config.hostEnvironment(os.Environ())
+	for k, v := range m.Config.Environment {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	// Later entries win, so the run's overrides replace configured values
+	for k, v := range m.overrides.Environment {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	env = append(env, fmt.Sprintf("%s=%s", AuthTokenEnvVar, m.authToken))
+	if m.autoTLS != nil {
+		env = append(env, m.autoTLS.Env()...)
+	}
+	env = append(env, m.Config.limitsEnv()...)
+	return env
+}
+
+// dialOptions returns the gRPC dial options for connecting to the plugin
+func (m *ManagedPlugin) dialOptions() []grpc.DialOption {
+	var opts []grpc.DialOption
+	if m.Config.IsRemote() {
+		// Remote plugins may be called with a per-request credential instead of the static token
+		opts = append(opts, WithRequestCredentials(m.authToken, m.Config.AuthHeaders))
+	} else {
+		opts = append(opts, grpc.WithConnectParams(localConnectParams))
+		if m.authToken != "" {
+			opts = append(opts, WithAuthToken(m.authToken))
+		}
+	}
+	if m.autoTLS != nil {
+		opts = append(opts, m.autoTLS.DialOption())
+	}
+	if opt, _ := CompressionDialOption(m.Config.Compression); opt != nil {
+		opts = append(opts, opt)
+	}
+	if opt := m.Config.limitsDialOption(); opt != nil {
+		opts = append(opts, opt)
+	}
+	return append(opts, m.chain.DialOptions()...)
+}
+
+// release stops health checking and the warm standby of a plugin that is being removed, and
+// gives back its allocated ports
+func (m *ManagedPlugin) release() {
+	defer m.ports.releaseAll()
+	if m.stopHealth != nil {
+		m.stopHealth()
+	}
+	if m.restartTimer != nil {
+		m.restartTimer.Stop()
+	}
+	if m.stopIdle != nil {
+		m.stopIdle()
+	}
+	if m.stopFailBack != nil {
+		m.stopFailBack()
+	}
+	if m.standby != nil {
+		m.standby.stop(m.Config.stopTimeout())
+		m.standby = nil
+	}
+	if m.tunnel != nil {
+		m.tunnel.close()
+		m.tunnel = nil
+	}
 }
 
 // NewPluginManager creates a new plugin manager
 func NewPluginManager(config *AppConfig) *PluginManager {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &PluginManager{
+	pm := &PluginManager{
 		config:     config,
 		plugins:    make(map[string]*ManagedPlugin),
 		ctx:        ctx,
 		cancelFunc: cancel,
+		readOnly:   config.ReadOnly,
 	}
+	pm.states.events = &pm.events
+	pm.ports.configure(config)
+	return pm
+}
+
+// SetProcessOutput sets where the stdout and stderr of plugin processes are written. By default,
+// or with nil writers, each line becomes a record of the process component labeled with the
+// plugin and the stream.
+func (pm *PluginManager) SetProcessOutput(stdout, stderr io.Writer) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.stdout = stdout
+	pm.stderr = stderr
 }
 
 // StartPlugin starts a plugin and manages its lifecycle
@@ -49,85 +170,393 @@ func (pm *PluginManager) StartPlugin(name string, pluginConfig PluginConfig) err
 		return fmt.Errorf("plugin %s is already running", name)
 	}
 
+	pm.states.starting(name, pluginConfig)
+	if err := pm.startDependencies(name, pluginConfig, make(map[string]bool)); err != nil {
+		pm.states.stopped(name, err)
+		return err
+	}
+	if err := pm.startPlugin(name, pluginConfig); err != nil {
+		pm.states.stopped(name, err)
+		return err
+	}
+	pm.states.update(pm.plugins[name], StateReady)
+	return nil
+}
+
+// startPlugin starts a plugin that isn't running; the caller must hold pm.mu
+func (pm *PluginManager) startPlugin(name string, pluginConfig PluginConfig) error {
 	// Create a copy of the plugin config to avoid race conditions
 	config := pluginConfig
 
-	// Get the appropriate start command based on plugin type
-	cmd, args, err := config.GetStartCommand(config.Port)
-	if err != nil {
-		return fmt.Errorf("failed to get start command: %v", err)
+	// Refuse to start binaries that don't match the configured checksum
+	if err := config.VerifyChecksum(); err != nil {
+		return fmt.Errorf("refusing to start tampered binary: %v", err)
+	}
+	if _, err := pm.config.VerifyManifest(config); err != nil {
+		return fmt.Errorf("refusing to start unverified plugin: %v", err)
 	}
 
-	// Start the plugin process
-	process := exec.CommandContext(pm.ctx, cmd, args...)
-	process.Dir = config.WorkingDir
-	process.Stderr = os.Stderr
-	process.Stdout = os.Stdout
+	if config.IsRemote() {
+		return pm.connectRemotePlugin(name, config)
+	}
 
-	// Set up environment
-	process.Env = os.Environ()
-	for k, v := range config.Environment {
-		process.Env = append(process.Env, fmt.Sprintf("%s=%s", k, v))
+	// Connecting to a remote plugin is harmless, but read-only mode never starts processes
+	if pm.readOnly {
+		return fmt.Errorf("refusing to start plugin %s: %w", name, ErrReadOnly)
 	}
 
-	if err := process.Start(); err != nil {
+	// Pick free ports for a plugin that doesn't configure them, given back if it doesn't start
+	autoPort := config.Port == 0
+	ports := pm.ports.lease()
+	started := false
+	defer func() {
+		if !started {
+			ports.releaseAll()
+		}
+	}()
+	if err := config.allocatePorts(ports); err != nil {
 		return fmt.Errorf("failed to start plugin %s: %v", name, err)
 	}
 
-	// Wait for the plugin to start and be ready
-	var client PluginInterface
-	var clientErr error
-	for retries := 0; retries < 5; retries++ {
-		time.Sleep(time.Second)
-		client, clientErr = NewPluginClient(config.Port)
-		if clientErr == nil {
-			break
+	managed := &ManagedPlugin{
+		Name:      name,
+		Config:    config,
+		authToken: config.AuthToken,
+		chain:     pm.clientInterceptors(config),
+		autoPort:  autoPort,
+		ports:     ports,
+		overrides: pm.overrides[name],
+	}
+
+	// Use the configured token or generate one so nothing else on localhost can drive the plugin
+	var err error
+	if managed.authToken == "" {
+		managed.authToken, err = GenerateAuthToken()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Generate ephemeral certificates so host and plugin talk over mutual TLS
+	if config.AutoMTLS {
+		managed.autoTLS, err = NewAutoMTLS()
+		if err != nil {
+			return fmt.Errorf("failed to set up mTLS for plugin %s: %v", name, err)
 		}
 	}
 
-	if clientErr != nil {
-		process.Process.Kill()
-		return fmt.Errorf("failed to connect to plugin %s after multiple attempts: %v", name, clientErr)
+	// Start the plugin process
+	process, err := pm.startProcess(managed, config, config.Port, pm.stdout, pm.stderr)
+	if err != nil {
+		return fmt.Errorf("failed to start plugin %s: %v", name, err)
 	}
 
-	grpcClient, ok := client.(*GRPCClient)
-	if !ok {
-		process.Process.Kill()
-		return fmt.Errorf("invalid client type for plugin %s", name)
+	// Wait for the plugin to report ready
+	client, err := NewPluginClient(config.Port, managed.dialOptions()...)
+	if err != nil {
+		process.kill()
+		return fmt.Errorf("failed to connect to plugin %s: %v", name, err)
+	}
+	grpcClient := client.(*GRPCClient)
+	if err := grpcClient.waitStarted(pm.ctx, time.Duration(config.ReadyTimeout)); err != nil {
+		client.Close()
+		process.kill()
+		return fmt.Errorf("plugin %s did not become ready: %v", name, err)
 	}
 
-	// Set the plugin name in the client for telemetry
-	grpcClient.name = name
+	pm.configureClient(grpcClient, name, config)
+	pm.verifyVersion(managed, grpcClient)
 
-	managed := &ManagedPlugin{
-		Name:       name,
-		Config:     config,
-		Client:     client,
-		GRPCClient: grpcClient,
-		Cmd:        process,
-	}
-
-	// Enable health checking with automatic restart
-	grpcClient.EnableHealthCheck(pm.ctx, HealthCheck{
-		Interval:   time.Second * 30,
-		MaxRetries: 3,
-		RetryDelay: time.Second * 5,
-		OnUnhealthy: func(err error) {
-			pm.mu.Lock()
-			defer pm.mu.Unlock()
+	managed.Client = client
+	managed.GRPCClient = grpcClient
+	managed.setProcess(process)
+
+	// Enable health checking with automatic failover or restart
+	pm.monitorHealth(managed)
 
+	// Keep a warm spare running so executions can move over without waiting for a restart
+	if config.Standby {
+		managed.standby, err = pm.startStandby(managed, config, pm.stdout, pm.stderr)
+		if err != nil {
 			managed.LastError = err
-			if managed.RestartCnt < 3 {
-				managed.RestartCnt++
-				pm.restartPlugin(managed)
+		}
+	}
+
+	// Additional instances share the executions; the plugin runs with those that started
+	if config.Replicas > 1 {
+		if err := pm.startReplicas(managed, pm.stdout, pm.stderr); err != nil {
+			managed.LastError = err
+		}
+	}
+
+	pm.watchIdle(managed)
+	pm.plugins[name] = managed
+	started = true
+	Logger(LogManager).Debug("plugin started", "plugin", name, "type", config.Type, "port", config.Port)
+	return nil
+}
+
+// configureClient sets up a client of one of the plugin's instances: the plugin name for
+// telemetry and events, and the plugin's execution settings
+func (pm *PluginManager) configureClient(c *GRPCClient, name string, config PluginConfig) {
+	c.name = name
+	c.resultValidation = config.ResultValidation
+	c.watchdog = config.Watchdog
+	c.executionTimeout = time.Duration(config.ExecutionTimeout)
+	c.infoTimeout = pm.config.infoTimeout()
+	c.events = &pm.events
+	c.dumpDir = pm.config.DumpPath()
+	c.channels = config.Channels
+	c.versionCheck = config.VersionCheck
+	c.expectedVersion = nil
+	if config.ExpectedVersion != "" {
+		c.expectedVersion, _ = ParseVersionConstraint(config.ExpectedVersion) // Checked by Validate
+	}
+}
+
+// monitorHealth (re)starts health checking of the plugin's current client unless its
+// health_check disables it; the caller must hold pm.mu
+func (pm *PluginManager) monitorHealth(managed *ManagedPlugin) {
+	if managed.stopHealth != nil {
+		managed.stopHealth()
+		managed.stopHealth = nil
+	}
+	check, enabled := pm.config.healthCheck(managed.Config)
+	if !enabled {
+		return
+	}
+	ctx, cancel := context.WithCancel(pm.ctx)
+	managed.stopHealth = cancel
+
+	check.OnUnhealthy = func(err error) {
+		pm.mu.Lock()
+		defer pm.mu.Unlock()
+
+		// A check that was already running when the client was replaced is stale
+		if ctx.Err() != nil {
+			return
+		}
+
+		managed.LastError = err
+		pm.states.update(managed, StateUnhealthy)
+		pm.recoverPlugin(managed)
+	}
+	// A remote plugin without an address to fail over to stays unhealthy until it recovers
+	check.OnHealthy = func() {
+		pm.mu.Lock()
+		defer pm.mu.Unlock()
+
+		if ctx.Err() != nil {
+			return
+		}
+		if status, _, ok := pm.states.get(managed.Name); ok && status.State == StateUnhealthy {
+			managed.LastError = nil
+			pm.states.update(managed, StateReady)
+		}
+	}
+	managed.GRPCClient.EnableHealthCheck(ctx, check)
+}
+
+// recoverPlugin fails a broken plugin over to its standby, or a remote one to its next address,
+// or restarts it; the caller must hold pm.mu
+func (pm *PluginManager) recoverPlugin(managed *ManagedPlugin) {
+	if managed.Config.IsRemote() {
+		pm.failoverRemote(managed)
+		return
+	}
+	if pm.failover(managed) {
+		return
+	}
+	pm.scheduleRestart(managed)
+}
+
+// setProcess makes process the plugin's current one
+func (m *ManagedPlugin) setProcess(process *pluginProcess) {
+	m.process = process
+	m.Cmd = process.cmd
+}
+
+// connectRemotePlugin connects to an already running remote plugin; the caller must hold pm.mu
+func (pm *PluginManager) connectRemotePlugin(name string, config PluginConfig) error {
+	managed := &ManagedPlugin{
+		Name:      name,
+		Config:    config,
+		authToken: config.AuthToken,
+		chain:     pm.clientInterceptors(config),
+	}
+
+	// Keep connections within the host's allowlist, including whatever DNS names resolve to
+	policy, err := NewAddressPolicy(pm.config.RemoteAllowlist)
+	if err != nil {
+		return err
+	}
+	if policy != nil {
+		targets := config.remoteTargets()
+		if config.SSH != nil {
+			targets = append(targets, config.SSH.Host)
+		}
+		for _, t := range targets {
+			if err := policy.CheckTarget(t); err != nil {
+				return fmt.Errorf("refusing to connect to remote plugin %s: %v", name, err)
 			}
-		},
-	})
+		}
+	}
+
+	// Behind an SSH tunnel the plugin is dialled on the tunnel's local end; the
+	// SSH server resolves and connects to the plugin address
+	if config.SSH != nil {
+		tunnel, err := openSSHTunnel(pm.ctx, config.SSH, config.Address, time.Duration(config.ConnectTimeout))
+		if err != nil {
+			return fmt.Errorf("failed to open ssh tunnel for remote plugin %s: %v", name, err)
+		}
+		managed.tunnel = tunnel
+	}
+
+	// Fallback addresses are tried in order while the ones before are unreachable
+	addresses := config.failoverAddresses()
+	var failures []string
+	for i, address := range addresses {
+		grpcClient, err := pm.dialRemote(managed, policy, address)
+		if err != nil {
+			if len(addresses) > 1 {
+				err = fmt.Errorf("%s: %v", address, err)
+			}
+			failures = append(failures, err.Error())
+			continue
+		}
+		managed.Client = grpcClient
+		managed.GRPCClient = grpcClient
+		managed.address = i
+		if i > 0 {
+			managed.LastError = fmt.Errorf("connected to fallback %s: %s", address, strings.Join(failures, "; "))
+		}
+		break
+	}
+	if managed.GRPCClient == nil {
+		managed.release()
+		return fmt.Errorf("remote plugin %s is not reachable: %s", name, strings.Join(failures, "; "))
+	}
 
+	// An unhealthy address is left for the next one, if there is somewhere to fail over to
+	pm.monitorHealth(managed)
+	if len(config.FallbackAddresses) > 0 {
+		pm.watchFailBack(managed)
+	}
+	pm.watchIdle(managed)
 	pm.plugins[name] = managed
 	return nil
 }
 
+// dialRemote connects to one address of a remote plugin, "" for its balanced addresses, and
+// waits until the plugin is reachable there
+func (pm *PluginManager) dialRemote(m *ManagedPlugin, policy *AddressPolicy, address string) (*GRPCClient, error) {
+	config := m.Config
+	if address != "" {
+		config.Address = address
+	}
+	target, opts := remoteDialTarget(&config)
+
+	// Proxies from the config or environment apply after the allowlist check
+	dial := config.proxyDialer()
+	if policy != nil {
+		dial = policy.Dialer(config.Address, dial)
+	}
+	if m.tunnel != nil {
+		target = m.tunnel.localAddr
+		dial = directDial
+	}
+	opts = append(opts, grpc.WithContextDialer(dial))
+
+	client, err := NewClientWithAddress(target, append(opts, m.dialOptions()...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %v", err)
+	}
+	grpcClient, ok := client.(*GRPCClient)
+	if !ok {
+		client.Close()
+		return nil, fmt.Errorf("invalid client type")
+	}
+	pm.configureClient(grpcClient, m.Name, config)
+	grpcClient.affinityKey = config.AffinityKey
+
+	if err := grpcClient.WaitReady(pm.ctx, time.Duration(config.ConnectTimeout)); err != nil {
+		client.Close()
+		return nil, err
+	}
+	pm.verifyVersion(m, grpcClient)
+	return grpcClient, nil
+}
+
+// startProcess launches the plugin listening on port, inside its sandbox if one is configured.
+// The process is waited on from the start, so its exit is handled as soon as it happens.
+func (pm *PluginManager) startProcess(m *ManagedPlugin, config PluginConfig, port int, stdout, stderr io.Writer) (*pluginProcess, error) {
+	// Get the appropriate start command based on plugin type
+	cmd, args, err := config.GetStartCommand(port)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get start command: %v", err)
+	}
+
+	// Lines of concurrent plugins stay apart and labeled in the host's log
+	var flushes []func()
+	if stdout == nil {
+		var flush func()
+		stdout, flush = processLogWriter(m.Name, "stdout")
+		flushes = append(flushes, flush)
+	}
+	if stderr == nil {
+		var flush func()
+		stderr, flush = processLogWriter(m.Name, "stderr")
+		flushes = append(flushes, flush)
+	}
+	// Output is also kept in the plugin's log file, where the logs command finds it
+	if log := pm.processLog(m.Name); log != nil {
+		logOut, flushOut := log.writer("stdout")
+		logErr, flushErr := log.writer("stderr")
+		stdout = io.MultiWriter(stdout, logOut)
+		stderr = io.MultiWriter(stderr, logErr)
+		flushes = append(flushes, flushOut, flushErr)
+	}
+	flushLog := func() {
+		for _, flush := range flushes {
+			flush()
+		}
+	}
+
+	tail := &tailWriter{size: stderrTailSize}
+	process := exec.CommandContext(pm.ctx, cmd, args...)
+	process.Dir = m.workingDir(config)
+	process.Stderr = io.MultiWriter(stderr, tail)
+	process.Stdout = stdout
+	// Children left holding the output pipes must not delay noticing the exit
+	process.WaitDelay = killWait
+	process.Env = m.environment()
+
+	if config.Sandbox != nil {
+		release, err := applySandbox(process, config.Sandbox, port)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up sandbox: %v", err)
+		}
+		defer release()
+	}
+
+	// Drop root privileges for plugins configured to run as another user
+	if err := applyCredential(process, &config); err != nil {
+		return nil, err
+	}
+
+	if err := process.Start(); err != nil {
+		return nil, err
+	}
+	Logger(LogProcess).Debug("process started", "plugin", m.Name, "pid", process.Process.Pid, "port", port, "dir", process.Dir)
+	// The pidfile lets a later run kill the process should this host crash
+	removePidfile := pm.writePidfile(m.Name, process.Process.Pid, port)
+	return watchProcess(m.Name, process, tail, func(p *pluginProcess) {
+		flushLog()
+		removePidfile()
+		pm.onProcessExit(m, p)
+	}), nil
+}
+
 // StopPlugin stops a running plugin
 func (pm *PluginManager) StopPlugin(name string) error {
 	pm.mu.Lock()
@@ -137,30 +566,32 @@ func (pm *PluginManager) StopPlugin(name string) error {
 	if !exists {
 		return fmt.Errorf("plugin %s is not running", name)
 	}
-
-	if err := plugin.Client.Close(); err != nil {
-		return fmt.Errorf("failed to close plugin client: %v", err)
-	}
-
-	if err := plugin.Cmd.Process.Kill(); err != nil {
-		return fmt.Errorf("failed to kill plugin process: %v", err)
+	if pm.readOnly {
+		return fmt.Errorf("refusing to stop plugin %s: %w", name, ErrReadOnly)
 	}
 
 	delete(pm.plugins, name)
-	return nil
+	defer pm.states.stopped(name, nil)
+	Logger(LogManager).Debug("stopping plugin", "plugin", name)
+	return plugin.shutdown()
 }
 
-// StopAll stops all running plugins
+// StopAll stops all running plugins, dependents before their dependencies. Plugins that don't
+// wait on each other get their grace periods at the same time.
 func (pm *PluginManager) StopAll() {
-	pm.cancelFunc()
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
+	plugins := make(map[string]*ManagedPlugin, len(pm.plugins))
 	for name, plugin := range pm.plugins {
-		plugin.Client.Close()
-		plugin.Cmd.Process.Kill()
+		plugins[name] = plugin
 		delete(pm.plugins, name)
 	}
+	pm.stopInOrder(plugins)
+
+	// Canceling the context kills whatever processes are left, so it comes last
+	pm.cancelFunc()
+	pm.closeLogs()
 }
 
 // GetPlugin returns a plugin client by name
@@ -173,51 +604,96 @@ func (pm *PluginManager) GetPlugin(name string) (PluginInterface, error) {
 		return nil, fmt.Errorf("plugin %s is not running", name)
 	}
 
-	return plugin.Client, nil
+	client := plugin.Client
+	if plugin.dispatch != nil {
+		client = plugin.dispatch
+	}
+	if pm.readOnly {
+		return readOnlyPlugin{client}, nil
+	}
+	if plugin.Config.MaxConcurrentExecutions > 0 || pm.config.MaxConcurrentExecutions > 0 {
+		return queuedPlugin{
+			PluginInterface: client,
+			queue:           &pm.queue,
+			name:            name,
+			limit:           plugin.Config.MaxConcurrentExecutions,
+			globalLimit:     pm.config.MaxConcurrentExecutions,
+			timeout:         time.Duration(plugin.Config.QueueTimeout),
+		}, nil
+	}
+	return client, nil
 }
 
 // restartPlugin attempts to restart a failed plugin
 func (pm *PluginManager) restartPlugin(plugin *ManagedPlugin) {
+	pm.states.update(plugin, StateRestarting)
+	// A failed attempt leaves the plugin unhealthy until the next one
+	restarted := false
+	defer func() {
+		if restarted {
+			pm.events.publish(Event{Type: EventPluginRestarted, Plugin: plugin.Name})
+			pm.states.update(plugin, StateReady)
+		} else {
+			pm.states.update(plugin, StateUnhealthy)
+		}
+	}()
 	plugin.Client.Close()
-	plugin.Cmd.Process.Kill()
+	plugin.process.kill()
+	// Replicas take the executions until the primary is back
+	if plugin.dispatch != nil {
+		plugin.dispatch.set(0, nil)
+	}
 
-	// Get the appropriate start command based on plugin type
-	cmd, args, err := plugin.Config.GetStartCommand(plugin.Config.Port)
-	if err != nil {
-		plugin.LastError = fmt.Errorf("failed to get restart command: %v", err)
+	if err := plugin.Config.VerifyChecksum(); err != nil {
+		plugin.LastError = fmt.Errorf("refusing to restart plugin: %v", err)
+		return
+	}
+	if _, err := pm.config.VerifyManifest(plugin.Config); err != nil {
+		plugin.LastError = fmt.Errorf("refusing to restart unverified plugin: %v", err)
 		return
 	}
 
-	process := exec.CommandContext(pm.ctx, cmd, args...)
-	process.Dir = plugin.Config.WorkingDir
-	process.Stderr = os.Stderr
-	process.Env = os.Environ()
-
-	// Set up environment
-	for k, v := range plugin.Config.Environment {
-		process.Env = append(process.Env, fmt.Sprintf("%s=%s", k, v))
+	// An allocated port may have been taken since, so allocate a fresh one
+	if plugin.autoPort {
+		plugin.ports.release(plugin.Config.Port)
+		port, err := plugin.ports.allocate()
+		if err != nil {
+			plugin.LastError = fmt.Errorf("failed to restart plugin: %v", err)
+			return
+		}
+		plugin.Config.Port = port
 	}
 
-	if err := process.Start(); err != nil {
+	process, err := pm.startProcess(plugin, plugin.Config, plugin.Config.Port, pm.stdout, pm.stderr)
+	if err != nil {
 		plugin.LastError = fmt.Errorf("failed to restart plugin: %v", err)
 		return
 	}
 
-	time.Sleep(time.Second)
-
-	client, err := NewPluginClient(plugin.Config.Port)
+	client, err := NewPluginClient(plugin.Config.Port, plugin.dialOptions()...)
 	if err != nil {
+		process.kill()
 		plugin.LastError = fmt.Errorf("failed to reconnect to plugin: %v", err)
 		return
 	}
-
-	grpcClient, ok := client.(*GRPCClient)
-	if !ok {
-		plugin.LastError = fmt.Errorf("invalid client type after restart")
+	grpcClient := client.(*GRPCClient)
+	if err := grpcClient.waitStarted(pm.ctx, time.Duration(plugin.Config.ReadyTimeout)); err != nil {
+		client.Close()
+		process.kill()
+		plugin.LastError = fmt.Errorf("restarted plugin did not become ready: %v", err)
 		return
 	}
 
+	pm.configureClient(grpcClient, plugin.Name, plugin.Config)
+	// The binary may have been replaced since the plugin last started
+	pm.verifyVersion(plugin, grpcClient)
+
 	plugin.Client = client
 	plugin.GRPCClient = grpcClient
-	plugin.Cmd = process
+	plugin.setProcess(process)
+	if plugin.dispatch != nil {
+		plugin.dispatch.set(0, grpcClient)
+	}
+	pm.monitorHealth(plugin)
+	restarted = true
 }
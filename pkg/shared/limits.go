@@ -0,0 +1,90 @@
+package shared
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"google.golang.org/grpc"
+)
+
+// Environment variables used to hand message and stream limits to local plugins. Sizes are
+// named from the plugin's point of view: what it receives is what the host sends.
+const (
+	MaxRecvMessageSizeEnvVar   = "PLUGIN_MAX_RECV_MESSAGE_SIZE"
+	MaxSendMessageSizeEnvVar   = "PLUGIN_MAX_SEND_MESSAGE_SIZE"
+	MaxConcurrentStreamsEnvVar = "PLUGIN_MAX_CONCURRENT_STREAMS"
+)
+
+// validateLimits checks the message size, stream and call timeout settings
+func (p *PluginConfig) validateLimits() error {
+	if p.MaxSendMessageSize < 0 {
+		return fmt.Errorf("invalid max_send_message_size: %d", p.MaxSendMessageSize)
+	}
+	if p.MaxRecvMessageSize < 0 {
+		return fmt.Errorf("invalid max_recv_message_size: %d", p.MaxRecvMessageSize)
+	}
+	if p.CallTimeout < 0 {
+		return fmt.Errorf("invalid call_timeout: %s", p.CallTimeout)
+	}
+	return nil
+}
+
+// limitsDialOption returns the call options applying the configured message sizes, or nil if unset
+func (p *PluginConfig) limitsDialOption() grpc.DialOption {
+	var callOpts []grpc.CallOption
+	if p.MaxSendMessageSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(p.MaxSendMessageSize))
+	}
+	if p.MaxRecvMessageSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(p.MaxRecvMessageSize))
+	}
+	if len(callOpts) == 0 {
+		return nil
+	}
+	return grpc.WithDefaultCallOptions(callOpts...)
+}
+
+// limitsEnv returns the environment variables that make a local plugin's server apply the same limits
+func (p *PluginConfig) limitsEnv() []string {
+	var env []string
+	if p.MaxSendMessageSize > 0 {
+		env = append(env, fmt.Sprintf("%s=%d", MaxRecvMessageSizeEnvVar, p.MaxSendMessageSize))
+	}
+	if p.MaxRecvMessageSize > 0 {
+		env = append(env, fmt.Sprintf("%s=%d", MaxSendMessageSizeEnvVar, p.MaxRecvMessageSize))
+	}
+	if p.MaxConcurrentStreams > 0 {
+		env = append(env, fmt.Sprintf("%s=%d", MaxConcurrentStreamsEnvVar, p.MaxConcurrentStreams))
+	}
+	return env
+}
+
+// ServerLimitsFromEnv returns server options for the message and stream limits the host provided
+func ServerLimitsFromEnv() ([]grpc.ServerOption, error) {
+	var opts []grpc.ServerOption
+
+	if v := os.Getenv(MaxRecvMessageSizeEnvVar); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid %s: %s", MaxRecvMessageSizeEnvVar, v)
+		}
+		opts = append(opts, grpc.MaxRecvMsgSize(size))
+	}
+	if v := os.Getenv(MaxSendMessageSizeEnvVar); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid %s: %s", MaxSendMessageSizeEnvVar, v)
+		}
+		opts = append(opts, grpc.MaxSendMsgSize(size))
+	}
+	if v := os.Getenv(MaxConcurrentStreamsEnvVar); v != "" {
+		streams, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || streams == 0 {
+			return nil, fmt.Errorf("invalid %s: %s", MaxConcurrentStreamsEnvVar, v)
+		}
+		opts = append(opts, grpc.MaxConcurrentStreams(uint32(streams)))
+	}
+
+	return opts, nil
+}
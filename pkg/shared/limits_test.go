@@ -0,0 +1,70 @@
+package shared
+
+import (
+	"testing"
+)
+
+func TestServerLimitsFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		wantOpts int
+		wantErr  bool
+	}{
+		{
+			name:     "No limits",
+			env:      map[string]string{},
+			wantOpts: 0,
+		},
+		{
+			name: "All limits",
+			env: map[string]string{
+				MaxRecvMessageSizeEnvVar:   "1048576",
+				MaxSendMessageSizeEnvVar:   "16777216",
+				MaxConcurrentStreamsEnvVar: "8",
+			},
+			wantOpts: 3,
+		},
+		{
+			name:    "Invalid size",
+			env:     map[string]string{MaxSendMessageSizeEnvVar: "lots"},
+			wantErr: true,
+		},
+		{
+			name:    "Zero streams",
+			env:     map[string]string{MaxConcurrentStreamsEnvVar: "0"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{MaxRecvMessageSizeEnvVar, MaxSendMessageSizeEnvVar, MaxConcurrentStreamsEnvVar} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			opts, err := ServerLimitsFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ServerLimitsFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(opts) != tt.wantOpts {
+				t.Errorf("ServerLimitsFromEnv() returned %d options, want %d", len(opts), tt.wantOpts)
+			}
+		})
+	}
+}
+
+func TestPluginConfig_LimitsEnv(t *testing.T) {
+	config := PluginConfig{MaxSendMessageSize: 100, MaxRecvMessageSize: 200}
+	env := config.limitsEnv()
+
+	want := []string{MaxRecvMessageSizeEnvVar + "=100", MaxSendMessageSizeEnvVar + "=200"}
+	if len(env) != len(want) {
+		t.Fatalf("limitsEnv() = %v, want %v", env, want)
+	}
+	for i := range want {
+		if env[i] != want[i] {
+			t.Errorf("limitsEnv()[%d] = %q, want %q", i, env[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package shared
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LintSeverity ranks lint findings
+type LintSeverity string
+
+// Lint severities, from most to least serious
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+	LintInfo    LintSeverity = "info"
+)
+
+// rank orders severities so findings can be filtered by a minimum
+func (s LintSeverity) rank() int {
+	switch s {
+	case LintError:
+		return 2
+	case LintWarning:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// AtLeast reports whether s is as serious as min
+func (s LintSeverity) AtLeast(min LintSeverity) bool {
+	return s.rank() >= min.rank()
+}
+
+// ParseLintSeverity parses a severity name
+func ParseLintSeverity(s string) (LintSeverity, error) {
+	switch severity := LintSeverity(s); severity {
+	case LintError, LintWarning, LintInfo:
+		return severity, nil
+	}
+	return "", fmt.Errorf("invalid severity: %s (must be error, warning or info)", s)
+}
+
+// Lint rule IDs, which a plugin's lint_ignore can suppress
+const (
+	LintInsecureRemote      = "insecure-remote"
+	LintWorldWritableBinary = "world-writable-binary"
+	LintMissingDescription  = "missing-description"
+	LintDuplicatePort       = "duplicate-port"
+	LintInheritedEnv        = "inherited-env"
+	LintLargeDefault        = "large-default"
+)
+
+// LintRules gives the severity of each rule
+var LintRules = map[string]LintSeverity{
+	LintInsecureRemote:      LintError,
+	LintWorldWritableBinary: LintError,
+	LintDuplicatePort:       LintError,
+	LintInheritedEnv:        LintWarning,
+	LintLargeDefault:        LintWarning,
+	LintMissingDescription:  LintInfo,
+}
+
+// lintMaxDefaultSize is the largest default value in bytes not flagged as suspicious
+const lintMaxDefaultSize = 4096
+
+// LintFinding is a best-practice violation in the configuration
+type LintFinding struct {
+	Plugin   string
+	Rule     string
+	Severity LintSeverity
+	Message  string
+}
+
+// validateLintIgnore checks that suppressed rules exist
+func (p *PluginConfig) validateLintIgnore() error {
+	for _, rule := range p.LintIgnore {
+		if _, ok := LintRules[rule]; !ok {
+			return fmt.Errorf("unknown lint rule in lint_ignore: %s", rule)
+		}
+	}
+	return nil
+}
+
+// Lint checks a validated configuration against best practices that validation doesn't
+// enforce. Findings are sorted by plugin and rule, without those the plugin suppresses.
+func (c *AppConfig) Lint() []LintFinding {
+	var findings []LintFinding
+	add := func(name, rule, format string, args ...interface{}) {
+		for _, ignored := range c.Plugins[name].LintIgnore {
+			if ignored == rule {
+				return
+			}
+		}
+		findings = append(findings, LintFinding{
+			Plugin:   name,
+			Rule:     rule,
+			Severity: LintRules[rule],
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	for name, plugin := range c.Plugins {
+		if plugin.Description == "" {
+			add(name, LintMissingDescription, "plugin has no description")
+		}
+		for param, value := range plugin.Defaults {
+			if len(value) > lintMaxDefaultSize {
+				add(name, LintLargeDefault, "default for %s is %d bytes, pass large inputs as files instead", param, len(value))
+			}
+		}
+
+		if plugin.IsRemote() {
+			if plugin.SSH == nil {
+				for _, address := range plugin.remoteAddresses() {
+					if !isLoopbackAddress(address) {
+						add(name, LintInsecureRemote, "remote address %s is reached without TLS, use an ssh tunnel", address)
+					}
+				}
+			}
+			continue
+		}
+
+		if info, err := os.Stat(plugin.Path); err == nil && info.Mode().Perm()&0002 != 0 {
+			add(name, LintWorldWritableBinary, "%s is writable by any user", plugin.Path)
+		}
+		// Isolating the plugin is undone by handing it everything in the host's environment
+		if (plugin.User != "" || plugin.Sandbox != nil) && plugin.envPolicy() == EnvInheritAll {
+			add(name, LintInheritedEnv, "plugin is isolated but inherits the host's entire environment, set env_policy to allowlist or none")
+		}
+	}
+
+	for _, port := range c.loopbackPortClashes() {
+		add(port.local, LintDuplicatePort, "port %d is also the address of remote plugin %q", port.port, port.remote)
+		add(port.remote, LintDuplicatePort, "address port %d is also used by local plugin %q", port.port, port.local)
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Plugin != findings[j].Plugin {
+			return findings[i].Plugin < findings[j].Plugin
+		}
+		if findings[i].Rule != findings[j].Rule {
+			return findings[i].Rule < findings[j].Rule
+		}
+		return findings[i].Message < findings[j].Message
+	})
+	return findings
+}
+
+// remoteAddresses returns the host:port addresses of a remote plugin, leaving out resolver targets
+func (p *PluginConfig) remoteAddresses() []string {
+	if len(p.Addresses) > 0 {
+		return p.Addresses
+	}
+	var addresses []string
+	for _, address := range p.failoverAddresses() {
+		if strings.Contains(address, "://") {
+			// e.g. dns:///host:port
+			address = address[strings.LastIndex(address, "/")+1:]
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+// isLoopbackAddress reports whether a host:port address stays on this machine
+func isLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// portClash is a local plugin listening on the port a remote plugin on this machine is reached at
+type portClash struct {
+	local, remote string
+	port          int
+}
+
+// loopbackPortClashes finds local plugin ports that remote plugins on loopback also point to,
+// which validation can't tell from a deliberate duplicate
+func (c *AppConfig) loopbackPortClashes() []portClash {
+	var clashes []portClash
+	for remoteName, remote := range c.Plugins {
+		if !remote.IsRemote() || remote.SSH != nil {
+			continue
+		}
+		for _, address := range remote.remoteAddresses() {
+			_, portStr, err := net.SplitHostPort(address)
+			if err != nil || !isLoopbackAddress(address) {
+				continue
+			}
+			port, err := strconv.Atoi(portStr)
+			if err != nil {
+				continue
+			}
+			for localName, local := range c.Plugins {
+				if local.IsRemote() {
+					continue
+				}
+				if local.Port == port || (local.Standby && local.StandbyPort == port) {
+					clashes = append(clashes, portClash{local: localName, remote: remoteName, port: port})
+				}
+			}
+		}
+	}
+	return clashes
+}
@@ -0,0 +1,123 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAppConfig_Lint(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "plugin")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writable := filepath.Join(dir, "writable")
+	if err := os.WriteFile(writable, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Set after creation so the umask doesn't get in the way
+	if err := os.Chmod(writable, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		plugins map[string]PluginConfig
+		want    []string // plugin/rule of each finding
+	}{
+		{
+			name: "Clean",
+			plugins: map[string]PluginConfig{
+				"local":  {Path: binary, Type: PluginTypeBinary, Port: 50051, Description: "d"},
+				"remote": {Type: PluginTypeRemote, Address: "127.0.0.1:9000", Description: "d"},
+			},
+		},
+		{
+			name: "Missing description",
+			plugins: map[string]PluginConfig{
+				"local": {Path: binary, Type: PluginTypeBinary},
+			},
+			want: []string{"local/missing-description"},
+		},
+		{
+			name: "Insecure remote addresses",
+			plugins: map[string]PluginConfig{
+				"remote": {Type: PluginTypeRemote, Addresses: []string{"localhost:9000", "10.0.0.5:9000"}, Description: "d"},
+				"dns":    {Type: PluginTypeRemote, Address: "dns:///plugins.example.com:9000", Description: "d"},
+				"tunnel": {Type: PluginTypeRemote, Address: "10.0.0.5:9000", SSH: &SSHConfig{Host: "bastion"}, Description: "d"},
+			},
+			want: []string{"dns/insecure-remote", "remote/insecure-remote"},
+		},
+		{
+			name: "World-writable binary",
+			plugins: map[string]PluginConfig{
+				"local": {Path: writable, Type: PluginTypeBinary, Description: "d"},
+			},
+			want: []string{"local/world-writable-binary"},
+		},
+		{
+			name: "Remote plugin on a local plugin's port",
+			plugins: map[string]PluginConfig{
+				"local":  {Path: binary, Type: PluginTypeBinary, Port: 50051, Description: "d"},
+				"remote": {Type: PluginTypeRemote, Address: "[::1]:50051", Description: "d"},
+			},
+			want: []string{"local/duplicate-port", "remote/duplicate-port"},
+		},
+		{
+			name: "Isolated plugin inheriting the environment",
+			plugins: map[string]PluginConfig{
+				"local": {Path: binary, Type: PluginTypeBinary, User: "nobody", Description: "d"},
+			},
+			want: []string{"local/inherited-env"},
+		},
+		{
+			name: "Isolated plugin with an environment policy",
+			plugins: map[string]PluginConfig{
+				"local": {Path: binary, Type: PluginTypeBinary, User: "nobody", EnvPolicy: EnvAllowlist, EnvAllow: []string{"HOME"}, Description: "d"},
+			},
+		},
+		{
+			name: "Large default",
+			plugins: map[string]PluginConfig{
+				"local": {Path: binary, Type: PluginTypeBinary, Description: "d", Defaults: map[string]string{"data": strings.Repeat("x", 5000)}},
+			},
+			want: []string{"local/large-default"},
+		},
+		{
+			name: "Suppressed per plugin",
+			plugins: map[string]PluginConfig{
+				"local":  {Path: writable, Type: PluginTypeBinary, LintIgnore: []string{LintWorldWritableBinary, LintMissingDescription}},
+				"other":  {Path: writable, Type: PluginTypeBinary, Description: "d"},
+				"remote": {Type: PluginTypeRemote, Address: "10.0.0.5:9000", LintIgnore: []string{LintInsecureRemote}, Description: "d"},
+			},
+			want: []string{"other/world-writable-binary"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &AppConfig{Plugins: tt.plugins}
+			var got []string
+			for _, finding := range config.Lint() {
+				if finding.Severity != LintRules[finding.Rule] {
+					t.Errorf("%s has severity %s, want %s", finding.Rule, finding.Severity, LintRules[finding.Rule])
+				}
+				got = append(got, finding.Plugin+"/"+finding.Rule)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintSeverity_AtLeast(t *testing.T) {
+	if !LintError.AtLeast(LintWarning) || LintInfo.AtLeast(LintWarning) || !LintWarning.AtLeast(LintWarning) {
+		t.Error("severities are not ordered error > warning > info")
+	}
+	if _, err := ParseLintSeverity("fatal"); err == nil {
+		t.Error("ParseLintSeverity(fatal) succeeded")
+	}
+}
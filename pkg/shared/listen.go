@@ -0,0 +1,35 @@
+package shared
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// ListenFDEnvVar names the inherited file descriptor of a listening socket opened by the host,
+// used when the plugin runs in a network namespace it couldn't otherwise be reached in
+const ListenFDEnvVar = "PLUGIN_LISTEN_FD"
+
+// Listen returns the listener handed over by the host, or listens on the given port
+func Listen(port int) (net.Listener, error) {
+	if v := os.Getenv(ListenFDEnvVar); v != "" {
+		fd, err := strconv.Atoi(v)
+		if err != nil || fd < 3 {
+			return nil, fmt.Errorf("invalid %s: %s", ListenFDEnvVar, v)
+		}
+		file := os.NewFile(uintptr(fd), "listener")
+		defer file.Close()
+		listener, err := net.FileListener(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to use inherited listener: %v", err)
+		}
+		return listener, nil
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on port %d: %v", port, err)
+	}
+	return listener, nil
+}
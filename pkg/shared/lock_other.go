@@ -0,0 +1,8 @@
+//go:build !unix
+
+package shared
+
+// lockFile is a no-op where flock isn't available; concurrent hosts may then lose updates
+func lockFile(path string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,170 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Components of the host, which their records name in the component attribute
+const (
+	LogManager = "manager" // Starting, restarting and stopping plugins
+	LogProcess = "process" // The processes of local plugins
+	LogClient  = "client"  // Calls to plugins
+	LogServer  = "server"  // The plugin side of calls
+	LogDaemon  = "daemon"
+	LogConfig  = "config"
+	LogWatch   = "watch" // Watched files and directories
+	LogUI      = "ui"    // What the CLI tells its user, including everything written with the log package
+)
+
+// Log formats
+const (
+	LogFormatText = "text" // A line per record as the log package writes them (default)
+	LogFormatJSON = "json" // A JSON object per line
+)
+
+// logHandler is where the components' loggers write, nil until ConfigureLogging is called
+var logHandler atomic.Pointer[slog.Handler]
+
+// Logger returns the logger of a component of the host
+func Logger(component string) *slog.Logger {
+	h := slog.Default().Handler()
+	if configured := logHandler.Load(); configured != nil {
+		h = *configured
+	}
+	return slog.New(h).With("component", component)
+}
+
+// ParseLogLevel parses a level name: debug, info, warn or error
+func ParseLogLevel(level string) (slog.Level, error) {
+	switch level {
+	case LogLevelDebug:
+		return slog.LevelDebug, nil
+	case "", LogLevelInfo:
+		return slog.LevelInfo, nil
+	case LogLevelWarn:
+		return slog.LevelWarn, nil
+	case LogLevelError:
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid log level: %s (must be debug, info, warn, or error)", level)
+}
+
+// ConfigureLogging makes the host write records at level and above to w in format. What is
+// written with the log package becomes records of the ui component.
+func ConfigureLogging(w io.Writer, format, level string) error {
+	minLevel, err := ParseLogLevel(level)
+	if err != nil {
+		return err
+	}
+	var h slog.Handler
+	switch format {
+	case "", LogFormatText:
+		h = &textHandler{mu: &sync.Mutex{}, w: w, level: minLevel}
+	case LogFormatJSON:
+		h = slog.NewJSONHandler(w, &slog.HandlerOptions{AddSource: true, Level: minLevel})
+	default:
+		return fmt.Errorf("invalid log format: %s (must be text or json)", format)
+	}
+	logHandler.Store(&h)
+	// The log package only passes on where it was called from with a file flag set
+	log.SetFlags(log.Lshortfile)
+	slog.SetDefault(slog.New(h).With("component", LogUI))
+	return nil
+}
+
+// textHandler writes a record as a line like the log package's: date, time, file and line, the
+// level unless it is info, the component unless it is the ui, the message and then the attributes
+type textHandler struct {
+	mu        *sync.Mutex
+	w         io.Writer
+	level     slog.Level
+	component string
+	attrs     string // Those of With, formatted
+	group     string // Prefix of attribute keys from WithGroup
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(r.Time.Format("2006/01/02 15:04:05 "))
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		fmt.Fprintf(&b, "%s:%d: ", filepath.Base(frame.File), frame.Line)
+	}
+	if r.Level != slog.LevelInfo {
+		b.WriteString(r.Level.String() + " ")
+	}
+	if h.component != "" && h.component != LogUI {
+		b.WriteString("[" + h.component + "] ")
+	}
+	b.WriteString(r.Message)
+	b.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		appendTextAttr(&b, h.group, a)
+		return true
+	})
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	var b strings.Builder
+	for _, a := range attrs {
+		if a.Key == "component" && h.group == "" {
+			c.component = a.Value.String()
+			continue
+		}
+		appendTextAttr(&b, h.group, a)
+	}
+	c.attrs += b.String()
+	return &c
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.group += name + "."
+	return &c
+}
+
+// appendTextAttr writes an attribute as key=value, quoting values that need it
+func appendTextAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			appendTextAttr(b, prefix, ga)
+		}
+		return
+	}
+	value := a.Value.String()
+	if value == "" || strings.ContainsAny(value, " =\"\n\t") {
+		value = strconv.Quote(value)
+	}
+	b.WriteString(" " + prefix + a.Key + "=" + value)
+}
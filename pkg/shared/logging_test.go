@@ -0,0 +1,57 @@
+package shared
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTextHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := &textHandler{mu: &sync.Mutex{}, w: &buf, level: slog.LevelInfo}
+
+	slog.New(h).With("component", LogUI).Info("Started plugin: hello")
+	manager := slog.New(h).With("component", LogManager)
+	manager.Warn("restarting plugin", "plugin", "hello", "cause", "exit status 3")
+	manager.Debug("not shown")
+	manager.WithGroup("limits").Error("too large", "size", 10, slog.Group("max", "bytes", 4))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := []string{
+		"logging_test.go:15: Started plugin: hello",
+		`logging_test.go:17: WARN [manager] restarting plugin plugin=hello cause="exit status 3"`,
+		"logging_test.go:19: ERROR [manager] too large limits.size=10 limits.max.bytes=4",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(want), buf.String())
+	}
+	for i, line := range lines {
+		// Lines start with the date and time, as the log package's do
+		if len(line) < 20 || line[4] != '/' || line[13] != ':' || line[20:] != want[i] {
+			t.Errorf("line %d = %q, want the time and %q", i, line, want[i])
+		}
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for level, want := range map[string]slog.Level{"": slog.LevelInfo, "debug": slog.LevelDebug, "warn": slog.LevelWarn, "error": slog.LevelError} {
+		if got, err := ParseLogLevel(level); err != nil || got != want {
+			t.Errorf("ParseLogLevel(%q) = %v, %v, want %v", level, got, err, want)
+		}
+	}
+	if _, err := ParseLogLevel("loud"); err == nil {
+		t.Error("ParseLogLevel(loud) error = nil, want error")
+	}
+}
+
+func TestConfigureLogging_invalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ConfigureLogging(&buf, "xml", LogLevelInfo); err == nil || !strings.Contains(err.Error(), "invalid log format") {
+		t.Errorf("ConfigureLogging() error = %v, want the invalid format", err)
+	}
+	if err := ConfigureLogging(&buf, LogFormatJSON, "loud"); err == nil || !strings.Contains(err.Error(), "invalid log level") {
+		t.Errorf("ConfigureLogging() error = %v, want the invalid level", err)
+	}
+}
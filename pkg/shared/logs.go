@@ -0,0 +1,359 @@
+package shared
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxPluginLogSize is the size at which a plugin's log file is rotated; one rotated file is kept
+const MaxPluginLogSize = 10 << 20
+
+// logFollowInterval is how often a followed log is checked for new lines
+const logFollowInterval = 250 * time.Millisecond
+
+// LogPath returns the directory the output of plugin processes is logged to
+func (c *AppConfig) LogPath() string {
+	return filepath.Join(c.StateDir, "logs")
+}
+
+// PluginLogFile returns the log file of a plugin; the rotated one has ".1" appended
+func (c *AppConfig) PluginLogFile(name string) string {
+	return filepath.Join(c.LogPath(), name+".log")
+}
+
+// LogLine is one line a plugin process wrote
+type LogLine struct {
+	Time   time.Time
+	Stream string // stdout or stderr
+	Text   string
+}
+
+func (l LogLine) String() string {
+	return fmt.Sprintf("%s %s %s", l.Time.UTC().Format(time.RFC3339Nano), l.Stream, l.Text)
+}
+
+// parseLogLine parses a line as written to a plugin's log file
+func parseLogLine(line string) (LogLine, bool) {
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) < 2 {
+		return LogLine{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return LogLine{}, false
+	}
+	parsed := LogLine{Time: t, Stream: fields[1]}
+	if len(fields) == 3 {
+		parsed.Text = fields[2]
+	}
+	return parsed, true
+}
+
+// pluginLog appends the output of the processes of one plugin to its log file, timestamped line
+// by line, and rotates the file once it outgrows MaxPluginLogSize
+type pluginLog struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	size    int64
+	maxSize int64
+}
+
+func openPluginLog(path string) (*pluginLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %v", err)
+	}
+	l := &pluginLog{path: path, maxSize: MaxPluginLogSize}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *pluginLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open plugin log: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open plugin log: %v", err)
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+func (l *pluginLog) writeLine(stream, text string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return
+	}
+	if l.size >= l.maxSize {
+		l.file.Close()
+		l.file = nil
+		os.Rename(l.path, l.path+".1")
+		if l.open() != nil {
+			return
+		}
+	}
+	line := LogLine{Time: time.Now(), Stream: stream, Text: text}.String() + "\n"
+	n, _ := l.file.WriteString(line)
+	l.size += int64(n)
+}
+
+// writer returns a writer for one stream of one process. Its last line, if unterminated, is
+// written by the returned flush once the process is gone.
+func (l *pluginLog) writer(stream string) (io.Writer, func()) {
+	w := &lineWriter{emit: func(text string) { l.writeLine(stream, text) }}
+	return w, w.flush
+}
+
+func (l *pluginLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// lineWriter splits what a process writes into lines, which it passes to emit
+type lineWriter struct {
+	emit func(text string)
+	mu   sync.Mutex
+	buf  []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(strings.TrimSuffix(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.emit(string(w.buf))
+		w.buf = nil
+	}
+}
+
+// processLogWriter returns a writer making each line a plugin's process writes to stream a
+// record of the process component. Its last line, if unterminated, is logged by the returned
+// flush once the process is gone.
+func processLogWriter(plugin, stream string) (io.Writer, func()) {
+	handler := Logger(LogProcess).With("plugin", plugin, "stream", stream).Handler()
+	w := &lineWriter{emit: func(text string) {
+		ctx := context.Background()
+		if !handler.Enabled(ctx, slog.LevelInfo) {
+			return
+		}
+		// Without a PC the record has no source, as the line isn't the host's
+		handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, text, 0))
+	}}
+	return w, w.flush
+}
+
+// processLog returns the log of a plugin, opening it on first use; nil without a state directory
+// or if it can't be opened, as the output still reaches the host's own stdout and stderr
+func (pm *PluginManager) processLog(name string) *pluginLog {
+	if pm.config.StateDir == "" {
+		return nil
+	}
+	pm.logsMu.Lock()
+	defer pm.logsMu.Unlock()
+	if l, ok := pm.logs[name]; ok {
+		return l
+	}
+	l, err := openPluginLog(pm.config.PluginLogFile(name))
+	if err != nil {
+		return nil
+	}
+	if pm.logs == nil {
+		pm.logs = make(map[string]*pluginLog)
+	}
+	pm.logs[name] = l
+	return l
+}
+
+// closeLogs closes the logs of all plugins
+func (pm *PluginManager) closeLogs() {
+	pm.logsMu.Lock()
+	defer pm.logsMu.Unlock()
+	for name, l := range pm.logs {
+		l.Close()
+		delete(pm.logs, name)
+	}
+}
+
+// LogOptions select the lines TailPluginLog reports
+type LogOptions struct {
+	Since  time.Time // Only lines written at or after Since, unless zero
+	Tail   int       // Only the last Tail lines written so far, unless 0
+	Follow bool      // Keep reporting lines as they are written, until the context is done
+}
+
+// TailPluginLog reports the lines of a plugin's log file at path to fn, oldest first, including
+// those of the rotated file. A log that doesn't exist yet is empty, or waited for when following.
+func TailPluginLog(ctx context.Context, path string, options LogOptions, fn func(LogLine)) error {
+	return TailPluginLogs(ctx, map[string]string{"": path}, options, func(_ string, line LogLine) { fn(line) })
+}
+
+// TailPluginLogs reports the lines of the log files of several plugins like TailPluginLog, those
+// written so far merged in the order they were written; paths maps plugin names to log files.
+// Lines to come are reported as each log is followed, from one goroutine at a time.
+func TailPluginLogs(ctx context.Context, paths map[string]string, options LogOptions, fn func(plugin string, line LogLine)) error {
+	type pluginLine struct {
+		plugin string
+		line   LogLine
+	}
+	var lines []pluginLine
+	followed := make(map[string]*followedLog, len(paths))
+	for plugin, path := range paths {
+		read, err := readPluginLog(path, options)
+		if err != nil {
+			return err
+		}
+		for _, line := range read.lines {
+			lines = append(lines, pluginLine{plugin, line})
+		}
+		followed[plugin] = read
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].line.Time.Before(lines[j].line.Time) })
+	if options.Tail > 0 && len(lines) > options.Tail {
+		lines = lines[len(lines)-options.Tail:]
+	}
+	for _, l := range lines {
+		fn(l.plugin, l.line)
+	}
+	if !options.Follow {
+		return nil
+	}
+
+	var mu sync.Mutex
+	errs := make(chan error, len(followed))
+	for plugin, log := range followed {
+		go func(plugin string, log *followedLog) {
+			errs <- log.follow(ctx, func(line LogLine) {
+				mu.Lock()
+				defer mu.Unlock()
+				fn(plugin, line)
+			})
+		}(plugin, log)
+	}
+	var firstErr error
+	for range followed {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// followedLog is a log file read up to offset, along with the lines selected from it so far
+type followedLog struct {
+	path   string
+	offset int64
+	read   os.FileInfo // Of the file read, to notice it being rotated
+	lines  []LogLine
+}
+
+// readPluginLog reads the lines of a log file and its rotated file that options select
+func readPluginLog(path string, options LogOptions) (*followedLog, error) {
+	l := &followedLog{path: path}
+	keep := func(line LogLine) {
+		if !options.Since.IsZero() && line.Time.Before(options.Since) {
+			return
+		}
+		l.lines = append(l.lines, line)
+		if options.Tail > 0 && len(l.lines) > options.Tail {
+			l.lines = l.lines[1:]
+		}
+	}
+	if _, err := readLogFile(path+".1", 0, keep); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	var err error
+	l.read, _ = os.Stat(path)
+	l.offset, err = readLogFile(path, 0, keep)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return l, nil
+}
+
+// follow reports the lines written to the log after those read, until the context is done
+func (l *followedLog) follow(ctx context.Context, fn func(LogLine)) error {
+	// The file is read from the start again once it has been rotated
+	ticker := time.NewTicker(logFollowInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if current, err := os.Stat(l.path); err == nil {
+			if l.read == nil || !os.SameFile(l.read, current) {
+				l.offset = 0
+			}
+			l.read = current
+		}
+		var err error
+		l.offset, err = readLogFile(l.path, l.offset, fn)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+}
+
+// readLogFile reports the complete lines of a log file from offset on and returns the offset
+// after the last of them
+func readLogFile(path string, offset int64, fn func(LogLine)) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return offset, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return offset, err
+	}
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// An unterminated line is still being written
+			return offset, nil
+		}
+		offset += int64(len(line))
+		if parsed, ok := parseLogLine(strings.TrimSuffix(line, "\n")); ok {
+			fn(parsed)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package shared
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPluginLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "hello.log")
+	log, err := openPluginLog(path)
+	if err != nil {
+		t.Fatalf("openPluginLog() error = %v", err)
+	}
+	defer log.Close()
+
+	stdout, flushStdout := log.writer("stdout")
+	stderr, _ := log.writer("stderr")
+	fmt.Fprint(stdout, "first\r\nsec")
+	fmt.Fprint(stderr, "oops\n")
+	fmt.Fprint(stdout, "ond\nunterminated")
+	flushStdout()
+
+	var got []string
+	err = TailPluginLog(context.Background(), path, LogOptions{}, func(line LogLine) {
+		got = append(got, line.Stream+" "+line.Text)
+	})
+	if err != nil {
+		t.Fatalf("TailPluginLog() error = %v", err)
+	}
+	want := []string{"stdout first", "stderr oops", "stdout second", "stdout unterminated"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TailPluginLog() = %q, want %q", got, want)
+	}
+}
+
+func TestTailPluginLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.log")
+	log, err := openPluginLog(path)
+	if err != nil {
+		t.Fatalf("openPluginLog() error = %v", err)
+	}
+	defer log.Close()
+	// Every line is about 45 bytes, so the log rotates after the fourth
+	log.maxSize = 4 * 45
+	for i := 1; i <= 6; i++ {
+		log.writeLine("stdout", fmt.Sprintf("line %d", i))
+	}
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Fatalf("log was not rotated: %v", err)
+	}
+
+	collect := func(options LogOptions) []string {
+		t.Helper()
+		var got []string
+		err := TailPluginLog(context.Background(), path, options, func(line LogLine) {
+			got = append(got, line.Text)
+		})
+		if err != nil {
+			t.Fatalf("TailPluginLog() error = %v", err)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name    string
+		options LogOptions
+		want    []string
+	}{
+		{"All lines across the rotation", LogOptions{}, []string{"line 1", "line 2", "line 3", "line 4", "line 5", "line 6"}},
+		{"Tail", LogOptions{Tail: 2}, []string{"line 5", "line 6"}},
+		{"Since after everything", LogOptions{Since: time.Now().Add(time.Minute)}, nil},
+		{"Since before everything", LogOptions{Since: time.Now().Add(-time.Minute), Tail: 1}, []string{"line 6"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := collect(tt.options); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TailPluginLog() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Follow", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		lines := make(chan string, 10)
+		done := make(chan error, 1)
+		go func() {
+			done <- TailPluginLog(ctx, path, LogOptions{Tail: 1, Follow: true}, func(line LogLine) {
+				lines <- line.Text
+			})
+		}()
+
+		// Following picks up new lines, also after the log is rotated again
+		for _, want := range []string{"line 6", "line 7", "line 8", "line 9", "line 10"} {
+			if want != "line 6" {
+				log.writeLine("stdout", want)
+			}
+			select {
+			case got := <-lines:
+				if got != want {
+					t.Fatalf("followed line = %q, want %q", got, want)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("%q was not followed", want)
+			}
+		}
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("TailPluginLog() error = %v", err)
+		}
+	})
+}
+
+func TestTailPluginLogs(t *testing.T) {
+	dir := t.TempDir()
+	paths := map[string]string{"hello": filepath.Join(dir, "hello.log"), "addition": filepath.Join(dir, "addition.log")}
+	logs := make(map[string]*pluginLog)
+	for name, path := range paths {
+		log, err := openPluginLog(path)
+		if err != nil {
+			t.Fatalf("openPluginLog() error = %v", err)
+		}
+		defer log.Close()
+		logs[name] = log
+	}
+	// Interleaved, so the merge can't just take one log after the other
+	for i, name := range []string{"hello", "addition", "hello", "addition"} {
+		logs[name].writeLine("stdout", fmt.Sprintf("line %d", i+1))
+		time.Sleep(time.Millisecond)
+	}
+
+	var got []string
+	err := TailPluginLogs(context.Background(), paths, LogOptions{Tail: 3}, func(plugin string, line LogLine) {
+		got = append(got, plugin+" "+line.Text)
+	})
+	if err != nil {
+		t.Fatalf("TailPluginLogs() error = %v", err)
+	}
+	want := []string{"addition line 2", "hello line 3", "addition line 4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TailPluginLogs() = %q, want %q", got, want)
+	}
+}
+
+func TestProcessLogWriter(t *testing.T) {
+	var buf bytes.Buffer
+	var h slog.Handler = &textHandler{mu: &sync.Mutex{}, w: &buf, level: slog.LevelInfo}
+	previous := logHandler.Swap(&h)
+	defer logHandler.Store(previous)
+
+	w, flush := processLogWriter("hello", "stderr")
+	fmt.Fprint(w, "listening on 50051\nunterminated")
+	flush()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	// The lines have no source, which would be the host's rather than the plugin's
+	want := []string{
+		"[process] listening on 50051 plugin=hello stream=stderr",
+		"[process] unterminated plugin=hello stream=stderr",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(want), buf.String())
+	}
+	for i, line := range lines {
+		if len(line) < 20 || line[20:] != want[i] {
+			t.Errorf("line %d = %q, want the time and %q", i, line, want[i])
+		}
+	}
+}
+
+func TestPluginManager_ProcessLog(t *testing.T) {
+	config := &AppConfig{StateDir: t.TempDir()}
+	pm := NewPluginManager(config)
+	if pm.processLog("hello") != pm.processLog("hello") {
+		t.Error("processLog() opened the log of a plugin twice")
+	}
+	pm.StopAll()
+	if _, err := os.Stat(config.PluginLogFile("hello")); err != nil {
+		t.Errorf("log file was not created: %v", err)
+	}
+
+	if NewPluginManager(&AppConfig{}).processLog("hello") != nil {
+		t.Error("processLog() without a state directory returned a log")
+	}
+}
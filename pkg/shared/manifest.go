@@ -0,0 +1,137 @@
+package shared
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ManifestSuffix is appended to a plugin's path to find its manifest when none is configured
+const ManifestSuffix = ".manifest.json"
+
+// PluginManifest describes a published plugin binary and carries its publisher's signature
+type PluginManifest struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	SHA256    string `json:"sha256"`
+	Publisher string `json:"publisher"`
+	Signature string `json:"signature,omitempty"` // Base64 Ed25519 signature over the other fields
+}
+
+// payload returns the bytes covered by the signature
+func (m *PluginManifest) payload() []byte {
+	unsigned := *m
+	unsigned.Signature = ""
+	data, _ := json.Marshal(unsigned)
+	return data
+}
+
+// Sign sets the manifest signature using the publisher's private key
+func (m *PluginManifest) Sign(key ed25519.PrivateKey) {
+	m.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, m.payload()))
+}
+
+// Verify checks the manifest signature against the publisher's public key
+func (m *PluginManifest) Verify(key ed25519.PublicKey) error {
+	signature, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil || m.Signature == "" {
+		return fmt.Errorf("manifest for %s is not signed", m.Name)
+	}
+	if !ed25519.Verify(key, m.payload(), signature) {
+		return fmt.Errorf("invalid signature on manifest for %s by %s", m.Name, m.Publisher)
+	}
+	return nil
+}
+
+// LoadManifest reads a plugin manifest
+func LoadManifest(path string) (*PluginManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %v", err)
+	}
+	var manifest PluginManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %v", path, err)
+	}
+	return &manifest, nil
+}
+
+// SaveManifest writes a plugin manifest
+func SaveManifest(manifest *PluginManifest, path string) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %v", err)
+	}
+	return nil
+}
+
+// ParsePublicKey decodes a base64 Ed25519 public key
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("not a base64 Ed25519 public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// checkTrustedKeys verifies that every trusted publisher key can be parsed
+func (c *AppConfig) checkTrustedKeys() error {
+	for publisher, key := range c.TrustedKeys {
+		if _, err := ParsePublicKey(key); err != nil {
+			return fmt.Errorf("invalid trusted key for publisher %q: %v", publisher, err)
+		}
+	}
+	for name, plugin := range c.Plugins {
+		if plugin.Manifest != "" && len(c.TrustedKeys) == 0 {
+			return fmt.Errorf("invalid configuration for plugin %q: manifest requires trusted_keys", name)
+		}
+	}
+	return nil
+}
+
+// ManifestPath returns where the plugin's manifest is expected
+func (p *PluginConfig) ManifestPath() string {
+	if p.Manifest != "" {
+		return p.Manifest
+	}
+	return p.Path + ManifestSuffix
+}
+
+// VerifyManifest checks that a local plugin ships a manifest signed by a trusted publisher that
+// matches its binary. Nothing is checked unless trusted keys are configured.
+func (c *AppConfig) VerifyManifest(plugin PluginConfig) (*PluginManifest, error) {
+	if len(c.TrustedKeys) == 0 || plugin.IsRemote() {
+		return nil, nil
+	}
+
+	manifest, err := LoadManifest(plugin.ManifestPath())
+	if err != nil {
+		return nil, err
+	}
+	encoded, ok := c.TrustedKeys[manifest.Publisher]
+	if !ok {
+		return nil, fmt.Errorf("publisher %q of %s is not trusted", manifest.Publisher, manifest.Name)
+	}
+	key, err := ParsePublicKey(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted key for publisher %q: %v", manifest.Publisher, err)
+	}
+	if err := manifest.Verify(key); err != nil {
+		return nil, err
+	}
+
+	actual, err := FileSHA256(plugin.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(actual, manifest.SHA256) {
+		return nil, fmt.Errorf("checksum mismatch for %s: manifest says %s, got %s", plugin.Path, manifest.SHA256, actual)
+	}
+	return manifest, nil
+}
@@ -0,0 +1,104 @@
+package shared
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAppConfig_VerifyManifest(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "plugin")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to write binary: %v", err)
+	}
+	sum, err := FileSHA256(binary)
+	if err != nil {
+		t.Fatalf("FileSHA256() error = %v", err)
+	}
+
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	config := &AppConfig{TrustedKeys: map[string]string{"acme": base64.StdEncoding.EncodeToString(public)}}
+
+	tests := []struct {
+		name     string
+		manifest PluginManifest
+		key      ed25519.PrivateKey
+		wantErr  bool
+		errorMsg string
+	}{
+		{
+			name:     "Valid signature",
+			manifest: PluginManifest{Name: "plugin", Version: "1.0.0", SHA256: sum, Publisher: "acme"},
+			key:      private,
+		},
+		{
+			name:     "Signed with another key",
+			manifest: PluginManifest{Name: "plugin", Version: "1.0.0", SHA256: sum, Publisher: "acme"},
+			key:      otherPrivate,
+			wantErr:  true,
+			errorMsg: "invalid signature",
+		},
+		{
+			name:     "Untrusted publisher",
+			manifest: PluginManifest{Name: "plugin", Version: "1.0.0", SHA256: sum, Publisher: "mallory"},
+			key:      private,
+			wantErr:  true,
+			errorMsg: "not trusted",
+		},
+		{
+			name:     "Binary doesn't match",
+			manifest: PluginManifest{Name: "plugin", Version: "1.0.0", SHA256: strings.Repeat("0", 64), Publisher: "acme"},
+			key:      private,
+			wantErr:  true,
+			errorMsg: "checksum mismatch",
+		},
+		{
+			name:     "Unsigned",
+			manifest: PluginManifest{Name: "plugin", Version: "1.0.0", SHA256: sum, Publisher: "acme"},
+			wantErr:  true,
+			errorMsg: "not signed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest := tt.manifest
+			if tt.key != nil {
+				manifest.Sign(tt.key)
+			}
+			if err := SaveManifest(&manifest, binary+ManifestSuffix); err != nil {
+				t.Fatalf("SaveManifest() error = %v", err)
+			}
+
+			_, err := config.VerifyManifest(PluginConfig{Path: binary, Type: PluginTypeBinary})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyManifest() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("VerifyManifest() error = %v, want error containing %q", err, tt.errorMsg)
+			}
+		})
+	}
+
+	// Tampering with a signed field invalidates the signature
+	manifest := PluginManifest{Name: "plugin", Version: "1.0.0", SHA256: sum, Publisher: "acme"}
+	manifest.Sign(private)
+	manifest.Version = "2.0.0"
+	if err := manifest.Verify(public); err == nil {
+		t.Errorf("Verify() accepted a modified manifest")
+	}
+}
+
+func TestAppConfig_VerifyManifestDisabled(t *testing.T) {
+	config := &AppConfig{}
+	if _, err := config.VerifyManifest(PluginConfig{Path: "/nonexistent", Type: PluginTypeBinary}); err != nil {
+		t.Errorf("VerifyManifest() without trusted keys error = %v", err)
+	}
+}
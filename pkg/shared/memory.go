@@ -0,0 +1,141 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMemoryCheckInterval is how often the host samples its own memory use
+const DefaultMemoryCheckInterval = time.Second
+
+// MemoryConfig bounds the host's own memory use during very large executions
+type MemoryConfig struct {
+	SoftLimitMB   int      `json:"soft_limit_mb"`            // Heap size at which output spills to disk and optional work is shed
+	CheckInterval Duration `json:"check_interval,omitempty"` // How often memory use is sampled (default 1s)
+	SpillDir      string   `json:"spill_dir,omitempty"`      // Where spilled output goes, defaults to <state_dir>/spill
+}
+
+// validate checks the memory settings
+func (c *MemoryConfig) validate() error {
+	if c.SoftLimitMB < 0 {
+		return fmt.Errorf("invalid memory soft_limit_mb: %d", c.SoftLimitMB)
+	}
+	if c.CheckInterval < 0 {
+		return fmt.Errorf("invalid memory check_interval: %s", c.CheckInterval)
+	}
+	return nil
+}
+
+// SpillPath returns the directory spilled output is written to
+func (c *AppConfig) SpillPath() string {
+	if c.Memory != nil && c.Memory.SpillDir != "" {
+		return c.Memory.SpillDir
+	}
+	return filepath.Join(c.StateDir, "spill")
+}
+
+// MemoryMonitor samples the host's heap and reports when it crosses the soft limit. A nil
+// monitor never reports pressure.
+type MemoryMonitor struct {
+	limit    uint64
+	interval time.Duration
+	usage    func() uint64
+	pressure atomic.Bool
+
+	mu       sync.Mutex
+	handlers []func(bool)
+}
+
+// NewMemoryMonitor returns a monitor for the configured soft limit, or nil if none is set
+func NewMemoryMonitor(config *MemoryConfig) *MemoryMonitor {
+	if config == nil || config.SoftLimitMB == 0 {
+		return nil
+	}
+	interval := time.Duration(config.CheckInterval)
+	if interval == 0 {
+		interval = DefaultMemoryCheckInterval
+	}
+	return &MemoryMonitor{
+		limit:    uint64(config.SoftLimitMB) << 20,
+		interval: interval,
+		usage:    heapInUse,
+	}
+}
+
+// heapInUse returns the bytes of heap the process currently holds
+func heapInUse() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
+
+// OnChange registers fn to be called when the monitor enters or leaves the pressure state
+func (m *MemoryMonitor) OnChange(fn func(pressure bool)) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = append(m.handlers, fn)
+}
+
+// UnderPressure reports whether memory use was above the soft limit at the last sample
+func (m *MemoryMonitor) UnderPressure() bool {
+	return m != nil && m.pressure.Load()
+}
+
+// Limit returns the soft limit in bytes
+func (m *MemoryMonitor) Limit() uint64 {
+	if m == nil {
+		return 0
+	}
+	return m.limit
+}
+
+// Start samples memory use until ctx is done
+func (m *MemoryMonitor) Start(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	m.check()
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.check()
+			}
+		}
+	}()
+}
+
+// check takes one sample. Pressure ends only once use drops below 90% of the limit so the
+// host doesn't flap around it.
+func (m *MemoryMonitor) check() {
+	used := m.usage()
+	pressure := m.pressure.Load()
+	switch {
+	case !pressure && used >= m.limit:
+		pressure = true
+	case pressure && used < m.limit/10*9:
+		pressure = false
+	default:
+		return
+	}
+	m.pressure.Store(pressure)
+
+	m.mu.Lock()
+	handlers := append([]func(bool){}, m.handlers...)
+	m.mu.Unlock()
+	for _, fn := range handlers {
+		fn(pressure)
+	}
+}
@@ -0,0 +1,58 @@
+package shared
+
+import (
+	"testing"
+)
+
+func TestMemoryMonitor_check(t *testing.T) {
+	m := NewMemoryMonitor(&MemoryConfig{SoftLimitMB: 100})
+	var usage uint64
+	m.usage = func() uint64 { return usage }
+	var changes []bool
+	m.OnChange(func(pressure bool) { changes = append(changes, pressure) })
+
+	steps := []struct {
+		name  string
+		usage uint64
+		want  bool
+	}{
+		{name: "Below limit", usage: 50 << 20, want: false},
+		{name: "Crosses limit", usage: 100 << 20, want: true},
+		{name: "Just below limit stays under pressure", usage: 95 << 20, want: true},
+		{name: "Well below limit relieves pressure", usage: 80 << 20, want: false},
+	}
+	for _, step := range steps {
+		usage = step.usage
+		m.check()
+		if got := m.UnderPressure(); got != step.want {
+			t.Errorf("%s: UnderPressure() = %v, want %v", step.name, got, step.want)
+		}
+	}
+	if len(changes) != 2 || !changes[0] || changes[1] {
+		t.Errorf("OnChange calls = %v, want [true false]", changes)
+	}
+}
+
+func TestNewMemoryMonitor(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *MemoryConfig
+		wantNil bool
+	}{
+		{name: "Not configured", config: nil, wantNil: true},
+		{name: "No limit", config: &MemoryConfig{SpillDir: "/tmp"}, wantNil: true},
+		{name: "Limit", config: &MemoryConfig{SoftLimitMB: 512}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemoryMonitor(tt.config)
+			if (m == nil) != tt.wantNil {
+				t.Fatalf("NewMemoryMonitor() = %v, wantNil %v", m, tt.wantNil)
+			}
+			// A nil monitor must be safe to use
+			if m.UnderPressure() {
+				t.Errorf("UnderPressure() = true before any sample")
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// localePlaceholder is replaced with the locale in a message file path
+const localePlaceholder = "{locale}"
+
+// MessagesConfig customizes and localizes the CLI's user-facing messages
+type MessagesConfig struct {
+	Locale    string            `json:"locale,omitempty"`    // Locale substituted for {locale} in file, defaults to $LANG
+	File      string            `json:"file,omitempty"`      // JSON object of message formats by id, e.g. messages/{locale}.json
+	Overrides map[string]string `json:"overrides,omitempty"` // Message formats by id, applied over the file
+}
+
+// Catalog holds printf-style message formats by id. A replacement format takes the same
+// arguments as the built-in one; explicit indexes such as %[2]s reorder or skip them.
+type Catalog struct {
+	mu       sync.RWMutex
+	defaults map[string]string
+	formats  map[string]string
+}
+
+// NewCatalog returns a catalog of the built-in formats
+func NewCatalog(defaults map[string]string) *Catalog {
+	formats := make(map[string]string, len(defaults))
+	for id, format := range defaults {
+		formats[id] = format
+	}
+	return &Catalog{defaults: defaults, formats: formats}
+}
+
+// Sprintf formats the message id; unknown ids format as the id itself
+func (c *Catalog) Sprintf(id string, args ...interface{}) string {
+	c.mu.RLock()
+	format, ok := c.formats[id]
+	c.mu.RUnlock()
+	if !ok {
+		return id
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Set replaces the format of a message after checking it against the built-in one
+func (c *Catalog) Set(id, format string) error {
+	builtin, ok := c.defaults[id]
+	if !ok {
+		return fmt.Errorf("unknown message: %s", id)
+	}
+	if formatted := fmt.Sprintf(format, sampleArgs(builtin)...); strings.Contains(formatted, "%!") {
+		return fmt.Errorf("message %s: format %q doesn't fit the arguments of %q", id, format, builtin)
+	}
+	c.mu.Lock()
+	c.formats[id] = format
+	c.mu.Unlock()
+	return nil
+}
+
+// Apply loads the configured message file and overrides. A file path with {locale} falls back
+// from the full locale to its language and then to the built-in messages.
+func (c *Catalog) Apply(config *MessagesConfig) error {
+	if config == nil {
+		return nil
+	}
+	if config.File != "" {
+		formats, err := loadMessageFile(config.File, config.locale())
+		if err != nil {
+			return err
+		}
+		if err := c.setAll(formats); err != nil {
+			return fmt.Errorf("%s: %v", config.File, err)
+		}
+	}
+	return c.setAll(config.Overrides)
+}
+
+// setAll applies formats in id order so the first error is reported consistently
+func (c *Catalog) setAll(formats map[string]string) error {
+	ids := make([]string, 0, len(formats))
+	for id := range formats {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err := c.Set(id, formats[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// locale returns the configured locale or the one from the environment, without encoding
+func (m *MessagesConfig) locale() string {
+	locale := m.Locale
+	if locale == "" {
+		locale = os.Getenv("LANG")
+	}
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	return locale
+}
+
+// loadMessageFile reads the formats in path, trying locale and then its language for {locale}
+func loadMessageFile(path, locale string) (map[string]string, error) {
+	candidates := []string{path}
+	if strings.Contains(path, localePlaceholder) {
+		candidates = nil
+		if locale != "" && locale != "C" && locale != "POSIX" {
+			candidates = append(candidates, strings.ReplaceAll(path, localePlaceholder, locale))
+			if language, _, found := strings.Cut(locale, "_"); found {
+				candidates = append(candidates, strings.ReplaceAll(path, localePlaceholder, language))
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		data, err := os.ReadFile(candidate)
+		if errors.Is(err, os.ErrNotExist) && strings.Contains(path, localePlaceholder) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message file: %v", err)
+		}
+		var formats map[string]string
+		if err := json.Unmarshal(data, &formats); err != nil {
+			return nil, fmt.Errorf("failed to parse message file %s: %v", candidate, err)
+		}
+		return formats, nil
+	}
+	return nil, nil
+}
+
+// sampleArgs returns placeholder arguments matching the verbs of a built-in format
+func sampleArgs(format string) []interface{} {
+	var args []interface{}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0123456789.", format[i]) >= 0 {
+			i++
+		}
+		if i >= len(format) {
+			break
+		}
+		switch format[i] {
+		case '%':
+		case 'd', 'x', 'X', 'o', 'c':
+			args = append(args, 0)
+		case 'f', 'g', 'e':
+			args = append(args, 0.0)
+		case 't':
+			args = append(args, false)
+		default:
+			args = append(args, "")
+		}
+	}
+	return args
+}
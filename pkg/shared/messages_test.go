@@ -0,0 +1,188 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testMessages = map[string]string{
+	"run.started": "Started plugin: %s (type: %s)",
+	"run.count":   "%d problem(s) found",
+}
+
+func TestCatalog_Set(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		format   string
+		want     string
+		wantErr  bool
+		errorMsg string
+	}{
+		{
+			name:   "Rebranded message",
+			id:     "run.started",
+			format: "Launched %s [%s]",
+			want:   "Launched hello [binary]",
+		},
+		{
+			name:   "Reordered arguments",
+			id:     "run.started",
+			format: "Plugin vom Typ %[2]s gestartet: %[1]s",
+			want:   "Plugin vom Typ binary gestartet: hello",
+		},
+		{
+			name:   "Skipped argument",
+			id:     "run.started",
+			format: "Started %[1]s",
+			want:   "Started hello",
+		},
+		{
+			name:     "Unknown message",
+			id:       "run.missing",
+			format:   "whatever",
+			wantErr:  true,
+			errorMsg: "unknown message: run.missing",
+		},
+		{
+			name:     "Extra argument",
+			id:       "run.started",
+			format:   "Started %s (%s) on %s",
+			wantErr:  true,
+			errorMsg: "doesn't fit the arguments",
+		},
+		{
+			name:     "Unused argument without indexes",
+			id:       "run.started",
+			format:   "Started %s",
+			wantErr:  true,
+			errorMsg: "doesn't fit the arguments",
+		},
+		{
+			name:     "Wrong verb",
+			id:       "run.count",
+			format:   "%s problem(s) found",
+			wantErr:  true,
+			errorMsg: "doesn't fit the arguments",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := NewCatalog(testMessages)
+			err := catalog.Set(tt.id, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("error %q does not contain %q", err, tt.errorMsg)
+				}
+				return
+			}
+			if got := catalog.Sprintf(tt.id, "hello", "binary"); got != tt.want {
+				t.Errorf("Sprintf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalog_Apply(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("de.json", `{"run.started": "Plugin %s gestartet (Typ: %s)", "run.count": "%d Problem(e) gefunden"}`)
+	write("broken.json", `{"run.count": "%s issue(s)"}`)
+
+	tests := []struct {
+		name     string
+		config   *MessagesConfig
+		lang     string
+		want     string
+		wantErr  bool
+		errorMsg string
+	}{
+		{
+			name: "No config",
+			want: "Started plugin: hello (type: binary)",
+		},
+		{
+			name:   "Locale falls back to language",
+			config: &MessagesConfig{Locale: "de_AT", File: filepath.Join(dir, "{locale}.json")},
+			want:   "Plugin hello gestartet (Typ: binary)",
+		},
+		{
+			name:   "Locale from LANG",
+			config: &MessagesConfig{File: filepath.Join(dir, "{locale}.json")},
+			lang:   "de_DE.UTF-8",
+			want:   "Plugin hello gestartet (Typ: binary)",
+		},
+		{
+			name:   "Missing locale keeps built-in messages",
+			config: &MessagesConfig{Locale: "fr_FR", File: filepath.Join(dir, "{locale}.json")},
+			want:   "Started plugin: hello (type: binary)",
+		},
+		{
+			name: "Override dropping an argument without indexes",
+			config: &MessagesConfig{
+				Locale:    "de",
+				File:      filepath.Join(dir, "{locale}.json"),
+				Overrides: map[string]string{"run.started": "%s läuft"},
+			},
+			wantErr:  true,
+			errorMsg: "doesn't fit the arguments",
+		},
+		{
+			name: "Indexed override",
+			config: &MessagesConfig{
+				Locale:    "de",
+				File:      filepath.Join(dir, "{locale}.json"),
+				Overrides: map[string]string{"run.started": "%[1]s läuft"},
+			},
+			want: "hello läuft",
+		},
+		{
+			name:     "Missing fixed file",
+			config:   &MessagesConfig{File: filepath.Join(dir, "missing.json")},
+			wantErr:  true,
+			errorMsg: "failed to read message file",
+		},
+		{
+			name:     "Invalid format in file",
+			config:   &MessagesConfig{File: filepath.Join(dir, "broken.json")},
+			wantErr:  true,
+			errorMsg: "broken.json: message run.count",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LANG", tt.lang)
+			catalog := NewCatalog(testMessages)
+			err := catalog.Apply(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("error %q does not contain %q", err, tt.errorMsg)
+				}
+				return
+			}
+			if got := catalog.Sprintf("run.started", "hello", "binary"); got != tt.want {
+				t.Errorf("Sprintf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Unknown id formats as the id", func(t *testing.T) {
+		if got := NewCatalog(testMessages).Sprintf("run.unknown"); got != "run.unknown" {
+			t.Errorf("Sprintf() = %q", got)
+		}
+	})
+}
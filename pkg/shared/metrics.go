@@ -0,0 +1,382 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cardinality guards applied unless the metrics block says otherwise
+const (
+	DefaultMaxLabelValues = 100
+	DefaultMaxSeries      = 10000
+)
+
+// OtherLabelValue replaces parameter label values beyond max_label_values
+const OtherLabelValue = "other"
+
+// labelValueEscaper escapes label values for the text exposition format
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelNamePattern matches valid Prometheus label names
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedLabels are set by the exporter itself, version on forwarded metrics only
+var reservedLabels = map[string]bool{"plugin": true, "status": true, "metric": true, "version": true}
+
+// MetricsConfig exports execution metrics with label dimensions for chargeback across teams
+type MetricsConfig struct {
+	File           string            `json:"file,omitempty"`             // Prometheus text file rewritten after each execution, defaults to <state_dir>/metrics.prom
+	Labels         map[string]string `json:"labels,omitempty"`           // Static labels on every series, e.g. environment
+	ParamLabels    map[string]string `json:"param_labels,omitempty"`     // Labels taken from a parameter's value, e.g. tenant: customer_id
+	MaxLabelValues int               `json:"max_label_values,omitempty"` // Distinct values kept per parameter label before folding into "other" (default 100)
+	MaxSeries      int               `json:"max_series,omitempty"`       // Series kept before further executions are dropped from the export (default 10000)
+
+	// Short-lived runs are gone before anything scrapes them, so each execution's metrics can
+	// also be sent on as it finishes
+	StatsD       string `json:"statsd,omitempty"`        // host:port of a StatsD server, sent metrics with DogStatsD tags over UDP
+	StatsDPrefix string `json:"statsd_prefix,omitempty"` // Prefix of the StatsD metric names (default plugin_app)
+	Pushgateway  string `json:"pushgateway,omitempty"`   // URL of a Prometheus Pushgateway, pushed metrics grouped by job and plugin
+	PushJob      string `json:"push_job,omitempty"`      // Job the metrics are pushed as (default plugin_app)
+}
+
+// validate checks the metrics settings
+func (c *MetricsConfig) validate() error {
+	if err := validateMetricLabels(c.Labels); err != nil {
+		return err
+	}
+	for label, param := range c.ParamLabels {
+		if err := validateMetricLabel(label); err != nil {
+			return err
+		}
+		if _, ok := c.Labels[label]; ok {
+			return fmt.Errorf("metric label %s is both static and taken from parameter %s", label, param)
+		}
+		if param == "" {
+			return fmt.Errorf("metric label %s needs a parameter name", label)
+		}
+	}
+	if c.MaxLabelValues < 0 {
+		return fmt.Errorf("invalid metrics max_label_values: %d", c.MaxLabelValues)
+	}
+	if c.MaxSeries < 0 {
+		return fmt.Errorf("invalid metrics max_series: %d", c.MaxSeries)
+	}
+	return c.validateForwarding()
+}
+
+// validateMetricLabels checks label names of a static label set
+func validateMetricLabels(labels map[string]string) error {
+	for label := range labels {
+		if err := validateMetricLabel(label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateMetricLabel(label string) error {
+	if !labelNamePattern.MatchString(label) || strings.HasPrefix(label, "__") {
+		return fmt.Errorf("invalid metric label name: %q", label)
+	}
+	if reservedLabels[label] {
+		return fmt.Errorf("metric label %s is reserved", label)
+	}
+	return nil
+}
+
+// ExecutionMetrics describes one execution for the export
+type ExecutionMetrics struct {
+	Plugin   string
+	Labels   map[string]string // The plugin's metric_labels
+	Params   map[string]string // Redacted parameters, so secrets never become label values
+	Success  bool
+	Duration time.Duration
+	Metrics  map[string]float64 // Metrics the plugin reported, exported as gauges
+	Version  string             // The plugin's version, a label of forwarded metrics
+	Host     map[string]float64 // What the host measured of the plugin's process, forwarded only
+}
+
+// MetricsExporter accumulates execution metrics in the state directory and writes them out in
+// the Prometheus text format, e.g. for node_exporter's textfile collector. A nil exporter
+// records nothing.
+type MetricsExporter struct {
+	config    MetricsConfig
+	file      string
+	statePath string
+}
+
+// NewMetricsExporter returns the exporter configured in the metrics block, or nil without one
+func NewMetricsExporter(config *AppConfig) *MetricsExporter {
+	if config.Metrics == nil {
+		return nil
+	}
+	e := &MetricsExporter{
+		config:    *config.Metrics,
+		file:      config.Metrics.File,
+		statePath: filepath.Join(config.StateDir, "metrics.json"),
+	}
+	if e.file == "" {
+		e.file = filepath.Join(config.StateDir, "metrics.prom")
+	}
+	if e.config.MaxLabelValues == 0 {
+		e.config.MaxLabelValues = DefaultMaxLabelValues
+	}
+	if e.config.MaxSeries == 0 {
+		e.config.MaxSeries = DefaultMaxSeries
+	}
+	return e
+}
+
+// metricSeries is the accumulated state of one label set
+type metricSeries struct {
+	Labels     map[string]string  `json:"labels"`
+	Executions map[string]float64 `json:"executions"` // By status
+	Seconds    float64            `json:"seconds"`
+	Gauges     map[string]float64 `json:"gauges,omitempty"`
+}
+
+// metricsState is what the exporter keeps between executions
+type metricsState struct {
+	Series      map[string]*metricSeries   `json:"series"`
+	LabelValues map[string]map[string]bool `json:"label_values"` // Values seen per parameter label
+	Dropped     float64                    `json:"dropped"`
+}
+
+// Record adds an execution to the export and forwards its metrics to StatsD and the Pushgateway
+// if they are configured
+func (e *MetricsExporter) Record(m ExecutionMetrics) error {
+	if e == nil {
+		return nil
+	}
+	labels, err := e.accumulate(m)
+	if err != nil {
+		return err
+	}
+	return e.forward(labels, m)
+}
+
+// accumulate adds an execution to the state and the export, returning its label set
+func (e *MetricsExporter) accumulate(m ExecutionMetrics) (map[string]string, error) {
+	if err := os.MkdirAll(filepath.Dir(e.statePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %v", err)
+	}
+	unlock, err := lockFile(e.statePath + ".lock")
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock metrics: %v", err)
+	}
+	defer unlock()
+
+	state, err := e.load()
+	if err != nil {
+		return nil, err
+	}
+	labels := e.labels(state, m)
+	key := seriesKey(labels)
+	series, ok := state.Series[key]
+	if !ok {
+		if len(state.Series) >= e.config.MaxSeries {
+			state.Dropped++
+			return labels, e.save(state)
+		}
+		series = &metricSeries{Labels: labels, Executions: make(map[string]float64)}
+		state.Series[key] = series
+	}
+
+	status := "success"
+	if !m.Success {
+		status = "failure"
+	}
+	series.Executions[status]++
+	series.Seconds += m.Duration.Seconds()
+	for name, value := range m.Metrics {
+		if series.Gauges == nil {
+			series.Gauges = make(map[string]float64)
+		}
+		series.Gauges[name] = value
+	}
+	return labels, e.save(state)
+}
+
+// labels resolves the label set of an execution: static labels, then the plugin's, then the
+// parameter labels, whose values are capped per label
+func (e *MetricsExporter) labels(state *metricsState, m ExecutionMetrics) map[string]string {
+	labels := map[string]string{"plugin": m.Plugin}
+	for label, value := range e.config.Labels {
+		labels[label] = value
+	}
+	for label, value := range m.Labels {
+		labels[label] = value
+	}
+	for label, param := range e.config.ParamLabels {
+		value, ok := m.Params[param]
+		if !ok {
+			continue
+		}
+		if value == SecretMask {
+			value = "redacted"
+		}
+		seen := state.LabelValues[label]
+		if seen == nil {
+			seen = make(map[string]bool)
+			state.LabelValues[label] = seen
+		}
+		if !seen[value] {
+			if len(seen) >= e.config.MaxLabelValues {
+				value = OtherLabelValue
+			} else {
+				seen[value] = true
+			}
+		}
+		labels[label] = value
+	}
+	return labels
+}
+
+// load reads the accumulated state, starting empty if there is none yet
+func (e *MetricsExporter) load() (*metricsState, error) {
+	state := &metricsState{}
+	data, err := os.ReadFile(e.statePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read metrics state: %v", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, fmt.Errorf("failed to parse metrics state: %v", err)
+		}
+	}
+	if state.Series == nil {
+		state.Series = make(map[string]*metricSeries)
+	}
+	if state.LabelValues == nil {
+		state.LabelValues = make(map[string]map[string]bool)
+	}
+	return state, nil
+}
+
+// save writes the state and the export, each replaced atomically
+func (e *MetricsExporter) save(state *metricsState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode metrics state: %v", err)
+	}
+	if err := writeFileAtomic(e.statePath, data); err != nil {
+		return fmt.Errorf("failed to write metrics state: %v", err)
+	}
+	if err := writeFileAtomic(e.file, []byte(formatMetrics(state))); err != nil {
+		return fmt.Errorf("failed to write metrics: %v", err)
+	}
+	return nil
+}
+
+// formatMetrics renders the state in the Prometheus text exposition format
+func formatMetrics(state *metricsState) string {
+	keys := make([]string, 0, len(state.Series))
+	for key := range state.Series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("# HELP plugin_app_executions_total Plugin executions by outcome.\n")
+	b.WriteString("# TYPE plugin_app_executions_total counter\n")
+	for _, key := range keys {
+		series := state.Series[key]
+		statuses := make([]string, 0, len(series.Executions))
+		for status := range series.Executions {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		for _, status := range statuses {
+			writeSample(&b, "plugin_app_executions_total", series.Labels, map[string]string{"status": status}, series.Executions[status])
+		}
+	}
+
+	b.WriteString("# HELP plugin_app_execution_seconds_total Time spent executing plugins.\n")
+	b.WriteString("# TYPE plugin_app_execution_seconds_total counter\n")
+	for _, key := range keys {
+		series := state.Series[key]
+		writeSample(&b, "plugin_app_execution_seconds_total", series.Labels, nil, series.Seconds)
+	}
+
+	b.WriteString("# HELP plugin_app_plugin_metric Latest value of each metric reported by plugins.\n")
+	b.WriteString("# TYPE plugin_app_plugin_metric gauge\n")
+	for _, key := range keys {
+		series := state.Series[key]
+		names := make([]string, 0, len(series.Gauges))
+		for name := range series.Gauges {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			writeSample(&b, "plugin_app_plugin_metric", series.Labels, map[string]string{"metric": name}, series.Gauges[name])
+		}
+	}
+
+	b.WriteString("# HELP plugin_app_metrics_dropped_total Executions left out because the series limit was reached.\n")
+	b.WriteString("# TYPE plugin_app_metrics_dropped_total counter\n")
+	writeSample(&b, "plugin_app_metrics_dropped_total", nil, nil, state.Dropped)
+	return b.String()
+}
+
+// writeSample writes one sample line with the labels in name order
+func writeSample(b *strings.Builder, name string, labels, extra map[string]string, value float64) {
+	all := make(map[string]string, len(labels)+len(extra))
+	for k, v := range labels {
+		all[k] = v
+	}
+	for k, v := range extra {
+		all[k] = v
+	}
+	b.WriteString(name)
+	if len(all) > 0 {
+		b.WriteString("{" + seriesKey(all) + "}")
+	}
+	b.WriteString(" " + strconv.FormatFloat(value, 'g', -1, 64) + "\n")
+}
+
+// seriesKey renders labels as sorted name="value" pairs, which also identifies the series
+func seriesKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + labelValueEscaper.Replace(labels[name]) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+// writeFileAtomic replaces path with data so readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,132 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsConfig_validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   MetricsConfig
+		wantErr  bool
+		errorMsg string
+	}{
+		{
+			name:   "Valid",
+			config: MetricsConfig{Labels: map[string]string{"environment": "prod"}, ParamLabels: map[string]string{"tenant": "customer_id"}},
+		},
+		{
+			name:     "Invalid label name",
+			config:   MetricsConfig{Labels: map[string]string{"cost-center": "x"}},
+			wantErr:  true,
+			errorMsg: "invalid metric label name",
+		},
+		{
+			name:     "Reserved label",
+			config:   MetricsConfig{ParamLabels: map[string]string{"plugin": "name"}},
+			wantErr:  true,
+			errorMsg: "metric label plugin is reserved",
+		},
+		{
+			name:     "Label both static and from a parameter",
+			config:   MetricsConfig{Labels: map[string]string{"tenant": "acme"}, ParamLabels: map[string]string{"tenant": "customer_id"}},
+			wantErr:  true,
+			errorMsg: "both static and taken from parameter",
+		},
+		{
+			name:   "Forwarding",
+			config: MetricsConfig{StatsD: "localhost:8125", Pushgateway: "http://pushgateway:9091"},
+		},
+		{
+			name:     "StatsD without a port",
+			config:   MetricsConfig{StatsD: "localhost"},
+			wantErr:  true,
+			errorMsg: "invalid metrics statsd",
+		},
+		{
+			name:     "Pushgateway without a scheme",
+			config:   MetricsConfig{Pushgateway: "pushgateway:9091"},
+			wantErr:  true,
+			errorMsg: "invalid metrics pushgateway",
+		},
+		{
+			name:     "Negative series limit",
+			config:   MetricsConfig{MaxSeries: -1},
+			wantErr:  true,
+			errorMsg: "invalid metrics max_series",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("error %q does not contain %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestMetricsExporter_Record(t *testing.T) {
+	dir := t.TempDir()
+	config := &AppConfig{StateDir: dir, Metrics: &MetricsConfig{
+		Labels:         map[string]string{"environment": "prod"},
+		ParamLabels:    map[string]string{"tenant": "customer_id"},
+		MaxLabelValues: 2,
+		MaxSeries:      3,
+	}}
+	team := map[string]string{"team": "billing"}
+
+	executions := []ExecutionMetrics{
+		{Plugin: "hello", Labels: team, Params: map[string]string{"customer_id": `acme "corp"`}, Success: true, Duration: time.Second, Metrics: map[string]float64{"rows": 10}},
+		{Plugin: "hello", Labels: team, Params: map[string]string{"customer_id": `acme "corp"`}, Success: false, Duration: 500 * time.Millisecond},
+		// Masked values never become label values
+		{Plugin: "hello", Labels: team, Params: map[string]string{"customer_id": SecretMask}, Success: true, Duration: time.Second},
+		// Beyond max_label_values, tenants fold into "other"
+		{Plugin: "hello", Labels: team, Params: map[string]string{"customer_id": "initech"}, Success: true, Duration: time.Second},
+		{Plugin: "hello", Labels: team, Params: map[string]string{"customer_id": "umbrella"}, Success: true, Duration: time.Second},
+		// Beyond max_series, executions are dropped
+		{Plugin: "addition", Success: true, Duration: time.Second},
+	}
+	for _, m := range executions {
+		// A fresh exporter per execution, like separate host runs
+		if err := NewMetricsExporter(config).Record(m); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "metrics.prom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		`plugin_app_executions_total{environment="prod",plugin="hello",status="failure",team="billing",tenant="acme \"corp\""} 1`,
+		`plugin_app_executions_total{environment="prod",plugin="hello",status="success",team="billing",tenant="acme \"corp\""} 1`,
+		`plugin_app_executions_total{environment="prod",plugin="hello",status="success",team="billing",tenant="redacted"} 1`,
+		`plugin_app_executions_total{environment="prod",plugin="hello",status="success",team="billing",tenant="other"} 2`,
+		`plugin_app_execution_seconds_total{environment="prod",plugin="hello",team="billing",tenant="acme \"corp\""} 1.5`,
+		`plugin_app_plugin_metric{environment="prod",metric="rows",plugin="hello",team="billing",tenant="acme \"corp\""} 10`,
+		`plugin_app_metrics_dropped_total 1`,
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("export is missing %s\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, `plugin="addition"`) {
+		t.Errorf("export contains a series beyond max_series:\n%s", got)
+	}
+
+	t.Run("Nil exporter", func(t *testing.T) {
+		if err := NewMetricsExporter(&AppConfig{StateDir: dir}).Record(executions[0]); err != nil {
+			t.Errorf("Record() error = %v", err)
+		}
+	})
+}
@@ -0,0 +1,234 @@
+package shared
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of forwarded metrics
+const (
+	DefaultStatsDPrefix = "plugin_app"
+	DefaultPushJob      = "plugin_app"
+)
+
+// metricsForwardTimeout bounds sending one execution's metrics, so a dead endpoint doesn't hold
+// up the end of a run
+const metricsForwardTimeout = 5 * time.Second
+
+// statsdPacketSize keeps datagrams below the common MTU
+const statsdPacketSize = 1432
+
+// statsdEscaper replaces what separates the parts of a StatsD line
+var statsdEscaper = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", "\n", "_", " ", "_")
+
+// validateForwarding checks where execution metrics are forwarded to
+func (c *MetricsConfig) validateForwarding() error {
+	if c.StatsD != "" {
+		if _, _, err := net.SplitHostPort(c.StatsD); err != nil {
+			return fmt.Errorf("invalid metrics statsd: %v", err)
+		}
+	}
+	if c.StatsDPrefix != "" && statsdEscaper.Replace(c.StatsDPrefix) != c.StatsDPrefix {
+		return fmt.Errorf("invalid metrics statsd_prefix: %q", c.StatsDPrefix)
+	}
+	if c.Pushgateway != "" {
+		u, err := url.Parse(c.Pushgateway)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid metrics pushgateway: %q (must be an http or https URL)", c.Pushgateway)
+		}
+	}
+	return nil
+}
+
+// forward sends the metrics of an execution with the labels it was exported with, and the
+// plugin's version, to the StatsD server and Pushgateway configured
+func (e *MetricsExporter) forward(labels map[string]string, m ExecutionMetrics) error {
+	forwarded := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		forwarded[k] = v
+	}
+	if m.Version != "" {
+		forwarded["version"] = m.Version
+	}
+
+	var errs []error
+	if e.config.StatsD != "" {
+		prefix := e.config.StatsDPrefix
+		if prefix == "" {
+			prefix = DefaultStatsDPrefix
+		}
+		if err := sendStatsD(e.config.StatsD, formatStatsD(prefix, forwarded, m)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send metrics to StatsD: %v", err))
+		}
+	}
+	if e.config.Pushgateway != "" {
+		job := e.config.PushJob
+		if job == "" {
+			job = DefaultPushJob
+		}
+		if err := pushMetrics(e.config.Pushgateway, job, forwarded, m); err != nil {
+			errs = append(errs, fmt.Errorf("failed to push metrics: %v", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// formatStatsD renders an execution as StatsD lines: a count by outcome, the execution time and
+// a gauge per plugin and host metric, tagged with the labels
+func formatStatsD(prefix string, labels map[string]string, m ExecutionMetrics) []string {
+	tags := statsdTags(labels)
+	status := "success"
+	if !m.Success {
+		status = "failure"
+	}
+	ms := strconv.FormatFloat(float64(m.Duration)/float64(time.Millisecond), 'f', -1, 64)
+	lines := []string{
+		prefix + ".executions:1|c" + tags + ",status:" + status,
+		prefix + ".execution_time:" + ms + "|ms" + tags,
+	}
+	for _, group := range []struct {
+		name    string
+		metrics map[string]float64
+	}{{"plugin", m.Metrics}, {"host", m.Host}} {
+		for _, name := range sortedMetricNames(group.metrics) {
+			value := strconv.FormatFloat(group.metrics[name], 'f', -1, 64)
+			lines = append(lines, prefix+"."+group.name+"."+statsdEscaper.Replace(name)+":"+value+"|g"+tags)
+		}
+	}
+	return lines
+}
+
+// statsdTags renders labels as DogStatsD tags in name order
+func statsdTags(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tags := make([]string, len(names))
+	for i, name := range names {
+		tags[i] = name + ":" + statsdEscaper.Replace(labels[name])
+	}
+	return "|#" + strings.Join(tags, ",")
+}
+
+// sendStatsD sends lines to a StatsD server, as many to a datagram as fit
+func sendStatsD(addr string, lines []string) error {
+	conn, err := net.DialTimeout("udp", addr, metricsForwardTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(metricsForwardTimeout))
+	var packet []byte
+	for _, line := range lines {
+		if len(packet) > 0 && len(packet)+1+len(line) > statsdPacketSize {
+			if _, err := conn.Write(packet); err != nil {
+				return err
+			}
+			packet = packet[:0]
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	if len(packet) > 0 {
+		_, err = conn.Write(packet)
+	}
+	return err
+}
+
+// formatPushMetrics renders the last execution of a plugin in the Prometheus text format; the
+// plugin label is left to the grouping key
+func formatPushMetrics(labels map[string]string, m ExecutionMetrics, finished time.Time) string {
+	sampleLabels := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if k != "plugin" {
+			sampleLabels[k] = v
+		}
+	}
+	success := 0.0
+	if m.Success {
+		success = 1
+	}
+
+	var b strings.Builder
+	b.WriteString("# HELP plugin_app_last_execution_success Whether the last execution succeeded.\n")
+	b.WriteString("# TYPE plugin_app_last_execution_success gauge\n")
+	writeSample(&b, "plugin_app_last_execution_success", sampleLabels, nil, success)
+	b.WriteString("# HELP plugin_app_last_execution_seconds How long the last execution took.\n")
+	b.WriteString("# TYPE plugin_app_last_execution_seconds gauge\n")
+	writeSample(&b, "plugin_app_last_execution_seconds", sampleLabels, nil, m.Duration.Seconds())
+	b.WriteString("# HELP plugin_app_last_execution_timestamp_seconds When the last execution finished.\n")
+	b.WriteString("# TYPE plugin_app_last_execution_timestamp_seconds gauge\n")
+	writeSample(&b, "plugin_app_last_execution_timestamp_seconds", sampleLabels, nil, float64(finished.UnixNano())/1e9)
+	if len(m.Metrics) > 0 {
+		b.WriteString("# HELP plugin_app_plugin_metric Latest value of each metric reported by plugins.\n")
+		b.WriteString("# TYPE plugin_app_plugin_metric gauge\n")
+		for _, name := range sortedMetricNames(m.Metrics) {
+			writeSample(&b, "plugin_app_plugin_metric", sampleLabels, map[string]string{"metric": name}, m.Metrics[name])
+		}
+	}
+	if len(m.Host) > 0 {
+		b.WriteString("# HELP plugin_app_host_metric What the host measured of the plugin's process in the last execution.\n")
+		b.WriteString("# TYPE plugin_app_host_metric gauge\n")
+		for _, name := range sortedMetricNames(m.Host) {
+			writeSample(&b, "plugin_app_host_metric", sampleLabels, map[string]string{"metric": name}, m.Host[name])
+		}
+	}
+	return b.String()
+}
+
+// pushMetrics replaces the metrics of the plugin's group on a Pushgateway with those of an execution
+func pushMetrics(gateway, job string, labels map[string]string, m ExecutionMetrics) error {
+	target := strings.TrimSuffix(gateway, "/") + "/metrics" + pushGroupingPath("job", job) + pushGroupingPath("plugin", labels["plugin"])
+	body := formatPushMetrics(labels, m, time.Now())
+	req, err := http.NewRequest(http.MethodPut, target, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	client := &http.Client{Timeout: metricsForwardTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+// pushGroupingPath renders a label of the grouping key as the Pushgateway expects it in the URL,
+// base64 encoded if a slash or emptiness would break the path
+func pushGroupingPath(label, value string) string {
+	if value == "" {
+		return "/" + label + "@base64/="
+	}
+	if strings.Contains(value, "/") {
+		return "/" + label + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return "/" + label + "/" + url.PathEscape(value)
+}
+
+func sortedMetricNames(metrics map[string]float64) []string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
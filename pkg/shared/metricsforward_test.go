@@ -0,0 +1,103 @@
+package shared
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsExporter_forward(t *testing.T) {
+	statsd, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer statsd.Close()
+
+	var pushPath, pushBody string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		pushPath, pushBody = r.Method+" "+r.URL.EscapedPath(), string(body)
+	}))
+	defer gateway.Close()
+
+	config := &AppConfig{StateDir: t.TempDir(), Metrics: &MetricsConfig{
+		Labels:      map[string]string{"environment": "prod"},
+		StatsD:      statsd.LocalAddr().String(),
+		Pushgateway: gateway.URL,
+	}}
+	err = NewMetricsExporter(config).Record(ExecutionMetrics{
+		Plugin:   "hello",
+		Version:  "1.2.0",
+		Success:  false,
+		Duration: 1500 * time.Millisecond,
+		Metrics:  map[string]float64{"rows": 42},
+		Host:     map[string]float64{"process_rss_bytes": 1024},
+	})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	statsd.SetReadDeadline(time.Now().Add(2 * time.Second))
+	packet := make([]byte, statsdPacketSize)
+	n, _, err := statsd.ReadFrom(packet)
+	if err != nil {
+		t.Fatalf("no StatsD packet: %v", err)
+	}
+	tags := "|#environment:prod,plugin:hello,version:1.2.0"
+	wantLines := []string{
+		"plugin_app.executions:1|c" + tags + ",status:failure",
+		"plugin_app.execution_time:1500|ms" + tags,
+		"plugin_app.plugin.rows:42|g" + tags,
+		"plugin_app.host.process_rss_bytes:1024|g" + tags,
+	}
+	if got := string(packet[:n]); got != strings.Join(wantLines, "\n") {
+		t.Errorf("StatsD packet = %q, want %q", got, strings.Join(wantLines, "\n"))
+	}
+
+	if pushPath != "PUT /metrics/job/plugin_app/plugin/hello" {
+		t.Errorf("pushed to %s", pushPath)
+	}
+	for _, want := range []string{
+		`plugin_app_last_execution_success{environment="prod",version="1.2.0"} 0`,
+		`plugin_app_last_execution_seconds{environment="prod",version="1.2.0"} 1.5`,
+		`plugin_app_plugin_metric{environment="prod",metric="rows",version="1.2.0"} 42`,
+		`plugin_app_host_metric{environment="prod",metric="process_rss_bytes",version="1.2.0"} 1024`,
+	} {
+		if !strings.Contains(pushBody, want+"\n") {
+			t.Errorf("pushed metrics lack %q:\n%s", want, pushBody)
+		}
+	}
+}
+
+func TestMetricsExporter_forwardFailure(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "pushed metrics are invalid", http.StatusBadRequest)
+	}))
+	defer gateway.Close()
+
+	config := &AppConfig{StateDir: t.TempDir(), Metrics: &MetricsConfig{Pushgateway: gateway.URL}}
+	err := NewMetricsExporter(config).Record(ExecutionMetrics{Plugin: "hello", Success: true})
+	if err == nil || !strings.Contains(err.Error(), "failed to push metrics: 400 Bad Request: pushed metrics are invalid") {
+		t.Errorf("Record() error = %v, want the Pushgateway's", err)
+	}
+	// The export is still written
+	if state, loadErr := NewMetricsExporter(config).load(); loadErr != nil || len(state.Series) != 1 {
+		t.Errorf("load() = %v, %v, want the execution's series", state, loadErr)
+	}
+}
+
+func TestPushGroupingPath(t *testing.T) {
+	for value, want := range map[string]string{
+		"hello":     "/plugin/hello",
+		"team/tool": "/plugin@base64/dGVhbS90b29s",
+		"":          "/plugin@base64/=",
+	} {
+		if got := pushGroupingPath("plugin", value); got != want {
+			t.Errorf("pushGroupingPath(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
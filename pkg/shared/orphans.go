@@ -0,0 +1,136 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pidRecord is what the pidfile of a launched plugin process holds. Start times tell a process
+// apart from a later one that reused its PID.
+type pidRecord struct {
+	Plugin      string `json:"plugin"`
+	PID         int    `json:"pid"`
+	Started     uint64 `json:"started"`
+	Port        int    `json:"port"`
+	HostPID     int    `json:"host_pid"`
+	HostStarted uint64 `json:"host_started"`
+}
+
+// OrphanProcess is a plugin process left running by a host that is gone
+type OrphanProcess struct {
+	Plugin string
+	PID    int
+	Port   int
+	Err    error // Why the process couldn't be killed
+}
+
+// PidPath returns the directory the pidfiles of launched plugin processes are kept in
+func (c *AppConfig) PidPath() string {
+	return filepath.Join(c.StateDir, "pids")
+}
+
+// writePidfile records a started plugin process and returns a function removing the record. It
+// records nothing without a state directory, or where processes can't be identified.
+func (pm *PluginManager) writePidfile(name string, pid, port int) func() {
+	if pm.config.StateDir == "" {
+		return func() {}
+	}
+	started, err := processStartTime(pid)
+	if err != nil {
+		return func() {}
+	}
+	hostStarted, err := processStartTime(os.Getpid())
+	if err != nil {
+		return func() {}
+	}
+	record := pidRecord{Plugin: name, PID: pid, Started: started, Port: port, HostPID: os.Getpid(), HostStarted: hostStarted}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return func() {}
+	}
+	// Losing the record only means the process isn't cleaned up after a crash of the host
+	dir := pm.config.PidPath()
+	path := filepath.Join(dir, fmt.Sprintf("%s.%d.json", name, pid))
+	if os.MkdirAll(dir, 0755) != nil || os.WriteFile(path, data, 0644) != nil {
+		return func() {}
+	}
+	return func() { os.Remove(path) }
+}
+
+// KillOrphans kills the plugin processes a crashed host run left behind, which would otherwise
+// keep their ports bound, and removes stale pidfiles. Processes of hosts that are still running
+// are left alone, and so is everything in read-only mode.
+func (pm *PluginManager) KillOrphans() ([]OrphanProcess, error) {
+	if pm.config.StateDir == "" || pm.ReadOnly() {
+		return nil, nil
+	}
+	dir := pm.config.PidPath()
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pidfiles: %v", err)
+	}
+
+	var orphans []OrphanProcess
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var record pidRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			os.Remove(path)
+			continue
+		}
+		if isRunning(record.HostPID, record.HostStarted) {
+			continue
+		}
+		if isRunning(record.PID, record.Started) {
+			orphan := OrphanProcess{Plugin: record.Plugin, PID: record.PID, Port: record.Port}
+			orphan.Err = killOrphan(record.PID, record.Started)
+			orphans = append(orphans, orphan)
+			// Keep the record of a process that survived, to try again next time
+			if orphan.Err != nil {
+				continue
+			}
+		}
+		os.Remove(path)
+	}
+	return orphans, nil
+}
+
+// isRunning reports whether the process that started at started still has pid
+func isRunning(pid int, started uint64) bool {
+	current, err := processStartTime(pid)
+	return err == nil && current == started
+}
+
+// killOrphan kills a process and waits for it to be gone, so its port is free once it returns
+func killOrphan(pid int, started uint64) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if err := process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to kill orphaned process %d: %v", pid, err)
+	}
+	deadline := time.Now().Add(killWait)
+	for isRunning(pid, started) && !isZombie(pid) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("orphaned process %d did not exit after being killed", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
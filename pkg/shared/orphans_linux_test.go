@@ -0,0 +1,97 @@
+package shared
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPluginManager_KillOrphans(t *testing.T) {
+	config := &AppConfig{StateDir: t.TempDir()}
+	pm := NewPluginManager(config)
+	defer pm.StopAll()
+
+	startSleep := func() *pluginProcess {
+		t.Helper()
+		cmd := exec.Command("sleep", "30")
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+		process := watchProcess("sleep", cmd, nil, nil)
+		t.Cleanup(func() { process.terminate(0) })
+		return process
+	}
+	startTime := func(pid int) uint64 {
+		t.Helper()
+		started, err := processStartTime(pid)
+		if err != nil {
+			t.Fatalf("processStartTime() error = %v", err)
+		}
+		return started
+	}
+	writeRecord := func(record pidRecord) string {
+		t.Helper()
+		data, err := json.Marshal(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(config.PidPath(), record.Plugin+".json")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	// The pidfile of a launched process is removed once it is no longer needed
+	owned := startSleep()
+	remove := pm.writePidfile("owned", owned.cmd.Process.Pid, 4000)
+	matches, _ := filepath.Glob(filepath.Join(config.PidPath(), "owned.*.json"))
+	if len(matches) != 1 {
+		t.Fatalf("pidfiles = %v, want one for the launched process", matches)
+	}
+	remove()
+	if matches, _ := filepath.Glob(filepath.Join(config.PidPath(), "owned.*.json")); len(matches) != 0 {
+		t.Errorf("pidfiles = %v after removal", matches)
+	}
+
+	// A process whose host is gone is killed, one of a running host is left alone, and the
+	// record of a process that already exited is dropped
+	host, self := os.Getpid(), startTime(os.Getpid())
+	orphaned, alive := startSleep(), startSleep()
+	orphanedPath := writeRecord(pidRecord{Plugin: "orphaned", PID: orphaned.cmd.Process.Pid, Started: startTime(orphaned.cmd.Process.Pid), Port: 4001, HostPID: host, HostStarted: self + 1})
+	alivePath := writeRecord(pidRecord{Plugin: "alive", PID: alive.cmd.Process.Pid, Started: startTime(alive.cmd.Process.Pid), Port: 4002, HostPID: host, HostStarted: self})
+	stalePath := writeRecord(pidRecord{Plugin: "stale", PID: alive.cmd.Process.Pid, Started: startTime(alive.cmd.Process.Pid) + 1, HostPID: host, HostStarted: self + 1})
+
+	orphans, err := pm.KillOrphans()
+	if err != nil {
+		t.Fatalf("KillOrphans() error = %v", err)
+	}
+	if len(orphans) != 1 || orphans[0].Plugin != "orphaned" || orphans[0].Port != 4001 || orphans[0].Err != nil {
+		t.Fatalf("KillOrphans() = %+v, want the orphaned process", orphans)
+	}
+	select {
+	case <-orphaned.exited:
+	case <-time.After(killWait):
+		t.Error("orphaned process is still running")
+	}
+	select {
+	case <-alive.exited:
+		t.Error("process of a running host was killed")
+	default:
+	}
+	for path, want := range map[string]bool{orphanedPath: false, alivePath: true, stalePath: false} {
+		if _, err := os.Stat(path); (err == nil) != want {
+			t.Errorf("pidfile %s exists = %v, want %v", filepath.Base(path), err == nil, want)
+		}
+	}
+
+	// Read-only mode never kills processes
+	pm.SetReadOnly(true)
+	writeRecord(pidRecord{Plugin: "orphaned", PID: alive.cmd.Process.Pid, Started: startTime(alive.cmd.Process.Pid), HostPID: host, HostStarted: self + 1})
+	if orphans, err := pm.KillOrphans(); err != nil || len(orphans) != 0 {
+		t.Errorf("KillOrphans() in read-only mode = %+v, %v", orphans, err)
+	}
+}
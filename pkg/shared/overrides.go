@@ -0,0 +1,44 @@
+package shared
+
+import (
+	"fmt"
+	"os"
+)
+
+// RunOverrides change how a local plugin is started for one run, leaving its configuration alone
+type RunOverrides struct {
+	Environment map[string]string // Set in the plugin's environment, over its configured env
+	WorkingDir  string            // Replaces the configured workdir unless empty
+}
+
+// SetRunOverrides makes the manager start the named local plugin with overrides, including when
+// it restarts the plugin; plugins already running keep how they were started
+func (pm *PluginManager) SetRunOverrides(name string, overrides RunOverrides) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if config, ok := pm.config.Plugins[name]; ok && config.IsRemote() {
+		return fmt.Errorf("plugin %s is remote; -env and -workdir only apply to local plugins", name)
+	}
+	if overrides.WorkingDir != "" {
+		info, err := os.Stat(overrides.WorkingDir)
+		if err != nil {
+			return fmt.Errorf("invalid working directory: %v", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("invalid working directory: %s is not a directory", overrides.WorkingDir)
+		}
+	}
+	if pm.overrides == nil {
+		pm.overrides = make(map[string]RunOverrides)
+	}
+	pm.overrides[name] = overrides
+	return nil
+}
+
+// workingDir returns the directory the plugin's processes run in
+func (m *ManagedPlugin) workingDir(config PluginConfig) string {
+	if m.overrides.WorkingDir != "" {
+		return m.overrides.WorkingDir
+	}
+	return config.WorkingDir
+}
@@ -0,0 +1,66 @@
+package shared
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPluginManager_SetRunOverrides(t *testing.T) {
+	dir := t.TempDir()
+	pm := NewPluginManager(&AppConfig{Plugins: map[string]PluginConfig{
+		"local":  {Type: PluginTypeBinary, Path: "/bin/true", WorkingDir: "/tmp"},
+		"remote": {Type: PluginTypeRemote, Address: "localhost:50051"},
+	}})
+	defer pm.StopAll()
+
+	tests := []struct {
+		name      string
+		plugin    string
+		overrides RunOverrides
+		wantErr   string
+	}{
+		{name: "Environment and directory", plugin: "local", overrides: RunOverrides{Environment: map[string]string{"A": "1"}, WorkingDir: dir}},
+		{name: "Remote plugin", plugin: "remote", overrides: RunOverrides{WorkingDir: dir}, wantErr: "only apply to local plugins"},
+		{name: "Missing directory", plugin: "local", overrides: RunOverrides{WorkingDir: filepath.Join(dir, "missing")}, wantErr: "invalid working directory"},
+		{name: "Not a directory", plugin: "local", overrides: RunOverrides{WorkingDir: "/bin/true"}, wantErr: "not a directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := pm.SetRunOverrides(tt.plugin, tt.overrides)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("SetRunOverrides() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("SetRunOverrides() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManagedPlugin_overrides(t *testing.T) {
+	config := PluginConfig{WorkingDir: "/srv/plugin", Environment: map[string]string{"LOG_LEVEL": "info", "REGION": "eu"}}
+	m := &ManagedPlugin{Config: config}
+	if got := m.workingDir(config); got != "/srv/plugin" {
+		t.Errorf("workingDir() = %q without overrides, want the configured one", got)
+	}
+
+	m.overrides = RunOverrides{Environment: map[string]string{"LOG_LEVEL": "debug"}, WorkingDir: "/tmp/run"}
+	if got := m.workingDir(config); got != "/tmp/run" {
+		t.Errorf("workingDir() = %q, want the override", got)
+	}
+	// Like exec.Cmd, the last entry for a variable is the one the plugin sees
+	values := make(map[string]string)
+	for _, kv := range m.environment() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			values[k] = v
+		}
+	}
+	if values["LOG_LEVEL"] != "debug" || values["REGION"] != "eu" {
+		t.Errorf("environment() has LOG_LEVEL=%q REGION=%q, want the override over the configured env", values["LOG_LEVEL"], values["REGION"])
+	}
+}
@@ -0,0 +1,80 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ParallelExecution is one plugin execution of a parallel run
+type ParallelExecution struct {
+	Name       string
+	Params     map[string]string
+	Handler    OutputHandler
+	Credential string          // Caller's own credential for a remote plugin, if any
+	ID         string          // Execution id, see WithExecutionID; "" for none
+	Timeout    time.Duration   // Deadline of the execution, 0 for none
+	Plugin     PluginInterface // Client to execute through, such as the daemon's; nil for the manager's plugin
+}
+
+// ParallelResult is the outcome of one execution of a parallel run
+type ParallelResult struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+	Err   error
+	Usage *ResourceUsage // Of the plugin's process over the execution, nil for remote plugins
+}
+
+// ExecuteParallel executes plugins concurrently, starting those that aren't running yet from the
+// configuration unless an execution brings its own client. Each execution streams to its own handler; the results are in the order of the
+// executions, and one plugin failing doesn't cancel the others.
+func (pm *PluginManager) ExecuteParallel(ctx context.Context, executions []ParallelExecution) []ParallelResult {
+	results := make([]ParallelResult, len(executions))
+	var wg sync.WaitGroup
+	for i, execution := range executions {
+		wg.Add(1)
+		go func(i int, execution ParallelExecution) {
+			defer wg.Done()
+			result := &results[i]
+			result.Name = execution.Name
+			result.Start = time.Now()
+			defer func() { result.End = time.Now() }()
+
+			if execution.Plugin != nil {
+				result.Err = ExecuteWithTimeout(WithExecutionID(WithCredential(ctx, execution.Credential), execution.ID), execution.Plugin, execution.Name, execution.Timeout, execution.Params, execution.Handler)
+				return
+			}
+			plugin, err := pm.runningPlugin(execution.Name)
+			if err != nil {
+				result.Err = err
+				return
+			}
+			measured := pm.MeasureUsage(execution.Name)
+			result.Err = ExecuteWithTimeout(WithExecutionID(WithCredential(ctx, execution.Credential), execution.ID), plugin, execution.Name, execution.Timeout, execution.Params, execution.Handler)
+			result.Usage = measured()
+		}(i, execution)
+	}
+	wg.Wait()
+	return results
+}
+
+// runningPlugin returns the named plugin, starting it if it isn't running
+func (pm *PluginManager) runningPlugin(name string) (PluginInterface, error) {
+	if plugin, err := pm.GetPlugin(name); err == nil {
+		return plugin, nil
+	}
+	config, ok := pm.Config().Plugins[name]
+	if !ok {
+		return nil, fmt.Errorf("plugin %q not found in configuration", name)
+	}
+	// Another execution of the run may have started it in the meantime
+	if err := pm.StartPlugin(name, config); err != nil {
+		if plugin, getErr := pm.GetPlugin(name); getErr == nil {
+			return plugin, nil
+		}
+		return nil, fmt.Errorf("failed to start plugin %s: %v", name, err)
+	}
+	return pm.GetPlugin(name)
+}
@@ -0,0 +1,123 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+)
+
+// barrierPlugin returns only once every execution sharing its barrier has started
+type barrierPlugin struct {
+	stubPlugin
+	arrived *sync.WaitGroup
+}
+
+func (p barrierPlugin) Execute(ctx context.Context, params map[string]string, output OutputHandler) error {
+	p.arrived.Done()
+	all := make(chan struct{})
+	go func() {
+		p.arrived.Wait()
+		close(all)
+	}()
+	select {
+	case <-all:
+		return output.OnOutput("hello " + params["name"])
+	case <-time.After(2 * time.Second):
+		return errors.New("executions did not run concurrently")
+	}
+}
+
+func TestPluginManager_ExecuteParallel(t *testing.T) {
+	arrived := &sync.WaitGroup{}
+	arrived.Add(2)
+	config := &AppConfig{Plugins: map[string]PluginConfig{}}
+	for _, name := range []string{"first", "second"} {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		server := grpc.NewServer()
+		proto.RegisterPluginServer(server, &GRPCServer{Impl: barrierPlugin{arrived: arrived}})
+		StartHealthServer(server)
+		go server.Serve(listener)
+		defer server.Stop()
+		config.Plugins[name] = PluginConfig{Type: PluginTypeRemote, Address: listener.Addr().String()}
+	}
+
+	pm := NewPluginManager(config)
+	defer pm.StopAll()
+	// One plugin is already running, the other is started for the run
+	if err := pm.StartPlugin("first", config.Plugins["first"]); err != nil {
+		t.Fatalf("StartPlugin() error = %v", err)
+	}
+
+	handlers := []*recordingHandler{{}, {}, {}}
+	results := pm.ExecuteParallel(context.Background(), []ParallelExecution{
+		{Name: "first", Params: map[string]string{"name": "a"}, Handler: handlers[0]},
+		{Name: "second", Params: map[string]string{"name": "b"}, Handler: handlers[1]},
+		{Name: "missing", Handler: handlers[2]},
+	})
+
+	if len(results) != 3 {
+		t.Fatalf("ExecuteParallel() returned %d results, want 3", len(results))
+	}
+	for i, want := range []string{"hello a", "hello b"} {
+		if results[i].Err != nil {
+			t.Errorf("%s: error = %v", results[i].Name, results[i].Err)
+			continue
+		}
+		if strings.Join(handlers[i].output, "\n") != want {
+			t.Errorf("%s: output = %v, want %q", results[i].Name, handlers[i].output, want)
+		}
+		if results[i].End.Before(results[i].Start) {
+			t.Errorf("%s: ended before it started", results[i].Name)
+		}
+	}
+	if results[2].Name != "missing" || results[2].Err == nil || !strings.Contains(results[2].Err.Error(), "not found in configuration") {
+		t.Errorf("missing: error = %v, want not found", results[2].Err)
+	}
+}
+
+func TestPluginManager_ExecuteParallelOwnClient(t *testing.T) {
+	arrived := &sync.WaitGroup{}
+	arrived.Add(1)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	proto.RegisterPluginServer(server, &GRPCServer{Impl: barrierPlugin{arrived: arrived}})
+	go server.Serve(listener)
+	defer server.Stop()
+
+	client, err := NewClientWithAddress(listener.Addr().String())
+	if err != nil {
+		t.Fatalf("NewClientWithAddress() error = %v", err)
+	}
+	defer client.Close()
+
+	// The plugin isn't configured, so the manager would fail to start it
+	pm := NewPluginManager(&AppConfig{Plugins: map[string]PluginConfig{}})
+	defer pm.StopAll()
+	handler := &recordingHandler{}
+	results := pm.ExecuteParallel(context.Background(), []ParallelExecution{
+		{Name: "warm", Params: map[string]string{"name": "d"}, Handler: handler, Plugin: client},
+	})
+
+	if results[0].Err != nil {
+		t.Fatalf("ExecuteParallel() error = %v", results[0].Err)
+	}
+	if strings.Join(handler.output, "\n") != "hello d" {
+		t.Errorf("output = %v, want %q", handler.output, "hello d")
+	}
+	if _, err := pm.GetPlugin("warm"); err == nil {
+		t.Error("the manager started a plugin for an execution with its own client")
+	}
+}
@@ -0,0 +1,189 @@
+package shared
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"google.golang.org/protobuf/types/known/structpb"
+	"gopkg.in/yaml.v3"
+)
+
+// TypedParams converts string parameters into typed values according to the parameter schema.
+// Values that don't parse as their declared type are passed through as strings so the plugin
+// can report the problem itself.
+func TypedParams(schema map[string]ParameterSpec, params map[string]string) (*structpb.Struct, error) {
+	values := make(map[string]interface{}, len(params))
+	for name, raw := range params {
+		values[name] = typedValue(schema[name].Type, raw)
+	}
+	return structpb.NewStruct(values)
+}
+
+// typedValue converts a single raw parameter value to the given schema type
+func typedValue(paramType, raw string) interface{} {
+	switch paramType {
+	case "int", "integer", "float", "number":
+		if f, err := strconv.ParseFloat(raw, 64); err == nil {
+			return f
+		}
+	case "bool", "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	case "object", "list", "array", "json":
+		var v interface{}
+		if err := json.Unmarshal([]byte(raw), &v); err == nil {
+			return v
+		}
+	}
+	return raw
+}
+
+// MissingParams returns the required parameters of a schema that have no value, in name order
+func MissingParams(schema map[string]ParameterSpec, params map[string]string) []string {
+	var missing []string
+	for name, spec := range schema {
+		if _, ok := params[name]; spec.Required && !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// LoadParamsFile reads parameters from a JSON object, or from a YAML mapping unless the file ends
+// in .json. Numbers and booleans become their usual string form; objects and lists become JSON,
+// as object, list and json parameters expect.
+func LoadParamsFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parameters file: %v", err)
+	}
+
+	var raw map[string]interface{}
+	if filepath.Ext(path) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(&raw)
+	} else {
+		err = yaml.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters file %s: %v", path, err)
+	}
+
+	params := make(map[string]string, len(raw))
+	for name, value := range raw {
+		text, err := paramFileValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameters file %s: parameter %s: %v", path, name, err)
+		}
+		params[name] = text
+	}
+	return params, nil
+}
+
+// paramFileValue converts a value decoded from a parameters file to a parameter value
+func paramFileValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", fmt.Errorf("no value")
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case json.Number:
+		return v.String(), nil
+	case int, int64, uint64:
+		return fmt.Sprint(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		// YAML mappings may have keys JSON can't represent, which fail here
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
+
+// UnknownParams returns the parameters a schema doesn't declare, in name order
+func UnknownParams(schema map[string]ParameterSpec, params map[string]string) []string {
+	var unknown []string
+	for name := range params {
+		if _, ok := schema[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// CheckDefaults checks a plugin's configured defaults against its parameter schema: each must be
+// a declared parameter, with one of its allowed values and of its type. Problems are returned in
+// parameter name order; values of secret parameters are left out of them.
+func CheckDefaults(schema map[string]ParameterSpec, defaults map[string]string) []error {
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []error
+	for _, name := range names {
+		value := defaults[name]
+		spec, ok := schema[name]
+		if !ok {
+			problems = append(problems, fmt.Errorf("default for %s: %v", name, unknownParam(name, schema)))
+			continue
+		}
+		switch {
+		case len(spec.AllowedValues) > 0 && !containsString(spec.AllowedValues, value):
+			if spec.IsSecret() {
+				problems = append(problems, fmt.Errorf("default for %s: must be one of the allowed values", name))
+			} else {
+				problems = append(problems, fmt.Errorf("default for %s: %q is not one of the allowed values %v", name, value, spec.AllowedValues))
+			}
+		case !validParamValue(spec.Type, value):
+			problems = append(problems, fmt.Errorf("default for %s: %q is not a valid %s", name, value, spec.Type))
+		}
+	}
+	return problems
+}
+
+// unknownParam describes a parameter the schema doesn't declare, suggesting the closest one it does
+func unknownParam(name string, schema map[string]ParameterSpec) error {
+	best, distance := "", 3 // Suggest only parameters up to two edits away
+	for known := range schema {
+		if d := editDistance(name, known); d < distance || d == distance && known < best {
+			best, distance = known, d
+		}
+	}
+	if best == "" {
+		return fmt.Errorf("not a parameter of the plugin")
+	}
+	return fmt.Errorf("not a parameter of the plugin (did you mean %s?)", best)
+}
+
+// validParamValue reports whether a raw value parses as the given schema type; values of types
+// the host doesn't know are left to the plugin
+func validParamValue(paramType, raw string) bool {
+	var err error
+	switch paramType {
+	case "int", "integer":
+		_, err = strconv.ParseInt(raw, 10, 64)
+	case "float", "number":
+		_, err = strconv.ParseFloat(raw, 64)
+	case "bool", "boolean":
+		_, err = strconv.ParseBool(raw)
+	case "object", "list", "array", "json":
+		return json.Valid([]byte(raw))
+	}
+	return err == nil
+}
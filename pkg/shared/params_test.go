@@ -0,0 +1,138 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMissingParams(t *testing.T) {
+	schema := map[string]ParameterSpec{
+		"num2":     {Name: "num2", Required: true},
+		"num1":     {Name: "num1", Required: true},
+		"language": {Name: "language"},
+	}
+	tests := []struct {
+		name   string
+		params map[string]string
+		want   []string
+	}{
+		{"All missing", map[string]string{}, []string{"num1", "num2"}},
+		{"Some given", map[string]string{"num2": "3", "language": "en"}, []string{"num1"}},
+		{"Empty values count as given", map[string]string{"num1": "", "num2": ""}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MissingParams(schema, tt.params); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MissingParams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadParamsFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:    "JSON",
+			file:    "params.json",
+			content: `{"message": "World", "count": 3, "ratio": 0.25, "big": 12345678901234567890, "loud": true, "tags": ["a", "b"], "opts": {"x": 1}}`,
+			want: map[string]string{
+				"message": "World", "count": "3", "ratio": "0.25", "big": "12345678901234567890", "loud": "true",
+				"tags": `["a","b"]`, "opts": `{"x":1}`,
+			},
+		},
+		{
+			name:    "YAML",
+			file:    "params.yaml",
+			content: "message: World\ncount: 3\nratio: 0.25\nloud: yes\nquoted: \"007\"\ntags:\n  - a\n  - b\nopts:\n  x: 1\n",
+			want: map[string]string{
+				"message": "World", "count": "3", "ratio": "0.25", "loud": "yes", "quoted": "007",
+				"tags": `["a","b"]`, "opts": `{"x":1}`,
+			},
+		},
+		{
+			name:    "Null value",
+			file:    "params.yml",
+			content: "message:\n",
+			wantErr: "parameter message: no value",
+		},
+		{
+			name:    "Not a mapping",
+			file:    "params.json",
+			content: `["message"]`,
+			wantErr: "invalid parameters file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadParamsFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadParamsFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadParamsFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadParamsFile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknownParams(t *testing.T) {
+	schema := map[string]ParameterSpec{"num1": {}, "num2": {}}
+	got := UnknownParams(schema, map[string]string{"num1": "1", "num3": "3", "lang": "en"})
+	if want := []string{"lang", "num3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UnknownParams() = %v, want %v", got, want)
+	}
+}
+
+func TestCheckDefaults(t *testing.T) {
+	schema := map[string]ParameterSpec{
+		"language": {Type: "string", AllowedValues: []string{"en", "fr"}},
+		"count":    {Type: "int"},
+		"verbose":  {Type: "bool"},
+		"options":  {Type: "object"},
+		"token":    {Name: "token", Type: "secret", AllowedValues: []string{"a"}},
+	}
+	defaults := map[string]string{
+		"langauge": "en",
+		"language": "de",
+		"count":    "1.5",
+		"verbose":  "true",
+		"options":  `{"a": 1}`,
+		"token":    "hunter2",
+		"colour":   "red",
+	}
+
+	var got []string
+	for _, err := range CheckDefaults(schema, defaults) {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		"default for colour: not a parameter of the plugin",
+		`default for count: "1.5" is not a valid int`,
+		"default for langauge: not a parameter of the plugin (did you mean language?)",
+		`default for language: "de" is not one of the allowed values [en fr]`,
+		"default for token: must be one of the allowed values",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckDefaults() = %q, want %q", got, want)
+	}
+}
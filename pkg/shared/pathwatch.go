@@ -0,0 +1,184 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchPaths watches files and directories, directories with everything below them, and sends
+// the paths that changed once no change has followed for debounce. Changes that arrive while the
+// receiver is busy are merged into the next send. The channel is closed when ctx is done.
+func WatchPaths(ctx context.Context, paths []string, debounce time.Duration) (<-chan []string, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch paths: %v", err)
+	}
+
+	// Files are watched through their directory, which follows editors replacing them
+	files := make(map[string]bool)
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		stat, err := os.Stat(path)
+		if err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("failed to watch %s: %v", path, err)
+		}
+		if stat.IsDir() {
+			dirs[path] = true
+			if err := addTree(watcher, path); err != nil {
+				watcher.Close()
+				return nil, err
+			}
+			continue
+		}
+		files[path] = true
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("failed to watch %s: %v", path, err)
+		}
+	}
+
+	// watched reports whether a changed path is one of the files or below one of the directories
+	watched := func(name string) bool {
+		if files[name] {
+			return true
+		}
+		for dir := filepath.Dir(name); ; dir = filepath.Dir(dir) {
+			if dirs[dir] {
+				return true
+			}
+			if parent := filepath.Dir(dir); parent == dir {
+				return false
+			}
+		}
+	}
+
+	changes := make(chan []string)
+	go func() {
+		defer close(changes)
+		defer watcher.Close()
+
+		pending := make(map[string]bool)
+		settle := time.NewTimer(debounce)
+		settle.Stop()
+		defer settle.Stop()
+		var ready []string // Settled changes waiting for the receiver
+		for {
+			var send chan<- []string
+			if ready != nil {
+				send = changes
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				name := filepath.Clean(event.Name)
+				if event.Has(fsnotify.Chmod) || !watched(name) {
+					continue
+				}
+				// Directories created below a watched one are watched too
+				if event.Has(fsnotify.Create) {
+					if stat, err := os.Stat(name); err == nil && stat.IsDir() {
+						if err := addTree(watcher, name); err != nil {
+							Logger(LogWatch).Warn("failed to watch a new directory", "error", err)
+						}
+					}
+				}
+				pending[name] = true
+				settle.Reset(debounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				Logger(LogWatch).Warn("watching paths failed", "error", err)
+			case <-settle.C:
+				for name := range pending {
+					ready = append(ready, name)
+				}
+				sort.Strings(ready)
+				ready = uniqueSorted(ready)
+				pending = make(map[string]bool)
+			case send <- ready:
+				ready = nil
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// addTree watches a directory and every directory below it
+func addTree(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to watch %s: %v", path, err)
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if err := watcher.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %v", path, err)
+		}
+		return nil
+	})
+}
+
+// uniqueSorted drops repeated values from a sorted slice
+func uniqueSorted(values []string) []string {
+	unique := values[:0]
+	for i, value := range values {
+		if i == 0 || value != values[i-1] {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// RerunOnChange calls run once and again for every set of changes, canceling the run still in
+// flight and waiting for it to return before starting the next. It returns when ctx is done or
+// changes is closed, after the last run returned.
+func RerunOnChange(ctx context.Context, changes <-chan []string, run func(ctx context.Context, changed []string)) {
+	var (
+		cancel context.CancelFunc
+		wg     sync.WaitGroup
+	)
+	start := func(changed []string) {
+		var runCtx context.Context
+		runCtx, cancel = context.WithCancel(ctx)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run(runCtx, changed)
+		}()
+	}
+	stop := func() {
+		cancel()
+		wg.Wait()
+	}
+
+	start(nil)
+	defer stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case changed, ok := <-changes:
+			if !ok {
+				return
+			}
+			stop()
+			start(changed)
+		}
+	}
+}
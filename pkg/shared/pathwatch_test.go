@@ -0,0 +1,140 @@
+package shared
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// nextChange waits for the watcher to send changes
+func nextChange(t *testing.T, changes <-chan []string) []string {
+	t.Helper()
+	select {
+	case changed := <-changes:
+		return changed
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported")
+		return nil
+	}
+}
+
+func TestWatchPaths(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.Mkdir(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(dir, "input.txt")
+	other := filepath.Join(dir, "other.txt")
+	for _, path := range []string{input, other} {
+		if err := os.WriteFile(path, []byte("a"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := WatchPaths(ctx, []string{src, input}, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WatchPaths() error = %v", err)
+	}
+
+	t.Run("Burst of writes is sent once", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			if err := os.WriteFile(input, []byte{byte('a' + i)}, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := nextChange(t, changes); !reflect.DeepEqual(got, []string{input}) {
+			t.Errorf("changed = %v, want [%s]", got, input)
+		}
+	})
+
+	t.Run("Unwatched file next to a watched one", func(t *testing.T) {
+		if err := os.WriteFile(other, []byte("b"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case got := <-changes:
+			t.Errorf("changed = %v, want nothing", got)
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+
+	t.Run("New directory below a watched one", func(t *testing.T) {
+		nested := filepath.Join(src, "nested")
+		if err := os.Mkdir(nested, 0755); err != nil {
+			t.Fatal(err)
+		}
+		nextChange(t, changes)
+		file := filepath.Join(nested, "main.go")
+		if err := os.WriteFile(file, []byte("package main"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := nextChange(t, changes); !reflect.DeepEqual(got, []string{file}) {
+			t.Errorf("changed = %v, want [%s]", got, file)
+		}
+	})
+
+	cancel()
+	select {
+	case _, ok := <-changes:
+		if ok {
+			t.Error("change sent after the context was canceled")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("channel not closed after the context was canceled")
+	}
+}
+
+func TestWatchPaths_missing(t *testing.T) {
+	if _, err := WatchPaths(context.Background(), []string{filepath.Join(t.TempDir(), "missing")}, time.Millisecond); err == nil {
+		t.Error("WatchPaths() error = nil, want an error for a missing path")
+	}
+}
+
+func TestRerunOnChange(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan []string)
+
+	var mu sync.Mutex
+	var started [][]string
+	canceled := 0
+	running := make(chan struct{}, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		RerunOnChange(ctx, changes, func(ctx context.Context, changed []string) {
+			mu.Lock()
+			started = append(started, changed)
+			mu.Unlock()
+			running <- struct{}{}
+			<-ctx.Done()
+			mu.Lock()
+			canceled++
+			mu.Unlock()
+		})
+	}()
+
+	<-running
+	changes <- []string{"a.txt"}
+	<-running
+	changes <- []string{"b.txt"}
+	<-running
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := [][]string{nil, {"a.txt"}, {"b.txt"}}
+	if !reflect.DeepEqual(started, want) {
+		t.Errorf("runs = %v, want %v", started, want)
+	}
+	if canceled != 3 {
+		t.Errorf("canceled runs = %d, want all 3 to have returned", canceled)
+	}
+}
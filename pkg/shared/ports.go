@@ -0,0 +1,220 @@
+package shared
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+)
+
+// allocatePort returns a localhost port the kernel reports as free. The listener is closed
+// before the plugin binds the port, so callers should expect a rare collision with another
+// process and surface it as a start failure.
+func allocatePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// portFree reports whether a localhost port can be bound right now
+func portFree(port int) bool {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
+
+// portAllocator hands out ports to plugins that don't configure theirs: from settings.port_range
+// if set, otherwise whichever the kernel picks. Ports are tracked until they are released, so
+// plugins starting at the same time never get the same one, and ports configured for plugins
+// are left to them.
+type portAllocator struct {
+	mu          sync.Mutex
+	first, last int          // settings.port_range, 0 without one
+	next        int          // Offset into the range the search continues from, so released ports aren't reused right away
+	fixed       map[int]bool // Ports configured for plugins
+	inUse       map[int]bool
+}
+
+// configure takes the port range and the fixed ports of config
+func (a *portAllocator) configure(config *AppConfig) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.first, a.last = 0, 0
+	if config.Settings != nil && len(config.Settings.PortRange) == 2 {
+		a.first, a.last = config.Settings.PortRange[0], config.Settings.PortRange[1]
+	}
+	a.fixed = make(map[int]bool)
+	for _, plugin := range config.Plugins {
+		if plugin.IsRemote() {
+			continue
+		}
+		a.fixed[plugin.Port] = true
+		a.fixed[plugin.StandbyPort] = true
+	}
+	delete(a.fixed, 0)
+}
+
+// allocate returns a free port and tracks it as in use
+func (a *portAllocator) allocate() (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.inUse == nil {
+		a.inUse = make(map[int]bool)
+	}
+	if a.first == 0 {
+		// The kernel may hand out a port again that was allocated but not bound yet
+		for attempt := 0; attempt < 10; attempt++ {
+			port, err := allocatePort()
+			if err != nil {
+				return 0, err
+			}
+			if !a.inUse[port] && !a.fixed[port] {
+				a.inUse[port] = true
+				return port, nil
+			}
+		}
+		return 0, fmt.Errorf("failed to allocate port: the kernel keeps picking ports in use")
+	}
+	size := a.last - a.first + 1
+	for i := 0; i < size; i++ {
+		port := a.first + (a.next+i)%size
+		if a.inUse[port] || a.fixed[port] || !portFree(port) {
+			continue
+		}
+		a.next = (a.next + i + 1) % size
+		a.inUse[port] = true
+		return port, nil
+	}
+	return 0, fmt.Errorf("failed to allocate port: none free in port_range %d-%d", a.first, a.last)
+}
+
+// release makes an allocated port available again
+func (a *portAllocator) release(port int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.inUse, port)
+}
+
+// lease returns a lease on ports of the allocator for one plugin
+func (a *portAllocator) lease() *portLease {
+	return &portLease{allocator: a}
+}
+
+// portLease holds the ports allocated to the instances of one plugin, released when it stops.
+// A nil lease allocates untracked ports.
+type portLease struct {
+	allocator *portAllocator
+	mu        sync.Mutex
+	ports     []int
+}
+
+// allocate returns a free port held by the lease
+func (l *portLease) allocate() (int, error) {
+	if l == nil {
+		return allocatePort()
+	}
+	port, err := l.allocator.allocate()
+	if err != nil {
+		return 0, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ports = append(l.ports, port)
+	return port, nil
+}
+
+// release gives back one of the lease's ports; other ports are ignored
+func (l *portLease) release(port int) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, p := range l.ports {
+		if p == port {
+			l.ports = append(l.ports[:i], l.ports[i+1:]...)
+			l.allocator.release(port)
+			return
+		}
+	}
+}
+
+// releaseAll gives back every port of the lease
+func (l *portLease) releaseAll() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, port := range l.ports {
+		l.allocator.release(port)
+	}
+	l.ports = nil
+}
+
+// allocatePorts fills in the ports a local plugin leaves unset from lease
+func (p *PluginConfig) allocatePorts(lease *portLease) error {
+	if p.Port == 0 {
+		port, err := lease.allocate()
+		if err != nil {
+			return err
+		}
+		p.Port = port
+	}
+	for p.Standby && (p.StandbyPort == 0 || p.StandbyPort == p.Port) {
+		port, err := lease.allocate()
+		if err != nil {
+			return err
+		}
+		p.StandbyPort = port
+	}
+	return nil
+}
+
+// checkPorts rejects local plugins configured with the same fixed port, which would otherwise
+// only fail once both are started
+func (c *AppConfig) checkPorts() error {
+	if clashes := c.portClashes(); len(clashes) > 0 {
+		return clashes[0]
+	}
+	return nil
+}
+
+// portClashes returns an error for every local plugin whose fixed port another one already uses
+func (c *AppConfig) portClashes() []error {
+	names := make([]string, 0, len(c.Plugins))
+	for name := range c.Plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var clashes []error
+	owners := make(map[int]string)
+	for _, name := range names {
+		plugin := c.Plugins[name]
+		if plugin.IsRemote() {
+			continue
+		}
+		ports := []int{plugin.Port}
+		if plugin.Standby {
+			ports = append(ports, plugin.StandbyPort)
+		}
+		for _, port := range ports {
+			if port == 0 {
+				continue
+			}
+			if owner, ok := owners[port]; ok && owner != name {
+				clashes = append(clashes, fmt.Errorf("plugins %q and %q both use port %d (omit port to use a free one)", owner, name, port))
+				continue
+			}
+			owners[port] = name
+		}
+	}
+	return clashes
+}
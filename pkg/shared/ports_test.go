@@ -0,0 +1,176 @@
+package shared
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPluginConfig_allocatePorts(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PluginConfig
+		fixed  int
+	}{
+		{
+			name:   "Automatic port",
+			config: PluginConfig{Type: PluginTypeBinary, Path: "plugin"},
+		},
+		{
+			name:   "Fixed port with automatic standby",
+			config: PluginConfig{Type: PluginTypeBinary, Path: "plugin", Port: 50051, Standby: true},
+			fixed:  50051,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			if err := config.allocatePorts((&portAllocator{}).lease()); err != nil {
+				t.Fatalf("allocatePorts() error = %v", err)
+			}
+			if config.Port <= 0 {
+				t.Errorf("Port = %d, want an allocated port", config.Port)
+			}
+			if tt.fixed != 0 && config.Port != tt.fixed {
+				t.Errorf("Port = %d, want configured %d", config.Port, tt.fixed)
+			}
+			if config.Standby && (config.StandbyPort <= 0 || config.StandbyPort == config.Port) {
+				t.Errorf("StandbyPort = %d, want a port other than %d", config.StandbyPort, config.Port)
+			}
+		})
+	}
+}
+
+func TestPortAllocator_portRange(t *testing.T) {
+	// A range of free ports, one of which is taken by another process
+	first, err := allocatePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for port := first; port <= first+4; port++ {
+		if port > 65535 || !portFree(port) {
+			t.Skipf("port %d not free", port)
+		}
+	}
+	taken, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", first+4))
+	if err != nil {
+		t.Skipf("port %d not free: %v", first+4, err)
+	}
+	defer taken.Close()
+
+	a := &portAllocator{}
+	a.configure(&AppConfig{
+		Settings: &Settings{PortRange: []int{first, first + 4}},
+		Plugins:  map[string]PluginConfig{"fixed": {Type: PluginTypeBinary, Path: "p", Port: first + 1}},
+	})
+
+	// Concurrent allocations never share a port, nor get the fixed or taken one
+	ports := make([]int, 3)
+	errs := make([]error, 3)
+	var wg sync.WaitGroup
+	for i := range ports {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ports[i], errs[i] = a.allocate()
+		}(i)
+	}
+	wg.Wait()
+	seen := map[int]bool{}
+	for i, port := range ports {
+		if errs[i] != nil {
+			t.Fatalf("allocate() error = %v", errs[i])
+		}
+		if port < first || port > first+4 || port == first+1 || port == first+4 || seen[port] {
+			t.Errorf("allocate() = %d, want a distinct free port of %d-%d", port, first, first+4)
+		}
+		seen[port] = true
+	}
+	if _, err := a.allocate(); err == nil || !strings.Contains(err.Error(), "none free in port_range") {
+		t.Fatalf("allocate() on an exhausted range error = %v", err)
+	}
+
+	// A released port can be allocated again
+	a.release(ports[1])
+	if port, err := a.allocate(); err != nil || port != ports[1] {
+		t.Errorf("allocate() after release = %d, %v; want %d", port, err, ports[1])
+	}
+}
+
+func TestPortLease(t *testing.T) {
+	a := &portAllocator{}
+	lease := a.lease()
+	config := PluginConfig{Type: PluginTypeBinary, Path: "plugin", Standby: true}
+	if err := config.allocatePorts(lease); err != nil {
+		t.Fatalf("allocatePorts() error = %v", err)
+	}
+	if !a.inUse[config.Port] || !a.inUse[config.StandbyPort] {
+		t.Fatalf("ports %d and %d not tracked as in use", config.Port, config.StandbyPort)
+	}
+	lease.release(config.Port)
+	if a.inUse[config.Port] || !a.inUse[config.StandbyPort] {
+		t.Errorf("release() freed the wrong ports: %v", a.inUse)
+	}
+	lease.releaseAll()
+	if len(a.inUse) != 0 {
+		t.Errorf("releaseAll() left %v in use", a.inUse)
+	}
+}
+
+func TestAppConfig_checkPorts(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugins  map[string]PluginConfig
+		wantErr  bool
+		errorMsg string
+	}{
+		{
+			name: "Automatic ports never collide",
+			plugins: map[string]PluginConfig{
+				"a": {Type: PluginTypeBinary, Path: "a"},
+				"b": {Type: PluginTypeBinary, Path: "b"},
+			},
+		},
+		{
+			name: "Shared fixed port",
+			plugins: map[string]PluginConfig{
+				"a": {Type: PluginTypeBinary, Path: "a", Port: 50051},
+				"b": {Type: PluginTypeBinary, Path: "b", Port: 50051},
+			},
+			wantErr:  true,
+			errorMsg: `plugins "a" and "b" both use port 50051`,
+		},
+		{
+			name: "Standby port of another plugin",
+			plugins: map[string]PluginConfig{
+				"a": {Type: PluginTypeBinary, Path: "a", Port: 50051, Standby: true, StandbyPort: 50052},
+				"b": {Type: PluginTypeBinary, Path: "b", Port: 50052},
+			},
+			wantErr:  true,
+			errorMsg: "both use port 50052",
+		},
+		{
+			name: "Remote plugins are ignored",
+			plugins: map[string]PluginConfig{
+				"a": {Type: PluginTypeBinary, Path: "a", Port: 50051},
+				"b": {Type: PluginTypeRemote, Address: "localhost:50051", Port: 50051},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &AppConfig{Plugins: tt.plugins}
+			err := config.checkPorts()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkPorts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("error %q does not contain %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
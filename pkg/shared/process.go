@@ -0,0 +1,169 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// stderrTailSize is how much of a plugin's latest stderr is kept for its exit report
+const stderrTailSize = 4096
+
+// ProcessExit describes how a plugin process ended
+type ProcessExit struct {
+	Plugin     string
+	PID        int
+	ExitCode   int // -1 if the process was killed by a signal
+	Err        error
+	StderrTail string // The last lines the process wrote to stderr
+	Time       time.Time
+}
+
+func (e *ProcessExit) Error() string {
+	msg := fmt.Sprintf("plugin %s (pid %d) exited: %v", e.Plugin, e.PID, e.Err)
+	if e.StderrTail != "" {
+		msg += "; stderr: " + e.StderrTail
+	}
+	return msg
+}
+
+// tailWriter keeps the last bytes written to it
+type tailWriter struct {
+	mu   sync.Mutex
+	buf  []byte
+	size int
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	if over := len(w.buf) - w.size; over > 0 {
+		w.buf = append(w.buf[:0], w.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+// String returns the kept output, starting at a line boundary once output was dropped
+func (w *tailWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	tail := string(w.buf)
+	if len(w.buf) == w.size {
+		if i := strings.IndexByte(tail, '\n'); i >= 0 {
+			tail = tail[i+1:]
+		}
+	}
+	return strings.TrimSpace(tail)
+}
+
+// pluginProcess is a started plugin process. A goroutine of its own waits on it, so crashes are
+// noticed as they happen and no zombie is left behind.
+type pluginProcess struct {
+	cmd     *exec.Cmd
+	started time.Time
+	exited  chan struct{}
+	exit    *ProcessExit // Set before exited is closed
+
+	mu      sync.Mutex
+	sampled *ResourceUsage // Previous sample of recentUsage
+}
+
+// watchProcess waits on a started command in the background and calls onExit, if set, once it
+// has exited
+func watchProcess(name string, cmd *exec.Cmd, stderr *tailWriter, onExit func(*pluginProcess)) *pluginProcess {
+	p := &pluginProcess{cmd: cmd, started: time.Now(), exited: make(chan struct{})}
+	go func() {
+		err := cmd.Wait()
+		exit := &ProcessExit{Plugin: name, PID: cmd.Process.Pid, ExitCode: cmd.ProcessState.ExitCode(), Err: err, Time: time.Now()}
+		if err == nil {
+			exit.Err = errors.New(cmd.ProcessState.String())
+		}
+		if stderr != nil {
+			exit.StderrTail = stderr.String()
+		}
+		p.exit = exit
+		close(p.exited)
+		if onExit != nil {
+			onExit(p)
+		}
+	}()
+	return p
+}
+
+// kill kills the process without waiting for it
+func (p *pluginProcess) kill() {
+	p.cmd.Process.Kill()
+}
+
+// terminate sends SIGTERM and kills the process if it hasn't exited within the grace period.
+// Where signals aren't supported the process is killed right away.
+func (p *pluginProcess) terminate(grace time.Duration) error {
+	if grace > 0 && p.cmd.Process.Signal(syscall.SIGTERM) == nil {
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-p.exited:
+			return nil
+		case <-timer.C:
+		}
+	}
+
+	if err := p.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	// Output copying can outlive the process when it left children holding its pipes
+	select {
+	case <-p.exited:
+	case <-time.After(killWait):
+	}
+	return nil
+}
+
+// SetProcessExitHandler registers a function called whenever a plugin process exits without
+// being stopped, once the plugin was failed over or restarted
+func (pm *PluginManager) SetProcessExitHandler(handler func(*ProcessExit)) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.processExited = handler
+}
+
+// onProcessExit handles the exit of one of a plugin's processes. Exits of processes that were
+// stopped or replaced are expected; a crashed primary is failed over or restarted right away, a
+// crashed standby or replica is replaced.
+func (pm *PluginManager) onProcessExit(m *ManagedPlugin, p *pluginProcess) {
+	Logger(LogProcess).Debug("process exited", "plugin", m.Name, "exit", p.exit)
+	pm.mu.Lock()
+	if pm.plugins[m.Name] != m {
+		pm.mu.Unlock()
+		return
+	}
+	switch {
+	case m.process == p:
+		m.LastError = p.exit
+		m.LastExit = p.exit
+		pm.states.update(m, StateUnhealthy)
+		pm.recoverPlugin(m)
+	case m.standby != nil && m.standby.process == p:
+		m.LastError = fmt.Errorf("standby crashed: %v", p.exit)
+		m.standby.client.Close()
+		m.standby = nil
+		go pm.replaceStandby(m, m.Config, pm.stdout, pm.stderr)
+	case m.replicaOf(p) != nil:
+		pm.replaceReplica(m, m.replicaOf(p), p.exit)
+	default:
+		pm.mu.Unlock()
+		return
+	}
+	handler := pm.processExited
+	pm.mu.Unlock()
+
+	if handler != nil {
+		handler(p.exit)
+	}
+}
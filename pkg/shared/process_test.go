@@ -0,0 +1,120 @@
+package shared
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTailWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		size   int
+		want   string
+	}{
+		{name: "Fits", writes: []string{"first\n", "second\n"}, size: 64, want: "first\nsecond"},
+		{name: "Keeps the last whole lines", writes: []string{"one\ntwo\n", "three\nfour\n"}, size: 12, want: "three\nfour"},
+		{name: "Single long line", writes: []string{"abcdefghij"}, size: 4, want: "ghij"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &tailWriter{size: tt.size}
+			for _, s := range tt.writes {
+				if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+					t.Fatalf("Write() = %d, %v", n, err)
+				}
+			}
+			if got := w.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPluginManager_ProcessExit(t *testing.T) {
+	primary, _ := NewClientWithAddress("127.0.0.1:1")
+	pm := NewPluginManager(&AppConfig{})
+	defer pm.StopAll()
+	managed := &ManagedPlugin{
+		Name:       "crashing",
+		Config:     PluginConfig{Path: "/nonexistent/plugin", Port: 1000, Type: PluginTypeBinary},
+		Client:     primary,
+		GRPCClient: primary.(*GRPCClient),
+	}
+
+	exits := make(chan *ProcessExit, 1)
+	pm.SetProcessExitHandler(func(exit *ProcessExit) { exits <- exit })
+
+	tail := &tailWriter{size: stderrTailSize}
+	cmd := exec.Command("sh", "-c", "read line; echo 'panic: boom' >&2; exit 3")
+	cmd.Stderr = tail
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	pm.mu.Lock()
+	managed.setProcess(watchProcess(managed.Name, cmd, tail, func(p *pluginProcess) { pm.onProcessExit(managed, p) }))
+	pm.plugins["crashing"] = managed
+	pm.mu.Unlock()
+
+	stdin.Write([]byte("crash\n"))
+	var exit *ProcessExit
+	select {
+	case exit = <-exits:
+	case <-time.After(2 * time.Second):
+		t.Fatal("crash was not reported")
+	}
+
+	if exit.Plugin != "crashing" || exit.ExitCode != 3 || exit.StderrTail != "panic: boom" {
+		t.Errorf("exit = %+v, want code 3 with the stderr tail", exit)
+	}
+	if !strings.Contains(exit.Error(), "exit status 3") {
+		t.Errorf("Error() = %q, want the exit status", exit.Error())
+	}
+
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	// The restart was attempted right away and failed on the missing binary
+	if managed.RestartCnt != 1 || managed.LastExit != exit {
+		t.Errorf("RestartCnt = %d, LastExit = %v; want a restart after the recorded exit", managed.RestartCnt, managed.LastExit)
+	}
+	if managed.LastError == nil || !strings.Contains(managed.LastError.Error(), "failed to restart") {
+		t.Errorf("LastError = %v, want the failed restart", managed.LastError)
+	}
+}
+
+func TestPluginManager_ProcessExitAfterStop(t *testing.T) {
+	pm := NewPluginManager(&AppConfig{})
+	client, _ := NewClientWithAddress("127.0.0.1:1")
+	managed := &ManagedPlugin{Name: "stopped", Client: client, GRPCClient: client.(*GRPCClient)}
+
+	reported := make(chan *ProcessExit, 1)
+	pm.SetProcessExitHandler(func(exit *ProcessExit) { reported <- exit })
+
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	pm.mu.Lock()
+	managed.setProcess(watchProcess(managed.Name, cmd, nil, func(p *pluginProcess) { pm.onProcessExit(managed, p) }))
+	pm.plugins["stopped"] = managed
+	pm.mu.Unlock()
+
+	if err := pm.StopPlugin("stopped"); err != nil {
+		t.Fatalf("StopPlugin() error = %v", err)
+	}
+	select {
+	case exit := <-reported:
+		t.Errorf("exit of a stopped plugin was reported: %v", exit)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if managed.RestartCnt != 0 {
+		t.Errorf("stopped plugin was restarted")
+	}
+}
@@ -0,0 +1,128 @@
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ProfileEnvVar selects the profile LoadConfig applies, such as staging
+const ProfileEnvVar = "PLUGIN_APP_PROFILE"
+
+// ProfileConfig overrides the settings of plugins that differ between environments
+type ProfileConfig struct {
+	Plugins map[string]PluginOverride `json:"plugins"`
+}
+
+// PluginOverride is what a profile changes about a plugin. Addresses replace the plugin's, env
+// and defaults are merged into its own key by key.
+type PluginOverride struct {
+	Address           string            `json:"address,omitempty"`
+	Addresses         []string          `json:"addresses,omitempty"`
+	FallbackAddresses []string          `json:"fallback_addresses,omitempty"`
+	Environment       map[string]string `json:"env,omitempty"`
+	Defaults          map[string]string `json:"defaults,omitempty"`
+}
+
+// applyProfile merges the overrides of the named profile into the plugins of config, returning
+// the overrides of plugins that don't exist as problems. The error is for an unknown profile.
+func applyProfile(config *AppConfig, name string) ([]ConfigProblem, error) {
+	if name == "" {
+		return nil, nil
+	}
+	profile, ok := config.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(config.Profiles))
+		for known := range config.Profiles {
+			names = append(names, known)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown profile %q (profiles: %s)", name, strings.Join(names, ", "))
+	}
+	config.profile = name
+
+	var problems []ConfigProblem
+	for plugin, override := range profile.Plugins {
+		p, ok := config.Plugins[plugin]
+		if !ok {
+			problems = append(problems, ConfigProblem{Path: "profiles." + name + ".plugins." + plugin, Err: fmt.Errorf("no such plugin")})
+			continue
+		}
+		if override.Address != "" {
+			p.Address = override.Address
+		}
+		if override.Addresses != nil {
+			p.Addresses = override.Addresses
+		}
+		if override.FallbackAddresses != nil {
+			p.FallbackAddresses = override.FallbackAddresses
+		}
+		p.Environment = mergeStrings(p.Environment, override.Environment)
+		p.Defaults = mergeStrings(p.Defaults, override.Defaults)
+		config.Plugins[plugin] = p
+	}
+	sort.Slice(problems, func(i, j int) bool { return problems[i].Path < problems[j].Path })
+	return problems, nil
+}
+
+// mergeStrings returns a copy of base with overrides set over it
+func mergeStrings(base, overrides map[string]string) map[string]string {
+	if len(overrides) == 0 {
+		return base
+	}
+	merged := make(map[string]string, len(base)+len(overrides))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged
+}
+
+// Profile returns the profile the configuration was loaded with, "" for none
+func (c *AppConfig) Profile() string {
+	return c.profile
+}
+
+// Masked returns a copy of the configuration to show, such as the effective configuration of a
+// profile: credentials, and values that are encrypted on disk, are masked
+func (c *AppConfig) Masked() (*AppConfig, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy config: %v", err)
+	}
+	var masked AppConfig
+	if err := json.Unmarshal(data, &masked); err != nil {
+		return nil, fmt.Errorf("failed to copy config: %v", err)
+	}
+	encrypted := make(map[string]bool, len(c.encrypted))
+	for _, value := range c.encrypted {
+		encrypted[value.plaintext] = true
+	}
+	err = walkStrings(reflect.ValueOf(&masked).Elem(), "", func(path, value string) (string, error) {
+		if value != "" && (encrypted[value] || isCredentialPath(path)) {
+			return SecretMask, nil
+		}
+		return value, nil
+	})
+	return &masked, err
+}
+
+// isCredentialPath reports whether a walkStrings path holds a credential of a plugin
+func isCredentialPath(path string) bool {
+	parts := strings.Split(path, "/")
+	if len(parts) < 3 || parts[0] != "plugins" {
+		return false
+	}
+	setting := parts[2:]
+	switch {
+	case len(setting) == 1:
+		return setting[0] == "auth_token"
+	case setting[0] == "auth_headers":
+		return setting[1] == "bearer" || setting[1] == "headers" || len(setting) == 3 && setting[2] == "password"
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig_profile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{
+		"plugins": {
+			"remote": {"type": "remote", "address": "localhost:9001", "env": {"LEVEL": "debug", "REGION": "eu"}, "defaults": {"greeting": "hi"}}
+		},
+		"profiles": {
+			"prod": {"plugins": {"remote": {"address": "prod.example.com:443", "env": {"LEVEL": "info"}, "defaults": {"name": "prod"}}}},
+			"broken": {"plugins": {"missing": {"address": "localhost:1"}}}
+		}
+	}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		profile      string
+		wantAddress  string
+		wantEnv      map[string]string
+		wantDefaults map[string]string
+		wantErr      string
+	}{
+		{
+			name:         "No profile",
+			wantAddress:  "localhost:9001",
+			wantEnv:      map[string]string{"LEVEL": "debug", "REGION": "eu"},
+			wantDefaults: map[string]string{"greeting": "hi"},
+		},
+		{
+			name:         "Profile merged",
+			profile:      "prod",
+			wantAddress:  "prod.example.com:443",
+			wantEnv:      map[string]string{"LEVEL": "info", "REGION": "eu"},
+			wantDefaults: map[string]string{"greeting": "hi", "name": "prod"},
+		},
+		{
+			name:    "Unknown profile",
+			profile: "staging",
+			wantErr: `unknown profile "staging" (profiles: broken, prod)`,
+		},
+		{
+			name:    "Override of a missing plugin",
+			profile: "broken",
+			wantErr: "profiles.broken.plugins.missing: no such plugin",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(ProfileEnvVar, tt.profile)
+			config, err := LoadConfig(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if config.Profile() != tt.profile {
+				t.Errorf("Profile() = %q, want %q", config.Profile(), tt.profile)
+			}
+			remote := config.Plugins["remote"]
+			if remote.Address != tt.wantAddress {
+				t.Errorf("address = %q, want %q", remote.Address, tt.wantAddress)
+			}
+			if !reflect.DeepEqual(remote.Environment, tt.wantEnv) {
+				t.Errorf("env = %v, want %v", remote.Environment, tt.wantEnv)
+			}
+			if !reflect.DeepEqual(remote.Defaults, tt.wantDefaults) {
+				t.Errorf("defaults = %v, want %v", remote.Defaults, tt.wantDefaults)
+			}
+		})
+	}
+}
+
+func TestAppConfig_Masked(t *testing.T) {
+	config := &AppConfig{
+		Plugins: map[string]PluginConfig{
+			"hello": {Path: "bin/hello", AuthToken: "secret", Environment: map[string]string{"API_KEY": "from-disk", "MODE": "fast"}},
+			"remote": {Type: PluginTypeRemote, Address: "localhost:9001", AuthHeaders: &AuthHeaders{
+				Basic:   &BasicAuth{Username: "me", Password: "pw"},
+				Headers: map[string]string{"x-api-key": "key"},
+			}},
+		},
+		encrypted: map[string]encryptedValue{"plugins/hello/env/API_KEY": {plaintext: "from-disk", ciphertext: "enc:..."}},
+	}
+
+	masked, err := config.Masked()
+	if err != nil {
+		t.Fatalf("Masked() error = %v", err)
+	}
+	hello, remote := masked.Plugins["hello"], masked.Plugins["remote"]
+	if hello.AuthToken != SecretMask || hello.Environment["API_KEY"] != SecretMask || hello.Environment["MODE"] != "fast" {
+		t.Errorf("hello = %+v", hello)
+	}
+	if remote.AuthHeaders.Basic.Username != "me" || remote.AuthHeaders.Basic.Password != SecretMask || remote.AuthHeaders.Headers["x-api-key"] != SecretMask {
+		t.Errorf("remote auth headers = %+v %+v", remote.AuthHeaders.Basic, remote.AuthHeaders.Headers)
+	}
+	if config.Plugins["hello"].AuthToken != "secret" {
+		t.Error("Masked() changed the configuration")
+	}
+}
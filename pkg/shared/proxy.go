@@ -0,0 +1,159 @@
+package shared
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// ProxyDirect disables proxying for a remote plugin, including proxies set in the environment
+const ProxyDirect = "direct"
+
+// dialFunc opens a connection to a host:port address
+type dialFunc func(ctx context.Context, addr string) (net.Conn, error)
+
+// directDial connects without a proxy
+func directDial(ctx context.Context, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+// validateProxy checks the proxy setting of a remote plugin
+func (p *PluginConfig) validateProxy() error {
+	if p.Proxy == "" || p.Proxy == ProxyDirect {
+		return nil
+	}
+	u, err := url.Parse(p.Proxy)
+	if err != nil {
+		return fmt.Errorf("invalid proxy: %v", err)
+	}
+	switch u.Scheme {
+	case "http", "socks5":
+	default:
+		return fmt.Errorf("unsupported proxy scheme: %q (must be http or socks5)", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("proxy %s has no host", p.Proxy)
+	}
+	return nil
+}
+
+// proxyURL returns the proxy to reach addr through, or nil to connect directly. Without a
+// configured proxy, HTTPS_PROXY and NO_PROXY from the environment apply.
+func (p *PluginConfig) proxyURL(addr string) (*url.URL, error) {
+	switch p.Proxy {
+	case ProxyDirect:
+		return nil, nil
+	case "":
+		return http.ProxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: "https", Host: addr}})
+	default:
+		return url.Parse(p.Proxy)
+	}
+}
+
+// proxyDialer returns a dial function that goes through the plugin's proxy where one applies
+func (p *PluginConfig) proxyDialer() dialFunc {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		proxyURL, err := p.proxyURL(addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine proxy for %s: %v", addr, err)
+		}
+		if proxyURL == nil {
+			return directDial(ctx, addr)
+		}
+
+		switch proxyURL.Scheme {
+		case "socks5":
+			var auth *proxy.Auth
+			if proxyURL.User != nil {
+				password, _ := proxyURL.User.Password()
+				auth = &proxy.Auth{User: proxyURL.User.Username(), Password: password}
+			}
+			dialer, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, proxy.Direct)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SOCKS proxy %s: %v", proxyURL.Host, err)
+			}
+			conn, err := dialer.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return nil, fmt.Errorf("SOCKS proxy %s failed to reach %s: %v", proxyURL.Host, addr, err)
+			}
+			return conn, nil
+		default:
+			return connectTunnel(ctx, proxyURL, addr)
+		}
+	}
+}
+
+// connectTunnel opens a tunnel to addr through an HTTP proxy using CONNECT
+func connectTunnel(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	conn, err := directDial(ctx, proxyURL.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach proxy %s: %v", proxyURL.Host, err)
+	}
+
+	// Give up on the handshake once the dial deadline passes
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Host: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send CONNECT to proxy %s: %v", proxyURL.Host, err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read CONNECT response from proxy %s: %v", proxyURL.Host, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused to connect to %s: %s", proxyURL.Host, addr, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+
+	// The proxy may already have relayed bytes from the plugin past the response
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a connection whose first bytes were read ahead into a buffer
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// RedactProxyURL hides the password of a proxy URL for display
+func RedactProxyURL(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return proxy
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,131 @@
+package shared
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startConnectProxy runs an HTTP CONNECT proxy that requires the given basic auth credentials
+func startConnectProxy(t *testing.T, user, password string) (string, *atomic.Int32) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	tunnels := &atomic.Int32{}
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil || req.Method != http.MethodConnect {
+					return
+				}
+				if req.Header.Get("Proxy-Authorization") != want {
+					io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+					return
+				}
+				upstream, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+					return
+				}
+				defer upstream.Close()
+				tunnels.Add(1)
+				io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+				go io.Copy(upstream, conn)
+				io.Copy(conn, upstream)
+			}()
+		}
+	}()
+	return listener.Addr().String(), tunnels
+}
+
+func TestPluginConfig_validateProxy(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxy   string
+		wantErr bool
+	}{
+		{name: "Unset", proxy: ""},
+		{name: "Direct", proxy: ProxyDirect},
+		{name: "HTTP", proxy: "http://proxy.corp:3128"},
+		{name: "SOCKS with credentials", proxy: "socks5://user:pw@proxy.corp:1080"},
+		{name: "Unsupported scheme", proxy: "ftp://proxy.corp:21", wantErr: true},
+		{name: "Missing host", proxy: "http://", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := PluginConfig{Type: PluginTypeRemote, Address: "localhost:50051", Proxy: tt.proxy}
+			if err := p.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPluginManager_RemoteThroughProxy(t *testing.T) {
+	server, addr := startStubPluginServer(t)
+	defer server.Stop()
+	proxyAddr, tunnels := startConnectProxy(t, "alice", "secret")
+
+	tests := []struct {
+		name     string
+		proxy    string
+		wantErr  bool
+		errorMsg string
+	}{
+		{
+			name:  "Tunnel with credentials",
+			proxy: "http://alice:secret@" + proxyAddr,
+		},
+		{
+			name:     "Wrong credentials",
+			proxy:    "http://alice:wrong@" + proxyAddr,
+			wantErr:  true,
+			errorMsg: "407",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := tunnels.Load()
+			config := PluginConfig{
+				Type:           PluginTypeRemote,
+				Address:        addr,
+				Proxy:          tt.proxy,
+				ConnectTimeout: Duration(time.Second),
+			}
+			pm := NewPluginManager(&AppConfig{Plugins: map[string]PluginConfig{"stub": config}})
+			defer pm.StopAll()
+
+			err := pm.StartPlugin("stub", config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StartPlugin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("StartPlugin() error = %v, want substring %q", err, tt.errorMsg)
+				}
+				return
+			}
+			if tunnels.Load() == before {
+				t.Errorf("connection did not go through the proxy")
+			}
+		})
+	}
+}
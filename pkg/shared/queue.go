@@ -0,0 +1,202 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrorCodeQueueTimeout is the error code of executions that waited in the queue past their queue_timeout
+const ErrorCodeQueueTimeout = "QUEUE_TIMEOUT"
+
+// QueueTimeoutError reports an execution that didn't get to run within the plugin's queue_timeout
+type QueueTimeoutError struct {
+	Plugin  string
+	Timeout time.Duration
+}
+
+func (e *QueueTimeoutError) Error() string {
+	return fmt.Sprintf("%s: plugin %s was busy for longer than %s", ErrorCodeQueueTimeout, e.Plugin, e.Timeout)
+}
+
+// priorityKey is the context key of an execution's queue priority
+type priorityKey struct{}
+
+// WithPriority returns a context whose executions are taken from the queue before those of
+// lower priority. Executions without one have priority 0.
+func WithPriority(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns the queue priority carried by ctx, or 0
+func PriorityFromContext(ctx context.Context) int {
+	priority, _ := ctx.Value(priorityKey{}).(int)
+	return priority
+}
+
+// validateQueue checks the concurrency limit and queue timeout
+func (p *PluginConfig) validateQueue() error {
+	if p.MaxConcurrentExecutions < 0 {
+		return fmt.Errorf("invalid max_concurrent_executions: %d", p.MaxConcurrentExecutions)
+	}
+	if p.QueueTimeout < 0 {
+		return fmt.Errorf("invalid queue_timeout: %s", p.QueueTimeout)
+	}
+	return nil
+}
+
+// executionQueue holds back executions beyond the per-plugin and global concurrency limits.
+// Waiting executions are let through by priority, in arrival order within one priority; one
+// held back by its plugin's limit doesn't hold up those of other plugins.
+type executionQueue struct {
+	mu      sync.Mutex
+	running map[string]int
+	total   int
+	waiting []*queuedExecution
+}
+
+// queuedExecution is an execution waiting for a slot
+type queuedExecution struct {
+	plugin   string
+	limit    int // The plugin's max_concurrent_executions when it was queued
+	priority int
+	ready    chan struct{} // Closed once the execution holds a slot
+}
+
+// acquire waits until the plugin may run one more execution, given its limit and the global
+// one (0 for none), and returns the function releasing the slot
+func (q *executionQueue) acquire(ctx context.Context, plugin string, limit, globalLimit int, timeout time.Duration) (func(), error) {
+	q.mu.Lock()
+	if q.running == nil {
+		q.running = make(map[string]int)
+	}
+	release := func() { q.release(plugin, globalLimit) }
+	if len(q.waiting) == 0 && q.fits(plugin, limit, globalLimit) {
+		q.running[plugin]++
+		q.total++
+		q.mu.Unlock()
+		return release, nil
+	}
+	waiter := &queuedExecution{plugin: plugin, limit: limit, priority: PriorityFromContext(ctx), ready: make(chan struct{})}
+	q.insert(waiter)
+	// A slot freed before this execution arrived may already fit it
+	q.dispatch(globalLimit)
+	q.mu.Unlock()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case <-waiter.ready:
+		return release, nil
+	case <-ctx.Done():
+		return nil, q.abandon(waiter, release, ctx.Err())
+	case <-expired:
+		return nil, q.abandon(waiter, release, &QueueTimeoutError{Plugin: plugin, Timeout: timeout})
+	}
+}
+
+// abandon takes a waiter that gave up out of the queue, giving back the slot if it was granted meanwhile
+func (q *executionQueue) abandon(waiter *queuedExecution, release func(), err error) error {
+	q.mu.Lock()
+	select {
+	case <-waiter.ready:
+		q.mu.Unlock()
+		release()
+		return err
+	default:
+	}
+	for i, w := range q.waiting {
+		if w == waiter {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			break
+		}
+	}
+	q.mu.Unlock()
+	return err
+}
+
+// release frees a slot of the plugin and lets waiting executions through
+func (q *executionQueue) release(plugin string, globalLimit int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.running[plugin]--
+	if q.running[plugin] == 0 {
+		delete(q.running, plugin)
+	}
+	q.total--
+	q.dispatch(globalLimit)
+}
+
+// dispatch grants slots to waiting executions that fit, highest priority first; the caller
+// must hold q.mu
+func (q *executionQueue) dispatch(globalLimit int) {
+	kept := q.waiting[:0]
+	for _, w := range q.waiting {
+		if q.fits(w.plugin, w.limit, globalLimit) {
+			q.running[w.plugin]++
+			q.total++
+			close(w.ready)
+			continue
+		}
+		kept = append(kept, w)
+	}
+	q.waiting = kept
+}
+
+// fits reports whether the plugin may run one more execution; the caller must hold q.mu
+func (q *executionQueue) fits(plugin string, limit, globalLimit int) bool {
+	if globalLimit > 0 && q.total >= globalLimit {
+		return false
+	}
+	return limit <= 0 || q.running[plugin] < limit
+}
+
+// insert adds a waiter behind those of the same or higher priority; the caller must hold q.mu
+func (q *executionQueue) insert(waiter *queuedExecution) {
+	i := len(q.waiting)
+	for i > 0 && q.waiting[i-1].priority < waiter.priority {
+		i--
+	}
+	q.waiting = append(q.waiting, nil)
+	copy(q.waiting[i+1:], q.waiting[i:])
+	q.waiting[i] = waiter
+}
+
+// queued returns how many executions of the plugin are waiting
+func (q *executionQueue) queued(plugin string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, w := range q.waiting {
+		if w.plugin == plugin {
+			n++
+		}
+	}
+	return n
+}
+
+// queuedPlugin runs a plugin's executions through the manager's queue
+type queuedPlugin struct {
+	PluginInterface
+	queue       *executionQueue
+	name        string
+	limit       int
+	globalLimit int
+	timeout     time.Duration
+}
+
+// Execute waits for a slot under the plugin's max_concurrent_executions and the global limit,
+// failing with a *QueueTimeoutError after the plugin's queue_timeout
+func (p queuedPlugin) Execute(ctx context.Context, params map[string]string, output OutputHandler) error {
+	release, err := p.queue.acquire(ctx, p.name, p.limit, p.globalLimit, p.timeout)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return p.PluginInterface.Execute(ctx, params, output)
+}
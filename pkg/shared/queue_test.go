@@ -0,0 +1,126 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+)
+
+func TestExecutionQueue(t *testing.T) {
+	var q executionQueue
+	ctx := context.Background()
+
+	hold, err := q.acquire(ctx, "a", 1, 2, 0)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	// Waiters of a plugin at its limit are let through by priority, then in arrival order
+	order := make(chan string, 3)
+	waitFor := func(name string, ctx context.Context) {
+		release, err := q.acquire(ctx, "a", 1, 2, 0)
+		if err != nil {
+			t.Errorf("acquire(%s) error = %v", name, err)
+			return
+		}
+		order <- name
+		release()
+	}
+	go waitFor("first", ctx)
+	time.Sleep(20 * time.Millisecond)
+	go waitFor("second", ctx)
+	time.Sleep(20 * time.Millisecond)
+	go waitFor("urgent", WithPriority(ctx, 5))
+	time.Sleep(20 * time.Millisecond)
+	if queued := q.queued("a"); queued != 3 {
+		t.Errorf("queued() = %d, want 3", queued)
+	}
+
+	// Another plugin still runs while "a" is at its own limit, up to the global one
+	other, err := q.acquire(ctx, "b", 0, 2, 0)
+	if err != nil {
+		t.Fatalf("acquire() of another plugin error = %v", err)
+	}
+	_, err = q.acquire(ctx, "c", 0, 2, 50*time.Millisecond)
+	var timedOut *QueueTimeoutError
+	if !errors.As(err, &timedOut) || timedOut.Plugin != "c" {
+		t.Errorf("acquire() beyond the global limit error = %v, want a QueueTimeoutError", err)
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := q.acquire(canceled, "c", 0, 2, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("acquire() with a canceled context error = %v", err)
+	}
+	other()
+
+	hold()
+	for _, want := range []string{"urgent", "first", "second"} {
+		select {
+		case got := <-order:
+			if got != want {
+				t.Errorf("let through %s, want %s", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s was never let through", want)
+		}
+	}
+	// The last waiter releases its slot right after reporting
+	time.Sleep(20 * time.Millisecond)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.total != 0 || len(q.waiting) != 0 {
+		t.Errorf("queue holds %d slots and %d waiters after all were released", q.total, len(q.waiting))
+	}
+}
+
+func TestPluginManager_ConcurrencyLimit(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	proto.RegisterPluginServer(server, &GRPCServer{Impl: tickingPlugin{}})
+	StartHealthServer(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	config := &AppConfig{Plugins: map[string]PluginConfig{
+		"ticking": {
+			Type:                    PluginTypeRemote,
+			Address:                 listener.Addr().String(),
+			MaxConcurrentExecutions: 1,
+			QueueTimeout:            Duration(300 * time.Millisecond),
+		},
+	}}
+	pm := NewPluginManager(config)
+	defer pm.StopAll()
+	if err := pm.StartPlugin("ticking", config.Plugins["ticking"]); err != nil {
+		t.Fatalf("StartPlugin() error = %v", err)
+	}
+	plugin, err := pm.GetPlugin("ticking")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go plugin.Execute(ctx, nil, discardHandler{})
+	time.Sleep(100 * time.Millisecond)
+
+	done := make(chan error)
+	go func() { done <- plugin.Execute(context.Background(), nil, discardHandler{}) }()
+	time.Sleep(100 * time.Millisecond)
+	if status, err := pm.Status("ticking"); err != nil || status.Queued != 1 {
+		t.Errorf("Status() = %+v, %v; want one queued execution", status, err)
+	}
+
+	var timedOut *QueueTimeoutError
+	if err := <-done; !errors.As(err, &timedOut) {
+		t.Errorf("Execute() beyond the limit error = %v, want a QueueTimeoutError", err)
+	}
+}
@@ -0,0 +1,34 @@
+package shared
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrReadOnly is returned for operations refused while the manager is in read-only mode
+var ErrReadOnly = errors.New("read-only mode: operation not permitted")
+
+// SetReadOnly switches read-only mode, in which no plugin process is started or stopped and
+// nothing is executed; info, listing and status stay available
+func (pm *PluginManager) SetReadOnly(readOnly bool) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.readOnly = readOnly
+}
+
+// ReadOnly reports whether the manager is in read-only mode
+func (pm *PluginManager) ReadOnly() bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.readOnly
+}
+
+// readOnlyPlugin exposes a plugin for inspection only
+type readOnlyPlugin struct {
+	PluginInterface
+}
+
+// Execute refuses to run the plugin
+func (p readOnlyPlugin) Execute(ctx context.Context, params map[string]string, output OutputHandler) error {
+	return ErrReadOnly
+}
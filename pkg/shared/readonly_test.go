@@ -0,0 +1,41 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPluginManager_ReadOnly(t *testing.T) {
+	pm := NewPluginManager(&AppConfig{ReadOnly: true})
+	defer pm.StopAll()
+
+	if !pm.ReadOnly() {
+		t.Fatalf("ReadOnly() = false for a config with read_only set")
+	}
+
+	err := pm.StartPlugin("local", PluginConfig{Path: "/bin/true", Port: 1, Type: PluginTypeBinary})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("StartPlugin() error = %v, want ErrReadOnly", err)
+	}
+
+	// A plugin that was running before the switch can be inspected but not stopped or executed
+	client, _ := NewClientWithAddress("127.0.0.1:1")
+	pm.plugins["running"] = &ManagedPlugin{Name: "running", Client: client}
+
+	if err := pm.StopPlugin("running"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("StopPlugin() error = %v, want ErrReadOnly", err)
+	}
+	plugin, err := pm.GetPlugin("running")
+	if err != nil {
+		t.Fatalf("GetPlugin() error = %v", err)
+	}
+	if err := plugin.Execute(context.Background(), nil, nil); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Execute() error = %v, want ErrReadOnly", err)
+	}
+
+	pm.SetReadOnly(false)
+	if plugin, _ := pm.GetPlugin("running"); plugin != client {
+		t.Errorf("GetPlugin() after leaving read-only mode did not return the plugin client")
+	}
+}
@@ -0,0 +1,136 @@
+package shared
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RedactionConfig lists what to mask in output, execution summaries and history besides secret parameters
+type RedactionConfig struct {
+	Params   []string `json:"params,omitempty"`   // Parameter names to mask, with * matching any run of characters (e.g. *token*)
+	Patterns []string `json:"patterns,omitempty"` // Regular expressions whose matches are masked in values and output text
+}
+
+// validate checks that every name pattern and regular expression parses
+func (c *RedactionConfig) validate() error {
+	for _, name := range c.Params {
+		if _, err := path.Match(name, ""); err != nil {
+			return fmt.Errorf("invalid redaction param pattern %q: %v", name, err)
+		}
+	}
+	for _, pattern := range c.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid redaction pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Redactor masks sensitive parameters and anything matching configured patterns
+type Redactor struct {
+	schema   map[string]ParameterSpec
+	names    []string
+	patterns []*regexp.Regexp
+	values   []string
+}
+
+// NewRedactor builds a redactor for one execution. The values of sensitive parameters in params
+// are also masked wherever they show up in text, e.g. when a plugin echoes them back.
+func NewRedactor(config *RedactionConfig, schema map[string]ParameterSpec, params map[string]string) (*Redactor, error) {
+	r := &Redactor{schema: schema}
+	if config != nil {
+		for _, name := range config.Params {
+			r.names = append(r.names, strings.ToLower(name))
+		}
+		for _, pattern := range config.Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid redaction pattern %q: %v", pattern, err)
+			}
+			r.patterns = append(r.patterns, re)
+		}
+	}
+	for name, value := range params {
+		if value != "" && r.Sensitive(name) {
+			r.values = append(r.values, value)
+		}
+	}
+	r.sortValues()
+	return r, nil
+}
+
+// Mask adds a literal value, such as a credential, to be masked wherever it shows up in text
+func (r *Redactor) Mask(value string) {
+	if value == "" {
+		return
+	}
+	r.values = append(r.values, value)
+	r.sortValues()
+}
+
+// sortValues orders values longest first so a secret containing another is masked whole
+func (r *Redactor) sortValues() {
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+}
+
+// Sensitive reports whether a parameter's value must be masked
+func (r *Redactor) Sensitive(name string) bool {
+	if r.schema[name].IsSecret() {
+		return true
+	}
+	for _, pattern := range r.names {
+		if matched, _ := path.Match(pattern, strings.ToLower(name)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// String masks sensitive values and pattern matches in text
+func (r *Redactor) String(s string) string {
+	for _, value := range r.values {
+		s = strings.ReplaceAll(s, value, SecretMask)
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, SecretMask)
+	}
+	return s
+}
+
+// Params returns a copy of params with sensitive parameters masked and the rest redacted as text
+func (r *Redactor) Params(params map[string]string) map[string]string {
+	redacted := make(map[string]string, len(params))
+	for name, value := range params {
+		if r.Sensitive(name) {
+			redacted[name] = SecretMask
+		} else {
+			redacted[name] = r.String(value)
+		}
+	}
+	return redacted
+}
+
+// Value redacts the strings inside a decoded JSON value such as a structured result
+func (r *Redactor) Value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return r.String(v)
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			redacted[key] = r.Value(item)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = r.Value(item)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
@@ -0,0 +1,138 @@
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DryRunParam is the boolean parameter through which plugins with side effects offer a dry run
+const DryRunParam = "dry_run"
+
+// CheckReplayable reports whether recorded executions may be run again against a plugin: it must
+// be side-effect free or accept the dry_run parameter
+func CheckReplayable(info *PluginInfo) error {
+	if info.SideEffectFree {
+		return nil
+	}
+	if spec, ok := info.ParameterSchema[DryRunParam]; ok && (spec.Type == "" || spec.Type == "bool" || spec.Type == "boolean") {
+		return nil
+	}
+	return fmt.Errorf("%s %s is neither side-effect free nor supports %s", info.Name, info.Version, DryRunParam)
+}
+
+// ReplayParams returns the parameters to replay a recorded execution with. Masked values are left
+// out so they are resolved again like any unset parameter, and plugins that aren't side-effect
+// free are asked for a dry run.
+func ReplayParams(info *PluginInfo, recorded map[string]string) map[string]string {
+	params := make(map[string]string, len(recorded))
+	for name, value := range recorded {
+		if value != SecretMask {
+			params[name] = value
+		}
+	}
+	if !info.SideEffectFree {
+		params[DryRunParam] = "true"
+	}
+	return params
+}
+
+// DistinctParams returns the distinct parameter sets of the records in their original order
+func DistinctParams(records []HistoryRecord) []map[string]string {
+	seen := make(map[string]bool)
+	var sets []map[string]string
+	for _, record := range records {
+		key, _ := json.Marshal(record.Params) // map keys are sorted
+		if seen[string(key)] {
+			continue
+		}
+		seen[string(key)] = true
+		sets = append(sets, record.Params)
+	}
+	return sets
+}
+
+// ReplayResult is the outcome of running a parameter set against one version
+type ReplayResult struct {
+	Result map[string]interface{}
+	Error  string
+}
+
+// ResultDiff is one difference between the outcomes of two versions. Field is the dotted path
+// of a result field, or "error" when the versions failed differently.
+type ResultDiff struct {
+	Field       string
+	Baseline    interface{}
+	Candidate   interface{}
+	InBaseline  bool
+	InCandidate bool
+}
+
+func (d ResultDiff) String() string {
+	switch {
+	case !d.InBaseline:
+		return fmt.Sprintf("%s: added %s", d.Field, formatDiffValue(d.Candidate))
+	case !d.InCandidate:
+		return fmt.Sprintf("%s: removed (was %s)", d.Field, formatDiffValue(d.Baseline))
+	default:
+		return fmt.Sprintf("%s: %s -> %s", d.Field, formatDiffValue(d.Baseline), formatDiffValue(d.Candidate))
+	}
+}
+
+// CompareReplays diffs the outcomes of the baseline and candidate for the same parameters
+func CompareReplays(baseline, candidate ReplayResult) []ResultDiff {
+	if baseline.Error != candidate.Error {
+		return []ResultDiff{{
+			Field:       "error",
+			Baseline:    baseline.Error,
+			Candidate:   candidate.Error,
+			InBaseline:  baseline.Error != "",
+			InCandidate: candidate.Error != "",
+		}}
+	}
+	return DiffResults(baseline.Result, candidate.Result)
+}
+
+// DiffResults compares two structured results field by field, descending into nested objects
+func DiffResults(baseline, candidate map[string]interface{}) []ResultDiff {
+	var diffs []ResultDiff
+	diffResults("", baseline, candidate, &diffs)
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Field < diffs[j].Field })
+	return diffs
+}
+
+func diffResults(prefix string, baseline, candidate map[string]interface{}, diffs *[]ResultDiff) {
+	for name, b := range baseline {
+		field := prefix + name
+		c, ok := candidate[name]
+		if !ok {
+			*diffs = append(*diffs, ResultDiff{Field: field, Baseline: b, InBaseline: true})
+			continue
+		}
+		bm, bok := b.(map[string]interface{})
+		cm, cok := c.(map[string]interface{})
+		if bok && cok {
+			diffResults(field+".", bm, cm, diffs)
+			continue
+		}
+		if !reflect.DeepEqual(b, c) {
+			*diffs = append(*diffs, ResultDiff{Field: field, Baseline: b, Candidate: c, InBaseline: true, InCandidate: true})
+		}
+	}
+	for name, c := range candidate {
+		if _, ok := baseline[name]; !ok {
+			*diffs = append(*diffs, ResultDiff{Field: prefix + name, Candidate: c, InCandidate: true})
+		}
+	}
+}
+
+// formatDiffValue renders a result value as JSON, which keeps strings and numbers apart
+func formatDiffValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.TrimSpace(string(data))
+}
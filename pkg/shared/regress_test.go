@@ -0,0 +1,132 @@
+package shared
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDiffResults(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseline  map[string]interface{}
+		candidate map[string]interface{}
+		want      []string
+	}{
+		{
+			name:      "Identical",
+			baseline:  map[string]interface{}{"sum": 3.0, "meta": map[string]interface{}{"count": 2.0}},
+			candidate: map[string]interface{}{"sum": 3.0, "meta": map[string]interface{}{"count": 2.0}},
+		},
+		{
+			name:      "Changed value",
+			baseline:  map[string]interface{}{"sum": 3.0},
+			candidate: map[string]interface{}{"sum": 4.0},
+			want:      []string{"sum: 3 -> 4"},
+		},
+		{
+			name:      "Changed type",
+			baseline:  map[string]interface{}{"sum": 3.0},
+			candidate: map[string]interface{}{"sum": "3"},
+			want:      []string{`sum: 3 -> "3"`},
+		},
+		{
+			name:      "Added and removed fields",
+			baseline:  map[string]interface{}{"old": true},
+			candidate: map[string]interface{}{"new": []interface{}{1.0}},
+			want:      []string{"new: added [1]", "old: removed (was true)"},
+		},
+		{
+			name:      "Nested field",
+			baseline:  map[string]interface{}{"meta": map[string]interface{}{"count": 2.0, "unit": "n"}},
+			candidate: map[string]interface{}{"meta": map[string]interface{}{"count": 3.0, "unit": "n"}},
+			want:      []string{"meta.count: 2 -> 3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, diff := range DiffResults(tt.baseline, tt.candidate) {
+				got = append(got, diff.String())
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("DiffResults() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareReplays_Error(t *testing.T) {
+	diffs := CompareReplays(
+		ReplayResult{Result: map[string]interface{}{"sum": 3.0}},
+		ReplayResult{Error: "boom"},
+	)
+	if len(diffs) != 1 || diffs[0].String() != `error: added "boom"` {
+		t.Errorf("CompareReplays() = %v", diffs)
+	}
+}
+
+func TestReplayParams(t *testing.T) {
+	recorded := map[string]string{"name": "World", "token": SecretMask}
+
+	tests := []struct {
+		name     string
+		info     PluginInfo
+		want     string
+		wantErr  bool
+		errorMsg string
+	}{
+		{
+			name: "Side-effect free",
+			info: PluginInfo{Name: "hello", SideEffectFree: true},
+			want: "map[name:World]",
+		},
+		{
+			name: "Dry run",
+			info: PluginInfo{Name: "deploy", ParameterSchema: map[string]ParameterSpec{DryRunParam: {Type: "bool"}}},
+			want: "map[dry_run:true name:World]",
+		},
+		{
+			name:     "Not replayable",
+			info:     PluginInfo{Name: "deploy", Version: "2.0.0"},
+			wantErr:  true,
+			errorMsg: "deploy 2.0.0 is neither side-effect free nor supports dry_run",
+		},
+		{
+			name:     "Dry run parameter of another type",
+			info:     PluginInfo{Name: "deploy", ParameterSchema: map[string]ParameterSpec{DryRunParam: {Type: "string"}}},
+			wantErr:  true,
+			errorMsg: "neither side-effect free",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckReplayable(&tt.info)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckReplayable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("error %q does not contain %q", err, tt.errorMsg)
+				}
+				return
+			}
+			if got := fmt.Sprint(ReplayParams(&tt.info, recorded)); got != tt.want {
+				t.Errorf("ReplayParams() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDistinctParams(t *testing.T) {
+	records := []HistoryRecord{
+		{Params: map[string]string{"a": "1", "b": "2"}},
+		{Params: map[string]string{"b": "2", "a": "1"}},
+		{Params: map[string]string{"a": "3"}},
+	}
+	if got := fmt.Sprint(DistinctParams(records)); got != "[map[a:1 b:2] map[a:3]]" {
+		t.Errorf("DistinctParams() = %s", got)
+	}
+}
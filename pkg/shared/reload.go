@@ -0,0 +1,186 @@
+package shared
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ConfigDiff lists the plugins that differ between two configurations
+type ConfigDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// Empty reports whether the configurations define the same plugins
+func (d ConfigDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func (d ConfigDiff) String() string {
+	var parts []string
+	if len(d.Added) > 0 {
+		parts = append(parts, "added: "+strings.Join(d.Added, ", "))
+	}
+	if len(d.Removed) > 0 {
+		parts = append(parts, "removed: "+strings.Join(d.Removed, ", "))
+	}
+	if len(d.Changed) > 0 {
+		parts = append(parts, "changed: "+strings.Join(d.Changed, ", "))
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// DiffConfigs compares the plugin definitions of two configurations
+func DiffConfigs(old, new *AppConfig) ConfigDiff {
+	var diff ConfigDiff
+	for name, plugin := range new.Plugins {
+		previous, exists := old.Plugins[name]
+		switch {
+		case !exists:
+			diff.Added = append(diff.Added, name)
+		case !reflect.DeepEqual(previous, plugin):
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for name := range old.Plugins {
+		if _, exists := new.Plugins[name]; !exists {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
+// ReloadError describes a configuration reload that failed and was rolled back
+type ReloadError struct {
+	Diff        ConfigDiff
+	Err         error
+	RollbackErr error
+}
+
+func (e *ReloadError) Error() string {
+	msg := fmt.Sprintf("config reload failed and was rolled back (%s): %v", e.Diff, e.Err)
+	if e.RollbackErr != nil {
+		msg += fmt.Sprintf("; rollback also failed: %v", e.RollbackErr)
+	}
+	return msg
+}
+
+func (e *ReloadError) Unwrap() error {
+	return e.Err
+}
+
+// SetReloadFailedHandler registers a function called whenever a reload is rolled back
+func (pm *PluginManager) SetReloadFailedHandler(handler func(*ReloadError)) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.reloadFailed = handler
+}
+
+// Config returns the configuration the manager currently runs with
+func (pm *PluginManager) Config() *AppConfig {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.config
+}
+
+// ReloadConfig loads the configuration at path, with overlays merged over it like LoadConfig,
+// and applies it, keeping the current configuration if the new one is invalid
+func (pm *PluginManager) ReloadConfig(path string, overlays ...string) error {
+	config, err := LoadConfig(path, overlays...)
+	if err != nil {
+		reloadErr := &ReloadError{Err: err}
+		pm.notifyReloadFailed(reloadErr)
+		return reloadErr
+	}
+	return pm.ApplyConfig(config)
+}
+
+// ApplyConfig switches to a new configuration, stopping running plugins that were removed and
+// replacing those whose definition changed, without dropping their executions. If any step fails, the plugins already switched
+// are restored with the last known-good configuration and a *ReloadError is returned.
+func (pm *PluginManager) ApplyConfig(config *AppConfig) error {
+	pm.mu.Lock()
+	old := pm.config
+	running := make(map[string]bool, len(pm.plugins))
+	for name := range pm.plugins {
+		running[name] = true
+	}
+	pm.config = config
+	pm.ports.configure(config)
+	pm.mu.Unlock()
+
+	diff := DiffConfigs(old, config)
+	affected := append(append([]string{}, diff.Removed...), diff.Changed...)
+	sort.Strings(affected)
+
+	var switched []string
+	for _, name := range affected {
+		if !running[name] {
+			continue
+		}
+
+		var err error
+		if newPlugin, exists := config.Plugins[name]; exists {
+			err = pm.Replace(name, newPlugin)
+		} else {
+			err = pm.StopPlugin(name)
+		}
+		if err != nil {
+			reloadErr := &ReloadError{
+				Diff:        diff,
+				Err:         fmt.Errorf("plugin %s: %v", name, err),
+				RollbackErr: pm.rollback(old, switched),
+			}
+			pm.notifyReloadFailed(reloadErr)
+			return reloadErr
+		}
+		switched = append(switched, name)
+	}
+
+	return nil
+}
+
+// rollback restores the previous configuration and switches the given plugins back to it
+func (pm *PluginManager) rollback(old *AppConfig, switched []string) error {
+	pm.mu.Lock()
+	pm.config = old
+	pm.ports.configure(old)
+	pm.mu.Unlock()
+
+	var failed []string
+	for _, name := range switched {
+		// Replaced plugins are replaced back, removed ones started again
+		var err error
+		if _, running := pm.GetPlugin(name); running == nil {
+			err = pm.Replace(name, old.Plugins[name])
+		} else {
+			err = pm.StartPlugin(name, old.Plugins[name])
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to restore %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// notifyReloadFailed calls the reload-failed handler if one is registered
+func (pm *PluginManager) notifyReloadFailed(err *ReloadError) {
+	pm.mu.RLock()
+	handler := pm.reloadFailed
+	pm.mu.RUnlock()
+	if handler != nil {
+		handler(err)
+	}
+}
@@ -0,0 +1,170 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+)
+
+// stubPlugin answers GetInfo and nothing else
+type stubPlugin struct{}
+
+func (stubPlugin) GetInfo(ctx context.Context) (*PluginInfo, error) {
+	return &PluginInfo{Name: "stub", Version: "1.0.0"}, nil
+}
+
+func (stubPlugin) Execute(ctx context.Context, params map[string]string, output OutputHandler) error {
+	return nil
+}
+
+func (stubPlugin) ReportExecutionSummary(startTime, endTime int64, success bool, err error, metadata map[string]string, metrics map[string]float64) (*ExecutionSummary, error) {
+	return &ExecutionSummary{}, nil
+}
+
+func (stubPlugin) ValidateParameters(params map[string]string) error { return nil }
+
+func (stubPlugin) Close() error { return nil }
+
+// startStubPluginServer serves the plugin and health services on a random localhost port
+func startStubPluginServer(t *testing.T) (*grpc.Server, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	proto.RegisterPluginServer(server, &GRPCServer{Impl: stubPlugin{}})
+	StartHealthServer(server)
+	go server.Serve(listener)
+	return server, listener.Addr().String()
+}
+
+func TestDiffConfigs(t *testing.T) {
+	old := &AppConfig{Plugins: map[string]PluginConfig{
+		"kept":    {Address: "localhost:1"},
+		"changed": {Address: "localhost:2"},
+		"removed": {Address: "localhost:3"},
+	}}
+	new := &AppConfig{Plugins: map[string]PluginConfig{
+		"kept":    {Address: "localhost:1"},
+		"changed": {Address: "localhost:4"},
+		"added":   {Address: "localhost:5"},
+	}}
+
+	got := DiffConfigs(old, new)
+	want := ConfigDiff{Added: []string{"added"}, Removed: []string{"removed"}, Changed: []string{"changed"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffConfigs() = %+v, want %+v", got, want)
+	}
+	if !DiffConfigs(old, old).Empty() {
+		t.Errorf("DiffConfigs() of identical configs is not empty")
+	}
+}
+
+func TestPluginManager_ApplyConfig(t *testing.T) {
+	goodServer, goodAddr := startStubPluginServer(t)
+	defer goodServer.Stop()
+	// Serves health but not the plugin service, so connecting fails
+	badServer, badAddr := startHealthOnlyServer(t)
+	defer badServer.Stop()
+
+	timeout := Duration(2 * time.Second)
+	original := &AppConfig{Plugins: map[string]PluginConfig{
+		"stub": {Type: PluginTypeRemote, Address: goodAddr, ConnectTimeout: timeout},
+	}}
+
+	tests := []struct {
+		name       string
+		plugins    map[string]PluginConfig
+		wantErr    bool
+		wantConfig bool
+	}{
+		{
+			name:       "Changed plugin is restarted",
+			plugins:    map[string]PluginConfig{"stub": {Type: PluginTypeRemote, Address: goodAddr, ConnectTimeout: timeout, CallTimeout: timeout}},
+			wantConfig: true,
+		},
+		{
+			name:    "Broken plugin is rolled back",
+			plugins: map[string]PluginConfig{"stub": {Type: PluginTypeRemote, Address: badAddr, ConnectTimeout: Duration(time.Second)}},
+			wantErr: true,
+		},
+		{
+			name:       "Removed plugin is stopped",
+			plugins:    map[string]PluginConfig{},
+			wantConfig: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := NewPluginManager(original)
+			defer pm.StopAll()
+			if err := pm.StartPlugin("stub", original.Plugins["stub"]); err != nil {
+				t.Fatalf("StartPlugin() error = %v", err)
+			}
+
+			var reported *ReloadError
+			pm.SetReloadFailedHandler(func(err *ReloadError) { reported = err })
+
+			updated := &AppConfig{Plugins: tt.plugins}
+			err := pm.ApplyConfig(updated)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantConfig {
+				if pm.Config() != updated {
+					t.Errorf("Config() was not switched to the new configuration")
+				}
+				_, stillRunning := tt.plugins["stub"]
+				if _, err := pm.GetPlugin("stub"); (err == nil) != stillRunning {
+					t.Errorf("GetPlugin() error = %v, want running = %v", err, stillRunning)
+				}
+				return
+			}
+
+			var reloadErr *ReloadError
+			if !errors.As(err, &reloadErr) || reported != reloadErr {
+				t.Fatalf("ApplyConfig() error = %v, want the *ReloadError passed to the handler", err)
+			}
+			if reloadErr.RollbackErr != nil {
+				t.Errorf("RollbackErr = %v", reloadErr.RollbackErr)
+			}
+			if !reflect.DeepEqual(reloadErr.Diff.Changed, []string{"stub"}) {
+				t.Errorf("Diff = %+v, want stub changed", reloadErr.Diff)
+			}
+			if pm.Config() != original {
+				t.Errorf("Config() was not rolled back")
+			}
+			plugin, err := pm.GetPlugin("stub")
+			if err != nil {
+				t.Fatalf("GetPlugin() after rollback error = %v", err)
+			}
+			if _, err := plugin.GetInfo(context.Background()); err != nil {
+				t.Errorf("GetInfo() after rollback error = %v", err)
+			}
+		})
+	}
+}
+
+func TestPluginManager_ReloadConfigInvalid(t *testing.T) {
+	original := &AppConfig{Plugins: map[string]PluginConfig{}}
+	pm := NewPluginManager(original)
+
+	var reported *ReloadError
+	pm.SetReloadFailedHandler(func(err *ReloadError) { reported = err })
+
+	if err := pm.ReloadConfig("testdata/does-not-exist.json"); err == nil || reported == nil {
+		t.Fatalf("ReloadConfig() error = %v, reported = %v; want both set", err, reported)
+	}
+	if pm.Config() != original {
+		t.Errorf("Config() changed after a failed reload")
+	}
+}
@@ -0,0 +1,86 @@
+package shared
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+// Load-balancing policies supported for remote plugins
+const (
+	LoadBalancingPickFirst  = "pick_first"
+	LoadBalancingRoundRobin = "round_robin"
+)
+
+// resolverSeq gives every static address resolver a unique scheme
+var resolverSeq atomic.Int64
+
+// IsRemote reports whether the plugin is reached over the network instead of being started locally
+func (p *PluginConfig) IsRemote() bool {
+	return p.Type == PluginTypeRemote
+}
+
+// validateRemote checks the remote-specific settings of a plugin configuration
+func (p *PluginConfig) validateRemote() error {
+	if p.Address == "" && len(p.Addresses) == 0 {
+		return fmt.Errorf("address or addresses is required for remote plugins")
+	}
+	if p.Address != "" && len(p.Addresses) > 0 {
+		return fmt.Errorf("address and addresses are mutually exclusive")
+	}
+	for _, addr := range p.Addresses {
+		if strings.Contains(addr, "://") {
+			return fmt.Errorf("addresses must be host:port, use address for resolver targets: %s", addr)
+		}
+	}
+	if p.AutoMTLS {
+		return fmt.Errorf("auto_mtls is only supported for local plugins")
+	}
+	switch p.LoadBalancing {
+	case "", LoadBalancingPickFirst, LoadBalancingRoundRobin, LoadBalancingConsistentHash:
+	default:
+		return fmt.Errorf("unsupported load_balancing: %s (must be pick_first, round_robin or consistent_hash)", p.LoadBalancing)
+	}
+	if p.AffinityKey != "" && p.LoadBalancing != "" && p.LoadBalancing != LoadBalancingConsistentHash {
+		return fmt.Errorf("affinity_key requires load_balancing %s", LoadBalancingConsistentHash)
+	}
+	if p.SSH != nil {
+		if err := p.SSH.validate(p); err != nil {
+			return err
+		}
+	}
+	return p.validateProxy()
+}
+
+// remoteDialTarget returns the gRPC target and the resolver/balancer options for a remote plugin
+func remoteDialTarget(p *PluginConfig) (string, []grpc.DialOption) {
+	var opts []grpc.DialOption
+	policy := p.LoadBalancing
+	if policy == "" && p.AffinityKey != "" {
+		policy = LoadBalancingConsistentHash
+	}
+	if policy != "" {
+		opts = append(opts, grpc.WithDefaultServiceConfig(
+			fmt.Sprintf(`{"loadBalancingConfig":[{%q:{}}]}`, policy)))
+	}
+
+	// A single address may be any gRPC target, including dns:/// for resolver-driven replicas
+	if len(p.Addresses) == 0 {
+		return p.Address, opts
+	}
+
+	// Several static addresses are fed to the balancer through a manual resolver
+	r := manual.NewBuilderWithScheme(fmt.Sprintf("plugin-static-%d", resolverSeq.Add(1)))
+	state := resolver.State{}
+	for _, addr := range p.Addresses {
+		state.Addresses = append(state.Addresses, resolver.Address{Addr: addr})
+	}
+	r.InitialState(state)
+	opts = append(opts, grpc.WithResolvers(r))
+
+	return r.Scheme() + ":///remote", opts
+}
@@ -0,0 +1,101 @@
+package shared
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// remoteConfigTimeout bounds fetching a remote configuration before the cached copy is used
+const remoteConfigTimeout = 10 * time.Second
+
+// IsRemoteConfig reports whether a configuration path is the URL of a configuration served over
+// HTTP(S), which teams use to manage the configuration of many hosts centrally
+func IsRemoteConfig(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// remoteConfigCache returns the file a remote configuration is cached in; its ETag is kept next
+// to it
+func remoteConfigCache(url string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the cache for remote config: %v", err)
+	}
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(dir, "plugin-app", "config", hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// fetchRemoteConfig downloads a remote configuration into its cache and returns the cached file.
+// An unchanged configuration isn't downloaded again, going by its ETag, and the cached copy is
+// used when the source can't be reached or fails.
+func fetchRemoteConfig(url string) (string, error) {
+	cache, err := remoteConfigCache(url)
+	if err != nil {
+		return "", err
+	}
+	err = downloadRemoteConfig(url, cache)
+	if err == nil {
+		return cache, nil
+	}
+	if _, statErr := os.Stat(cache); statErr != nil {
+		return "", err
+	}
+	Logger(LogConfig).Warn("using the cached copy of a remote configuration", "url", url, "error", err)
+	return cache, nil
+}
+
+// downloadRemoteConfig refreshes the cached copy of a remote configuration
+func downloadRemoteConfig(url, cache string) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("invalid config URL: %v", err)
+	}
+	etagPath := cache + ".etag"
+	if _, err := os.Stat(cache); err == nil {
+		if etag, err := os.ReadFile(etagPath); err == nil {
+			req.Header.Set("If-None-Match", strings.TrimSpace(string(etag)))
+		}
+	}
+
+	client := &http.Client{Timeout: remoteConfigTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch config: %v", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil
+	case http.StatusOK:
+	default:
+		return fmt.Errorf("failed to fetch config: %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to fetch config: %v", err)
+	}
+
+	// Replace the cached copy at once, so a concurrent load never reads half of it
+	if err := os.MkdirAll(filepath.Dir(cache), 0700); err != nil {
+		return fmt.Errorf("failed to cache config: %v", err)
+	}
+	tmp := cache + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to cache config: %v", err)
+	}
+	if err := os.Rename(tmp, cache); err != nil {
+		return fmt.Errorf("failed to cache config: %v", err)
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		return os.WriteFile(etagPath, []byte(etag), 0600)
+	}
+	os.Remove(etagPath)
+	return nil
+}
@@ -0,0 +1,58 @@
+package shared
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLoadConfig_remote(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	const etag = `"v1"`
+	var fetches, notModified atomic.Int32
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		fetches.Add(1)
+		if r.Header.Get("If-None-Match") == etag {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(`{"plugins": {"remote": {"type": "remote", "address": "localhost:9001"}}}`))
+	}))
+	defer server.Close()
+	url := server.URL + "/config.json"
+
+	for i := 0; i < 2; i++ {
+		config, err := LoadConfig(url)
+		if err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+		if _, ok := config.Plugins["remote"]; !ok {
+			t.Fatalf("plugins = %v, want the remote config's", config.Plugins)
+		}
+	}
+	if fetches.Load() != 2 || notModified.Load() != 1 {
+		t.Errorf("fetches = %d, not modified = %d; want the second load revalidated by ETag", fetches.Load(), notModified.Load())
+	}
+
+	// The cached copy stands in while the source fails
+	failing.Store(true)
+	if _, err := LoadConfig(url); err != nil {
+		t.Errorf("LoadConfig() error = %v, want the cached copy", err)
+	}
+	if _, err := LoadConfig(server.URL + "/other.json"); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("LoadConfig() error = %v, want the failure without a cached copy", err)
+	}
+
+	if err := SaveConfig(&AppConfig{}, url); err == nil {
+		t.Error("SaveConfig() to a remote config succeeded")
+	}
+}
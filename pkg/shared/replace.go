@@ -0,0 +1,65 @@
+package shared
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultReplaceDrain is how long executions in flight on a replaced instance may take to finish
+// before they are canceled, unless the plugin's execution_timeout ends them sooner
+const DefaultReplaceDrain = time.Minute
+
+// replaceDrain returns how long a replaced instance of the plugin waits for its executions
+func (p *PluginConfig) replaceDrain() time.Duration {
+	if p.ExecutionTimeout > 0 && time.Duration(p.ExecutionTimeout) < DefaultReplaceDrain {
+		return time.Duration(p.ExecutionTimeout)
+	}
+	return DefaultReplaceDrain
+}
+
+// Replace upgrades a running plugin to newConfig, e.g. a new binary or version, without dropping
+// executions. A new instance is started next to the old one; once it is ready, it receives all
+// new executions while those in flight finish on the old instance, which is then stopped. A
+// local plugin whose ports are held by the old instance gets free ones. If the new instance
+// doesn't start, the old one keeps running and the error is returned.
+func (pm *PluginManager) Replace(name string, newConfig PluginConfig) error {
+	if err := newConfig.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration for plugin %s: %v", name, err)
+	}
+
+	pm.mu.Lock()
+	old, exists := pm.plugins[name]
+	if !exists {
+		pm.mu.Unlock()
+		return fmt.Errorf("plugin %s is not running", name)
+	}
+	if pm.readOnly {
+		pm.mu.Unlock()
+		return fmt.Errorf("refusing to replace plugin %s: %w", name, ErrReadOnly)
+	}
+
+	if !newConfig.IsRemote() && !old.Config.IsRemote() {
+		held := map[int]bool{old.Config.Port: true, old.Config.StandbyPort: true}
+		if held[newConfig.Port] {
+			newConfig.Port = 0
+		}
+		if held[newConfig.StandbyPort] {
+			newConfig.StandbyPort = 0
+		}
+	}
+
+	// The old instance stays registered, and keeps executing, until the new one is ready
+	if err := pm.startDependencies(name, newConfig, make(map[string]bool)); err != nil {
+		pm.mu.Unlock()
+		return err
+	}
+	if err := pm.startPlugin(name, newConfig); err != nil {
+		pm.mu.Unlock()
+		return fmt.Errorf("failed to replace plugin %s: %v", name, err)
+	}
+	pm.states.update(pm.plugins[name], StateReady)
+	pm.mu.Unlock()
+
+	waitIdle(old.clients(), old.Config.replaceDrain())
+	return old.shutdown()
+}
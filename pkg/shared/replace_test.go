@@ -0,0 +1,119 @@
+package shared
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+)
+
+// gatedPlugin's executions finish once the gate is closed
+type gatedPlugin struct {
+	stubPlugin
+	gate chan struct{}
+}
+
+func (p gatedPlugin) Execute(ctx context.Context, params map[string]string, output OutputHandler) error {
+	select {
+	case <-p.gate:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestPluginManager_Replace(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	gate := make(chan struct{})
+	oldServer := grpc.NewServer()
+	proto.RegisterPluginServer(oldServer, &GRPCServer{Impl: gatedPlugin{gate: gate}})
+	StartHealthServer(oldServer)
+	go oldServer.Serve(listener)
+	defer oldServer.Stop()
+	newServer, newAddr := startStubPluginServer(t)
+	defer newServer.Stop()
+
+	config := PluginConfig{Type: PluginTypeRemote, Address: listener.Addr().String()}
+	pm := NewPluginManager(&AppConfig{Plugins: map[string]PluginConfig{"stub": config}})
+	defer pm.StopAll()
+	if err := pm.StartPlugin("stub", config); err != nil {
+		t.Fatalf("StartPlugin() error = %v", err)
+	}
+	old, _ := pm.GetPlugin("stub")
+
+	executed := make(chan error, 1)
+	go func() { executed <- old.Execute(context.Background(), nil, discardHandler{}) }()
+	for {
+		if _, idle := old.(*GRPCClient).inflight.idleSince(); !idle {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	replaced := make(chan error, 1)
+	go func() { replaced <- pm.Replace("stub", PluginConfig{Type: PluginTypeRemote, Address: newAddr}) }()
+
+	// New executions go to the new instance while the old one drains
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		if plugin, err := pm.GetPlugin("stub"); err == nil && plugin != old {
+			if err := plugin.Execute(context.Background(), nil, discardHandler{}); err != nil {
+				t.Fatalf("Execute() on the new instance error = %v", err)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("executions were not switched to the new instance")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case err := <-replaced:
+		t.Fatalf("Replace() returned before the old instance drained: %v", err)
+	default:
+	}
+
+	close(gate)
+	if err := <-executed; err != nil {
+		t.Errorf("execution in flight on the old instance error = %v", err)
+	}
+	if err := <-replaced; err != nil {
+		t.Errorf("Replace() error = %v", err)
+	}
+}
+
+func TestPluginManager_ReplaceFailed(t *testing.T) {
+	server, addr := startStubPluginServer(t)
+	defer server.Stop()
+
+	config := PluginConfig{Type: PluginTypeRemote, Address: addr}
+	pm := NewPluginManager(&AppConfig{Plugins: map[string]PluginConfig{"stub": config}})
+	defer pm.StopAll()
+
+	if err := pm.Replace("stub", config); err == nil {
+		t.Error("Replace() of a plugin that isn't running succeeded")
+	}
+	if err := pm.StartPlugin("stub", config); err != nil {
+		t.Fatalf("StartPlugin() error = %v", err)
+	}
+	old, _ := pm.GetPlugin("stub")
+
+	// The old instance keeps serving when the new one doesn't come up
+	broken := PluginConfig{Type: PluginTypeRemote, Address: "127.0.0.1:1", ConnectTimeout: Duration(time.Second)}
+	if err := pm.Replace("stub", broken); err == nil {
+		t.Fatal("Replace() with an unreachable plugin succeeded")
+	}
+	plugin, err := pm.GetPlugin("stub")
+	if err != nil || plugin != old {
+		t.Fatalf("GetPlugin() = %v, %v; want the old instance", plugin, err)
+	}
+	if err := plugin.Execute(context.Background(), nil, discardHandler{}); err != nil {
+		t.Errorf("Execute() on the old instance error = %v", err)
+	}
+}
@@ -0,0 +1,271 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// replica is an additional instance of a local plugin that takes its share of executions
+type replica struct {
+	index      int // Position among the plugin's instances; the primary is 0
+	port       int
+	process    *pluginProcess
+	client     *GRPCClient
+	restarts   int
+	stopHealth context.CancelFunc
+}
+
+// stop closes the replica's connection and terminates its process
+func (r *replica) stop(grace time.Duration) error {
+	if r.stopHealth != nil {
+		r.stopHealth()
+	}
+	r.client.Close()
+	return r.process.terminate(grace)
+}
+
+// validateReplicas checks the replica settings
+func (p *PluginConfig) validateReplicas() error {
+	if p.Replicas < 0 {
+		return fmt.Errorf("invalid replicas: %d", p.Replicas)
+	}
+	if p.Replicas <= 1 {
+		return nil
+	}
+	if p.IsRemote() {
+		return fmt.Errorf("replicas is only supported for local plugins")
+	}
+	if p.Standby {
+		return fmt.Errorf("replicas can't be combined with standby")
+	}
+	return nil
+}
+
+// replicaSet spreads executions round-robin over the instances of a plugin. An instance that
+// is being restarted has no client and is skipped until it is back.
+type replicaSet struct {
+	mu      sync.Mutex
+	clients []*GRPCClient // By instance index
+	next    int
+}
+
+func newReplicaSet(instances int) *replicaSet {
+	return &replicaSet{clients: make([]*GRPCClient, instances)}
+}
+
+// set makes client the one of instance index, nil while the instance is down
+func (s *replicaSet) set(index int, client *GRPCClient) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[index] = client
+}
+
+// pick returns the client of the next instance in turn
+func (s *replicaSet) pick() (*GRPCClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for range s.clients {
+		client := s.clients[s.next]
+		s.next = (s.next + 1) % len(s.clients)
+		if client != nil {
+			return client, nil
+		}
+	}
+	return nil, fmt.Errorf("no instance of the plugin is available")
+}
+
+// first returns the client of the primary, or of another instance while it is down
+func (s *replicaSet) first() (*GRPCClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, client := range s.clients {
+		if client != nil {
+			return client, nil
+		}
+	}
+	return nil, fmt.Errorf("no instance of the plugin is available")
+}
+
+func (s *replicaSet) Execute(ctx context.Context, params map[string]string, output OutputHandler) error {
+	client, err := s.pick()
+	if err != nil {
+		return err
+	}
+	return client.Execute(ctx, params, output)
+}
+
+func (s *replicaSet) GetInfo(ctx context.Context) (*PluginInfo, error) {
+	client, err := s.first()
+	if err != nil {
+		return nil, err
+	}
+	return client.GetInfo(ctx)
+}
+
+func (s *replicaSet) ValidateParameters(params map[string]string) error {
+	client, err := s.first()
+	if err != nil {
+		return err
+	}
+	return client.ValidateParameters(params)
+}
+
+func (s *replicaSet) ReportExecutionSummary(startTime, endTime int64, success bool, err error, metadata map[string]string, metrics map[string]float64) (*ExecutionSummary, error) {
+	client, pickErr := s.first()
+	if pickErr != nil {
+		return nil, pickErr
+	}
+	return client.ReportExecutionSummary(startTime, endTime, success, err, metadata, metrics)
+}
+
+// Close does nothing, as the instances belong to the manager
+func (s *replicaSet) Close() error {
+	return nil
+}
+
+// clients returns the clients of the plugin's running instances, the primary first
+func (m *ManagedPlugin) clients() []*GRPCClient {
+	var clients []*GRPCClient
+	if m.GRPCClient != nil {
+		clients = append(clients, m.GRPCClient)
+	}
+	for _, r := range m.replicas {
+		if r != nil {
+			clients = append(clients, r.client)
+		}
+	}
+	return clients
+}
+
+// replicaOf returns the running replica whose process is p, if any
+func (m *ManagedPlugin) replicaOf(p *pluginProcess) *replica {
+	for _, r := range m.replicas {
+		if r != nil && r.process == p {
+			return r
+		}
+	}
+	return nil
+}
+
+// startReplicas launches the plugin's additional instances at the same time and waits until
+// they are ready. Instances that fail to start stay down; the error of the last is returned.
+func (pm *PluginManager) startReplicas(m *ManagedPlugin, stdout, stderr io.Writer) error {
+	m.replicas = make([]*replica, m.Config.Replicas-1)
+	m.dispatch = newReplicaSet(m.Config.Replicas)
+	m.dispatch.set(0, m.GRPCClient)
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(m.replicas))
+	for i := range m.replicas {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m.replicas[i], errs[i] = pm.startReplica(m, i+1, stdout, stderr)
+		}(i)
+	}
+	wg.Wait()
+
+	var err error
+	for i, r := range m.replicas {
+		if r == nil {
+			err = errs[i]
+			continue
+		}
+		m.dispatch.set(r.index, r.client)
+		pm.monitorReplica(m, r)
+	}
+	return err
+}
+
+// startReplica launches instance index of the plugin on a free port and waits until it is ready
+func (pm *PluginManager) startReplica(m *ManagedPlugin, index int, stdout, stderr io.Writer) (*replica, error) {
+	config := m.Config
+	port, err := m.ports.allocate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start replica %d: %v", index, err)
+	}
+	process, err := pm.startProcess(m, config, port, stdout, stderr)
+	if err != nil {
+		m.ports.release(port)
+		return nil, fmt.Errorf("failed to start replica %d: %v", index, err)
+	}
+
+	client, err := NewPluginClient(port, m.dialOptions()...)
+	if err != nil {
+		process.kill()
+		m.ports.release(port)
+		return nil, fmt.Errorf("failed to connect to replica %d: %v", index, err)
+	}
+	grpcClient := client.(*GRPCClient)
+	pm.configureClient(grpcClient, m.Name, config)
+
+	if err := grpcClient.waitStarted(pm.ctx, time.Duration(config.ReadyTimeout)); err != nil {
+		client.Close()
+		process.kill()
+		m.ports.release(port)
+		return nil, fmt.Errorf("replica %d did not become ready: %v", index, err)
+	}
+
+	return &replica{index: index, port: port, process: process, client: grpcClient}, nil
+}
+
+// monitorReplica starts health checking of a replica as the plugin's health_check says, the
+// replica being replaced once it turns unhealthy; the caller must hold pm.mu
+func (pm *PluginManager) monitorReplica(m *ManagedPlugin, r *replica) {
+	check, enabled := pm.config.healthCheck(m.Config)
+	if !enabled {
+		return
+	}
+	ctx, cancel := context.WithCancel(pm.ctx)
+	r.stopHealth = cancel
+
+	check.OnUnhealthy = func(err error) {
+		pm.mu.Lock()
+		defer pm.mu.Unlock()
+
+		if ctx.Err() != nil || pm.plugins[m.Name] != m {
+			return
+		}
+		pm.replaceReplica(m, r, err)
+	}
+	r.client.EnableHealthCheck(ctx, check)
+}
+
+// replaceReplica takes a failed replica out of rotation and restarts it in the background while
+// the other instances keep executing; the caller must hold pm.mu
+func (pm *PluginManager) replaceReplica(m *ManagedPlugin, r *replica, cause error) {
+	m.LastError = fmt.Errorf("replica %d failed: %v", r.index, cause)
+	m.dispatch.set(r.index, nil)
+	m.replicas[r.index-1] = nil
+	r.stop(0)
+	m.ports.release(r.port)
+	pm.states.refresh(m)
+
+	if r.restarts >= 3 {
+		return
+	}
+	go func() {
+		restarted, err := pm.startReplica(m, r.index, pm.stdout, pm.stderr)
+
+		pm.mu.Lock()
+		defer pm.mu.Unlock()
+
+		if err != nil {
+			m.LastError = fmt.Errorf("failed to restart replica %d: %v", r.index, err)
+			return
+		}
+		if pm.plugins[m.Name] != m || m.replicas[r.index-1] != nil {
+			restarted.stop(m.Config.stopTimeout())
+			m.ports.release(restarted.port)
+			return
+		}
+		restarted.restarts = r.restarts + 1
+		m.replicas[r.index-1] = restarted
+		m.dispatch.set(r.index, restarted.client)
+		pm.monitorReplica(m, restarted)
+		pm.states.refresh(m)
+	}()
+}
@@ -0,0 +1,107 @@
+package shared
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplicaSet_Execute(t *testing.T) {
+	set := newReplicaSet(3)
+	var clients []*GRPCClient
+	for i := 0; i < 3; i++ {
+		server, addr := startStubPluginServer(t)
+		defer server.Stop()
+		client, err := NewClientWithAddress(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		clients = append(clients, client.(*GRPCClient))
+		set.set(i, clients[i])
+	}
+
+	// Each execution goes to the next instance in turn
+	for i := 0; i < 3; i++ {
+		if err := set.Execute(context.Background(), nil, discardHandler{}); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if last, _ := clients[i].inflight.idleSince(); last.IsZero() {
+			t.Fatalf("execution %d did not go to instance %d", i, i)
+		}
+	}
+
+	// An instance that is down is skipped until it is back
+	set.set(1, nil)
+	for _, want := range []int{0, 2, 0} {
+		if got, _ := set.pick(); got != clients[want] {
+			t.Errorf("pick() did not return instance %d", want)
+		}
+	}
+	set.set(0, nil)
+	if got, _ := set.first(); got != clients[2] {
+		t.Error("first() did not fall back to the remaining instance")
+	}
+	set.set(2, nil)
+	if err := set.Execute(context.Background(), nil, discardHandler{}); err == nil {
+		t.Error("Execute() without instances succeeded")
+	}
+}
+
+func TestPluginManager_ReplicaExit(t *testing.T) {
+	primary, _ := NewClientWithAddress("127.0.0.1:1")
+	replicaClient, _ := NewClientWithAddress("127.0.0.1:2")
+	pm := NewPluginManager(&AppConfig{})
+	defer pm.StopAll()
+	managed := &ManagedPlugin{
+		Name:       "replicated",
+		Config:     PluginConfig{Path: "/nonexistent/plugin", Port: 1000, Type: PluginTypeBinary, Replicas: 2},
+		Client:     primary,
+		GRPCClient: primary.(*GRPCClient),
+		dispatch:   newReplicaSet(2),
+	}
+	managed.dispatch.set(0, managed.GRPCClient)
+	managed.dispatch.set(1, replicaClient.(*GRPCClient))
+
+	cmd := exec.Command("sh", "-c", "read line; exit 3")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	pm.mu.Lock()
+	process := watchProcess(managed.Name, cmd, nil, func(p *pluginProcess) { pm.onProcessExit(managed, p) })
+	managed.replicas = []*replica{{index: 1, process: process, client: replicaClient.(*GRPCClient)}}
+	pm.plugins["replicated"] = managed
+	pm.mu.Unlock()
+
+	stdin.Write([]byte("crash\n"))
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		pm.mu.RLock()
+		lastErr := managed.LastError
+		pm.mu.RUnlock()
+		// The replacement fails on the missing binary
+		if lastErr != nil && strings.Contains(lastErr.Error(), "failed to restart replica 1") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("LastError = %v, want the failed replica restart", lastErr)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	// Only the replica was taken down; the primary keeps executing
+	if managed.RestartCnt != 0 || managed.replicas[0] != nil {
+		t.Errorf("RestartCnt = %d, replica = %v; want only the replica replaced", managed.RestartCnt, managed.replicas[0])
+	}
+	if got, _ := managed.dispatch.pick(); got != managed.GRPCClient {
+		t.Error("executions were not left to the primary")
+	}
+}
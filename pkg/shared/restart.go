@@ -0,0 +1,151 @@
+package shared
+
+import (
+	"fmt"
+	"time"
+)
+
+// RestartPolicy is when a local plugin whose process exited or turned unhealthy is restarted
+type RestartPolicy string
+
+const (
+	RestartAlways    RestartPolicy = "always"     // Whenever the process exits or turns unhealthy (default)
+	RestartOnFailure RestartPolicy = "on-failure" // Unless the process exited with status 0
+	RestartNever     RestartPolicy = "never"      // The plugin stays unhealthy until it is started again
+)
+
+const (
+	// DefaultRestartMax is how many restarts within the window open the circuit breaker
+	DefaultRestartMax = 3
+
+	// DefaultRestartWindow is how long restarts count toward the circuit breaker
+	DefaultRestartWindow = 10 * time.Minute
+)
+
+// RestartConfig is how a failed local plugin is restarted. A circuit breaker stops restarting a
+// plugin that failed max times within window, and publishes EventPluginCircuitOpen; restarts
+// resume once the earliest of those drops out of the window.
+type RestartConfig struct {
+	Policy  RestartPolicy `json:"policy,omitempty"`          // When to restart (always/on-failure/never, default always)
+	Max     int           `json:"max,omitempty" min:"0"`     // Restarts within window before the circuit breaker opens (default 3)
+	Backoff Duration      `json:"backoff,omitempty" min:"0"` // Delay before a restart, doubling with each earlier one within window; 0 to restart right away
+	Window  Duration      `json:"window,omitempty" min:"0"`  // How long a restart counts toward max (default 10m)
+}
+
+// validateRestart checks the restart settings
+func (p *PluginConfig) validateRestart() error {
+	r := p.Restart
+	if r == nil {
+		return nil
+	}
+	if p.IsRemote() {
+		return fmt.Errorf("restart is only supported for local plugins")
+	}
+	switch r.Policy {
+	case "", RestartAlways, RestartOnFailure, RestartNever:
+	default:
+		return fmt.Errorf("invalid restart policy: %s (must be always, on-failure, or never)", r.Policy)
+	}
+	if r.Max < 0 {
+		return fmt.Errorf("invalid restart max: %d", r.Max)
+	}
+	if r.Backoff < 0 {
+		return fmt.Errorf("invalid restart backoff: %s", r.Backoff)
+	}
+	if r.Window < 0 {
+		return fmt.Errorf("invalid restart window: %s", r.Window)
+	}
+	return nil
+}
+
+// restartSettings returns the plugin's restart settings with the defaults filled in
+func (p *PluginConfig) restartSettings() RestartConfig {
+	var r RestartConfig
+	if p.Restart != nil {
+		r = *p.Restart
+	}
+	if r.Policy == "" {
+		r.Policy = RestartAlways
+	}
+	if r.Max == 0 {
+		r.Max = DefaultRestartMax
+	}
+	if r.Window == 0 {
+		r.Window = Duration(DefaultRestartWindow)
+	}
+	return r
+}
+
+// allows reports whether the policy restarts a plugin that failed with cause
+func (r RestartConfig) allows(cause error) bool {
+	switch r.Policy {
+	case RestartNever:
+		return false
+	case RestartOnFailure:
+		exit, ok := cause.(*ProcessExit)
+		return !ok || exit.ExitCode != 0
+	default:
+		return true
+	}
+}
+
+// delay returns the backoff before a restart that follows earlier ones within the window
+func (r RestartConfig) delay(earlier int) time.Duration {
+	delay := time.Duration(r.Backoff)
+	for i := 0; i < earlier && delay < time.Duration(r.Window); i++ {
+		delay *= 2
+	}
+	if delay > time.Duration(r.Window) {
+		delay = time.Duration(r.Window)
+	}
+	return delay
+}
+
+// scheduleRestart restarts a failed local plugin as its restart settings say, after the
+// backoff, unless the circuit breaker is open; the caller must hold pm.mu
+func (pm *PluginManager) scheduleRestart(m *ManagedPlugin) {
+	settings := m.Config.restartSettings()
+	if m.restartTimer != nil || !settings.allows(m.LastError) {
+		return
+	}
+
+	now := time.Now()
+	window := time.Duration(settings.Window)
+	recent := m.restarts[:0]
+	for _, t := range m.restarts {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	m.restarts = recent
+	if len(m.restarts) >= settings.Max {
+		if !m.circuitOpen {
+			m.circuitOpen = true
+			m.LastError = fmt.Errorf("restarts stopped after %d within %s: %v", len(m.restarts), window, m.LastError)
+			pm.states.update(m, StateUnhealthy)
+			pm.events.publish(Event{Type: EventPluginCircuitOpen, Plugin: m.Name, Time: now, Err: m.LastError})
+			Logger(LogManager).Debug("restarts stopped", "plugin", m.Name, "restarts", len(m.restarts), "window", window)
+		}
+		return
+	}
+	m.circuitOpen = false
+
+	delay := settings.delay(len(m.restarts))
+	m.restarts = append(m.restarts, now)
+	m.RestartCnt++
+	Logger(LogManager).Debug("restarting plugin", "plugin", m.Name, "delay", delay, "cause", m.LastError)
+	if delay <= 0 {
+		pm.restartPlugin(m)
+		return
+	}
+	m.restartTimer = time.AfterFunc(delay, func() {
+		pm.mu.Lock()
+		defer pm.mu.Unlock()
+		m.restartTimer = nil
+		// The plugin may have been stopped or replaced meanwhile
+		if pm.plugins[m.Name] != m || pm.ctx.Err() != nil {
+			return
+		}
+		pm.restartPlugin(m)
+	})
+}
@@ -0,0 +1,115 @@
+package shared
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRestartConfig_allows(t *testing.T) {
+	crash := &ProcessExit{Plugin: "p", ExitCode: 3}
+	clean := &ProcessExit{Plugin: "p", ExitCode: 0}
+	unhealthy := errors.New("health check failed")
+	tests := []struct {
+		name   string
+		policy RestartPolicy
+		cause  error
+		want   bool
+	}{
+		{name: "Always after a clean exit", policy: RestartAlways, cause: clean, want: true},
+		{name: "On failure after a crash", policy: RestartOnFailure, cause: crash, want: true},
+		{name: "On failure after a clean exit", policy: RestartOnFailure, cause: clean, want: false},
+		{name: "On failure when unhealthy", policy: RestartOnFailure, cause: unhealthy, want: true},
+		{name: "Never", policy: RestartNever, cause: crash, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (RestartConfig{Policy: tt.policy}).allows(tt.cause); got != tt.want {
+				t.Errorf("allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestartConfig_delay(t *testing.T) {
+	r := RestartConfig{Backoff: Duration(time.Second), Window: Duration(5 * time.Second)}
+	for earlier, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if got := r.delay(earlier); got != want {
+			t.Errorf("delay(%d) = %v, want %v", earlier, got, want)
+		}
+	}
+}
+
+func TestPluginConfig_validateRestart(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  PluginConfig
+		wantErr string
+	}{
+		{name: "Valid", config: PluginConfig{Restart: &RestartConfig{Policy: RestartOnFailure, Max: 5, Backoff: Duration(time.Second)}}},
+		{name: "Unknown policy", config: PluginConfig{Restart: &RestartConfig{Policy: "sometimes"}}, wantErr: "invalid restart policy"},
+		{name: "Remote plugin", config: PluginConfig{Type: PluginTypeRemote, Restart: &RestartConfig{}}, wantErr: "only supported for local plugins"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.validateRestart()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateRestart() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateRestart() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPluginManager_scheduleRestart(t *testing.T) {
+	pm := NewPluginManager(&AppConfig{})
+	events := pm.Subscribe(0, EventPluginCircuitOpen)
+	defer events.Close()
+
+	now := time.Now()
+	managed := &ManagedPlugin{
+		Name:      "flaky",
+		Config:    PluginConfig{Path: "/nonexistent/plugin", Restart: &RestartConfig{Max: 2, Backoff: Duration(time.Hour), Window: Duration(time.Minute)}},
+		LastError: errors.New("health check failed"),
+		restarts:  []time.Time{now.Add(-2 * time.Minute), now.Add(-time.Second)},
+	}
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.plugins["flaky"] = managed
+
+	// One restart is still within the window, so another waits for its backoff
+	pm.scheduleRestart(managed)
+	if managed.restartTimer == nil || len(managed.restarts) != 2 || managed.RestartCnt != 1 {
+		t.Fatalf("restartTimer = %v, restarts = %d; want a restart waiting for its backoff", managed.restartTimer, len(managed.restarts))
+	}
+	managed.restartTimer.Stop()
+	managed.restartTimer = nil
+
+	// With max restarts in the window, the circuit opens once
+	pm.scheduleRestart(managed)
+	pm.scheduleRestart(managed)
+	if !managed.circuitOpen || managed.restartTimer != nil || managed.RestartCnt != 1 {
+		t.Fatalf("circuitOpen = %v, RestartCnt = %d; want restarts stopped", managed.circuitOpen, managed.RestartCnt)
+	}
+	select {
+	case event := <-events.Events():
+		if event.Plugin != "flaky" || !strings.Contains(event.Err.Error(), "restarts stopped after 2") {
+			t.Errorf("event = %+v, want the circuit of flaky opened", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("circuit open event was not published")
+	}
+	select {
+	case event := <-events.Events():
+		t.Errorf("circuit open published again: %+v", event)
+	default:
+	}
+}
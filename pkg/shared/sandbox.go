@@ -0,0 +1,126 @@
+package shared
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// SandboxConfig restricts what a local plugin process can reach. It is only supported on Linux.
+type SandboxConfig struct {
+	NoNetwork     bool              `json:"no_network,omitempty"`      // Run in an empty network namespace; the host hands over the listening socket
+	ReadOnlyPaths []string          `json:"read_only_paths,omitempty"` // Absolute paths remounted read-only for the plugin
+	Rlimits       map[string]uint64 `json:"rlimits,omitempty"`         // Resource limits by name (cpu, as, nofile, ...), applied as soft and hard limit
+	Seccomp       string            `json:"seccomp,omitempty"`         // Path to a seccomp profile in the OCI/Docker JSON format
+}
+
+// SeccompProfile is the supported subset of the OCI/Docker seccomp profile format
+type SeccompProfile struct {
+	DefaultAction   string        `json:"defaultAction"`
+	DefaultErrnoRet *uint         `json:"defaultErrnoRet,omitempty"`
+	Syscalls        []SeccompRule `json:"syscalls"`
+}
+
+// SeccompRule applies an action to a set of syscalls; argument filters are not supported
+type SeccompRule struct {
+	Names    []string `json:"names"`
+	Action   string   `json:"action"`
+	ErrnoRet *uint    `json:"errnoRet,omitempty"`
+}
+
+// Seccomp actions understood in profiles
+const (
+	SeccompActAllow       = "SCMP_ACT_ALLOW"
+	SeccompActErrno       = "SCMP_ACT_ERRNO"
+	SeccompActKill        = "SCMP_ACT_KILL"
+	SeccompActKillProcess = "SCMP_ACT_KILL_PROCESS"
+	SeccompActLog         = "SCMP_ACT_LOG"
+)
+
+// sandboxRlimits maps rlimit names accepted in the config to Linux resource numbers
+var sandboxRlimits = map[string]int{
+	"cpu":     0,
+	"fsize":   1,
+	"data":    2,
+	"stack":   3,
+	"core":    4,
+	"nproc":   6,
+	"nofile":  7,
+	"memlock": 8,
+	"as":      9,
+}
+
+// needsNamespaces reports whether the sandbox requires new user, mount or network namespaces
+func (s *SandboxConfig) needsNamespaces() bool {
+	return s.NoNetwork || len(s.ReadOnlyPaths) > 0
+}
+
+// validate checks the sandbox settings without touching the filesystem
+func (s *SandboxConfig) validate() error {
+	for _, path := range s.ReadOnlyPaths {
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("sandbox read_only_paths must be absolute: %s", path)
+		}
+	}
+	for name := range s.Rlimits {
+		if _, ok := sandboxRlimits[name]; !ok {
+			names := make([]string, 0, len(sandboxRlimits))
+			for n := range sandboxRlimits {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("unsupported sandbox rlimit: %s (must be one of %v)", name, names)
+		}
+	}
+	return nil
+}
+
+// validateSandbox checks the sandbox section of a plugin
+func (p *PluginConfig) validateSandbox() error {
+	if p.Sandbox == nil {
+		return nil
+	}
+	if p.IsRemote() {
+		return fmt.Errorf("sandbox is only supported for local plugins")
+	}
+	return p.Sandbox.validate()
+}
+
+// LoadSeccompProfile reads and checks a seccomp profile, rejecting features it can't enforce
+func LoadSeccompProfile(path string) (*SeccompProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seccomp profile: %v", err)
+	}
+
+	// Unknown fields such as argument filters are rejected rather than silently ignored
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var profile SeccompProfile
+	if err := decoder.Decode(&profile); err != nil {
+		return nil, fmt.Errorf("failed to parse seccomp profile %s: %v", path, err)
+	}
+
+	if err := checkSeccompAction(profile.DefaultAction); err != nil {
+		return nil, fmt.Errorf("invalid defaultAction in %s: %v", path, err)
+	}
+	for i, rule := range profile.Syscalls {
+		if err := checkSeccompAction(rule.Action); err != nil {
+			return nil, fmt.Errorf("invalid action for syscalls[%d] in %s: %v", i, path, err)
+		}
+	}
+	return &profile, nil
+}
+
+// checkSeccompAction verifies that an action name is supported
+func checkSeccompAction(action string) error {
+	switch action {
+	case SeccompActAllow, SeccompActErrno, SeccompActKill, SeccompActKillProcess, SeccompActLog:
+		return nil
+	default:
+		return fmt.Errorf("unsupported seccomp action: %q", action)
+	}
+}
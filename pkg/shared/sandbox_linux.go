@@ -0,0 +1,278 @@
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// sandboxSpecEnvVar carries the sandbox spec to the re-executed host binary that sets up the sandbox
+const sandboxSpecEnvVar = "PLUGIN_SANDBOX_SPEC"
+
+// Values from linux/prctl.h, linux/seccomp.h and linux/filter.h that the syscall package lacks
+const (
+	prSetNoNewPrivs = 38
+	prSetSeccomp    = 22
+	prCapbsetDrop   = 24
+	seccompModeFilt = 2
+	bpfMaxInsns     = 4096
+
+	seccompRetKillProcess = 0x80000000
+	seccompRetKillThread  = 0x00000000
+	seccompRetErrno       = 0x00050000
+	seccompRetLog         = 0x7ffc0000
+	seccompRetAllow       = 0x7fff0000
+)
+
+// Filesystem flags reported by statfs that must be preserved when remounting inside a user namespace
+var lockedMountFlags = map[int64]uintptr{
+	0x0002: syscall.MS_NOSUID,
+	0x0004: syscall.MS_NODEV,
+	0x0008: syscall.MS_NOEXEC,
+	0x0400: syscall.MS_NOATIME,
+	0x0800: syscall.MS_NODIRATIME,
+	0x1000: syscall.MS_RELATIME,
+}
+
+// sandboxSpec is what the host hands to the sandbox init step
+type sandboxSpec struct {
+	Path    string          `json:"path"`
+	Sandbox SandboxConfig   `json:"sandbox"`
+	Seccomp *SeccompProfile `json:"seccomp,omitempty"`
+}
+
+// init turns the process into the sandbox init step when the host re-executed itself to start a plugin
+func init() {
+	data, ok := os.LookupEnv(sandboxSpecEnvVar)
+	if !ok {
+		return
+	}
+	if err := runSandboxInit(data); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// applySandbox rewrites the command so the plugin starts inside its sandbox. The returned
+// function releases host-side resources and must be called once the process has started.
+func applySandbox(process *exec.Cmd, sandbox *SandboxConfig, port int) (func(), error) {
+	spec := sandboxSpec{Path: process.Path, Sandbox: *sandbox}
+	if sandbox.Seccomp != "" {
+		profile, err := LoadSeccompProfile(sandbox.Seccomp)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := compileSeccomp(profile); err != nil {
+			return nil, err
+		}
+		spec.Seccomp = profile
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode sandbox spec: %v", err)
+	}
+
+	// Re-execute the host binary, whose init sets up the sandbox and then execs the plugin
+	process.Path = "/proc/self/exe"
+	process.Env = append(process.Env, fmt.Sprintf("%s=%s", sandboxSpecEnvVar, data))
+
+	release := func() {}
+	if sandbox.needsNamespaces() {
+		// The init step is root in the new user namespace so it can mount; it drops every
+		// capability before exec so the plugin can't undo the mounts
+		process.SysProcAttr = &syscall.SysProcAttr{
+			Cloneflags:  syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS,
+			UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+			GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		}
+	}
+	if sandbox.NoNetwork {
+		process.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+
+		// The plugin can't bind anything reachable from its empty network namespace, so the host
+		// opens the listening socket and passes it down
+		listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on port %d for sandboxed plugin: %v", port, err)
+		}
+		file, err := listener.(*net.TCPListener).File()
+		listener.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to hand over listener: %v", err)
+		}
+		process.ExtraFiles = append(process.ExtraFiles, file)
+		process.Env = append(process.Env, fmt.Sprintf("%s=%d", ListenFDEnvVar, 2+len(process.ExtraFiles)))
+		release = func() { file.Close() }
+	}
+
+	return release, nil
+}
+
+// runSandboxInit applies the sandbox to the current process and execs the plugin
+func runSandboxInit(data string) error {
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(data), &spec); err != nil {
+		return fmt.Errorf("invalid sandbox spec: %v", err)
+	}
+
+	// prctl and seccomp apply to the calling thread, which must also be the one calling exec
+	runtime.LockOSThread()
+
+	if len(spec.Sandbox.ReadOnlyPaths) > 0 {
+		if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+			return fmt.Errorf("failed to make mounts private: %v", err)
+		}
+		for _, path := range spec.Sandbox.ReadOnlyPaths {
+			if err := mountReadOnly(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	for name, limit := range spec.Sandbox.Rlimits {
+		rlimit := &syscall.Rlimit{Cur: limit, Max: limit}
+		if err := syscall.Setrlimit(sandboxRlimits[name], rlimit); err != nil {
+			return fmt.Errorf("failed to set rlimit %s: %v", name, err)
+		}
+	}
+
+	if spec.Sandbox.needsNamespaces() {
+		if err := dropCapabilities(); err != nil {
+			return err
+		}
+	}
+
+	env := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, sandboxSpecEnvVar+"=") {
+			env = append(env, kv)
+		}
+	}
+
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return fmt.Errorf("failed to set no_new_privs: %v", errno)
+	}
+	if spec.Seccomp != nil {
+		if err := installSeccomp(spec.Seccomp); err != nil {
+			return err
+		}
+	}
+
+	return syscall.Exec(spec.Path, os.Args, env)
+}
+
+// dropCapabilities empties the bounding set so the plugin gains no capabilities when exec'd as namespace root
+func dropCapabilities() error {
+	for capability := 0; ; capability++ {
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prCapbsetDrop, uintptr(capability), 0); errno != 0 {
+			if errno == syscall.EINVAL {
+				// Past the last capability the kernel knows about
+				return nil
+			}
+			return fmt.Errorf("failed to drop capability %d: %v", capability, errno)
+		}
+	}
+}
+
+// mountReadOnly bind-mounts path onto itself and remounts it read-only
+func mountReadOnly(path string) error {
+	if err := syscall.Mount(path, path, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("failed to bind %s: %v", path, err)
+	}
+
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return fmt.Errorf("failed to stat %s: %v", path, err)
+	}
+	flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY)
+	for statFlag, mountFlag := range lockedMountFlags {
+		if stat.Flags&statFlag != 0 {
+			flags |= mountFlag
+		}
+	}
+	if err := syscall.Mount("", path, "", flags, ""); err != nil {
+		return fmt.Errorf("failed to remount %s read-only: %v", path, err)
+	}
+	return nil
+}
+
+// installSeccomp loads the profile as a seccomp filter on the calling thread
+func installSeccomp(profile *SeccompProfile) error {
+	filter, err := compileSeccomp(profile)
+	if err != nil {
+		return err
+	}
+	prog := syscall.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetSeccomp, seccompModeFilt, uintptr(unsafe.Pointer(&prog))); errno != 0 {
+		return fmt.Errorf("failed to install seccomp filter: %v", errno)
+	}
+	return nil
+}
+
+// compileSeccomp translates a profile into a BPF program. Syscalls unknown on this architecture are skipped.
+func compileSeccomp(profile *SeccompProfile) ([]syscall.SockFilter, error) {
+	if seccompAuditArch == 0 {
+		return nil, fmt.Errorf("seccomp profiles are not supported on %s", runtime.GOARCH)
+	}
+
+	filter := []syscall.SockFilter{
+		// Kill anything not using the native syscall ABI
+		bpfStmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, 4),
+		bpfJump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, seccompAuditArch, 1, 0),
+		bpfStmt(syscall.BPF_RET|syscall.BPF_K, seccompRetKillProcess),
+		bpfStmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, 0),
+	}
+	for _, rule := range profile.Syscalls {
+		action := seccompRetValue(rule.Action, rule.ErrnoRet)
+		for _, name := range rule.Names {
+			nr, ok := seccompSyscalls[name]
+			if !ok {
+				continue
+			}
+			filter = append(filter,
+				bpfJump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, nr, 0, 1),
+				bpfStmt(syscall.BPF_RET|syscall.BPF_K, action),
+			)
+		}
+	}
+	filter = append(filter, bpfStmt(syscall.BPF_RET|syscall.BPF_K, seccompRetValue(profile.DefaultAction, profile.DefaultErrnoRet)))
+
+	if len(filter) > bpfMaxInsns {
+		return nil, fmt.Errorf("seccomp profile is too large: %d instructions (max %d)", len(filter), bpfMaxInsns)
+	}
+	return filter, nil
+}
+
+// seccompRetValue returns the filter return value for a profile action
+func seccompRetValue(action string, errnoRet *uint) uint32 {
+	switch action {
+	case SeccompActAllow:
+		return seccompRetAllow
+	case SeccompActErrno:
+		errno := uint32(syscall.EPERM)
+		if errnoRet != nil {
+			errno = uint32(*errnoRet)
+		}
+		return seccompRetErrno | (errno & 0xffff)
+	case SeccompActLog:
+		return seccompRetLog
+	case SeccompActKill:
+		return seccompRetKillThread
+	default:
+		return seccompRetKillProcess
+	}
+}
+
+func bpfStmt(code uint16, k uint32) syscall.SockFilter {
+	return syscall.SockFilter{Code: code, K: k}
+}
+
+func bpfJump(code uint16, k uint32, jt, jf uint8) syscall.SockFilter {
+	return syscall.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+}
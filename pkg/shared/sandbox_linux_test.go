@@ -0,0 +1,97 @@
+package shared
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplySandbox(t *testing.T) {
+	if err := exec.Command("unshare", "-Ur", "true").Run(); err != nil {
+		t.Skipf("User namespaces are not available: %v", err)
+	}
+
+	dir := t.TempDir()
+	profile := filepath.Join(dir, "seccomp.json")
+	if err := os.WriteFile(profile, []byte(`{
+		"defaultAction": "SCMP_ACT_ALLOW",
+		"syscalls": [{"names": ["mkdir", "mkdirat", "not_a_syscall"], "action": "SCMP_ACT_ERRNO"}]
+	}`), 0644); err != nil {
+		t.Fatalf("Failed to write profile: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		sandbox    SandboxConfig
+		script     string
+		wantOutput string
+		wantErr    bool
+	}{
+		{
+			name:       "Rlimits are applied",
+			sandbox:    SandboxConfig{Rlimits: map[string]uint64{"nofile": 64}},
+			script:     "ulimit -n",
+			wantOutput: "64",
+		},
+		{
+			name:    "Read-only paths reject writes",
+			sandbox: SandboxConfig{ReadOnlyPaths: []string{dir}},
+			script:  "touch " + filepath.Join(dir, "written"),
+			wantErr: true,
+		},
+		{
+			name:    "Seccomp profile denies syscalls",
+			sandbox: SandboxConfig{Seccomp: profile},
+			script:  "mkdir " + filepath.Join(dir, "created"),
+			wantErr: true,
+		},
+		{
+			name:       "No network gets a listener handed over",
+			sandbox:    SandboxConfig{NoNetwork: true},
+			script:     "echo $" + ListenFDEnvVar + "; cat /proc/net/dev",
+			wantOutput: "3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := 0
+			if tt.sandbox.NoNetwork {
+				port = freePort(t)
+			}
+
+			process := exec.Command("/bin/sh", "-c", tt.script)
+			process.Env = os.Environ()
+			release, err := applySandbox(process, &tt.sandbox, port)
+			if err != nil {
+				t.Fatalf("applySandbox() error = %v", err)
+			}
+			output, err := process.CombinedOutput()
+			release()
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sandboxed command error = %v, wantErr %v (output: %s)", err, tt.wantErr, output)
+			}
+			if !strings.HasPrefix(strings.TrimSpace(string(output)), tt.wantOutput) {
+				t.Errorf("sandboxed command output = %q, want prefix %q", output, tt.wantOutput)
+			}
+			if tt.sandbox.NoNetwork && strings.Contains(string(output), "eth") {
+				t.Errorf("sandboxed command can see host interfaces: %s", output)
+			}
+		})
+	}
+}
+
+// freePort returns a localhost port that was free a moment ago
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
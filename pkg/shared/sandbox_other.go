@@ -0,0 +1,14 @@
+//go:build !linux
+
+package shared
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// applySandbox fails because process sandboxing relies on Linux namespaces, rlimits and seccomp
+func applySandbox(process *exec.Cmd, sandbox *SandboxConfig, port int) (func(), error) {
+	return nil, fmt.Errorf("sandbox is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,112 @@
+package shared
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ResultValidationMode controls how the host reacts to results that don't match the published schema
+type ResultValidationMode string
+
+const (
+	// ResultValidationOff disables result schema validation
+	ResultValidationOff ResultValidationMode = "off"
+	// ResultValidationWarn logs violations but keeps the execution going
+	ResultValidationWarn ResultValidationMode = "warn"
+	// ResultValidationError fails the execution on the first invalid result
+	ResultValidationError ResultValidationMode = "error"
+)
+
+// Result field types understood by ValidateResult
+const (
+	ResultTypeString = "string"
+	ResultTypeNumber = "number"
+	ResultTypeBool   = "bool"
+	ResultTypeObject = "object"
+	ResultTypeList   = "list"
+	ResultTypeAny    = "any"
+)
+
+// ValidateResult checks a structured result against a result schema and returns every violation found
+func ValidateResult(schema map[string]ResultFieldSpec, result map[string]interface{}) []string {
+	var violations []string
+
+	// Sort names so violations are reported in a stable order
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		spec := schema[name]
+		value, exists := result[name]
+		if !exists {
+			if spec.Required {
+				violations = append(violations, fmt.Sprintf("missing required field: %s", name))
+			}
+			continue
+		}
+		if !matchesResultType(spec.Type, value) {
+			violations = append(violations, fmt.Sprintf("field %s: expected %s, got %s", name, spec.Type, describeResultType(value)))
+		}
+	}
+
+	var unexpected []string
+	for name := range result {
+		if _, declared := schema[name]; !declared {
+			unexpected = append(unexpected, name)
+		}
+	}
+	sort.Strings(unexpected)
+	for _, name := range unexpected {
+		violations = append(violations, fmt.Sprintf("undeclared field: %s", name))
+	}
+
+	return violations
+}
+
+// matchesResultType reports whether a decoded value has the declared type
+func matchesResultType(fieldType string, value interface{}) bool {
+	switch fieldType {
+	case "", ResultTypeAny:
+		return true
+	case ResultTypeString:
+		_, ok := value.(string)
+		return ok
+	case ResultTypeNumber, "int", "float":
+		_, ok := value.(float64)
+		return ok
+	case ResultTypeBool:
+		_, ok := value.(bool)
+		return ok
+	case ResultTypeObject:
+		_, ok := value.(map[string]interface{})
+		return ok
+	case ResultTypeList:
+		_, ok := value.([]interface{})
+		return ok
+	default:
+		return false
+	}
+}
+
+// describeResultType returns the schema type name of a decoded value
+func describeResultType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return ResultTypeString
+	case float64:
+		return ResultTypeNumber
+	case bool:
+		return ResultTypeBool
+	case map[string]interface{}:
+		return ResultTypeObject
+	case []interface{}:
+		return ResultTypeList
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
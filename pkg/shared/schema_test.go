@@ -0,0 +1,55 @@
+package shared
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateResult(t *testing.T) {
+	schema := map[string]ResultFieldSpec{
+		"sum":   {Name: "sum", Required: true, Type: ResultTypeNumber},
+		"label": {Name: "label", Required: false, Type: ResultTypeString},
+		"extra": {Name: "extra", Required: false, Type: ResultTypeAny},
+	}
+
+	tests := []struct {
+		name   string
+		result map[string]interface{}
+		want   []string
+	}{
+		{
+			name:   "Valid result",
+			result: map[string]interface{}{"sum": 3.0, "label": "total"},
+			want:   nil,
+		},
+		{
+			name:   "Any type accepts everything",
+			result: map[string]interface{}{"sum": 3.0, "extra": []interface{}{"a", 1.0}},
+			want:   nil,
+		},
+		{
+			name:   "Missing required field",
+			result: map[string]interface{}{"label": "total"},
+			want:   []string{"missing required field: sum"},
+		},
+		{
+			name:   "Wrong type",
+			result: map[string]interface{}{"sum": "3"},
+			want:   []string{"field sum: expected number, got string"},
+		},
+		{
+			name:   "Undeclared fields",
+			result: map[string]interface{}{"sum": 3.0, "zeta": true, "alpha": nil},
+			want:   []string{"undeclared field: alpha", "undeclared field: zeta"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ValidateResult(schema, tt.result)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateResult() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// How breaking plugin schema changes are handled
+const (
+	SchemaChangesWarn  = "warn"  // Show the changes and run anyway
+	SchemaChangesBlock = "block" // Refuse to run until the changes are acknowledged
+)
+
+// SchemaChange is one difference between two versions of a plugin's schema
+type SchemaChange struct {
+	Field    string // Parameter or result field name
+	Result   bool   // Whether Field is a result field rather than a parameter
+	Detail   string
+	Breaking bool // Whether saved parameter sets or downstream consumers may stop working
+}
+
+func (c SchemaChange) String() string {
+	kind := "parameter"
+	if c.Result {
+		kind = "result field"
+	}
+	return fmt.Sprintf("%s %s: %s", kind, c.Field, c.Detail)
+}
+
+// DiffSchemas compares the parameter and result schemas of two versions of a plugin
+func DiffSchemas(old, new *PluginInfo) []SchemaChange {
+	var changes []SchemaChange
+	add := func(field string, result, breaking bool, format string, args ...interface{}) {
+		changes = append(changes, SchemaChange{Field: field, Result: result, Detail: fmt.Sprintf(format, args...), Breaking: breaking})
+	}
+
+	for _, name := range paramNames(old.ParameterSchema, new.ParameterSchema) {
+		before, existed := old.ParameterSchema[name]
+		after, exists := new.ParameterSchema[name]
+		switch {
+		case !exists:
+			add(name, false, true, "removed")
+		case !existed:
+			required := after.Required && after.DefaultValue == ""
+			if required {
+				add(name, false, true, "added as required without a default")
+			} else {
+				add(name, false, false, "added")
+			}
+		default:
+			if before.Type != after.Type {
+				add(name, false, true, "type changed from %s to %s", typeName(before.Type), typeName(after.Type))
+			}
+			if !before.Required && after.Required && after.DefaultValue == "" {
+				add(name, false, true, "now required without a default")
+			}
+			if removed := missingValues(before.AllowedValues, after.AllowedValues); len(removed) > 0 {
+				add(name, false, true, "no longer allows %v", removed)
+			}
+			if before.DefaultValue != after.DefaultValue && !before.IsSecret() {
+				add(name, false, false, "default changed from %q to %q", before.DefaultValue, after.DefaultValue)
+			}
+		}
+	}
+
+	for _, name := range resultFieldNames(old.ResultSchema, new.ResultSchema) {
+		before, existed := old.ResultSchema[name]
+		after, exists := new.ResultSchema[name]
+		switch {
+		case !exists:
+			add(name, true, before.Required, "removed")
+		case !existed:
+			add(name, true, false, "added")
+		case before.Type != after.Type:
+			add(name, true, true, "type changed from %s to %s", typeName(before.Type), typeName(after.Type))
+		case before.Required && !after.Required:
+			add(name, true, true, "no longer always present")
+		}
+	}
+	return changes
+}
+
+// HasBreakingChanges reports whether any change is breaking
+func HasBreakingChanges(changes []SchemaChange) bool {
+	for _, change := range changes {
+		if change.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// paramNames returns the parameters defined in either schema, in order
+func paramNames(a, b map[string]ParameterSpec) []string {
+	names := make(map[string]bool, len(a)+len(b))
+	for name := range a {
+		names[name] = true
+	}
+	for name := range b {
+		names[name] = true
+	}
+	return sortedSet(names)
+}
+
+// resultFieldNames returns the result fields defined in either schema, in order
+func resultFieldNames(a, b map[string]ResultFieldSpec) []string {
+	names := make(map[string]bool, len(a)+len(b))
+	for name := range a {
+		names[name] = true
+	}
+	for name := range b {
+		names[name] = true
+	}
+	return sortedSet(names)
+}
+
+func sortedSet(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// missingValues returns the allowed values dropped between two lists; an empty list allows anything
+func missingValues(before, after []string) []string {
+	if len(after) == 0 {
+		return nil
+	}
+	if len(before) == 0 {
+		return []string{"values outside " + fmt.Sprint(after)}
+	}
+	allowed := make(map[string]bool, len(after))
+	for _, value := range after {
+		allowed[value] = true
+	}
+	var missing []string
+	for _, value := range before {
+		if !allowed[value] {
+			missing = append(missing, value)
+		}
+	}
+	return missing
+}
+
+func typeName(t string) string {
+	if t == "" {
+		return "untyped"
+	}
+	return t
+}
+
+// SchemaStore keeps the last acknowledged schema of each plugin
+type SchemaStore struct {
+	dir string
+}
+
+// NewSchemaStore returns a store keeping schemas below the state directory
+func NewSchemaStore(stateDir string) *SchemaStore {
+	return &SchemaStore{dir: filepath.Join(stateDir, "schemas")}
+}
+
+// Load returns the acknowledged schema of a plugin, or nil if none was recorded yet
+func (s *SchemaStore) Load(plugin string) (*PluginInfo, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, plugin+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema snapshot: %v", err)
+	}
+	var info PluginInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse schema snapshot for %s: %v", plugin, err)
+	}
+	return &info, nil
+}
+
+// Save records a plugin's schema as acknowledged
+func (s *SchemaStore) Save(plugin string, info *PluginInfo) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create schema directory: %v", err)
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode schema snapshot: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, plugin+".json"), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write schema snapshot: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package shared
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffSchemas(t *testing.T) {
+	old := &PluginInfo{
+		ParameterSchema: map[string]ParameterSpec{
+			"name":     {Name: "name", Type: "string"},
+			"count":    {Name: "count", Type: "int"},
+			"mode":     {Name: "mode", Type: "string", AllowedValues: []string{"fast", "slow"}},
+			"verbose":  {Name: "verbose", Type: "bool"},
+			"language": {Name: "language", Type: "string", DefaultValue: "en"},
+		},
+		ResultSchema: map[string]ResultFieldSpec{
+			"total": {Name: "total", Type: "number", Required: true},
+			"note":  {Name: "note", Type: "string"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		update       func(info *PluginInfo)
+		wantChanges  []string
+		wantBreaking bool
+	}{
+		{
+			name:   "Unchanged",
+			update: func(info *PluginInfo) {},
+		},
+		{
+			name:         "Parameter removed",
+			update:       func(info *PluginInfo) { delete(info.ParameterSchema, "verbose") },
+			wantChanges:  []string{"parameter verbose: removed"},
+			wantBreaking: true,
+		},
+		{
+			name: "Optional parameter added",
+			update: func(info *PluginInfo) {
+				info.ParameterSchema["extra"] = ParameterSpec{Name: "extra", Type: "string"}
+			},
+			wantChanges: []string{"parameter extra: added"},
+		},
+		{
+			name: "Required parameter added",
+			update: func(info *PluginInfo) {
+				info.ParameterSchema["extra"] = ParameterSpec{Name: "extra", Type: "string", Required: true}
+			},
+			wantChanges:  []string{"parameter extra: added as required without a default"},
+			wantBreaking: true,
+		},
+		{
+			name: "Type changed",
+			update: func(info *PluginInfo) {
+				info.ParameterSchema["count"] = ParameterSpec{Name: "count", Type: "string"}
+			},
+			wantChanges:  []string{"parameter count: type changed from int to string"},
+			wantBreaking: true,
+		},
+		{
+			name: "Allowed value dropped",
+			update: func(info *PluginInfo) {
+				info.ParameterSchema["mode"] = ParameterSpec{Name: "mode", Type: "string", AllowedValues: []string{"fast"}}
+			},
+			wantChanges:  []string{"parameter mode: no longer allows [slow]"},
+			wantBreaking: true,
+		},
+		{
+			name: "Default changed",
+			update: func(info *PluginInfo) {
+				info.ParameterSchema["language"] = ParameterSpec{Name: "language", Type: "string", DefaultValue: "fr"}
+			},
+			wantChanges: []string{`parameter language: default changed from "en" to "fr"`},
+		},
+		{
+			name:         "Required result field removed",
+			update:       func(info *PluginInfo) { delete(info.ResultSchema, "total") },
+			wantChanges:  []string{"result field total: removed"},
+			wantBreaking: true,
+		},
+		{
+			name:        "Optional result field removed",
+			update:      func(info *PluginInfo) { delete(info.ResultSchema, "note") },
+			wantChanges: []string{"result field note: removed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			new := &PluginInfo{
+				ParameterSchema: make(map[string]ParameterSpec),
+				ResultSchema:    make(map[string]ResultFieldSpec),
+			}
+			for k, v := range old.ParameterSchema {
+				new.ParameterSchema[k] = v
+			}
+			for k, v := range old.ResultSchema {
+				new.ResultSchema[k] = v
+			}
+			tt.update(new)
+
+			changes := DiffSchemas(old, new)
+			var got []string
+			for _, change := range changes {
+				got = append(got, change.String())
+			}
+			if !reflect.DeepEqual(got, tt.wantChanges) {
+				t.Errorf("DiffSchemas() = %v, want %v", got, tt.wantChanges)
+			}
+			if HasBreakingChanges(changes) != tt.wantBreaking {
+				t.Errorf("HasBreakingChanges() = %v, want %v", !tt.wantBreaking, tt.wantBreaking)
+			}
+		})
+	}
+}
+
+func TestSchemaStore(t *testing.T) {
+	store := NewSchemaStore(t.TempDir())
+
+	info, err := store.Load("hello")
+	if err != nil || info != nil {
+		t.Fatalf("Load() of unknown plugin = %v, %v; want nil, nil", info, err)
+	}
+
+	saved := &PluginInfo{
+		Name:            "hello",
+		Version:         "1.0.0",
+		ParameterSchema: map[string]ParameterSpec{"message": {Name: "message", Type: "string"}},
+	}
+	if err := store.Save("hello", saved); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := store.Load("hello")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, saved) {
+		t.Errorf("Load() = %+v, want %+v", loaded, saved)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build linux && !amd64 && !arm64
+
+package shared
+
+// seccompAuditArch is zero on architectures without a syscall table, which makes seccomp profiles unsupported
+const seccompAuditArch = 0
+
+// seccompSyscalls is empty on architectures without a syscall table
+var seccompSyscalls = map[string]uint32{}
@@ -0,0 +1,30 @@
+package shared
+
+import (
+	"strings"
+)
+
+// ParamTypeSecret marks a parameter whose value must never be logged or shown
+const ParamTypeSecret = "secret"
+
+// SecretMask replaces secret values wherever parameters are displayed or reported
+const SecretMask = "***"
+
+// SecretParamEnvPrefix prefixes the host environment variables secret parameters can be read from,
+// so they don't have to appear on the host's command line
+const SecretParamEnvPrefix = "PLUGIN_PARAM_"
+
+// IsSecret reports whether the parameter holds a secret
+func (s ParameterSpec) IsSecret() bool {
+	return s.Type == ParamTypeSecret
+}
+
+// SecretParamEnvVar returns the environment variable a secret parameter is read from
+func SecretParamEnvVar(name string) string {
+	return SecretParamEnvPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// RedactParams returns a copy of params with the values of secret parameters masked
+func RedactParams(schema map[string]ParameterSpec, params map[string]string) map[string]string {
+	return (&Redactor{schema: schema}).Params(params)
+}
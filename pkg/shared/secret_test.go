@@ -0,0 +1,44 @@
+package shared
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedactParams(t *testing.T) {
+	schema := map[string]ParameterSpec{
+		"token": {Name: "token", Type: ParamTypeSecret},
+		"name":  {Name: "name", Type: "string"},
+	}
+	params := map[string]string{"token": "s3cr3t", "name": "demo", "extra": "x"}
+
+	got := RedactParams(schema, params)
+	want := map[string]string{"token": SecretMask, "name": "demo", "extra": "x"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RedactParams() = %v, want %v", got, want)
+	}
+	if params["token"] != "s3cr3t" {
+		t.Errorf("RedactParams() modified its input")
+	}
+}
+
+func TestSecretParamEnvVar(t *testing.T) {
+	if got := SecretParamEnvVar("api-key"); got != "PLUGIN_PARAM_API_KEY" {
+		t.Errorf("SecretParamEnvVar() = %s, want PLUGIN_PARAM_API_KEY", got)
+	}
+}
+
+func TestGRPCClient_ValidateParametersHidesSecrets(t *testing.T) {
+	client := &GRPCClient{info: &PluginInfo{ParameterSchema: map[string]ParameterSpec{
+		"token": {Name: "token", Type: ParamTypeSecret, AllowedValues: []string{"a", "b"}},
+	}}}
+
+	err := client.ValidateParameters(map[string]string{"token": "s3cr3t"})
+	if err == nil {
+		t.Fatalf("ValidateParameters() error = nil, want error")
+	}
+	if strings.Contains(err.Error(), "s3cr3t") || strings.Contains(err.Error(), "[a b]") {
+		t.Errorf("ValidateParameters() error = %v, leaks the secret or its allowed values", err)
+	}
+}
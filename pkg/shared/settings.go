@@ -0,0 +1,63 @@
+package shared
+
+import (
+	"fmt"
+	"time"
+)
+
+// Log levels of settings.log_level, from the most verbose
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// Settings are defaults for the whole host; what a plugin configures for itself takes precedence
+type Settings struct {
+	ExecutionTimeout    Duration  `json:"execution_timeout,omitempty" min:"0"`     // Deadline of executions of plugins without their own execution_timeout; 0 for none
+	HealthCheckInterval Duration  `json:"health_check_interval,omitempty" min:"0"` // How often running plugins are health checked (default 30s)
+	InfoTimeout         Duration  `json:"info_timeout,omitempty" min:"0"`          // Deadline for asking a plugin for its info; 0 for none
+	LogLevel            string    `json:"log_level,omitempty"`                     // Least severe plugin log the CLI shows (debug/info/warn/error, default info)
+	OutputDir           string    `json:"output_dir,omitempty"`                    // Where the CLI saves the artifacts of executions, below a directory per plugin
+	PortRange           []int     `json:"port_range,omitempty"`                    // First and last port given to local plugins without a fixed one, such as [51000, 51999]; any free one by default
+	EnvPolicy           EnvPolicy `json:"env_policy,omitempty"`                    // Host variables local plugins without their own env_policy inherit (inherit-all/allowlist/none, default inherit-all)
+	HistoryMaxAge       Duration  `json:"history_max_age,omitempty" min:"0"`       // How long recorded executions are kept; 0 for as long as history_max_runs allows
+	HistoryMaxRuns      int       `json:"history_max_runs,omitempty" min:"0"`      // Latest executions kept of each plugin; 0 for all of them
+}
+
+// validate checks the settings
+func (s *Settings) validate() error {
+	switch s.LogLevel {
+	case "", LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError:
+	default:
+		return fmt.Errorf("invalid settings log_level: %s (must be debug, info, warn, or error)", s.LogLevel)
+	}
+	switch s.EnvPolicy {
+	case "", EnvInheritAll, EnvAllowlist, EnvNone:
+	default:
+		return fmt.Errorf("invalid settings env_policy: %s (must be inherit-all, allowlist, or none)", s.EnvPolicy)
+	}
+	if s.PortRange != nil {
+		if len(s.PortRange) != 2 || s.PortRange[0] < 1 || s.PortRange[0] > s.PortRange[1] || s.PortRange[1] > 65535 {
+			return fmt.Errorf("invalid settings port_range: %v (must be [first, last] within 1-65535)", s.PortRange)
+		}
+	}
+	return nil
+}
+
+// healthCheckInterval returns how often running plugins are health checked
+func (c *AppConfig) healthCheckInterval() time.Duration {
+	if c.Settings != nil && c.Settings.HealthCheckInterval > 0 {
+		return time.Duration(c.Settings.HealthCheckInterval)
+	}
+	return DefaultHealthCheck().Interval
+}
+
+// infoTimeout returns the deadline for asking a plugin for its info, 0 for none
+func (c *AppConfig) infoTimeout() time.Duration {
+	if c.Settings == nil {
+		return 0
+	}
+	return time.Duration(c.Settings.InfoTimeout)
+}
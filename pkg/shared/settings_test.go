@@ -0,0 +1,65 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig_settings(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	data := `{
+		"settings": {"execution_timeout": "1m", "health_check_interval": "10s", "info_timeout": "2s", "log_level": "warn", "output_dir": "out"},
+		"plugins": {
+			"default": {"type": "remote", "address": "localhost:9001"},
+			"own": {"type": "remote", "address": "localhost:9002", "execution_timeout": "5s"}
+		}
+	}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if got := time.Duration(config.Plugins["default"].ExecutionTimeout); got != time.Minute {
+		t.Errorf("execution_timeout of a plugin without one = %v, want the settings' 1m", got)
+	}
+	if got := time.Duration(config.Plugins["own"].ExecutionTimeout); got != 5*time.Second {
+		t.Errorf("execution_timeout of a plugin with one = %v, want its own 5s", got)
+	}
+	if got := config.healthCheckInterval(); got != 10*time.Second {
+		t.Errorf("healthCheckInterval() = %v, want 10s", got)
+	}
+	if got := config.infoTimeout(); got != 2*time.Second {
+		t.Errorf("infoTimeout() = %v, want 2s", got)
+	}
+	if !filepath.IsAbs(config.Settings.OutputDir) {
+		t.Errorf("output_dir = %q, want it resolved", config.Settings.OutputDir)
+	}
+
+	// Without settings, the defaults apply
+	if got := (&AppConfig{}).healthCheckInterval(); got != DefaultHealthCheck().Interval {
+		t.Errorf("healthCheckInterval() = %v, want the default", got)
+	}
+
+	data = `{"settings": {"log_level": "loud"}, "plugins": {}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "invalid settings log_level") {
+		t.Errorf("LoadConfig() error = %v, want the invalid log level", err)
+	}
+
+	data = `{"settings": {"port_range": [52000, 51000]}, "plugins": {}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "invalid settings port_range") {
+		t.Errorf("LoadConfig() error = %v, want the invalid port range", err)
+	}
+}
@@ -0,0 +1,155 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultStopTimeout is how long a stopping plugin has to exit after SIGTERM before it is killed
+const DefaultStopTimeout = 5 * time.Second
+
+// killWait bounds the wait for a killed process to be reaped
+const killWait = time.Second
+
+// stopTimeout returns the configured grace period or the default
+func (p *PluginConfig) stopTimeout() time.Duration {
+	if p.StopTimeout > 0 {
+		return time.Duration(p.StopTimeout)
+	}
+	return DefaultStopTimeout
+}
+
+// shutdown stops a plugin gracefully: in-flight executions are canceled, the connection is closed
+// and the process gets SIGTERM, followed by SIGKILL once the grace period is over
+func (m *ManagedPlugin) shutdown() error {
+	if m.stopHealth != nil {
+		m.stopHealth()
+	}
+	if m.restartTimer != nil {
+		m.restartTimer.Stop()
+	}
+	deadline := time.Now().Add(m.Config.stopTimeout())
+
+	for _, client := range m.clients() {
+		client.inflight.drain(time.Until(deadline))
+	}
+
+	// Replicas get their grace period at the same time as the primary
+	var wg sync.WaitGroup
+	replicaErrs := make([]error, len(m.replicas))
+	for i, r := range m.replicas {
+		if r == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, r *replica) {
+			defer wg.Done()
+			if err := r.stop(time.Until(deadline)); err != nil {
+				replicaErrs[i] = fmt.Errorf("failed to stop replica %d: %v", r.index, err)
+			}
+		}(i, r)
+	}
+
+	var errs []error
+	if err := m.Client.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close plugin client: %v", err))
+	}
+	if m.process != nil {
+		if err := m.process.terminate(time.Until(deadline)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop plugin process: %v", err))
+		}
+	}
+	wg.Wait()
+	errs = append(errs, replicaErrs...)
+	m.release()
+	return errors.Join(errs...)
+}
+
+// idlePollInterval is how often waitIdle checks for executions still in flight
+const idlePollInterval = 100 * time.Millisecond
+
+// waitIdle waits up to timeout for the executions in flight on the clients to finish on their
+// own, unlike drain, and reports whether they did
+func waitIdle(clients []*GRPCClient, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if _, idle := idleSince(clients); idle {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(idlePollInterval)
+	}
+}
+
+// executions tracks the in-flight executions of a client so they can be canceled on shutdown
+type executions struct {
+	mu      sync.Mutex
+	cancels map[int]context.CancelFunc
+	next    int
+	idle    chan struct{} // closed when the last execution finishes during a drain
+	last    time.Time     // when the last execution finished
+	busy    time.Time     // when the executions in flight began to run without a break
+}
+
+// track registers an execution's cancel function; the returned function unregisters it
+func (e *executions) track(cancel context.CancelFunc) func() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.cancels == nil {
+		e.cancels = make(map[int]context.CancelFunc)
+	}
+	id := e.next
+	e.next++
+	if len(e.cancels) == 0 {
+		e.busy = time.Now()
+	}
+	e.cancels[id] = cancel
+	return func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		delete(e.cancels, id)
+		e.last = time.Now()
+		if len(e.cancels) == 0 && e.idle != nil {
+			close(e.idle)
+			e.idle = nil
+		}
+	}
+}
+
+// count returns how many executions are in flight
+func (e *executions) count() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.cancels)
+}
+
+// drain cancels all in-flight executions and waits up to timeout for them to return
+func (e *executions) drain(timeout time.Duration) bool {
+	e.mu.Lock()
+	if len(e.cancels) == 0 {
+		e.mu.Unlock()
+		return true
+	}
+	for _, cancel := range e.cancels {
+		cancel()
+	}
+	if e.idle == nil {
+		e.idle = make(chan struct{})
+	}
+	idle := e.idle
+	e.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-idle:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
@@ -0,0 +1,157 @@
+package shared
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestTerminate(t *testing.T) {
+	tests := []struct {
+		name      string
+		script    string
+		grace     time.Duration
+		minWait   time.Duration
+		maxWait   time.Duration
+		wantState string
+	}{
+		{
+			name:      "Exits on SIGTERM",
+			script:    "trap 'exit 3' TERM; echo ready; while :; do sleep 0.01; done",
+			grace:     5 * time.Second,
+			maxWait:   2 * time.Second,
+			wantState: "exit status 3",
+		},
+		{
+			name:      "Ignores SIGTERM until killed",
+			script:    "trap '' TERM; echo ready; while :; do sleep 0.01; done",
+			grace:     200 * time.Millisecond,
+			minWait:   200 * time.Millisecond,
+			maxWait:   2 * time.Second,
+			wantState: "signal: killed",
+		},
+		{
+			name:      "No grace period",
+			script:    "trap 'exit 3' TERM; echo ready; while :; do sleep 0.01; done",
+			maxWait:   2 * time.Second,
+			wantState: "signal: killed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("sh", "-c", tt.script)
+			stdout, err := cmd.StdoutPipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cmd.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			// Only signal once the trap is in place
+			if _, err := bufio.NewReader(stdout).ReadString('\n'); err != nil {
+				t.Fatalf("script did not start: %v", err)
+			}
+
+			start := time.Now()
+			if err := watchProcess("test", cmd, nil, nil).terminate(tt.grace); err != nil {
+				t.Fatalf("terminate() error = %v", err)
+			}
+			elapsed := time.Since(start)
+			if elapsed < tt.minWait || elapsed > tt.maxWait {
+				t.Errorf("terminate() took %s, want between %s and %s", elapsed, tt.minWait, tt.maxWait)
+			}
+			if got := cmd.ProcessState.String(); got != tt.wantState {
+				t.Errorf("process state = %q, want %q", got, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestExecutions_drain(t *testing.T) {
+	var inflight executions
+	if !inflight.drain(0) {
+		t.Fatal("drain() without executions = false")
+	}
+
+	// An execution that returns shortly after being canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	done := inflight.track(cancel)
+	go func() {
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond)
+		done()
+	}()
+	if !inflight.drain(time.Second) {
+		t.Error("drain() = false, want true once the execution returned")
+	}
+
+	// An execution that never returns
+	stuck, cancelStuck := context.WithCancel(context.Background())
+	defer inflight.track(cancelStuck)()
+	if inflight.drain(50 * time.Millisecond) {
+		t.Error("drain() = true, want false while an execution is still running")
+	}
+	if stuck.Err() == nil {
+		t.Error("drain() did not cancel the execution")
+	}
+}
+
+func TestPluginManager_StopPluginCancelsExecutions(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	proto.RegisterPluginServer(server, &GRPCServer{Impl: tickingPlugin{}})
+	StartHealthServer(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	pm := NewPluginManager(&AppConfig{})
+	defer pm.StopAll()
+	if err := pm.StartPlugin("ticker", PluginConfig{Type: PluginTypeRemote, Address: listener.Addr().String()}); err != nil {
+		t.Fatalf("StartPlugin() error = %v", err)
+	}
+	plugin, err := pm.GetPlugin("ticker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcClient := plugin.(*GRPCClient)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- plugin.Execute(context.Background(), nil, discardHandler{})
+	}()
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		grpcClient.inflight.mu.Lock()
+		running := len(grpcClient.inflight.cancels)
+		grpcClient.inflight.mu.Unlock()
+		if running > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("execution did not start")
+		}
+	}
+
+	if err := pm.StopPlugin("ticker"); err != nil {
+		t.Fatalf("StopPlugin() error = %v", err)
+	}
+	select {
+	case err := <-result:
+		if err == nil || !strings.Contains(err.Error(), codes.Canceled.String()) {
+			t.Errorf("Execute() error = %v, want canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Execute() did not return after StopPlugin")
+	}
+}
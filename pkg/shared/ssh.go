@@ -0,0 +1,163 @@
+package shared
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSHConfig reaches a remote plugin through an SSH tunnel instead of connecting to it directly
+type SSHConfig struct {
+	Host           string `json:"host"`                       // SSH server that can reach the plugin address
+	Port           int    `json:"port,omitempty"`             // SSH port, defaults to 22
+	User           string `json:"user,omitempty"`             // Login user, defaults to the ssh client's choice
+	IdentityFile   string `json:"identity_file,omitempty"`    // Private key to authenticate with; the SSH agent is used when empty
+	KnownHostsFile string `json:"known_hosts_file,omitempty"` // Known hosts file to verify the server against
+}
+
+// sshCommand is the ssh client invocation; tests replace it with a stand-in
+var sshCommand = []string{"ssh"}
+
+// validate checks the SSH settings of a remote plugin
+func (s *SSHConfig) validate(p *PluginConfig) error {
+	if s.Host == "" {
+		return fmt.Errorf("ssh host is required")
+	}
+	if s.Port < 0 || s.Port > 65535 {
+		return fmt.Errorf("invalid ssh port: %d", s.Port)
+	}
+	if len(p.Addresses) > 0 || strings.Contains(p.Address, "://") {
+		return fmt.Errorf("ssh requires a single host:port address")
+	}
+	if _, _, err := net.SplitHostPort(p.Address); err != nil {
+		return fmt.Errorf("ssh requires a host:port address: %v", err)
+	}
+	if p.Proxy != "" {
+		return fmt.Errorf("ssh and proxy are mutually exclusive")
+	}
+	return nil
+}
+
+// destination returns the [user@]host argument for ssh
+func (s *SSHConfig) destination() string {
+	if s.User != "" {
+		return s.User + "@" + s.Host
+	}
+	return s.Host
+}
+
+// sshTunnel is a running ssh client forwarding a local port to the plugin
+type sshTunnel struct {
+	cmd       *exec.Cmd
+	localAddr string
+	stderr    *lockedBuffer
+	done      chan struct{}
+}
+
+// lockedBuffer collects the ssh client's stderr for error messages
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.TrimSpace(b.buf.String())
+}
+
+// openSSHTunnel forwards a free local port to remoteAddr through the SSH server and waits until it accepts connections
+func openSSHTunnel(ctx context.Context, config *SSHConfig, remoteAddr string, timeout time.Duration) (*sshTunnel, error) {
+	if timeout <= 0 {
+		timeout = DefaultConnectTimeout
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to pick a local port for the ssh tunnel: %v", err)
+	}
+	localAddr := listener.Addr().String()
+	listener.Close()
+
+	args := append([]string{}, sshCommand[1:]...)
+	args = append(args,
+		"-N",
+		"-o", "BatchMode=yes",
+		"-o", "ExitOnForwardFailure=yes",
+		"-o", "ServerAliveInterval=15",
+		"-L", localAddr+":"+remoteAddr,
+	)
+	if config.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(config.Port))
+	}
+	if config.IdentityFile != "" {
+		args = append(args, "-i", config.IdentityFile, "-o", "IdentitiesOnly=yes")
+	}
+	if config.KnownHostsFile != "" {
+		args = append(args, "-o", "UserKnownHostsFile="+config.KnownHostsFile, "-o", "StrictHostKeyChecking=yes")
+	}
+	args = append(args, config.destination())
+
+	tunnel := &sshTunnel{
+		cmd:       exec.Command(sshCommand[0], args...),
+		localAddr: localAddr,
+		stderr:    &lockedBuffer{},
+		done:      make(chan struct{}),
+	}
+	tunnel.cmd.Stderr = tunnel.stderr
+	if err := tunnel.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ssh: %v", err)
+	}
+	go func() {
+		tunnel.cmd.Wait()
+		close(tunnel.done)
+	}()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		conn, err := net.DialTimeout("tcp", localAddr, connectRetryDelay)
+		if err == nil {
+			conn.Close()
+			return tunnel, nil
+		}
+
+		select {
+		case <-tunnel.done:
+			return nil, fmt.Errorf("ssh to %s exited: %s", config.destination(), tunnel.failure())
+		case <-deadline.C:
+			tunnel.close()
+			return nil, fmt.Errorf("ssh tunnel via %s not ready after %s: %s", config.destination(), timeout, tunnel.failure())
+		case <-ctx.Done():
+			tunnel.close()
+			return nil, ctx.Err()
+		case <-time.After(connectRetryDelay):
+		}
+	}
+}
+
+// failure describes why the ssh client didn't come up
+func (t *sshTunnel) failure() string {
+	if msg := t.stderr.String(); msg != "" {
+		return msg
+	}
+	return "no output from ssh"
+}
+
+// close stops the ssh client
+func (t *sshTunnel) close() {
+	t.cmd.Process.Kill()
+	<-t.done
+}
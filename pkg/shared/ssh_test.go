@@ -0,0 +1,183 @@
+package shared
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSSHHelperProcess stands in for the ssh client: it forwards the -L port directly to its target
+func TestSSHHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_SSH_HELPER") != "1" {
+		return
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	args = args[1:]
+
+	var forward string
+	for i, arg := range args {
+		if arg == "-L" {
+			forward = args[i+1]
+		}
+	}
+	if destination := args[len(args)-1]; strings.HasSuffix(destination, "unreachable") {
+		fmt.Fprintf(os.Stderr, "ssh: connect to host %s port 22: Connection refused\n", destination)
+		os.Exit(255)
+	}
+
+	// 127.0.0.1:<local>:<host>:<port>
+	parts := strings.SplitN(forward, ":", 3)
+	listener, err := net.Listen("tcp", parts[0]+":"+parts[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bind: %v\n", err)
+		os.Exit(255)
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			os.Exit(255)
+		}
+		go func() {
+			defer conn.Close()
+			upstream, err := net.Dial("tcp", parts[2])
+			if err != nil {
+				return
+			}
+			defer upstream.Close()
+			go io.Copy(upstream, conn)
+			io.Copy(conn, upstream)
+		}()
+	}
+}
+
+func TestSSHConfig_validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   PluginConfig
+		wantErr  bool
+		errorMsg string
+	}{
+		{
+			name:   "Valid",
+			config: PluginConfig{Type: PluginTypeRemote, Address: "10.0.0.5:50051", SSH: &SSHConfig{Host: "bastion", User: "deploy"}},
+		},
+		{
+			name:     "Missing host",
+			config:   PluginConfig{Type: PluginTypeRemote, Address: "10.0.0.5:50051", SSH: &SSHConfig{}},
+			wantErr:  true,
+			errorMsg: "ssh host is required",
+		},
+		{
+			name:     "Resolver target",
+			config:   PluginConfig{Type: PluginTypeRemote, Address: "dns:///plugins:50051", SSH: &SSHConfig{Host: "bastion"}},
+			wantErr:  true,
+			errorMsg: "single host:port address",
+		},
+		{
+			name:     "Replicas",
+			config:   PluginConfig{Type: PluginTypeRemote, Addresses: []string{"a:1", "b:1"}, SSH: &SSHConfig{Host: "bastion"}},
+			wantErr:  true,
+			errorMsg: "single host:port address",
+		},
+		{
+			name:     "Combined with proxy",
+			config:   PluginConfig{Type: PluginTypeRemote, Address: "10.0.0.5:50051", Proxy: "http://proxy:3128", SSH: &SSHConfig{Host: "bastion"}},
+			wantErr:  true,
+			errorMsg: "mutually exclusive",
+		},
+		{
+			name:     "Local plugin",
+			config:   PluginConfig{Type: PluginTypeBinary, Path: "plugin", Port: 50051, SSH: &SSHConfig{Host: "bastion"}},
+			wantErr:  true,
+			errorMsg: "only supported for remote plugins",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Validate() error = %v, want substring %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestPluginManager_RemoteThroughSSH(t *testing.T) {
+	server, addr := startStubPluginServer(t)
+	defer server.Stop()
+
+	t.Setenv("GO_WANT_SSH_HELPER", "1")
+	saved := sshCommand
+	sshCommand = []string{os.Args[0], "-test.run=TestSSHHelperProcess", "--"}
+	defer func() { sshCommand = saved }()
+
+	tests := []struct {
+		name     string
+		host     string
+		wantErr  bool
+		errorMsg string
+	}{
+		{
+			name: "Tunnel",
+			host: "bastion",
+		},
+		{
+			name:     "SSH fails",
+			host:     "unreachable",
+			wantErr:  true,
+			errorMsg: "Connection refused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := PluginConfig{
+				Type:           PluginTypeRemote,
+				Address:        addr,
+				SSH:            &SSHConfig{Host: tt.host, User: "deploy"},
+				ConnectTimeout: Duration(5 * time.Second),
+			}
+			pm := NewPluginManager(&AppConfig{Plugins: map[string]PluginConfig{"stub": config}})
+			defer pm.StopAll()
+
+			err := pm.StartPlugin("stub", config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StartPlugin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("StartPlugin() error = %v, want substring %q", err, tt.errorMsg)
+				}
+				return
+			}
+
+			tunnel := pm.plugins["stub"].tunnel
+			if tunnel == nil {
+				t.Fatalf("plugin was not connected through an ssh tunnel")
+			}
+			if _, err := pm.plugins["stub"].Client.GetInfo(pm.ctx); err != nil {
+				t.Errorf("GetInfo() through tunnel error = %v", err)
+			}
+
+			if err := pm.StopPlugin("stub"); err != nil {
+				t.Fatalf("StopPlugin() error = %v", err)
+			}
+			select {
+			case <-tunnel.done:
+			case <-time.After(time.Second):
+				t.Errorf("ssh tunnel still running after the plugin was stopped")
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// standbyProbeTimeout bounds the health probe made before promoting a standby
+const standbyProbeTimeout = 2 * time.Second
+
+// standbyProcess is a warm spare instance of a local plugin, ready to take over executions
+type standbyProcess struct {
+	process *pluginProcess
+	client  *GRPCClient
+}
+
+// stop closes the standby connection and terminates its process
+func (s *standbyProcess) stop(grace time.Duration) {
+	s.client.Close()
+	s.process.terminate(grace)
+}
+
+// validateStandby checks the warm standby settings
+func (p *PluginConfig) validateStandby() error {
+	if !p.Standby {
+		return nil
+	}
+	if p.IsRemote() {
+		return fmt.Errorf("standby is only supported for local plugins")
+	}
+	if p.StandbyPort < 0 || p.StandbyPort > 65535 || (p.StandbyPort != 0 && p.StandbyPort == p.Port) {
+		return fmt.Errorf("invalid standby_port: %d (must differ from port, 0 to use a free one)", p.StandbyPort)
+	}
+	return nil
+}
+
+// startStandby launches a spare instance of the plugin on config.StandbyPort and waits until it is ready
+func (pm *PluginManager) startStandby(m *ManagedPlugin, config PluginConfig, stdout, stderr io.Writer) (*standbyProcess, error) {
+	process, err := pm.startProcess(m, config, config.StandbyPort, stdout, stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start standby: %v", err)
+	}
+
+	client, err := NewPluginClient(config.StandbyPort, m.dialOptions()...)
+	if err != nil {
+		process.kill()
+		return nil, fmt.Errorf("failed to connect to standby: %v", err)
+	}
+	grpcClient := client.(*GRPCClient)
+	pm.configureClient(grpcClient, m.Name, config)
+
+	if err := grpcClient.waitStarted(pm.ctx, time.Duration(config.ReadyTimeout)); err != nil {
+		client.Close()
+		process.kill()
+		return nil, fmt.Errorf("standby did not become ready: %v", err)
+	}
+
+	return &standbyProcess{process: process, client: grpcClient}, nil
+}
+
+// failover promotes the warm standby to primary and replaces the failed instance in the
+// background. It reports false if there is no healthy standby; the caller must hold pm.mu.
+func (pm *PluginManager) failover(m *ManagedPlugin) bool {
+	standby := m.standby
+	if standby == nil {
+		return false
+	}
+	m.standby = nil
+
+	ctx, cancel := context.WithTimeout(pm.ctx, standbyProbeTimeout)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(standby.client.conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil || resp.Status != healthpb.HealthCheckResponse_SERVING {
+		standby.stop(0)
+		return false
+	}
+
+	// Swap the instances; the standby port now belongs to the failed primary's replacement
+	m.Client.Close()
+	if m.process != nil {
+		m.process.kill()
+	}
+	m.Client = standby.client
+	m.GRPCClient = standby.client
+	m.setProcess(standby.process)
+	m.Config.Port, m.Config.StandbyPort = m.Config.StandbyPort, m.Config.Port
+	m.FailoverCnt++
+	pm.monitorHealth(m)
+	pm.events.publish(Event{Type: EventPluginRestarted, Plugin: m.Name})
+	pm.states.update(m, StateReady)
+
+	go pm.replaceStandby(m, m.Config, pm.stdout, pm.stderr)
+	return true
+}
+
+// replaceStandby starts a new standby for the plugin and attaches it unless the plugin was stopped meanwhile
+func (pm *PluginManager) replaceStandby(m *ManagedPlugin, config PluginConfig, stdout, stderr io.Writer) {
+	standby, err := pm.startStandby(m, config, stdout, stderr)
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if err != nil {
+		m.LastError = fmt.Errorf("failed to replace standby: %v", err)
+		return
+	}
+	if pm.plugins[m.Name] != m || m.standby != nil {
+		standby.stop(config.stopTimeout())
+		return
+	}
+	m.standby = standby
+}
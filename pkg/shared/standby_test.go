@@ -0,0 +1,94 @@
+package shared
+
+import (
+	"net"
+	"os/exec"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+// startHealthOnlyServer serves just the health service on a random localhost port
+func startHealthOnlyServer(t *testing.T) (*grpc.Server, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	StartHealthServer(server)
+	go server.Serve(listener)
+	return server, listener.Addr().String()
+}
+
+func TestPluginManager_Failover(t *testing.T) {
+	tests := []struct {
+		name           string
+		standbyHealthy bool
+		wantFailover   bool
+	}{
+		{
+			name:           "Healthy standby is promoted",
+			standbyHealthy: true,
+			wantFailover:   true,
+		},
+		{
+			name:           "Unhealthy standby is discarded",
+			standbyHealthy: false,
+			wantFailover:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primaryServer, primaryAddr := startHealthOnlyServer(t)
+			defer primaryServer.Stop()
+			standbyServer, standbyAddr := startHealthOnlyServer(t)
+			defer standbyServer.Stop()
+			if !tt.standbyHealthy {
+				standbyServer.Stop()
+			}
+
+			primary, _ := NewClientWithAddress(primaryAddr)
+			standby, _ := NewClientWithAddress(standbyAddr)
+			standbyCmd := exec.Command("sleep", "30")
+			if err := standbyCmd.Start(); err != nil {
+				t.Fatalf("Failed to start standby process: %v", err)
+			}
+
+			pm := NewPluginManager(&AppConfig{})
+			defer pm.StopAll()
+			managed := &ManagedPlugin{
+				Name:       "test",
+				Config:     PluginConfig{Path: "/nonexistent", Port: 1000, Type: PluginTypeBinary, Standby: true, StandbyPort: 1001},
+				Client:     primary,
+				GRPCClient: primary.(*GRPCClient),
+				standby:    &standbyProcess{process: watchProcess("test", standbyCmd, nil, nil), client: standby.(*GRPCClient)},
+			}
+			pm.plugins["test"] = managed
+
+			pm.mu.Lock()
+			promoted := pm.failover(managed)
+			pm.mu.Unlock()
+
+			if promoted != tt.wantFailover {
+				t.Fatalf("failover() = %v, want %v", promoted, tt.wantFailover)
+			}
+			if !tt.wantFailover {
+				if managed.GRPCClient != primary {
+					t.Errorf("failover() replaced the primary without a healthy standby")
+				}
+				return
+			}
+			if managed.GRPCClient != standby {
+				t.Errorf("failover() did not route to the standby client")
+			}
+			if managed.Config.Port != 1001 || managed.Config.StandbyPort != 1000 {
+				t.Errorf("failover() ports = %d/%d, want 1001/1000", managed.Config.Port, managed.Config.StandbyPort)
+			}
+			if managed.FailoverCnt != 1 {
+				t.Errorf("failover() FailoverCnt = %d, want 1", managed.FailoverCnt)
+			}
+		})
+	}
+}
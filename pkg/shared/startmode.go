@@ -0,0 +1,127 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+)
+
+// StartMode is when a long-lived host such as the daemon starts a plugin
+type StartMode string
+
+const (
+	// StartEager starts the plugin along with the host
+	StartEager StartMode = "eager"
+	// StartLazy starts the plugin on its first GetInfo or Execute
+	StartLazy StartMode = "lazy"
+)
+
+// validateStart checks the start mode
+func (p *PluginConfig) validateStart() error {
+	switch p.Start {
+	case "", StartEager, StartLazy:
+		return nil
+	default:
+		return fmt.Errorf("invalid start: %s (must be %s or %s)", p.Start, StartEager, StartLazy)
+	}
+}
+
+// IsLazy reports whether the plugin is only started when first used
+func (p *PluginConfig) IsLazy() bool {
+	return p.Start == StartLazy
+}
+
+// StartEager starts the configured plugins that aren't lazy, group by group so dependencies come
+// first, and returns the errors of those that failed, by name. Lazy plugins are left to start
+// on first use, unless an eager plugin depends on them.
+func (pm *PluginManager) StartEager() map[string]error {
+	return pm.startSelected(func(plugin PluginConfig) bool {
+		return !plugin.IsLazy()
+	})
+}
+
+// startSelected starts the configured plugins selected by selected that aren't running, group by
+// group so dependencies come first, and returns the errors of those that failed, by name
+func (pm *PluginManager) startSelected(selected func(PluginConfig) bool) map[string]error {
+	config := pm.Config()
+	failed := make(map[string]error)
+	for _, group := range config.StartupGroups() {
+		for _, name := range group {
+			plugin := config.Plugins[name]
+			if !selected(plugin) {
+				continue
+			}
+			// Starting would only retry a dependency that just failed
+			if dep := failedDependency(plugin, failed); dep != "" {
+				failed[name] = fmt.Errorf("dependency %s failed to start", dep)
+				continue
+			}
+			if _, err := pm.GetPlugin(name); err == nil {
+				continue
+			}
+			if err := pm.StartPlugin(name, plugin); err != nil {
+				failed[name] = err
+			}
+		}
+	}
+	return failed
+}
+
+// failedDependency returns the first dependency of plugin that failed to start, if any
+func failedDependency(plugin PluginConfig, failed map[string]error) string {
+	for _, dep := range plugin.DependsOn {
+		if _, ok := failed[dep]; ok {
+			return dep
+		}
+	}
+	return ""
+}
+
+// LazyPlugin returns a client for the named plugin that starts it on its first GetInfo or
+// Execute, and again after it was stopped, e.g. once idle past its keep_alive. Closing the
+// client leaves the plugin to the manager.
+func (pm *PluginManager) LazyPlugin(name string) PluginInterface {
+	return lazyPlugin{pm: pm, name: name}
+}
+
+// lazyPlugin resolves the running plugin on every call
+type lazyPlugin struct {
+	pm   *PluginManager
+	name string
+}
+
+func (p lazyPlugin) GetInfo(ctx context.Context) (*PluginInfo, error) {
+	plugin, err := p.pm.runningPlugin(p.name)
+	if err != nil {
+		return nil, err
+	}
+	return plugin.GetInfo(ctx)
+}
+
+func (p lazyPlugin) Execute(ctx context.Context, params map[string]string, output OutputHandler) error {
+	plugin, err := p.pm.runningPlugin(p.name)
+	if err != nil {
+		return err
+	}
+	return plugin.Execute(ctx, params, output)
+}
+
+func (p lazyPlugin) ValidateParameters(params map[string]string) error {
+	plugin, err := p.pm.runningPlugin(p.name)
+	if err != nil {
+		return err
+	}
+	return plugin.ValidateParameters(params)
+}
+
+func (p lazyPlugin) ReportExecutionSummary(startTime, endTime int64, success bool, err error, metadata map[string]string, metrics map[string]float64) (*ExecutionSummary, error) {
+	plugin, startErr := p.pm.runningPlugin(p.name)
+	if startErr != nil {
+		return nil, startErr
+	}
+	return plugin.ReportExecutionSummary(startTime, endTime, success, err, metadata, metrics)
+}
+
+// Close does nothing, as the plugin belongs to the manager
+func (p lazyPlugin) Close() error {
+	return nil
+}
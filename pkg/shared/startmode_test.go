@@ -0,0 +1,52 @@
+package shared
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPluginManager_StartEager(t *testing.T) {
+	server, addr := startStubPluginServer(t)
+	defer server.Stop()
+
+	config := &AppConfig{Plugins: map[string]PluginConfig{
+		"eager":   {Type: PluginTypeRemote, Address: addr},
+		"lazy":    {Type: PluginTypeRemote, Address: addr, Start: StartLazy},
+		"sidecar": {Type: PluginTypeRemote, Address: addr, Start: StartLazy},
+		"app":     {Type: PluginTypeRemote, Address: addr, DependsOn: []string{"sidecar"}},
+	}}
+	pm := NewPluginManager(config)
+	defer pm.StopAll()
+
+	if failed := pm.StartEager(); len(failed) != 0 {
+		t.Fatalf("StartEager() failed = %v", failed)
+	}
+	// A lazy plugin an eager one depends on is started along with it
+	for name, want := range map[string]bool{"eager": true, "app": true, "sidecar": true, "lazy": false} {
+		if _, err := pm.GetPlugin(name); (err == nil) != want {
+			t.Errorf("%s running = %v after StartEager(), want %v", name, err == nil, want)
+		}
+	}
+
+	// Starting again leaves running plugins alone
+	if failed := pm.StartEager(); len(failed) != 0 {
+		t.Errorf("second StartEager() failed = %v", failed)
+	}
+
+	// A lazy client starts its plugin on first use, and again once it was stopped
+	lazy := pm.LazyPlugin("lazy")
+	for i := 0; i < 2; i++ {
+		if _, err := lazy.GetInfo(context.Background()); err != nil {
+			t.Fatalf("GetInfo() error = %v", err)
+		}
+		if _, err := pm.GetPlugin("lazy"); err != nil {
+			t.Fatalf("lazy plugin not running after first use: %v", err)
+		}
+		if err := pm.StopPlugin("lazy"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pm.LazyPlugin("missing").Execute(context.Background(), nil, discardHandler{}); err == nil {
+		t.Error("Execute() of an unconfigured plugin succeeded")
+	}
+}
@@ -0,0 +1,215 @@
+package shared
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PluginState is where a plugin is in its lifecycle
+type PluginState string
+
+const (
+	StateStarting   PluginState = "starting"
+	StateReady      PluginState = "ready"
+	StateExecuting  PluginState = "executing" // Ready with executions in flight
+	StateUnhealthy  PluginState = "unhealthy"
+	StateRestarting PluginState = "restarting"
+	StateStopped    PluginState = "stopped"
+)
+
+// PluginStatus is the state of a configured plugin
+type PluginStatus struct {
+	Name        string
+	Type        PluginType
+	Description string
+	State       PluginState
+	Since       time.Time // When the plugin entered State; zero if it was never started
+	Started     time.Time // When the plugin last became ready after starting or restarting; zero unless it is running
+	Running     bool
+	Restarts    int
+	Failovers   int
+	Address     string // Of a remote plugin, the one it is connected to after failovers
+	LastError   error
+	Queued      int            // Executions waiting for a slot under max_concurrent_executions
+	Usage       *ResourceUsage // Of a running local plugin, with its CPU share since the previous status
+	Executions  int            // In flight on any of the plugin's instances
+}
+
+// stateTracker records the state of each plugin the manager has started. It has a lock of its
+// own, so states can be read while a plugin is starting or restarting under pm.mu.
+type stateTracker struct {
+	mu        sync.Mutex
+	plugins   map[string]*PluginStatus
+	clients   map[string][]*GRPCClient // Current clients of each plugin's instances, to tell whether it's executing
+	processes map[string]*pluginProcess
+	events    *eventBus // Where state changes are published
+}
+
+// starting records that a plugin is being started with config
+func (t *stateTracker) starting(name string, config PluginConfig) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.plugins == nil {
+		t.plugins = make(map[string]*PluginStatus)
+		t.clients = make(map[string][]*GRPCClient)
+		t.processes = make(map[string]*pluginProcess)
+	}
+	t.plugins[name] = &PluginStatus{Name: name, Type: config.Type, Description: config.Description, State: StateStarting, Since: time.Now()}
+	delete(t.clients, name)
+	delete(t.processes, name)
+	t.events.publish(Event{Type: EventPluginStarted, Plugin: name})
+}
+
+// update records a running plugin's state along with its counters and last error; the caller
+// must hold pm.mu
+func (t *stateTracker) update(m *ManagedPlugin, state PluginState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	status, ok := t.plugins[m.Name]
+	if !ok {
+		return
+	}
+	if status.State != state {
+		if state == StateReady && (status.State == StateStarting || status.State == StateRestarting) {
+			status.Started = time.Now()
+		}
+		status.State = state
+		status.Since = time.Now()
+		switch state {
+		case StateReady:
+			t.events.publish(Event{Type: EventPluginReady, Plugin: m.Name, Time: status.Since})
+		case StateUnhealthy:
+			t.events.publish(Event{Type: EventPluginUnhealthy, Plugin: m.Name, Time: status.Since, Err: m.LastError})
+		}
+	}
+	status.Type = m.Config.Type
+	status.Description = m.Config.Description
+	status.Restarts = m.RestartCnt
+	status.Failovers = m.FailoverCnt
+	status.Address = m.activeAddress()
+	status.LastError = m.LastError
+	t.clients[m.Name] = m.clients()
+	if m.process != nil {
+		t.processes[m.Name] = m.process
+	} else {
+		delete(t.processes, m.Name)
+	}
+}
+
+// refresh records which instances of a running plugin are up after one of its replicas was
+// replaced; the caller must hold pm.mu
+func (t *stateTracker) refresh(m *ManagedPlugin) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.plugins[m.Name]; ok {
+		t.clients[m.Name] = m.clients()
+	}
+}
+
+// stopped records that a plugin was stopped, or failed to start with err
+func (t *stateTracker) stopped(name string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	status, ok := t.plugins[name]
+	if !ok {
+		return
+	}
+	status.State = StateStopped
+	status.Since = time.Now()
+	status.Started = time.Time{}
+	if err != nil {
+		status.LastError = err
+	}
+	delete(t.clients, name)
+	delete(t.processes, name)
+	t.events.publish(Event{Type: EventPluginStopped, Plugin: name, Time: status.Since, Err: err})
+}
+
+// get returns a copy of a plugin's recorded status, with ready plugins that have executions in
+// flight reported as executing, and the plugin's process if it has one
+func (t *stateTracker) get(name string) (PluginStatus, *pluginProcess, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	recorded, ok := t.plugins[name]
+	if !ok {
+		return PluginStatus{}, nil, false
+	}
+	status := *recorded
+	status.Running = status.State != StateStopped && status.State != StateStarting
+	for _, client := range t.clients[name] {
+		status.Executions += client.inflight.count()
+	}
+	if status.State == StateReady {
+		if since, busy := busySince(t.clients[name]); busy {
+			status.State = StateExecuting
+			status.Since = since
+		}
+	}
+	return status, t.processes[name], true
+}
+
+// active returns the plugins that are starting or running
+func (t *stateTracker) active() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var names []string
+	for name, status := range t.plugins {
+		if status.State != StateStopped {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Status returns the state of a configured or running plugin. Plugins that were never started
+// are reported as stopped.
+func (pm *PluginManager) Status(name string) (PluginStatus, error) {
+	status, process, recorded := pm.states.get(name)
+	status.Queued = pm.queue.queued(name)
+	if recorded && status.State != StateStopped {
+		// Sampling can fail as the process exits; the status is still worth returning
+		if process != nil {
+			if usage, err := process.recentUsage(); err == nil {
+				status.Usage = &usage
+			}
+		}
+		return status, nil
+	}
+
+	config, ok := pm.Config().Plugins[name]
+	if !ok {
+		return PluginStatus{}, fmt.Errorf("plugin %q not found in configuration", name)
+	}
+	if !recorded {
+		status = PluginStatus{Name: name, State: StateStopped, Queued: status.Queued}
+	}
+	// A reload may have changed the definition since the plugin last ran
+	status.Type = config.Type
+	status.Description = config.Description
+	return status, nil
+}
+
+// Statuses returns the state of every configured or running plugin, sorted by name
+func (pm *PluginManager) Statuses() []PluginStatus {
+	config := pm.Config()
+	names := make([]string, 0, len(config.Plugins))
+	for name := range config.Plugins {
+		names = append(names, name)
+	}
+	for _, name := range pm.states.active() {
+		if _, ok := config.Plugins[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	statuses := make([]PluginStatus, 0, len(names))
+	for _, name := range names {
+		if status, err := pm.Status(name); err == nil {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,110 @@
+package shared
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+)
+
+func TestPluginManager_Status(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	proto.RegisterPluginServer(server, &GRPCServer{Impl: tickingPlugin{}})
+	StartHealthServer(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	// Nothing listens on a closed listener's address, so connecting times out
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+
+	config := &AppConfig{Plugins: map[string]PluginConfig{
+		"ticking":     {Type: PluginTypeRemote, Address: listener.Addr().String(), Description: "Ticks"},
+		"unreachable": {Type: PluginTypeRemote, Address: closed.Addr().String(), ConnectTimeout: Duration(300 * time.Millisecond)},
+	}}
+	pm := NewPluginManager(config)
+	defer pm.StopAll()
+
+	expectState := func(name string, want PluginState) PluginStatus {
+		t.Helper()
+		status, err := pm.Status(name)
+		if err != nil {
+			t.Fatalf("Status(%s) error = %v", name, err)
+		}
+		if status.State != want {
+			t.Fatalf("Status(%s).State = %s, want %s", name, status.State, want)
+		}
+		return status
+	}
+
+	if status := expectState("ticking", StateStopped); !status.Since.IsZero() || status.Description != "Ticks" {
+		t.Errorf("never started plugin = %+v, want stopped without a timestamp", status)
+	}
+	if _, err := pm.Status("missing"); err == nil {
+		t.Error("Status() of an unknown plugin succeeded")
+	}
+
+	// A plugin being started can be seen as starting, and one that failed to start keeps its error
+	started := make(chan error)
+	go func() { started <- pm.StartPlugin("unreachable", config.Plugins["unreachable"]) }()
+	time.Sleep(100 * time.Millisecond)
+	expectState("unreachable", StateStarting)
+	if err := <-started; err == nil {
+		t.Fatal("StartPlugin() of an unreachable plugin succeeded")
+	}
+	if status := expectState("unreachable", StateStopped); status.LastError == nil || status.Running {
+		t.Errorf("failed start = %+v, want stopped with the error", status)
+	}
+
+	if err := pm.StartPlugin("ticking", config.Plugins["ticking"]); err != nil {
+		t.Fatalf("StartPlugin() error = %v", err)
+	}
+	ready := expectState("ticking", StateReady)
+	if !ready.Running || ready.Since.IsZero() || ready.Started.IsZero() || ready.Executions != 0 {
+		t.Errorf("started plugin = %+v, want running and idle with timestamps", ready)
+	}
+
+	plugin, err := pm.GetPlugin("ticking")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		plugin.Execute(ctx, nil, discardHandler{})
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	executing := expectState("ticking", StateExecuting)
+	if executing.Since.Before(ready.Since) {
+		t.Errorf("executing since %v, before the plugin was ready at %v", executing.Since, ready.Since)
+	}
+	if executing.Executions != 1 || !executing.Started.Equal(ready.Started) {
+		t.Errorf("executing plugin = %+v, want one execution and the start time kept", executing)
+	}
+	cancel()
+	<-done
+	expectState("ticking", StateReady)
+
+	statuses := pm.Statuses()
+	if len(statuses) != 2 || statuses[0].Name != "ticking" || statuses[1].Name != "unreachable" {
+		t.Errorf("Statuses() = %+v, want both plugins sorted", statuses)
+	}
+
+	if err := pm.StopPlugin("ticking"); err != nil {
+		t.Fatalf("StopPlugin() error = %v", err)
+	}
+	if status := expectState("ticking", StateStopped); status.Since.Before(ready.Since) || status.Running || !status.Started.IsZero() {
+		t.Errorf("stopped plugin = %+v", status)
+	}
+}
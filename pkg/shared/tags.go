@@ -0,0 +1,55 @@
+package shared
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// validateTags checks the plugin's tags, which commands select groups of plugins by
+func (p *PluginConfig) validateTags() error {
+	for _, tag := range p.Tags {
+		if tag == "" || strings.ContainsAny(tag, ", \t") {
+			return fmt.Errorf("invalid tag %q: must be set and contain no commas or spaces", tag)
+		}
+	}
+	return nil
+}
+
+// HasTags reports whether the plugin has every one of tags
+func (p *PluginConfig) HasTags(tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, t := range p.Tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Tagged returns the names of the plugins that have every one of tags, in name order
+func (c *AppConfig) Tagged(tags []string) []string {
+	var names []string
+	for name, plugin := range c.Plugins {
+		if plugin.HasTags(tags) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StartTagged starts the configured plugins that have every one of tags, lazy ones included,
+// group by group so dependencies come first, and returns the errors of those that failed, by
+// name. Plugins already running are left as they are.
+func (pm *PluginManager) StartTagged(tags []string) map[string]error {
+	return pm.startSelected(func(plugin PluginConfig) bool {
+		return plugin.HasTags(tags)
+	})
+}
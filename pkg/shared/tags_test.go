@@ -0,0 +1,66 @@
+package shared
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAppConfig_Tagged(t *testing.T) {
+	config := &AppConfig{Plugins: map[string]PluginConfig{
+		"addition": {Tags: []string{"math", "demo"}},
+		"hello":    {Tags: []string{"demo"}},
+		"plain":    {},
+	}}
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{name: "No tags", want: []string{"addition", "hello", "plain"}},
+		{name: "One tag", tags: []string{"demo"}, want: []string{"addition", "hello"}},
+		{name: "Every tag", tags: []string{"demo", "math"}, want: []string{"addition"}},
+		{name: "Unknown tag", tags: []string{"io"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := config.Tagged(tt.tags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tagged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPluginConfig_validateTags(t *testing.T) {
+	for _, tags := range [][]string{{""}, {"a,b"}, {"a b"}} {
+		p := PluginConfig{Tags: tags}
+		if err := p.validateTags(); err == nil {
+			t.Errorf("validateTags(%q) succeeded", tags)
+		}
+	}
+	p := PluginConfig{Tags: []string{"math", "team-a"}}
+	if err := p.validateTags(); err != nil {
+		t.Errorf("validateTags() error = %v", err)
+	}
+}
+
+func TestPluginManager_StartTagged(t *testing.T) {
+	server, addr := startStubPluginServer(t)
+	defer server.Stop()
+
+	config := &AppConfig{Plugins: map[string]PluginConfig{
+		"addition": {Type: PluginTypeRemote, Address: addr, Tags: []string{"math"}, Start: StartLazy},
+		"hello":    {Type: PluginTypeRemote, Address: addr, Tags: []string{"demo"}},
+	}}
+	pm := NewPluginManager(config)
+	defer pm.StopAll()
+
+	if failed := pm.StartTagged([]string{"math"}); len(failed) != 0 {
+		t.Fatalf("StartTagged() failed = %v", failed)
+	}
+	for name, want := range map[string]bool{"addition": true, "hello": false} {
+		if _, err := pm.GetPlugin(name); (err == nil) != want {
+			t.Errorf("%s running = %v after StartTagged(), want %v", name, err == nil, want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ErrorCodeTimeout is the error code of executions that ran past their timeout
+const ErrorCodeTimeout = "TIMEOUT"
+
+// TimeoutError reports an execution cancelled at its execution_timeout or --timeout
+type TimeoutError struct {
+	Plugin  string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s: plugin %s did not finish within %s", ErrorCodeTimeout, e.Plugin, e.Timeout)
+}
+
+// ExecuteWithTimeout executes a plugin under a deadline of timeout, if positive. The deadline
+// travels with the Execute call, so the plugin sees it on its context. An execution still
+// running at the deadline fails with a *TimeoutError, even if the plugin reported the
+// cancellation itself.
+func ExecuteWithTimeout(ctx context.Context, plugin PluginInterface, name string, timeout time.Duration, params map[string]string, handler OutputHandler) error {
+	return withTimeout(ctx, name, timeout, func(ctx context.Context) error {
+		return plugin.Execute(ctx, params, handler)
+	})
+}
+
+// withTimeout runs an execution under a deadline of timeout, if positive
+func withTimeout(ctx context.Context, name string, timeout time.Duration, execute func(context.Context) error) error {
+	if timeout <= 0 {
+		return execute(ctx)
+	}
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := execute(execCtx)
+	// Deadlines and cancellation of the caller's own are left to the caller
+	if execCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		return &TimeoutError{Plugin: name, Timeout: timeout}
+	}
+	return err
+}
+
+// deadlineClockLayouts are the clock times a deadline may be given as, meaning today
+var deadlineClockLayouts = []string{"15:04", "15:04:05"}
+
+// ParseDeadline parses a deadline given as an RFC 3339 time or as a clock time today, in now's
+// location
+func ParseDeadline(value string, now time.Time) (time.Time, error) {
+	if deadline, err := time.Parse(time.RFC3339, value); err == nil {
+		return deadline, nil
+	}
+	for _, layout := range deadlineClockLayouts {
+		clock, err := time.ParseInLocation(layout, value, now.Location())
+		if err == nil {
+			year, month, day := now.Date()
+			return time.Date(year, month, day, clock.Hour(), clock.Minute(), clock.Second(), 0, now.Location()), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid deadline %q: want an RFC 3339 time or a clock time such as 17:30", value)
+}
+
+// TimeoutUntil returns the timeout that ends an execution started at now by deadline, or after
+// timeout if that comes first. A zero deadline leaves timeout as it is; one that has passed is
+// an error.
+func TimeoutUntil(timeout time.Duration, deadline, now time.Time) (time.Duration, error) {
+	if deadline.IsZero() {
+		return timeout, nil
+	}
+	// Whole milliseconds keep the timeout readable where it is reported
+	left := deadline.Sub(now).Truncate(time.Millisecond)
+	if left <= 0 {
+		return 0, fmt.Errorf("deadline %s has passed", deadline.Format(time.RFC3339))
+	}
+	if timeout > 0 && timeout < left {
+		return timeout, nil
+	}
+	return left, nil
+}
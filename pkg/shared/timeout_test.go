@@ -0,0 +1,151 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+)
+
+// deadlinePlugin reports whether its context carries a deadline, then blocks until canceled
+type deadlinePlugin struct {
+	stubPlugin
+}
+
+func (deadlinePlugin) Execute(ctx context.Context, params map[string]string, output OutputHandler) error {
+	if _, ok := ctx.Deadline(); ok {
+		output.OnOutput("deadline")
+	}
+	<-ctx.Done()
+	// Report the cancellation the way plugins do, which alone would count as success
+	return output.OnError("CANCELLED", "execution cancelled", "")
+}
+
+func TestExecutionTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	proto.RegisterPluginServer(server, &GRPCServer{Impl: deadlinePlugin{}})
+	StartHealthServer(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	pm := NewPluginManager(&AppConfig{})
+	defer pm.StopAll()
+	config := PluginConfig{Type: PluginTypeRemote, Address: listener.Addr().String(), ExecutionTimeout: Duration(200 * time.Millisecond)}
+	if err := pm.StartPlugin("slow", config); err != nil {
+		t.Fatalf("StartPlugin() error = %v", err)
+	}
+	plugin, err := pm.GetPlugin("slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		ctxTimeout  time.Duration
+		runTimeout  time.Duration
+		wantTimeout time.Duration // 0 if the error must not be a timeout
+	}{
+		{name: "execution_timeout", wantTimeout: 200 * time.Millisecond},
+		{name: "Shorter run timeout", runTimeout: 50 * time.Millisecond, wantTimeout: 50 * time.Millisecond},
+		{name: "Caller's deadline is left alone", ctxTimeout: 50 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.ctxTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.ctxTimeout)
+				defer cancel()
+			}
+			handler := &recordingHandler{}
+			start := time.Now()
+			err := ExecuteWithTimeout(ctx, plugin, "slow", tt.runTimeout, nil, handler)
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Fatalf("execution took %s", elapsed)
+			}
+			if len(handler.output) == 0 || handler.output[0] != "deadline" {
+				t.Errorf("plugin saw no deadline, output = %v", handler.output)
+			}
+
+			var timedOut *TimeoutError
+			if !errors.As(err, &timedOut) {
+				if tt.wantTimeout > 0 {
+					t.Fatalf("error = %v, want a timeout", err)
+				}
+				return
+			}
+			if tt.wantTimeout == 0 {
+				t.Fatalf("error = %v, want no timeout", err)
+			}
+			if timedOut.Timeout != tt.wantTimeout || timedOut.Plugin != "slow" || !strings.HasPrefix(err.Error(), "TIMEOUT") {
+				t.Errorf("error = %v, want TIMEOUT after %s", err, tt.wantTimeout)
+			}
+		})
+	}
+}
+
+func TestParseDeadline(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2024-03-01T12:30:00Z", want: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)},
+		{value: "17:30", want: time.Date(2024, 3, 1, 17, 30, 0, 0, time.UTC)},
+		{value: "09:15:30", want: time.Date(2024, 3, 1, 9, 15, 30, 0, time.UTC)},
+		{value: "30s", wantErr: true},
+		{value: "25:00", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseDeadline(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDeadline() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseDeadline() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeoutUntil(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		deadline time.Time
+		want     time.Duration
+		wantErr  bool
+	}{
+		{name: "No deadline", timeout: time.Minute, want: time.Minute},
+		{name: "Deadline only", deadline: now.Add(time.Hour), want: time.Hour},
+		{name: "Timeout first", timeout: time.Minute, deadline: now.Add(time.Hour), want: time.Minute},
+		{name: "Deadline first", timeout: time.Hour, deadline: now.Add(time.Minute), want: time.Minute},
+		{name: "Deadline passed", deadline: now.Add(-time.Second), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TimeoutUntil(tt.timeout, tt.deadline, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TimeoutUntil() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("TimeoutUntil() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
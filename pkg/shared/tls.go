@@ -0,0 +1,176 @@
+package shared
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Environment variables used to hand the ephemeral TLS material to local plugins
+const (
+	TLSCACertEnvVar     = "PLUGIN_TLS_CA_CERT"
+	TLSServerCertEnvVar = "PLUGIN_TLS_SERVER_CERT"
+	TLSServerKeyEnvVar  = "PLUGIN_TLS_SERVER_KEY"
+)
+
+const (
+	autoMTLSServerName = "localhost"
+	autoMTLSValidity   = 365 * 24 * time.Hour
+)
+
+// AutoMTLS holds an ephemeral CA and the certificates it issued for one host/plugin pair
+type AutoMTLS struct {
+	caPEM         []byte
+	serverCertPEM []byte
+	serverKeyPEM  []byte
+	clientCert    tls.Certificate
+	caPool        *x509.CertPool
+}
+
+// NewAutoMTLS generates a fresh CA plus server and client certificates signed by it
+func NewAutoMTLS() (*AutoMTLS, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %v", err)
+	}
+	caTemplate, err := certTemplate("plugin-app ephemeral CA")
+	if err != nil {
+		return nil, err
+	}
+	caTemplate.IsCA = true
+	caTemplate.BasicConstraintsValid = true
+	caTemplate.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate: %v", err)
+	}
+
+	serverCertPEM, serverKeyPEM, err := issueCert(caCert, caKey, "plugin", x509.ExtKeyUsageServerAuth)
+	if err != nil {
+		return nil, err
+	}
+	clientCertPEM, clientKeyPEM, err := issueCert(caCert, caKey, "plugin-host", x509.ExtKeyUsageClientAuth)
+	if err != nil {
+		return nil, err
+	}
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+
+	return &AutoMTLS{
+		caPEM:         pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		serverCertPEM: serverCertPEM,
+		serverKeyPEM:  serverKeyPEM,
+		clientCert:    clientCert,
+		caPool:        pool,
+	}, nil
+}
+
+// certTemplate returns a certificate template with a random serial number
+func certTemplate(commonName string) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %v", err)
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(autoMTLSValidity),
+	}, nil
+}
+
+// issueCert creates a leaf certificate signed by the CA and returns it with its key in PEM form
+func issueCert(ca *x509.Certificate, caKey *ecdsa.PrivateKey, commonName string, usage x509.ExtKeyUsage) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key for %s: %v", commonName, err)
+	}
+	template, err := certTemplate(commonName)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+	template.DNSNames = []string{autoMTLSServerName}
+	template.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate for %s: %v", commonName, err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal key for %s: %v", commonName, err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// Env returns the environment variables that let the plugin serve TLS and verify the host
+func (m *AutoMTLS) Env() []string {
+	return []string{
+		fmt.Sprintf("%s=%s", TLSCACertEnvVar, m.caPEM),
+		fmt.Sprintf("%s=%s", TLSServerCertEnvVar, m.serverCertPEM),
+		fmt.Sprintf("%s=%s", TLSServerKeyEnvVar, m.serverKeyPEM),
+	}
+}
+
+// DialOption returns the client transport credentials for connecting to the plugin
+func (m *AutoMTLS) DialOption() grpc.DialOption {
+	return grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{m.clientCert},
+		RootCAs:      m.caPool,
+		ServerName:   autoMTLSServerName,
+		MinVersion:   tls.VersionTLS12,
+	}))
+}
+
+// ServerTLSFromEnv returns server credentials requiring client certificates if the host provided TLS material
+func ServerTLSFromEnv() ([]grpc.ServerOption, error) {
+	certPEM := os.Getenv(TLSServerCertEnvVar)
+	if certPEM == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(os.Getenv(TLSServerKeyEnvVar)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(os.Getenv(TLSCACertEnvVar))) {
+		return nil, fmt.Errorf("failed to load CA certificate from %s", TLSCACertEnvVar)
+	}
+
+	return []grpc.ServerOption{
+		grpc.Creds(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientCAs:    pool,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			MinVersion:   tls.VersionTLS12,
+		})),
+	}, nil
+}
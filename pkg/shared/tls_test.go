@@ -0,0 +1,73 @@
+package shared
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestAutoMTLS(t *testing.T) {
+	autoTLS, err := NewAutoMTLS()
+	if err != nil {
+		t.Fatalf("NewAutoMTLS() error = %v", err)
+	}
+
+	// Hand the TLS material to the "plugin" the same way the manager does
+	for _, kv := range autoTLS.Env() {
+		parts := strings.SplitN(kv, "=", 2)
+		t.Setenv(parts[0], parts[1])
+	}
+	serverOpts, err := ServerTLSFromEnv()
+	if err != nil {
+		t.Fatalf("ServerTLSFromEnv() error = %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer(serverOpts...)
+	StartHealthServer(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	tests := []struct {
+		name    string
+		opt     grpc.DialOption
+		wantErr bool
+	}{
+		{
+			name:    "Client with host certificate",
+			opt:     autoTLS.DialOption(),
+			wantErr: false,
+		},
+		{
+			name:    "Plaintext client",
+			opt:     grpc.WithTransportCredentials(insecure.NewCredentials()),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := grpc.Dial(listener.Addr().String(), tt.opt)
+			if err != nil {
+				t.Fatalf("grpc.Dial() error = %v", err)
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Health check error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errUsageUnsupported is returned where process usage can't be sampled
+var errUsageUnsupported = errors.New("process resource usage is only available on Linux")
+
+// ResourceUsage is what a plugin process uses of the machine
+type ResourceUsage struct {
+	PID        int
+	CPUSeconds float64 // User and system time since the process started
+	CPUPercent float64 // Share of one core over the sampled period
+	RSSBytes   uint64
+	OpenFDs    int // -1 if the descriptors can't be listed, e.g. for plugins running as another user
+	Sampled    time.Time
+}
+
+// Since returns the usage with its CPU share computed over the time since an earlier sample of
+// the same process
+func (u ResourceUsage) Since(before ResourceUsage) ResourceUsage {
+	u.CPUPercent = 0
+	if elapsed := u.Sampled.Sub(before.Sampled).Seconds(); elapsed > 0 {
+		u.CPUPercent = 100 * (u.CPUSeconds - before.CPUSeconds) / elapsed
+	}
+	return u
+}
+
+// usage samples the process, with its CPU share over its lifetime
+func (p *pluginProcess) usage() (ResourceUsage, error) {
+	usage, err := readProcessUsage(p.cmd.Process.Pid)
+	if err != nil {
+		return ResourceUsage{}, err
+	}
+	usage.PID = p.cmd.Process.Pid
+	return usage.Since(ResourceUsage{Sampled: p.started}), nil
+}
+
+// recentUsage samples the process, with its CPU share since the previous recent sample
+func (p *pluginProcess) recentUsage() (ResourceUsage, error) {
+	usage, err := p.usage()
+	if err != nil {
+		return ResourceUsage{}, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sampled != nil {
+		usage = usage.Since(*p.sampled)
+	}
+	p.sampled = &usage
+	return usage, nil
+}
+
+// Usage samples the resources the process of a running local plugin uses, with its CPU share
+// over the process's lifetime; see Since for the share over an execution.
+func (pm *PluginManager) Usage(name string) (ResourceUsage, error) {
+	pm.mu.RLock()
+	plugin, exists := pm.plugins[name]
+	var process *pluginProcess
+	if exists {
+		process = plugin.process
+	}
+	pm.mu.RUnlock()
+
+	if !exists {
+		return ResourceUsage{}, fmt.Errorf("plugin %s is not running", name)
+	}
+	if process == nil {
+		return ResourceUsage{}, fmt.Errorf("plugin %s has no local process", name)
+	}
+	return process.usage()
+}
+
+// MeasureUsage samples a plugin's process before an execution; the returned function samples it
+// again and returns the usage over the execution, or nil for plugins without a local process
+func (pm *PluginManager) MeasureUsage(name string) func() *ResourceUsage {
+	before, err := pm.Usage(name)
+	return func() *ResourceUsage {
+		if err != nil {
+			return nil
+		}
+		after, afterErr := pm.Usage(name)
+		if afterErr != nil || after.PID != before.PID {
+			return nil
+		}
+		during := after.Since(before)
+		return &during
+	}
+}
@@ -0,0 +1,73 @@
+package shared
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is the kernel's USER_HZ, in which /proc reports CPU time; it is 100 on every
+// architecture Linux runs plugins on
+const clockTicks = 100
+
+// readProcessUsage reads a process's CPU time and resident memory from /proc/<pid>/stat and
+// counts its open descriptors
+func readProcessUsage(pid int) (ResourceUsage, error) {
+	fields, err := readStat(pid)
+	if err != nil {
+		return ResourceUsage{}, fmt.Errorf("failed to read process usage: %v", err)
+	}
+	usage := ResourceUsage{Sampled: time.Now(), OpenFDs: -1}
+
+	utime, err1 := strconv.ParseUint(fields[11], 10, 64)
+	stime, err2 := strconv.ParseUint(fields[12], 10, 64)
+	rss, err3 := strconv.ParseUint(fields[21], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return ResourceUsage{}, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	usage.CPUSeconds = float64(utime+stime) / clockTicks
+	usage.RSSBytes = rss * uint64(os.Getpagesize())
+
+	if entries, err := os.ReadDir(fmt.Sprintf("/proc/%d/fd", pid)); err == nil {
+		usage.OpenFDs = len(entries)
+	}
+	return usage, nil
+}
+
+// processStartTime returns when a process started, in clock ticks after boot. Together with the
+// PID it identifies a process, as PIDs are reused.
+func processStartTime(pid int) (uint64, error) {
+	fields, err := readStat(pid)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(fields[19], 10, 64)
+}
+
+// isZombie reports whether a process has exited but wasn't reaped yet; it holds no resources anymore
+func isZombie(pid int) bool {
+	fields, err := readStat(pid)
+	return err == nil && fields[0] == "Z"
+}
+
+// readStat returns the fields of /proc/<pid>/stat from the state on, so utime (14) is at index
+// 11, stime at 12, starttime (22) at 19 and rss (24) at 21
+func readStat(pid int) ([]string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return nil, err
+	}
+	// The command name may contain spaces and parentheses, so fields are counted from its end
+	stat := string(data)
+	end := strings.LastIndexByte(stat, ')')
+	if end < 0 {
+		return nil, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	return fields, nil
+}
@@ -0,0 +1,48 @@
+package shared
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestPluginProcess_usage(t *testing.T) {
+	// Keep a core busy so the process has CPU time to report
+	cmd := exec.Command("sh", "-c", "while :; do :; done")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	process := watchProcess("busy", cmd, nil, nil)
+	defer process.terminate(0)
+
+	time.Sleep(300 * time.Millisecond)
+	usage, err := process.usage()
+	if err != nil {
+		t.Fatalf("usage() error = %v", err)
+	}
+	if usage.PID != cmd.Process.Pid || usage.RSSBytes == 0 || usage.OpenFDs < 3 {
+		t.Errorf("usage() = %+v, want the process's memory and descriptors", usage)
+	}
+	if usage.CPUSeconds <= 0 || usage.CPUPercent <= 10 {
+		t.Errorf("usage() CPU = %vs, %v%%; want a busy process", usage.CPUSeconds, usage.CPUPercent)
+	}
+
+	// Recent samples cover the time since the previous one
+	first, err := process.recentUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	second, err := process.recentUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := second.Since(first).CPUPercent; second.CPUPercent != want {
+		t.Errorf("recentUsage() CPUPercent = %v, want %v since the previous sample", second.CPUPercent, want)
+	}
+
+	process.terminate(0)
+	if _, err := process.usage(); err == nil {
+		t.Error("usage() of an exited process succeeded")
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package shared
+
+// readProcessUsage fails because sampling relies on Linux's /proc
+func readProcessUsage(pid int) (ResourceUsage, error) {
+	return ResourceUsage{}, errUsageUnsupported
+}
+
+// processStartTime fails for the same reason, so processes can't be told apart from ones that
+// reused their PID
+func processStartTime(pid int) (uint64, error) {
+	return 0, errUsageUnsupported
+}
+
+// isZombie can't tell without /proc
+func isZombie(pid int) bool {
+	return false
+}
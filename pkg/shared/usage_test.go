@@ -0,0 +1,57 @@
+package shared
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResourceUsage_Since(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		name   string
+		before ResourceUsage
+		after  ResourceUsage
+		want   float64
+	}{
+		{
+			name:   "Half a core",
+			before: ResourceUsage{CPUSeconds: 1, Sampled: start},
+			after:  ResourceUsage{CPUSeconds: 2, Sampled: start.Add(2 * time.Second)},
+			want:   50,
+		},
+		{
+			name:   "Two cores",
+			before: ResourceUsage{CPUSeconds: 0, Sampled: start},
+			after:  ResourceUsage{CPUSeconds: 2, Sampled: start.Add(time.Second)},
+			want:   200,
+		},
+		{
+			name:   "No time elapsed",
+			before: ResourceUsage{CPUSeconds: 1, Sampled: start},
+			after:  ResourceUsage{CPUSeconds: 1, CPUPercent: 80, Sampled: start},
+			want:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.after.Since(tt.before)
+			if got.CPUPercent != tt.want {
+				t.Errorf("Since().CPUPercent = %v, want %v", got.CPUPercent, tt.want)
+			}
+			if got.CPUSeconds != tt.after.CPUSeconds {
+				t.Errorf("Since() changed CPUSeconds to %v", got.CPUSeconds)
+			}
+		})
+	}
+}
+
+func TestPluginManager_UsageNotRunning(t *testing.T) {
+	pm := NewPluginManager(&AppConfig{})
+	if _, err := pm.Usage("missing"); err == nil {
+		t.Error("Usage() of a plugin that isn't running succeeded")
+	}
+	if measured := pm.MeasureUsage("missing")(); measured != nil {
+		t.Errorf("MeasureUsage() = %+v, want nil", measured)
+	}
+}
@@ -0,0 +1,333 @@
+package shared
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// VersionCheckMode controls how the host reacts to a plugin whose version is outside its
+// expected_version
+type VersionCheckMode string
+
+const (
+	// VersionCheckWarn logs the mismatch once and executes the plugin anyway
+	VersionCheckWarn VersionCheckMode = "warn"
+	// VersionCheckError refuses to execute the plugin
+	VersionCheckError VersionCheckMode = "error"
+)
+
+// semver is a semantic version: major.minor.patch with an optional pre-release
+type semver struct {
+	major, minor, patch int
+	pre                 string
+}
+
+func (v semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch)
+	if v.pre != "" {
+		s += "-" + v.pre
+	}
+	return s
+}
+
+// compare returns -1, 0 or 1 as v is lower than, equal to or higher than o. A pre-release is
+// lower than the release it precedes.
+func (v semver) compare(o semver) int {
+	for _, d := range []int{v.major - o.major, v.minor - o.minor, v.patch - o.patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.pre == o.pre:
+		return 0
+	case v.pre == "":
+		return 1
+	case o.pre == "":
+		return -1
+	}
+	a, b := strings.Split(v.pre, "."), strings.Split(o.pre, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		x, errX := strconv.Atoi(a[i])
+		y, errY := strconv.Atoi(b[i])
+		switch {
+		case errX == nil && errY == nil:
+			return sign(x - y)
+		case errX == nil:
+			return -1 // Numeric identifiers sort before alphanumeric ones
+		case errY == nil:
+			return 1
+		case a[i] < b[i]:
+			return -1
+		default:
+			return 1
+		}
+	}
+	return sign(len(a) - len(b))
+}
+
+func sign(d int) int {
+	switch {
+	case d < 0:
+		return -1
+	case d > 0:
+		return 1
+	}
+	return 0
+}
+
+// parseVersion parses a version as plugins report it, such as 1.4.2, v2.0.0-rc.1 or 1.2; build
+// metadata is ignored
+func parseVersion(s string) (semver, error) {
+	v, parts, err := parsePartialVersion(s)
+	if err != nil {
+		return semver{}, err
+	}
+	if parts == 0 {
+		return semver{}, fmt.Errorf("invalid version %q", s)
+	}
+	return v, nil
+}
+
+// parsePartialVersion parses a version whose minor and patch may be missing or wildcards (x, X
+// or *), returning how many of major, minor and patch are given
+func parsePartialVersion(s string) (semver, int, error) {
+	var v semver
+	core := strings.TrimPrefix(strings.TrimPrefix(s, "v"), "V")
+	core, _, _ = strings.Cut(core, "+")
+	core, v.pre, _ = strings.Cut(core, "-")
+	fields := strings.Split(core, ".")
+	if core == "" || len(fields) > 3 {
+		return semver{}, 0, fmt.Errorf("invalid version %q", s)
+	}
+	numbers := []*int{&v.major, &v.minor, &v.patch}
+	parts := 0
+	for i, field := range fields {
+		if field == "x" || field == "X" || field == "*" {
+			continue
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 || parts != i { // Nothing but wildcards follows a wildcard
+			return semver{}, 0, fmt.Errorf("invalid version %q", s)
+		}
+		*numbers[i] = n
+		parts++
+	}
+	if v.pre != "" && parts < 3 {
+		return semver{}, 0, fmt.Errorf("invalid version %q: a pre-release needs major.minor.patch", s)
+	}
+	return v, parts, nil
+}
+
+// next returns the lowest version above every version matching the first parts of v
+func (v semver) next(parts int) semver {
+	switch parts {
+	case 1:
+		return semver{major: v.major + 1}
+	case 2:
+		return semver{major: v.major, minor: v.minor + 1}
+	default:
+		return semver{major: v.major, minor: v.minor, patch: v.patch + 1}
+	}
+}
+
+// versionBound is one comparison a version must pass
+type versionBound struct {
+	op      string // >=, >, <= or <
+	version semver
+}
+
+func (b versionBound) allows(v semver) bool {
+	c := v.compare(b.version)
+	switch b.op {
+	case ">=":
+		return c >= 0
+	case ">":
+		return c > 0
+	case "<=":
+		return c <= 0
+	default:
+		return c < 0
+	}
+}
+
+// VersionConstraint is a semver range such as ">=1.2.0 <2.0.0", "^1.4", "~1.4.2", "1.x" or
+// "1.2.3 || >=2.1". Comparators separated by spaces or commas must all hold; ranges separated
+// by || are alternatives.
+type VersionConstraint struct {
+	text   string
+	ranges [][]versionBound
+}
+
+// ParseVersionConstraint parses a semver range
+func ParseVersionConstraint(s string) (*VersionConstraint, error) {
+	c := &VersionConstraint{text: s}
+	for _, alternative := range strings.Split(s, "||") {
+		comparators := strings.FieldsFunc(alternative, func(r rune) bool {
+			return r == ' ' || r == ',' || r == '\t'
+		})
+		if len(comparators) == 0 {
+			return nil, fmt.Errorf("invalid version range %q: empty range", s)
+		}
+		var bounds []versionBound
+		for _, comparator := range comparators {
+			b, err := parseComparator(comparator)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version range %q: %v", s, err)
+			}
+			bounds = append(bounds, b...)
+		}
+		c.ranges = append(c.ranges, bounds)
+	}
+	return c, nil
+}
+
+// parseComparator turns one comparator into the bounds it stands for
+func parseComparator(s string) ([]versionBound, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(s, prefix) {
+			op = prefix
+			break
+		}
+	}
+	v, parts, err := parsePartialVersion(strings.TrimPrefix(s, op))
+	if err != nil {
+		return nil, err
+	}
+	lower := versionBound{">=", v}
+	switch op {
+	case ">=":
+		return []versionBound{lower}, nil
+	case ">":
+		if parts < 3 {
+			return []versionBound{{">=", v.next(parts)}}, nil
+		}
+		return []versionBound{{">", v}}, nil
+	case "<=":
+		if parts < 3 {
+			return []versionBound{{"<", v.next(parts)}}, nil
+		}
+		return []versionBound{{"<=", v}}, nil
+	case "<":
+		return []versionBound{{"<", v}}, nil
+	case "^":
+		// Changes that leave the left-most non-zero part alone are compatible
+		switch {
+		case v.major > 0 || parts < 2:
+			return []versionBound{lower, {"<", v.next(1)}}, nil
+		case v.minor > 0 || parts < 3:
+			return []versionBound{lower, {"<", v.next(2)}}, nil
+		default:
+			return []versionBound{lower, {"<", v.next(3)}}, nil
+		}
+	case "~":
+		if parts < 2 {
+			return []versionBound{lower, {"<", v.next(1)}}, nil
+		}
+		return []versionBound{lower, {"<", v.next(2)}}, nil
+	default:
+		// A bare or = version matches what it leaves unspecified
+		if parts == 0 {
+			return nil, nil
+		}
+		if parts == 3 {
+			return []versionBound{lower, {"<=", v}}, nil
+		}
+		return []versionBound{lower, {"<", v.next(parts)}}, nil
+	}
+}
+
+func (c *VersionConstraint) String() string {
+	return c.text
+}
+
+// Allows reports whether version is within the range
+func (c *VersionConstraint) Allows(version string) (bool, error) {
+	v, err := parseVersion(version)
+	if err != nil {
+		return false, err
+	}
+	for _, bounds := range c.ranges {
+		allowed := true
+		for _, b := range bounds {
+			if !b.allows(v) {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// validateVersion checks expected_version and version_check
+func (p *PluginConfig) validateVersion() error {
+	switch p.VersionCheck {
+	case "", VersionCheckWarn, VersionCheckError:
+	default:
+		return fmt.Errorf("invalid version_check: %s (must be warn or error)", p.VersionCheck)
+	}
+	if p.ExpectedVersion == "" {
+		if p.VersionCheck != "" {
+			return fmt.Errorf("version_check requires expected_version")
+		}
+		return nil
+	}
+	if _, err := ParseVersionConstraint(p.ExpectedVersion); err != nil {
+		return fmt.Errorf("invalid expected_version: %v", err)
+	}
+	return nil
+}
+
+// VersionMismatchError reports a plugin whose version is outside its expected_version
+type VersionMismatchError struct {
+	Plugin   string
+	Version  string
+	Expected string
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("plugin %s reports version %q, outside expected_version %q; is the binary at its path stale?", e.Plugin, e.Version, e.Expected)
+}
+
+// checkVersion compares the plugin's version with its expected_version once its info is known:
+// a mismatch fails every execution, or is logged once with version_check warn
+func (c *GRPCClient) checkVersion(info *PluginInfo) error {
+	if c.expectedVersion == nil {
+		return nil
+	}
+	allowed, err := c.expectedVersion.Allows(info.Version)
+	if err == nil && allowed {
+		return nil
+	}
+	mismatch := &VersionMismatchError{Plugin: c.name, Version: info.Version, Expected: c.expectedVersion.String()}
+	if c.versionCheck == VersionCheckWarn {
+		c.versionWarned.Do(func() {
+			Logger(LogClient).Warn("plugin version outside expected_version", "plugin", c.name, "version", mismatch.Version, "expected", mismatch.Expected)
+		})
+		return nil
+	}
+	return mismatch
+}
+
+// verifyVersion compares the version of a plugin just connected to with its expected_version, so
+// a stale binary shows up as the plugin's last error before anything executes it
+func (pm *PluginManager) verifyVersion(m *ManagedPlugin, c *GRPCClient) {
+	if c.expectedVersion == nil {
+		return
+	}
+	info, err := c.GetInfo(pm.ctx)
+	if err != nil {
+		return // Executions report that themselves
+	}
+	if err := c.checkVersion(info); err != nil {
+		m.LastError = err
+	}
+}
@@ -0,0 +1,120 @@
+package shared
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVersionConstraint_Allows(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{">=1.2.0 <2.0.0", "1.2.0", true},
+		{">=1.2.0 <2.0.0", "1.9.9", true},
+		{">=1.2.0 <2.0.0", "2.0.0", false},
+		{">=1.2.0, <2.0.0", "1.1.9", false},
+		{"^1.4", "1.7.0", true},
+		{"^1.4", "2.0.0", false},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"~1.4.2", "1.4.9", true},
+		{"~1.4.2", "1.5.0", false},
+		{"1.x", "1.99.0", true},
+		{"1.x", "2.0.0", false},
+		{"1.2.3", "v1.2.3", true},
+		{"=1.2.3", "1.2.4", false},
+		{">1.2", "1.2.9", false},
+		{">1.2", "1.3.0", true},
+		{"<=1.2", "1.2.9", true},
+		{"1.2.3 || >=2.1", "2.0.0", false},
+		{"1.2.3 || >=2.1", "2.1.0", true},
+		{">=2.0.0", "2.0.0-rc.1", false},
+		{">=2.0.0-rc.2", "2.0.0-rc.10", true},
+		{"*", "0.0.1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint+" "+tt.version, func(t *testing.T) {
+			c, err := ParseVersionConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("ParseVersionConstraint() error = %v", err)
+			}
+			got, err := c.Allows(tt.version)
+			if err != nil {
+				t.Fatalf("Allows() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVersionConstraint_Invalid(t *testing.T) {
+	for _, constraint := range []string{"", ">=", "1.2.3.4", "^one", "1.2 ||", "1.2-rc.1"} {
+		if _, err := ParseVersionConstraint(constraint); err == nil {
+			t.Errorf("ParseVersionConstraint(%q) error = nil, want error", constraint)
+		}
+	}
+}
+
+func TestPluginConfig_validateVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  PluginConfig
+		wantErr string
+	}{
+		{name: "None"},
+		{name: "Valid", config: PluginConfig{ExpectedVersion: "^1.2", VersionCheck: VersionCheckWarn}},
+		{name: "Invalid range", config: PluginConfig{ExpectedVersion: ">=x.y"}, wantErr: "invalid expected_version"},
+		{name: "Unknown mode", config: PluginConfig{ExpectedVersion: "1.x", VersionCheck: "strict"}, wantErr: "invalid version_check"},
+		{name: "Mode without range", config: PluginConfig{VersionCheck: VersionCheckError}, wantErr: "requires expected_version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.validateVersion()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateVersion() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateVersion() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGRPCClient_checkVersion(t *testing.T) {
+	expected, err := ParseVersionConstraint("^1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := &PluginInfo{Name: "hello", Version: "1.1.0"}
+
+	c := &GRPCClient{name: "hello", expectedVersion: expected}
+	err = c.checkVersion(stale)
+	var mismatch *VersionMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Version != "1.1.0" || mismatch.Expected != "^1.2" {
+		t.Errorf("checkVersion() error = %v, want a version mismatch", err)
+	}
+	if err := c.checkVersion(&PluginInfo{Name: "hello", Version: "1.3.0"}); err != nil {
+		t.Errorf("checkVersion() error = %v for a version in range", err)
+	}
+	if err := c.checkVersion(&PluginInfo{Name: "hello", Version: "unknown"}); err == nil {
+		t.Error("checkVersion() error = nil for an unparsable version")
+	}
+
+	c = &GRPCClient{name: "hello", expectedVersion: expected, versionCheck: VersionCheckWarn}
+	if err := c.checkVersion(stale); err != nil {
+		t.Errorf("checkVersion() error = %v with version_check warn", err)
+	}
+	if err := (&GRPCClient{name: "hello"}).checkVersion(stale); err != nil {
+		t.Errorf("checkVersion() error = %v without expected_version", err)
+	}
+}
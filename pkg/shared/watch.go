@@ -0,0 +1,135 @@
+package shared
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// configSettleDelay lets a burst of writes to the config file finish before it is reloaded
+const configSettleDelay = 250 * time.Millisecond
+
+// ConfigWatcher reloads a manager's configuration files whenever one of them changes. Added
+// plugins become available, removed ones are drained and stopped, and running plugins whose
+// definition changed are restarted with it.
+type ConfigWatcher struct {
+	manager  *PluginManager
+	paths    []string // The base configuration and its overlays
+	onReload func(ConfigDiff)
+
+	mu      sync.Mutex
+	applied []byte // Content of the files last applied
+}
+
+// NewConfigWatcher returns a watcher for the configuration files at paths, a base configuration
+// and the overlays merged over it, calling onReload, if set, after each successful reload. Failed
+// reloads go to the manager's reload-failed handler.
+func (pm *PluginManager) NewConfigWatcher(paths []string, onReload func(ConfigDiff)) *ConfigWatcher {
+	w := &ConfigWatcher{manager: pm, paths: paths, onReload: onReload}
+	w.applied, _ = w.read()
+	return w
+}
+
+// Run watches the files until ctx is done. Their directories are watched rather than the files,
+// so editors and tools that replace a file are followed too.
+func (w *ConfigWatcher) Run(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch config: %v", err)
+	}
+	defer watcher.Close()
+	targets := make(map[string]bool)
+	for _, path := range w.paths {
+		// There is no file to watch for a remote configuration; Reload fetches it again
+		if IsRemoteConfig(path) {
+			continue
+		}
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			return fmt.Errorf("failed to watch config: %v", err)
+		}
+		targets[filepath.Clean(path)] = true
+	}
+
+	settle := time.NewTimer(configSettleDelay)
+	settle.Stop()
+	defer settle.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if targets[filepath.Clean(event.Name)] && !event.Has(fsnotify.Chmod) {
+				settle.Reset(configSettleDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.manager.notifyReloadFailed(&ReloadError{Err: fmt.Errorf("watching config: %v", err)})
+		case <-settle.C:
+			w.reload(false)
+		}
+	}
+}
+
+// read returns the content of the files, leaving out remote configurations, which are applied
+// whenever they are fetched
+func (w *ConfigWatcher) read() ([]byte, error) {
+	var content []byte
+	for _, path := range w.paths {
+		if IsRemoteConfig(path) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		content = append(append(content, data...), 0)
+	}
+	return content, nil
+}
+
+// Reload reloads the files now, even if they are unchanged, as on SIGHUP
+func (w *ConfigWatcher) Reload() (ConfigDiff, error) {
+	return w.reload(true)
+}
+
+// reload applies the files if they changed since they were last applied, or always when forced
+func (w *ConfigWatcher) reload(force bool) (ConfigDiff, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data, err := w.read()
+	if err != nil {
+		// A file may be in the middle of being replaced; its creation triggers another reload
+		if !force && os.IsNotExist(err) {
+			return ConfigDiff{}, nil
+		}
+		reloadErr := &ReloadError{Err: err}
+		w.manager.notifyReloadFailed(reloadErr)
+		return ConfigDiff{}, reloadErr
+	}
+	if !force && bytes.Equal(data, w.applied) {
+		return ConfigDiff{}, nil
+	}
+
+	old := w.manager.Config()
+	if err := w.manager.ReloadConfig(w.paths[0], w.paths[1:]...); err != nil {
+		return ConfigDiff{}, err
+	}
+	w.applied = data
+	diff := DiffConfigs(old, w.manager.Config())
+	if w.onReload != nil {
+		w.onReload(diff)
+	}
+	return diff, nil
+}
@@ -0,0 +1,97 @@
+package shared
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfigWatcher(t *testing.T) {
+	server, addr := startStubPluginServer(t)
+	defer server.Stop()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeConfig := func(plugins map[string]PluginConfig) {
+		t.Helper()
+		data, err := json.Marshal(AppConfig{Plugins: plugins})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Replace the file the way editors do
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := map[string]PluginConfig{"first": {Type: PluginTypeRemote, Address: addr}}
+	writeConfig(first)
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	pm := NewPluginManager(config)
+	defer pm.StopAll()
+	if err := pm.StartPlugin("first", config.Plugins["first"]); err != nil {
+		t.Fatalf("StartPlugin() error = %v", err)
+	}
+
+	reloads := make(chan ConfigDiff, 4)
+	watcher := pm.NewConfigWatcher([]string{path}, func(diff ConfigDiff) { reloads <- diff })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx)
+	// Give the watcher time to be set up
+	time.Sleep(100 * time.Millisecond)
+
+	writeConfig(map[string]PluginConfig{"second": {Type: PluginTypeRemote, Address: addr}})
+	select {
+	case diff := <-reloads:
+		want := ConfigDiff{Added: []string{"second"}, Removed: []string{"first"}}
+		if !reflect.DeepEqual(diff, want) {
+			t.Errorf("reload diff = %+v, want %+v", diff, want)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("config change was not applied")
+	}
+	if _, err := pm.GetPlugin("first"); err == nil {
+		t.Error("removed plugin is still running")
+	}
+	if _, ok := pm.Config().Plugins["second"]; !ok {
+		t.Error("added plugin is not configured")
+	}
+
+	// Rewriting the same content isn't a change
+	writeConfig(map[string]PluginConfig{"second": {Type: PluginTypeRemote, Address: addr}})
+	select {
+	case diff := <-reloads:
+		t.Errorf("unchanged config was reloaded: %+v", diff)
+	case <-time.After(2 * configSettleDelay):
+	}
+
+	// An explicit reload, as on SIGHUP, applies even so
+	if diff, err := watcher.Reload(); err != nil || !diff.Empty() {
+		t.Errorf("Reload() = %+v, %v; want an empty diff", diff, err)
+	}
+	<-reloads
+
+	// An invalid file is reported and the configuration kept
+	reported := make(chan *ReloadError, 4)
+	pm.SetReloadFailedHandler(func(err *ReloadError) { reported <- err })
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := watcher.Reload(); err == nil || len(reported) == 0 {
+		t.Errorf("Reload() of invalid config error = %v, reported %d; want both", err, len(reported))
+	}
+	if _, ok := pm.Config().Plugins["second"]; !ok {
+		t.Error("configuration was lost after an invalid reload")
+	}
+}
@@ -0,0 +1,226 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// pluginDumpTimeout bounds how long the watchdog waits for a hung plugin's own dumps
+const pluginDumpTimeout = 5 * time.Second
+
+// WatchdogConfig cancels executions that run too long or stop producing output, collecting
+// diagnostic dumps first
+type WatchdogConfig struct {
+	MaxDuration  Duration `json:"max_duration,omitempty"`  // Hard wall-clock limit on one execution
+	StallTimeout Duration `json:"stall_timeout,omitempty"` // Longest silence on the output stream before the execution counts as stalled
+	PluginDump   bool     `json:"plugin_dump,omitempty"`   // Also request the plugin's own dumps before cancelling
+}
+
+// validate checks the watchdog settings
+func (w *WatchdogConfig) validate() error {
+	if w.MaxDuration < 0 {
+		return fmt.Errorf("invalid watchdog max_duration: %s", w.MaxDuration)
+	}
+	if w.StallTimeout < 0 {
+		return fmt.Errorf("invalid watchdog stall_timeout: %s", w.StallTimeout)
+	}
+	if w.MaxDuration == 0 && w.StallTimeout == 0 {
+		return fmt.Errorf("watchdog requires max_duration or stall_timeout")
+	}
+	return nil
+}
+
+// DumpPath returns the directory diagnostic dumps are written to
+func (c *AppConfig) DumpPath() string {
+	return filepath.Join(c.StateDir, "dumps")
+}
+
+// HangError reports an execution the watchdog cancelled
+type HangError struct {
+	Plugin  string
+	Reason  string
+	DumpDir string // Where the diagnostic dumps were written, empty if writing them failed
+	DumpErr error  // Why some dumps are missing
+}
+
+func (e *HangError) Error() string {
+	msg := fmt.Sprintf("plugin %s execution cancelled by watchdog: %s", e.Plugin, e.Reason)
+	if e.DumpDir != "" {
+		msg += fmt.Sprintf(" (diagnostics in %s)", e.DumpDir)
+	}
+	if e.DumpErr != nil {
+		msg += fmt.Sprintf(" (incomplete diagnostics: %v)", e.DumpErr)
+	}
+	return msg
+}
+
+// watchdog guards one execution
+type watchdog struct {
+	config  *WatchdogConfig
+	dumpDir string
+	client  *GRPCClient
+	cancel  context.CancelFunc
+	touch   chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+
+	mu   sync.Mutex
+	hang *HangError
+}
+
+// startWatchdog returns a context the watchdog cancels when the execution hangs
+func (c *GRPCClient) startWatchdog(ctx context.Context) (context.Context, *watchdog) {
+	ctx, cancel := context.WithCancel(ctx)
+	w := &watchdog{
+		config:  c.watchdog,
+		dumpDir: c.dumpDir,
+		client:  c,
+		cancel:  cancel,
+		touch:   make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return ctx, w
+}
+
+func (w *watchdog) run() {
+	defer close(w.done)
+
+	var deadline, stall <-chan time.Time
+	if w.config.MaxDuration > 0 {
+		timer := time.NewTimer(time.Duration(w.config.MaxDuration))
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	var stallTimer *time.Timer
+	if w.config.StallTimeout > 0 {
+		stallTimer = time.NewTimer(time.Duration(w.config.StallTimeout))
+		defer stallTimer.Stop()
+		stall = stallTimer.C
+	}
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.touch:
+			if stallTimer != nil {
+				stallTimer.Reset(time.Duration(w.config.StallTimeout))
+			}
+		case <-deadline:
+			w.fire(fmt.Sprintf("exceeded max_duration of %s", w.config.MaxDuration))
+			return
+		case <-stall:
+			w.fire(fmt.Sprintf("no output for %s", w.config.StallTimeout))
+			return
+		}
+	}
+}
+
+// fire collects the dumps and cancels the execution
+func (w *watchdog) fire(reason string) {
+	hang := &HangError{Plugin: w.client.name, Reason: reason}
+	dumps := make(map[string]string)
+	for name, text := range ProcessDumps() {
+		dumps["host-"+name] = text
+	}
+	if w.config.PluginDump {
+		ctx, cancel := context.WithTimeout(context.Background(), pluginDumpTimeout)
+		pluginDumps, err := w.client.Diagnostics(ctx, reason)
+		cancel()
+		if err != nil {
+			hang.DumpErr = fmt.Errorf("plugin dump failed: %v", err)
+		}
+		for name, text := range pluginDumps {
+			dumps["plugin-"+filepath.Base(name)] = text
+		}
+	}
+
+	dir := filepath.Join(w.dumpDir, fmt.Sprintf("%s-%s", w.client.name, time.Now().Format("20060102-150405")))
+	if err := writeDumps(dir, dumps); err != nil {
+		hang.DumpErr = err
+	} else {
+		hang.DumpDir = dir
+	}
+
+	w.mu.Lock()
+	w.hang = hang
+	w.mu.Unlock()
+	w.cancel()
+}
+
+// writeDumps writes each dump to <dir>/<name>.txt
+func writeDumps(dir string, dumps map[string]string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create dump directory: %v", err)
+	}
+	for name, text := range dumps {
+		if err := os.WriteFile(filepath.Join(dir, name+".txt"), []byte(text), 0600); err != nil {
+			return fmt.Errorf("failed to write %s dump: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// finish stops the watchdog and returns the hang it detected, if any
+func (w *watchdog) finish() *HangError {
+	close(w.stop)
+	<-w.done
+	w.cancel()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.hang
+}
+
+// watchedHandler tells the watchdog about every message on the output stream
+type watchedHandler struct {
+	OutputHandler
+	watchdog *watchdog
+}
+
+func (h watchedHandler) alive() {
+	select {
+	case h.watchdog.touch <- struct{}{}:
+	default:
+	}
+}
+
+func (h watchedHandler) OnOutput(msg string) error {
+	h.alive()
+	return h.OutputHandler.OnOutput(msg)
+}
+
+func (h watchedHandler) OnProgress(p Progress) error {
+	h.alive()
+	return h.OutputHandler.OnProgress(p)
+}
+
+func (h watchedHandler) OnResult(result map[string]interface{}) error {
+	h.alive()
+	return h.OutputHandler.OnResult(result)
+}
+
+func (h watchedHandler) OnLog(level, message string) error {
+	h.alive()
+	return asChannelHandler(h.OutputHandler).OnLog(level, message)
+}
+
+func (h watchedHandler) OnArtifact(name string, data []byte, last bool) error {
+	h.alive()
+	return asChannelHandler(h.OutputHandler).OnArtifact(name, data, last)
+}
+
+func (h watchedHandler) OnPrompt(id, message string) error {
+	h.alive()
+	return asChannelHandler(h.OutputHandler).OnPrompt(id, message)
+}
+
+func (h watchedHandler) OnMetric(name string, value float64, labels map[string]string) error {
+	h.alive()
+	return asChannelHandler(h.OutputHandler).OnMetric(name, value, labels)
+}
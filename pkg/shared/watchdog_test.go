@@ -0,0 +1,139 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/grpc"
+)
+
+// tickingPlugin sends output every interval until the call is cancelled; a zero interval sends nothing
+type tickingPlugin struct {
+	stubPlugin
+	interval time.Duration
+}
+
+func (p tickingPlugin) Execute(ctx context.Context, params map[string]string, output OutputHandler) error {
+	if p.interval == 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := output.OnOutput("tick"); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// discardHandler ignores everything an execution sends
+type discardHandler struct{}
+
+func (discardHandler) OnOutput(msg string) error                    { return nil }
+func (discardHandler) OnProgress(p Progress) error                  { return nil }
+func (discardHandler) OnResult(result map[string]interface{}) error { return nil }
+func (discardHandler) OnError(code, message, details string) error  { return nil }
+
+func TestWatchdogConfig_validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  WatchdogConfig
+		wantErr bool
+	}{
+		{name: "Max duration", config: WatchdogConfig{MaxDuration: Duration(time.Minute)}},
+		{name: "Stall timeout", config: WatchdogConfig{StallTimeout: Duration(time.Minute), PluginDump: true}},
+		{name: "Nothing to watch", config: WatchdogConfig{PluginDump: true}, wantErr: true},
+		{name: "Negative", config: WatchdogConfig{MaxDuration: Duration(-time.Second)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGRPCClient_ExecuteWatchdog(t *testing.T) {
+	tests := []struct {
+		name        string
+		interval    time.Duration
+		watchdog    WatchdogConfig
+		wantReason  string
+		wantDumps   []string
+		wantNoDumps []string
+	}{
+		{
+			name:        "Stalled stream",
+			watchdog:    WatchdogConfig{StallTimeout: Duration(200 * time.Millisecond)},
+			wantReason:  "no output for 200ms",
+			wantDumps:   []string{"host-goroutine.txt", "host-heap.txt"},
+			wantNoDumps: []string{"plugin-goroutine.txt"},
+		},
+		{
+			name:       "Wall-clock limit despite output",
+			interval:   50 * time.Millisecond,
+			watchdog:   WatchdogConfig{MaxDuration: Duration(300 * time.Millisecond), StallTimeout: Duration(200 * time.Millisecond), PluginDump: true},
+			wantReason: "exceeded max_duration of 300ms",
+			wantDumps:  []string{"host-goroutine.txt", "plugin-goroutine.txt", "plugin-heap.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("Failed to listen: %v", err)
+			}
+			server := grpc.NewServer()
+			proto.RegisterPluginServer(server, &GRPCServer{Impl: tickingPlugin{interval: tt.interval}})
+			go server.Serve(listener)
+			defer server.Stop()
+
+			client, err := NewClientWithAddress(listener.Addr().String())
+			if err != nil {
+				t.Fatalf("NewClientWithAddress() error = %v", err)
+			}
+			defer client.Close()
+			grpcClient := client.(*GRPCClient)
+			grpcClient.name = "ticker"
+			grpcClient.watchdog = &tt.watchdog
+			grpcClient.dumpDir = t.TempDir()
+
+			err = client.Execute(context.Background(), nil, discardHandler{})
+			var hang *HangError
+			if !errors.As(err, &hang) {
+				t.Fatalf("Execute() error = %v, want *HangError", err)
+			}
+			if hang.Reason != tt.wantReason {
+				t.Errorf("Reason = %q, want %q", hang.Reason, tt.wantReason)
+			}
+			if hang.DumpDir == "" || !strings.Contains(err.Error(), hang.DumpDir) {
+				t.Fatalf("error %q does not name the dump directory", err)
+			}
+			for _, name := range tt.wantDumps {
+				if _, err := os.Stat(filepath.Join(hang.DumpDir, name)); err != nil {
+					t.Errorf("missing dump %s: %v", name, err)
+				}
+			}
+			for _, name := range tt.wantNoDumps {
+				if _, err := os.Stat(filepath.Join(hang.DumpDir, name)); err == nil {
+					t.Errorf("unexpected dump %s", name)
+				}
+			}
+		})
+	}
+}
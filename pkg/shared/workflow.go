@@ -0,0 +1,125 @@
+package shared
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Workflow is a pipeline of plugin executions, run one step after another
+type Workflow struct {
+	Name  string         `json:"name,omitempty"`
+	Steps []WorkflowStep `json:"steps"`
+}
+
+// WorkflowStep is one plugin execution of a workflow
+type WorkflowStep struct {
+	Name              string            `json:"name,omitempty"` // Unique within the workflow, defaults to the plugin name
+	Plugin            string            `json:"plugin"`
+	Params            map[string]string `json:"params,omitempty"`
+	From              map[string]string `json:"from,omitempty"`                // Parameters taken from the result of an earlier step, as param: "step.field"
+	Timeout           Duration          `json:"timeout,omitempty"`             // Deadline of the step, none if zero
+	ContinueOnFailure bool              `json:"continue_on_failure,omitempty"` // Go on with the next steps if this one fails
+}
+
+// LoadWorkflow reads a workflow file and checks it against the configuration
+func LoadWorkflow(path string, config *AppConfig) (*Workflow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow file: %v", err)
+	}
+	var workflow Workflow
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&workflow); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow file: %v", err)
+	}
+	if err := workflow.Validate(config); err != nil {
+		return nil, fmt.Errorf("invalid workflow %s: %v", path, err)
+	}
+	return &workflow, nil
+}
+
+// Validate fills in step names and checks that every step runs a configured plugin and only
+// takes parameters from steps before it
+func (w *Workflow) Validate(config *AppConfig) error {
+	if len(w.Steps) == 0 {
+		return fmt.Errorf("no steps")
+	}
+	seen := make(map[string]bool, len(w.Steps))
+	for i := range w.Steps {
+		step := &w.Steps[i]
+		if step.Plugin == "" {
+			return fmt.Errorf("step %d: plugin is required", i+1)
+		}
+		if _, ok := config.Plugins[step.Plugin]; !ok {
+			return fmt.Errorf("step %d: plugin %q not found in configuration", i+1, step.Plugin)
+		}
+		if step.Name == "" {
+			step.Name = step.Plugin
+		}
+		if strings.Contains(step.Name, ".") {
+			return fmt.Errorf("step %d: name %q must not contain a dot", i+1, step.Name)
+		}
+		if seen[step.Name] {
+			return fmt.Errorf("step %d: name %q is used twice; give the steps names of their own", i+1, step.Name)
+		}
+		if step.Timeout < 0 {
+			return fmt.Errorf("step %s: invalid timeout %s", step.Name, step.Timeout)
+		}
+		for param, source := range step.From {
+			from, field, ok := strings.Cut(source, ".")
+			if !ok || field == "" {
+				return fmt.Errorf("step %s: from %s: expected <step>.<result-field>, got %q", step.Name, param, source)
+			}
+			if !seen[from] {
+				return fmt.Errorf("step %s: from %s: %q is not an earlier step", step.Name, param, from)
+			}
+		}
+		seen[step.Name] = true
+	}
+	return nil
+}
+
+// StepParams returns the parameters of a step, with those taken from earlier steps filled in
+// from their results. Parameters set explicitly win.
+func (s *WorkflowStep) StepParams(results map[string]map[string]interface{}) (map[string]string, error) {
+	params := make(map[string]string, len(s.Params)+len(s.From))
+	for name, value := range s.Params {
+		params[name] = value
+	}
+	for param, source := range s.From {
+		if _, exists := params[param]; exists {
+			continue
+		}
+		from, field, _ := strings.Cut(source, ".")
+		result, ok := results[from]
+		if !ok {
+			return nil, fmt.Errorf("step %s produced no result for %s", from, param)
+		}
+		value, ok := result[field]
+		if !ok {
+			return nil, fmt.Errorf("result of step %s has no field %q", from, field)
+		}
+		params[param] = ResultValueString(value)
+	}
+	return params, nil
+}
+
+// ResultValueString renders a decoded result value as a parameter string
+func ResultValueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
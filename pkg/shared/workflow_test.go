@@ -0,0 +1,150 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWorkflow_Validate(t *testing.T) {
+	config := &AppConfig{Plugins: map[string]PluginConfig{"hello": {}, "addition": {}}}
+
+	tests := []struct {
+		name     string
+		steps    []WorkflowStep
+		wantErr  bool
+		errorMsg string
+	}{
+		{
+			name: "Valid workflow",
+			steps: []WorkflowStep{
+				{Plugin: "addition", Params: map[string]string{"num1": "1", "num2": "2"}},
+				{Name: "again", Plugin: "addition", From: map[string]string{"num1": "addition.sum"}},
+			},
+		},
+		{
+			name:     "No steps",
+			wantErr:  true,
+			errorMsg: "no steps",
+		},
+		{
+			name:     "Unknown plugin",
+			steps:    []WorkflowStep{{Plugin: "nope"}},
+			wantErr:  true,
+			errorMsg: `step 1: plugin "nope" not found`,
+		},
+		{
+			name:     "Duplicate step names",
+			steps:    []WorkflowStep{{Plugin: "hello"}, {Plugin: "hello"}},
+			wantErr:  true,
+			errorMsg: `step 2: name "hello" is used twice`,
+		},
+		{
+			name:     "From a later step",
+			steps:    []WorkflowStep{{Plugin: "hello", From: map[string]string{"name": "addition.sum"}}, {Plugin: "addition"}},
+			wantErr:  true,
+			errorMsg: `"addition" is not an earlier step`,
+		},
+		{
+			name:     "From without a field",
+			steps:    []WorkflowStep{{Plugin: "hello"}, {Plugin: "addition", From: map[string]string{"num1": "hello"}}},
+			wantErr:  true,
+			errorMsg: "expected <step>.<result-field>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workflow := &Workflow{Steps: tt.steps}
+			err := workflow.Validate(config)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Validate() error = %v, want %q", err, tt.errorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if workflow.Steps[0].Name != "addition" {
+				t.Errorf("step name = %q, want the plugin name", workflow.Steps[0].Name)
+			}
+		})
+	}
+}
+
+func TestLoadWorkflow(t *testing.T) {
+	config := &AppConfig{Plugins: map[string]PluginConfig{"hello": {}}}
+	path := filepath.Join(t.TempDir(), "workflow.json")
+
+	os.WriteFile(path, []byte(`{"steps": [{"plugin": "hello", "timeout": "5s", "continue_on_failure": true}]}`), 0644)
+	workflow, err := LoadWorkflow(path, config)
+	if err != nil {
+		t.Fatalf("LoadWorkflow() error = %v", err)
+	}
+	if step := workflow.Steps[0]; step.Name != "hello" || step.Timeout.String() != "5s" || !step.ContinueOnFailure {
+		t.Errorf("LoadWorkflow() step = %+v", step)
+	}
+
+	os.WriteFile(path, []byte(`{"steps": [{"plugin": "hello", "parmas": {}}]}`), 0644)
+	if _, err := LoadWorkflow(path, config); err == nil || !strings.Contains(err.Error(), "parmas") {
+		t.Errorf("LoadWorkflow() with a misspelled setting error = %v", err)
+	}
+}
+
+func TestWorkflowStep_StepParams(t *testing.T) {
+	results := map[string]map[string]interface{}{
+		"sum": {"sum": 3.0, "count": 2.0, "parts": []interface{}{1.0, 2.0}},
+	}
+	tests := []struct {
+		name     string
+		step     WorkflowStep
+		want     map[string]string
+		wantErr  bool
+		errorMsg string
+	}{
+		{
+			name: "From results",
+			step: WorkflowStep{
+				Params: map[string]string{"num2": "4"},
+				From:   map[string]string{"num1": "sum.sum", "parts": "sum.parts"},
+			},
+			want: map[string]string{"num1": "3", "num2": "4", "parts": "[1,2]"},
+		},
+		{
+			name: "Explicit parameters win",
+			step: WorkflowStep{Params: map[string]string{"num1": "7"}, From: map[string]string{"num1": "sum.sum"}},
+			want: map[string]string{"num1": "7"},
+		},
+		{
+			name:     "Missing field",
+			step:     WorkflowStep{From: map[string]string{"num1": "sum.total"}},
+			wantErr:  true,
+			errorMsg: `result of step sum has no field "total"`,
+		},
+		{
+			name:     "Step without a result",
+			step:     WorkflowStep{From: map[string]string{"num1": "failed.sum"}},
+			wantErr:  true,
+			errorMsg: "step failed produced no result",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.step.StepParams(results)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("StepParams() error = %v, want %q", err, tt.errorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("StepParams() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("StepParams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
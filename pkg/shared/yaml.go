@@ -0,0 +1,176 @@
+package shared
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// MarshalYAML returns v as a YAML document in block style. v is encoded as by encoding/json, so
+// json tags and marshalers apply and struct fields keep their order.
+func MarshalYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	node, err := decodeYAMLNode(dec)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writeYAMLNode(&buf, node, 0)
+	return buf.Bytes(), nil
+}
+
+// yamlEntry is a key of a JSON object with its value, kept in order
+type yamlEntry struct {
+	key   string
+	value interface{}
+}
+
+// decodeYAMLNode decodes the next JSON value into a string, json.Number, bool, nil,
+// []interface{} or []yamlEntry for an object
+func decodeYAMLNode(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+	switch delim {
+	case '{':
+		entries := []yamlEntry{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeYAMLNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, yamlEntry{key.(string), value})
+		}
+		_, err = dec.Token()
+		return entries, err
+	case '[':
+		items := []interface{}{}
+		for dec.More() {
+			item, err := decodeYAMLNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		_, err = dec.Token()
+		return items, err
+	}
+	return nil, errors.New("unexpected " + delim.String())
+}
+
+func writeYAMLNode(buf *bytes.Buffer, node interface{}, indent int) {
+	pad := strings.Repeat(" ", indent)
+	switch node := node.(type) {
+	case []yamlEntry:
+		if len(node) == 0 {
+			buf.WriteString(pad + "{}\n")
+			return
+		}
+		for _, entry := range node {
+			buf.WriteString(pad + yamlScalar(entry.key) + ":")
+			if yamlBlock(entry.value) {
+				buf.WriteString("\n")
+				writeYAMLNode(buf, entry.value, indent+2)
+				continue
+			}
+			buf.WriteString(" ")
+			writeYAMLNode(buf, entry.value, 0)
+		}
+	case []interface{}:
+		if len(node) == 0 {
+			buf.WriteString(pad + "[]\n")
+			return
+		}
+		for _, item := range node {
+			if !yamlBlock(item) {
+				buf.WriteString(pad + "- ")
+				writeYAMLNode(buf, item, 0)
+				continue
+			}
+			// The item's first line starts after the dash, the others are indented to match it
+			var nested bytes.Buffer
+			writeYAMLNode(&nested, item, indent+2)
+			buf.WriteString(pad + "- ")
+			buf.Write(nested.Bytes()[indent+2:])
+		}
+	default:
+		buf.WriteString(pad + yamlScalar(node) + "\n")
+	}
+}
+
+// yamlBlock reports whether a node is written on lines of its own
+func yamlBlock(node interface{}) bool {
+	switch node := node.(type) {
+	case []yamlEntry:
+		return len(node) > 0
+	case []interface{}:
+		return len(node) > 0
+	}
+	return false
+}
+
+func yamlScalar(node interface{}) string {
+	switch node := node.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(node)
+	case json.Number:
+		return node.String()
+	case string:
+		if plainYAML(node) {
+			return node
+		}
+		// JSON strings are valid double-quoted YAML scalars
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.Encode(node)
+		return strings.TrimSuffix(buf.String(), "\n")
+	case []yamlEntry:
+		return "{}"
+	case []interface{}:
+		return "[]"
+	}
+	return ""
+}
+
+// plainYAML reports whether a string can be written unquoted without being read back as
+// something else, such as a number, a boolean or an indicator
+func plainYAML(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return false
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "y", "n", "on", "off", "null", "~", ".inf", "-.inf", "+.inf", ".nan":
+		return false
+	}
+	if strings.ContainsAny(s[:1], "0123456789-+.?:,[]{}#&*!|>'\"%@`") {
+		return false
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
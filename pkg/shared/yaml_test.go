@@ -0,0 +1,71 @@
+package shared
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMarshalYAML(t *testing.T) {
+	type param struct {
+		Name     string `json:"name"`
+		Required bool   `json:"required"`
+	}
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{
+			name:  "Scalar",
+			value: "hello",
+			want:  "hello\n",
+		},
+		{
+			name: "Struct fields in order",
+			value: struct {
+				Name     string   `json:"name"`
+				Version  string   `json:"version"`
+				Duration Duration `json:"duration"`
+				Rate     float64  `json:"rate"`
+				Error    *string  `json:"error"`
+			}{"hello", "1.0.0", Duration(1500 * time.Millisecond), 0.5, nil},
+			want: "name: hello\nversion: \"1.0.0\"\nduration: \"1.5s\"\nrate: 0.5\nerror: null\n",
+		},
+		{
+			name: "Nested",
+			value: map[string]interface{}{
+				"params": []param{{"name", true}, {"greeting", false}},
+				"labels": map[string]string{"team": "core"},
+				"tags":   []string{"a", "b"},
+				"empty":  []string{},
+				"none":   map[string]string{},
+			},
+			want: "empty: []\n" +
+				"labels:\n  team: core\n" +
+				"none: {}\n" +
+				"params:\n  - name: name\n    required: true\n  - name: greeting\n    required: false\n" +
+				"tags:\n  - a\n  - b\n",
+		},
+		{
+			name:  "Nested lists",
+			value: [][]int{{1, 2}, {}},
+			want:  "- - 1\n  - 2\n- []\n",
+		},
+		{
+			name:  "Quoted strings",
+			value: []string{"", "true", "No", "42", " padded", "a: b", "x #y", "line\nbreak", "-flag", "<tag>", "plain text"},
+			want:  "- \"\"\n- \"true\"\n- \"No\"\n- \"42\"\n- \" padded\"\n- \"a: b\"\n- \"x #y\"\n- \"line\\nbreak\"\n- \"-flag\"\n- <tag>\n- plain text\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MarshalYAML(tt.value)
+			if err != nil {
+				t.Fatalf("MarshalYAML() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("MarshalYAML() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
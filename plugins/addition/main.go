@@ -6,12 +6,13 @@ import (
 	"fmt"
 	"log"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/example/grpc-plugin-app/pkg/common"
+	"github.com/example/grpc-plugin-app/pkg/shared"
 	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 const (
@@ -61,24 +62,41 @@ func (p *AdditionPlugin) GetInfo(ctx context.Context, req *proto.InfoRequest) (*
 				Type:        "float",
 			},
 		},
+		ResultSchema: map[string]*proto.ResultFieldSpec{
+			"sum": {
+				Name:        "sum",
+				Description: "Sum of all provided numbers",
+				Required:    true,
+				Type:        "number",
+			},
+			"count": {
+				Name:        "count",
+				Description: "How many numbers were added",
+				Required:    true,
+				Type:        "number",
+			},
+		},
+		ProtocolVersion: common.ProtocolVersion,
+		Features:        []string{shared.FeatureTypedParams},
+		SideEffectFree:  true,
 	}, nil
 }
 
 // validateParameters validates the input parameters
-func (p *AdditionPlugin) validateParameters(params map[string]string) error {
+func (p *AdditionPlugin) validateParameters(params *common.Params) error {
 	// Check for required parameters
-	if _, ok := params["num1"]; !ok {
+	if !params.Has("num1") {
 		return fmt.Errorf("missing required parameter: num1")
 	}
-	if _, ok := params["num2"]; !ok {
+	if !params.Has("num2") {
 		return fmt.Errorf("missing required parameter: num2")
 	}
 
 	// Validate all number parameters
-	for key, value := range params {
+	for _, key := range params.Names() {
 		if strings.HasPrefix(key, "num") {
-			if _, err := strconv.ParseFloat(value, 64); err != nil {
-				return fmt.Errorf("invalid number for %s: %v", key, err)
+			if _, err := params.Float(key); err != nil {
+				return err
 			}
 		}
 	}
@@ -88,8 +106,10 @@ func (p *AdditionPlugin) validateParameters(params map[string]string) error {
 
 // Execute implements the Execute RPC method
 func (p *AdditionPlugin) Execute(req *proto.ExecuteRequest, stream proto.Plugin_ExecuteServer) error {
+	params := common.NewParams(req)
+
 	// Validate parameters
-	if err := p.validateParameters(req.Params); err != nil {
+	if err := p.validateParameters(params); err != nil {
 		return stream.Send(&proto.ExecuteOutput{
 			Content: &proto.ExecuteOutput_Error{
 				Error: &proto.Error{
@@ -112,22 +132,14 @@ func (p *AdditionPlugin) Execute(req *proto.ExecuteRequest, stream proto.Plugin_
 		return err
 	}
 
-	if err := stream.Send(&proto.ExecuteOutput{
-		Content: &proto.ExecuteOutput_Progress{
-			Progress: &proto.Progress{
-				Stage:           "Initialization",
-				PercentComplete: 0,
-				CurrentStep:     1,
-				TotalSteps:      4,
-			},
-		},
-	}); err != nil {
+	progress := common.NewProgress(stream, "Initialization", "Processing Input", "Calculating", "Finalizing")
+	if err := progress.Start(); err != nil {
 		return err
 	}
 	time.Sleep(500 * time.Millisecond)
 
 	// Find all number parameters (num1, num2, num3, etc.)
-	for key := range req.Params {
+	for _, key := range params.Names() {
 		if strings.HasPrefix(key, "num") {
 			keys = append(keys, key)
 		}
@@ -147,16 +159,9 @@ func (p *AdditionPlugin) Execute(req *proto.ExecuteRequest, stream proto.Plugin_
 		})
 	}
 
-	if err := stream.Send(&proto.ExecuteOutput{
-		Content: &proto.ExecuteOutput_Progress{
-			Progress: &proto.Progress{
-				Stage:           "Processing Input",
-				PercentComplete: 25,
-				CurrentStep:     2,
-				TotalSteps:      4,
-			},
-		},
-	}); err != nil {
+	// Each number is converted and then added
+	progress.SetTotal(int64(2 * len(keys)))
+	if err := progress.Stage("Processing Input"); err != nil {
 		return err
 	}
 
@@ -174,8 +179,7 @@ func (p *AdditionPlugin) Execute(req *proto.ExecuteRequest, stream proto.Plugin_
 				},
 			})
 		default:
-			numStr := req.Params[key]
-			num, err := strconv.ParseFloat(numStr, 64)
+			num, err := params.Float(key)
 			if err != nil {
 				return stream.Send(&proto.ExecuteOutput{
 					Content: &proto.ExecuteOutput_Error{
@@ -195,6 +199,9 @@ func (p *AdditionPlugin) Execute(req *proto.ExecuteRequest, stream proto.Plugin_
 			}); err != nil {
 				return err
 			}
+			if err := progress.Add(1); err != nil {
+				return err
+			}
 			time.Sleep(300 * time.Millisecond)
 		}
 	}
@@ -207,16 +214,7 @@ func (p *AdditionPlugin) Execute(req *proto.ExecuteRequest, stream proto.Plugin_
 		return err
 	}
 
-	if err := stream.Send(&proto.ExecuteOutput{
-		Content: &proto.ExecuteOutput_Progress{
-			Progress: &proto.Progress{
-				Stage:           "Calculating",
-				PercentComplete: 50,
-				CurrentStep:     3,
-				TotalSteps:      4,
-			},
-		},
-	}); err != nil {
+	if err := progress.Stage("Calculating"); err != nil {
 		return err
 	}
 	time.Sleep(500 * time.Millisecond)
@@ -245,21 +243,11 @@ func (p *AdditionPlugin) Execute(req *proto.ExecuteRequest, stream proto.Plugin_
 				}); err != nil {
 					return err
 				}
-
-				if err := stream.Send(&proto.ExecuteOutput{
-					Content: &proto.ExecuteOutput_Progress{
-						Progress: &proto.Progress{
-							Stage:           "Calculating",
-							PercentComplete: 50 + float32(i)*25/float32(len(numbers)-1),
-							CurrentStep:     3,
-							TotalSteps:      4,
-						},
-					},
-				}); err != nil {
-					return err
-				}
 				time.Sleep(300 * time.Millisecond)
 			}
+			if err := progress.Add(1); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -269,22 +257,29 @@ func (p *AdditionPlugin) Execute(req *proto.ExecuteRequest, stream proto.Plugin_
 		expression = append(expression, fmt.Sprintf("%.2f", num))
 	}
 
+	if err := progress.Done(); err != nil {
+		return err
+	}
+
 	if err := stream.Send(&proto.ExecuteOutput{
-		Content: &proto.ExecuteOutput_Progress{
-			Progress: &proto.Progress{
-				Stage:           "Finalizing",
-				PercentComplete: 100,
-				CurrentStep:     4,
-				TotalSteps:      4,
-			},
+		Content: &proto.ExecuteOutput_Output{
+			Output: fmt.Sprintf("\nFinal result: %s = %.2f", strings.Join(expression, " + "), sum),
 		},
 	}); err != nil {
 		return err
 	}
 
+	// Send the structured result
+	result, err := structpb.NewStruct(map[string]interface{}{
+		"sum":   sum,
+		"count": len(numbers),
+	})
+	if err != nil {
+		return err
+	}
 	if err := stream.Send(&proto.ExecuteOutput{
-		Content: &proto.ExecuteOutput_Output{
-			Output: fmt.Sprintf("\nFinal result: %s = %.2f", strings.Join(expression, " + "), sum),
+		Content: &proto.ExecuteOutput_Result{
+			Result: result,
 		},
 	}); err != nil {
 		return err
@@ -9,6 +9,7 @@ import (
 
 	"github.com/example/grpc-plugin-app/pkg/common"
 	"github.com/example/grpc-plugin-app/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 const (
@@ -43,6 +44,22 @@ func (p *HelloPlugin) GetInfo(ctx context.Context, req *proto.InfoRequest) (*pro
 				AllowedValues: []string{"en", "es", "fr", "de"},
 			},
 		},
+		ResultSchema: map[string]*proto.ResultFieldSpec{
+			"greeting": {
+				Name:        "greeting",
+				Description: "The greeting in the requested language",
+				Required:    true,
+				Type:        "string",
+			},
+			"language": {
+				Name:        "language",
+				Description: "The language used for the greeting",
+				Required:    true,
+				Type:        "string",
+			},
+		},
+		ProtocolVersion: common.ProtocolVersion,
+		SideEffectFree:  true,
 	}, nil
 }
 
@@ -89,17 +106,10 @@ func (p *HelloPlugin) Execute(req *proto.ExecuteRequest, stream proto.Plugin_Exe
 		language = "en"
 	}
 
-	// Report initial progress
-	if err := stream.Send(&proto.ExecuteOutput{
-		Content: &proto.ExecuteOutput_Progress{
-			Progress: &proto.Progress{
-				Stage:           "Starting",
-				PercentComplete: 0,
-				CurrentStep:     1,
-				TotalSteps:      4,
-			},
-		},
-	}); err != nil {
+	// Each dot is a unit of work
+	progress := common.NewProgress(stream, "Starting", "Processing", "Finalizing")
+	progress.SetTotal(3)
+	if err := progress.Start(); err != nil {
 		return err
 	}
 
@@ -113,22 +123,11 @@ func (p *HelloPlugin) Execute(req *proto.ExecuteRequest, stream proto.Plugin_Exe
 	}
 	time.Sleep(time.Second)
 
-	// Report progress before dots
-	if err := stream.Send(&proto.ExecuteOutput{
-		Content: &proto.ExecuteOutput_Progress{
-			Progress: &proto.Progress{
-				Stage:           "Processing",
-				PercentComplete: 25,
-				CurrentStep:     2,
-				TotalSteps:      4,
-			},
-		},
-	}); err != nil {
+	if err := progress.Stage("Processing"); err != nil {
 		return err
 	}
 
 	// Send some dots to show progress
-	dots := 0
 	for i := 0; i < 3; i++ {
 		select {
 		case <-stream.Context().Done():
@@ -150,19 +149,7 @@ func (p *HelloPlugin) Execute(req *proto.ExecuteRequest, stream proto.Plugin_Exe
 			}); err != nil {
 				return err
 			}
-			dots++
-
-			// Update progress during dots
-			if err := stream.Send(&proto.ExecuteOutput{
-				Content: &proto.ExecuteOutput_Progress{
-					Progress: &proto.Progress{
-						Stage:           "Processing",
-						PercentComplete: 25 + float32(i+1)*25,
-						CurrentStep:     int32(2 + i),
-						TotalSteps:      4,
-					},
-				},
-			}); err != nil {
+			if err := progress.Add(1); err != nil {
 				return err
 			}
 
@@ -184,16 +171,7 @@ func (p *HelloPlugin) Execute(req *proto.ExecuteRequest, stream proto.Plugin_Exe
 	}
 
 	// Send final progress
-	if err := stream.Send(&proto.ExecuteOutput{
-		Content: &proto.ExecuteOutput_Progress{
-			Progress: &proto.Progress{
-				Stage:           "Finalizing",
-				PercentComplete: 100,
-				CurrentStep:     4,
-				TotalSteps:      4,
-			},
-		},
-	}); err != nil {
+	if err := progress.Done(); err != nil {
 		return err
 	}
 
@@ -206,6 +184,22 @@ func (p *HelloPlugin) Execute(req *proto.ExecuteRequest, stream proto.Plugin_Exe
 		return err
 	}
 
+	// Send the structured result
+	result, err := structpb.NewStruct(map[string]interface{}{
+		"greeting": greeting,
+		"language": language,
+	})
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(&proto.ExecuteOutput{
+		Content: &proto.ExecuteOutput_Result{
+			Result: result,
+		},
+	}); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -2,8 +2,11 @@
 
 import argparse
 import grpc
+import os
+import signal
 import time
 from concurrent import futures
+from google.protobuf import struct_pb2
 
 import plugin_pb2
 import plugin_pb2_grpc
@@ -11,6 +14,26 @@ from grpc_health.v1 import health
 from grpc_health.v1 import health_pb2
 from grpc_health.v1 import health_pb2_grpc
 
+class AuthInterceptor(grpc.ServerInterceptor):
+    """Rejects calls that don't carry the token handed to us by the host."""
+
+    def __init__(self, token):
+        self._expected = f"Bearer {token}"
+
+        def abort(request, context):
+            context.abort(grpc.StatusCode.UNAUTHENTICATED, "invalid auth token")
+
+        self._abort_unary = grpc.unary_unary_rpc_method_handler(abort)
+        self._abort_stream = grpc.unary_stream_rpc_method_handler(abort)
+
+    def intercept_service(self, continuation, handler_call_details):
+        metadata = dict(handler_call_details.invocation_metadata)
+        if metadata.get("authorization") == self._expected:
+            return continuation(handler_call_details)
+        if handler_call_details.method.endswith("/Execute"):
+            return self._abort_stream
+        return self._abort_unary
+
 class MultiplyPlugin(plugin_pb2_grpc.PluginServicer):
     def GetInfo(self, request, context):
         return plugin_pb2.PluginInfo(
@@ -32,14 +55,26 @@ class MultiplyPlugin(plugin_pb2_grpc.PluginServicer):
                     default_value="3",
                     type="float"
                 )
-            }
+            },
+            result_schema={
+                "result": plugin_pb2.ResultFieldSpec(
+                    name="result",
+                    description="Product of the two numbers",
+                    required=True,
+                    type="number"
+                )
+            },
+            protocol_version=2,
+            features=["typed_params"],
+            side_effect_free=True
         )
 
     def Execute(self, request, context):
         try:
-            # Parse parameters
-            num1 = float(request.params.get("num1", "2"))
-            num2 = float(request.params.get("num2", "3"))
+            # Parse parameters, preferring the typed values sent by newer hosts
+            typed = request.typed_params
+            num1 = float(typed["num1"] if "num1" in typed else request.params.get("num1", "2"))
+            num2 = float(typed["num2"] if "num2" in typed else request.params.get("num2", "3"))
 
             # Initial progress
             yield plugin_pb2.ExecuteOutput(
@@ -96,6 +131,11 @@ class MultiplyPlugin(plugin_pb2_grpc.PluginServicer):
                 output=f"\nResult: {num1} × {num2} = {result}"
             )
 
+            # Structured result
+            data = struct_pb2.Struct()
+            data.update({"result": result})
+            yield plugin_pb2.ExecuteOutput(result=data)
+
         except ValueError as e:
             yield plugin_pb2.ExecuteOutput(
                 error=plugin_pb2.Error(
@@ -124,7 +164,23 @@ class MultiplyPlugin(plugin_pb2_grpc.PluginServicer):
         )
 
 def serve(port):
-    server = grpc.server(futures.ThreadPoolExecutor(max_workers=10))
+    interceptors = []
+    token = os.environ.get("PLUGIN_AUTH_TOKEN")
+    if token:
+        interceptors.append(AuthInterceptor(token))
+
+    # Apply the message size and stream limits configured on the host
+    options = []
+    limits = {
+        "PLUGIN_MAX_RECV_MESSAGE_SIZE": "grpc.max_receive_message_length",
+        "PLUGIN_MAX_SEND_MESSAGE_SIZE": "grpc.max_send_message_length",
+        "PLUGIN_MAX_CONCURRENT_STREAMS": "grpc.max_concurrent_streams",
+    }
+    for env_var, option in limits.items():
+        if os.environ.get(env_var):
+            options.append((option, int(os.environ[env_var])))
+
+    server = grpc.server(futures.ThreadPoolExecutor(max_workers=10), interceptors=interceptors, options=options)
     plugin_pb2_grpc.add_PluginServicer_to_server(MultiplyPlugin(), server)
 
     # Add health service
@@ -132,12 +188,36 @@ def serve(port):
     health_pb2_grpc.add_HealthServicer_to_server(health_servicer, server)
     health_servicer.set("", health_pb2.HealthCheckResponse.SERVING)
 
+    # Serve mutual TLS if the host handed us certificates
+    credentials = None
+    server_cert = os.environ.get("PLUGIN_TLS_SERVER_CERT")
+    if server_cert:
+        credentials = grpc.ssl_server_credentials(
+            [(os.environ["PLUGIN_TLS_SERVER_KEY"].encode(), server_cert.encode())],
+            root_certificates=os.environ["PLUGIN_TLS_CA_CERT"].encode(),
+            require_client_auth=True,
+        )
+
+    def bind(address):
+        if credentials:
+            server.add_secure_port(address, credentials)
+        else:
+            server.add_insecure_port(address)
+
     # Try to bind to the port
     try:
-        server.add_insecure_port(f'localhost:{port}')
+        bind(f'localhost:{port}')
     except RuntimeError:
         print(f"Failed to bind to port {port}, trying 127.0.0.1")
-        server.add_insecure_port(f'127.0.0.1:{port}')
+        bind(f'127.0.0.1:{port}')
+
+    # Let in-flight calls finish when the host stops us; it kills the process once its grace period is over
+    def stop(signum, frame):
+        health_servicer.enter_graceful_shutdown()
+        server.stop(grace=5)
+
+    signal.signal(signal.SIGTERM, stop)
+    signal.signal(signal.SIGINT, stop)
 
     server.start()
     print(f"Plugin server running on port {port}")
@@ -22,9 +22,10 @@ _runtime_version.ValidateProtobufRuntimeVersion(
 _sym_db = _symbol_database.Default()
 
 
+from google.protobuf import struct_pb2 as google_dot_protobuf_dot_struct__pb2
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0cplugin.proto\x12\x06plugin\"\r\n\x0bInfoRequest\"\xf0\x01\n\nPluginInfo\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\x0f\n\x07version\x18\x02 \x01(\t\x12\x13\n\x0b\x64\x65scription\x18\x03 \x01(\t\x12?\n\x0fparameter_specs\x18\x05 \x03(\x0b\x32&.plugin.PluginInfo.ParameterSpecsEntry\x12#\n\x04\x61uth\x18\x06 \x01(\x0b\x32\x15.plugin.Authorization\x1aH\n\x13ParameterSpecsEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12 \n\x05value\x18\x02 \x01(\x0b\x32\x11.plugin.ParamSpec:\x02\x38\x01\"}\n\tParamSpec\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\x13\n\x0b\x64\x65scription\x18\x02 \x01(\t\x12\x10\n\x08required\x18\x03 \x01(\x08\x12\x15\n\rdefault_value\x18\x04 \x01(\t\x12\x0c\n\x04type\x18\x05 \x01(\t\x12\x16\n\x0e\x61llowed_values\x18\x06 \x03(\t\"s\n\x0e\x45xecuteRequest\x12\x32\n\x06params\x18\x01 \x03(\x0b\x32\".plugin.ExecuteRequest.ParamsEntry\x1a-\n\x0bParamsEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\t:\x02\x38\x01\"r\n\rExecuteOutput\x12\x10\n\x06output\x18\x01 \x01(\tH\x00\x12\x1e\n\x05\x65rror\x18\x02 \x01(\x0b\x32\r.plugin.ErrorH\x00\x12$\n\x08progress\x18\x03 \x01(\x0b\x32\x10.plugin.ProgressH\x00\x42\t\n\x07\x63ontent\"7\n\x05\x45rror\x12\x0f\n\x07message\x18\x01 \x01(\t\x12\x0c\n\x04\x63ode\x18\x02 \x01(\t\x12\x0f\n\x07\x64\x65tails\x18\x03 \x01(\t\"^\n\x08Progress\x12\x18\n\x10percent_complete\x18\x01 \x01(\x02\x12\r\n\x05stage\x18\x02 \x01(\t\x12\x14\n\x0c\x63urrent_step\x18\x03 \x01(\x05\x12\x13\n\x0btotal_steps\x18\x04 \x01(\x05\"\xba\x02\n\x0eSummaryRequest\x12\x13\n\x0bplugin_name\x18\x01 \x01(\t\x12\x12\n\nstart_time\x18\x02 \x01(\x03\x12\x10\n\x08\x65nd_time\x18\x03 \x01(\x03\x12\x0f\n\x07success\x18\x04 \x01(\x08\x12\r\n\x05\x65rror\x18\x05 \x01(\t\x12\x36\n\x08metadata\x18\x06 \x03(\x0b\x32$.plugin.SummaryRequest.MetadataEntry\x12\x34\n\x07metrics\x18\x07 \x03(\x0b\x32#.plugin.SummaryRequest.MetricsEntry\x1a/\n\rMetadataEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\t:\x02\x38\x01\x1a.\n\x0cMetricsEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x01:\x02\x38\x01\"\xcf\x02\n\x0fSummaryResponse\x12\x13\n\x0bplugin_name\x18\x01 \x01(\t\x12\x12\n\nstart_time\x18\x02 \x01(\x03\x12\x10\n\x08\x65nd_time\x18\x03 \x01(\x03\x12\x10\n\x08\x64uration\x18\x04 \x01(\x01\x12\x0f\n\x07success\x18\x05 \x01(\x08\x12\r\n\x05\x65rror\x18\x06 \x01(\t\x12\x37\n\x08metadata\x18\x07 \x03(\x0b\x32%.plugin.SummaryResponse.MetadataEntry\x12\x35\n\x07metrics\x18\x08 \x03(\x0b\x32$.plugin.SummaryResponse.MetricsEntry\x1a/\n\rMetadataEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\t:\x02\x38\x01\x1a.\n\x0cMetricsEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x01:\x02\x38\x01\"/\n\rAuthorization\x12\x0e\n\x06source\x18\x01 \x01(\t\x12\x0e\n\x06values\x18\x02 \x03(\t2\xc9\x01\n\x06Plugin\x12\x34\n\x07GetInfo\x12\x13.plugin.InfoRequest\x1a\x12.plugin.PluginInfo\"\x00\x12<\n\x07\x45xecute\x12\x16.plugin.ExecuteRequest\x1a\x15.plugin.ExecuteOutput\"\x00\x30\x01\x12K\n\x16ReportExecutionSummary\x12\x16.plugin.SummaryRequest\x1a\x17.plugin.SummaryResponse\"\x00\x42*Z(github.com/example/grpc-plugin-app/protob\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0cplugin.proto\x12\x06plugin\x1a\x1cgoogle/protobuf/struct.proto\"\r\n\x0bInfoRequest\"\xc1\x03\n\nPluginInfo\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\x0f\n\x07version\x18\x02 \x01(\t\x12\x13\n\x0b\x64\x65scription\x18\x03 \x01(\t\x12?\n\x0fparameter_specs\x18\x05 \x03(\x0b\x32&.plugin.PluginInfo.ParameterSpecsEntry\x12#\n\x04\x61uth\x18\x06 \x01(\x0b\x32\x15.plugin.Authorization\x12;\n\rresult_schema\x18\x07 \x03(\x0b\x32$.plugin.PluginInfo.ResultSchemaEntry\x12\x18\n\x10protocol_version\x18\x08 \x01(\r\x12\x10\n\x08\x66\x65\x61tures\x18\t \x03(\t\x12\x18\n\x10side_effect_free\x18\n \x01(\x08\x1aH\n\x13ParameterSpecsEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12 \n\x05value\x18\x02 \x01(\x0b\x32\x11.plugin.ParamSpec:\x02\x38\x01\x1aL\n\x11ResultSchemaEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12&\n\x05value\x18\x02 \x01(\x0b\x32\x17.plugin.ResultFieldSpec:\x02\x38\x01\"}\n\tParamSpec\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\x13\n\x0b\x64\x65scription\x18\x02 \x01(\t\x12\x10\n\x08required\x18\x03 \x01(\x08\x12\x15\n\rdefault_value\x18\x04 \x01(\t\x12\x0c\n\x04type\x18\x05 \x01(\t\x12\x16\n\x0e\x61llowed_values\x18\x06 \x03(\t\"T\n\x0fResultFieldSpec\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\x13\n\x0b\x64\x65scription\x18\x02 \x01(\t\x12\x10\n\x08required\x18\x03 \x01(\x08\x12\x0c\n\x04type\x18\x04 \x01(\t\"\xa0\x02\n\x0e\x45xecuteRequest\x12\x32\n\x06params\x18\x01 \x03(\x0b\x32\".plugin.ExecuteRequest.ParamsEntry\x12-\n\x0ctyped_params\x18\x02 \x01(\x0b\x32\x17.google.protobuf.Struct\x12\x36\n\x08\x63hannels\x18\x03 \x03(\x0b\x32$.plugin.ExecuteRequest.ChannelsEntry\x1a-\n\x0bParamsEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\t:\x02\x38\x01\x1a\x44\n\rChannelsEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\"\n\x05value\x18\x02 \x01(\x0b\x32\x13.plugin.ChannelFlow:\x02\x38\x01\",\n\x0b\x43hannelFlow\x12\x0e\n\x06\x62uffer\x18\x01 \x01(\r\x12\r\n\x05lossy\x18\x02 \x01(\x08\"\xca\x02\n\rExecuteOutput\x12\x10\n\x06output\x18\x01 \x01(\tH\x00\x12\x1e\n\x05\x65rror\x18\x02 \x01(\x0b\x32\r.plugin.ErrorH\x00\x12$\n\x08progress\x18\x03 \x01(\x0b\x32\x10.plugin.ProgressH\x00\x12)\n\x06result\x18\x04 \x01(\x0b\x32\x17.google.protobuf.StructH\x00\x12\x1f\n\x03log\x18\x05 \x01(\x0b\x32\x10.plugin.LogEntryH\x00\x12$\n\x08\x61rtifact\x18\x06 \x01(\x0b\x32\x10.plugin.ArtifactH\x00\x12 \n\x06prompt\x18\x07 \x01(\x0b\x32\x0e.plugin.PromptH\x00\x12 \n\x06metric\x18\x08 \x01(\x0b\x32\x0e.plugin.MetricH\x00\x12 \n\x07\x63hannel\x18\t \x01(\x0e\x32\x0f.plugin.ChannelB\t\n\x07\x63ontent\"*\n\x08LogEntry\x12\r\n\x05level\x18\x01 \x01(\t\x12\x0f\n\x07message\x18\x02 \x01(\t\"4\n\x08\x41rtifact\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\x0c\n\x04\x64\x61ta\x18\x02 \x01(\x0c\x12\x0c\n\x04last\x18\x03 \x01(\x08\"%\n\x06Prompt\x12\n\n\x02id\x18\x01 \x01(\t\x12\x0f\n\x07message\x18\x02 \x01(\t\"\x80\x01\n\x06Metric\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x01\x12*\n\x06labels\x18\x03 \x03(\x0b\x32\x1a.plugin.Metric.LabelsEntry\x1a-\n\x0bLabelsEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\t:\x02\x38\x01\"7\n\x05\x45rror\x12\x0f\n\x07message\x18\x01 \x01(\t\x12\x0c\n\x04\x63ode\x18\x02 \x01(\t\x12\x0f\n\x07\x64\x65tails\x18\x03 \x01(\t\"\xa8\x01\n\x08Progress\x12\x18\n\x10percent_complete\x18\x01 \x01(\x02\x12\r\n\x05stage\x18\x02 \x01(\t\x12\x14\n\x0c\x63urrent_step\x18\x03 \x01(\x05\x12\x13\n\x0btotal_steps\x18\x04 \x01(\x05\x12\x18\n\x10units_per_second\x18\x05 \x01(\x01\x12\x18\n\x10stage_elapsed_ms\x18\x06 \x01(\x03\x12\x14\n\x0cremaining_ms\x18\x07 \x01(\x03\"\xba\x02\n\x0eSummaryRequest\x12\x13\n\x0bplugin_name\x18\x01 \x01(\t\x12\x12\n\nstart_time\x18\x02 \x01(\x03\x12\x10\n\x08\x65nd_time\x18\x03 \x01(\x03\x12\x0f\n\x07success\x18\x04 \x01(\x08\x12\r\n\x05\x65rror\x18\x05 \x01(\t\x12\x36\n\x08metadata\x18\x06 \x03(\x0b\x32$.plugin.SummaryRequest.MetadataEntry\x12\x34\n\x07metrics\x18\x07 \x03(\x0b\x32#.plugin.SummaryRequest.MetricsEntry\x1a/\n\rMetadataEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\t:\x02\x38\x01\x1a.\n\x0cMetricsEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x01:\x02\x38\x01\"\xcf\x02\n\x0fSummaryResponse\x12\x13\n\x0bplugin_name\x18\x01 \x01(\t\x12\x12\n\nstart_time\x18\x02 \x01(\x03\x12\x10\n\x08\x65nd_time\x18\x03 \x01(\x03\x12\x10\n\x08\x64uration\x18\x04 \x01(\x01\x12\x0f\n\x07success\x18\x05 \x01(\x08\x12\r\n\x05\x65rror\x18\x06 \x01(\t\x12\x37\n\x08metadata\x18\x07 \x03(\x0b\x32%.plugin.SummaryResponse.MetadataEntry\x12\x35\n\x07metrics\x18\x08 \x03(\x0b\x32$.plugin.SummaryResponse.MetricsEntry\x1a/\n\rMetadataEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\t:\x02\x38\x01\x1a.\n\x0cMetricsEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x01:\x02\x38\x01\"$\n\x12\x44iagnosticsRequest\x12\x0e\n\x06reason\x18\x01 \x01(\t\"z\n\x13\x44iagnosticsResponse\x12\x35\n\x05\x64umps\x18\x01 \x03(\x0b\x32&.plugin.DiagnosticsResponse.DumpsEntry\x1a,\n\nDumpsEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\t:\x02\x38\x01\"/\n\rAuthorization\x12\x0e\n\x06source\x18\x01 \x01(\t\x12\x0e\n\x06values\x18\x02 \x03(\t*p\n\x07\x43hannel\x12\x12\n\x0e\x43HANNEL_OUTPUT\x10\x00\x12\x13\n\x0f\x43HANNEL_CONTROL\x10\x01\x12\x10\n\x0c\x43HANNEL_LOGS\x10\x02\x12\x13\n\x0f\x43HANNEL_METRICS\x10\x03\x12\x15\n\x11\x43HANNEL_ARTIFACTS\x10\x04\x32\x93\x02\n\x06Plugin\x12\x34\n\x07GetInfo\x12\x13.plugin.InfoRequest\x1a\x12.plugin.PluginInfo\"\x00\x12<\n\x07\x45xecute\x12\x16.plugin.ExecuteRequest\x1a\x15.plugin.ExecuteOutput\"\x00\x30\x01\x12K\n\x16ReportExecutionSummary\x12\x16.plugin.SummaryRequest\x1a\x17.plugin.SummaryResponse\"\x00\x12H\n\x0b\x44iagnostics\x12\x1a.plugin.DiagnosticsRequest\x1a\x1b.plugin.DiagnosticsResponse\"\x00\x42*Z(github.com/example/grpc-plugin-app/protob\x06proto3')
 
 _globals = globals()
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
@@ -34,8 +35,14 @@ if not _descriptor._USE_C_DESCRIPTORS:
   _globals['DESCRIPTOR']._serialized_options = b'Z(github.com/example/grpc-plugin-app/proto'
   _globals['_PLUGININFO_PARAMETERSPECSENTRY']._loaded_options = None
   _globals['_PLUGININFO_PARAMETERSPECSENTRY']._serialized_options = b'8\001'
+  _globals['_PLUGININFO_RESULTSCHEMAENTRY']._loaded_options = None
+  _globals['_PLUGININFO_RESULTSCHEMAENTRY']._serialized_options = b'8\001'
   _globals['_EXECUTEREQUEST_PARAMSENTRY']._loaded_options = None
   _globals['_EXECUTEREQUEST_PARAMSENTRY']._serialized_options = b'8\001'
+  _globals['_EXECUTEREQUEST_CHANNELSENTRY']._loaded_options = None
+  _globals['_EXECUTEREQUEST_CHANNELSENTRY']._serialized_options = b'8\001'
+  _globals['_METRIC_LABELSENTRY']._loaded_options = None
+  _globals['_METRIC_LABELSENTRY']._serialized_options = b'8\001'
   _globals['_SUMMARYREQUEST_METADATAENTRY']._loaded_options = None
   _globals['_SUMMARYREQUEST_METADATAENTRY']._serialized_options = b'8\001'
   _globals['_SUMMARYREQUEST_METRICSENTRY']._loaded_options = None
@@ -44,38 +51,66 @@ if not _descriptor._USE_C_DESCRIPTORS:
   _globals['_SUMMARYRESPONSE_METADATAENTRY']._serialized_options = b'8\001'
   _globals['_SUMMARYRESPONSE_METRICSENTRY']._loaded_options = None
   _globals['_SUMMARYRESPONSE_METRICSENTRY']._serialized_options = b'8\001'
-  _globals['_INFOREQUEST']._serialized_start=24
-  _globals['_INFOREQUEST']._serialized_end=37
-  _globals['_PLUGININFO']._serialized_start=40
-  _globals['_PLUGININFO']._serialized_end=280
-  _globals['_PLUGININFO_PARAMETERSPECSENTRY']._serialized_start=208
-  _globals['_PLUGININFO_PARAMETERSPECSENTRY']._serialized_end=280
-  _globals['_PARAMSPEC']._serialized_start=282
-  _globals['_PARAMSPEC']._serialized_end=407
-  _globals['_EXECUTEREQUEST']._serialized_start=409
-  _globals['_EXECUTEREQUEST']._serialized_end=524
-  _globals['_EXECUTEREQUEST_PARAMSENTRY']._serialized_start=479
-  _globals['_EXECUTEREQUEST_PARAMSENTRY']._serialized_end=524
-  _globals['_EXECUTEOUTPUT']._serialized_start=526
-  _globals['_EXECUTEOUTPUT']._serialized_end=640
-  _globals['_ERROR']._serialized_start=642
-  _globals['_ERROR']._serialized_end=697
-  _globals['_PROGRESS']._serialized_start=699
-  _globals['_PROGRESS']._serialized_end=793
-  _globals['_SUMMARYREQUEST']._serialized_start=796
-  _globals['_SUMMARYREQUEST']._serialized_end=1110
-  _globals['_SUMMARYREQUEST_METADATAENTRY']._serialized_start=1015
-  _globals['_SUMMARYREQUEST_METADATAENTRY']._serialized_end=1062
-  _globals['_SUMMARYREQUEST_METRICSENTRY']._serialized_start=1064
-  _globals['_SUMMARYREQUEST_METRICSENTRY']._serialized_end=1110
-  _globals['_SUMMARYRESPONSE']._serialized_start=1113
-  _globals['_SUMMARYRESPONSE']._serialized_end=1448
-  _globals['_SUMMARYRESPONSE_METADATAENTRY']._serialized_start=1015
-  _globals['_SUMMARYRESPONSE_METADATAENTRY']._serialized_end=1062
-  _globals['_SUMMARYRESPONSE_METRICSENTRY']._serialized_start=1064
-  _globals['_SUMMARYRESPONSE_METRICSENTRY']._serialized_end=1110
-  _globals['_AUTHORIZATION']._serialized_start=1450
-  _globals['_AUTHORIZATION']._serialized_end=1497
-  _globals['_PLUGIN']._serialized_start=1500
-  _globals['_PLUGIN']._serialized_end=1701
+  _globals['_DIAGNOSTICSRESPONSE_DUMPSENTRY']._loaded_options = None
+  _globals['_DIAGNOSTICSRESPONSE_DUMPSENTRY']._serialized_options = b'8\001'
+  _globals['_CHANNEL']._serialized_start=2766
+  _globals['_CHANNEL']._serialized_end=2878
+  _globals['_INFOREQUEST']._serialized_start=54
+  _globals['_INFOREQUEST']._serialized_end=67
+  _globals['_PLUGININFO']._serialized_start=70
+  _globals['_PLUGININFO']._serialized_end=519
+  _globals['_PLUGININFO_PARAMETERSPECSENTRY']._serialized_start=369
+  _globals['_PLUGININFO_PARAMETERSPECSENTRY']._serialized_end=441
+  _globals['_PLUGININFO_RESULTSCHEMAENTRY']._serialized_start=443
+  _globals['_PLUGININFO_RESULTSCHEMAENTRY']._serialized_end=519
+  _globals['_PARAMSPEC']._serialized_start=521
+  _globals['_PARAMSPEC']._serialized_end=646
+  _globals['_RESULTFIELDSPEC']._serialized_start=648
+  _globals['_RESULTFIELDSPEC']._serialized_end=732
+  _globals['_EXECUTEREQUEST']._serialized_start=735
+  _globals['_EXECUTEREQUEST']._serialized_end=1023
+  _globals['_EXECUTEREQUEST_PARAMSENTRY']._serialized_start=908
+  _globals['_EXECUTEREQUEST_PARAMSENTRY']._serialized_end=953
+  _globals['_EXECUTEREQUEST_CHANNELSENTRY']._serialized_start=955
+  _globals['_EXECUTEREQUEST_CHANNELSENTRY']._serialized_end=1023
+  _globals['_CHANNELFLOW']._serialized_start=1025
+  _globals['_CHANNELFLOW']._serialized_end=1069
+  _globals['_EXECUTEOUTPUT']._serialized_start=1072
+  _globals['_EXECUTEOUTPUT']._serialized_end=1402
+  _globals['_LOGENTRY']._serialized_start=1404
+  _globals['_LOGENTRY']._serialized_end=1446
+  _globals['_ARTIFACT']._serialized_start=1448
+  _globals['_ARTIFACT']._serialized_end=1500
+  _globals['_PROMPT']._serialized_start=1502
+  _globals['_PROMPT']._serialized_end=1539
+  _globals['_METRIC']._serialized_start=1542
+  _globals['_METRIC']._serialized_end=1670
+  _globals['_METRIC_LABELSENTRY']._serialized_start=1625
+  _globals['_METRIC_LABELSENTRY']._serialized_end=1670
+  _globals['_ERROR']._serialized_start=1672
+  _globals['_ERROR']._serialized_end=1727
+  _globals['_PROGRESS']._serialized_start=1730
+  _globals['_PROGRESS']._serialized_end=1898
+  _globals['_SUMMARYREQUEST']._serialized_start=1901
+  _globals['_SUMMARYREQUEST']._serialized_end=2215
+  _globals['_SUMMARYREQUEST_METADATAENTRY']._serialized_start=2120
+  _globals['_SUMMARYREQUEST_METADATAENTRY']._serialized_end=2167
+  _globals['_SUMMARYREQUEST_METRICSENTRY']._serialized_start=2169
+  _globals['_SUMMARYREQUEST_METRICSENTRY']._serialized_end=2215
+  _globals['_SUMMARYRESPONSE']._serialized_start=2218
+  _globals['_SUMMARYRESPONSE']._serialized_end=2553
+  _globals['_SUMMARYRESPONSE_METADATAENTRY']._serialized_start=2120
+  _globals['_SUMMARYRESPONSE_METADATAENTRY']._serialized_end=2167
+  _globals['_SUMMARYRESPONSE_METRICSENTRY']._serialized_start=2169
+  _globals['_SUMMARYRESPONSE_METRICSENTRY']._serialized_end=2215
+  _globals['_DIAGNOSTICSREQUEST']._serialized_start=2555
+  _globals['_DIAGNOSTICSREQUEST']._serialized_end=2591
+  _globals['_DIAGNOSTICSRESPONSE']._serialized_start=2593
+  _globals['_DIAGNOSTICSRESPONSE']._serialized_end=2715
+  _globals['_DIAGNOSTICSRESPONSE_DUMPSENTRY']._serialized_start=2671
+  _globals['_DIAGNOSTICSRESPONSE_DUMPSENTRY']._serialized_end=2715
+  _globals['_AUTHORIZATION']._serialized_start=2717
+  _globals['_AUTHORIZATION']._serialized_end=2764
+  _globals['_PLUGIN']._serialized_start=2881
+  _globals['_PLUGIN']._serialized_end=3156
 # @@protoc_insertion_point(module_scope)
@@ -1,16 +1,31 @@
+from google.protobuf import struct_pb2 as _struct_pb2
 from google.protobuf.internal import containers as _containers
+from google.protobuf.internal import enum_type_wrapper as _enum_type_wrapper
 from google.protobuf import descriptor as _descriptor
 from google.protobuf import message as _message
 from typing import ClassVar as _ClassVar, Iterable as _Iterable, Mapping as _Mapping, Optional as _Optional, Union as _Union
 
 DESCRIPTOR: _descriptor.FileDescriptor
 
+class Channel(int, metaclass=_enum_type_wrapper.EnumTypeWrapper):
+    __slots__ = ()
+    CHANNEL_OUTPUT: _ClassVar[Channel]
+    CHANNEL_CONTROL: _ClassVar[Channel]
+    CHANNEL_LOGS: _ClassVar[Channel]
+    CHANNEL_METRICS: _ClassVar[Channel]
+    CHANNEL_ARTIFACTS: _ClassVar[Channel]
+CHANNEL_OUTPUT: Channel
+CHANNEL_CONTROL: Channel
+CHANNEL_LOGS: Channel
+CHANNEL_METRICS: Channel
+CHANNEL_ARTIFACTS: Channel
+
 class InfoRequest(_message.Message):
     __slots__ = ()
     def __init__(self) -> None: ...
 
 class PluginInfo(_message.Message):
-    __slots__ = ("name", "version", "description", "parameter_specs", "auth")
+    __slots__ = ("name", "version", "description", "parameter_specs", "auth", "result_schema", "protocol_version", "features", "side_effect_free")
     class ParameterSpecsEntry(_message.Message):
         __slots__ = ("key", "value")
         KEY_FIELD_NUMBER: _ClassVar[int]
@@ -18,17 +33,32 @@ class PluginInfo(_message.Message):
         key: str
         value: ParamSpec
         def __init__(self, key: _Optional[str] = ..., value: _Optional[_Union[ParamSpec, _Mapping]] = ...) -> None: ...
+    class ResultSchemaEntry(_message.Message):
+        __slots__ = ("key", "value")
+        KEY_FIELD_NUMBER: _ClassVar[int]
+        VALUE_FIELD_NUMBER: _ClassVar[int]
+        key: str
+        value: ResultFieldSpec
+        def __init__(self, key: _Optional[str] = ..., value: _Optional[_Union[ResultFieldSpec, _Mapping]] = ...) -> None: ...
     NAME_FIELD_NUMBER: _ClassVar[int]
     VERSION_FIELD_NUMBER: _ClassVar[int]
     DESCRIPTION_FIELD_NUMBER: _ClassVar[int]
     PARAMETER_SPECS_FIELD_NUMBER: _ClassVar[int]
     AUTH_FIELD_NUMBER: _ClassVar[int]
+    RESULT_SCHEMA_FIELD_NUMBER: _ClassVar[int]
+    PROTOCOL_VERSION_FIELD_NUMBER: _ClassVar[int]
+    FEATURES_FIELD_NUMBER: _ClassVar[int]
+    SIDE_EFFECT_FREE_FIELD_NUMBER: _ClassVar[int]
     name: str
     version: str
     description: str
     parameter_specs: _containers.MessageMap[str, ParamSpec]
     auth: Authorization
-    def __init__(self, name: _Optional[str] = ..., version: _Optional[str] = ..., description: _Optional[str] = ..., parameter_specs: _Optional[_Mapping[str, ParamSpec]] = ..., auth: _Optional[_Union[Authorization, _Mapping]] = ...) -> None: ...
+    result_schema: _containers.MessageMap[str, ResultFieldSpec]
+    protocol_version: int
+    features: _containers.RepeatedScalarFieldContainer[str]
+    side_effect_free: bool
+    def __init__(self, name: _Optional[str] = ..., version: _Optional[str] = ..., description: _Optional[str] = ..., parameter_specs: _Optional[_Mapping[str, ParamSpec]] = ..., auth: _Optional[_Union[Authorization, _Mapping]] = ..., result_schema: _Optional[_Mapping[str, ResultFieldSpec]] = ..., protocol_version: _Optional[int] = ..., features: _Optional[_Iterable[str]] = ..., side_effect_free: bool = ...) -> None: ...
 
 class ParamSpec(_message.Message):
     __slots__ = ("name", "description", "required", "default_value", "type", "allowed_values")
@@ -46,8 +76,20 @@ class ParamSpec(_message.Message):
     allowed_values: _containers.RepeatedScalarFieldContainer[str]
     def __init__(self, name: _Optional[str] = ..., description: _Optional[str] = ..., required: bool = ..., default_value: _Optional[str] = ..., type: _Optional[str] = ..., allowed_values: _Optional[_Iterable[str]] = ...) -> None: ...
 
+class ResultFieldSpec(_message.Message):
+    __slots__ = ("name", "description", "required", "type")
+    NAME_FIELD_NUMBER: _ClassVar[int]
+    DESCRIPTION_FIELD_NUMBER: _ClassVar[int]
+    REQUIRED_FIELD_NUMBER: _ClassVar[int]
+    TYPE_FIELD_NUMBER: _ClassVar[int]
+    name: str
+    description: str
+    required: bool
+    type: str
+    def __init__(self, name: _Optional[str] = ..., description: _Optional[str] = ..., required: bool = ..., type: _Optional[str] = ...) -> None: ...
+
 class ExecuteRequest(_message.Message):
-    __slots__ = ("params",)
+    __slots__ = ("params", "typed_params", "channels")
     class ParamsEntry(_message.Message):
         __slots__ = ("key", "value")
         KEY_FIELD_NUMBER: _ClassVar[int]
@@ -55,19 +97,93 @@ class ExecuteRequest(_message.Message):
         key: str
         value: str
         def __init__(self, key: _Optional[str] = ..., value: _Optional[str] = ...) -> None: ...
+    class ChannelsEntry(_message.Message):
+        __slots__ = ("key", "value")
+        KEY_FIELD_NUMBER: _ClassVar[int]
+        VALUE_FIELD_NUMBER: _ClassVar[int]
+        key: str
+        value: ChannelFlow
+        def __init__(self, key: _Optional[str] = ..., value: _Optional[_Union[ChannelFlow, _Mapping]] = ...) -> None: ...
     PARAMS_FIELD_NUMBER: _ClassVar[int]
+    TYPED_PARAMS_FIELD_NUMBER: _ClassVar[int]
+    CHANNELS_FIELD_NUMBER: _ClassVar[int]
     params: _containers.ScalarMap[str, str]
-    def __init__(self, params: _Optional[_Mapping[str, str]] = ...) -> None: ...
+    typed_params: _struct_pb2.Struct
+    channels: _containers.MessageMap[str, ChannelFlow]
+    def __init__(self, params: _Optional[_Mapping[str, str]] = ..., typed_params: _Optional[_Union[_struct_pb2.Struct, _Mapping]] = ..., channels: _Optional[_Mapping[str, ChannelFlow]] = ...) -> None: ...
+
+class ChannelFlow(_message.Message):
+    __slots__ = ("buffer", "lossy")
+    BUFFER_FIELD_NUMBER: _ClassVar[int]
+    LOSSY_FIELD_NUMBER: _ClassVar[int]
+    buffer: int
+    lossy: bool
+    def __init__(self, buffer: _Optional[int] = ..., lossy: bool = ...) -> None: ...
 
 class ExecuteOutput(_message.Message):
-    __slots__ = ("output", "error", "progress")
+    __slots__ = ("output", "error", "progress", "result", "log", "artifact", "prompt", "metric", "channel")
     OUTPUT_FIELD_NUMBER: _ClassVar[int]
     ERROR_FIELD_NUMBER: _ClassVar[int]
     PROGRESS_FIELD_NUMBER: _ClassVar[int]
+    RESULT_FIELD_NUMBER: _ClassVar[int]
+    LOG_FIELD_NUMBER: _ClassVar[int]
+    ARTIFACT_FIELD_NUMBER: _ClassVar[int]
+    PROMPT_FIELD_NUMBER: _ClassVar[int]
+    METRIC_FIELD_NUMBER: _ClassVar[int]
+    CHANNEL_FIELD_NUMBER: _ClassVar[int]
     output: str
     error: Error
     progress: Progress
-    def __init__(self, output: _Optional[str] = ..., error: _Optional[_Union[Error, _Mapping]] = ..., progress: _Optional[_Union[Progress, _Mapping]] = ...) -> None: ...
+    result: _struct_pb2.Struct
+    log: LogEntry
+    artifact: Artifact
+    prompt: Prompt
+    metric: Metric
+    channel: Channel
+    def __init__(self, output: _Optional[str] = ..., error: _Optional[_Union[Error, _Mapping]] = ..., progress: _Optional[_Union[Progress, _Mapping]] = ..., result: _Optional[_Union[_struct_pb2.Struct, _Mapping]] = ..., log: _Optional[_Union[LogEntry, _Mapping]] = ..., artifact: _Optional[_Union[Artifact, _Mapping]] = ..., prompt: _Optional[_Union[Prompt, _Mapping]] = ..., metric: _Optional[_Union[Metric, _Mapping]] = ..., channel: _Optional[_Union[Channel, str]] = ...) -> None: ...
+
+class LogEntry(_message.Message):
+    __slots__ = ("level", "message")
+    LEVEL_FIELD_NUMBER: _ClassVar[int]
+    MESSAGE_FIELD_NUMBER: _ClassVar[int]
+    level: str
+    message: str
+    def __init__(self, level: _Optional[str] = ..., message: _Optional[str] = ...) -> None: ...
+
+class Artifact(_message.Message):
+    __slots__ = ("name", "data", "last")
+    NAME_FIELD_NUMBER: _ClassVar[int]
+    DATA_FIELD_NUMBER: _ClassVar[int]
+    LAST_FIELD_NUMBER: _ClassVar[int]
+    name: str
+    data: bytes
+    last: bool
+    def __init__(self, name: _Optional[str] = ..., data: _Optional[bytes] = ..., last: bool = ...) -> None: ...
+
+class Prompt(_message.Message):
+    __slots__ = ("id", "message")
+    ID_FIELD_NUMBER: _ClassVar[int]
+    MESSAGE_FIELD_NUMBER: _ClassVar[int]
+    id: str
+    message: str
+    def __init__(self, id: _Optional[str] = ..., message: _Optional[str] = ...) -> None: ...
+
+class Metric(_message.Message):
+    __slots__ = ("name", "value", "labels")
+    class LabelsEntry(_message.Message):
+        __slots__ = ("key", "value")
+        KEY_FIELD_NUMBER: _ClassVar[int]
+        VALUE_FIELD_NUMBER: _ClassVar[int]
+        key: str
+        value: str
+        def __init__(self, key: _Optional[str] = ..., value: _Optional[str] = ...) -> None: ...
+    NAME_FIELD_NUMBER: _ClassVar[int]
+    VALUE_FIELD_NUMBER: _ClassVar[int]
+    LABELS_FIELD_NUMBER: _ClassVar[int]
+    name: str
+    value: float
+    labels: _containers.ScalarMap[str, str]
+    def __init__(self, name: _Optional[str] = ..., value: _Optional[float] = ..., labels: _Optional[_Mapping[str, str]] = ...) -> None: ...
 
 class Error(_message.Message):
     __slots__ = ("message", "code", "details")
@@ -80,16 +196,22 @@ class Error(_message.Message):
     def __init__(self, message: _Optional[str] = ..., code: _Optional[str] = ..., details: _Optional[str] = ...) -> None: ...
 
 class Progress(_message.Message):
-    __slots__ = ("percent_complete", "stage", "current_step", "total_steps")
+    __slots__ = ("percent_complete", "stage", "current_step", "total_steps", "units_per_second", "stage_elapsed_ms", "remaining_ms")
     PERCENT_COMPLETE_FIELD_NUMBER: _ClassVar[int]
     STAGE_FIELD_NUMBER: _ClassVar[int]
     CURRENT_STEP_FIELD_NUMBER: _ClassVar[int]
     TOTAL_STEPS_FIELD_NUMBER: _ClassVar[int]
+    UNITS_PER_SECOND_FIELD_NUMBER: _ClassVar[int]
+    STAGE_ELAPSED_MS_FIELD_NUMBER: _ClassVar[int]
+    REMAINING_MS_FIELD_NUMBER: _ClassVar[int]
     percent_complete: float
     stage: str
     current_step: int
     total_steps: int
-    def __init__(self, percent_complete: _Optional[float] = ..., stage: _Optional[str] = ..., current_step: _Optional[int] = ..., total_steps: _Optional[int] = ...) -> None: ...
+    units_per_second: float
+    stage_elapsed_ms: int
+    remaining_ms: int
+    def __init__(self, percent_complete: _Optional[float] = ..., stage: _Optional[str] = ..., current_step: _Optional[int] = ..., total_steps: _Optional[int] = ..., units_per_second: _Optional[float] = ..., stage_elapsed_ms: _Optional[int] = ..., remaining_ms: _Optional[int] = ...) -> None: ...
 
 class SummaryRequest(_message.Message):
     __slots__ = ("plugin_name", "start_time", "end_time", "success", "error", "metadata", "metrics")
@@ -157,6 +279,25 @@ class SummaryResponse(_message.Message):
     metrics: _containers.ScalarMap[str, float]
     def __init__(self, plugin_name: _Optional[str] = ..., start_time: _Optional[int] = ..., end_time: _Optional[int] = ..., duration: _Optional[float] = ..., success: bool = ..., error: _Optional[str] = ..., metadata: _Optional[_Mapping[str, str]] = ..., metrics: _Optional[_Mapping[str, float]] = ...) -> None: ...
 
+class DiagnosticsRequest(_message.Message):
+    __slots__ = ("reason",)
+    REASON_FIELD_NUMBER: _ClassVar[int]
+    reason: str
+    def __init__(self, reason: _Optional[str] = ...) -> None: ...
+
+class DiagnosticsResponse(_message.Message):
+    __slots__ = ("dumps",)
+    class DumpsEntry(_message.Message):
+        __slots__ = ("key", "value")
+        KEY_FIELD_NUMBER: _ClassVar[int]
+        VALUE_FIELD_NUMBER: _ClassVar[int]
+        key: str
+        value: str
+        def __init__(self, key: _Optional[str] = ..., value: _Optional[str] = ...) -> None: ...
+    DUMPS_FIELD_NUMBER: _ClassVar[int]
+    dumps: _containers.ScalarMap[str, str]
+    def __init__(self, dumps: _Optional[_Mapping[str, str]] = ...) -> None: ...
+
 class Authorization(_message.Message):
     __slots__ = ("source", "values")
     SOURCE_FIELD_NUMBER: _ClassVar[int]
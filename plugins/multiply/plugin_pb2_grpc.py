@@ -50,6 +50,11 @@ class PluginStub(object):
                 request_serializer=plugin__pb2.SummaryRequest.SerializeToString,
                 response_deserializer=plugin__pb2.SummaryResponse.FromString,
                 _registered_method=True)
+        self.Diagnostics = channel.unary_unary(
+                '/plugin.Plugin/Diagnostics',
+                request_serializer=plugin__pb2.DiagnosticsRequest.SerializeToString,
+                response_deserializer=plugin__pb2.DiagnosticsResponse.FromString,
+                _registered_method=True)
 
 
 class PluginServicer(object):
@@ -77,6 +82,13 @@ class PluginServicer(object):
         context.set_details('Method not implemented!')
         raise NotImplementedError('Method not implemented!')
 
+    def Diagnostics(self, request, context):
+        """Diagnostics returns dumps of the plugin's internal state, e.g. when an execution hangs
+        """
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
 
 def add_PluginServicer_to_server(servicer, server):
     rpc_method_handlers = {
@@ -95,6 +107,11 @@ def add_PluginServicer_to_server(servicer, server):
                     request_deserializer=plugin__pb2.SummaryRequest.FromString,
                     response_serializer=plugin__pb2.SummaryResponse.SerializeToString,
             ),
+            'Diagnostics': grpc.unary_unary_rpc_method_handler(
+                    servicer.Diagnostics,
+                    request_deserializer=plugin__pb2.DiagnosticsRequest.FromString,
+                    response_serializer=plugin__pb2.DiagnosticsResponse.SerializeToString,
+            ),
     }
     generic_handler = grpc.method_handlers_generic_handler(
             'plugin.Plugin', rpc_method_handlers)
@@ -187,3 +204,30 @@ class Plugin(object):
             timeout,
             metadata,
             _registered_method=True)
+
+    @staticmethod
+    def Diagnostics(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_unary(
+            request,
+            target,
+            '/plugin.Plugin/Diagnostics',
+            plugin__pb2.DiagnosticsRequest.SerializeToString,
+            plugin__pb2.DiagnosticsResponse.FromString,
+            options,
+            channel_credentials,
+            insecure,
+            call_credentials,
+            compression,
+            wait_for_ready,
+            timeout,
+            metadata,
+            _registered_method=True)
@@ -9,6 +9,7 @@ package proto
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -21,6 +22,62 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Channel is a logical stream multiplexed on the Execute stream
+type Channel int32
+
+const (
+	Channel_CHANNEL_OUTPUT    Channel = 0 // Output lines, errors and results
+	Channel_CHANNEL_CONTROL   Channel = 1 // Progress and prompts, sent ahead of every other channel
+	Channel_CHANNEL_LOGS      Channel = 2 // Plugin log entries
+	Channel_CHANNEL_METRICS   Channel = 3 // Metric samples
+	Channel_CHANNEL_ARTIFACTS Channel = 4 // Bulk data, sent only when no other channel has messages waiting
+)
+
+// Enum value maps for Channel.
+var (
+	Channel_name = map[int32]string{
+		0: "CHANNEL_OUTPUT",
+		1: "CHANNEL_CONTROL",
+		2: "CHANNEL_LOGS",
+		3: "CHANNEL_METRICS",
+		4: "CHANNEL_ARTIFACTS",
+	}
+	Channel_value = map[string]int32{
+		"CHANNEL_OUTPUT":    0,
+		"CHANNEL_CONTROL":   1,
+		"CHANNEL_LOGS":      2,
+		"CHANNEL_METRICS":   3,
+		"CHANNEL_ARTIFACTS": 4,
+	}
+)
+
+func (x Channel) Enum() *Channel {
+	p := new(Channel)
+	*p = x
+	return p
+}
+
+func (x Channel) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Channel) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_plugin_proto_enumTypes[0].Descriptor()
+}
+
+func (Channel) Type() protoreflect.EnumType {
+	return &file_proto_plugin_proto_enumTypes[0]
+}
+
+func (x Channel) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Channel.Descriptor instead.
+func (Channel) EnumDescriptor() ([]byte, []int) {
+	return file_proto_plugin_proto_rawDescGZIP(), []int{0}
+}
+
 // InfoRequest is empty for now but may contain fields in the future
 type InfoRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -60,14 +117,18 @@ func (*InfoRequest) Descriptor() ([]byte, []int) {
 
 // PluginInfo contains metadata about the plugin
 type PluginInfo struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	Name           string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Version        string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
-	Description    string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
-	ParameterSpecs map[string]*ParamSpec  `protobuf:"bytes,5,rep,name=parameter_specs,json=parameterSpecs,proto3" json:"parameter_specs,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	Auth           *Authorization         `protobuf:"bytes,6,opt,name=auth,proto3" json:"auth,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+	state           protoimpl.MessageState      `protogen:"open.v1"`
+	Name            string                      `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version         string                      `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Description     string                      `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	ParameterSpecs  map[string]*ParamSpec       `protobuf:"bytes,5,rep,name=parameter_specs,json=parameterSpecs,proto3" json:"parameter_specs,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Auth            *Authorization              `protobuf:"bytes,6,opt,name=auth,proto3" json:"auth,omitempty"`
+	ResultSchema    map[string]*ResultFieldSpec `protobuf:"bytes,7,rep,name=result_schema,json=resultSchema,proto3" json:"result_schema,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // if empty, results are not validated
+	ProtocolVersion uint32                      `protobuf:"varint,8,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`                                                                 // host/plugin protocol the plugin was built for, 0 for plugins predating versioning
+	Features        []string                    `protobuf:"bytes,9,rep,name=features,proto3" json:"features,omitempty"`                                                                                                       // host features the plugin relies on, e.g. "typed_params"
+	SideEffectFree  bool                        `protobuf:"varint,10,opt,name=side_effect_free,json=sideEffectFree,proto3" json:"side_effect_free,omitempty"`                                                                 // executions only compute a result, so the host may replay them
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *PluginInfo) Reset() {
@@ -135,6 +196,34 @@ func (x *PluginInfo) GetAuth() *Authorization {
 	return nil
 }
 
+func (x *PluginInfo) GetResultSchema() map[string]*ResultFieldSpec {
+	if x != nil {
+		return x.ResultSchema
+	}
+	return nil
+}
+
+func (x *PluginInfo) GetProtocolVersion() uint32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *PluginInfo) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+func (x *PluginInfo) GetSideEffectFree() bool {
+	if x != nil {
+		return x.SideEffectFree
+	}
+	return false
+}
+
 // ParamSpec describes a plugin parameter
 type ParamSpec struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -220,17 +309,88 @@ func (x *ParamSpec) GetAllowedValues() []string {
 	return nil
 }
 
+// ResultFieldSpec describes a field of the plugin's structured result
+type ResultFieldSpec struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Required      bool                   `protobuf:"varint,3,opt,name=required,proto3" json:"required,omitempty"`
+	Type          string                 `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"` // "string", "number", "bool", "object", "list", or "any"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResultFieldSpec) Reset() {
+	*x = ResultFieldSpec{}
+	mi := &file_proto_plugin_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResultFieldSpec) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResultFieldSpec) ProtoMessage() {}
+
+func (x *ResultFieldSpec) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResultFieldSpec.ProtoReflect.Descriptor instead.
+func (*ResultFieldSpec) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ResultFieldSpec) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ResultFieldSpec) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *ResultFieldSpec) GetRequired() bool {
+	if x != nil {
+		return x.Required
+	}
+	return false
+}
+
+func (x *ResultFieldSpec) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
 // ExecuteRequest contains the parameters for plugin execution
 type ExecuteRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Params        map[string]string      `protobuf:"bytes,1,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	state         protoimpl.MessageState  `protogen:"open.v1"`
+	Params        map[string]string       `protobuf:"bytes,1,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`     // string form of every parameter, kept for older plugins
+	TypedParams   *structpb.Struct        `protobuf:"bytes,2,opt,name=typed_params,json=typedParams,proto3" json:"typed_params,omitempty"`                                                  // parameters converted according to their ParamSpec type
+	Channels      map[string]*ChannelFlow `protobuf:"bytes,3,rep,name=channels,proto3" json:"channels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // flow control per channel, keyed by name (e.g. "artifacts")
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ExecuteRequest) Reset() {
 	*x = ExecuteRequest{}
-	mi := &file_proto_plugin_proto_msgTypes[3]
+	mi := &file_proto_plugin_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -242,7 +402,7 @@ func (x *ExecuteRequest) String() string {
 func (*ExecuteRequest) ProtoMessage() {}
 
 func (x *ExecuteRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_proto_msgTypes[3]
+	mi := &file_proto_plugin_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -255,7 +415,7 @@ func (x *ExecuteRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExecuteRequest.ProtoReflect.Descriptor instead.
 func (*ExecuteRequest) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_proto_rawDescGZIP(), []int{3}
+	return file_proto_plugin_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *ExecuteRequest) GetParams() map[string]string {
@@ -265,6 +425,73 @@ func (x *ExecuteRequest) GetParams() map[string]string {
 	return nil
 }
 
+func (x *ExecuteRequest) GetTypedParams() *structpb.Struct {
+	if x != nil {
+		return x.TypedParams
+	}
+	return nil
+}
+
+func (x *ExecuteRequest) GetChannels() map[string]*ChannelFlow {
+	if x != nil {
+		return x.Channels
+	}
+	return nil
+}
+
+// ChannelFlow is the flow control the host asks for on one channel
+type ChannelFlow struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Buffer        uint32                 `protobuf:"varint,1,opt,name=buffer,proto3" json:"buffer,omitempty"` // messages the plugin may queue on the channel, 0 for the default
+	Lossy         bool                   `protobuf:"varint,2,opt,name=lossy,proto3" json:"lossy,omitempty"`   // drop the oldest queued message when full instead of blocking the sender
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChannelFlow) Reset() {
+	*x = ChannelFlow{}
+	mi := &file_proto_plugin_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChannelFlow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChannelFlow) ProtoMessage() {}
+
+func (x *ChannelFlow) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChannelFlow.ProtoReflect.Descriptor instead.
+func (*ChannelFlow) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ChannelFlow) GetBuffer() uint32 {
+	if x != nil {
+		return x.Buffer
+	}
+	return 0
+}
+
+func (x *ChannelFlow) GetLossy() bool {
+	if x != nil {
+		return x.Lossy
+	}
+	return false
+}
+
 // ExecuteOutput represents a single output message from the execution
 type ExecuteOutput struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -273,14 +500,20 @@ type ExecuteOutput struct {
 	//	*ExecuteOutput_Output
 	//	*ExecuteOutput_Error
 	//	*ExecuteOutput_Progress
+	//	*ExecuteOutput_Result
+	//	*ExecuteOutput_Log
+	//	*ExecuteOutput_Artifact
+	//	*ExecuteOutput_Prompt
+	//	*ExecuteOutput_Metric
 	Content       isExecuteOutput_Content `protobuf_oneof:"content"`
+	Channel       Channel                 `protobuf:"varint,9,opt,name=channel,proto3,enum=plugin.Channel" json:"channel,omitempty"` // Channel the message was sent on
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ExecuteOutput) Reset() {
 	*x = ExecuteOutput{}
-	mi := &file_proto_plugin_proto_msgTypes[4]
+	mi := &file_proto_plugin_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -292,7 +525,7 @@ func (x *ExecuteOutput) String() string {
 func (*ExecuteOutput) ProtoMessage() {}
 
 func (x *ExecuteOutput) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_proto_msgTypes[4]
+	mi := &file_proto_plugin_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -305,7 +538,7 @@ func (x *ExecuteOutput) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExecuteOutput.ProtoReflect.Descriptor instead.
 func (*ExecuteOutput) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_proto_rawDescGZIP(), []int{4}
+	return file_proto_plugin_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *ExecuteOutput) GetContent() isExecuteOutput_Content {
@@ -342,6 +575,58 @@ func (x *ExecuteOutput) GetProgress() *Progress {
 	return nil
 }
 
+func (x *ExecuteOutput) GetResult() *structpb.Struct {
+	if x != nil {
+		if x, ok := x.Content.(*ExecuteOutput_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+func (x *ExecuteOutput) GetLog() *LogEntry {
+	if x != nil {
+		if x, ok := x.Content.(*ExecuteOutput_Log); ok {
+			return x.Log
+		}
+	}
+	return nil
+}
+
+func (x *ExecuteOutput) GetArtifact() *Artifact {
+	if x != nil {
+		if x, ok := x.Content.(*ExecuteOutput_Artifact); ok {
+			return x.Artifact
+		}
+	}
+	return nil
+}
+
+func (x *ExecuteOutput) GetPrompt() *Prompt {
+	if x != nil {
+		if x, ok := x.Content.(*ExecuteOutput_Prompt); ok {
+			return x.Prompt
+		}
+	}
+	return nil
+}
+
+func (x *ExecuteOutput) GetMetric() *Metric {
+	if x != nil {
+		if x, ok := x.Content.(*ExecuteOutput_Metric); ok {
+			return x.Metric
+		}
+	}
+	return nil
+}
+
+func (x *ExecuteOutput) GetChannel() Channel {
+	if x != nil {
+		return x.Channel
+	}
+	return Channel_CHANNEL_OUTPUT
+}
+
 type isExecuteOutput_Content interface {
 	isExecuteOutput_Content()
 }
@@ -358,12 +643,270 @@ type ExecuteOutput_Progress struct {
 	Progress *Progress `protobuf:"bytes,3,opt,name=progress,proto3,oneof"` // Progress information
 }
 
+type ExecuteOutput_Result struct {
+	Result *structpb.Struct `protobuf:"bytes,4,opt,name=result,proto3,oneof"` // Structured result data
+}
+
+type ExecuteOutput_Log struct {
+	Log *LogEntry `protobuf:"bytes,5,opt,name=log,proto3,oneof"` // Plugin log entry
+}
+
+type ExecuteOutput_Artifact struct {
+	Artifact *Artifact `protobuf:"bytes,6,opt,name=artifact,proto3,oneof"` // Chunk of a bulk artifact
+}
+
+type ExecuteOutput_Prompt struct {
+	Prompt *Prompt `protobuf:"bytes,7,opt,name=prompt,proto3,oneof"` // Message asking for the user's attention
+}
+
+type ExecuteOutput_Metric struct {
+	Metric *Metric `protobuf:"bytes,8,opt,name=metric,proto3,oneof"` // Metric sample
+}
+
 func (*ExecuteOutput_Output) isExecuteOutput_Content() {}
 
 func (*ExecuteOutput_Error) isExecuteOutput_Content() {}
 
 func (*ExecuteOutput_Progress) isExecuteOutput_Content() {}
 
+func (*ExecuteOutput_Result) isExecuteOutput_Content() {}
+
+func (*ExecuteOutput_Log) isExecuteOutput_Content() {}
+
+func (*ExecuteOutput_Artifact) isExecuteOutput_Content() {}
+
+func (*ExecuteOutput_Prompt) isExecuteOutput_Content() {}
+
+func (*ExecuteOutput_Metric) isExecuteOutput_Content() {}
+
+// LogEntry is a log line from the plugin
+type LogEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Level         string                 `protobuf:"bytes,1,opt,name=level,proto3" json:"level,omitempty"` // "debug", "info", "warn" or "error"
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogEntry) Reset() {
+	*x = LogEntry{}
+	mi := &file_proto_plugin_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogEntry) ProtoMessage() {}
+
+func (x *LogEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogEntry.ProtoReflect.Descriptor instead.
+func (*LogEntry) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *LogEntry) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *LogEntry) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Artifact is one chunk of a named artifact; chunks of an artifact arrive in order
+type Artifact struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Last          bool                   `protobuf:"varint,3,opt,name=last,proto3" json:"last,omitempty"` // set on the final chunk
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Artifact) Reset() {
+	*x = Artifact{}
+	mi := &file_proto_plugin_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Artifact) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Artifact) ProtoMessage() {}
+
+func (x *Artifact) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Artifact.ProtoReflect.Descriptor instead.
+func (*Artifact) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Artifact) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Artifact) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Artifact) GetLast() bool {
+	if x != nil {
+		return x.Last
+	}
+	return false
+}
+
+// Prompt asks for the user's attention, e.g. to approve a step out of band
+type Prompt struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Prompt) Reset() {
+	*x = Prompt{}
+	mi := &file_proto_plugin_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Prompt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Prompt) ProtoMessage() {}
+
+func (x *Prompt) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Prompt.ProtoReflect.Descriptor instead.
+func (*Prompt) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Prompt) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Prompt) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Metric is a sample of a named metric
+type Metric struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value         float64                `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Metric) Reset() {
+	*x = Metric{}
+	mi := &file_proto_plugin_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Metric) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metric) ProtoMessage() {}
+
+func (x *Metric) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metric.ProtoReflect.Descriptor instead.
+func (*Metric) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Metric) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Metric) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *Metric) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
 // Error represents an execution error
 type Error struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -376,7 +919,7 @@ type Error struct {
 
 func (x *Error) Reset() {
 	*x = Error{}
-	mi := &file_proto_plugin_proto_msgTypes[5]
+	mi := &file_proto_plugin_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -388,7 +931,7 @@ func (x *Error) String() string {
 func (*Error) ProtoMessage() {}
 
 func (x *Error) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_proto_msgTypes[5]
+	mi := &file_proto_plugin_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -401,7 +944,7 @@ func (x *Error) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Error.ProtoReflect.Descriptor instead.
 func (*Error) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_proto_rawDescGZIP(), []int{5}
+	return file_proto_plugin_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *Error) GetMessage() string {
@@ -432,13 +975,16 @@ type Progress struct {
 	Stage           string                 `protobuf:"bytes,2,opt,name=stage,proto3" json:"stage,omitempty"`
 	CurrentStep     int32                  `protobuf:"varint,3,opt,name=current_step,json=currentStep,proto3" json:"current_step,omitempty"`
 	TotalSteps      int32                  `protobuf:"varint,4,opt,name=total_steps,json=totalSteps,proto3" json:"total_steps,omitempty"`
+	UnitsPerSecond  float64                `protobuf:"fixed64,5,opt,name=units_per_second,json=unitsPerSecond,proto3" json:"units_per_second,omitempty"` // Rate of completed work units, when the plugin reports them
+	StageElapsedMs  int64                  `protobuf:"varint,6,opt,name=stage_elapsed_ms,json=stageElapsedMs,proto3" json:"stage_elapsed_ms,omitempty"`  // Time spent in the current stage
+	RemainingMs     int64                  `protobuf:"varint,7,opt,name=remaining_ms,json=remainingMs,proto3" json:"remaining_ms,omitempty"`             // Estimated time left, 0 if unknown
 	unknownFields   protoimpl.UnknownFields
 	sizeCache       protoimpl.SizeCache
 }
 
 func (x *Progress) Reset() {
 	*x = Progress{}
-	mi := &file_proto_plugin_proto_msgTypes[6]
+	mi := &file_proto_plugin_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -450,7 +996,7 @@ func (x *Progress) String() string {
 func (*Progress) ProtoMessage() {}
 
 func (x *Progress) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_proto_msgTypes[6]
+	mi := &file_proto_plugin_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -463,7 +1009,7 @@ func (x *Progress) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Progress.ProtoReflect.Descriptor instead.
 func (*Progress) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_proto_rawDescGZIP(), []int{6}
+	return file_proto_plugin_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *Progress) GetPercentComplete() float32 {
@@ -494,6 +1040,27 @@ func (x *Progress) GetTotalSteps() int32 {
 	return 0
 }
 
+func (x *Progress) GetUnitsPerSecond() float64 {
+	if x != nil {
+		return x.UnitsPerSecond
+	}
+	return 0
+}
+
+func (x *Progress) GetStageElapsedMs() int64 {
+	if x != nil {
+		return x.StageElapsedMs
+	}
+	return 0
+}
+
+func (x *Progress) GetRemainingMs() int64 {
+	if x != nil {
+		return x.RemainingMs
+	}
+	return 0
+}
+
 // SummaryRequest contains execution summary data
 type SummaryRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -510,7 +1077,7 @@ type SummaryRequest struct {
 
 func (x *SummaryRequest) Reset() {
 	*x = SummaryRequest{}
-	mi := &file_proto_plugin_proto_msgTypes[7]
+	mi := &file_proto_plugin_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -522,7 +1089,7 @@ func (x *SummaryRequest) String() string {
 func (*SummaryRequest) ProtoMessage() {}
 
 func (x *SummaryRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_proto_msgTypes[7]
+	mi := &file_proto_plugin_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -535,7 +1102,7 @@ func (x *SummaryRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SummaryRequest.ProtoReflect.Descriptor instead.
 func (*SummaryRequest) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_proto_rawDescGZIP(), []int{7}
+	return file_proto_plugin_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *SummaryRequest) GetPluginName() string {
@@ -604,7 +1171,7 @@ type SummaryResponse struct {
 
 func (x *SummaryResponse) Reset() {
 	*x = SummaryResponse{}
-	mi := &file_proto_plugin_proto_msgTypes[8]
+	mi := &file_proto_plugin_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -616,7 +1183,7 @@ func (x *SummaryResponse) String() string {
 func (*SummaryResponse) ProtoMessage() {}
 
 func (x *SummaryResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_proto_msgTypes[8]
+	mi := &file_proto_plugin_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -629,7 +1196,7 @@ func (x *SummaryResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SummaryResponse.ProtoReflect.Descriptor instead.
 func (*SummaryResponse) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_proto_rawDescGZIP(), []int{8}
+	return file_proto_plugin_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *SummaryResponse) GetPluginName() string {
@@ -688,6 +1255,96 @@ func (x *SummaryResponse) GetMetrics() map[string]float64 {
 	return nil
 }
 
+// DiagnosticsRequest asks the plugin for diagnostic dumps
+type DiagnosticsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"` // Why the host is asking, e.g. "execution exceeded 5m0s"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiagnosticsRequest) Reset() {
+	*x = DiagnosticsRequest{}
+	mi := &file_proto_plugin_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiagnosticsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiagnosticsRequest) ProtoMessage() {}
+
+func (x *DiagnosticsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiagnosticsRequest.ProtoReflect.Descriptor instead.
+func (*DiagnosticsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DiagnosticsRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// DiagnosticsResponse carries the plugin's diagnostic dumps
+type DiagnosticsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dumps         map[string]string      `protobuf:"bytes,1,rep,name=dumps,proto3" json:"dumps,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Dump name (e.g. "goroutines") to its text
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiagnosticsResponse) Reset() {
+	*x = DiagnosticsResponse{}
+	mi := &file_proto_plugin_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiagnosticsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiagnosticsResponse) ProtoMessage() {}
+
+func (x *DiagnosticsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiagnosticsResponse.ProtoReflect.Descriptor instead.
+func (*DiagnosticsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *DiagnosticsResponse) GetDumps() map[string]string {
+	if x != nil {
+		return x.Dumps
+	}
+	return nil
+}
+
 type Authorization struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Source of authorization, e.g., "AD Group", "Repo", "Gitlab User"
@@ -700,7 +1357,7 @@ type Authorization struct {
 
 func (x *Authorization) Reset() {
 	*x = Authorization{}
-	mi := &file_proto_plugin_proto_msgTypes[9]
+	mi := &file_proto_plugin_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -712,7 +1369,7 @@ func (x *Authorization) String() string {
 func (*Authorization) ProtoMessage() {}
 
 func (x *Authorization) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_proto_msgTypes[9]
+	mi := &file_proto_plugin_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -725,7 +1382,7 @@ func (x *Authorization) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Authorization.ProtoReflect.Descriptor instead.
 func (*Authorization) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_proto_rawDescGZIP(), []int{9}
+	return file_proto_plugin_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *Authorization) GetSource() string {
@@ -746,45 +1403,92 @@ var File_proto_plugin_proto protoreflect.FileDescriptor
 
 const file_proto_plugin_proto_rawDesc = "" +
 	"\n" +
-	"\x12proto/plugin.proto\x12\x06plugin\"\r\n" +
-	"\vInfoRequest\"\xae\x02\n" +
+	"\x12proto/plugin.proto\x12\x06plugin\x1a\x1cgoogle/protobuf/struct.proto\"\r\n" +
+	"\vInfoRequest\"\xc4\x04\n" +
 	"\n" +
 	"PluginInfo\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
 	"\aversion\x18\x02 \x01(\tR\aversion\x12 \n" +
 	"\vdescription\x18\x03 \x01(\tR\vdescription\x12O\n" +
 	"\x0fparameter_specs\x18\x05 \x03(\v2&.plugin.PluginInfo.ParameterSpecsEntryR\x0eparameterSpecs\x12)\n" +
-	"\x04auth\x18\x06 \x01(\v2\x15.plugin.AuthorizationR\x04auth\x1aT\n" +
+	"\x04auth\x18\x06 \x01(\v2\x15.plugin.AuthorizationR\x04auth\x12I\n" +
+	"\rresult_schema\x18\a \x03(\v2$.plugin.PluginInfo.ResultSchemaEntryR\fresultSchema\x12)\n" +
+	"\x10protocol_version\x18\b \x01(\rR\x0fprotocolVersion\x12\x1a\n" +
+	"\bfeatures\x18\t \x03(\tR\bfeatures\x12(\n" +
+	"\x10side_effect_free\x18\n" +
+	" \x01(\bR\x0esideEffectFree\x1aT\n" +
 	"\x13ParameterSpecsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12'\n" +
-	"\x05value\x18\x02 \x01(\v2\x11.plugin.ParamSpecR\x05value:\x028\x01\"\xbd\x01\n" +
+	"\x05value\x18\x02 \x01(\v2\x11.plugin.ParamSpecR\x05value:\x028\x01\x1aX\n" +
+	"\x11ResultSchemaEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12-\n" +
+	"\x05value\x18\x02 \x01(\v2\x17.plugin.ResultFieldSpecR\x05value:\x028\x01\"\xbd\x01\n" +
 	"\tParamSpec\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
 	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x1a\n" +
 	"\brequired\x18\x03 \x01(\bR\brequired\x12#\n" +
 	"\rdefault_value\x18\x04 \x01(\tR\fdefaultValue\x12\x12\n" +
 	"\x04type\x18\x05 \x01(\tR\x04type\x12%\n" +
-	"\x0eallowed_values\x18\x06 \x03(\tR\rallowedValues\"\x87\x01\n" +
+	"\x0eallowed_values\x18\x06 \x03(\tR\rallowedValues\"w\n" +
+	"\x0fResultFieldSpec\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x1a\n" +
+	"\brequired\x18\x03 \x01(\bR\brequired\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\"\xd7\x02\n" +
 	"\x0eExecuteRequest\x12:\n" +
-	"\x06params\x18\x01 \x03(\v2\".plugin.ExecuteRequest.ParamsEntryR\x06params\x1a9\n" +
+	"\x06params\x18\x01 \x03(\v2\".plugin.ExecuteRequest.ParamsEntryR\x06params\x12:\n" +
+	"\ftyped_params\x18\x02 \x01(\v2\x17.google.protobuf.StructR\vtypedParams\x12@\n" +
+	"\bchannels\x18\x03 \x03(\v2$.plugin.ExecuteRequest.ChannelsEntryR\bchannels\x1a9\n" +
 	"\vParamsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x8b\x01\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1aP\n" +
+	"\rChannelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12)\n" +
+	"\x05value\x18\x02 \x01(\v2\x13.plugin.ChannelFlowR\x05value:\x028\x01\";\n" +
+	"\vChannelFlow\x12\x16\n" +
+	"\x06buffer\x18\x01 \x01(\rR\x06buffer\x12\x14\n" +
+	"\x05lossy\x18\x02 \x01(\bR\x05lossy\"\x93\x03\n" +
 	"\rExecuteOutput\x12\x18\n" +
 	"\x06output\x18\x01 \x01(\tH\x00R\x06output\x12%\n" +
 	"\x05error\x18\x02 \x01(\v2\r.plugin.ErrorH\x00R\x05error\x12.\n" +
-	"\bprogress\x18\x03 \x01(\v2\x10.plugin.ProgressH\x00R\bprogressB\t\n" +
-	"\acontent\"O\n" +
+	"\bprogress\x18\x03 \x01(\v2\x10.plugin.ProgressH\x00R\bprogress\x121\n" +
+	"\x06result\x18\x04 \x01(\v2\x17.google.protobuf.StructH\x00R\x06result\x12$\n" +
+	"\x03log\x18\x05 \x01(\v2\x10.plugin.LogEntryH\x00R\x03log\x12.\n" +
+	"\bartifact\x18\x06 \x01(\v2\x10.plugin.ArtifactH\x00R\bartifact\x12(\n" +
+	"\x06prompt\x18\a \x01(\v2\x0e.plugin.PromptH\x00R\x06prompt\x12(\n" +
+	"\x06metric\x18\b \x01(\v2\x0e.plugin.MetricH\x00R\x06metric\x12)\n" +
+	"\achannel\x18\t \x01(\x0e2\x0f.plugin.ChannelR\achannelB\t\n" +
+	"\acontent\":\n" +
+	"\bLogEntry\x12\x14\n" +
+	"\x05level\x18\x01 \x01(\tR\x05level\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"F\n" +
+	"\bArtifact\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\x12\x12\n" +
+	"\x04last\x18\x03 \x01(\bR\x04last\"2\n" +
+	"\x06Prompt\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\xa1\x01\n" +
+	"\x06Metric\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value\x122\n" +
+	"\x06labels\x18\x03 \x03(\v2\x1a.plugin.Metric.LabelsEntryR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"O\n" +
 	"\x05Error\x12\x18\n" +
 	"\amessage\x18\x01 \x01(\tR\amessage\x12\x12\n" +
 	"\x04code\x18\x02 \x01(\tR\x04code\x12\x18\n" +
-	"\adetails\x18\x03 \x01(\tR\adetails\"\x8f\x01\n" +
+	"\adetails\x18\x03 \x01(\tR\adetails\"\x86\x02\n" +
 	"\bProgress\x12)\n" +
 	"\x10percent_complete\x18\x01 \x01(\x02R\x0fpercentComplete\x12\x14\n" +
 	"\x05stage\x18\x02 \x01(\tR\x05stage\x12!\n" +
 	"\fcurrent_step\x18\x03 \x01(\x05R\vcurrentStep\x12\x1f\n" +
 	"\vtotal_steps\x18\x04 \x01(\x05R\n" +
-	"totalSteps\"\x95\x03\n" +
+	"totalSteps\x12(\n" +
+	"\x10units_per_second\x18\x05 \x01(\x01R\x0eunitsPerSecond\x12(\n" +
+	"\x10stage_elapsed_ms\x18\x06 \x01(\x03R\x0estageElapsedMs\x12!\n" +
+	"\fremaining_ms\x18\a \x01(\x03R\vremainingMs\"\x95\x03\n" +
 	"\x0eSummaryRequest\x12\x1f\n" +
 	"\vplugin_name\x18\x01 \x01(\tR\n" +
 	"pluginName\x12\x1d\n" +
@@ -817,14 +1521,29 @@ const file_proto_plugin_proto_rawDesc = "" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a:\n" +
 	"\fMetricsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\"?\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\",\n" +
+	"\x12DiagnosticsRequest\x12\x16\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reason\"\x8d\x01\n" +
+	"\x13DiagnosticsResponse\x12<\n" +
+	"\x05dumps\x18\x01 \x03(\v2&.plugin.DiagnosticsResponse.DumpsEntryR\x05dumps\x1a8\n" +
+	"\n" +
+	"DumpsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"?\n" +
 	"\rAuthorization\x12\x16\n" +
 	"\x06source\x18\x01 \x01(\tR\x06source\x12\x16\n" +
-	"\x06values\x18\x02 \x03(\tR\x06values2\xc9\x01\n" +
+	"\x06values\x18\x02 \x03(\tR\x06values*p\n" +
+	"\aChannel\x12\x12\n" +
+	"\x0eCHANNEL_OUTPUT\x10\x00\x12\x13\n" +
+	"\x0fCHANNEL_CONTROL\x10\x01\x12\x10\n" +
+	"\fCHANNEL_LOGS\x10\x02\x12\x13\n" +
+	"\x0fCHANNEL_METRICS\x10\x03\x12\x15\n" +
+	"\x11CHANNEL_ARTIFACTS\x10\x042\x93\x02\n" +
 	"\x06Plugin\x124\n" +
 	"\aGetInfo\x12\x13.plugin.InfoRequest\x1a\x12.plugin.PluginInfo\"\x00\x12<\n" +
 	"\aExecute\x12\x16.plugin.ExecuteRequest\x1a\x15.plugin.ExecuteOutput\"\x000\x01\x12K\n" +
-	"\x16ReportExecutionSummary\x12\x16.plugin.SummaryRequest\x1a\x17.plugin.SummaryResponse\"\x00B*Z(github.com/example/grpc-plugin-app/protob\x06proto3"
+	"\x16ReportExecutionSummary\x12\x16.plugin.SummaryRequest\x1a\x17.plugin.SummaryResponse\"\x00\x12H\n" +
+	"\vDiagnostics\x12\x1a.plugin.DiagnosticsRequest\x1a\x1b.plugin.DiagnosticsResponse\"\x00B*Z(github.com/example/grpc-plugin-app/protob\x06proto3"
 
 var (
 	file_proto_plugin_proto_rawDescOnce sync.Once
@@ -838,47 +1557,77 @@ func file_proto_plugin_proto_rawDescGZIP() []byte {
 	return file_proto_plugin_proto_rawDescData
 }
 
-var file_proto_plugin_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_proto_plugin_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proto_plugin_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
 var file_proto_plugin_proto_goTypes = []any{
-	(*InfoRequest)(nil),     // 0: plugin.InfoRequest
-	(*PluginInfo)(nil),      // 1: plugin.PluginInfo
-	(*ParamSpec)(nil),       // 2: plugin.ParamSpec
-	(*ExecuteRequest)(nil),  // 3: plugin.ExecuteRequest
-	(*ExecuteOutput)(nil),   // 4: plugin.ExecuteOutput
-	(*Error)(nil),           // 5: plugin.Error
-	(*Progress)(nil),        // 6: plugin.Progress
-	(*SummaryRequest)(nil),  // 7: plugin.SummaryRequest
-	(*SummaryResponse)(nil), // 8: plugin.SummaryResponse
-	(*Authorization)(nil),   // 9: plugin.Authorization
-	nil,                     // 10: plugin.PluginInfo.ParameterSpecsEntry
-	nil,                     // 11: plugin.ExecuteRequest.ParamsEntry
-	nil,                     // 12: plugin.SummaryRequest.MetadataEntry
-	nil,                     // 13: plugin.SummaryRequest.MetricsEntry
-	nil,                     // 14: plugin.SummaryResponse.MetadataEntry
-	nil,                     // 15: plugin.SummaryResponse.MetricsEntry
+	(Channel)(0),                // 0: plugin.Channel
+	(*InfoRequest)(nil),         // 1: plugin.InfoRequest
+	(*PluginInfo)(nil),          // 2: plugin.PluginInfo
+	(*ParamSpec)(nil),           // 3: plugin.ParamSpec
+	(*ResultFieldSpec)(nil),     // 4: plugin.ResultFieldSpec
+	(*ExecuteRequest)(nil),      // 5: plugin.ExecuteRequest
+	(*ChannelFlow)(nil),         // 6: plugin.ChannelFlow
+	(*ExecuteOutput)(nil),       // 7: plugin.ExecuteOutput
+	(*LogEntry)(nil),            // 8: plugin.LogEntry
+	(*Artifact)(nil),            // 9: plugin.Artifact
+	(*Prompt)(nil),              // 10: plugin.Prompt
+	(*Metric)(nil),              // 11: plugin.Metric
+	(*Error)(nil),               // 12: plugin.Error
+	(*Progress)(nil),            // 13: plugin.Progress
+	(*SummaryRequest)(nil),      // 14: plugin.SummaryRequest
+	(*SummaryResponse)(nil),     // 15: plugin.SummaryResponse
+	(*DiagnosticsRequest)(nil),  // 16: plugin.DiagnosticsRequest
+	(*DiagnosticsResponse)(nil), // 17: plugin.DiagnosticsResponse
+	(*Authorization)(nil),       // 18: plugin.Authorization
+	nil,                         // 19: plugin.PluginInfo.ParameterSpecsEntry
+	nil,                         // 20: plugin.PluginInfo.ResultSchemaEntry
+	nil,                         // 21: plugin.ExecuteRequest.ParamsEntry
+	nil,                         // 22: plugin.ExecuteRequest.ChannelsEntry
+	nil,                         // 23: plugin.Metric.LabelsEntry
+	nil,                         // 24: plugin.SummaryRequest.MetadataEntry
+	nil,                         // 25: plugin.SummaryRequest.MetricsEntry
+	nil,                         // 26: plugin.SummaryResponse.MetadataEntry
+	nil,                         // 27: plugin.SummaryResponse.MetricsEntry
+	nil,                         // 28: plugin.DiagnosticsResponse.DumpsEntry
+	(*structpb.Struct)(nil),     // 29: google.protobuf.Struct
 }
 var file_proto_plugin_proto_depIdxs = []int32{
-	10, // 0: plugin.PluginInfo.parameter_specs:type_name -> plugin.PluginInfo.ParameterSpecsEntry
-	9,  // 1: plugin.PluginInfo.auth:type_name -> plugin.Authorization
-	11, // 2: plugin.ExecuteRequest.params:type_name -> plugin.ExecuteRequest.ParamsEntry
-	5,  // 3: plugin.ExecuteOutput.error:type_name -> plugin.Error
-	6,  // 4: plugin.ExecuteOutput.progress:type_name -> plugin.Progress
-	12, // 5: plugin.SummaryRequest.metadata:type_name -> plugin.SummaryRequest.MetadataEntry
-	13, // 6: plugin.SummaryRequest.metrics:type_name -> plugin.SummaryRequest.MetricsEntry
-	14, // 7: plugin.SummaryResponse.metadata:type_name -> plugin.SummaryResponse.MetadataEntry
-	15, // 8: plugin.SummaryResponse.metrics:type_name -> plugin.SummaryResponse.MetricsEntry
-	2,  // 9: plugin.PluginInfo.ParameterSpecsEntry.value:type_name -> plugin.ParamSpec
-	0,  // 10: plugin.Plugin.GetInfo:input_type -> plugin.InfoRequest
-	3,  // 11: plugin.Plugin.Execute:input_type -> plugin.ExecuteRequest
-	7,  // 12: plugin.Plugin.ReportExecutionSummary:input_type -> plugin.SummaryRequest
-	1,  // 13: plugin.Plugin.GetInfo:output_type -> plugin.PluginInfo
-	4,  // 14: plugin.Plugin.Execute:output_type -> plugin.ExecuteOutput
-	8,  // 15: plugin.Plugin.ReportExecutionSummary:output_type -> plugin.SummaryResponse
-	13, // [13:16] is the sub-list for method output_type
-	10, // [10:13] is the sub-list for method input_type
-	10, // [10:10] is the sub-list for extension type_name
-	10, // [10:10] is the sub-list for extension extendee
-	0,  // [0:10] is the sub-list for field type_name
+	19, // 0: plugin.PluginInfo.parameter_specs:type_name -> plugin.PluginInfo.ParameterSpecsEntry
+	18, // 1: plugin.PluginInfo.auth:type_name -> plugin.Authorization
+	20, // 2: plugin.PluginInfo.result_schema:type_name -> plugin.PluginInfo.ResultSchemaEntry
+	21, // 3: plugin.ExecuteRequest.params:type_name -> plugin.ExecuteRequest.ParamsEntry
+	29, // 4: plugin.ExecuteRequest.typed_params:type_name -> google.protobuf.Struct
+	22, // 5: plugin.ExecuteRequest.channels:type_name -> plugin.ExecuteRequest.ChannelsEntry
+	12, // 6: plugin.ExecuteOutput.error:type_name -> plugin.Error
+	13, // 7: plugin.ExecuteOutput.progress:type_name -> plugin.Progress
+	29, // 8: plugin.ExecuteOutput.result:type_name -> google.protobuf.Struct
+	8,  // 9: plugin.ExecuteOutput.log:type_name -> plugin.LogEntry
+	9,  // 10: plugin.ExecuteOutput.artifact:type_name -> plugin.Artifact
+	10, // 11: plugin.ExecuteOutput.prompt:type_name -> plugin.Prompt
+	11, // 12: plugin.ExecuteOutput.metric:type_name -> plugin.Metric
+	0,  // 13: plugin.ExecuteOutput.channel:type_name -> plugin.Channel
+	23, // 14: plugin.Metric.labels:type_name -> plugin.Metric.LabelsEntry
+	24, // 15: plugin.SummaryRequest.metadata:type_name -> plugin.SummaryRequest.MetadataEntry
+	25, // 16: plugin.SummaryRequest.metrics:type_name -> plugin.SummaryRequest.MetricsEntry
+	26, // 17: plugin.SummaryResponse.metadata:type_name -> plugin.SummaryResponse.MetadataEntry
+	27, // 18: plugin.SummaryResponse.metrics:type_name -> plugin.SummaryResponse.MetricsEntry
+	28, // 19: plugin.DiagnosticsResponse.dumps:type_name -> plugin.DiagnosticsResponse.DumpsEntry
+	3,  // 20: plugin.PluginInfo.ParameterSpecsEntry.value:type_name -> plugin.ParamSpec
+	4,  // 21: plugin.PluginInfo.ResultSchemaEntry.value:type_name -> plugin.ResultFieldSpec
+	6,  // 22: plugin.ExecuteRequest.ChannelsEntry.value:type_name -> plugin.ChannelFlow
+	1,  // 23: plugin.Plugin.GetInfo:input_type -> plugin.InfoRequest
+	5,  // 24: plugin.Plugin.Execute:input_type -> plugin.ExecuteRequest
+	14, // 25: plugin.Plugin.ReportExecutionSummary:input_type -> plugin.SummaryRequest
+	16, // 26: plugin.Plugin.Diagnostics:input_type -> plugin.DiagnosticsRequest
+	2,  // 27: plugin.Plugin.GetInfo:output_type -> plugin.PluginInfo
+	7,  // 28: plugin.Plugin.Execute:output_type -> plugin.ExecuteOutput
+	15, // 29: plugin.Plugin.ReportExecutionSummary:output_type -> plugin.SummaryResponse
+	17, // 30: plugin.Plugin.Diagnostics:output_type -> plugin.DiagnosticsResponse
+	27, // [27:31] is the sub-list for method output_type
+	23, // [23:27] is the sub-list for method input_type
+	23, // [23:23] is the sub-list for extension type_name
+	23, // [23:23] is the sub-list for extension extendee
+	0,  // [0:23] is the sub-list for field type_name
 }
 
 func init() { file_proto_plugin_proto_init() }
@@ -886,23 +1635,29 @@ func file_proto_plugin_proto_init() {
 	if File_proto_plugin_proto != nil {
 		return
 	}
-	file_proto_plugin_proto_msgTypes[4].OneofWrappers = []any{
+	file_proto_plugin_proto_msgTypes[6].OneofWrappers = []any{
 		(*ExecuteOutput_Output)(nil),
 		(*ExecuteOutput_Error)(nil),
 		(*ExecuteOutput_Progress)(nil),
+		(*ExecuteOutput_Result)(nil),
+		(*ExecuteOutput_Log)(nil),
+		(*ExecuteOutput_Artifact)(nil),
+		(*ExecuteOutput_Prompt)(nil),
+		(*ExecuteOutput_Metric)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_plugin_proto_rawDesc), len(file_proto_plugin_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   16,
+			NumEnums:      1,
+			NumMessages:   28,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_proto_plugin_proto_goTypes,
 		DependencyIndexes: file_proto_plugin_proto_depIdxs,
+		EnumInfos:         file_proto_plugin_proto_enumTypes,
 		MessageInfos:      file_proto_plugin_proto_msgTypes,
 	}.Build()
 	File_proto_plugin_proto = out.File
@@ -22,6 +22,7 @@ const (
 	Plugin_GetInfo_FullMethodName                = "/plugin.Plugin/GetInfo"
 	Plugin_Execute_FullMethodName                = "/plugin.Plugin/Execute"
 	Plugin_ReportExecutionSummary_FullMethodName = "/plugin.Plugin/ReportExecutionSummary"
+	Plugin_Diagnostics_FullMethodName            = "/plugin.Plugin/Diagnostics"
 )
 
 // PluginClient is the client API for Plugin service.
@@ -34,6 +35,8 @@ type PluginClient interface {
 	Execute(ctx context.Context, in *ExecuteRequest, opts ...grpc.CallOption) (Plugin_ExecuteClient, error)
 	// ReportExecutionSummary sends execution summary data
 	ReportExecutionSummary(ctx context.Context, in *SummaryRequest, opts ...grpc.CallOption) (*SummaryResponse, error)
+	// Diagnostics returns dumps of the plugin's internal state, e.g. when an execution hangs
+	Diagnostics(ctx context.Context, in *DiagnosticsRequest, opts ...grpc.CallOption) (*DiagnosticsResponse, error)
 }
 
 type pluginClient struct {
@@ -94,6 +97,15 @@ func (c *pluginClient) ReportExecutionSummary(ctx context.Context, in *SummaryRe
 	return out, nil
 }
 
+func (c *pluginClient) Diagnostics(ctx context.Context, in *DiagnosticsRequest, opts ...grpc.CallOption) (*DiagnosticsResponse, error) {
+	out := new(DiagnosticsResponse)
+	err := c.cc.Invoke(ctx, Plugin_Diagnostics_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PluginServer is the server API for Plugin service.
 // All implementations must embed UnimplementedPluginServer
 // for forward compatibility
@@ -104,6 +116,8 @@ type PluginServer interface {
 	Execute(*ExecuteRequest, Plugin_ExecuteServer) error
 	// ReportExecutionSummary sends execution summary data
 	ReportExecutionSummary(context.Context, *SummaryRequest) (*SummaryResponse, error)
+	// Diagnostics returns dumps of the plugin's internal state, e.g. when an execution hangs
+	Diagnostics(context.Context, *DiagnosticsRequest) (*DiagnosticsResponse, error)
 	mustEmbedUnimplementedPluginServer()
 }
 
@@ -120,6 +134,9 @@ func (UnimplementedPluginServer) Execute(*ExecuteRequest, Plugin_ExecuteServer)
 func (UnimplementedPluginServer) ReportExecutionSummary(context.Context, *SummaryRequest) (*SummaryResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ReportExecutionSummary not implemented")
 }
+func (UnimplementedPluginServer) Diagnostics(context.Context, *DiagnosticsRequest) (*DiagnosticsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Diagnostics not implemented")
+}
 func (UnimplementedPluginServer) mustEmbedUnimplementedPluginServer() {}
 
 // UnsafePluginServer may be embedded to opt out of forward compatibility for this service.
@@ -190,6 +207,24 @@ func _Plugin_ReportExecutionSummary_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Plugin_Diagnostics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiagnosticsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginServer).Diagnostics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Plugin_Diagnostics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginServer).Diagnostics(ctx, req.(*DiagnosticsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Plugin_ServiceDesc is the grpc.ServiceDesc for Plugin service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -205,6 +240,10 @@ var Plugin_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ReportExecutionSummary",
 			Handler:    _Plugin_ReportExecutionSummary_Handler,
 		},
+		{
+			MethodName: "Diagnostics",
+			Handler:    _Plugin_Diagnostics_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{